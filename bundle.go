@@ -1,3 +1,4 @@
 package bundle
 
-//go:generate sh -c "go run goembed.go -package bundled -var assets assets/header.tmpl assets/footer.tmpl assets/style.css assets/manpage.tmpl assets/manpageerror.tmpl assets/manpagefooterextra.tmpl assets/contents.tmpl assets/pkgindex.tmpl assets/srcpkgindex.tmpl assets/index.tmpl assets/faq.tmpl assets/notfound.tmpl assets/Inconsolata.woff assets/Inconsolata.woff2 assets/opensearch.xml assets/Roboto-Bold.woff assets/Roboto-Bold.woff2 assets/Roboto-Regular.woff assets/Roboto-Regular.woff2 > internal/bundled/GENERATED_bundled.go"
+//go:generate sh -c "go run goembed.go -package bundled -var assets assets/header.tmpl assets/footer.tmpl assets/style.css assets/favicon.ico assets/favicon.svg assets/apple-touch-icon.png assets/manifest.webmanifest assets/manpage.tmpl assets/manpageerror.tmpl assets/manpagefooterextra.tmpl assets/manpagefragment.tmpl assets/manpageminimal.tmpl assets/contents.tmpl assets/files.tmpl assets/filespage.tmpl assets/pkgindex.tmpl assets/srcpkgindex.tmpl assets/sectionindex.tmpl assets/sectionpage.tmpl assets/index.tmpl assets/faq.tmpl assets/notfound.tmpl assets/llms.tmpl assets/news.tmpl assets/Inconsolata.woff assets/Inconsolata.woff2 assets/opensearch.xml assets/Roboto-Bold.woff assets/Roboto-Bold.woff2 assets/Roboto-Regular.woff assets/Roboto-Regular.woff2 > internal/bundled/GENERATED_bundled.go"
+//go:generate sh -c "go run goembed.go -package bundled -var selftest assets/selftest/i3lock.1 assets/selftest/i3lock.1.html > internal/bundled/GENERATED_selftest.go"
@@ -2,18 +2,23 @@
 package main
 
 import (
+	"bytes"
 	"flag"
+	"fmt"
 	"html/template"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime/debug"
 	"syscall"
 
 	"github.com/Debian/debiman/internal/aux"
+	"github.com/Debian/debiman/internal/blob"
 	"github.com/Debian/debiman/internal/bundled"
 	"github.com/Debian/debiman/internal/commontmpl"
+	"github.com/Debian/debiman/internal/logging"
 	"github.com/Debian/debiman/internal/redirect"
 )
 
@@ -22,6 +27,10 @@ var (
 		"/srv/man/auxserver.idx",
 		"Path to an auxserver index generated by debiman")
 
+	overridesPath = flag.String("overrides",
+		"",
+		"If non-empty, path to a file of “from to” URL path pairs (one per line, e.g. “/legacy/ls /jessie/coreutils/ls.1”): requests for a from path are resolved like requests for the to path, taking precedence over the manpages of the index. Overrides whose to path does not resolve are logged and ignored. Reloaded along with the index on SIGHUP")
+
 	listenAddr = flag.String("listen",
 		"localhost:2431",
 		"host:port address to listen on")
@@ -30,33 +39,192 @@ var (
 		"",
 		"If non-empty, a file system path to a directory containing assets to overwrite")
 
+	icons = flag.String("icons",
+		"",
+		"If non-empty, a file system path to a directory containing replacements for some or all of favicon.ico, favicon.svg and apple-touch-icon.png (e.g. for rebranding)")
+
 	baseURL = flag.String("base_url",
 		"https://manpages.debian.org",
 		"Base URL (without trailing slash) to the site. Used where absolute URLs are required, e.g. sitemaps.")
+
+	suiteFallback = flag.String("suite_fallback",
+		"any",
+		"What to do when the requested suite does not contain the requested manpage (in the requested section, if any). One of “any” (redirect to any suite containing the manpage, preferring the default suite), “newer” (redirect to the nearest newer suite, whose page then displays a banner, or show the not found page if there is none) or “none” (show the not found page)")
+
+	corsOrigin = flag.String("cors_origin",
+		"",
+		"If non-empty, a comma-separated list of origins (e.g. https://example.com,https://example.org:8443) or * to allow cross-origin requests to /suggest and to manpage fragments (?embed=1) from. Origins are matched exactly. Does not apply to any other responses")
+
+	multipleChoicesBelow = flag.Int("multiple_choices_below",
+		0,
+		"If non-zero, respond with HTTP 300 Multiple Choices (the preferred manpage in the Location header, the alternatives in Link headers) instead of redirecting when multiple binary packages ship the requested manpage and fewer than this many of suite, binary package, section and language were requested and match exactly. E.g. 1 makes /vi respond with 300, but not /jessie/vi. Every redirect carries an X-Debiman-Specificity header describing how well it matches")
+
+	prefixRedirects = flag.Bool("prefix_redirects",
+		false,
+		"Treat requests for an unknown name without suite, package or section (e.g. /coreut) as a prefix: redirect to the manpage or binary package page if exactly one name starts with it, or respond with HTTP 300 Multiple Choices listing the matches. If false, such requests result in HTTP 404")
+
+	cacheMaxAge = flag.String("cache_max_age",
+		"testing=1h,unstable=1h,experimental=1h,*=24h",
+		"Comma-separated list of suite=duration pairs determining for how long caches (e.g. CDNs) may reuse redirects to the manpages of each suite (Cache-Control: max-age). Suites may be specified by suite name or codename; “*” stands for all other suites. Not found pages use the shortest duration. Empty sends no Cache-Control headers")
+
+	preloadLinks = flag.Bool("preload_links",
+		true,
+		"Advertise the stylesheet of the pages via Link: rel=preload headers on redirects and HTML responses. Disable if your CDN injects its own resource hints")
+
+	logLevel = flag.String("log_level",
+		"info",
+		"One of error (only log errors), info (log index reloads) or debug (additionally log how each redirect was resolved)")
+
+	buildInfoPath = flag.String("build_info",
+		"",
+		"Path to the build-info.json file generated by debiman, served at /build-info. Defaults to build-info.json in the directory of -index. If the file does not exist, /build-info responds with HTTP 404")
+
+	llmsTxtPath = flag.String("llms_txt",
+		"",
+		"Path to the llms.txt file generated by debiman -llms_txt, served at /llms.txt. Defaults to llms.txt in the directory of -index. If the file does not exist, /llms.txt responds with HTTP 404")
+
+	verbose = flag.Bool("v",
+		false,
+		"Shorthand for -log_level=debug")
+
+	contentSecurityPolicy = flag.String("content_security_policy",
+		aux.DefaultContentSecurityPolicy,
+		"Content-Security-Policy header to send with every response, or empty to send none. See “Security headers and content types” in README.md for what the default allows")
+
+	referrerPolicy = flag.String("referrer_policy",
+		aux.DefaultReferrerPolicy,
+		"Referrer-Policy header to send with every response, or empty to send none. See “Security headers and content types” in README.md")
+
+	rwmapPath = flag.String("rwmap",
+		"",
+		"If non-empty, path to a binary rewrite map generated by debiman-idx2rwmap -format=binary. Requests for one of its keys (without query) are redirected to the key’s target straight from the (memory-mapped) map, like a web server using the rewrite map would; all other requests are resolved using the index. The map is opened once at startup and not reloaded on SIGHUP, so restart debiman-auxserver after regenerating it")
+
+	contentTypes = flag.String("content_types",
+		"",
+		"Comma-separated list of extension=type pairs (e.g. “.txt=text/plain; charset=us-ascii”) overriding the Content-Type of served files. See “Security headers and content types” in README.md")
 )
 
 // use go build -ldflags "-X main.debimanVersion=<version>" to set the version
 var debimanVersion = "HEAD"
 
+// loadOverrides sets idx.Overrides from -overrides, if specified,
+// logging broken overrides.
+func loadOverrides(idx *redirect.Index, lg *logging.Logger) error {
+	if *overridesPath == "" {
+		return nil
+	}
+	overrides, broken, err := idx.LoadOverrides(*overridesPath)
+	if err != nil {
+		return err
+	}
+	for _, err := range broken {
+		lg.Errorf("Ignoring broken override in %q: %v", *overridesPath, err)
+	}
+	log.Printf("Loaded %d overrides from %q", len(overrides), *overridesPath)
+	idx.Overrides = overrides
+	return nil
+}
+
 func main() {
 	flag.Parse()
 
+	level, err := logging.ParseLevel(*logLevel)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *verbose {
+		level = logging.Debug
+	}
+	lg := logging.SetupStd(level)
+
+	overrides, err := blob.ParseContentTypes(*contentTypes)
+	if err != nil {
+		log.Fatalf("parsing -content_types: %v", err)
+	}
+	blob.OverrideContentTypes(overrides)
+
 	log.Printf("debiman auxserver loading index from %q", *indexPath)
 
 	if *injectAssets != "" {
 		if err := bundled.Inject(*injectAssets); err != nil {
-			log.Fatal(err)
+			lg.Fatalf("%v", err)
+		}
+	}
+	if *icons != "" {
+		if err := bundled.InjectFiles(*icons, commontmpl.Icons); err != nil {
+			lg.Fatalf("%v", err)
 		}
 	}
 
+	fallback, err := redirect.ParseSuiteFallback(*suiteFallback)
+	if err != nil {
+		lg.Fatalf("%v", err)
+	}
+
 	idx, err := redirect.IndexFromProto(*indexPath)
 	if err != nil {
-		log.Fatal(err)
+		lg.Fatalf("%v", err)
+	}
+	idx.SuiteFallback = fallback
+	idx.Log = lg
+	if err := loadOverrides(&idx, lg); err != nil {
+		lg.Fatalf("%v", err)
 	}
 
 	commonTmpls := commontmpl.MustParseCommonTmpls()
 	notFoundTmpl := template.Must(commonTmpls.New("notfound").Parse(bundled.Asset("notfound.tmpl")))
+	var manifest bytes.Buffer
+	if err := commonTmpls.ExecuteTemplate(&manifest, "manifest", nil); err != nil {
+		lg.Fatalf("%v", err)
+	}
 	server := aux.NewServer(idx, notFoundTmpl, debimanVersion)
+	server.CORS, err = aux.ParseCORSPolicy(*corsOrigin)
+	if err != nil {
+		lg.Fatalf("parsing -cors_origin: %v", err)
+	}
+	server.MultipleChoicesBelow = *multipleChoicesBelow
+	server.PrefixRedirects = *prefixRedirects
+	server.Cache, err = aux.ParseCachePolicy(*cacheMaxAge)
+	if err != nil {
+		lg.Fatalf("parsing -cache_max_age: %v", err)
+	}
+	if *preloadLinks {
+		server.Preload = commontmpl.PreloadLinks()
+	}
+	if *rwmapPath != "" {
+		rwmap, err := redirect.OpenBinaryMap(*rwmapPath)
+		if err != nil {
+			lg.Fatalf("opening -rwmap: %v", err)
+		}
+		log.Printf("Loaded %d keys from rewrite map %q", rwmap.Len(), *rwmapPath)
+		server.RewriteMap = rwmap
+	}
+
+	var metrics indexMetrics
+	metrics.swapped(idx)
+
+	// While a new index is loaded, requests continue to be served from
+	// the current one (see aux.Server.SwapIndex).
+	reload := func() error {
+		newidx, err := redirect.IndexFromProto(*indexPath)
+		if err != nil {
+			return fmt.Errorf("loading new index from %q: %v", *indexPath, err)
+		}
+		newidx.SuiteFallback = fallback
+		newidx.Log = lg
+		if err := loadOverrides(&newidx, lg); err != nil {
+			return fmt.Errorf("loading overrides: %v", err)
+		}
+
+		log.Printf("Loaded %d manpage entries, %d suites, %d languages from new index %q (version %s)",
+			len(newidx.Entries), len(newidx.Suites), len(newidx.Langs), *indexPath, newidx.Version)
+
+		if err := server.SwapIndex(newidx); err != nil {
+			return fmt.Errorf("swapping index: %v", err)
+		}
+		metrics.swapped(newidx)
+		return nil
+	}
 
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, syscall.SIGHUP)
@@ -64,17 +232,10 @@ func main() {
 		for _ = range c {
 			log.Printf("SIGHUP received, trying to reload index")
 
-			newidx, err := redirect.IndexFromProto(*indexPath)
+			err := reload()
+			metrics.reloaded(err)
 			if err != nil {
-				log.Printf("Could not load new index from %q: %v", *indexPath, err)
-				continue
-			}
-
-			log.Printf("Loaded %d manpage entries, %d suites, %d languages from new index %q",
-				len(newidx.Entries), len(newidx.Suites), len(newidx.Langs), *indexPath)
-
-			if err := server.SwapIndex(newidx); err != nil {
-				log.Printf("Swapping index failed: %v", err)
+				lg.Errorf("Could not reload index: %v", err)
 				continue
 			}
 
@@ -88,16 +249,60 @@ func main() {
 		}
 	}()
 
+	var ready readiness
+	if *metricsListenAddr != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", &metrics)
+		metricsMux.Handle("/ready", &ready)
+		log.Printf("Starting metrics HTTP listener on %q", *metricsListenAddr)
+		go func() {
+			lg.Fatalf("%v", http.ListenAndServe(*metricsListenAddr, metricsMux))
+		}()
+	}
+
+	buildInfo := *buildInfoPath
+	if buildInfo == "" {
+		buildInfo = filepath.Join(filepath.Dir(*indexPath), "build-info.json")
+	}
+	llmsTxt := *llmsTxtPath
+	if llmsTxt == "" {
+		llmsTxt = filepath.Join(filepath.Dir(*indexPath), "llms.txt")
+	}
+
 	basePath := commontmpl.BaseURLPath()
 	mux := http.NewServeMux()
 	mux.HandleFunc("/jump", server.HandleJump)
 	mux.HandleFunc("/suggest", server.HandleSuggest)
+	mux.HandleFunc("/random", server.HandleRandom)
+	mux.HandleFunc("/build-info", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		http.ServeFile(w, r, buildInfo)
+	})
+	mux.HandleFunc("/llms.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		http.ServeFile(w, r, llmsTxt)
+	})
+	for _, name := range commontmpl.Icons {
+		mux.Handle("/"+name, aux.StaticHandler(name, []byte(bundled.Asset(name))))
+	}
+	mux.Handle("/manifest.webmanifest", aux.StaticHandler("manifest.webmanifest", manifest.Bytes()))
 	mux.HandleFunc("/", server.HandleRedirect)
+	if *readinessPath != "" {
+		// Not below basePath: load balancers check the backend itself.
+		http.Handle(*readinessPath, &ready)
+	}
 	http.Handle("/", http.StripPrefix(basePath, mux))
 
-	log.Printf("Loaded %d manpage entries, %d suites, %d languages from index %q",
-		len(idx.Entries), len(idx.Suites), len(idx.Langs), *indexPath)
+	log.Printf("Loaded %d manpage entries, %d suites, %d languages from index %q (version %s)",
+		len(idx.Entries), len(idx.Suites), len(idx.Langs), *indexPath, idx.Version)
 
 	log.Printf("Starting HTTP listener on %q", *listenAddr)
-	log.Fatal(http.ListenAndServe(*listenAddr, nil))
+	headers := aux.SecurityHeaders{
+		ContentSecurityPolicy: *contentSecurityPolicy,
+		ReferrerPolicy:        *referrerPolicy,
+	}
+	serve(&http.Server{
+		Addr:    *listenAddr,
+		Handler: headers.Wrap(http.DefaultServeMux),
+	}, &ready, lg)
 }
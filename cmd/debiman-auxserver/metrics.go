@@ -0,0 +1,81 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"net/http"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/Debian/debiman/internal/redirect"
+)
+
+var metricsListenAddr = flag.String("metrics_listen",
+	"",
+	"If non-empty, host:port address (e.g. localhost:2432) on which to serve metrics in the Prometheus text format at /metrics: the number of index reloads (by result) and the version of the index serving requests (see X-Index-Version). Separate from -listen, so that metrics are not exposed to the clients")
+
+const metricsTmplContent = `# HELP auxserver_index_reloads_total Number of index reloads (on SIGHUP), by result.
+# TYPE auxserver_index_reloads_total counter
+auxserver_index_reloads_total{result="success"} {{ .Reloads }}
+auxserver_index_reloads_total{result="failure"} {{ .Failures }}
+
+# HELP auxserver_index_info Version of the index serving requests.
+# TYPE auxserver_index_info gauge
+auxserver_index_info{version="{{ .Version }}"} 1
+
+# HELP auxserver_index_loaded_timestamp_seconds Time at which the index serving requests was loaded, in seconds since the epoch.
+# TYPE auxserver_index_loaded_timestamp_seconds gauge
+auxserver_index_loaded_timestamp_seconds {{ .Loaded.Unix }}
+{{ if not .Built.IsZero }}
+# HELP auxserver_index_built_timestamp_seconds Start of the debiman run which wrote the index serving requests, in seconds since the epoch.
+# TYPE auxserver_index_built_timestamp_seconds gauge
+auxserver_index_built_timestamp_seconds {{ .Built.Unix }}
+{{ end -}}
+`
+
+var metricsTmpl = template.Must(template.New("metrics").Parse(metricsTmplContent))
+
+// indexMetrics tracks the index reloads for the -metrics_listen
+// handler.
+type indexMetrics struct {
+	mu       sync.Mutex
+	Reloads  uint64
+	Failures uint64
+	Version  string
+	Built    time.Time
+	Loaded   time.Time
+}
+
+// swapped records that idx serves requests from now on.
+func (m *indexMetrics) swapped(idx redirect.Index) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Version = idx.Version
+	m.Built = idx.Built
+	m.Loaded = time.Now()
+}
+
+// reloaded counts a reload which failed if err is non-nil.
+func (m *indexMetrics) reloaded(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		m.Failures++
+	} else {
+		m.Reloads++
+	}
+}
+
+func (m *indexMetrics) write(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return metricsTmpl.Execute(w, m)
+}
+
+func (m *indexMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := m.write(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/Debian/debiman/internal/logging"
+)
+
+var (
+	drainTimeout = flag.Duration("drain_timeout",
+		30*time.Second,
+		"On SIGTERM or SIGINT, how long to wait for in-flight requests to finish after closing the listener, before closing their connections forcibly and exiting")
+
+	shutdownDelay = flag.Duration("shutdown_delay",
+		0,
+		"On SIGTERM or SIGINT, how long to keep accepting requests while the readiness endpoint (see -readiness_path) already reports not ready, so that load balancers stop sending traffic before the listener is closed. Should exceed the interval of the load balancer’s health checks")
+
+	readinessPath = flag.String("readiness_path",
+		"",
+		"If non-empty, URL path (e.g. /ready) on -listen at which to respond with HTTP 200 while serving, and with HTTP 503 once shutting down (see -shutdown_delay), for the health checks of load balancers. Not relative to the path of -base_url. Also served at /ready on -metrics_listen, if specified")
+)
+
+// readiness reports whether the auxserver accepts traffic.
+type readiness struct {
+	shuttingDown int32 // atomic
+}
+
+func (r *readiness) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	if atomic.LoadInt32(&r.shuttingDown) != 0 {
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("ready\n"))
+}
+
+// serve serves srv until SIGTERM or SIGINT is received, see
+// serveUntil.
+func serve(srv *http.Server, ready *readiness, lg *logging.Logger) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGTERM, os.Interrupt)
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		lg.Fatalf("%v", err)
+	}
+	if err := serveUntil(srv, ln, c, ready, lg); err != nil {
+		lg.Fatalf("%v", err)
+	}
+	log.Printf("All connections closed, exiting")
+}
+
+// serveUntil serves srv on ln until a signal is received on sigs, then
+// marks ready as not ready, closes the listener after -shutdown_delay
+// and waits up to -drain_timeout for in-flight requests to finish
+// before closing their connections.
+func serveUntil(srv *http.Server, ln net.Listener, sigs <-chan os.Signal, ready *readiness, lg *logging.Logger) error {
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		sig := <-sigs
+		atomic.StoreInt32(&ready.shuttingDown, 1)
+		log.Printf("%v received, shutting down", sig)
+		if *shutdownDelay > 0 {
+			log.Printf("Reporting not ready for %v before closing the listener", *shutdownDelay)
+			time.Sleep(*shutdownDelay)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), *drainTimeout)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			lg.Errorf("Could not drain connections within %v, closing them: %v", *drainTimeout, err)
+			srv.Close()
+		}
+	}()
+
+	if err := srv.Serve(ln); err != http.ErrServerClosed {
+		return err
+	}
+	<-drained
+	return nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Debian/debiman/internal/logging"
+)
+
+func TestServeUntil(t *testing.T) {
+	oldDelay, oldTimeout := *shutdownDelay, *drainTimeout
+	defer func() { *shutdownDelay, *drainTimeout = oldDelay, oldTimeout }()
+
+	for _, entry := range []struct {
+		name         string
+		drainTimeout time.Duration
+		finish       bool // whether the in-flight request can finish
+	}{
+		{name: "Drained", drainTimeout: 10 * time.Second, finish: true},
+		{name: "TimedOut", drainTimeout: 10 * time.Millisecond},
+	} {
+		t.Run(entry.name, func(t *testing.T) {
+			*shutdownDelay, *drainTimeout = 50*time.Millisecond, entry.drainTimeout
+
+			var ready readiness
+			started := make(chan struct{})
+			release := make(chan struct{})
+			mux := http.NewServeMux()
+			mux.Handle("/ready", &ready)
+			mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				<-release
+				w.Write([]byte("done"))
+			})
+			ln, err := net.Listen("tcp", "localhost:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			url := "http://" + ln.Addr().String()
+			sigs := make(chan os.Signal, 1)
+			served := make(chan error, 1)
+			go func() {
+				served <- serveUntil(&http.Server{Handler: mux}, ln, sigs, &ready, logging.New(logging.Error))
+			}()
+
+			type result struct {
+				body string
+				err  error
+			}
+			slow := make(chan result, 1)
+			go func() {
+				resp, err := http.Get(url + "/slow")
+				if err != nil {
+					slow <- result{err: err}
+					return
+				}
+				defer resp.Body.Close()
+				b, err := ioutil.ReadAll(resp.Body)
+				slow <- result{string(b), err}
+			}()
+			<-started
+
+			resp, err := http.Get(url + "/ready")
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if got, want := resp.StatusCode, http.StatusOK; got != want {
+				t.Errorf("readiness while serving: got HTTP %d, want %d", got, want)
+			}
+
+			sigs <- syscall.SIGTERM
+			// Within -shutdown_delay, requests are still served, but
+			// the readiness endpoint reports not ready.
+			time.Sleep(10 * time.Millisecond)
+			resp, err = http.Get(url + "/ready")
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if got, want := resp.StatusCode, http.StatusServiceUnavailable; got != want {
+				t.Errorf("readiness during shutdown: got HTTP %d, want %d", got, want)
+			}
+
+			if entry.finish {
+				time.Sleep(*shutdownDelay)
+				close(release)
+			}
+			select {
+			case err := <-served:
+				if err != nil {
+					t.Fatalf("serveUntil: %v", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("serveUntil did not return after the signal")
+			}
+			r := <-slow
+			if entry.finish && (r.err != nil || r.body != "done") {
+				t.Errorf("in-flight request: got %q, %v, want it to finish", r.body, r.err)
+			}
+			if !entry.finish && r.err == nil {
+				t.Errorf("in-flight request unexpectedly finished despite -drain_timeout")
+			}
+			if !entry.finish {
+				close(release)
+			}
+		})
+	}
+}
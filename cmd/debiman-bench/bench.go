@@ -0,0 +1,379 @@
+// debiman-bench measures how many redirect lookups per second
+// debiman-auxserver handles with a given index. It generates a mix of
+// request paths covering the 16 URL forms (see the redirect package)
+// and some misses, and drives either the in-process handler or a
+// running server (-url) at the configured concurrency:
+//
+//	debiman-bench -index=/srv/man/auxserver.idx -duration=30s
+//
+// Without -index, a synthetic index of -synthetic_manpages manpages is
+// used, so that a smoke test does not require a debiman run:
+//
+//	debiman-bench -synthetic_manpages=1000 -requests=10000 -min_rps=1000
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Debian/debiman/internal/aux"
+	"github.com/Debian/debiman/internal/bundled"
+	"github.com/Debian/debiman/internal/commontmpl"
+	"github.com/Debian/debiman/internal/redirect"
+)
+
+var (
+	indexPath = flag.String("index",
+		"",
+		"Path to an auxserver index generated by debiman. If empty, a synthetic index is generated (see -synthetic_manpages)")
+
+	syntheticManpages = flag.Int("synthetic_manpages",
+		10000,
+		"Number of manpage names in the synthetic index used when -index is empty")
+
+	targetURL = flag.String("url",
+		"",
+		"If non-empty, the base URL of a running debiman-auxserver (e.g. http://localhost:2431) to send requests to, instead of calling the handler in-process. The paths are still generated from -index, which should be the index the server uses")
+
+	baseURL = flag.String("base_url",
+		"https://manpages.debian.org",
+		"Base URL (without trailing slash) to the site, like debiman-auxserver’s -base_url. Only used for the in-process handler")
+
+	concurrency = flag.Int("concurrency",
+		0,
+		"Number of requests in flight at once. Defaults to the number of logical CPUs")
+
+	requests = flag.Int("requests",
+		0,
+		"Number of requests to send. 0 sends requests until -duration has elapsed")
+
+	duration = flag.Duration("duration",
+		10*time.Second,
+		"For how long to send requests if -requests is 0")
+
+	missRatio = flag.Float64("miss_ratio",
+		0.1,
+		"Fraction of requests for manpages which are not in the index")
+
+	seed = flag.Int64("seed",
+		1,
+		"Seed for generating request paths, so that runs are comparable")
+
+	minRPS = flag.Float64("min_rps",
+		0,
+		"If positive, exit with a non-zero status if fewer requests per second were handled, e.g. to catch lookup performance regressions in CI")
+)
+
+var syntheticSuites = []string{"jessie", "stretch", "sid"}
+
+// syntheticIndex returns an index of n manpage names in
+// syntheticSuites, shipped by one or two binary packages each, in
+// various sections and languages.
+func syntheticIndex(n int, rnd *rand.Rand) redirect.Index {
+	idx := redirect.Index{
+		Entries: make(map[string][]redirect.IndexEntry, n),
+		Suites: map[string]string{
+			"jessie":    "jessie",
+			"oldstable": "jessie",
+			"stretch":   "stretch",
+			"stable":    "stretch",
+			"sid":       "sid",
+			"unstable":  "sid",
+		},
+		Langs:    map[string]bool{"en": true, "de": true, "fr": true},
+		Sections: map[string]bool{"0": true},
+	}
+	sections := []string{"1", "3", "3pm", "5", "8"}
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("tool%d", i)
+		pkgs := []string{fmt.Sprintf("pkg%d", i/3)}
+		if rnd.Intn(10) == 0 {
+			pkgs = append(pkgs, fmt.Sprintf("alt%d", i))
+		}
+		section := sections[rnd.Intn(len(sections))]
+		idx.Sections[section] = true
+		langs := []string{"en"}
+		if rnd.Intn(4) == 0 {
+			langs = append(langs, "de", "fr")
+		}
+		for _, suite := range syntheticSuites {
+			for _, pkg := range pkgs {
+				for _, lang := range langs {
+					idx.Entries[name] = append(idx.Entries[name], redirect.IndexEntry{
+						Name:      name,
+						Suite:     suite,
+						Binarypkg: pkg,
+						Section:   section,
+						Language:  lang,
+					})
+				}
+			}
+		}
+	}
+	// Required by aux.Server.SwapIndex, see debiman-auxserver.
+	for _, suite := range syntheticSuites {
+		idx.Entries["i3"] = append(idx.Entries["i3"], redirect.IndexEntry{
+			Name:      "i3",
+			Suite:     suite,
+			Binarypkg: "i3-wm",
+			Section:   "1",
+			Language:  "en",
+		})
+	}
+	return idx
+}
+
+// pathGenerator generates request paths for the manpages of an index.
+type pathGenerator struct {
+	idx     redirect.Index
+	names   []string
+	aliases map[string][]string // suite → names in idx.Suites
+}
+
+func newPathGenerator(idx redirect.Index) *pathGenerator {
+	g := &pathGenerator{
+		idx:     idx,
+		names:   make([]string, 0, len(idx.Entries)),
+		aliases: make(map[string][]string),
+	}
+	for name := range idx.Entries {
+		g.names = append(g.names, name)
+	}
+	// Map iteration order is random, but paths must only depend on
+	// -seed.
+	sort.Strings(g.names)
+	for name, suite := range idx.Suites {
+		g.aliases[suite] = append(g.aliases[suite], name)
+	}
+	for _, names := range g.aliases {
+		sort.Strings(names)
+	}
+	return g
+}
+
+// path returns a request path for a random manpage of the index in one
+// of the 16 URL forms (suite, binary package, section and language
+// each present or not), or, with probability missRatio, for a manpage
+// which is not in the index.
+func (g *pathGenerator) path(rnd *rand.Rand, missRatio float64) string {
+	if rnd.Float64() < missRatio {
+		name := fmt.Sprintf("missing%d", rnd.Intn(1000000))
+		if rnd.Intn(2) == 0 {
+			return "/" + syntheticSuites[rnd.Intn(len(syntheticSuites))] + "/" + name
+		}
+		return "/" + name
+	}
+	entries := g.idx.Entries[g.names[rnd.Intn(len(g.names))]]
+	e := entries[rnd.Intn(len(entries))]
+	form := rnd.Intn(16)
+	var parts []string
+	if form&8 != 0 {
+		suite := e.Suite
+		if aliases := g.aliases[e.Suite]; len(aliases) > 0 {
+			suite = aliases[rnd.Intn(len(aliases))]
+		}
+		parts = append(parts, suite)
+	}
+	if form&4 != 0 {
+		parts = append(parts, e.Binarypkg)
+	}
+	name := g.idx.URLCase.Name(e.Name)
+	if form&2 != 0 {
+		name += "." + e.Section
+	}
+	if form&1 != 0 {
+		name += "." + e.Language
+	}
+	return "/" + strings.Join(append(parts, name), "/")
+}
+
+// doer sends the request for path and returns the HTTP status code.
+type doer func(path string) (int, error)
+
+func inProcess(idx redirect.Index) doer {
+	notFoundTmpl := template.Must(commontmpl.MustParseCommonTmpls().New("notfound").Parse(bundled.Asset("notfound.tmpl")))
+	server := aux.NewServer(idx, notFoundTmpl, "bench")
+	return func(path string) (int, error) {
+		rec := httptest.NewRecorder()
+		server.HandleRedirect(rec, httptest.NewRequest("GET", path, nil))
+		return rec.Code, nil
+	}
+}
+
+func remote(base string) doer {
+	client := &http.Client{
+		// Measure the redirects, not the pages they lead to.
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+		Transport: &http.Transport{
+			MaxIdleConnsPerHost: *concurrency,
+		},
+	}
+	base = strings.TrimSuffix(base, "/")
+	return func(path string) (int, error) {
+		resp, err := client.Get(base + path)
+		if err != nil {
+			return 0, err
+		}
+		defer resp.Body.Close()
+		io.Copy(ioutil.Discard, resp.Body)
+		return resp.StatusCode, nil
+	}
+}
+
+type result struct {
+	elapsed   time.Duration
+	latencies []time.Duration // sorted
+	statuses  map[int]int
+	errors    int
+}
+
+func (r result) rps() float64 {
+	return float64(len(r.latencies)) / r.elapsed.Seconds()
+}
+
+// percentile returns the latency below which p percent of the
+// requests completed.
+func (r result) percentile(p float64) time.Duration {
+	if len(r.latencies) == 0 {
+		return 0
+	}
+	i := int(float64(len(r.latencies)-1) * p / 100)
+	return r.latencies[i]
+}
+
+// run sends n requests (or requests until d has elapsed if n is 0)
+// using workers goroutines. Each worker generates its paths with its
+// own source of randomness, derived from seed.
+func run(do doer, g *pathGenerator, workers, n int, d time.Duration, seed int64, missRatio float64) result {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		res      = result{statuses: make(map[int]int)}
+		deadline = time.Now().Add(d)
+		work     chan struct{}
+	)
+	if n > 0 {
+		work = make(chan struct{}, n)
+		for i := 0; i < n; i++ {
+			work <- struct{}{}
+		}
+		close(work)
+	}
+	start := time.Now()
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(seed + int64(i)))
+			var latencies []time.Duration
+			statuses := make(map[int]int)
+			var errors int
+			for {
+				if work != nil {
+					if _, ok := <-work; !ok {
+						break
+					}
+				} else if time.Now().After(deadline) {
+					break
+				}
+				path := g.path(rnd, missRatio)
+				t := time.Now()
+				status, err := do(path)
+				latencies = append(latencies, time.Since(t))
+				if err != nil {
+					errors++
+					continue
+				}
+				statuses[status]++
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			res.latencies = append(res.latencies, latencies...)
+			for status, count := range statuses {
+				res.statuses[status] += count
+			}
+			res.errors += errors
+		}(i)
+	}
+	wg.Wait()
+	res.elapsed = time.Since(start)
+	sort.Slice(res.latencies, func(i, j int) bool { return res.latencies[i] < res.latencies[j] })
+	return res
+}
+
+func (r result) print(w io.Writer, workers int) {
+	fmt.Fprintf(w, "requests:    %d in %v (%.0f req/s), concurrency %d\n", len(r.latencies), r.elapsed, r.rps(), workers)
+	codes := make([]int, 0, len(r.statuses))
+	for code := range r.statuses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	parts := make([]string, 0, len(codes)+1)
+	for _, code := range codes {
+		parts = append(parts, fmt.Sprintf("%d: %d", code, r.statuses[code]))
+	}
+	if r.errors > 0 {
+		parts = append(parts, fmt.Sprintf("errors: %d", r.errors))
+	}
+	fmt.Fprintf(w, "responses:   %s\n", strings.Join(parts, ", "))
+	fmt.Fprintf(w, "latency:     p50 %v, p90 %v, p99 %v, p99.9 %v, max %v\n",
+		r.percentile(50), r.percentile(90), r.percentile(99), r.percentile(99.9), r.percentile(100))
+}
+
+func main() {
+	flag.Parse()
+
+	workers := *concurrency
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	*concurrency = workers
+
+	var idx redirect.Index
+	if *indexPath != "" {
+		var err error
+		idx, err = redirect.IndexFromProto(*indexPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Loaded %d index entries from %q", len(idx.Entries), *indexPath)
+	} else {
+		idx = syntheticIndex(*syntheticManpages, rand.New(rand.NewSource(*seed)))
+		log.Printf("Generated synthetic index with %d entries", len(idx.Entries))
+	}
+
+	var do doer
+	if *targetURL != "" {
+		do = remote(*targetURL)
+	} else {
+		do = inProcess(idx)
+		// The redirect package logs every lookup.
+		log.SetOutput(ioutil.Discard)
+	}
+
+	res := run(do, newPathGenerator(idx), workers, *requests, *duration, *seed, *missRatio)
+	log.SetOutput(os.Stderr)
+	res.print(os.Stdout, workers)
+
+	if *minRPS > 0 && res.rps() < *minRPS {
+		log.Fatalf("throughput of %.0f req/s is below -min_rps=%.0f", res.rps(), *minRPS)
+	}
+	if res.errors > 0 {
+		log.Fatalf("%d requests failed", res.errors)
+	}
+}
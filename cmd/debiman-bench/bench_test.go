@@ -0,0 +1,59 @@
+package main
+
+import (
+	"io/ioutil"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestPathGenerator(t *testing.T) {
+	idx := syntheticIndex(100, rand.New(rand.NewSource(1)))
+	g := newPathGenerator(idx)
+	generate := func() []string {
+		rnd := rand.New(rand.NewSource(1))
+		paths := make([]string, 100)
+		for i := range paths {
+			paths[i] = g.path(rnd, 0.1)
+		}
+		return paths
+	}
+	first, second := generate(), generate()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("paths differ for the same seed: %q vs. %q", first[i], second[i])
+		}
+	}
+}
+
+// TestSmoke is a smoke version of a benchmark run against the
+// in-process handler.
+func TestSmoke(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	idx := syntheticIndex(200, rand.New(rand.NewSource(1)))
+	res := run(inProcess(idx), newPathGenerator(idx), 4, 2000, time.Minute, 1, 0.1)
+	if got, want := len(res.latencies), 2000; got != want {
+		t.Fatalf("unexpected number of requests: got %d, want %d", got, want)
+	}
+	if res.errors > 0 {
+		t.Fatalf("%d requests failed", res.errors)
+	}
+	if res.statuses[http.StatusTemporaryRedirect] == 0 || res.statuses[http.StatusNotFound] == 0 {
+		t.Fatalf("expected both redirects and misses, got %v", res.statuses)
+	}
+	for status := range res.statuses {
+		switch status {
+		case http.StatusTemporaryRedirect, http.StatusNotFound:
+		default:
+			t.Errorf("unexpected HTTP status %d (%d times)", status, res.statuses[status])
+		}
+	}
+	if res.percentile(50) > res.percentile(100) {
+		t.Errorf("p50 %v > max %v", res.percentile(50), res.percentile(100))
+	}
+}
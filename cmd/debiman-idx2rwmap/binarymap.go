@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/Debian/debiman/internal/redirect"
+)
+
+// sortedLines returns the rewrite map lines of bufs (as printed by
+// printAll and printOverrides), sorted by key.
+func sortedLines(bufs []*bytes.Buffer) [][]byte {
+	var lines [][]byte
+	for _, buf := range bufs {
+		b := buf.Bytes()
+		for idx := bytes.IndexByte(b, '\n'); idx != -1; idx = bytes.IndexByte(b, '\n') {
+			lines = append(lines, b[:idx])
+			b = b[idx+1:]
+		}
+	}
+	key := func(line []byte) []byte {
+		if idx := bytes.IndexByte(line, ' '); idx != -1 {
+			return line[:idx]
+		}
+		return line
+	}
+	sort.Slice(lines, func(i, j int) bool { return bytes.Compare(key(lines[i]), key(lines[j])) < 0 })
+	return lines
+}
+
+// writeBinaryMap writes lines (“key target”, sorted, see sortedLines)
+// to w in the format of redirect.BinaryMap.
+func writeBinaryMap(w io.Writer, lines [][]byte) error {
+	var (
+		keys          bytes.Buffer
+		targets       bytes.Buffer
+		keyOffsets    = make([]uint32, 0, len(lines)+1)
+		keyTargets    = make([]uint32, 0, len(lines))
+		targetOffsets = []uint32{0}
+		targetIdx     = make(map[string]uint32)
+		prev          []byte
+	)
+	for _, line := range lines {
+		idx := bytes.IndexByte(line, ' ')
+		if idx == -1 {
+			return fmt.Errorf("malformed line %q", line)
+		}
+		key, target := line[:idx], line[idx+1:]
+		if prev != nil && bytes.Equal(key, prev) {
+			return fmt.Errorf("duplicate key %q", key)
+		}
+		prev = key
+		keyOffsets = append(keyOffsets, uint32(keys.Len()))
+		keys.Write(key)
+		ti, ok := targetIdx[string(target)]
+		if !ok {
+			ti = uint32(len(targetOffsets) - 1)
+			targetIdx[string(target)] = ti
+			targets.Write(target)
+			targetOffsets = append(targetOffsets, uint32(targets.Len()))
+		}
+		keyTargets = append(keyTargets, ti)
+	}
+	keyOffsets = append(keyOffsets, uint32(keys.Len()))
+	if keys.Len() > math.MaxUint32 || targets.Len() > math.MaxUint32 {
+		return fmt.Errorf("rewrite map too large for the binary format (%d bytes of keys, %d bytes of targets)", keys.Len(), targets.Len())
+	}
+
+	bufw := bufio.NewWriter(w)
+	if _, err := bufw.WriteString(redirect.BinaryMapMagic); err != nil {
+		return err
+	}
+	header := []uint32{
+		redirect.BinaryMapVersion,
+		uint32(len(keyTargets)),
+		uint32(len(targetOffsets) - 1),
+		uint32(keys.Len()),
+		uint32(targets.Len()),
+		0, // reserved
+	}
+	for _, s := range [][]uint32{header, keyOffsets, keyTargets, targetOffsets} {
+		if err := binary.Write(bufw, binary.LittleEndian, s); err != nil {
+			return err
+		}
+	}
+	if _, err := keys.WriteTo(bufw); err != nil {
+		return err
+	}
+	if _, err := targets.WriteTo(bufw); err != nil {
+		return err
+	}
+	return bufw.Flush()
+}
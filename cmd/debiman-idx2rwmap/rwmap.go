@@ -1,10 +1,19 @@
 // idx2rwmap converts an auxserver index into a file that can be used
 // as an Apache RewriteMap. The resulting file contains all possible
 // URLs under which manpages can be reached. For a 30MB auxserver
-// index, the resulting rwmap is 1.6GB.
+// index, the resulting rwmap is 1.6GB. -package_keys=ambiguous shrinks
+// it by leaving out /<binarypkg>/<name> keys which do not disambiguate.
+//
+// Keys including the suite (/<suite>/…, 12 per manpage variant and
+// suite name) are emitted for every name of the suite, so they make up
+// most of the map: for a suite reachable as e.g. stretch, stable and
+// latest, -suite_alias_keys=codenames leaves out two thirds of them,
+// and -suite_alias_keys=none all but the keys of the suite itself
+// (which is also the suite of the canonical URL).
 //
 // The -concurrency option determines how many shards are created in
-// -output_dir. To sort and combine the individual shards, use:
+// -output_dir (plus output.overrides with -overrides). To sort and
+// combine the individual shards, use:
 //
 //    LC_ALL=C sort output.* > /srv/man/rwmap.txt
 //
@@ -12,12 +21,18 @@
 // can quickly look up keys:
 //
 //    httxt2dbm -i /srv/man/rwmap.txt -o /srv/man/rwmap.dbm
+//
+// With -format=binary, a single, sorted file (rwmap.bin in -output_dir,
+// including the overrides) in the format of redirect.BinaryMap is
+// written instead, which debiman-auxserver -rwmap can query directly.
 package main
 
 import (
 	"bufio"
+	"bytes"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
@@ -28,6 +43,8 @@ import (
 	"sync"
 
 	"github.com/Debian/debiman/internal/redirect"
+	"github.com/Debian/debiman/internal/releases"
+	"github.com/Debian/debiman/internal/write"
 )
 
 var (
@@ -42,10 +59,73 @@ var (
 	outputDir = flag.String("output_dir",
 		"",
 		"Directory in which to store the output.n (with n = 0 to -concurrency) files. Defaults to the working directory")
+
+	packageKeys = flag.String("package_keys",
+		"all",
+		"Which keys starting with a binary package name (/<binarypkg>/<name>…) to emit. One of “all” or “ambiguous” (only for manpages shipped by more than one binary package, where the binary package disambiguates). “ambiguous” results in a smaller map without keys like /cron/cron; keys including the suite (/<suite>/<binarypkg>/<name>…) are always emitted")
+
+	overridesPath = flag.String("overrides",
+		"",
+		"If non-empty, path to a file of “from to” URL path pairs, like debiman-auxserver’s -overrides flag. The from paths map to the manpages their to paths resolve to (in output.overrides), replacing the computed keys. Overrides whose to path does not resolve are logged and ignored")
+
+	format = flag.String("format",
+		"text",
+		"Output format. One of “text” (output.n shards of “key target” lines, to be sorted and converted with httxt2dbm) or “binary” (a single rwmap.bin in -output_dir, see redirect.BinaryMap, which debiman-auxserver -rwmap can query directly). The binary format is built in memory, which requires memory in the order of the size of the text rewrite map")
+
+	suitesFlag = flag.String("suites",
+		"",
+		"If non-empty, only emit keys for these suites of the index: a comma-separated list of suites, codenames or aliases (e.g. stretch,buster,unstable) or “latest:N” for the N newest suites, like debiman’s -suites flag")
+
+	suiteAliasKeys = flag.String("suite_alias_keys",
+		"all",
+		"For which names of a suite to emit keys starting with the suite (/<suite>/<name>…). One of “all” (codenames and rolling names like stable, testing or latest), “codenames” (e.g. stretch and sid, but not stable, unstable or stable-backports) or “none” (only the suite itself, i.e. the suite of the canonical URL, which is always emitted). Each name adds 12 keys per manpage variant, so leaving out names bounds the size of the map; requests for the names left out are then not answered from the map")
 )
 
+// rollingSuites are the suite names which refer to a different codename
+// over time (see debiman’s -latest_suite for “latest”).
+var rollingSuites = map[string]bool{
+	"oldoldstable": true,
+	"oldstable":    true,
+	"stable":       true,
+	"testing":      true,
+	"unstable":     true,
+	"experimental": true,
+	"latest":       true,
+}
+
+// isRolling returns whether the suite name (e.g. “stable” or
+// “stable-backports”) is a rolling name rather than a codename.
+func isRolling(name string) bool {
+	if idx := strings.IndexByte(name, '-'); idx > -1 {
+		name = name[:idx]
+	}
+	return rollingSuites[name]
+}
+
+// keyedAliases returns the names of aliases (see suiteAliases) for
+// which printAll emits keys, as selected by class (see
+// -suite_alias_keys). The suite itself is emitted by printAll in any
+// case.
+func keyedAliases(aliases map[string][]string, class string) map[string][]string {
+	if class == "all" {
+		return aliases
+	}
+	keyed := make(map[string][]string, len(aliases))
+	for suite, names := range aliases {
+		var kept []string
+		for _, name := range names {
+			if name == suite || (class == "codenames" && !isRolling(name)) {
+				kept = append(kept, name)
+			}
+		}
+		keyed[suite] = kept
+	}
+	return keyed
+}
+
 type oncePrinter struct {
 	printed  map[string]bool
+	skip     map[string]string // keys of overrides, see printOverrides
 	w        *bufio.Writer
 	idx      redirect.Index
 	variants []redirect.IndexEntry
@@ -55,6 +135,9 @@ func (op oncePrinter) mustPrint(key string, template redirect.IndexEntry) {
 	if op.printed[key] {
 		return
 	}
+	if _, ok := op.skip[key]; ok {
+		return
+	}
 	filtered := op.idx.Narrow("", template, redirect.IndexEntry{}, op.variants)
 	if _, err := op.w.WriteString(key); err != nil {
 		log.Fatal(err)
@@ -71,190 +154,342 @@ func (op oncePrinter) mustPrint(key string, template redirect.IndexEntry) {
 	op.printed[key] = true
 }
 
-type byServingPath []redirect.IndexEntry
-
-func (p byServingPath) Len() int {
-	return len(p)
-}
-
-func (p byServingPath) Swap(i, j int) {
-	p[i], p[j] = p[j], p[i]
+// suiteAliases maps each suite to the (sorted) names which redirect
+// to it in idx.Suites, e.g. map[stretch:[stable stretch]].
+func suiteAliases(idx redirect.Index) map[string][]string {
+	aliases := make(map[string][]string)
+	for name, rewrite := range idx.Suites {
+		aliases[rewrite] = append(aliases[rewrite], name)
+	}
+	for _, names := range aliases {
+		sort.Strings(names)
+	}
+	return aliases
 }
 
-func (p byServingPath) Less(i, j int) bool {
-	return p[i].ServingPath(".html") < p[j].ServingPath(".html")
+// restrictSuites returns a copy of idx which only contains the entries
+// and suite aliases of the suites selected by sel.
+func restrictSuites(idx redirect.Index, sel *releases.Selection) redirect.Index {
+	selected := sel.Select(suiteAliases(idx))
+	restricted := idx // copy
+	restricted.Suites = make(map[string]string, len(idx.Suites))
+	for name, suite := range idx.Suites {
+		if selected[suite] {
+			restricted.Suites[name] = suite
+		}
+	}
+	restricted.Entries = make(map[string][]redirect.IndexEntry, len(idx.Entries))
+	for name, entries := range idx.Entries {
+		var kept []redirect.IndexEntry
+		for _, e := range entries {
+			if selected[e.Suite] {
+				kept = append(kept, e)
+			}
+		}
+		if len(kept) > 0 {
+			restricted.Entries[name] = kept
+		}
+	}
+	return restricted
 }
 
-func printAll(bufw *bufio.Writer, idx redirect.Index, name string) {
-	variants := idx.Entries[name]
-
+// printAll prints the rewrite map entries for variants, all entries of
+// one manpage name in the order of redirect.Index.ForEach. aliases are
+// the names of each suite to emit keys for, i.e. suiteAliases(idx)
+// restricted by keyedAliases.
+func printAll(bufw *bufio.Writer, idx redirect.Index, aliases map[string][]string, variants []redirect.IndexEntry) {
 	op := oncePrinter{
 		printed:  make(map[string]bool),
+		skip:     idx.Overrides,
 		w:        bufw,
 		idx:      idx,
 		variants: variants,
 	}
 
-	// sort to make the output deterministic
-	sort.Stable(byServingPath(variants))
-
-	for _, v := range variants {
-		suites := []string{v.Suite}
-		for name, rewrite := range idx.Suites {
-			if rewrite == v.Suite {
-				suites = append(suites, name)
+	pkgKeys := *packageKeys == "all"
+	if !pkgKeys {
+		for _, v := range variants[1:] {
+			if v.Binarypkg != variants[0].Binarypkg {
+				pkgKeys = true
+				break
 			}
 		}
+	}
 
-		lcName := strings.ToLower(v.Name)
+	for _, v := range variants {
+		suites := append([]string{v.Suite}, aliases[v.Suite]...)
+
+		nameKey := idx.URLCase.Name(v.Name)
 
-		// case 01
-		op.mustPrint(fmt.Sprintf("/%s", lcName),
+		// case 01: resolves to the section preferred by
+		// idx.SectionPriority (see debiman -section_priority)
+		op.mustPrint(fmt.Sprintf("/%s", nameKey),
 			redirect.IndexEntry{})
 
 		// case 02
-		op.mustPrint(fmt.Sprintf("/%s.%s", lcName, v.Language),
+		op.mustPrint(fmt.Sprintf("/%s.%s", nameKey, v.Language),
 			redirect.IndexEntry{Language: v.Language})
 
 		// case 03
-		op.mustPrint(fmt.Sprintf("/%s.%s", lcName, v.Section),
+		op.mustPrint(fmt.Sprintf("/%s.%s", nameKey, v.Section),
 			redirect.IndexEntry{Section: v.Section})
 
 		// case 03
-		op.mustPrint(fmt.Sprintf("/%s.%s", lcName, v.Section[:1]),
+		op.mustPrint(fmt.Sprintf("/%s.%s", nameKey, v.Section[:1]),
 			redirect.IndexEntry{Section: v.Section[:1]})
 
 		// FreeBSD-style case 03
-		op.mustPrint(fmt.Sprintf("/%s/%s", lcName, v.Section),
+		op.mustPrint(fmt.Sprintf("/%s/%s", nameKey, v.Section),
 			redirect.IndexEntry{Section: v.Section})
 
 		// FreeBSD-style case 03
-		op.mustPrint(fmt.Sprintf("/%s/%s", lcName, v.Section[:1]),
+		op.mustPrint(fmt.Sprintf("/%s/%s", nameKey, v.Section[:1]),
 			redirect.IndexEntry{Section: v.Section[:1]})
 
 		// case 04
-		op.mustPrint(fmt.Sprintf("/%s.%s.%s", lcName, v.Section, v.Language),
+		op.mustPrint(fmt.Sprintf("/%s.%s.%s", nameKey, v.Section, v.Language),
 			redirect.IndexEntry{Language: v.Language, Section: v.Section})
 
 		// case 04
-		op.mustPrint(fmt.Sprintf("/%s.%s.%s", lcName, v.Section[:1], v.Language),
+		op.mustPrint(fmt.Sprintf("/%s.%s.%s", nameKey, v.Section[:1], v.Language),
 			redirect.IndexEntry{Language: v.Language, Section: v.Section[:1]})
 
-		// case 05
-		op.mustPrint(fmt.Sprintf("/%s/%s", v.Binarypkg, lcName),
-			redirect.IndexEntry{Binarypkg: v.Binarypkg})
+		if pkgKeys {
+			// case 05
+			op.mustPrint(fmt.Sprintf("/%s/%s", v.Binarypkg, nameKey),
+				redirect.IndexEntry{Binarypkg: v.Binarypkg})
 
-		// case 06
-		op.mustPrint(fmt.Sprintf("/%s/%s.%s", v.Binarypkg, lcName, v.Language),
-			redirect.IndexEntry{Language: v.Language, Binarypkg: v.Binarypkg})
+			// case 06
+			op.mustPrint(fmt.Sprintf("/%s/%s.%s", v.Binarypkg, nameKey, v.Language),
+				redirect.IndexEntry{Language: v.Language, Binarypkg: v.Binarypkg})
 
-		// case 07
-		op.mustPrint(fmt.Sprintf("/%s/%s.%s", v.Binarypkg, lcName, v.Section),
-			redirect.IndexEntry{Binarypkg: v.Binarypkg, Section: v.Section})
+			// case 07
+			op.mustPrint(fmt.Sprintf("/%s/%s.%s", v.Binarypkg, nameKey, v.Section),
+				redirect.IndexEntry{Binarypkg: v.Binarypkg, Section: v.Section})
 
-		// case 07
-		op.mustPrint(fmt.Sprintf("/%s/%s.%s", v.Binarypkg, lcName, v.Section[:1]),
-			redirect.IndexEntry{Binarypkg: v.Binarypkg, Section: v.Section[:1]})
+			// case 07
+			op.mustPrint(fmt.Sprintf("/%s/%s.%s", v.Binarypkg, nameKey, v.Section[:1]),
+				redirect.IndexEntry{Binarypkg: v.Binarypkg, Section: v.Section[:1]})
 
-		// case 08
-		op.mustPrint(fmt.Sprintf("/%s/%s.%s.%s", v.Binarypkg, lcName, v.Section, v.Language),
-			redirect.IndexEntry{Language: v.Language, Section: v.Section, Binarypkg: v.Binarypkg})
+			// case 08
+			op.mustPrint(fmt.Sprintf("/%s/%s.%s.%s", v.Binarypkg, nameKey, v.Section, v.Language),
+				redirect.IndexEntry{Language: v.Language, Section: v.Section, Binarypkg: v.Binarypkg})
 
-		// case 08
-		op.mustPrint(fmt.Sprintf("/%s/%s.%s.%s", v.Binarypkg, lcName, v.Section[:1], v.Language),
-			redirect.IndexEntry{Language: v.Language, Section: v.Section[:1], Binarypkg: v.Binarypkg})
+			// case 08
+			op.mustPrint(fmt.Sprintf("/%s/%s.%s.%s", v.Binarypkg, nameKey, v.Section[:1], v.Language),
+				redirect.IndexEntry{Language: v.Language, Section: v.Section[:1], Binarypkg: v.Binarypkg})
+		}
 
 		for _, suite := range suites {
 			// case 09
-			op.mustPrint(fmt.Sprintf("/%s/%s", suite, lcName),
+			op.mustPrint(fmt.Sprintf("/%s/%s", suite, nameKey),
 				redirect.IndexEntry{Suite: v.Suite})
 
 			// case 10
-			op.mustPrint(fmt.Sprintf("/%s/%s.%s", suite, lcName, v.Language),
+			op.mustPrint(fmt.Sprintf("/%s/%s.%s", suite, nameKey, v.Language),
 				redirect.IndexEntry{Language: v.Language, Suite: v.Suite})
 
 			// case 11
-			op.mustPrint(fmt.Sprintf("/%s/%s.%s", suite, lcName, v.Section),
+			op.mustPrint(fmt.Sprintf("/%s/%s.%s", suite, nameKey, v.Section),
 				redirect.IndexEntry{Section: v.Section, Suite: v.Suite})
 
 			// case 11
-			op.mustPrint(fmt.Sprintf("/%s/%s.%s", suite, lcName, v.Section[:1]),
+			op.mustPrint(fmt.Sprintf("/%s/%s.%s", suite, nameKey, v.Section[:1]),
 				redirect.IndexEntry{Section: v.Section, Suite: v.Suite})
 
 			// case 12
-			op.mustPrint(fmt.Sprintf("/%s/%s.%s.%s", suite, lcName, v.Section, v.Language),
+			op.mustPrint(fmt.Sprintf("/%s/%s.%s.%s", suite, nameKey, v.Section, v.Language),
 				redirect.IndexEntry{Language: v.Language, Section: v.Section, Suite: v.Suite})
 
 			// case 12
-			op.mustPrint(fmt.Sprintf("/%s/%s.%s.%s", suite, lcName, v.Section[:1], v.Language),
+			op.mustPrint(fmt.Sprintf("/%s/%s.%s.%s", suite, nameKey, v.Section[:1], v.Language),
 				redirect.IndexEntry{Language: v.Language, Section: v.Section[:1], Suite: v.Suite})
 
 			// case 13
-			op.mustPrint(fmt.Sprintf("/%s/%s/%s", suite, v.Binarypkg, lcName),
+			op.mustPrint(fmt.Sprintf("/%s/%s/%s", suite, v.Binarypkg, nameKey),
 				redirect.IndexEntry{Binarypkg: v.Binarypkg, Suite: v.Suite})
 
 			// case 14
-			op.mustPrint(fmt.Sprintf("/%s/%s/%s.%s", suite, v.Binarypkg, lcName, v.Language),
+			op.mustPrint(fmt.Sprintf("/%s/%s/%s.%s", suite, v.Binarypkg, nameKey, v.Language),
 				redirect.IndexEntry{Language: v.Language, Binarypkg: v.Binarypkg, Suite: v.Suite})
 
 			// case 15
-			op.mustPrint(fmt.Sprintf("/%s/%s/%s.%s", suite, v.Binarypkg, lcName, v.Section),
+			op.mustPrint(fmt.Sprintf("/%s/%s/%s.%s", suite, v.Binarypkg, nameKey, v.Section),
 				redirect.IndexEntry{Section: v.Section, Binarypkg: v.Binarypkg, Suite: v.Suite})
 
 			// case 15
-			op.mustPrint(fmt.Sprintf("/%s/%s/%s.%s", suite, v.Binarypkg, lcName, v.Section[:1]),
+			op.mustPrint(fmt.Sprintf("/%s/%s/%s.%s", suite, v.Binarypkg, nameKey, v.Section[:1]),
 				redirect.IndexEntry{Section: v.Section[:1], Binarypkg: v.Binarypkg, Suite: v.Suite})
 
 			// case 16
-			op.mustPrint(fmt.Sprintf("/%s/%s/%s.%s.%s", suite, v.Binarypkg, lcName, v.Section, v.Language),
+			op.mustPrint(fmt.Sprintf("/%s/%s/%s.%s.%s", suite, v.Binarypkg, nameKey, v.Section, v.Language),
 				redirect.IndexEntry{Language: v.Language, Binarypkg: v.Binarypkg, Section: v.Section, Suite: v.Suite})
 
 			// case 16
-			op.mustPrint(fmt.Sprintf("/%s/%s/%s.%s.%s", suite, v.Binarypkg, lcName, v.Section[:1], v.Language),
+			op.mustPrint(fmt.Sprintf("/%s/%s/%s.%s.%s", suite, v.Binarypkg, nameKey, v.Section[:1], v.Language),
 				redirect.IndexEntry{Language: v.Language, Binarypkg: v.Binarypkg, Section: v.Section[:1], Suite: v.Suite})
 		}
 	}
 }
 
+// printOverrides prints the rewrite map entries for idx.Overrides,
+// sorted by key.
+func printOverrides(bufw *bufio.Writer, idx redirect.Index) error {
+	froms := make([]string, 0, len(idx.Overrides))
+	for from := range idx.Overrides {
+		froms = append(froms, from)
+	}
+	sort.Strings(froms)
+	for _, from := range froms {
+		to, err := idx.ResolveOverride(idx.Overrides[from])
+		if err != nil {
+			return fmt.Errorf("override %q: %v", from, err)
+		}
+		if _, err := fmt.Fprintf(bufw, "%s %s\n", from, to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func main() {
 	flag.Parse()
 
+	if *packageKeys != "all" && *packageKeys != "ambiguous" {
+		log.Fatalf("invalid -package_keys=%q: expected one of all, ambiguous", *packageKeys)
+	}
+	if *suiteAliasKeys != "all" && *suiteAliasKeys != "codenames" && *suiteAliasKeys != "none" {
+		log.Fatalf("invalid -suite_alias_keys=%q: expected one of all, codenames, none", *suiteAliasKeys)
+	}
+	if *format != "text" && *format != "binary" {
+		log.Fatalf("invalid -format=%q: expected one of text, binary", *format)
+	}
+	binaryFormat := *format == "binary"
+	// bufs collects the output in memory with -format=binary.
+	var bufs []*bytes.Buffer
+
+	sel, err := releases.ParseSelection(*suitesFlag)
+	if err != nil {
+		log.Fatalf("invalid -suites: %v", err)
+	}
+
 	idx, err := redirect.IndexFromProto(*indexPath)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	log.Printf("Loaded %d index entries from %q", len(idx.Entries), *indexPath)
+	log.Printf("Loaded %d index entries from %q (URL case policy %v, section priority %q)", len(idx.Entries), *indexPath, idx.URLCase, idx.SectionPriority)
 
-	work := make(chan string)
+	if sel != nil {
+		idx = restrictSuites(idx, sel)
+		log.Printf("Restricted to %d index entries of -suites=%v", len(idx.Entries), sel)
+	}
+
+	if *overridesPath != "" {
+		overrides, broken, err := idx.LoadOverrides(*overridesPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, err := range broken {
+			log.Printf("Ignoring broken override in %q: %v", *overridesPath, err)
+		}
+		idx.Overrides = overrides
+		log.Printf("Loaded %d overrides from %q", len(overrides), *overridesPath)
+
+		if binaryFormat {
+			var buf bytes.Buffer
+			bufw := bufio.NewWriter(&buf)
+			if err := printOverrides(bufw, idx); err != nil {
+				log.Fatal(err)
+			}
+			if err := bufw.Flush(); err != nil {
+				log.Fatal(err)
+			}
+			bufs = append(bufs, &buf)
+		}
+	}
+
+	if *overridesPath != "" && !binaryFormat {
+		f, err := os.Create(filepath.Join(*outputDir, "output.overrides"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		bufw := bufio.NewWriter(f)
+		if err := printOverrides(bufw, idx); err != nil {
+			log.Fatal(err)
+		}
+		if err := bufw.Flush(); err != nil {
+			log.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	aliases := keyedAliases(suiteAliases(idx), *suiteAliasKeys)
+	work := make(chan []redirect.IndexEntry)
 	var wg sync.WaitGroup
 	workers := *concurrency
 	if workers <= 0 {
 		workers = runtime.NumCPU()
 	}
 	for i := 0; i < workers; i++ {
-		wg.Add(1)
-		go func(i int) {
-			defer wg.Done()
+		var w io.Writer
+		if binaryFormat {
+			buf := new(bytes.Buffer)
+			bufs = append(bufs, buf)
+			w = buf
+		} else {
 			f, err := os.Create(filepath.Join(*outputDir, "output."+strconv.Itoa(i)))
 			if err != nil {
 				log.Fatal(err)
 			}
 			defer f.Close()
-			bufw := bufio.NewWriter(f)
-			for name := range work {
-				printAll(bufw, idx, name)
+			w = f
+		}
+		wg.Add(1)
+		go func(w io.Writer) {
+			defer wg.Done()
+			bufw := bufio.NewWriter(w)
+			for variants := range work {
+				printAll(bufw, idx, aliases, variants)
 			}
 			if err := bufw.Flush(); err != nil {
 				log.Fatal(err)
 			}
-		}(i)
+		}(w)
 	}
 
-	for name, _ := range idx.Entries {
-		work <- name
+	var (
+		last     string
+		variants []redirect.IndexEntry
+	)
+	if err := idx.ForEach(func(name string, e redirect.IndexEntry) error {
+		if name != last && len(variants) > 0 {
+			work <- variants
+			variants = nil
+		}
+		last = name
+		variants = append(variants, e)
+		return nil
+	}); err != nil {
+		log.Fatal(err)
+	}
+	if len(variants) > 0 {
+		work <- variants
 	}
 	close(work)
 
 	wg.Wait()
+
+	if binaryFormat {
+		lines := sortedLines(bufs)
+		path := filepath.Join(*outputDir, "rwmap.bin")
+		if err := write.Atomically(path, false, func(w io.Writer) error {
+			return writeBinaryMap(w, lines)
+		}); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Wrote %d keys to %q", len(lines), path)
+	}
 }
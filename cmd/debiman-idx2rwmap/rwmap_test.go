@@ -0,0 +1,378 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Debian/debiman/internal/redirect"
+	"github.com/Debian/debiman/internal/releases"
+)
+
+// variantsOf returns the entries of name, as main passes them to
+// printAll.
+func variantsOf(idx redirect.Index, name string) []redirect.IndexEntry {
+	var variants []redirect.IndexEntry
+	idx.ForEach(func(n string, e redirect.IndexEntry) error {
+		if n == name {
+			variants = append(variants, e)
+		}
+		return nil
+	})
+	return variants
+}
+
+func TestBareNameSectionPriority(t *testing.T) {
+	idx := redirect.Index{
+		Entries: map[string][]redirect.IndexEntry{
+			"crontab": []redirect.IndexEntry{
+				{Name: "crontab", Suite: "jessie", Binarypkg: "systemd-cron", Section: "8", Language: "en"},
+				{Name: "crontab", Suite: "jessie", Binarypkg: "cron", Section: "5", Language: "en"},
+				{Name: "crontab", Suite: "jessie", Binarypkg: "cron", Section: "1", Language: "en"},
+			},
+		},
+		Suites:   map[string]string{"jessie": "jessie"},
+		Langs:    map[string]bool{"en": true},
+		Sections: map[string]bool{"1": true, "5": true, "8": true},
+	}
+
+	for _, entry := range []struct {
+		priority []string
+		want     string
+	}{
+		{nil, "/jessie/cron/crontab.1.en.html"},
+		{[]string{"8", "5"}, "/jessie/systemd-cron/crontab.8.en.html"},
+		{[]string{"5"}, "/jessie/cron/crontab.5.en.html"},
+		{[]string{"7", "8"}, "/jessie/systemd-cron/crontab.8.en.html"},
+	} {
+		idx.SectionPriority = entry.priority
+		var buf bytes.Buffer
+		bufw := bufio.NewWriter(&buf)
+		printAll(bufw, idx, suiteAliases(idx), variantsOf(idx, "crontab"))
+		if err := bufw.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		var got string
+		for _, line := range strings.Split(buf.String(), "\n") {
+			if strings.HasPrefix(line, "/crontab ") {
+				got = strings.TrimPrefix(line, "/crontab ")
+			}
+		}
+		if got != entry.want {
+			t.Errorf("section priority %q: /crontab resolves to %q, want %q", entry.priority, got, entry.want)
+		}
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	idx := redirect.Index{
+		Entries: map[string][]redirect.IndexEntry{
+			"crontab": []redirect.IndexEntry{
+				{Name: "crontab", Suite: "jessie", Binarypkg: "systemd-cron", Section: "8", Language: "en"},
+				{Name: "crontab", Suite: "jessie", Binarypkg: "cron", Section: "1", Language: "en"},
+			},
+		},
+		Suites:   map[string]string{"jessie": "jessie"},
+		Langs:    map[string]bool{"en": true},
+		Sections: map[string]bool{"1": true, "8": true},
+		Overrides: map[string]string{
+			"/crontab":        "/jessie/systemd-cron/crontab.8",
+			"/legacy/crontab": "/crontab.1",
+		},
+	}
+
+	var buf bytes.Buffer
+	bufw := bufio.NewWriter(&buf)
+	printAll(bufw, idx, suiteAliases(idx), variantsOf(idx, "crontab"))
+	if err := bufw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.HasPrefix(line, "/crontab ") {
+			t.Errorf("overridden key unexpectedly emitted: %q", line)
+		}
+	}
+
+	buf.Reset()
+	if err := printOverrides(bufw, idx); err != nil {
+		t.Fatal(err)
+	}
+	if err := bufw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	want := "/crontab /jessie/systemd-cron/crontab.8.en.html\n" +
+		"/legacy/crontab /jessie/cron/crontab.1.en.html\n"
+	if got := buf.String(); got != want {
+		t.Errorf("printOverrides: got %q, want %q", got, want)
+	}
+}
+
+func TestPackageKeys(t *testing.T) {
+	idx := redirect.Index{
+		Entries: map[string][]redirect.IndexEntry{
+			// cron(8) is only shipped by the binary package cron,
+			// so /cron/cron does not disambiguate anything.
+			"cron": []redirect.IndexEntry{
+				{Name: "cron", Suite: "jessie", Binarypkg: "cron", Section: "8", Language: "en"},
+			},
+			"crontab": []redirect.IndexEntry{
+				{Name: "crontab", Suite: "jessie", Binarypkg: "systemd-cron", Section: "5", Language: "en"},
+				{Name: "crontab", Suite: "jessie", Binarypkg: "cron", Section: "5", Language: "en"},
+			},
+		},
+		Suites:   map[string]string{"jessie": "jessie"},
+		Langs:    map[string]bool{"en": true},
+		Sections: map[string]bool{"5": true, "8": true},
+	}
+
+	keys := func(policy, name string) map[string]bool {
+		old := *packageKeys
+		defer func() { *packageKeys = old }()
+		*packageKeys = policy
+		var buf bytes.Buffer
+		bufw := bufio.NewWriter(&buf)
+		printAll(bufw, idx, suiteAliases(idx), variantsOf(idx, name))
+		if err := bufw.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		result := make(map[string]bool)
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			result[strings.Fields(line)[0]] = true
+		}
+		return result
+	}
+
+	for _, entry := range []struct {
+		policy, name, key string
+		want              bool
+	}{
+		{"all", "cron", "/cron/cron", true},
+		{"all", "cron", "/cron/cron.8.en", true},
+		{"ambiguous", "cron", "/cron/cron", false},
+		{"ambiguous", "cron", "/cron/cron.8.en", false},
+		// Keys without a binary package and keys including the
+		// suite are not affected.
+		{"ambiguous", "cron", "/cron", true},
+		{"ambiguous", "cron", "/jessie/cron/cron", true},
+		{"ambiguous", "crontab", "/cron/crontab", true},
+		{"ambiguous", "crontab", "/systemd-cron/crontab.5", true},
+	} {
+		if got := keys(entry.policy, entry.name)[entry.key]; got != entry.want {
+			t.Errorf("-package_keys=%s: key %q emitted = %v, want %v", entry.policy, entry.key, got, entry.want)
+		}
+	}
+	if all, ambiguous := len(keys("all", "cron")), len(keys("ambiguous", "cron")); ambiguous >= all {
+		t.Errorf("-package_keys=ambiguous does not result in fewer keys (%d) than -package_keys=all (%d)", ambiguous, all)
+	}
+}
+
+// benchIdx returns an index resembling manpages.debian.org’s in its
+// number of suite aliases: each suite is reachable via its codename,
+// suite name and a number of additional names.
+func benchIdx() redirect.Index {
+	idx := redirect.Index{
+		Entries:  make(map[string][]redirect.IndexEntry),
+		Suites:   make(map[string]string),
+		Langs:    map[string]bool{"en": true, "de": true},
+		Sections: map[string]bool{"1": true, "5": true},
+	}
+	var variants []redirect.IndexEntry
+	for s := 0; s < 10; s++ {
+		suite := "suite" + strconv.Itoa(s)
+		idx.Suites[suite] = suite
+		for a := 0; a < 10; a++ {
+			idx.Suites[suite+"-alias"+strconv.Itoa(a)] = suite
+		}
+		for _, lang := range []string{"en", "de"} {
+			for _, section := range []string{"1", "5"} {
+				variants = append(variants, redirect.IndexEntry{
+					Name:      "crontab",
+					Suite:     suite,
+					Binarypkg: "cron",
+					Section:   section,
+					Language:  lang,
+				})
+			}
+		}
+	}
+	idx.Entries["crontab"] = variants
+	return idx
+}
+
+func BenchmarkPrintAll(b *testing.B) {
+	idx := benchIdx()
+	aliases := suiteAliases(idx)
+	bufw := bufio.NewWriter(ioutil.Discard)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		printAll(bufw, idx, aliases, variantsOf(idx, "crontab"))
+	}
+}
+
+func TestSuiteAliases(t *testing.T) {
+	idx := redirect.Index{
+		Suites: map[string]string{
+			"stretch":  "stretch",
+			"stable":   "stretch",
+			"sid":      "sid",
+			"unstable": "sid",
+		},
+	}
+	got := suiteAliases(idx)
+	want := map[string][]string{
+		"stretch": {"stable", "stretch"},
+		"sid":     {"sid", "unstable"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("suiteAliases() = %v, want %v", got, want)
+	}
+}
+
+func TestRestrictSuites(t *testing.T) {
+	idx := redirect.Index{
+		Entries: map[string][]redirect.IndexEntry{
+			"crontab": []redirect.IndexEntry{
+				{Name: "crontab", Suite: "wheezy", Binarypkg: "cron", Section: "5", Language: "en"},
+				{Name: "crontab", Suite: "stretch", Binarypkg: "cron", Section: "5", Language: "en"},
+			},
+			"i3": []redirect.IndexEntry{
+				{Name: "i3", Suite: "wheezy", Binarypkg: "i3-wm", Section: "1", Language: "en"},
+			},
+		},
+		Suites: map[string]string{
+			"wheezy":       "wheezy",
+			"oldoldstable": "wheezy",
+			"stretch":      "stretch",
+			"stable":       "stretch",
+		},
+	}
+	sel, err := releases.ParseSelection("stable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	restricted := restrictSuites(idx, sel)
+	if want := map[string]string{"stretch": "stretch", "stable": "stretch"}; !reflect.DeepEqual(restricted.Suites, want) {
+		t.Errorf("unexpected suites: got %v, want %v", restricted.Suites, want)
+	}
+	if want := map[string][]redirect.IndexEntry{"crontab": idx.Entries["crontab"][1:]}; !reflect.DeepEqual(restricted.Entries, want) {
+		t.Errorf("unexpected entries: got %v, want %v", restricted.Entries, want)
+	}
+	if got := len(idx.Entries["crontab"]); got != 2 {
+		t.Errorf("restrictSuites modified idx: got %d crontab entries, want 2", got)
+	}
+}
+
+func TestBinaryMapRoundTrip(t *testing.T) {
+	idx := benchIdx()
+	idx.Entries["i3"] = []redirect.IndexEntry{
+		{Name: "i3", Suite: "suite0", Binarypkg: "i3-wm", Section: "1", Language: "en"},
+	}
+	aliases := suiteAliases(idx)
+	var bufs []*bytes.Buffer
+	for _, name := range []string{"crontab", "i3"} {
+		var buf bytes.Buffer
+		bufw := bufio.NewWriter(&buf)
+		printAll(bufw, idx, aliases, variantsOf(idx, name))
+		if err := bufw.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		bufs = append(bufs, &buf)
+	}
+	lines := sortedLines(bufs)
+
+	var bin bytes.Buffer
+	if err := writeBinaryMap(&bin, lines); err != nil {
+		t.Fatal(err)
+	}
+	m, err := redirect.ParseBinaryMap(bin.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := m.Len(), len(lines); got != want {
+		t.Errorf("unexpected number of keys: got %d, want %d", got, want)
+	}
+	// Every key of the text rewrite map resolves identically.
+	for _, line := range lines {
+		parts := strings.Split(string(line), " ")
+		if got, ok := m.Lookup(parts[0]); !ok || got != parts[1] {
+			t.Errorf("Lookup(%q) = %q, %v, want %q, true", parts[0], got, ok, parts[1])
+		}
+	}
+	for _, key := range []string{"", "/", "/crontab.9", "/i4", "/zzz"} {
+		if got, ok := m.Lookup(key); ok {
+			t.Errorf("Lookup(%q) = %q, want no match", key, got)
+		}
+	}
+	if got, want := bin.Len(), len(bytes.Join(lines, []byte("\n"))); got >= want {
+		t.Errorf("binary rewrite map unexpectedly not smaller than the text rewrite map: %d >= %d bytes", got, want)
+	}
+
+	if _, err := redirect.ParseBinaryMap(bin.Bytes()[:bin.Len()-1]); err == nil {
+		t.Errorf("ParseBinaryMap unexpectedly accepted a truncated map")
+	}
+	if err := writeBinaryMap(ioutil.Discard, [][]byte{[]byte("/i3 a"), []byte("/i3 b")}); err == nil {
+		t.Errorf("writeBinaryMap unexpectedly accepted duplicate keys")
+	}
+}
+
+func TestSuiteAliasKeys(t *testing.T) {
+	idx := redirect.NewIndex(map[string][]redirect.IndexEntry{
+		"crontab": []redirect.IndexEntry{
+			{Name: "crontab", Suite: "stretch", Binarypkg: "cron", Section: "5", Language: "en"},
+			{Name: "crontab", Suite: "unstable", Binarypkg: "cron", Section: "5", Language: "en"},
+		},
+	}, map[string]string{
+		"stable":           "stretch",
+		"latest":           "stretch",
+		"stretch":          "stretch",
+		"sid":              "unstable",
+		"unstable":         "unstable",
+		"stable-backports": "stretch",
+	})
+
+	keys := func(class string) map[string]bool {
+		var buf bytes.Buffer
+		bufw := bufio.NewWriter(&buf)
+		printAll(bufw, idx, keyedAliases(suiteAliases(idx), class), variantsOf(idx, "crontab"))
+		if err := bufw.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		keys := make(map[string]bool)
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			keys[strings.Split(line, " ")[0]] = true
+		}
+		return keys
+	}
+	all, codenames, none := keys("all"), keys("codenames"), keys("none")
+	if !(len(none) < len(codenames) && len(codenames) < len(all)) {
+		t.Errorf("unexpected number of keys: all=%d, codenames=%d, none=%d", len(all), len(codenames), len(none))
+	}
+	for _, entry := range []struct {
+		key                  string
+		all, codenames, none bool
+	}{
+		// The suites of the canonical URLs are always emitted.
+		{"/stretch/cron/crontab.5.en", true, true, true},
+		{"/unstable/cron/crontab.5.en", true, true, true},
+		{"/sid/cron/crontab.5.en", true, true, false},
+		{"/stable/cron/crontab.5.en", true, false, false},
+		{"/latest/crontab", true, false, false},
+		{"/stable-backports/crontab", true, false, false},
+		// Keys without a suite are not affected.
+		{"/cron/crontab.5", true, true, true},
+	} {
+		if got, want := all[entry.key], entry.all; got != want {
+			t.Errorf("-suite_alias_keys=all: %q emitted = %v, want %v", entry.key, got, want)
+		}
+		if got, want := codenames[entry.key], entry.codenames; got != want {
+			t.Errorf("-suite_alias_keys=codenames: %q emitted = %v, want %v", entry.key, got, want)
+		}
+		if got, want := none[entry.key], entry.none; got != want {
+			t.Errorf("-suite_alias_keys=none: %q emitted = %v, want %v", entry.key, got, want)
+		}
+	}
+}
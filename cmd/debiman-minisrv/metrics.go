@@ -0,0 +1,30 @@
+package main
+
+import (
+	"net/http"
+	"text/template"
+)
+
+const metricsTmplContent = `# HELP minisrv_file_cache_requests_total Number of files looked up in the -file_cache_bytes cache, by result.
+# TYPE minisrv_file_cache_requests_total counter
+minisrv_file_cache_requests_total{result="hit"} {{ .Hits }}
+minisrv_file_cache_requests_total{result="miss"} {{ .Misses }}
+
+# HELP minisrv_file_cache_bytes Size of the files currently cached.
+# TYPE minisrv_file_cache_bytes gauge
+minisrv_file_cache_bytes {{ .Bytes }}
+`
+
+var metricsTmpl = template.Must(template.New("metrics").Parse(metricsTmplContent))
+
+func serveMetrics(w http.ResponseWriter, r *http.Request) {
+	var data struct {
+		Hits, Misses uint64
+		Bytes        int64
+	}
+	data.Hits, data.Misses, data.Bytes = fileCache.Stats()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := metricsTmpl.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
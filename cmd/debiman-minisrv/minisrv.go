@@ -3,19 +3,22 @@
 package main
 
 import (
+	"bytes"
 	"compress/gzip"
 	"errors"
 	"flag"
+	"fmt"
 	"html/template"
 	"io"
+	"io/ioutil"
 	"log"
-	"mime"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/Debian/debiman/internal/aux"
+	"github.com/Debian/debiman/internal/blob"
 	"github.com/Debian/debiman/internal/bundled"
 	"github.com/Debian/debiman/internal/commontmpl"
 	"github.com/Debian/debiman/internal/redirect"
@@ -29,6 +32,26 @@ var (
 	listenAddr = flag.String("listen",
 		"localhost:8089",
 		"host:port on which to serve manpages")
+
+	contentSecurityPolicy = flag.String("content_security_policy",
+		aux.DefaultContentSecurityPolicy,
+		"Content-Security-Policy header to send with every response, or empty to send none. See “Security headers and content types” in README.md for what the default allows")
+
+	referrerPolicy = flag.String("referrer_policy",
+		aux.DefaultReferrerPolicy,
+		"Referrer-Policy header to send with every response, or empty to send none. See “Security headers and content types” in README.md")
+
+	contentTypes = flag.String("content_types",
+		"",
+		"Comma-separated list of extension=type pairs (e.g. “.txt=text/plain; charset=us-ascii”) overriding the Content-Type of served files. See “Security headers and content types” in README.md")
+
+	fileCacheBytes = flag.Int64("file_cache_bytes",
+		0,
+		"If positive, keep the contents of recently served files in memory, up to this many bytes in total, so that popular pages are not read from disk for every request. Cached files are revalidated using their size and modification time (and their content hash from the ETag manifest, if any), so files which debiman rewrites are not served stale. 0 disables the cache")
+
+	metricsListenAddr = flag.String("metrics_listen",
+		"",
+		"If non-empty, host:port address on which to serve metrics in the Prometheus text format at /metrics: the hits and misses of -file_cache_bytes")
 )
 
 // use go build -ldflags "-X main.debimanVersion=<version>" to set the version
@@ -36,13 +59,47 @@ var debimanVersion = "HEAD"
 
 var fileNotFound = errors.New("File not found")
 
+// etags is the ETag manifest of -serving_dir, if debiman wrote one
+// (see -etag_manifest).
+var etags *blob.ETagManifest
+
+// fileCache is nil unless -file_cache_bytes is positive.
+var fileCache *aux.FileCache
+
+// readFile returns the contents of f, whose content hash is hash (or
+// empty if unknown), using fileCache.
+func readFile(f *os.File, hash string) ([]byte, error) {
+	// The ETag manifest is only loaded at startup, so the file
+	// itself must be unchanged, too.
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	validator := fmt.Sprintf("%s %d %d", hash, fi.Size(), fi.ModTime().UnixNano())
+	if content, ok := fileCache.Get(f.Name(), validator); ok {
+		return content, nil
+	}
+	content, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	fileCache.Add(f.Name(), validator, content)
+	return content, nil
+}
+
 func serveFile(w http.ResponseWriter, r *http.Request) error {
 	compressed := false
 	path := filepath.Join(*servingDir, r.URL.Path)
 	if r.URL.Path == "/" {
 		path = filepath.Join(path, "index.html")
 	}
-	f, err := os.Open(path)
+	// Serve the best precompressed variant the client accepts, if
+	// any, and decompress the .gz file for clients which accept none.
+	variant, encoding := aux.PrecompressedVariant(path, r.Header.Get("Accept-Encoding"))
+	if variant == "" {
+		variant = path
+	}
+	f, err := os.Open(variant)
 	if err != nil {
 		if os.IsNotExist(err) {
 			// Try with .gz suffix
@@ -58,15 +115,33 @@ func serveFile(w http.ResponseWriter, r *http.Request) error {
 	}
 	defer f.Close()
 
-	ctype := mime.TypeByExtension(filepath.Ext(path))
-	if ctype == "" {
-		ctype = "text/html"
+	// The type of the file itself, not of its precompressed variant.
+	w.Header().Set("Content-Type", blob.ContentType(filepath.Base(path)))
+	w.Header().Add("Vary", "Accept-Encoding")
+	if encoding != "" {
+		w.Header().Set("Content-Encoding", encoding)
+	}
+	var hash string
+	if key, err := filepath.Rel(*servingDir, path); err == nil {
+		if h, ok := etags.Lookup(*servingDir, filepath.ToSlash(key)); ok {
+			hash = h
+			if aux.FileETag(w, r, key, hash, encoding) {
+				w.WriteHeader(http.StatusNotModified)
+				return nil
+			}
+		}
 	}
-	w.Header().Set("Content-Type", ctype)
 
 	rd := io.Reader(f)
+	if fileCache != nil {
+		content, err := readFile(f, hash)
+		if err != nil {
+			return err
+		}
+		rd = bytes.NewReader(content)
+	}
 	if compressed {
-		gzipr, err := gzip.NewReader(f)
+		gzipr, err := gzip.NewReader(rd)
 		if err != nil {
 			return err
 		}
@@ -81,16 +156,37 @@ func serveFile(w http.ResponseWriter, r *http.Request) error {
 func main() {
 	flag.Parse()
 
+	overrides, err := blob.ParseContentTypes(*contentTypes)
+	if err != nil {
+		log.Fatalf("parsing -content_types: %v", err)
+	}
+	blob.OverrideContentTypes(overrides)
+
 	idx, err := redirect.IndexFromProto(filepath.Join(*servingDir, "auxserver.idx"))
 	if err != nil {
 		log.Fatalf("Could not load auxserver index: %v", err)
 	}
 
+	etags, err = blob.ReadETagManifest(filepath.Join(*servingDir, blob.ETagManifestName))
+	if err != nil && !os.IsNotExist(err) {
+		log.Fatalf("Could not load ETag manifest: %v", err)
+	}
+
+	fileCache = aux.NewFileCache(*fileCacheBytes)
+	if *metricsListenAddr != "" {
+		go func() {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/metrics", serveMetrics)
+			log.Fatal(http.ListenAndServe(*metricsListenAddr, mux))
+		}()
+	}
+
 	commonTmpls := commontmpl.MustParseCommonTmpls()
 	notFoundTmpl := template.Must(commonTmpls.New("notfound").Parse(bundled.Asset("notfound.tmpl")))
 	server := aux.NewServer(idx, notFoundTmpl, debimanVersion)
 
 	http.HandleFunc("/jump", server.HandleJump)
+	http.HandleFunc("/random", server.HandleRandom)
 
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		// Similarly to http.ServeFile, deny requests containing .. as
@@ -118,5 +214,9 @@ func main() {
 	})
 
 	log.Printf("Serving manpages from %q on %q", *servingDir, *listenAddr)
-	log.Fatal(http.ListenAndServe(*listenAddr, nil))
+	headers := aux.SecurityHeaders{
+		ContentSecurityPolicy: *contentSecurityPolicy,
+		ReferrerPolicy:        *referrerPolicy,
+	}
+	log.Fatal(http.ListenAndServe(*listenAddr, headers.Wrap(http.DefaultServeMux)))
 }
@@ -0,0 +1,43 @@
+// debiman-render converts a single manpage into the page which debiman
+// would write for it, for reproducing rendering bugs without running
+// the whole pipeline.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/Debian/debiman/internal/debiman"
+)
+
+var (
+	renderAs = flag.String("render_as",
+		"",
+		"Serving path (<suite>/<binarypkg>/<name>.<section>.<language>, e.g. sid/coreutils/ls.1.de) of the manpage, which determines its metadata. Defaults to local/local/<name>.<section>.<language>, with name, section and language taken from the path like for /usr/share/man (e.g. de/man1/ls.1.gz). Required when reading from stdin")
+
+	renderIndex = flag.String("render_index",
+		"",
+		"If non-empty, path to an auxserver index generated by debiman, whose manpages cross-references are linked to, within the suite of -render_as. Cross-references are not linked if empty")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [flags] [<manpage file>]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Reads the manpage from stdin if no file (or “-”) is specified.\n")
+		fmt.Fprintf(os.Stderr, "The flags of debiman which change the pages (e.g. -mandoc_path, -postprocessors or -inject_assets) apply as well.\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	src := "-"
+	if flag.NArg() > 0 {
+		src = flag.Arg(0)
+	}
+	// The page (the error page if the conversion failed) is written
+	// nonetheless.
+	if err := debiman.RenderOne(os.Stdout, src, *renderAs, *renderIndex); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -10,36 +10,110 @@ import (
 	"net/url"
 	"sort"
 	"strings"
-	"sync"
+	"sync/atomic"
 
 	"github.com/Debian/debiman/internal/commontmpl"
 	"github.com/Debian/debiman/internal/manpage"
 	"github.com/Debian/debiman/internal/redirect"
 )
 
+// snapshot is an index and everything derived from it. Each request
+// is served from a single snapshot, which is never modified.
+type snapshot struct {
+	idx         redirect.Index
+	sortedNames []string
+	sortedPkgs  []string
+	pkgSuites   map[string][]string
+	random      randomManpages
+}
+
+func newSnapshot(idx redirect.Index) *snapshot {
+	sortedPkgs, pkgSuites := suggestPackages(idx)
+	return &snapshot{
+		idx:         idx,
+		sortedNames: suggestNames(idx),
+		sortedPkgs:  sortedPkgs,
+		pkgSuites:   pkgSuites,
+		random:      newRandomManpages(idx),
+	}
+}
+
 type Server struct {
-	idx            redirect.Index
-	idxMu          sync.RWMutex
+	current        atomic.Value // *snapshot
 	notFoundTmpl   *template.Template
 	debimanVersion string
-	sortedNames    []string
+
+	// CORS governs which other sites can use the JSON API and fetch
+	// manpage fragments. It does not apply to any other responses.
+	CORS CORSPolicy
+
+	// MultipleChoicesBelow makes HandleRedirect respond with HTTP 300
+	// Multiple Choices instead of redirecting when the request is
+	// ambiguous and its redirect target satisfies fewer than this many
+	// facets exactly (see redirect.Specificity). 0 always redirects.
+	MultipleChoicesBelow int
+
+	// PrefixRedirects makes HandleRedirect treat an unknown name
+	// (e.g. /coreut) as a prefix: if exactly one manpage or binary
+	// package name starts with it, HandleRedirect redirects there,
+	// otherwise it responds with HTTP 300 Multiple Choices listing the
+	// matches (see PrefixMatch). If false, unknown names result in
+	// HTTP 404.
+	PrefixRedirects bool
+
+	// Cache determines the Cache-Control and Expires headers of the
+	// responses of HandleRedirect, depending on the suite of the
+	// redirect target. The zero value sends no such headers.
+	Cache CachePolicy
+
+	// Preload are the values of Link headers (see
+	// commontmpl.PreloadLinks) which HandleRedirect sends with
+	// redirects and HTML responses, so that browsers (or CDNs sending
+	// 103 Early Hints) can fetch the stylesheet early. Empty disables
+	// the resource hints.
+	Preload []string
+
+	// RewriteMap, if non-nil, is consulted before the index:
+	// HandleRedirect redirects requests (without query) for one of its
+	// keys to the key’s target, exactly like a web server using the
+	// rewrite map of debiman-idx2rwmap would, regardless of the
+	// preferred language. Typically a *redirect.BinaryMap.
+	RewriteMap RewriteMap
+}
+
+// RewriteMap maps request paths (e.g. /i3) to redirect targets
+// (e.g. /jessie/i3-wm/i3.1.en.html).
+type RewriteMap interface {
+	Lookup(path string) (target string, ok bool)
 }
 
 func NewServer(idx redirect.Index, notFoundTmpl *template.Template, debimanVersion string) *Server {
 	s := &Server{
-		idx:            idx,
 		notFoundTmpl:   notFoundTmpl,
 		debimanVersion: debimanVersion,
 	}
-	s.prepareSuggest()
+	s.current.Store(newSnapshot(idx))
 	return s
 }
 
-// prepareSuggest sets sortedNames to a sorted slice of
-// <name>.<section> strings found in idx.
-func (s *Server) prepareSuggest() {
+func (s *Server) snapshot() *snapshot {
+	return s.current.Load().(*snapshot)
+}
+
+// setIndexVersion sets the X-Index-Version header, which identifies
+// the index generation which served the request, e.g. for correlating
+// client reports with index reloads.
+func setIndexVersion(w http.ResponseWriter, snap *snapshot) {
+	if v := snap.idx.Version; v != "" {
+		w.Header().Set("X-Index-Version", v)
+	}
+}
+
+// suggestNames returns a sorted slice of <name>.<section> strings
+// found in idx.
+func suggestNames(idx redirect.Index) []string {
 	names := make(map[string]bool)
-	for name, entries := range s.idx.Entries {
+	for name, entries := range idx.Entries {
 		for _, entry := range entries {
 			names[name+"."+entry.Section] = true
 		}
@@ -50,9 +124,15 @@ func (s *Server) prepareSuggest() {
 		result = append(result, name)
 	}
 	sort.Strings(result)
-	s.sortedNames = result
+	return result
 }
 
+// SwapIndex verifies idx and makes s use it for all subsequent
+// requests. Everything derived from idx is computed before the index
+// is swapped atomically: requests are never blocked, they are served
+// from the previous index until SwapIndex returns. Requests in flight
+// finish with the index they started with. idx must not be modified
+// afterwards (see redirect.Index).
 func (s *Server) SwapIndex(idx redirect.Index) error {
 	u, err := url.Parse("/i3")
 	if err != nil {
@@ -64,26 +144,74 @@ func (s *Server) SwapIndex(idx redirect.Index) error {
 	if err != nil {
 		return fmt.Errorf("idx.Redirect: %v", err)
 	}
-	if !strings.HasSuffix(redir, "i3.1.en.html") {
+	// With -group_languages, the page is language-independent and
+	// the fragment selects the language.
+	if page := strings.TrimSuffix(redir, "#lang-en"); !strings.HasSuffix(page, "i3.1.en.html") && !strings.HasSuffix(page, "i3.1.html") {
 		return fmt.Errorf("Redirect(/i3) does not lead to i3.1.en.html: got %q", redir)
 	}
-	s.idxMu.Lock()
-	defer s.idxMu.Unlock()
-	s.idx = idx
-	s.prepareSuggest()
+	s.current.Store(newSnapshot(idx))
 	return nil
 }
 
-func (s *Server) redirect(r *http.Request) (string, error) {
-	s.idxMu.RLock()
-	defer s.idxMu.RUnlock()
-	return s.idx.Redirect(r)
+func (s *Server) HandleRedirect(w http.ResponseWriter, r *http.Request) {
+	if isFragmentRequest(r) && s.CORS.apply(w, r) {
+		return
+	}
+
+	snap := s.snapshot()
+	setIndexVersion(w, snap)
+	if s.RewriteMap != nil && r.URL.RawQuery == "" {
+		if target, ok := s.RewriteMap.Lookup(r.URL.Path); ok {
+			s.cache(w, snap, servingPathSuite(target))
+			if linksStylesheet(target) {
+				s.preload(w)
+			}
+			http.Redirect(w, r, commontmpl.BaseURLPath()+target, http.StatusTemporaryRedirect)
+			return
+		}
+	}
+	s.handleRedirect(w, r, snap)
 }
 
-func (s *Server) HandleRedirect(w http.ResponseWriter, r *http.Request) {
-	redir, err := s.redirect(r)
+func (s *Server) handleRedirect(w http.ResponseWriter, r *http.Request, snap *snapshot) {
+	redir, spec, alternatives, err := snap.idx.RedirectSpecificity(r)
 	if err != nil {
+		if bp, ok := err.(*redirect.BadPathError); ok {
+			http.Error(w, bp.Error(), http.StatusBadRequest)
+			return
+		}
+		// Which manpage is offered depends on Accept-Language.
+		addVary(w, "Accept-Language")
 		if nf, ok := err.(*redirect.NotFoundError); ok {
+			var matches []PrefixMatch
+			if name, ok := prefixCandidate(r); ok && s.PrefixRedirects && nf.BestChoice.Suite == "" {
+				matches = s.prefixMatches(snap, name, r.FormValue("suite"))
+			}
+			if len(matches) == 1 {
+				if matches[0].Package {
+					s.cache(w, snap, servingPathSuite(matches[0].Path))
+					http.Redirect(w, r, commontmpl.BaseURLPath()+matches[0].Path, http.StatusTemporaryRedirect)
+					return
+				}
+				// Resolve the manpage like any other request.
+				u := *r.URL
+				u.Path = matches[0].Path
+				r2 := *r
+				r2.URL = &u
+				s.handleRedirect(w, &r2, snap)
+				return
+			}
+			status := http.StatusNotFound
+			if len(matches) > 0 {
+				status = http.StatusMultipleChoices
+				for _, m := range matches {
+					w.Header().Add("Link", "<"+commontmpl.BaseURLPath()+m.Path+">; rel=\"alternate\"")
+				}
+			}
+			more := len(matches) > maxPrefixMatches
+			if more {
+				matches = matches[:maxPrefixMatches]
+			}
 			var buf bytes.Buffer
 			err = s.notFoundTmpl.Execute(&buf, struct {
 				Title          string
@@ -92,6 +220,8 @@ func (s *Server) HandleRedirect(w http.ResponseWriter, r *http.Request) {
 				FooterExtra    string
 				Manpage        string
 				BestChoice     redirect.IndexEntry
+				Matches        []PrefixMatch
+				MoreMatches    bool
 				Meta           *manpage.Meta
 				HrefLangs      []*manpage.Meta
 			}{
@@ -99,11 +229,15 @@ func (s *Server) HandleRedirect(w http.ResponseWriter, r *http.Request) {
 				DebimanVersion: s.debimanVersion,
 				Manpage:        nf.Manpage,
 				BestChoice:     nf.BestChoice,
+				Matches:        matches,
+				MoreMatches:    more,
 			})
 			if err == nil {
+				s.cache(w, snap, "")
+				s.preload(w)
 				w.Header().Set("Content-Type", "text/html; charset=utf-8")
 				w.Header().Set("X-Content-Type-Options", "nosniff")
-				w.WriteHeader(http.StatusNotFound)
+				w.WriteHeader(status)
 				io.Copy(w, &buf)
 				return
 			}
@@ -113,12 +247,52 @@ func (s *Server) HandleRedirect(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Clients (e.g. of ?embed=1) can use the specificity to decide
+	// whether to follow the redirect or to offer a choice.
+	w.Header().Set("X-Debiman-Specificity", spec.String())
+	// The redirect target depends on the preferred language.
+	addVary(w, "Accept-Language")
+	s.cache(w, snap, servingPathSuite(redir))
+
+	if len(alternatives) > 0 && spec.Score() < s.MultipleChoicesBelow {
+		for _, alt := range alternatives {
+			w.Header().Add("Link", "<"+commontmpl.BaseURLPath()+alt+">; rel=\"alternate\"")
+		}
+		if linksStylesheet(redir) {
+			s.preload(w)
+		}
+		// The Location header indicates the preferred choice.
+		http.Redirect(w, r, commontmpl.BaseURLPath()+redir, http.StatusMultipleChoices)
+		return
+	}
+
 	// StatusTemporaryRedirect (HTTP 307) means subsequent requests
 	// should use the old URI, which is what we want — the redirect
 	// target will likely change in the future.
+	if linksStylesheet(redir) {
+		s.preload(w)
+	}
 	http.Redirect(w, r, commontmpl.BaseURLPath()+redir, http.StatusTemporaryRedirect)
 }
 
+// linksStylesheet returns whether the page at path links the
+// stylesheet, i.e. whether it is neither a raw manpage, a fragment nor
+// a minimal page.
+func linksStylesheet(path string) bool {
+	if idx := strings.Index(path, "#"); idx > -1 {
+		path = path[:idx] // see redirect.IndexEntry.Fragment
+	}
+	return strings.HasSuffix(path, ".html") &&
+		!strings.HasSuffix(path, ".frag.html") &&
+		!strings.HasSuffix(path, ".min.html")
+}
+
+func (s *Server) preload(w http.ResponseWriter) {
+	for _, link := range s.Preload {
+		w.Header().Add("Link", link)
+	}
+}
+
 func (s *Server) HandleJump(w http.ResponseWriter, r *http.Request) {
 	q := r.FormValue("q")
 	if strings.TrimSpace(q) == "" {
@@ -130,18 +304,16 @@ func (s *Server) HandleJump(w http.ResponseWriter, r *http.Request) {
 	s.HandleRedirect(w, r)
 }
 
-func (s *Server) suggest(q string) []string {
-	s.idxMu.RLock()
-	defer s.idxMu.RUnlock()
-
-	i := sort.Search(len(s.sortedNames), func(i int) bool {
-		return s.sortedNames[i] >= q
+func (s *Server) suggest(snap *snapshot, q string) []string {
+	sortedNames := snap.sortedNames
+	i := sort.Search(len(sortedNames), func(i int) bool {
+		return sortedNames[i] >= q
 	})
 
 	var result []string
-	for i < len(s.sortedNames) {
-		if strings.HasPrefix(s.sortedNames[i], q) {
-			result = append(result, s.sortedNames[i])
+	for i < len(sortedNames) {
+		if strings.HasPrefix(sortedNames[i], q) {
+			result = append(result, sortedNames[i])
 		} else {
 			break
 		}
@@ -154,6 +326,10 @@ func (s *Server) suggest(q string) []string {
 }
 
 func (s *Server) HandleSuggest(w http.ResponseWriter, r *http.Request) {
+	if s.CORS.apply(w, r) {
+		return
+	}
+
 	q := r.FormValue("q")
 	if strings.TrimSpace(q) == "" {
 		http.Error(w, "No q= query parameter specified", http.StatusBadRequest)
@@ -161,7 +337,9 @@ func (s *Server) HandleSuggest(w http.ResponseWriter, r *http.Request) {
 	}
 
 	r.URL.Path = "/" + q
-	completions := s.suggest(q)
+	snap := s.snapshot()
+	setIndexVersion(w, snap)
+	completions := s.suggest(snap, q)
 
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode([]interface{}{
@@ -1,11 +1,19 @@
 package aux
 
 import (
+	"bytes"
+	"flag"
+	"html/template"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"reflect"
+	"regexp"
 	"testing"
 
+	"github.com/Debian/debiman/internal/bundled"
+	"github.com/Debian/debiman/internal/commontmpl"
+	"github.com/Debian/debiman/internal/manpage"
 	"github.com/Debian/debiman/internal/redirect"
 )
 
@@ -37,7 +45,7 @@ func mustRedirectI3(t *testing.T, s *Server) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	redir, err := s.redirect(&http.Request{URL: u})
+	redir, _, _, err := s.snapshot().idx.RedirectSpecificity(&http.Request{URL: u})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -57,7 +65,7 @@ func TestIndexSwapSucceed(t *testing.T) {
 	s := NewServer(i3OnlyIdx, nil, "")
 	mustRedirectI3(t, s)
 
-	redir, err := s.redirect(&http.Request{URL: u})
+	redir, _, _, err := s.snapshot().idx.RedirectSpecificity(&http.Request{URL: u})
 	if err == nil {
 		t.Fatal("redirect(/w3m) unexpectedly succeeded")
 	}
@@ -101,7 +109,7 @@ func TestIndexSwapSucceed(t *testing.T) {
 
 	mustRedirectI3(t, s)
 
-	redir, err = s.redirect(&http.Request{URL: u})
+	redir, _, _, err = s.snapshot().idx.RedirectSpecificity(&http.Request{URL: u})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -110,6 +118,56 @@ func TestIndexSwapSucceed(t *testing.T) {
 	}
 }
 
+func TestIndexVersion(t *testing.T) {
+	// Defined by debiman-auxserver, required for HandleRedirect.
+	if flag.Lookup("base_url") == nil {
+		flag.String("base_url", "https://manpages.debian.org", "")
+	}
+
+	w3m := func(binarypkg string) map[string][]redirect.IndexEntry {
+		return map[string][]redirect.IndexEntry{
+			"i3": i3OnlyIdx.Entries["i3"],
+			"w3m": []redirect.IndexEntry{
+				{Name: "w3m", Suite: "jessie", Binarypkg: binarypkg, Section: "1", Language: "en"},
+			},
+		}
+	}
+	old := redirect.NewIndex(w3m("w3m"), nil)
+	old.Version = "20170523T141500Z-1a2b3c4d"
+	newer := redirect.NewIndex(w3m("w3m-img"), nil)
+	newer.Version = "20170524T141500Z-5e6f7a8b"
+	s := NewServer(old, nil, "")
+
+	// Requests racing with SwapIndex are served entirely by either
+	// index, never by a mix of both.
+	want := map[string]string{
+		old.Version:   "/jessie/w3m/w3m.1.en.html",
+		newer.Version: "/jessie/w3m-img/w3m.1.en.html",
+	}
+	done := make(chan bool)
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			rec := httptest.NewRecorder()
+			s.HandleRedirect(rec, httptest.NewRequest("GET", "/w3m", nil))
+			v := rec.Header().Get("X-Index-Version")
+			if got := rec.Header().Get("Location"); got != want[v] {
+				t.Errorf("/w3m served by version %q: got Location %q, want %q", v, got, want[v])
+			}
+		}
+	}()
+	if err := s.SwapIndex(newer); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+
+	rec := httptest.NewRecorder()
+	s.HandleSuggest(rec, httptest.NewRequest("GET", "/suggest?q=w3", nil))
+	if got, want := rec.Header().Get("X-Index-Version"), newer.Version; got != want {
+		t.Errorf("/suggest: X-Index-Version = %q, want %q", got, want)
+	}
+}
+
 func TestIndexSwapFail(t *testing.T) {
 	t.Parallel()
 
@@ -140,7 +198,7 @@ func TestSuggest(t *testing.T) {
 			want:  nil,
 		},
 	} {
-		if got, want := s.suggest(entry.query), entry.want; !reflect.DeepEqual(got, want) {
+		if got, want := s.suggest(s.snapshot(), entry.query), entry.want; !reflect.DeepEqual(got, want) {
 			t.Fatalf("unexpected result: got %v, want %v", got, want)
 		}
 	}
@@ -152,6 +210,215 @@ func BenchmarkSuggest(b *testing.B) {
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		// TODO: run sub benchmarks for a few search terms
-		s.suggest("i")
+		s.suggest(s.snapshot(), "i")
+	}
+}
+
+func TestHandleRedirectBadPath(t *testing.T) {
+	t.Parallel()
+
+	s := NewServer(i3OnlyIdx, nil, "")
+	rec := httptest.NewRecorder()
+	s.HandleRedirect(rec, &http.Request{URL: &url.URL{Path: "/i3\xff"}})
+	if got, want := rec.Code, http.StatusBadRequest; got != want {
+		t.Fatalf("unexpected HTTP status: got %d, want %d", got, want)
+	}
+}
+
+func TestMultipleChoices(t *testing.T) {
+	// Defined by debiman-auxserver, required for HandleRedirect.
+	if flag.Lookup("base_url") == nil {
+		flag.String("base_url", "https://manpages.debian.org", "")
+	}
+
+	idx := i3OnlyIdx // copy
+	idx.Entries = map[string][]redirect.IndexEntry{
+		"vi": []redirect.IndexEntry{
+			{Name: "vi", Suite: "jessie", Binarypkg: "vim", Section: "1", Language: "en"},
+			{Name: "vi", Suite: "jessie", Binarypkg: "nvi", Section: "1", Language: "en"},
+		},
+		// Required by NewServer
+		"i3": i3OnlyIdx.Entries["i3"],
+	}
+	s := NewServer(idx, nil, "")
+	s.MultipleChoicesBelow = 1
+
+	table := []struct {
+		path     string
+		wantCode int
+		wantLink string
+	}{
+		{"/vi", http.StatusMultipleChoices, "</jessie/nvi/vi.1.en.html>; rel=\"alternate\""},
+		{"/jessie/vi", http.StatusTemporaryRedirect, ""},
+		{"/i3", http.StatusTemporaryRedirect, ""}, // not ambiguous
+	}
+	for _, entry := range table {
+		rec := httptest.NewRecorder()
+		s.HandleRedirect(rec, httptest.NewRequest("GET", entry.path, nil))
+		if got, want := rec.Code, entry.wantCode; got != want {
+			t.Errorf("%s: unexpected HTTP status: got %d, want %d", entry.path, got, want)
+		}
+		if got, want := rec.Header().Get("Link"), entry.wantLink; got != want {
+			t.Errorf("%s: unexpected Link header: got %q, want %q", entry.path, got, want)
+		}
+		if rec.Header().Get("Location") == "" {
+			t.Errorf("%s: no Location header", entry.path)
+		}
+		if rec.Header().Get("X-Debiman-Specificity") == "" {
+			t.Errorf("%s: no X-Debiman-Specificity header", entry.path)
+		}
+	}
+}
+
+func TestStaticHandler(t *testing.T) {
+	h := StaticHandler("manifest.webmanifest", []byte(`{"name": "debiman"}`))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/manifest.webmanifest", nil))
+	if got, want := rec.Code, http.StatusOK; got != want {
+		t.Fatalf("unexpected HTTP status code: got %d, want %d", got, want)
+	}
+	if got, want := rec.Header().Get("Content-Type"), "application/manifest+json"; got != want {
+		t.Fatalf("unexpected Content-Type: got %q, want %q", got, want)
+	}
+	if got, want := rec.Body.String(), `{"name": "debiman"}`; got != want {
+		t.Fatalf("unexpected body: got %q, want %q", got, want)
+	}
+}
+
+func TestPreloadLinks(t *testing.T) {
+	// Defined by debiman-auxserver, required for HandleRedirect.
+	if flag.Lookup("base_url") == nil {
+		flag.String("base_url", "https://manpages.debian.org", "")
+	}
+
+	// The Link header must refer to the stylesheet the pages link.
+	var buf bytes.Buffer
+	if err := commontmpl.MustParseCommonTmpls().ExecuteTemplate(&buf, "header", struct {
+		Title       string
+		Breadcrumbs []string
+		Meta        *manpage.Meta
+		HrefLangs   []*manpage.Meta
+	}{Title: "i3"}); err != nil {
+		t.Fatal(err)
+	}
+	m := regexp.MustCompile(`<link rel="stylesheet" href="([^"]+)"`).FindStringSubmatch(buf.String())
+	if m == nil {
+		t.Fatalf("header does not link a stylesheet:\n%s", buf.String())
+	}
+	links := commontmpl.PreloadLinks()
+	if want := []string{"<" + m[1] + ">; rel=preload; as=style"}; !reflect.DeepEqual(links, want) {
+		t.Fatalf("unexpected preload links: got %q, want %q", links, want)
+	}
+
+	s := NewServer(i3OnlyIdx, nil, "")
+	s.Preload = links
+	for _, entry := range []struct {
+		path string
+		want []string
+	}{
+		{"/i3", links},
+		{"/i3.frag.html", nil},
+		{"/i3.min.html", nil},
+	} {
+		rec := httptest.NewRecorder()
+		s.HandleRedirect(rec, httptest.NewRequest("GET", entry.path, nil))
+		if got := rec.Header()["Link"]; !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("%s: unexpected Link headers: got %q, want %q", entry.path, got, entry.want)
+		}
+	}
+}
+
+func TestPrefixRedirects(t *testing.T) {
+	// Defined by debiman-auxserver, required for HandleRedirect.
+	if flag.Lookup("base_url") == nil {
+		flag.String("base_url", "https://manpages.debian.org", "")
+	}
+
+	idx := i3OnlyIdx // copy
+	idx.Entries = map[string][]redirect.IndexEntry{
+		"ls": []redirect.IndexEntry{
+			{Name: "ls", Suite: "jessie", Binarypkg: "coreutils", Section: "1", Language: "en"},
+		},
+		"lsblk": []redirect.IndexEntry{
+			{Name: "lsblk", Suite: "jessie", Binarypkg: "util-linux", Section: "8", Language: "en"},
+		},
+		"w3m": []redirect.IndexEntry{
+			{Name: "w3m", Suite: "jessie", Binarypkg: "w3m", Section: "1", Language: "en"},
+		},
+		"i3": i3OnlyIdx.Entries["i3"],
+	}
+	notFoundTmpl := template.Must(commontmpl.MustParseCommonTmpls().New("notfound").Parse(bundled.Asset("notfound.tmpl")))
+	s := NewServer(idx, notFoundTmpl, "")
+
+	table := []struct {
+		path         string
+		prefix       bool
+		wantCode     int
+		wantLocation string
+		wantLinks    []string
+	}{
+		{"/coreut", false, http.StatusNotFound, "", nil},
+		{"/coreut", true, http.StatusTemporaryRedirect, "/jessie/coreutils/index.html", nil},
+		{"/lsb", true, http.StatusTemporaryRedirect, "/jessie/util-linux/lsblk.8.en.html", nil},
+		{"/ls", true, http.StatusTemporaryRedirect, "/jessie/coreutils/ls.1.en.html", nil}, // exact match
+		{"/w3", true, http.StatusMultipleChoices, "", []string{
+			"</w3m>; rel=\"alternate\"",
+			"</jessie/w3m/index.html>; rel=\"alternate\"",
+		}},
+		{"/jessie/coreut", true, http.StatusNotFound, "", nil},
+		{"/coreut.1", true, http.StatusNotFound, "", nil},
+		{"/xyz", true, http.StatusNotFound, "", nil},
+	}
+	for _, entry := range table {
+		s.PrefixRedirects = entry.prefix
+		rec := httptest.NewRecorder()
+		s.HandleRedirect(rec, httptest.NewRequest("GET", entry.path, nil))
+		if got, want := rec.Code, entry.wantCode; got != want {
+			t.Errorf("%s (prefix %v): unexpected HTTP status: got %d, want %d", entry.path, entry.prefix, got, want)
+		}
+		if got, want := rec.Header().Get("Location"), entry.wantLocation; got != want {
+			t.Errorf("%s (prefix %v): unexpected Location header: got %q, want %q", entry.path, entry.prefix, got, want)
+		}
+		if got, want := rec.Header()["Link"], entry.wantLinks; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s (prefix %v): unexpected Link headers: got %q, want %q", entry.path, entry.prefix, got, want)
+		}
+	}
+}
+
+type fakeRewriteMap map[string]string
+
+func (m fakeRewriteMap) Lookup(path string) (string, bool) {
+	target, ok := m[path]
+	return target, ok
+}
+
+func TestRewriteMap(t *testing.T) {
+	// Defined by debiman-auxserver, required for HandleRedirect.
+	if flag.Lookup("base_url") == nil {
+		flag.String("base_url", "https://manpages.debian.org", "")
+	}
+
+	s := NewServer(i3OnlyIdx, nil, "")
+	s.RewriteMap = fakeRewriteMap{
+		"/legacy-i3": "/jessie/i3-wm/i3.1.en.html",
+		"/i3":        "/stretch/i3-wm/i3.1.en.html",
+	}
+	for _, entry := range []struct {
+		path string
+		want string
+	}{
+		{"/legacy-i3", "/jessie/i3-wm/i3.1.en.html"},
+		{"/i3", "/stretch/i3-wm/i3.1.en.html"},
+		{"/i3?lang=en", "/jessie/i3-wm/i3.1.en.html"}, // via the index
+		{"/i3.1", "/jessie/i3-wm/i3.1.en.html"},       // via the index
+	} {
+		rec := httptest.NewRecorder()
+		s.HandleRedirect(rec, httptest.NewRequest("GET", entry.path, nil))
+		if got, want := rec.Code, http.StatusTemporaryRedirect; got != want {
+			t.Errorf("%s: unexpected status: got %d, want %d", entry.path, got, want)
+		}
+		if got := rec.Header().Get("Location"); got != entry.want {
+			t.Errorf("%s: unexpected redirect: got %q, want %q", entry.path, got, entry.want)
+		}
 	}
 }
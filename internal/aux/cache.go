@@ -0,0 +1,137 @@
+package aux
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// immutableMaxAge is the max-age of assets whose URL contains their
+// hash (see commontmpl.AssetVersion).
+const immutableMaxAge = 365 * 24 * time.Hour
+
+// CachePolicy specifies for how long caches (e.g. CDNs) may reuse the
+// responses of HandleRedirect, depending on the suite of the redirect
+// target. The zero value sends no Cache-Control headers.
+type CachePolicy struct {
+	maxAge map[string]time.Duration // by suite or codename
+	keys   []string                 // of maxAge, sorted
+	def    time.Duration            // for suites not in maxAge
+	min    time.Duration            // for responses without a suite
+}
+
+// ParseCachePolicy parses a comma-separated list of suite=duration
+// pairs (e.g. “unstable=10m,stretch=24h”), in which the suite “*”
+// stands for all other suites. Suites may be specified by suite name
+// (e.g. “testing”) or codename (e.g. “buster”), see redirect.Index.Suites.
+func ParseCachePolicy(s string) (CachePolicy, error) {
+	var p CachePolicy
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.Split(pair, "=")
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return CachePolicy{}, fmt.Errorf("%q: expected suite=duration", pair)
+		}
+		suite := strings.TrimSpace(parts[0])
+		d, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+		if err != nil || d < 0 {
+			return CachePolicy{}, fmt.Errorf("%q: invalid duration, expected e.g. 10m or 24h", pair)
+		}
+		if p.maxAge == nil {
+			p.maxAge = make(map[string]time.Duration)
+		}
+		if suite == "*" {
+			p.def = d
+		}
+		if len(p.maxAge) == 0 || d < p.min {
+			p.min = d
+		}
+		p.maxAge[suite] = d
+	}
+	for suite := range p.maxAge {
+		if suite != "*" {
+			p.keys = append(p.keys, suite)
+		}
+	}
+	sort.Strings(p.keys)
+	return p, nil
+}
+
+// Enabled returns whether p sends Cache-Control headers at all.
+func (p CachePolicy) Enabled() bool {
+	return p.maxAge != nil
+}
+
+// MaxAge returns the max-age for responses referring to suite (as in
+// the values of suites, i.e. redirect.Index.Suites). Responses which do
+// not refer to any suite (suite is empty), e.g. not found pages, use
+// the shortest configured max-age.
+func (p CachePolicy) MaxAge(suite string, suites map[string]string) time.Duration {
+	if suite == "" {
+		return p.min
+	}
+	if d, ok := p.maxAge[suite]; ok {
+		return d
+	}
+	for _, key := range p.keys {
+		if suites[key] == suite {
+			return p.maxAge[key]
+		}
+	}
+	return p.def
+}
+
+// setCacheHeaders sets the Cache-Control and Expires headers for a
+// response which caches may reuse for maxAge.
+func setCacheHeaders(w http.ResponseWriter, maxAge time.Duration, immutable bool) {
+	if maxAge <= 0 {
+		w.Header().Set("Cache-Control", "no-cache")
+		return
+	}
+	cc := "public, max-age=" + strconv.FormatInt(int64(maxAge/time.Second), 10)
+	if immutable {
+		cc += ", immutable"
+	}
+	w.Header().Set("Cache-Control", cc)
+	w.Header().Set("Expires", time.Now().Add(maxAge).UTC().Format(http.TimeFormat))
+}
+
+// servingPathSuite returns the suite of a path returned by
+// redirect.IndexEntry.ServingPath, e.g. “stretch” for
+// “/stretch/i3-wm/i3.1.en.html”.
+func servingPathSuite(path string) string {
+	parts := strings.SplitN(strings.TrimPrefix(path, "/"), "/", 2)
+	if len(parts) != 2 {
+		return ""
+	}
+	return parts[0]
+}
+
+// cache sets the cache headers for a response referring to suite (see
+// CachePolicy.MaxAge) if s.Cache is enabled. Aliases are resolved
+// using the suites of snap.
+func (s *Server) cache(w http.ResponseWriter, snap *snapshot, suite string) {
+	if !s.Cache.Enabled() {
+		return
+	}
+	setCacheHeaders(w, s.Cache.MaxAge(suite, snap.idx.Suites), false)
+}
+
+// addVary adds header to the Vary header of w unless it is already
+// listed.
+func addVary(w http.ResponseWriter, header string) {
+	for _, v := range w.Header()["Vary"] {
+		for _, h := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(h), header) {
+				return
+			}
+		}
+	}
+	w.Header().Add("Vary", header)
+}
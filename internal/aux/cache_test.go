@@ -0,0 +1,168 @@
+package aux
+
+import (
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Debian/debiman/internal/commontmpl"
+	"github.com/Debian/debiman/internal/redirect"
+)
+
+func TestParseCachePolicy(t *testing.T) {
+	p, err := ParseCachePolicy("testing=1h, unstable=10m,*=24h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	suites := map[string]string{
+		"stable":  "stretch",
+		"stretch": "stretch",
+		"testing": "buster",
+		"buster":  "buster",
+		"sid":     "sid",
+	}
+	for _, entry := range []struct {
+		suite string
+		want  time.Duration
+	}{
+		{"buster", time.Hour},          // by suite name
+		{"unstable", 10 * time.Minute}, // directly
+		{"stretch", 24 * time.Hour},    // default
+		{"", 10 * time.Minute},         // shortest
+	} {
+		if got := p.MaxAge(entry.suite, suites); got != entry.want {
+			t.Errorf("MaxAge(%q): got %v, want %v", entry.suite, got, entry.want)
+		}
+	}
+
+	for _, invalid := range []string{"testing", "testing=soon", "=1h", "unstable=-1h"} {
+		if _, err := ParseCachePolicy(invalid); err == nil {
+			t.Errorf("ParseCachePolicy(%q) unexpectedly succeeded", invalid)
+		}
+	}
+
+	p, err = ParseCachePolicy("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Enabled() {
+		t.Errorf("ParseCachePolicy(\"\").Enabled() = true, want false")
+	}
+}
+
+func TestCacheHeaders(t *testing.T) {
+	// Defined by debiman-auxserver, required for HandleRedirect.
+	if flag.Lookup("base_url") == nil {
+		flag.String("base_url", "https://manpages.debian.org", "")
+	}
+
+	idx := i3OnlyIdx // copy
+	idx.Entries = map[string][]redirect.IndexEntry{
+		"i3": append([]redirect.IndexEntry{
+			{Name: "i3", Suite: "sid", Binarypkg: "i3-wm", Section: "1", Language: "en"},
+		}, i3OnlyIdx.Entries["i3"]...),
+	}
+	idx.Suites = map[string]string{"jessie": "jessie", "unstable": "sid", "sid": "sid"}
+	s := NewServer(idx, nil, "")
+	var err error
+	s.Cache, err = ParseCachePolicy("unstable=10m,*=24h")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range []struct {
+		path string
+		want string
+	}{
+		{"/jessie/i3", "public, max-age=86400"},
+		{"/sid/i3", "public, max-age=600"},
+	} {
+		rec := httptest.NewRecorder()
+		s.HandleRedirect(rec, httptest.NewRequest("GET", entry.path, nil))
+		if got, want := rec.Code, http.StatusTemporaryRedirect; got != want {
+			t.Fatalf("%s: unexpected HTTP status: got %d, want %d", entry.path, got, want)
+		}
+		if got := rec.Header().Get("Cache-Control"); got != entry.want {
+			t.Errorf("%s: unexpected Cache-Control header: got %q, want %q", entry.path, got, entry.want)
+		}
+		if rec.Header().Get("Expires") == "" {
+			t.Errorf("%s: no Expires header", entry.path)
+		}
+		if got, want := rec.Header()["Vary"], []string{"Accept-Language"}; len(got) != 1 || got[0] != want[0] {
+			t.Errorf("%s: unexpected Vary headers: got %q, want %q", entry.path, got, want)
+		}
+	}
+}
+
+func TestStaticHandlerImmutable(t *testing.T) {
+	h := StaticHandler("favicon.ico", []byte("icon"))
+	for _, entry := range []struct {
+		query string
+		want  string
+	}{
+		{commontmpl.AssetVersion("favicon.ico"), "public, max-age=31536000, immutable"},
+		{"", ""},
+		{"00000000", ""},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/favicon.ico?"+entry.query, nil))
+		if got := rec.Header().Get("Cache-Control"); got != entry.want {
+			t.Errorf("?%s: unexpected Cache-Control header: got %q, want %q", entry.query, got, entry.want)
+		}
+	}
+}
+
+func TestStaticHandlerETag(t *testing.T) {
+	h := StaticHandler("favicon.ico", []byte("icon"))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/favicon.ico", nil))
+	etag := rec.Header().Get("ETag")
+	if want := `"c2d4b446a44ce54fab8e01150e24dd24f3d850c7c14dcfe31f6321341dd86874"`; etag != want {
+		t.Fatalf("unexpected ETag: got %q, want %q", etag, want)
+	}
+	req := httptest.NewRequest("GET", "/favicon.ico", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if got, want := rec.Code, http.StatusNotModified; got != want {
+		t.Errorf("If-None-Match: unexpected status: got %d, want %d", got, want)
+	}
+}
+
+func TestFileETag(t *testing.T) {
+	const hash = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	for _, entry := range []struct {
+		url         string
+		encoding    string
+		ifNoneMatch string
+		wantETag    string
+		wantCache   string
+		wantMatch   bool
+	}{
+		{"/jessie/i3-wm/i3.1.en.html", "", "", `W/"` + hash + `"`, "", false},
+		{"/jessie/i3-wm/i3.1.en.html", "gzip", `W/"` + hash + `"`, `W/"` + hash + `"`, "", true},
+		{"/jessie/i3-wm/i3.1.en.html", "", `"other", "` + hash + `"`, `W/"` + hash + `"`, "", true},
+		{"/jessie/i3-wm/i3.1.en.html", "", `W/"other"`, `W/"` + hash + `"`, "", false},
+		{"/style.css?" + commontmpl.AssetVersion("style.css"), "gzip", "", `"` + hash + `-gzip"`, "public, max-age=31536000, immutable", false},
+		{"/style.css?" + commontmpl.AssetVersion("style.css"), "", "*", `"` + hash + `"`, "public, max-age=31536000, immutable", true},
+		{"/style.css?00000000", "", "", `W/"` + hash + `"`, "", false},
+	} {
+		req := httptest.NewRequest("GET", entry.url, nil)
+		if entry.ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", entry.ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		match := FileETag(rec, req, req.URL.Path, hash, entry.encoding)
+		if got := rec.Header().Get("ETag"); got != entry.wantETag {
+			t.Errorf("%s (%q): unexpected ETag: got %q, want %q", entry.url, entry.encoding, got, entry.wantETag)
+		}
+		if got := rec.Header().Get("Cache-Control"); got != entry.wantCache {
+			t.Errorf("%s: unexpected Cache-Control header: got %q, want %q", entry.url, got, entry.wantCache)
+		}
+		if match != entry.wantMatch {
+			t.Errorf("%s, If-None-Match %q: got match %v, want %v", entry.url, entry.ifNoneMatch, match, entry.wantMatch)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package aux
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// CORSPolicy specifies which origins may read the responses of the
+// JSON API (/suggest) and manpage fragments (?embed=1) via
+// cross-origin requests. The zero value allows no origins, i.e. no CORS
+// headers are sent.
+type CORSPolicy struct {
+	any     bool
+	origins map[string]bool
+}
+
+// ParseCORSPolicy parses a comma-separated list of origins
+// (e.g. “https://example.com,https://example.org:8443”), or “*” to
+// allow any origin. Origins are matched exactly, so they must not
+// contain a path or a trailing slash.
+func ParseCORSPolicy(s string) (CORSPolicy, error) {
+	var p CORSPolicy
+	for _, origin := range strings.Split(s, ",") {
+		origin = strings.TrimSpace(origin)
+		if origin == "" {
+			continue
+		}
+		if origin == "*" {
+			p.any = true
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil {
+			return CORSPolicy{}, fmt.Errorf("invalid origin %q: %v", origin, err)
+		}
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+			origin != u.Scheme+"://"+u.Host {
+			return CORSPolicy{}, fmt.Errorf("invalid origin %q: expected scheme://host[:port], e.g. https://example.com", origin)
+		}
+		if p.origins == nil {
+			p.origins = make(map[string]bool)
+		}
+		p.origins[origin] = true
+	}
+	return p, nil
+}
+
+// Enabled returns whether p allows any origin at all.
+func (p CORSPolicy) Enabled() bool {
+	return p.any || len(p.origins) > 0
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value for a
+// request from origin, or the empty string if origin is not allowed.
+func (p CORSPolicy) allowOrigin(origin string) string {
+	if origin == "" {
+		return ""
+	}
+	if p.any {
+		return "*"
+	}
+	if p.origins[origin] {
+		return origin
+	}
+	return ""
+}
+
+// apply sets the CORS response headers for r on w. It returns true if
+// r is a preflight request, which apply answered.
+func (p CORSPolicy) apply(w http.ResponseWriter, r *http.Request) (preflight bool) {
+	if !p.Enabled() {
+		return false
+	}
+	// The response differs depending on the Origin request header,
+	// which caches need to know about.
+	if !p.any {
+		w.Header().Add("Vary", "Origin")
+	}
+	allow := p.allowOrigin(r.Header.Get("Origin"))
+	if allow != "" {
+		w.Header().Set("Access-Control-Allow-Origin", allow)
+	}
+	if r.Method != "OPTIONS" || r.Header.Get("Access-Control-Request-Method") == "" {
+		return false
+	}
+	if allow != "" {
+		w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, OPTIONS")
+		if h := r.Header.Get("Access-Control-Request-Headers"); h != "" {
+			w.Header().Set("Access-Control-Allow-Headers", h)
+		}
+		w.Header().Set("Access-Control-Max-Age", "86400")
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+// isFragmentRequest returns whether r asks for a manpage fragment (see
+// debiman’s -embed_fragments flag).
+func isFragmentRequest(r *http.Request) bool {
+	return strings.HasSuffix(r.URL.Path, ".frag.html") ||
+		r.URL.Query().Get("embed") == "1"
+}
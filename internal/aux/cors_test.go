@@ -0,0 +1,82 @@
+package aux
+
+import (
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseCORSPolicy(t *testing.T) {
+	for _, s := range []string{"", "*", "https://example.com", "https://example.com, http://localhost:8080"} {
+		if _, err := ParseCORSPolicy(s); err != nil {
+			t.Errorf("ParseCORSPolicy(%q): %v", s, err)
+		}
+	}
+	for _, s := range []string{"example.com", "https://example.com/", "https://example.com/path", "ftp://example.com"} {
+		if _, err := ParseCORSPolicy(s); err == nil {
+			t.Errorf("ParseCORSPolicy(%q) unexpectedly succeeded", s)
+		}
+	}
+}
+
+func TestCORS(t *testing.T) {
+	// Defined by debiman-auxserver, required for HandleRedirect.
+	if flag.Lookup("base_url") == nil {
+		flag.String("base_url", "https://manpages.debian.org", "")
+	}
+
+	allowlist, err := ParseCORSPolicy("https://example.com,https://example.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	anyOrigin, err := ParseCORSPolicy("*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table := []struct {
+		name       string
+		policy     CORSPolicy
+		method     string
+		URL        string
+		origin     string
+		wantCode   int
+		wantOrigin string
+		wantVary   bool
+	}{
+		{"fragment", allowlist, "GET", "/i3?embed=1", "https://example.org", http.StatusTemporaryRedirect, "https://example.org", true},
+		{"fragment/mismatch", allowlist, "GET", "/i3?embed=1", "https://example.com.evil.org", http.StatusTemporaryRedirect, "", true},
+		{"fragment/any", anyOrigin, "GET", "/i3.frag.html", "https://evil.org", http.StatusTemporaryRedirect, "*", false},
+		{"fragment/disabled", CORSPolicy{}, "GET", "/i3?embed=1", "https://example.com", http.StatusTemporaryRedirect, "", false},
+		{"page", allowlist, "GET", "/i3", "https://example.com", http.StatusTemporaryRedirect, "", false},
+		{"preflight", allowlist, "OPTIONS", "/i3?embed=1", "https://example.com", http.StatusNoContent, "https://example.com", true},
+		{"suggest", allowlist, "GET", "/suggest?q=i3", "https://example.com", http.StatusOK, "https://example.com", true},
+	}
+	for _, entry := range table {
+		t.Run(entry.name, func(t *testing.T) {
+			s := NewServer(i3OnlyIdx, nil, "")
+			s.CORS = entry.policy
+			req := httptest.NewRequest(entry.method, entry.URL, nil)
+			req.Header.Set("Origin", entry.origin)
+			if entry.method == "OPTIONS" {
+				req.Header.Set("Access-Control-Request-Method", "GET")
+			}
+			rec := httptest.NewRecorder()
+			if req.URL.Path == "/suggest" {
+				s.HandleSuggest(rec, req)
+			} else {
+				s.HandleRedirect(rec, req)
+			}
+			if got, want := rec.Code, entry.wantCode; got != want {
+				t.Fatalf("unexpected HTTP status: got %d, want %d", got, want)
+			}
+			if got, want := rec.Header().Get("Access-Control-Allow-Origin"), entry.wantOrigin; got != want {
+				t.Errorf("unexpected Access-Control-Allow-Origin header: got %q, want %q", got, want)
+			}
+			if got, want := rec.Header().Get("Vary") == "Origin", entry.wantVary; got != want {
+				t.Errorf("unexpected Vary header: got %q, want Origin: %v", rec.Header().Get("Vary"), want)
+			}
+		})
+	}
+}
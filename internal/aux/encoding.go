@@ -0,0 +1,80 @@
+package aux
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// precompressed lists the content encodings of the precompressed
+// variants which may exist next to a file (e.g. ls.1.en.html.zst next
+// to ls.1.en.html), in order of preference. debiman writes the gzip
+// variant of each page; zstd and brotli variants can be added by other
+// tools.
+var precompressed = []struct {
+	encoding string
+	suffix   string
+}{
+	{"zstd", ".zst"},
+	{"br", ".br"},
+	{"gzip", ".gz"},
+}
+
+// acceptsEncoding returns whether the Accept-Encoding request header
+// value acceptEncoding allows encoding, i.e. whether it lists encoding
+// (or “*”) with a non-zero quality value.
+func acceptsEncoding(acceptEncoding, encoding string) bool {
+	accepted := false
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding := strings.TrimSpace(part)
+		q := 1.0
+		if idx := strings.Index(coding, ";"); idx > -1 {
+			params := coding[idx+1:]
+			coding = strings.TrimSpace(coding[:idx])
+			for _, param := range strings.Split(params, ";") {
+				param = strings.TrimSpace(param)
+				if !strings.HasPrefix(param, "q=") {
+					continue
+				}
+				if v, err := strconv.ParseFloat(param[len("q="):], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		switch {
+		case strings.EqualFold(coding, encoding):
+			// An explicit entry takes precedence over “*”.
+			return q > 0
+		case coding == "*":
+			accepted = q > 0
+		}
+	}
+	return accepted
+}
+
+// PrecompressedVariant returns the path and content encoding of the
+// preferred variant of path (a file name without compression suffix,
+// e.g. /srv/man/jessie/coreutils/ls.1.en.html) which exists and which
+// the client accepts according to acceptEncoding (the Accept-Encoding
+// request header): zstd, brotli or gzip, in that order. If the client
+// accepts none of the existing variants, path itself is returned with
+// an empty encoding if it exists, and the empty string otherwise.
+func PrecompressedVariant(path, acceptEncoding string) (variant, encoding string) {
+	for _, p := range precompressed {
+		if !acceptsEncoding(acceptEncoding, p.encoding) {
+			continue
+		}
+		if exists(path + p.suffix) {
+			return path + p.suffix, p.encoding
+		}
+	}
+	if exists(path) {
+		return path, ""
+	}
+	return "", ""
+}
+
+func exists(path string) bool {
+	st, err := os.Stat(path)
+	return err == nil && st.Mode().IsRegular()
+}
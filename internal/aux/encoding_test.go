@@ -0,0 +1,65 @@
+package aux
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAcceptsEncoding(t *testing.T) {
+	for _, entry := range []struct {
+		acceptEncoding string
+		encoding       string
+		want           bool
+	}{
+		{"gzip, deflate, br, zstd", "zstd", true},
+		{"gzip, deflate, br", "zstd", false},
+		{"", "gzip", false},
+		{"GZIP", "gzip", true},
+		{"zstd;q=0, gzip", "zstd", false},
+		{"zstd;q=0.5, gzip", "zstd", true},
+		{"*", "br", true},
+		{"*, br;q=0", "br", false},
+		{"br;q=0, *", "br", false},
+		{"*;q=0, gzip", "gzip", true},
+	} {
+		if got := acceptsEncoding(entry.acceptEncoding, entry.encoding); got != entry.want {
+			t.Errorf("acceptsEncoding(%q, %q) = %v, want %v", entry.acceptEncoding, entry.encoding, got, entry.want)
+		}
+	}
+}
+
+func TestPrecompressedVariant(t *testing.T) {
+	dir, err := ioutil.TempDir("", "debiman-encoding")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, fn := range []string{"ls.1.en.html.gz", "ls.1.en.html.zst", "cat.1.en.html.gz", "cat.1.en.html.br", "style.css"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, fn), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, entry := range []struct {
+		name           string
+		acceptEncoding string
+		wantVariant    string
+		wantEncoding   string
+	}{
+		{"ls.1.en.html", "gzip, br, zstd", "ls.1.en.html.zst", "zstd"},
+		{"ls.1.en.html", "gzip, br", "ls.1.en.html.gz", "gzip"},
+		{"ls.1.en.html", "", "", ""},
+		{"cat.1.en.html", "gzip, br, zstd", "cat.1.en.html.br", "br"},
+		{"cat.1.en.html", "zstd", "", ""},
+		{"style.css", "gzip, zstd", "style.css", ""},
+	} {
+		variant, encoding := PrecompressedVariant(filepath.Join(dir, entry.name), entry.acceptEncoding)
+		if entry.wantVariant != "" {
+			entry.wantVariant = filepath.Join(dir, entry.wantVariant)
+		}
+		if variant != entry.wantVariant || encoding != entry.wantEncoding {
+			t.Errorf("PrecompressedVariant(%q, %q) = %q, %q, want %q, %q", entry.name, entry.acceptEncoding, variant, encoding, entry.wantVariant, entry.wantEncoding)
+		}
+	}
+}
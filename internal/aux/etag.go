@@ -0,0 +1,62 @@
+package aux
+
+import (
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/Debian/debiman/internal/bundled"
+	"github.com/Debian/debiman/internal/commontmpl"
+)
+
+// isHashedAsset returns whether r requests the asset name (e.g.
+// “/style.css”) with its version in the query, as the pages link it
+// (see commontmpl.AssetVersion).
+func isHashedAsset(r *http.Request, name string) bool {
+	base := path.Base(name)
+	return r.URL.RawQuery != "" &&
+		bundled.Asset(base) != "" &&
+		r.URL.RawQuery == commontmpl.AssetVersion(base)
+}
+
+// FileETag sets the ETag header of a response serving the file name
+// of the serving directory in the given content encoding (empty for
+// none), where hash is the content hash of the file (see
+// blob.ETagManifest). It returns whether the If-None-Match header of r
+// matches, in which case the caller should reply with 304 Not Modified
+// instead of serving the file.
+//
+// Hashed assets get a strong ETag (which differs between content
+// encodings) and may be cached indefinitely. All other files, in
+// particular pages, get a weak ETag, which stays the same when debiman
+// rewrites a page with identical content.
+func FileETag(w http.ResponseWriter, r *http.Request, name, hash, encoding string) bool {
+	var etag string
+	if isHashedAsset(r, name) {
+		etag = `"` + hash
+		if encoding != "" {
+			etag += "-" + encoding
+		}
+		etag += `"`
+		setCacheHeaders(w, immutableMaxAge, true)
+	} else {
+		etag = `W/"` + hash + `"`
+	}
+	w.Header().Set("ETag", etag)
+	return etagMatch(r.Header.Get("If-None-Match"), etag)
+}
+
+// etagMatch returns whether the If-None-Match header value
+// ifNoneMatch matches etag, using the weak comparison of RFC 7232.
+func etagMatch(ifNoneMatch, etag string) bool {
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+package aux
+
+import (
+	"container/list"
+	"sync"
+)
+
+// FileCache is an in-memory least recently used cache of the contents
+// of files served from the serving directory, bounded by their total
+// size. Entries are keyed by path and carry a validator (e.g. the
+// content hash from the ETag manifest), so that files which were
+// replaced in the meantime are not served from the cache. A nil
+// *FileCache caches nothing.
+type FileCache struct {
+	maxBytes int64
+
+	mu     sync.Mutex
+	bytes  int64
+	lru    *list.List // of *fileCacheItem, most recently used first
+	items  map[string]*list.Element
+	hits   uint64
+	misses uint64
+}
+
+type fileCacheItem struct {
+	path      string
+	validator string
+	content   []byte
+}
+
+// NewFileCache returns a FileCache holding at most maxBytes bytes of
+// file contents, or nil if maxBytes is not positive.
+func NewFileCache(maxBytes int64) *FileCache {
+	if maxBytes <= 0 {
+		return nil
+	}
+	return &FileCache{
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// Get returns the cached contents of the file at path if they were
+// added with the same validator.
+func (c *FileCache) Get(path, validator string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[path]
+	if !ok || el.Value.(*fileCacheItem).validator != validator {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.lru.MoveToFront(el)
+	return el.Value.(*fileCacheItem).content, true
+}
+
+// Add caches content as the contents of the file at path, replacing
+// earlier contents, and evicts the least recently used files until the
+// cache fits. Files larger than the cache are not cached. content must
+// not be modified afterwards.
+func (c *FileCache) Add(path, validator string, content []byte) {
+	if c == nil || int64(len(content)) > c.maxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[path]; ok {
+		c.remove(el)
+	}
+	c.items[path] = c.lru.PushFront(&fileCacheItem{path: path, validator: validator, content: content})
+	c.bytes += int64(len(content))
+	for c.bytes > c.maxBytes {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *FileCache) remove(el *list.Element) {
+	item := c.lru.Remove(el).(*fileCacheItem)
+	delete(c.items, item.path)
+	c.bytes -= int64(len(item.content))
+}
+
+// Stats returns the number of Get calls which did (hits) and did not
+// (misses) find the file in the cache, and the number of bytes
+// currently cached.
+func (c *FileCache) Stats() (hits, misses uint64, bytes int64) {
+	if c == nil {
+		return 0, 0, 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses, c.bytes
+}
@@ -0,0 +1,66 @@
+package aux
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFileCache(t *testing.T) {
+	c := NewFileCache(10)
+	page := func(b byte) []byte { return bytes.Repeat([]byte{b}, 4) }
+
+	if _, ok := c.Get("jessie/i3-wm/i3.1.en.html.gz", "h1"); ok {
+		t.Fatalf("Get() on empty cache unexpectedly succeeded")
+	}
+	c.Add("jessie/i3-wm/i3.1.en.html.gz", "h1", page('a'))
+	if got, ok := c.Get("jessie/i3-wm/i3.1.en.html.gz", "h1"); !ok || !bytes.Equal(got, page('a')) {
+		t.Fatalf("Get() = %q, %v, want %q, true", got, ok, page('a'))
+	}
+	// A replaced file (different content hash) is not served.
+	if _, ok := c.Get("jessie/i3-wm/i3.1.en.html.gz", "h2"); ok {
+		t.Fatalf("Get() with a different validator unexpectedly succeeded")
+	}
+	c.Add("jessie/i3-wm/i3.1.en.html.gz", "h2", page('b'))
+	if got, ok := c.Get("jessie/i3-wm/i3.1.en.html.gz", "h2"); !ok || !bytes.Equal(got, page('b')) {
+		t.Fatalf("Get() after replacing = %q, %v, want %q, true", got, ok, page('b'))
+	}
+
+	// The third file exceeds the size bound, so the least recently
+	// used one is evicted.
+	c.Add("jessie/i3-wm/i3-msg.1.en.html.gz", "h3", page('c'))
+	c.Get("jessie/i3-wm/i3.1.en.html.gz", "h2")
+	c.Add("jessie/i3lock/i3lock.1.en.html.gz", "h4", page('d'))
+	for path, want := range map[string]bool{
+		"jessie/i3-wm/i3.1.en.html.gz":      true,
+		"jessie/i3-wm/i3-msg.1.en.html.gz":  false,
+		"jessie/i3lock/i3lock.1.en.html.gz": true,
+	} {
+		c.mu.Lock()
+		_, got := c.items[path]
+		c.mu.Unlock()
+		if got != want {
+			t.Errorf("%s cached = %v, want %v", path, got, want)
+		}
+	}
+
+	// Files larger than the cache are never cached.
+	c.Add("sid/texlive-doc/huge.1.en.html.gz", "h5", make([]byte, 11))
+	if _, ok := c.Get("sid/texlive-doc/huge.1.en.html.gz", "h5"); ok {
+		t.Errorf("file larger than the cache unexpectedly cached")
+	}
+
+	hits, misses, size := c.Stats()
+	if hits != 3 || misses != 3 {
+		t.Errorf("Stats() = %d hits, %d misses, want 3, 3", hits, misses)
+	}
+	if size != 8 {
+		t.Errorf("Stats() = %d bytes, want 8", size)
+	}
+
+	// A nil cache (see NewFileCache) caches nothing.
+	c = NewFileCache(0)
+	c.Add("jessie/i3-wm/i3.1.en.html.gz", "h1", page('a'))
+	if _, ok := c.Get("jessie/i3-wm/i3.1.en.html.gz", "h1"); ok {
+		t.Errorf("disabled cache unexpectedly returned a file")
+	}
+}
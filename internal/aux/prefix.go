@@ -0,0 +1,106 @@
+package aux
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/Debian/debiman/internal/redirect"
+)
+
+// maxPrefixMatches is the number of matches HandleRedirect offers for
+// an ambiguous prefix (see Server.PrefixRedirects).
+const maxPrefixMatches = 10
+
+// PrefixMatch is a manpage or binary package whose name starts with
+// the requested (unknown) name.
+type PrefixMatch struct {
+	Name string // e.g. “coreutils”
+	Path string // relative to the base URL, e.g. “/stretch/coreutils/index.html”
+
+	// Package is true if Path refers to the page of binary package
+	// Name, false if it refers to manpage Name.
+	Package bool
+}
+
+// suggestPackages returns a sorted slice of the binary packages found
+// in idx and the (sorted) suites which contain each of them.
+func suggestPackages(idx redirect.Index) ([]string, map[string][]string) {
+	present := make(map[string]map[string]bool)
+	for _, entries := range idx.Entries {
+		for _, entry := range entries {
+			pkg := idx.URLCase.Name(entry.Binarypkg)
+			if present[pkg] == nil {
+				present[pkg] = make(map[string]bool)
+			}
+			present[pkg][entry.Suite] = true
+		}
+	}
+
+	pkgs := make([]string, 0, len(present))
+	suites := make(map[string][]string, len(present))
+	for pkg, s := range present {
+		pkgs = append(pkgs, pkg)
+		for suite := range s {
+			suites[pkg] = append(suites[pkg], suite)
+		}
+		sort.Strings(suites[pkg])
+	}
+	sort.Strings(pkgs)
+	return pkgs, suites
+}
+
+// fromPrefix returns the elements of sorted starting with the first
+// element which is not smaller than prefix. Callers iterate until an
+// element does not start with prefix.
+func fromPrefix(sorted []string, prefix string) []string {
+	return sorted[sort.SearchStrings(sorted, prefix):]
+}
+
+// prefixMatches returns the manpages and binary packages whose names
+// start with prefix (at most maxPrefixMatches+1, so that callers can
+// tell whether there are more). ref is the suite of the referrer, see
+// redirect.Index.PreferredSuite.
+func (s *Server) prefixMatches(snap *snapshot, prefix, ref string) []PrefixMatch {
+	prefix = snap.idx.URLCase.Name(prefix)
+	var matches []PrefixMatch
+	seen := make(map[string]bool)
+	// sortedNames contains one <name>.<section> entry per section.
+	for _, ns := range fromPrefix(snap.sortedNames, prefix) {
+		if !strings.HasPrefix(ns, prefix) {
+			break
+		}
+		name := ns[:strings.LastIndex(ns, ".")]
+		if seen[name] || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		seen[name] = true
+		matches = append(matches, PrefixMatch{Name: name, Path: "/" + name})
+		if len(matches) > maxPrefixMatches {
+			return matches
+		}
+	}
+	for _, pkg := range fromPrefix(snap.sortedPkgs, prefix) {
+		if !strings.HasPrefix(pkg, prefix) || len(matches) > maxPrefixMatches {
+			break
+		}
+		suite := snap.idx.PreferredSuite(snap.pkgSuites[pkg], ref)
+		matches = append(matches, PrefixMatch{
+			Name:    pkg,
+			Path:    "/" + suite + "/" + pkg + "/index.html",
+			Package: true,
+		})
+	}
+	return matches
+}
+
+// prefixCandidate returns the requested name if r is eligible for
+// prefix matching, i.e. if it requests nothing but a name, e.g.
+// “/coreut”.
+func prefixCandidate(r *http.Request) (string, bool) {
+	name := strings.TrimPrefix(r.URL.Path, "/")
+	if name == "" || strings.ContainsAny(name, "/.()") {
+		return "", false
+	}
+	return name, true
+}
@@ -0,0 +1,101 @@
+package aux
+
+import (
+	"math/rand"
+	"net/http"
+
+	"github.com/Debian/debiman/internal/commontmpl"
+	"github.com/Debian/debiman/internal/redirect"
+)
+
+// randomManpages are the manpages HandleRandom picks from: one entry
+// per manpage (preferring English, leaving out aliases), grouped by
+// suite.
+type randomManpages struct {
+	entries []*redirect.IndexEntry
+
+	// suites maps each suite (codename) to its range of entries.
+	suites map[string][2]int
+}
+
+func newRandomManpages(idx redirect.Index) randomManpages {
+	bySuite := make(map[string][]*redirect.IndexEntry)
+	total := 0
+	best := make(map[[3]string]*redirect.IndexEntry)
+	for _, entries := range idx.Entries {
+		// All entries of a manpage share its name, so deduplicating
+		// the languages (and architectures) within entries suffices.
+		for key := range best {
+			delete(best, key)
+		}
+		for i := range entries {
+			e := &entries[i]
+			if e.Target != "" {
+				continue // an alias of another manpage
+			}
+			key := [3]string{e.Suite, e.Binarypkg, e.Section}
+			if prev, ok := best[key]; ok && (prev.Language == "en" || e.Language != "en") {
+				continue
+			}
+			if _, ok := best[key]; !ok {
+				total++
+			}
+			best[key] = e
+		}
+		for key, e := range best {
+			bySuite[key[0]] = append(bySuite[key[0]], e)
+		}
+	}
+
+	r := randomManpages{
+		entries: make([]*redirect.IndexEntry, 0, total),
+		suites:  make(map[string][2]int, len(bySuite)),
+	}
+	for suite, entries := range bySuite {
+		start := len(r.entries)
+		r.entries = append(r.entries, entries...)
+		r.suites[suite] = [2]int{start, len(r.entries)}
+	}
+	return r
+}
+
+// pick returns a random manpage of suite (any suite if empty), or nil
+// if the suite contains no manpages.
+func (r randomManpages) pick(suite string) *redirect.IndexEntry {
+	start, end := 0, len(r.entries)
+	if suite != "" {
+		rng := r.suites[suite]
+		start, end = rng[0], rng[1]
+	}
+	if start == end {
+		return nil
+	}
+	return r.entries[start+rand.Intn(end-start)]
+}
+
+// HandleRandom redirects to a random manpage, optionally of the suite
+// given in the suite= query parameter (a suite name or codename). The
+// redirect is neither cached nor indexed by search engines.
+func (s *Server) HandleRandom(w http.ResponseWriter, r *http.Request) {
+	snap := s.snapshot()
+	setIndexVersion(w, snap)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Robots-Tag", "noindex")
+
+	suite := r.FormValue("suite")
+	if suite != "" {
+		codename, ok := snap.idx.Suites[suite]
+		if !ok {
+			http.Error(w, "Unknown suite", http.StatusNotFound)
+			return
+		}
+		suite = codename
+	}
+	e := snap.random.pick(suite)
+	if e == nil {
+		http.Error(w, "No manpages found", http.StatusNotFound)
+		return
+	}
+	s.preload(w)
+	http.Redirect(w, r, commontmpl.BaseURLPath()+e.ServingPath(".html"), http.StatusTemporaryRedirect)
+}
@@ -0,0 +1,83 @@
+package aux
+
+import (
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/Debian/debiman/internal/redirect"
+)
+
+func TestHandleRandom(t *testing.T) {
+	// Defined by debiman-auxserver, required for HandleRandom.
+	if flag.Lookup("base_url") == nil {
+		flag.String("base_url", "https://manpages.debian.org", "")
+	}
+
+	idx := i3OnlyIdx // copy
+	idx.Suites = map[string]string{
+		"jessie":  "jessie",
+		"stretch": "stretch",
+		"stable":  "stretch",
+		"sid":     "sid",
+	}
+	idx.Entries = map[string][]redirect.IndexEntry{
+		"i3": i3OnlyIdx.Entries["i3"],
+		"ls": []redirect.IndexEntry{
+			{Name: "ls", Suite: "stretch", Binarypkg: "coreutils", Section: "1", Language: "de"},
+			{Name: "ls", Suite: "stretch", Binarypkg: "coreutils", Section: "1", Language: "en"},
+			{Name: "ls", Suite: "stretch", Binarypkg: "coreutils", Section: "1", Language: "fr"},
+		},
+		"dir": []redirect.IndexEntry{
+			{Name: "dir", Suite: "stretch", Binarypkg: "coreutils", Section: "1", Language: "en", Target: "stretch/coreutils/ls.1.en"},
+		},
+	}
+	s := NewServer(idx, nil, "")
+	if got, want := len(s.snapshot().random.entries), 2; got != want {
+		t.Fatalf("got %d random manpages, want %d (one per manpage, without aliases)", got, want)
+	}
+
+	for _, entry := range []struct {
+		query string
+		code  int
+		want  map[string]bool
+	}{
+		{"", http.StatusTemporaryRedirect, map[string]bool{
+			"/jessie/i3-wm/i3.1.en.html":      true,
+			"/stretch/coreutils/ls.1.en.html": true,
+		}},
+		{"suite=stable", http.StatusTemporaryRedirect, map[string]bool{
+			"/stretch/coreutils/ls.1.en.html": true,
+		}},
+		{"suite=sid", http.StatusNotFound, nil},
+		{"suite=wheezy", http.StatusNotFound, nil},
+	} {
+		seen := make(map[string]bool)
+		for i := 0; i < 50; i++ {
+			rec := httptest.NewRecorder()
+			s.HandleRandom(rec, &http.Request{URL: &url.URL{Path: "/random", RawQuery: entry.query}})
+			if got, want := rec.Code, entry.code; got != want {
+				t.Fatalf("%q: unexpected HTTP status: got %d, want %d", entry.query, got, want)
+			}
+			if got, want := rec.Header().Get("Cache-Control"), "no-store"; got != want {
+				t.Errorf("%q: unexpected Cache-Control: got %q, want %q", entry.query, got, want)
+			}
+			if got, want := rec.Header().Get("X-Robots-Tag"), "noindex"; got != want {
+				t.Errorf("%q: unexpected X-Robots-Tag: got %q, want %q", entry.query, got, want)
+			}
+			if loc := rec.Header().Get("Location"); loc != "" {
+				seen[loc] = true
+			}
+		}
+		for loc := range seen {
+			if !entry.want[loc] {
+				t.Errorf("%q: unexpected redirect to %q", entry.query, loc)
+			}
+		}
+		if len(entry.want) > 1 && len(seen) != len(entry.want) {
+			t.Errorf("%q: redirected to %d of %d manpages in 50 requests", entry.query, len(seen), len(entry.want))
+		}
+	}
+}
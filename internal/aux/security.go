@@ -0,0 +1,54 @@
+package aux
+
+import "net/http"
+
+// DefaultContentSecurityPolicy allows everything the pages debiman
+// renders need: the stylesheets, fonts and icons of the site itself,
+// the inline script of the suite fallback banner and the inline styles
+// of -inline_css (both need 'unsafe-inline', as the pages are static
+// and cannot carry a nonce), the web app manifest and the /jump form.
+// Everything else, in particular loading resources from other origins
+// and framing the pages on other sites, is denied.
+const DefaultContentSecurityPolicy = "default-src 'none'; " +
+	"script-src 'self' 'unsafe-inline'; " +
+	"style-src 'self' 'unsafe-inline'; " +
+	"img-src 'self'; " +
+	"font-src 'self'; " +
+	"manifest-src 'self'; " +
+	"form-action 'self'; " +
+	"base-uri 'self'; " +
+	"frame-ancestors 'self'"
+
+// DefaultReferrerPolicy sends the full URL only to the site itself,
+// so that other sites do not learn which manpage linked to them.
+const DefaultReferrerPolicy = "strict-origin-when-cross-origin"
+
+// SecurityHeaders are the security-related headers which Wrap adds to
+// every response. X-Content-Type-Options: nosniff is always added:
+// every file debiman writes is served with its correct Content-Type
+// (see blob.ContentType).
+type SecurityHeaders struct {
+	// ContentSecurityPolicy is the value of the Content-Security-Policy
+	// header, or empty to not send one.
+	ContentSecurityPolicy string
+
+	// ReferrerPolicy is the value of the Referrer-Policy header, or
+	// empty to not send one.
+	ReferrerPolicy string
+}
+
+// Wrap returns a handler which adds the headers to the responses of
+// h, before h runs, so that h can still override or remove them.
+func (s SecurityHeaders) Wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := w.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		if s.ContentSecurityPolicy != "" {
+			header.Set("Content-Security-Policy", s.ContentSecurityPolicy)
+		}
+		if s.ReferrerPolicy != "" {
+			header.Set("Referrer-Policy", s.ReferrerPolicy)
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,44 @@
+package aux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/embed" {
+			w.Header().Del("Content-Security-Policy")
+		}
+		w.Write([]byte("hello"))
+	})
+
+	table := []struct {
+		name    string
+		headers SecurityHeaders
+		URL     string
+		wantCSP string
+		wantRP  string
+	}{
+		{"defaults", SecurityHeaders{DefaultContentSecurityPolicy, DefaultReferrerPolicy}, "/", DefaultContentSecurityPolicy, DefaultReferrerPolicy},
+		{"disabled", SecurityHeaders{}, "/", "", ""},
+		{"overridden", SecurityHeaders{DefaultContentSecurityPolicy, DefaultReferrerPolicy}, "/embed", "", DefaultReferrerPolicy},
+	}
+	for _, entry := range table {
+		t.Run(entry.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			entry.headers.Wrap(h).ServeHTTP(rec, httptest.NewRequest("GET", entry.URL, nil))
+			resp := rec.Result()
+			if got, want := resp.Header.Get("X-Content-Type-Options"), "nosniff"; got != want {
+				t.Errorf("unexpected X-Content-Type-Options header: got %q, want %q", got, want)
+			}
+			if got, want := resp.Header.Get("Content-Security-Policy"), entry.wantCSP; got != want {
+				t.Errorf("unexpected Content-Security-Policy header: got %q, want %q", got, want)
+			}
+			if got, want := resp.Header.Get("Referrer-Policy"), entry.wantRP; got != want {
+				t.Errorf("unexpected Referrer-Policy header: got %q, want %q", got, want)
+			}
+		})
+	}
+}
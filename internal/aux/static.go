@@ -0,0 +1,40 @@
+package aux
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/Debian/debiman/internal/blob"
+	"github.com/Debian/debiman/internal/commontmpl"
+)
+
+// StaticHandler returns a handler which serves content as a file with
+// the given name. debiman-auxserver uses it for the icons and the web
+// app manifest, so that they are available even when the web server
+// forwards requests for them (e.g. /favicon.ico with a -base_url
+// path) instead of serving them from -serving_dir. Responses carry a
+// strong ETag (the hash of content), and requests which carry the
+// version of the asset in the query (as the pages link it, see
+// commontmpl.AssetVersion) may be cached indefinitely.
+func StaticHandler(name string, content []byte) http.Handler {
+	ctype := blob.ContentType(name)
+	modTime := time.Now()
+	version := commontmpl.AssetVersion(name)
+	h := sha256.Sum256(content)
+	etag := `"` + hex.EncodeToString(h[:]) + `"`
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ctype != "" {
+			w.Header().Set("Content-Type", ctype)
+		}
+		// http.ServeContent replies with 304 Not Modified if the
+		// If-None-Match header matches.
+		w.Header().Set("ETag", etag)
+		if r.URL.RawQuery == version {
+			setCacheHeaders(w, immutableMaxAge, true)
+		}
+		http.ServeContent(w, r, name, modTime, bytes.NewReader(content))
+	})
+}
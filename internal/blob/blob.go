@@ -0,0 +1,200 @@
+// Package blob implements the stores to which debiman can publish its
+// serving directory: another local directory, or an S3 bucket (e.g. as
+// the origin of a CDN such as CloudFront).
+package blob
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+)
+
+// Object describes a file of the serving directory.
+type Object struct {
+	// Name is the slash-separated path of the file relative to the
+	// serving directory, e.g. “jessie/cron/crontab.5.en.html.gz”.
+	Name string
+
+	// Key is the path under which the file is available via HTTP,
+	// e.g. “jessie/cron/crontab.5.en.html”. Web servers like nginx
+	// (gzip_static always) map the URL to the .gz file, whereas object
+	// stores need to store the file under Key and indicate
+	// ContentEncoding instead.
+	Key string
+
+	ContentType     string
+	ContentEncoding string
+
+	// Shadowed is true if the serving directory also contains an
+	// uncompressed file named Key (or, for zstd variants, a gzip
+	// variant). Object stores cannot negotiate the content encoding,
+	// so they store only the uncompressed (or gzip) file.
+	Shadowed bool
+}
+
+// Store is a destination for the files of a serving directory.
+type Store interface {
+	// Put stores the content of o, replacing any previous content.
+	Put(o Object, content io.Reader) error
+
+	// Delete removes o. Deleting an object which does not exist is
+	// not an error.
+	Delete(o Object) error
+}
+
+// contentTypes covers the files which debiman writes. Not all
+// mime.types files know about e.g. web app manifests, so these take
+// precedence over mime.TypeByExtension.
+var contentTypes = map[string]string{
+	".css":         "text/css; charset=utf-8",
+	".html":        "text/html; charset=utf-8",
+	".ico":         "image/x-icon",
+	".idx":         "application/octet-stream",
+	".js":          "text/javascript; charset=utf-8",
+	".json":        "application/json",
+	".png":         "image/png",
+	".svg":         "image/svg+xml",
+	".txt":         "text/plain; charset=utf-8",
+	".webmanifest": "application/manifest+json",
+	".xml":         "application/xml",
+}
+
+// encodedExts lists the extensions of files which are linked to
+// without their .gz suffix, i.e. which web servers serve with
+// Content-Encoding: gzip. Other .gz files (e.g. raw manpages and
+// sitemaps) are linked to including their .gz suffix.
+var encodedExts = map[string]bool{
+	".css":         true,
+	".html":        true,
+	".json":        true,
+	".txt":         true,
+	".webmanifest": true,
+}
+
+func contentType(name string) string {
+	ext := path.Ext(name)
+	if ctype, ok := contentTypeOverrides[ext]; ok {
+		return ctype
+	}
+	if ctype, ok := contentTypes[ext]; ok {
+		return ctype
+	}
+	if ctype := mime.TypeByExtension(ext); ctype != "" {
+		return ctype
+	}
+	return "application/octet-stream"
+}
+
+// contentTypeOverrides take precedence over contentTypes, see
+// OverrideContentTypes.
+var contentTypeOverrides map[string]string
+
+// ContentType returns the Content-Type of the file name when it is
+// served, e.g. “text/html; charset=utf-8” for “ls.1.en.html”. name must
+// not have a compression suffix (see Describe).
+func ContentType(name string) string {
+	return contentType(name)
+}
+
+// OverrideContentTypes makes ContentType (and Describe) use the given
+// content types, keyed by extension (e.g. “.txt”), instead of the
+// built-in ones. It must be called before files are described.
+func OverrideContentTypes(overrides map[string]string) {
+	contentTypeOverrides = overrides
+}
+
+// ParseContentTypes parses a comma-separated list of extension=type
+// pairs, e.g. “.txt=text/plain; charset=utf-8,.md=text/markdown”, for
+// OverrideContentTypes.
+func ParseContentTypes(s string) (map[string]string, error) {
+	result := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		idx := strings.Index(pair, "=")
+		if idx == -1 {
+			return nil, fmt.Errorf("invalid content type %q: expected extension=type, e.g. .txt=text/plain", pair)
+		}
+		ext, ctype := strings.TrimSpace(pair[:idx]), strings.TrimSpace(pair[idx+1:])
+		if !strings.HasPrefix(ext, ".") || len(ext) < 2 || strings.Contains(ext[1:], ".") {
+			return nil, fmt.Errorf("invalid extension %q: expected e.g. .txt", ext)
+		}
+		if _, _, err := mime.ParseMediaType(ctype); err != nil {
+			return nil, fmt.Errorf("invalid content type %q for %s: %v", ctype, ext, err)
+		}
+		result[ext] = ctype
+	}
+	return result, nil
+}
+
+// Describe returns the Object for the slash-separated name.
+func Describe(name string) Object {
+	o := Object{Name: name, Key: name}
+	if plain := strings.TrimSuffix(name, ".zst"); plain != name && encodedExts[path.Ext(plain)] {
+		// zstd variant, see write.ZstdVariant
+		o.Key = plain
+		o.ContentType = contentType(plain)
+		o.ContentEncoding = "zstd"
+		return o
+	}
+	if !strings.HasSuffix(name, ".gz") {
+		o.ContentType = contentType(name)
+		return o
+	}
+	plain := strings.TrimSuffix(name, ".gz")
+	if !encodedExts[path.Ext(plain)] {
+		o.ContentType = "application/gzip"
+		return o
+	}
+	o.Key = plain
+	o.ContentType = contentType(plain)
+	o.ContentEncoding = "gzip"
+	return o
+}
+
+// Open returns the Store for target, which is either an S3 URL of the
+// form s3://bucket/prefix (see NewS3) or a local directory.
+func Open(target string) (Store, error) {
+	if !strings.HasPrefix(target, "s3://") {
+		if strings.Contains(target, "://") {
+			return nil, fmt.Errorf("unsupported store %q: expected s3://bucket/prefix or a directory", target)
+		}
+		return Dir(target), nil
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid store %q: missing bucket name", target)
+	}
+	region := u.Query().Get("region")
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	s := &S3{
+		Bucket:          u.Host,
+		Prefix:          strings.Trim(u.Path, "/"),
+		Region:          region,
+		Endpoint:        strings.TrimSuffix(u.Query().Get("endpoint"), "/"),
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if s.AccessKeyID == "" || s.SecretAccessKey == "" {
+		return nil, fmt.Errorf("store %q: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set", target)
+	}
+	return s, nil
+}
@@ -0,0 +1,37 @@
+package blob
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/Debian/debiman/internal/write"
+)
+
+// Dir is a Store which mirrors the serving directory into a local
+// directory, e.g. a network file system mounted on the web servers.
+// Files are stored under their Name, i.e. precompressed files keep
+// their .gz suffix.
+type Dir string
+
+func (d Dir) path(o Object) string {
+	return filepath.Join(string(d), filepath.FromSlash(o.Name))
+}
+
+func (d Dir) Put(o Object, content io.Reader) error {
+	dest := d.path(o)
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	return write.Atomically(dest, false, func(w io.Writer) error {
+		_, err := io.Copy(w, content)
+		return err
+	})
+}
+
+func (d Dir) Delete(o Object) error {
+	if err := os.Remove(d.path(o)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
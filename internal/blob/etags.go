@@ -0,0 +1,91 @@
+package blob
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ETagManifestName is the name of the ETag manifest which debiman
+// writes to the serving directory with -etag_manifest. As a dot file,
+// it is not published itself.
+const ETagManifestName = ".etags.json.gz"
+
+// ETagManifestVersion is the version of the ETagManifest format.
+const ETagManifestVersion = 1
+
+// ETagManifest records the content hash of each file of a serving
+// directory, from which servers derive ETags that stay the same as
+// long as the content does, even if debiman rewrites the file. It is
+// stored as gzip-compressed JSON, e.g.:
+//
+//	{"version": 1, "files": {"jessie/cron/crontab.5.en.html": {
+//	  "name": "jessie/cron/crontab.5.en.html.gz", "size": 3012,
+//	  "mod_time": 1495987491000000000, "sha256": "9f86d0…"}}}
+type ETagManifest struct {
+	Version int `json:"version"`
+
+	// Files is keyed by Object.Key, i.e. by the path under which the
+	// file is available via HTTP. Precompressed variants share the
+	// entry of their Key.
+	Files map[string]ETagFile `json:"files"`
+}
+
+// ETagFile is an entry of an ETagManifest.
+type ETagFile struct {
+	// Name is the file which was hashed (see Object.Name), e.g. the
+	// gzip variant of a page.
+	Name string `json:"name"`
+
+	// Size and ModTime (Unix nanoseconds) describe Name when it was
+	// hashed, so that entries of files which changed since can be
+	// recognized as stale.
+	Size    int64 `json:"size"`
+	ModTime int64 `json:"mod_time"`
+
+	// SHA256 is the hex-encoded SHA-256 hash of the uncompressed
+	// content of Name.
+	SHA256 string `json:"sha256"`
+}
+
+// ReadETagManifest reads the ETagManifest stored at path.
+func ReadETagManifest(path string) (*ETagManifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var m ETagManifest
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, err
+	}
+	if m.Version != ETagManifestVersion {
+		return nil, fmt.Errorf("unsupported ETag manifest version %d (want %d)", m.Version, ETagManifestVersion)
+	}
+	return &m, nil
+}
+
+// Lookup returns the content hash of the file available under key
+// (see Object.Key) in servingDir, unless the file was changed after
+// the manifest was written.
+func (m *ETagManifest) Lookup(servingDir, key string) (string, bool) {
+	if m == nil {
+		return "", false
+	}
+	e, ok := m.Files[key]
+	if !ok {
+		return "", false
+	}
+	st, err := os.Stat(filepath.Join(servingDir, filepath.FromSlash(e.Name)))
+	if err != nil || st.Size() != e.Size || st.ModTime().UnixNano() != e.ModTime {
+		return "", false
+	}
+	return e.SHA256, true
+}
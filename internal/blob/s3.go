@@ -0,0 +1,199 @@
+package blob
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3 is a Store which uploads objects to an Amazon S3 bucket (or an
+// S3-compatible service like minio) via the REST API, authenticated
+// using AWS Signature Version 4. Precompressed files are stored under
+// their Key with a Content-Encoding: gzip header, so that the bucket
+// can be served directly (e.g. via CloudFront) using the same URLs as
+// a web server serving the serving directory.
+type S3 struct {
+	Bucket string
+
+	// Prefix (without leading or trailing slash) is prepended to all
+	// keys, e.g. “manpages” for s3://bucket/manpages.
+	Prefix string
+
+	Region string
+
+	// Endpoint is the base URL of an S3-compatible service, e.g.
+	// http://localhost:9000, which is addressed using path-style
+	// URLs. If empty, AWS virtual-hosted-style URLs are used.
+	Endpoint string
+
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+
+	// Client is used for all requests. If nil, http.DefaultClient is
+	// used.
+	Client *http.Client
+
+	// now is overridden in tests.
+	now func() time.Time
+}
+
+// uriEncode escapes s as required for the canonical request: all
+// characters but the unreserved ones (and slashes, unless encodeSlash
+// is set) are percent-encoded.
+func uriEncode(s string, encodeSlash bool) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			buf.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&buf, "%%%02X", c)
+	}
+	return buf.String()
+}
+
+// objectURL returns the URL under which key is stored, and its escaped
+// path as used in the canonical request.
+func (s *S3) objectURL(key string) (string, string) {
+	if s.Prefix != "" {
+		key = s.Prefix + "/" + key
+	}
+	if s.Endpoint != "" {
+		escaped := "/" + uriEncode(s.Bucket, true) + "/" + uriEncode(key, false)
+		return s.Endpoint + escaped, escaped
+	}
+	escaped := "/" + uriEncode(key, false)
+	return "https://" + s.Bucket + ".s3." + s.Region + ".amazonaws.com" + escaped, escaped
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func sha256Hex(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+// sign adds the x-amz-date and Authorization headers to req, signing
+// all headers set on req (plus Host). escapedPath is the canonical URI
+// and payloadHash the hex-encoded SHA-256 of the request body.
+func (s *S3) sign(req *http.Request, escapedPath, payloadHash string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	if s.SessionToken != "" {
+		req.Header.Set("x-amz-security-token", s.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders bytes.Buffer
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		escapedPath,
+		"", // canonical query string: none of our requests use one
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + s.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretAccessKey), date)
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 "+
+		"Credential="+s.AccessKeyID+"/"+scope+", "+
+		"SignedHeaders="+signedHeaders+", "+
+		"Signature="+signature)
+}
+
+func (s *S3) do(method, key string, body []byte, header http.Header) error {
+	u, escapedPath := s.objectURL(key)
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	s.sign(req, escapedPath, sha256Hex(body), now())
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 || (method == "DELETE" && resp.StatusCode == http.StatusNotFound) {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("%s %s: unexpected HTTP status %v: %s", method, u, resp.Status, strings.TrimSpace(string(msg)))
+}
+
+func (s *S3) Put(o Object, content io.Reader) error {
+	if o.Shadowed {
+		return nil
+	}
+	b, err := ioutil.ReadAll(content)
+	if err != nil {
+		return err
+	}
+	header := make(http.Header)
+	header.Set("Content-Type", o.ContentType)
+	if o.ContentEncoding != "" {
+		header.Set("Content-Encoding", o.ContentEncoding)
+	}
+	return s.do("PUT", o.Key, b, header)
+}
+
+func (s *S3) Delete(o Object) error {
+	if o.Shadowed {
+		return nil
+	}
+	return s.do("DELETE", o.Key, nil, nil)
+}
@@ -0,0 +1,9 @@
+package bundled
+
+// Table of contents
+var selftest = map[string]string{
+	"assets/selftest/i3lock.1": selftest_0,
+	"assets/selftest/i3lock.1.html": selftest_1,
+}
+var selftest_0 = "\x2e\x64\x65\x20\x56\x62\x20\x5c\x22\x20\x42\x65\x67\x69\x6e\x20\x76\x65\x72\x62\x61\x74\x69\x6d\x20\x74\x65\x78\x74\x0a\x2e\x66\x74\x20\x43\x57\x0a\x2e\x6e\x66\x0a\x2e\x6e\x65\x20\x5c\x5c\x24\x31\x0a\x2e\x2e\x0a\x2e\x64\x65\x20\x56\x65\x20\x5c\x22\x20\x45\x6e\x64\x20\x76\x65\x72\x62\x61\x74\x69\x6d\x20\x74\x65\x78\x74\x0a\x2e\x66\x74\x20\x52\x0a\x2e\x66\x69\x0a\x2e\x2e\x0a\x0a\x2e\x54\x48\x20\x69\x33\x6c\x6f\x63\x6b\x20\x31\x20\x22\x4a\x41\x4e\x55\x41\x52\x59\x20\x32\x30\x31\x32\x22\x20\x4c\x69\x6e\x75\x78\x20\x22\x55\x73\x65\x72\x20\x4d\x61\x6e\x75\x61\x6c\x73\x22\x0a\x0a\x2e\x53\x48\x20\x4e\x41\x4d\x45\x0a\x69\x33\x6c\x6f\x63\x6b\x20\x5c\x2d\x20\x69\x6d\x70\x72\x6f\x76\x65\x64\x20\x73\x63\x72\x65\x65\x6e\x20\x6c\x6f\x63\x6b\x65\x72\x0a\x0a\x2e\x53\x48\x20\x53\x59\x4e\x4f\x50\x53\x49\x53\x0a\x2e\x42\x20\x69\x33\x6c\x6f\x63\x6b\x0a\x2e\x52\x42\x20\x5b\x5c\x7c\x5c\x2d\x76\x5c\x7c\x5d\x0a\x2e\x52\x42\x20\x5b\x5c\x7c\x5c\x2d\x63\x0a\x2e\x49\x52\x20\x63\x6f\x6c\x6f\x72\x20\x5c\x7c\x5d\x0a\x0a\x2e\x53\x48\x20\x44\x45\x53\x43\x52\x49\x50\x54\x49\x4f\x4e\x0a\x2e\x42\x20\x69\x33\x6c\x6f\x63\x6b\x0a\x69\x73\x20\x61\x20\x73\x69\x6d\x70\x6c\x65\x20\x73\x63\x72\x65\x65\x6e\x20\x6c\x6f\x63\x6b\x65\x72\x20\x6c\x69\x6b\x65\x20\x73\x6c\x6f\x63\x6b\x2e\x20\x41\x66\x74\x65\x72\x20\x73\x74\x61\x72\x74\x69\x6e\x67\x20\x69\x74\x2c\x20\x79\x6f\x75\x20\x77\x69\x6c\x6c\x20\x73\x65\x65\x20\x61\x20\x77\x68\x69\x74\x65\x0a\x73\x63\x72\x65\x65\x6e\x20\x28\x79\x6f\x75\x20\x63\x61\x6e\x20\x63\x6f\x6e\x66\x69\x67\x75\x72\x65\x20\x74\x68\x65\x20\x63\x6f\x6c\x6f\x72\x2f\x61\x6e\x20\x69\x6d\x61\x67\x65\x29\x2e\x20\x59\x6f\x75\x20\x63\x61\x6e\x20\x72\x65\x74\x75\x72\x6e\x20\x74\x6f\x20\x79\x6f\x75\x72\x20\x73\x63\x72\x65\x65\x6e\x20\x62\x79\x0a\x65\x6e\x74\x65\x72\x69\x6e\x67\x20\x79\x6f\x75\x72\x20\x70\x61\x73\x73\x77\x6f\x72\x64\x2e\x0a\x0a\x2e\x53\x48\x20\x49\x4d\x50\x52\x4f\x56\x45\x4d\x45\x4e\x54\x53\x0a\x0a\x2e\x49\x50\x20\x5c\x5b\x62\x75\x5d\x20\x32\x0a\x69\x33\x6c\x6f\x63\x6b\x20\x66\x6f\x72\x6b\x73\x2c\x20\x73\x6f\x20\x79\x6f\x75\x20\x63\x61\x6e\x20\x63\x6f\x6d\x62\x69\x6e\x65\x20\x69\x74\x20\x77\x69\x74\x68\x20\x61\x6e\x20\x61\x6c\x69\x61\x73\x20\x74\x6f\x20\x73\x75\x73\x70\x65\x6e\x64\x20\x74\x6f\x20\x52\x41\x4d\x20\x28\x72\x75\x6e\x20\x22\x69\x33\x6c\x6f\x63\x6b\x20\x26\x26\x20\x65\x63\x68\x6f\x20\x6d\x65\x6d\x20\x3e\x20\x2f\x73\x79\x73\x2f\x70\x6f\x77\x65\x72\x2f\x73\x74\x61\x74\x65\x22\x20\x74\x6f\x20\x67\x65\x74\x20\x61\x20\x6c\x6f\x63\x6b\x65\x64\x20\x73\x63\x72\x65\x65\x6e\x20\x61\x66\x74\x65\x72\x20\x77\x61\x6b\x69\x6e\x67\x20\x75\x70\x20\x79\x6f\x75\x72\x20\x63\x6f\x6d\x70\x75\x74\x65\x72\x20\x66\x72\x6f\x6d\x20\x73\x75\x73\x70\x65\x6e\x64\x20\x74\x6f\x20\x52\x41\x4d\x29\x0a\x2e\x49\x50\x20\x5c\x5b\x62\x75\x5d\x0a\x59\x6f\x75\x20\x63\x61\x6e\x20\x73\x70\x65\x63\x69\x66\x79\x20\x65\x69\x74\x68\x65\x72\x20\x61\x20\x62\x61\x63\x6b\x67\x72\x6f\x75\x6e\x64\x20\x63\x6f\x6c\x6f\x72\x20\x6f\x72\x20\x61\x20\x50\x4e\x47\x20\x69\x6d\x61\x67\x65\x20\x77\x68\x69\x63\x68\x20\x77\x69\x6c\x6c\x20\x62\x65\x20\x64\x69\x73\x70\x6c\x61\x79\x65\x64\x20\x77\x68\x69\x6c\x65\x20\x79\x6f\x75\x72\x20\x73\x63\x72\x65\x65\x6e\x20\x69\x73\x20\x6c\x6f\x63\x6b\x65\x64\x2e\x0a\x2e\x49\x50\x20\x5c\x5b\x62\x75\x5d\x0a\x59\x6f\x75\x20\x63\x61\x6e\x20\x73\x70\x65\x63\x69\x66\x79\x20\x77\x68\x65\x74\x68\x65\x72\x20\x69\x33\x6c\x6f\x63\x6b\x20\x73\x68\x6f\x75\x6c\x64\x20\x62\x65\x6c\x6c\x20\x75\x70\x6f\x6e\x20\x61\x20\x77\x72\x6f\x6e\x67\x20\x70\x61\x73\x73\x77\x6f\x72\x64\x2e\x0a\x2e\x49\x50\x20\x5c\x5b\x62\x75\x5d\x0a\x69\x33\x6c\x6f\x63\x6b\x20\x75\x73\x65\x73\x20\x50\x41\x4d\x20\x61\x6e\x64\x20\x74\x68\x65\x72\x65\x66\x6f\x72\x65\x20\x69\x73\x20\x63\x6f\x6d\x70\x61\x74\x69\x62\x6c\x65\x20\x77\x69\x74\x68\x20\x4c\x44\x41\x50\x2c\x20\x65\x74\x63\x2e\x0a\x0a\x0a\x2e\x53\x48\x20\x4f\x50\x54\x49\x4f\x4e\x53\x0a\x2e\x54\x50\x0a\x2e\x42\x20\x5c\x2d\x76\x2c\x20\x5c\x2d\x5c\x2d\x76\x65\x72\x73\x69\x6f\x6e\x0a\x44\x69\x73\x70\x6c\x61\x79\x20\x74\x68\x65\x20\x76\x65\x72\x73\x69\x6f\x6e\x20\x6f\x66\x20\x79\x6f\x75\x72\x0a\x2e\x42\x20\x69\x33\x6c\x6f\x63\x6b\x0a\x0a\x2e\x54\x50\x0a\x2e\x42\x49\x20\x5c\x2d\x63\x5c\x20\x20\x72\x72\x67\x67\x62\x62\x20\x5c\x66\x52\x2c\x5c\x20\x5c\x66\x42\x5c\x2d\x5c\x2d\x63\x6f\x6c\x6f\x72\x3d\x20\x72\x72\x67\x67\x62\x62\x0a\x54\x75\x72\x6e\x20\x74\x68\x65\x20\x73\x63\x72\x65\x65\x6e\x20\x69\x6e\x74\x6f\x20\x74\x68\x65\x20\x67\x69\x76\x65\x6e\x20\x63\x6f\x6c\x6f\x72\x20\x69\x6e\x73\x74\x65\x61\x64\x20\x6f\x66\x20\x77\x68\x69\x74\x65\x2e\x20\x43\x6f\x6c\x6f\x72\x20\x6d\x75\x73\x74\x20\x62\x65\x20\x67\x69\x76\x65\x6e\x20\x69\x6e\x20\x33\x2d\x62\x79\x74\x65\x0a\x66\x6f\x72\x6d\x61\x74\x3a\x20\x72\x72\x67\x67\x62\x62\x20\x28\x69\x2e\x65\x2e\x20\x66\x66\x30\x30\x30\x30\x20\x69\x73\x20\x72\x65\x64\x29\x2e\x0a\x0a\x2e\x53\x48\x20\x44\x50\x4d\x53\x0a\x0a\x54\x68\x65\x20\x5c\x2d\x64\x20\x28\x5c\x2d\x5c\x2d\x64\x70\x6d\x73\x29\x20\x6f\x70\x74\x69\x6f\x6e\x2e\x0a\x0a\x2e\x56\x62\x20\x36\x0a\x5c\x26\x09\x76\x65\x72\x62\x61\x74\x69\x6d\x0a\x2e\x56\x65\x0a\x0a\x2e\x53\x48\x20\x53\x45\x45\x20\x41\x4c\x53\x4f\x0a\x2e\x49\x52\x20\x78\x61\x75\x74\x6f\x6c\x6f\x63\x6b\x28\x31\x29\x0a\x5c\x2d\x20\x75\x73\x65\x20\x69\x33\x6c\x6f\x63\x6b\x20\x61\x73\x20\x79\x6f\x75\x72\x20\x73\x63\x72\x65\x65\x6e\x20\x73\x61\x76\x65\x72\x0a\x0a\x2e\x53\x48\x20\x41\x55\x54\x48\x4f\x52\x0a\x4d\x69\x63\x68\x61\x65\x6c\x20\x53\x74\x61\x70\x65\x6c\x62\x65\x72\x67\x20\x3c\x6d\x69\x63\x68\x61\x65\x6c\x2b\x69\x33\x6c\x6f\x63\x6b\x40\x65\x78\x61\x6d\x70\x6c\x65\x2e\x69\x6e\x76\x61\x6c\x69\x64\x3e\x0a"
+var selftest_1 = "\x3c\x21\x44\x4f\x43\x54\x59\x50\x45\x20\x68\x74\x6d\x6c\x3e\x0a\x3c\x68\x74\x6d\x6c\x20\x6c\x61\x6e\x67\x3d\x22\x75\x6e\x64\x22\x3e\x0a\x3c\x68\x65\x61\x64\x3e\x0a\x3c\x6d\x65\x74\x61\x20\x63\x68\x61\x72\x73\x65\x74\x3d\x22\x55\x54\x46\x2d\x38\x22\x3e\x0a\x3c\x6d\x65\x74\x61\x20\x6e\x61\x6d\x65\x3d\x22\x76\x69\x65\x77\x70\x6f\x72\x74\x22\x20\x63\x6f\x6e\x74\x65\x6e\x74\x3d\x22\x77\x69\x64\x74\x68\x3d\x64\x65\x76\x69\x63\x65\x2d\x77\x69\x64\x74\x68\x2c\x20\x69\x6e\x69\x74\x69\x61\x6c\x2d\x73\x63\x61\x6c\x65\x3d\x31\x2e\x30\x22\x3e\x0a\x3c\x74\x69\x74\x6c\x65\x3e\x69\x33\x6c\x6f\x63\x6b\x28\x31\x29\x20\xe2\x80\x94\x20\x64\x65\x62\x69\x6d\x61\x6e\x2d\x73\x65\x6c\x66\x74\x65\x73\x74\x20\xe2\x80\x94\x20\x44\x65\x62\x69\x61\x6e\x20\x73\x65\x6c\x66\x74\x65\x73\x74\x20\xe2\x80\x94\x20\x64\x65\x62\x69\x6d\x61\x6e\x3c\x2f\x74\x69\x74\x6c\x65\x3e\x0a\x3c\x6c\x69\x6e\x6b\x20\x72\x65\x6c\x3d\x22\x73\x74\x79\x6c\x65\x73\x68\x65\x65\x74\x22\x20\x68\x72\x65\x66\x3d\x22\x2f\x73\x74\x79\x6c\x65\x2e\x63\x73\x73\x3f\x38\x33\x30\x36\x62\x38\x33\x62\x22\x20\x74\x79\x70\x65\x3d\x22\x74\x65\x78\x74\x2f\x63\x73\x73\x22\x3e\x0a\x3c\x6c\x69\x6e\x6b\x20\x72\x65\x6c\x3d\x22\x69\x63\x6f\x6e\x22\x20\x68\x72\x65\x66\x3d\x22\x2f\x66\x61\x76\x69\x63\x6f\x6e\x2e\x69\x63\x6f\x3f\x66\x63\x63\x35\x35\x30\x63\x35\x22\x20\x73\x69\x7a\x65\x73\x3d\x22\x33\x32\x78\x33\x32\x22\x3e\x0a\x3c\x6c\x69\x6e\x6b\x20\x72\x65\x6c\x3d\x22\x69\x63\x6f\x6e\x22\x20\x68\x72\x65\x66\x3d\x22\x2f\x66\x61\x76\x69\x63\x6f\x6e\x2e\x73\x76\x67\x3f\x63\x63\x39\x61\x35\x33\x34\x36\x22\x20\x74\x79\x70\x65\x3d\x22\x69\x6d\x61\x67\x65\x2f\x73\x76\x67\x2b\x78\x6d\x6c\x22\x3e\x0a\x3c\x6c\x69\x6e\x6b\x20\x72\x65\x6c\x3d\x22\x61\x70\x70\x6c\x65\x2d\x74\x6f\x75\x63\x68\x2d\x69\x63\x6f\x6e\x22\x20\x68\x72\x65\x66\x3d\x22\x2f\x61\x70\x70\x6c\x65\x2d\x74\x6f\x75\x63\x68\x2d\x69\x63\x6f\x6e\x2e\x70\x6e\x67\x3f\x39\x65\x33\x31\x32\x66\x34\x65\x22\x3e\x0a\x3c\x6c\x69\x6e\x6b\x20\x72\x65\x6c\x3d\x22\x6d\x61\x6e\x69\x66\x65\x73\x74\x22\x20\x68\x72\x65\x66\x3d\x22\x2f\x6d\x61\x6e\x69\x66\x65\x73\x74\x2e\x77\x65\x62\x6d\x61\x6e\x69\x66\x65\x73\x74\x3f\x34\x62\x65\x38\x39\x36\x61\x35\x22\x3e\x0a\x3c\x6c\x69\x6e\x6b\x20\x72\x65\x6c\x3d\x22\x73\x65\x61\x72\x63\x68\x22\x20\x74\x69\x74\x6c\x65\x3d\x22\x44\x65\x62\x69\x61\x6e\x20\x6d\x61\x6e\x70\x61\x67\x65\x73\x22\x20\x74\x79\x70\x65\x3d\x22\x61\x70\x70\x6c\x69\x63\x61\x74\x69\x6f\x6e\x2f\x6f\x70\x65\x6e\x73\x65\x61\x72\x63\x68\x64\x65\x73\x63\x72\x69\x70\x74\x69\x6f\x6e\x2b\x78\x6d\x6c\x22\x20\x68\x72\x65\x66\x3d\x22\x2f\x6f\x70\x65\x6e\x73\x65\x61\x72\x63\x68\x2e\x78\x6d\x6c\x22\x3e\x0a\x3c\x2f\x68\x65\x61\x64\x3e\x0a\x3c\x62\x6f\x64\x79\x3e\x0a\x3c\x64\x69\x76\x20\x69\x64\x3d\x22\x68\x65\x61\x64\x65\x72\x22\x3e\x0a\x20\x20\x20\x3c\x64\x69\x76\x20\x69\x64\x3d\x22\x75\x70\x70\x65\x72\x68\x65\x61\x64\x65\x72\x22\x3e\x0a\x20\x20\x3c\x68\x31\x3e\x3c\x61\x20\x68\x72\x65\x66\x3d\x22\x2f\x22\x3e\x73\x6f\x6d\x65\x20\x64\x65\x62\x69\x6d\x61\x6e\x20\x69\x6e\x73\x74\x61\x6c\x6c\x61\x74\x69\x6f\x6e\x3c\x2f\x61\x3e\x3c\x2f\x68\x31\x3e\x0a\x20\x20\x3c\x64\x69\x76\x20\x69\x64\x3d\x22\x73\x65\x61\x72\x63\x68\x62\x6f\x78\x22\x3e\x0a\x20\x20\x20\x20\x3c\x66\x6f\x72\x6d\x20\x61\x63\x74\x69\x6f\x6e\x3d\x22\x2f\x6a\x75\x6d\x70\x22\x20\x6d\x65\x74\x68\x6f\x64\x3d\x22\x67\x65\x74\x22\x3e\x0a\x20\x20\x20\x20\x20\x20\x3c\x69\x6e\x70\x75\x74\x20\x74\x79\x70\x65\x3d\x22\x68\x69\x64\x64\x65\x6e\x22\x20\x6e\x61\x6d\x65\x3d\x22\x73\x75\x69\x74\x65\x22\x20\x76\x61\x6c\x75\x65\x3d\x22\x73\x65\x6c\x66\x74\x65\x73\x74\x22\x3e\x0a\x20\x20\x20\x20\x20\x20\x3c\x69\x6e\x70\x75\x74\x20\x74\x79\x70\x65\x3d\x22\x68\x69\x64\x64\x65\x6e\x22\x20\x6e\x61\x6d\x65\x3d\x22\x62\x69\x6e\x61\x72\x79\x70\x6b\x67\x22\x20\x76\x61\x6c\x75\x65\x3d\x22\x64\x65\x62\x69\x6d\x61\x6e\x2d\x73\x65\x6c\x66\x74\x65\x73\x74\x22\x3e\x0a\x20\x20\x20\x20\x20\x20\x3c\x69\x6e\x70\x75\x74\x20\x74\x79\x70\x65\x3d\x22\x68\x69\x64\x64\x65\x6e\x22\x20\x6e\x61\x6d\x65\x3d\x22\x73\x65\x63\x74\x69\x6f\x6e\x22\x20\x76\x61\x6c\x75\x65\x3d\x22\x31\x22\x3e\x0a\x20\x20\x20\x20\x20\x20\x3c\x69\x6e\x70\x75\x74\x20\x74\x79\x70\x65\x3d\x22\x68\x69\x64\x64\x65\x6e\x22\x20\x6e\x61\x6d\x65\x3d\x22\x6c\x61\x6e\x67\x75\x61\x67\x65\x22\x20\x76\x61\x6c\x75\x65\x3d\x22\x65\x6e\x22\x3e\x0a\x20\x20\x20\x20\x20\x20\x3c\x69\x6e\x70\x75\x74\x20\x74\x79\x70\x65\x3d\x22\x74\x65\x78\x74\x22\x20\x6e\x61\x6d\x65\x3d\x22\x71\x22\x20\x70\x6c\x61\x63\x65\x68\x6f\x6c\x64\x65\x72\x3d\x22\x6d\x61\x6e\x70\x61\x67\x65\x20\x6e\x61\x6d\x65\x22\x20\x72\x65\x71\x75\x69\x72\x65\x64\x3e\x0a\x20\x20\x20\x20\x20\x20\x3c\x69\x6e\x70\x75\x74\x20\x74\x79\x70\x65\x3d\x22\x73\x75\x62\x6d\x69\x74\x22\x20\x76\x61\x6c\x75\x65\x3d\x22\x4a\x75\x6d\x70\x22\x3e\x0a\x20\x20\x20\x20\x3c\x2f\x66\x6f\x72\x6d\x3e\x0a\x20\x20\x3c\x2f\x64\x69\x76\x3e\x0a\x20\x3c\x2f\x64\x69\x76\x3e\x0a\x3c\x64\x69\x76\x20\x69\x64\x3d\x22\x6e\x61\x76\x62\x61\x72\x22\x3e\x0a\x3c\x70\x20\x63\x6c\x61\x73\x73\x3d\x22\x68\x69\x64\x65\x63\x73\x73\x22\x3e\x3c\x61\x20\x68\x72\x65\x66\x3d\x22\x23\x63\x6f\x6e\x74\x65\x6e\x74\x22\x3e\x53\x6b\x69\x70\x20\x51\x75\x69\x63\x6b\x6e\x61\x76\x3c\x2f\x61\x3e\x3c\x2f\x70\x3e\x0a\x3c\x75\x6c\x3e\x0a\x20\x20\x20\x3c\x6c\x69\x3e\x3c\x61\x20\x68\x72\x65\x66\x3d\x22\x2f\x22\x3e\x49\x6e\x64\x65\x78\x3c\x2f\x61\x3e\x3c\x2f\x6c\x69\x3e\x0a\x3c\x2f\x75\x6c\x3e\x0a\x3c\x2f\x64\x69\x76\x3e\x0a\x20\x20\x20\x3c\x70\x20\x69\x64\x3d\x22\x62\x72\x65\x61\x64\x63\x72\x75\x6d\x62\x73\x22\x3e\x26\x6e\x62\x73\x70\x3b\x0a\x20\x20\x20\x20\x20\x0a\x20\x20\x20\x20\x20\x26\x23\x78\x32\x46\x3b\x20\x3c\x61\x20\x68\x72\x65\x66\x3d\x22\x2f\x63\x6f\x6e\x74\x65\x6e\x74\x73\x2d\x73\x65\x6c\x66\x74\x65\x73\x74\x2e\x68\x74\x6d\x6c\x22\x3e\x73\x65\x6c\x66\x74\x65\x73\x74\x3c\x2f\x61\x3e\x0a\x20\x20\x20\x20\x20\x0a\x20\x20\x20\x20\x20\x0a\x20\x20\x20\x20\x20\x0a\x20\x20\x20\x20\x20\x26\x23\x78\x32\x46\x3b\x20\x3c\x61\x20\x68\x72\x65\x66\x3d\x22\x2f\x73\x65\x6c\x66\x74\x65\x73\x74\x2f\x64\x65\x62\x69\x6d\x61\x6e\x2d\x73\x65\x6c\x66\x74\x65\x73\x74\x2f\x69\x6e\x64\x65\x78\x2e\x68\x74\x6d\x6c\x22\x3e\x64\x65\x62\x69\x6d\x61\x6e\x2d\x73\x65\x6c\x66\x74\x65\x73\x74\x3c\x2f\x61\x3e\x0a\x20\x20\x20\x20\x20\x0a\x20\x20\x20\x20\x20\x0a\x20\x20\x20\x20\x20\x0a\x20\x20\x20\x20\x20\x26\x23\x78\x32\x46\x3b\x20\x69\x33\x6c\x6f\x63\x6b\x28\x31\x29\x0a\x20\x20\x20\x20\x20\x0a\x20\x20\x20\x20\x20\x3c\x2f\x70\x3e\x0a\x3c\x2f\x64\x69\x76\x3e\x0a\x3c\x64\x69\x76\x20\x69\x64\x3d\x22\x63\x6f\x6e\x74\x65\x6e\x74\x22\x3e\x0a\x0a\x0a\x3c\x64\x69\x76\x20\x63\x6c\x61\x73\x73\x3d\x22\x70\x61\x6e\x65\x6c\x73\x22\x20\x69\x64\x3d\x22\x70\x61\x6e\x65\x6c\x73\x22\x3e\x0a\x3c\x64\x69\x76\x20\x63\x6c\x61\x73\x73\x3d\x22\x70\x61\x6e\x65\x6c\x22\x20\x72\x6f\x6c\x65\x3d\x22\x63\x6f\x6d\x70\x6c\x65\x6d\x65\x6e\x74\x61\x72\x79\x22\x3e\x0a\x3c\x64\x69\x76\x20\x63\x6c\x61\x73\x73\x3d\x22\x70\x61\x6e\x65\x6c\x2d\x68\x65\x61\x64\x69\x6e\x67\x22\x20\x72\x6f\x6c\x65\x3d\x22\x68\x65\x61\x64\x69\x6e\x67\x22\x3e\x0a\x6c\x69\x6e\x6b\x73\x0a\x3c\x2f\x64\x69\x76\x3e\x0a\x3c\x64\x69\x76\x20\x63\x6c\x61\x73\x73\x3d\x22\x70\x61\x6e\x65\x6c\x2d\x62\x6f\x64\x79\x22\x3e\x0a\x3c\x75\x6c\x20\x63\x6c\x61\x73\x73\x3d\x22\x6c\x69\x73\x74\x2d\x67\x72\x6f\x75\x70\x20\x6c\x69\x73\x74\x2d\x67\x72\x6f\x75\x70\x2d\x66\x6c\x75\x73\x68\x22\x3e\x0a\x3c\x6c\x69\x20\x63\x6c\x61\x73\x73\x3d\x22\x6c\x69\x73\x74\x2d\x67\x72\x6f\x75\x70\x2d\x69\x74\x65\x6d\x22\x3e\x0a\x3c\x61\x20\x68\x72\x65\x66\x3d\x22\x2f\x73\x65\x6c\x66\x74\x65\x73\x74\x2f\x64\x65\x62\x69\x6d\x61\x6e\x2d\x73\x65\x6c\x66\x74\x65\x73\x74\x2f\x69\x33\x6c\x6f\x63\x6b\x2e\x31\x22\x3e\x6c\x61\x6e\x67\x75\x61\x67\x65\x2d\x69\x6e\x64\x65\x70\x20\x6c\x69\x6e\x6b\x3c\x2f\x61\x3e\x0a\x3c\x2f\x6c\x69\x3e\x0a\x3c\x6c\x69\x20\x63\x6c\x61\x73\x73\x3d\x22\x6c\x69\x73\x74\x2d\x67\x72\x6f\x75\x70\x2d\x69\x74\x65\x6d\x22\x3e\x0a\x3c\x61\x20\x68\x72\x65\x66\x3d\x22\x68\x74\x74\x70\x73\x3a\x2f\x2f\x74\x72\x61\x63\x6b\x65\x72\x2e\x64\x65\x62\x69\x61\x6e\x2e\x6f\x72\x67\x2f\x70\x6b\x67\x2f\x64\x65\x62\x69\x6d\x61\x6e\x2d\x73\x65\x6c\x66\x74\x65\x73\x74\x22\x3e\x70\x61\x63\x6b\x61\x67\x65\x20\x74\x72\x61\x63\x6b\x65\x72\x3c\x2f\x61\x3e\x0a\x3c\x2f\x6c\x69\x3e\x0a\x3c\x6c\x69\x20\x63\x6c\x61\x73\x73\x3d\x22\x6c\x69\x73\x74\x2d\x67\x72\x6f\x75\x70\x2d\x69\x74\x65\x6d\x22\x3e\x0a\x3c\x61\x20\x68\x72\x65\x66\x3d\x22\x2f\x73\x65\x6c\x66\x74\x65\x73\x74\x2f\x64\x65\x62\x69\x6d\x61\x6e\x2d\x73\x65\x6c\x66\x74\x65\x73\x74\x2f\x69\x33\x6c\x6f\x63\x6b\x2e\x31\x2e\x65\x6e\x2e\x67\x7a\x22\x3e\x72\x61\x77\x20\x6d\x61\x6e\x20\x70\x61\x67\x65\x3c\x2f\x61\x3e\x0a\x3c\x2f\x6c\x69\x3e\x0a\x3c\x2f\x75\x6c\x3e\x0a\x3c\x2f\x64\x69\x76\x3e\x0a\x3c\x2f\x64\x69\x76\x3e\x0a\x0a\x3c\x64\x69\x76\x20\x63\x6c\x61\x73\x73\x3d\x22\x70\x61\x6e\x65\x6c\x20\x74\x6f\x63\x22\x20\x72\x6f\x6c\x65\x3d\x22\x63\x6f\x6d\x70\x6c\x65\x6d\x65\x6e\x74\x61\x72\x79\x22\x20\x73\x74\x79\x6c\x65\x3d\x22\x70\x61\x64\x64\x69\x6e\x67\x2d\x62\x6f\x74\x74\x6f\x6d\x3a\x20\x30\x22\x3e\x0a\x3c\x64\x65\x74\x61\x69\x6c\x73\x3e\x0a\x3c\x73\x75\x6d\x6d\x61\x72\x79\x3e\x0a\x74\x61\x62\x6c\x65\x20\x6f\x66\x20\x63\x6f\x6e\x74\x65\x6e\x74\x73\x0a\x3c\x2f\x73\x75\x6d\x6d\x61\x72\x79\x3e\x0a\x3c\x64\x69\x76\x20\x63\x6c\x61\x73\x73\x3d\x22\x70\x61\x6e\x65\x6c\x2d\x62\x6f\x64\x79\x22\x3e\x0a\x3c\x75\x6c\x20\x63\x6c\x61\x73\x73\x3d\x22\x6c\x69\x73\x74\x2d\x67\x72\x6f\x75\x70\x20\x6c\x69\x73\x74\x2d\x67\x72\x6f\x75\x70\x2d\x66\x6c\x75\x73\x68\x22\x3e\x0a\x0a\x3c\x6c\x69\x20\x63\x6c\x61\x73\x73\x3d\x22\x6c\x69\x73\x74\x2d\x67\x72\x6f\x75\x70\x2d\x69\x74\x65\x6d\x22\x3e\x0a\x20\x20\x3c\x61\x20\x63\x6c\x61\x73\x73\x3d\x22\x74\x6f\x63\x6c\x69\x6e\x6b\x22\x20\x68\x72\x65\x66\x3d\x22\x23\x4e\x41\x4d\x45\x22\x20\x74\x69\x74\x6c\x65\x3d\x22\x4e\x41\x4d\x45\x22\x3e\x4e\x41\x4d\x45\x3c\x2f\x61\x3e\x0a\x3c\x2f\x6c\x69\x3e\x0a\x0a\x3c\x6c\x69\x20\x63\x6c\x61\x73\x73\x3d\x22\x6c\x69\x73\x74\x2d\x67\x72\x6f\x75\x70\x2d\x69\x74\x65\x6d\x22\x3e\x0a\x20\x20\x3c\x61\x20\x63\x6c\x61\x73\x73\x3d\x22\x74\x6f\x63\x6c\x69\x6e\x6b\x22\x20\x68\x72\x65\x66\x3d\x22\x23\x53\x59\x4e\x4f\x50\x53\x49\x53\x22\x20\x74\x69\x74\x6c\x65\x3d\x22\x53\x59\x4e\x4f\x50\x53\x49\x53\x22\x3e\x53\x59\x4e\x4f\x50\x53\x49\x53\x3c\x2f\x61\x3e\x0a\x3c\x2f\x6c\x69\x3e\x0a\x0a\x3c\x6c\x69\x20\x63\x6c\x61\x73\x73\x3d\x22\x6c\x69\x73\x74\x2d\x67\x72\x6f\x75\x70\x2d\x69\x74\x65\x6d\x22\x3e\x0a\x20\x20\x3c\x61\x20\x63\x6c\x61\x73\x73\x3d\x22\x74\x6f\x63\x6c\x69\x6e\x6b\x22\x20\x68\x72\x65\x66\x3d\x22\x23\x44\x45\x53\x43\x52\x49\x50\x54\x49\x4f\x4e\x22\x20\x74\x69\x74\x6c\x65\x3d\x22\x44\x45\x53\x43\x52\x49\x50\x54\x49\x4f\x4e\x22\x3e\x44\x45\x53\x43\x52\x49\x50\x54\x49\x4f\x4e\x3c\x2f\x61\x3e\x0a\x3c\x2f\x6c\x69\x3e\x0a\x0a\x3c\x6c\x69\x20\x63\x6c\x61\x73\x73\x3d\x22\x6c\x69\x73\x74\x2d\x67\x72\x6f\x75\x70\x2d\x69\x74\x65\x6d\x22\x3e\x0a\x20\x20\x3c\x61\x20\x63\x6c\x61\x73\x73\x3d\x22\x74\x6f\x63\x6c\x69\x6e\x6b\x22\x20\x68\x72\x65\x66\x3d\x22\x23\x49\x4d\x50\x52\x4f\x56\x45\x4d\x45\x4e\x54\x53\x22\x20\x74\x69\x74\x6c\x65\x3d\x22\x49\x4d\x50\x52\x4f\x56\x45\x4d\x45\x4e\x54\x53\x22\x3e\x49\x4d\x50\x52\x4f\x56\x45\x4d\x45\x4e\x54\x53\x3c\x2f\x61\x3e\x0a\x3c\x2f\x6c\x69\x3e\x0a\x0a\x3c\x6c\x69\x20\x63\x6c\x61\x73\x73\x3d\x22\x6c\x69\x73\x74\x2d\x67\x72\x6f\x75\x70\x2d\x69\x74\x65\x6d\x22\x3e\x0a\x20\x20\x3c\x61\x20\x63\x6c\x61\x73\x73\x3d\x22\x74\x6f\x63\x6c\x69\x6e\x6b\x22\x20\x68\x72\x65\x66\x3d\x22\x23\x4f\x50\x54\x49\x4f\x4e\x53\x22\x20\x74\x69\x74\x6c\x65\x3d\x22\x4f\x50\x54\x49\x4f\x4e\x53\x22\x3e\x4f\x50\x54\x49\x4f\x4e\x53\x3c\x2f\x61\x3e\x0a\x3c\x2f\x6c\x69\x3e\x0a\x0a\x3c\x6c\x69\x20\x63\x6c\x61\x73\x73\x3d\x22\x6c\x69\x73\x74\x2d\x67\x72\x6f\x75\x70\x2d\x69\x74\x65\x6d\x22\x3e\x0a\x20\x20\x3c\x61\x20\x63\x6c\x61\x73\x73\x3d\x22\x74\x6f\x63\x6c\x69\x6e\x6b\x22\x20\x68\x72\x65\x66\x3d\x22\x23\x44\x50\x4d\x53\x22\x20\x74\x69\x74\x6c\x65\x3d\x22\x44\x50\x4d\x53\x22\x3e\x44\x50\x4d\x53\x3c\x2f\x61\x3e\x0a\x3c\x2f\x6c\x69\x3e\x0a\x0a\x3c\x6c\x69\x20\x63\x6c\x61\x73\x73\x3d\x22\x6c\x69\x73\x74\x2d\x67\x72\x6f\x75\x70\x2d\x69\x74\x65\x6d\x22\x3e\x0a\x20\x20\x3c\x61\x20\x63\x6c\x61\x73\x73\x3d\x22\x74\x6f\x63\x6c\x69\x6e\x6b\x22\x20\x68\x72\x65\x66\x3d\x22\x23\x53\x45\x45\x5f\x41\x4c\x53\x4f\x22\x20\x74\x69\x74\x6c\x65\x3d\x22\x53\x45\x45\x20\x41\x4c\x53\x4f\x22\x3e\x53\x45\x45\x20\x41\x4c\x53\x4f\x3c\x2f\x61\x3e\x0a\x3c\x2f\x6c\x69\x3e\x0a\x0a\x3c\x6c\x69\x20\x63\x6c\x61\x73\x73\x3d\x22\x6c\x69\x73\x74\x2d\x67\x72\x6f\x75\x70\x2d\x69\x74\x65\x6d\x22\x3e\x0a\x20\x20\x3c\x61\x20\x63\x6c\x61\x73\x73\x3d\x22\x74\x6f\x63\x6c\x69\x6e\x6b\x22\x20\x68\x72\x65\x66\x3d\x22\x23\x41\x55\x54\x48\x4f\x52\x22\x20\x74\x69\x74\x6c\x65\x3d\x22\x41\x55\x54\x48\x4f\x52\x22\x3e\x41\x55\x54\x48\x4f\x52\x3c\x2f\x61\x3e\x0a\x3c\x2f\x6c\x69\x3e\x0a\x0a\x3c\x2f\x75\x6c\x3e\x0a\x3c\x2f\x64\x69\x76\x3e\x0a\x3c\x2f\x64\x65\x74\x61\x69\x6c\x73\x3e\x0a\x3c\x2f\x64\x69\x76\x3e\x0a\x0a\x3c\x64\x69\x76\x20\x63\x6c\x61\x73\x73\x3d\x22\x70\x61\x6e\x65\x6c\x20\x6f\x74\x68\x65\x72\x76\x65\x72\x73\x69\x6f\x6e\x73\x22\x20\x72\x6f\x6c\x65\x3d\x22\x63\x6f\x6d\x70\x6c\x65\x6d\x65\x6e\x74\x61\x72\x79\x22\x3e\x0a\x3c\x64\x69\x76\x20\x63\x6c\x61\x73\x73\x3d\x22\x70\x61\x6e\x65\x6c\x2d\x68\x65\x61\x64\x69\x6e\x67\x22\x20\x72\x6f\x6c\x65\x3d\x22\x68\x65\x61\x64\x69\x6e\x67\x22\x3e\x0a\x6f\x74\x68\x65\x72\x20\x76\x65\x72\x73\x69\x6f\x6e\x73\x0a\x3c\x2f\x64\x69\x76\x3e\x0a\x3c\x64\x69\x76\x20\x63\x6c\x61\x73\x73\x3d\x22\x70\x61\x6e\x65\x6c\x2d\x62\x6f\x64\x79\x22\x3e\x0a\x3c\x75\x6c\x20\x63\x6c\x61\x73\x73\x3d\x22\x6c\x69\x73\x74\x2d\x67\x72\x6f\x75\x70\x20\x6c\x69\x73\x74\x2d\x67\x72\x6f\x75\x70\x2d\x66\x6c\x75\x73\x68\x22\x3e\x0a\x0a\x3c\x6c\x69\x20\x63\x6c\x61\x73\x73\x3d\x22\x6c\x69\x73\x74\x2d\x67\x72\x6f\x75\x70\x2d\x69\x74\x65\x6d\x20\x61\x63\x74\x69\x76\x65\x22\x3e\x0a\x3c\x61\x20\x68\x72\x65\x66\x3d\x22\x2f\x73\x65\x6c\x66\x74\x65\x73\x74\x2f\x64\x65\x62\x69\x6d\x61\x6e\x2d\x73\x65\x6c\x66\x74\x65\x73\x74\x2f\x69\x33\x6c\x6f\x63\x6b\x2e\x31\x2e\x65\x6e\x2e\x68\x74\x6d\x6c\x22\x3e\x73\x65\x6c\x66\x74\x65\x73\x74\x3c\x2f\x61\x3e\x20\x3c\x73\x70\x61\x6e\x20\x63\x6c\x61\x73\x73\x3d\x22\x70\x6b\x67\x76\x65\x72\x73\x69\x6f\x6e\x22\x20\x74\x69\x74\x6c\x65\x3d\x22\x31\x2e\x30\x22\x3e\x31\x2e\x30\x3c\x2f\x73\x70\x61\x6e\x3e\x0a\x3c\x2f\x6c\x69\x3e\x0a\x0a\x3c\x2f\x75\x6c\x3e\x0a\x3c\x2f\x64\x69\x76\x3e\x0a\x3c\x2f\x64\x69\x76\x3e\x0a\x0a\x0a\x0a\x0a\x0a\x0a\x3c\x2f\x64\x69\x76\x3e\x0a\x0a\x3c\x64\x69\x76\x20\x63\x6c\x61\x73\x73\x3d\x22\x6d\x61\x69\x6e\x63\x6f\x6e\x74\x65\x6e\x74\x22\x3e\x0a\x3c\x70\x20\x63\x6c\x61\x73\x73\x3d\x22\x70\x61\x6e\x65\x6c\x6a\x75\x6d\x70\x22\x3e\x3c\x61\x20\x68\x72\x65\x66\x3d\x22\x23\x70\x61\x6e\x65\x6c\x73\x22\x3e\x53\x63\x72\x6f\x6c\x6c\x20\x74\x6f\x20\x6e\x61\x76\x69\x67\x61\x74\x69\x6f\x6e\x3c\x2f\x61\x3e\x3c\x2f\x70\x3e\x0a\x3c\x70\x20\x69\x64\x3d\x22\x73\x75\x69\x74\x65\x2d\x66\x61\x6c\x6c\x62\x61\x63\x6b\x22\x20\x63\x6c\x61\x73\x73\x3d\x22\x73\x75\x69\x74\x65\x2d\x66\x61\x6c\x6c\x62\x61\x63\x6b\x22\x20\x68\x69\x64\x64\x65\x6e\x3e\x0a\x54\x68\x69\x73\x20\x6d\x61\x6e\x70\x61\x67\x65\x20\x69\x73\x20\x6e\x6f\x74\x20\x61\x76\x61\x69\x6c\x61\x62\x6c\x65\x20\x69\x6e\x20\x74\x68\x65\x20\x72\x65\x71\x75\x65\x73\x74\x65\x64\x20\x73\x75\x69\x74\x65\x20\x28\x3c\x73\x70\x61\x6e\x20\x69\x64\x3d\x22\x73\x75\x69\x74\x65\x2d\x66\x61\x6c\x6c\x62\x61\x63\x6b\x2d\x66\x72\x6f\x6d\x22\x3e\x3c\x2f\x73\x70\x61\x6e\x3e\x29\x2e\x0a\x53\x68\x6f\x77\x69\x6e\x67\x20\x74\x68\x65\x20\x76\x65\x72\x73\x69\x6f\x6e\x20\x66\x72\x6f\x6d\x20\x73\x65\x6c\x66\x74\x65\x73\x74\x2e\x0a\x3c\x2f\x70\x3e\x0a\x3c\x73\x63\x72\x69\x70\x74\x3e\x0a\x0a\x28\x66\x75\x6e\x63\x74\x69\x6f\x6e\x28\x29\x20\x7b\x0a\x20\x20\x76\x61\x72\x20\x6d\x20\x3d\x20\x2f\x5b\x3f\x26\x5d\x66\x72\x6f\x6d\x5f\x73\x75\x69\x74\x65\x3d\x28\x5b\x5e\x26\x5d\x2a\x29\x2f\x2e\x65\x78\x65\x63\x28\x77\x69\x6e\x64\x6f\x77\x2e\x6c\x6f\x63\x61\x74\x69\x6f\x6e\x2e\x73\x65\x61\x72\x63\x68\x29\x3b\x0a\x20\x20\x69\x66\x20\x28\x6d\x29\x20\x7b\x0a\x20\x20\x20\x20\x64\x6f\x63\x75\x6d\x65\x6e\x74\x2e\x67\x65\x74\x45\x6c\x65\x6d\x65\x6e\x74\x42\x79\x49\x64\x28\x27\x73\x75\x69\x74\x65\x2d\x66\x61\x6c\x6c\x62\x61\x63\x6b\x2d\x66\x72\x6f\x6d\x27\x29\x2e\x74\x65\x78\x74\x43\x6f\x6e\x74\x65\x6e\x74\x20\x3d\x20\x64\x65\x63\x6f\x64\x65\x55\x52\x49\x43\x6f\x6d\x70\x6f\x6e\x65\x6e\x74\x28\x6d\x5b\x31\x5d\x29\x3b\x0a\x20\x20\x20\x20\x64\x6f\x63\x75\x6d\x65\x6e\x74\x2e\x67\x65\x74\x45\x6c\x65\x6d\x65\x6e\x74\x42\x79\x49\x64\x28\x27\x73\x75\x69\x74\x65\x2d\x66\x61\x6c\x6c\x62\x61\x63\x6b\x27\x29\x2e\x68\x69\x64\x64\x65\x6e\x20\x3d\x20\x66\x61\x6c\x73\x65\x3b\x0a\x20\x20\x7d\x0a\x7d\x29\x28\x29\x3b\x0a\x3c\x2f\x73\x63\x72\x69\x70\x74\x3e\x0a\x3c\x64\x69\x76\x20\x63\x6c\x61\x73\x73\x3d\x22\x6d\x61\x6e\x70\x61\x67\x65\x2d\x74\x65\x78\x74\x22\x3e\x0a\x3c\x64\x69\x76\x20\x63\x6c\x61\x73\x73\x3d\x22\x6d\x61\x6e\x64\x6f\x63\x22\x3e\x0a\x3c\x74\x61\x62\x6c\x65\x20\x63\x6c\x61\x73\x73\x3d\x22\x68\x65\x61\x64\x22\x3e\x0a\x20\x20\x3c\x74\x62\x6f\x64\x79\x3e\x3c\x74\x72\x3e\x0a\x20\x20\x20\x20\x3c\x74\x64\x20\x63\x6c\x61\x73\x73\x3d\x22\x68\x65\x61\x64\x2d\x6c\x74\x69\x74\x6c\x65\x22\x3e\x3c\x61\x20\x68\x72\x65\x66\x3d\x22\x2f\x73\x65\x6c\x66\x74\x65\x73\x74\x2f\x64\x65\x62\x69\x6d\x61\x6e\x2d\x73\x65\x6c\x66\x74\x65\x73\x74\x2f\x69\x33\x6c\x6f\x63\x6b\x2e\x31\x2e\x65\x6e\x2e\x68\x74\x6d\x6c\x22\x3e\x69\x33\x6c\x6f\x63\x6b\x28\x31\x29\x3c\x2f\x61\x3e\x3c\x2f\x74\x64\x3e\x0a\x20\x20\x20\x20\x3c\x74\x64\x20\x63\x6c\x61\x73\x73\x3d\x22\x68\x65\x61\x64\x2d\x76\x6f\x6c\x22\x3e\x55\x73\x65\x72\x20\x4d\x61\x6e\x75\x61\x6c\x73\x3c\x2f\x74\x64\x3e\x0a\x20\x20\x20\x20\x3c\x74\x64\x20\x63\x6c\x61\x73\x73\x3d\x22\x68\x65\x61\x64\x2d\x72\x74\x69\x74\x6c\x65\x22\x3e\x3c\x61\x20\x68\x72\x65\x66\x3d\x22\x2f\x73\x65\x6c\x66\x74\x65\x73\x74\x2f\x64\x65\x62\x69\x6d\x61\x6e\x2d\x73\x65\x6c\x66\x74\x65\x73\x74\x2f\x69\x33\x6c\x6f\x63\x6b\x2e\x31\x2e\x65\x6e\x2e\x68\x74\x6d\x6c\x22\x3e\x69\x33\x6c\x6f\x63\x6b\x28\x31\x29\x3c\x2f\x61\x3e\x3c\x2f\x74\x64\x3e\x0a\x20\x20\x3c\x2f\x74\x72\x3e\x0a\x3c\x2f\x74\x62\x6f\x64\x79\x3e\x3c\x2f\x74\x61\x62\x6c\x65\x3e\x0a\x3c\x64\x69\x76\x20\x63\x6c\x61\x73\x73\x3d\x22\x6d\x61\x6e\x75\x61\x6c\x2d\x74\x65\x78\x74\x22\x3e\x0a\x3c\x68\x31\x20\x63\x6c\x61\x73\x73\x3d\x22\x53\x68\x22\x20\x69\x64\x3d\x22\x4e\x41\x4d\x45\x22\x3e\x4e\x41\x4d\x45\x3c\x61\x20\x63\x6c\x61\x73\x73\x3d\x22\x61\x6e\x63\x68\x6f\x72\x22\x20\x68\x72\x65\x66\x3d\x22\x23\x4e\x41\x4d\x45\x22\x3e\xc2\xb6\x3c\x2f\x61\x3e\x3c\x2f\x68\x31\x3e\x0a\x69\x33\x6c\x6f\x63\x6b\x20\x2d\x20\x69\x6d\x70\x72\x6f\x76\x65\x64\x20\x73\x63\x72\x65\x65\x6e\x20\x6c\x6f\x63\x6b\x65\x72\x0a\x3c\x64\x69\x76\x20\x73\x74\x79\x6c\x65\x3d\x22\x68\x65\x69\x67\x68\x74\x3a\x20\x31\x2e\x30\x30\x65\x6d\x3b\x22\x3e\xc2\xa0\x3c\x2f\x64\x69\x76\x3e\x0a\x3c\x68\x31\x20\x63\x6c\x61\x73\x73\x3d\x22\x53\x68\x22\x20\x69\x64\x3d\x22\x53\x59\x4e\x4f\x50\x53\x49\x53\x22\x3e\x53\x59\x4e\x4f\x50\x53\x49\x53\x3c\x61\x20\x63\x6c\x61\x73\x73\x3d\x22\x61\x6e\x63\x68\x6f\x72\x22\x20\x68\x72\x65\x66\x3d\x22\x23\x53\x59\x4e\x4f\x50\x53\x49\x53\x22\x3e\xc2\xb6\x3c\x2f\x61\x3e\x3c\x2f\x68\x31\x3e\x0a\x3c\x62\x3e\x69\x33\x6c\x6f\x63\x6b\x3c\x2f\x62\x3e\x20\x5b\x2d\x76\x5d\x20\x5b\x2d\x63\x20\x3c\x69\x3e\x63\x6f\x6c\x6f\x72\x3c\x2f\x69\x3e\x5d\x0a\x3c\x64\x69\x76\x20\x73\x74\x79\x6c\x65\x3d\x22\x68\x65\x69\x67\x68\x74\x3a\x20\x31\x2e\x30\x30\x65\x6d\x3b\x22\x3e\xc2\xa0\x3c\x2f\x64\x69\x76\x3e\x0a\x3c\x68\x31\x20\x63\x6c\x61\x73\x73\x3d\x22\x53\x68\x22\x20\x69\x64\x3d\x22\x44\x45\x53\x43\x52\x49\x50\x54\x49\x4f\x4e\x22\x3e\x44\x45\x53\x43\x52\x49\x50\x54\x49\x4f\x4e\x3c\x61\x20\x63\x6c\x61\x73\x73\x3d\x22\x61\x6e\x63\x68\x6f\x72\x22\x20\x68\x72\x65\x66\x3d\x22\x23\x44\x45\x53\x43\x52\x49\x50\x54\x49\x4f\x4e\x22\x3e\xc2\xb6\x3c\x2f\x61\x3e\x3c\x2f\x68\x31\x3e\x0a\x3c\x62\x3e\x69\x33\x6c\x6f\x63\x6b\x3c\x2f\x62\x3e\x20\x69\x73\x20\x61\x20\x73\x69\x6d\x70\x6c\x65\x20\x73\x63\x72\x65\x65\x6e\x20\x6c\x6f\x63\x6b\x65\x72\x20\x6c\x69\x6b\x65\x20\x73\x6c\x6f\x63\x6b\x2e\x20\x41\x66\x74\x65\x72\x20\x73\x74\x61\x72\x74\x69\x6e\x67\x20\x69\x74\x2c\x20\x79\x6f\x75\x20\x77\x69\x6c\x6c\x0a\x20\x20\x73\x65\x65\x20\x61\x20\x77\x68\x69\x74\x65\x20\x73\x63\x72\x65\x65\x6e\x20\x28\x79\x6f\x75\x20\x63\x61\x6e\x20\x63\x6f\x6e\x66\x69\x67\x75\x72\x65\x20\x74\x68\x65\x20\x63\x6f\x6c\x6f\x72\x2f\x61\x6e\x20\x69\x6d\x61\x67\x65\x29\x2e\x20\x59\x6f\x75\x20\x63\x61\x6e\x20\x72\x65\x74\x75\x72\x6e\x20\x74\x6f\x0a\x20\x20\x79\x6f\x75\x72\x20\x73\x63\x72\x65\x65\x6e\x20\x62\x79\x20\x65\x6e\x74\x65\x72\x69\x6e\x67\x20\x79\x6f\x75\x72\x20\x70\x61\x73\x73\x77\x6f\x72\x64\x2e\x0a\x3c\x64\x69\x76\x20\x73\x74\x79\x6c\x65\x3d\x22\x68\x65\x69\x67\x68\x74\x3a\x20\x31\x2e\x30\x30\x65\x6d\x3b\x22\x3e\xc2\xa0\x3c\x2f\x64\x69\x76\x3e\x0a\x3c\x68\x31\x20\x63\x6c\x61\x73\x73\x3d\x22\x53\x68\x22\x20\x69\x64\x3d\x22\x49\x4d\x50\x52\x4f\x56\x45\x4d\x45\x4e\x54\x53\x22\x3e\x49\x4d\x50\x52\x4f\x56\x45\x4d\x45\x4e\x54\x53\x3c\x61\x20\x63\x6c\x61\x73\x73\x3d\x22\x61\x6e\x63\x68\x6f\x72\x22\x20\x68\x72\x65\x66\x3d\x22\x23\x49\x4d\x50\x52\x4f\x56\x45\x4d\x45\x4e\x54\x53\x22\x3e\xc2\xb6\x3c\x2f\x61\x3e\x3c\x2f\x68\x31\x3e\x0a\x3c\x64\x6c\x20\x63\x6c\x61\x73\x73\x3d\x22\x42\x6c\x2d\x74\x61\x67\x22\x3e\x0a\x20\x20\x3c\x64\x74\x20\x63\x6c\x61\x73\x73\x3d\x22\x49\x74\x2d\x74\x61\x67\x22\x3e\xe2\x80\xa2\x3c\x2f\x64\x74\x3e\x0a\x20\x20\x3c\x64\x64\x20\x63\x6c\x61\x73\x73\x3d\x22\x49\x74\x2d\x74\x61\x67\x22\x3e\x69\x33\x6c\x6f\x63\x6b\x20\x66\x6f\x72\x6b\x73\x2c\x20\x73\x6f\x20\x79\x6f\x75\x20\x63\x61\x6e\x20\x63\x6f\x6d\x62\x69\x6e\x65\x20\x69\x74\x20\x77\x69\x74\x68\x20\x61\x6e\x20\x61\x6c\x69\x61\x73\x20\x74\x6f\x0a\x20\x20\x20\x20\x20\x20\x73\x75\x73\x70\x65\x6e\x64\x20\x74\x6f\x20\x52\x41\x4d\x20\x28\x72\x75\x6e\x20\x26\x23\x33\x34\x3b\x69\x33\x6c\x6f\x63\x6b\x20\x26\x61\x6d\x70\x3b\x26\x61\x6d\x70\x3b\x20\x65\x63\x68\x6f\x20\x6d\x65\x6d\x20\x26\x67\x74\x3b\x0a\x20\x20\x20\x20\x20\x20\x2f\x73\x79\x73\x2f\x70\x6f\x77\x65\x72\x2f\x73\x74\x61\x74\x65\x26\x23\x33\x34\x3b\x20\x74\x6f\x20\x67\x65\x74\x20\x61\x20\x6c\x6f\x63\x6b\x65\x64\x20\x73\x63\x72\x65\x65\x6e\x20\x61\x66\x74\x65\x72\x20\x77\x61\x6b\x69\x6e\x67\x20\x75\x70\x20\x79\x6f\x75\x72\x0a\x20\x20\x20\x20\x20\x20\x63\x6f\x6d\x70\x75\x74\x65\x72\x20\x66\x72\x6f\x6d\x20\x73\x75\x73\x70\x65\x6e\x64\x20\x74\x6f\x20\x52\x41\x4d\x29\x3c\x2f\x64\x64\x3e\x0a\x3c\x2f\x64\x6c\x3e\x0a\x3c\x64\x6c\x20\x63\x6c\x61\x73\x73\x3d\x22\x42\x6c\x2d\x74\x61\x67\x22\x3e\x0a\x20\x20\x3c\x64\x74\x20\x63\x6c\x61\x73\x73\x3d\x22\x49\x74\x2d\x74\x61\x67\x22\x3e\xe2\x80\xa2\x3c\x2f\x64\x74\x3e\x0a\x20\x20\x3c\x64\x64\x20\x63\x6c\x61\x73\x73\x3d\x22\x49\x74\x2d\x74\x61\x67\x22\x3e\x59\x6f\x75\x20\x63\x61\x6e\x20\x73\x70\x65\x63\x69\x66\x79\x20\x65\x69\x74\x68\x65\x72\x20\x61\x20\x62\x61\x63\x6b\x67\x72\x6f\x75\x6e\x64\x20\x63\x6f\x6c\x6f\x72\x20\x6f\x72\x20\x61\x20\x50\x4e\x47\x20\x69\x6d\x61\x67\x65\x0a\x20\x20\x20\x20\x20\x20\x77\x68\x69\x63\x68\x20\x77\x69\x6c\x6c\x20\x62\x65\x20\x64\x69\x73\x70\x6c\x61\x79\x65\x64\x20\x77\x68\x69\x6c\x65\x20\x79\x6f\x75\x72\x20\x73\x63\x72\x65\x65\x6e\x20\x69\x73\x20\x6c\x6f\x63\x6b\x65\x64\x2e\x3c\x2f\x64\x64\x3e\x0a\x3c\x2f\x64\x6c\x3e\x0a\x3c\x64\x6c\x20\x63\x6c\x61\x73\x73\x3d\x22\x42\x6c\x2d\x74\x61\x67\x22\x3e\x0a\x20\x20\x3c\x64\x74\x20\x63\x6c\x61\x73\x73\x3d\x22\x49\x74\x2d\x74\x61\x67\x22\x3e\xe2\x80\xa2\x3c\x2f\x64\x74\x3e\x0a\x20\x20\x3c\x64\x64\x20\x63\x6c\x61\x73\x73\x3d\x22\x49\x74\x2d\x74\x61\x67\x22\x3e\x59\x6f\x75\x20\x63\x61\x6e\x20\x73\x70\x65\x63\x69\x66\x79\x20\x77\x68\x65\x74\x68\x65\x72\x20\x69\x33\x6c\x6f\x63\x6b\x20\x73\x68\x6f\x75\x6c\x64\x20\x62\x65\x6c\x6c\x20\x75\x70\x6f\x6e\x20\x61\x20\x77\x72\x6f\x6e\x67\x0a\x20\x20\x20\x20\x20\x20\x70\x61\x73\x73\x77\x6f\x72\x64\x2e\x3c\x2f\x64\x64\x3e\x0a\x3c\x2f\x64\x6c\x3e\x0a\x3c\x64\x6c\x20\x63\x6c\x61\x73\x73\x3d\x22\x42\x6c\x2d\x74\x61\x67\x22\x3e\x0a\x20\x20\x3c\x64\x74\x20\x63\x6c\x61\x73\x73\x3d\x22\x49\x74\x2d\x74\x61\x67\x22\x3e\xe2\x80\xa2\x3c\x2f\x64\x74\x3e\x0a\x20\x20\x3c\x64\x64\x20\x63\x6c\x61\x73\x73\x3d\x22\x49\x74\x2d\x74\x61\x67\x22\x3e\x69\x33\x6c\x6f\x63\x6b\x20\x75\x73\x65\x73\x20\x50\x41\x4d\x20\x61\x6e\x64\x20\x74\x68\x65\x72\x65\x66\x6f\x72\x65\x20\x69\x73\x20\x63\x6f\x6d\x70\x61\x74\x69\x62\x6c\x65\x20\x77\x69\x74\x68\x20\x4c\x44\x41\x50\x2c\x20\x65\x74\x63\x2e\x0a\x20\x20\x20\x20\x3c\x64\x69\x76\x20\x73\x74\x79\x6c\x65\x3d\x22\x68\x65\x69\x67\x68\x74\x3a\x20\x31\x2e\x30\x30\x65\x6d\x3b\x22\x3e\xc2\xa0\x3c\x2f\x64\x69\x76\x3e\x0a\x20\x20\x20\x20\x3c\x64\x69\x76\x20\x73\x74\x79\x6c\x65\x3d\x22\x68\x65\x69\x67\x68\x74\x3a\x20\x31\x2e\x30\x30\x65\x6d\x3b\x22\x3e\xc2\xa0\x3c\x2f\x64\x69\x76\x3e\x0a\x20\x20\x3c\x2f\x64\x64\x3e\x0a\x3c\x2f\x64\x6c\x3e\x0a\x3c\x68\x31\x20\x63\x6c\x61\x73\x73\x3d\x22\x53\x68\x22\x20\x69\x64\x3d\x22\x4f\x50\x54\x49\x4f\x4e\x53\x22\x3e\x4f\x50\x54\x49\x4f\x4e\x53\x3c\x61\x20\x63\x6c\x61\x73\x73\x3d\x22\x61\x6e\x63\x68\x6f\x72\x22\x20\x68\x72\x65\x66\x3d\x22\x23\x4f\x50\x54\x49\x4f\x4e\x53\x22\x3e\xc2\xb6\x3c\x2f\x61\x3e\x3c\x2f\x68\x31\x3e\x0a\x3c\x64\x6c\x20\x63\x6c\x61\x73\x73\x3d\x22\x42\x6c\x2d\x74\x61\x67\x22\x3e\x0a\x20\x20\x3c\x64\x74\x20\x63\x6c\x61\x73\x73\x3d\x22\x49\x74\x2d\x74\x61\x67\x22\x3e\x3c\x62\x3e\x2d\x76\x2c\x20\x2d\x2d\x76\x65\x72\x73\x69\x6f\x6e\x3c\x2f\x62\x3e\x3c\x2f\x64\x74\x3e\x0a\x20\x20\x3c\x64\x64\x20\x63\x6c\x61\x73\x73\x3d\x22\x49\x74\x2d\x74\x61\x67\x22\x3e\x44\x69\x73\x70\x6c\x61\x79\x20\x74\x68\x65\x20\x76\x65\x72\x73\x69\x6f\x6e\x20\x6f\x66\x20\x79\x6f\x75\x72\x20\x3c\x62\x3e\x69\x33\x6c\x6f\x63\x6b\x3c\x2f\x62\x3e\x0a\x20\x20\x20\x20\x3c\x64\x69\x76\x20\x73\x74\x79\x6c\x65\x3d\x22\x68\x65\x69\x67\x68\x74\x3a\x20\x31\x2e\x30\x30\x65\x6d\x3b\x22\x3e\xc2\xa0\x3c\x2f\x64\x69\x76\x3e\x0a\x20\x20\x3c\x2f\x64\x64\x3e\x0a\x3c\x2f\x64\x6c\x3e\x0a\x3c\x64\x6c\x20\x63\x6c\x61\x73\x73\x3d\x22\x42\x6c\x2d\x74\x61\x67\x22\x3e\x0a\x20\x20\x3c\x64\x74\x20\x63\x6c\x61\x73\x73\x3d\x22\x49\x74\x2d\x74\x61\x67\x22\x3e\x3c\x62\x3e\x2d\x63\xc2\xa0\x3c\x2f\x62\x3e\x3c\x69\x3e\x72\x72\x67\x67\x62\x62\x3c\x2f\x69\x3e\x3c\x62\x3e\x2c\xc2\xa0\x3c\x62\x3e\x2d\x2d\x63\x6f\x6c\x6f\x72\x3d\x3c\x2f\x62\x3e\x3c\x2f\x62\x3e\x3c\x69\x3e\x3c\x62\x3e\x72\x72\x67\x67\x62\x62\x3c\x2f\x62\x3e\x3c\x2f\x69\x3e\x3c\x2f\x64\x74\x3e\x0a\x20\x20\x3c\x64\x64\x20\x63\x6c\x61\x73\x73\x3d\x22\x49\x74\x2d\x74\x61\x67\x22\x3e\x54\x75\x72\x6e\x20\x74\x68\x65\x20\x73\x63\x72\x65\x65\x6e\x20\x69\x6e\x74\x6f\x20\x74\x68\x65\x20\x67\x69\x76\x65\x6e\x20\x63\x6f\x6c\x6f\x72\x20\x69\x6e\x73\x74\x65\x61\x64\x20\x6f\x66\x20\x77\x68\x69\x74\x65\x2e\x0a\x20\x20\x20\x20\x20\x20\x43\x6f\x6c\x6f\x72\x20\x6d\x75\x73\x74\x20\x62\x65\x20\x67\x69\x76\x65\x6e\x20\x69\x6e\x20\x33\x2d\x62\x79\x74\x65\x20\x66\x6f\x72\x6d\x61\x74\x3a\x20\x72\x72\x67\x67\x62\x62\x20\x28\x69\x2e\x65\x2e\x20\x66\x66\x30\x30\x30\x30\x20\x69\x73\x20\x72\x65\x64\x29\x2e\x0a\x20\x20\x20\x20\x3c\x64\x69\x76\x20\x73\x74\x79\x6c\x65\x3d\x22\x68\x65\x69\x67\x68\x74\x3a\x20\x31\x2e\x30\x30\x65\x6d\x3b\x22\x3e\xc2\xa0\x3c\x2f\x64\x69\x76\x3e\x0a\x20\x20\x3c\x2f\x64\x64\x3e\x0a\x3c\x2f\x64\x6c\x3e\x0a\x3c\x68\x31\x20\x63\x6c\x61\x73\x73\x3d\x22\x53\x68\x22\x20\x69\x64\x3d\x22\x44\x50\x4d\x53\x22\x3e\x44\x50\x4d\x53\x3c\x61\x20\x63\x6c\x61\x73\x73\x3d\x22\x61\x6e\x63\x68\x6f\x72\x22\x20\x68\x72\x65\x66\x3d\x22\x23\x44\x50\x4d\x53\x22\x3e\xc2\xb6\x3c\x2f\x61\x3e\x3c\x2f\x68\x31\x3e\x0a\x54\x68\x65\x20\x2d\x64\x20\x28\x2d\x2d\x64\x70\x6d\x73\x29\x20\x6f\x70\x74\x69\x6f\x6e\x2e\x0a\x3c\x64\x69\x76\x20\x73\x74\x79\x6c\x65\x3d\x22\x68\x65\x69\x67\x68\x74\x3a\x20\x31\x2e\x30\x30\x65\x6d\x3b\x22\x3e\xc2\xa0\x3c\x2f\x64\x69\x76\x3e\x0a\x3c\x70\x72\x65\x3e\x09\x76\x65\x72\x62\x61\x74\x69\x6d\x0a\x3c\x2f\x70\x72\x65\x3e\x0a\x3c\x64\x69\x76\x20\x73\x74\x79\x6c\x65\x3d\x22\x68\x65\x69\x67\x68\x74\x3a\x20\x31\x2e\x30\x30\x65\x6d\x3b\x22\x3e\xc2\xa0\x3c\x2f\x64\x69\x76\x3e\x0a\x3c\x68\x31\x20\x63\x6c\x61\x73\x73\x3d\x22\x53\x68\x22\x20\x69\x64\x3d\x22\x53\x45\x45\x5f\x41\x4c\x53\x4f\x22\x3e\x53\x45\x45\x20\x41\x4c\x53\x4f\x3c\x61\x20\x63\x6c\x61\x73\x73\x3d\x22\x61\x6e\x63\x68\x6f\x72\x22\x20\x68\x72\x65\x66\x3d\x22\x23\x53\x45\x45\x5f\x41\x4c\x53\x4f\x22\x3e\xc2\xb6\x3c\x2f\x61\x3e\x3c\x2f\x68\x31\x3e\x0a\x3c\x69\x3e\x78\x61\x75\x74\x6f\x6c\x6f\x63\x6b\x28\x31\x29\x3c\x2f\x69\x3e\x20\x2d\x20\x75\x73\x65\x20\x69\x33\x6c\x6f\x63\x6b\x20\x61\x73\x20\x79\x6f\x75\x72\x20\x73\x63\x72\x65\x65\x6e\x20\x73\x61\x76\x65\x72\x0a\x3c\x64\x69\x76\x20\x73\x74\x79\x6c\x65\x3d\x22\x68\x65\x69\x67\x68\x74\x3a\x20\x31\x2e\x30\x30\x65\x6d\x3b\x22\x3e\xc2\xa0\x3c\x2f\x64\x69\x76\x3e\x0a\x3c\x68\x31\x20\x63\x6c\x61\x73\x73\x3d\x22\x53\x68\x22\x20\x69\x64\x3d\x22\x41\x55\x54\x48\x4f\x52\x22\x3e\x41\x55\x54\x48\x4f\x52\x3c\x61\x20\x63\x6c\x61\x73\x73\x3d\x22\x61\x6e\x63\x68\x6f\x72\x22\x20\x68\x72\x65\x66\x3d\x22\x23\x41\x55\x54\x48\x4f\x52\x22\x3e\xc2\xb6\x3c\x2f\x61\x3e\x3c\x2f\x68\x31\x3e\x0a\x4d\x69\x63\x68\x61\x65\x6c\x20\x53\x74\x61\x70\x65\x6c\x62\x65\x72\x67\x20\x26\x6c\x74\x3b\x6d\x69\x63\x68\x61\x65\x6c\x2b\x69\x33\x6c\x6f\x63\x6b\x40\x65\x78\x61\x6d\x70\x6c\x65\x2e\x69\x6e\x76\x61\x6c\x69\x64\x26\x67\x74\x3b\x3c\x2f\x64\x69\x76\x3e\x0a\x3c\x74\x61\x62\x6c\x65\x20\x63\x6c\x61\x73\x73\x3d\x22\x66\x6f\x6f\x74\x22\x3e\x0a\x20\x20\x3c\x74\x62\x6f\x64\x79\x3e\x3c\x74\x72\x3e\x0a\x20\x20\x20\x20\x3c\x74\x64\x20\x63\x6c\x61\x73\x73\x3d\x22\x66\x6f\x6f\x74\x2d\x64\x61\x74\x65\x22\x3e\x4a\x41\x4e\x55\x41\x52\x59\x20\x32\x30\x31\x32\x3c\x2f\x74\x64\x3e\x0a\x20\x20\x20\x20\x3c\x74\x64\x20\x63\x6c\x61\x73\x73\x3d\x22\x66\x6f\x6f\x74\x2d\x6f\x73\x22\x3e\x4c\x69\x6e\x75\x78\x3c\x2f\x74\x64\x3e\x0a\x20\x20\x3c\x2f\x74\x72\x3e\x0a\x3c\x2f\x74\x62\x6f\x64\x79\x3e\x3c\x2f\x74\x61\x62\x6c\x65\x3e\x0a\x3c\x2f\x64\x69\x76\x3e\x0a\x3c\x2f\x64\x69\x76\x3e\x0a\x3c\x2f\x64\x69\x76\x3e\x0a\x3c\x2f\x64\x69\x76\x3e\x0a\x3c\x64\x69\x76\x20\x69\x64\x3d\x22\x66\x6f\x6f\x74\x65\x72\x22\x3e\x0a\x0a\x3c\x70\x3e\x3c\x74\x61\x62\x6c\x65\x3e\x0a\x3c\x74\x72\x3e\x0a\x3c\x74\x64\x3e\x0a\x53\x6f\x75\x72\x63\x65\x20\x66\x69\x6c\x65\x3a\x0a\x3c\x2f\x74\x64\x3e\x0a\x3c\x74\x64\x3e\x0a\x69\x33\x6c\x6f\x63\x6b\x2e\x31\x2e\x65\x6e\x2e\x67\x7a\x20\x28\x66\x72\x6f\x6d\x20\x3c\x61\x20\x68\x72\x65\x66\x3d\x22\x68\x74\x74\x70\x3a\x2f\x2f\x73\x6e\x61\x70\x73\x68\x6f\x74\x2e\x64\x65\x62\x69\x61\x6e\x2e\x6f\x72\x67\x2f\x70\x61\x63\x6b\x61\x67\x65\x2f\x64\x65\x62\x69\x6d\x61\x6e\x2f\x31\x2e\x30\x2f\x22\x3e\x64\x65\x62\x69\x6d\x61\x6e\x2d\x73\x65\x6c\x66\x74\x65\x73\x74\x20\x31\x2e\x30\x3c\x2f\x61\x3e\x29\x0a\x3c\x2f\x74\x64\x3e\x0a\x3c\x2f\x74\x72\x3e\x0a\x0a\x3c\x74\x72\x3e\x0a\x3c\x74\x64\x3e\x0a\x53\x6f\x75\x72\x63\x65\x20\x6c\x61\x73\x74\x20\x75\x70\x64\x61\x74\x65\x64\x3a\x0a\x3c\x2f\x74\x64\x3e\x0a\x3c\x74\x64\x3e\x0a\x30\x30\x30\x30\x2d\x30\x30\x2d\x30\x30\x54\x30\x30\x3a\x30\x30\x3a\x30\x30\x5a\x0a\x3c\x2f\x74\x64\x3e\x0a\x3c\x2f\x74\x72\x3e\x0a\x0a\x3c\x74\x72\x3e\x0a\x3c\x74\x64\x3e\x0a\x43\x6f\x6e\x76\x65\x72\x74\x65\x64\x20\x74\x6f\x20\x48\x54\x4d\x4c\x3a\x0a\x3c\x2f\x74\x64\x3e\x0a\x3c\x74\x64\x3e\x0a\x30\x30\x30\x30\x2d\x30\x30\x2d\x30\x30\x54\x30\x30\x3a\x30\x30\x3a\x30\x30\x5a\x0a\x3c\x2f\x74\x64\x3e\x0a\x3c\x2f\x74\x72\x3e\x0a\x0a\x3c\x2f\x74\x61\x62\x6c\x65\x3e\x3c\x2f\x70\x3e\x0a\x0a\x3c\x68\x72\x3e\x0a\x3c\x64\x69\x76\x20\x69\x64\x3d\x22\x66\x69\x6e\x65\x70\x72\x69\x6e\x74\x22\x3e\x0a\x3c\x70\x3e\x64\x65\x62\x69\x6d\x61\x6e\x20\x56\x45\x52\x53\x49\x4f\x4e\x2c\x20\x73\x65\x65\x20\x3c\x61\x20\x68\x72\x65\x66\x3d\x22\x68\x74\x74\x70\x73\x3a\x2f\x2f\x67\x69\x74\x68\x75\x62\x2e\x63\x6f\x6d\x2f\x44\x65\x62\x69\x61\x6e\x2f\x64\x65\x62\x69\x6d\x61\x6e\x2f\x22\x3e\x67\x69\x74\x68\x75\x62\x2e\x63\x6f\x6d\x2f\x44\x65\x62\x69\x61\x6e\x2f\x64\x65\x62\x69\x6d\x61\x6e\x3c\x2f\x61\x3e\x3c\x2f\x70\x3e\x0a\x3c\x2f\x64\x69\x76\x3e\x0a\x3c\x2f\x64\x69\x76\x3e\x0a\x0a\x3c\x73\x63\x72\x69\x70\x74\x20\x74\x79\x70\x65\x3d\x22\x61\x70\x70\x6c\x69\x63\x61\x74\x69\x6f\x6e\x2f\x6c\x64\x2b\x6a\x73\x6f\x6e\x22\x3e\x0a\x22\x7b\x5c\x22\x40\x63\x6f\x6e\x74\x65\x78\x74\x5c\x22\x3a\x5c\x22\x68\x74\x74\x70\x3a\x2f\x2f\x73\x63\x68\x65\x6d\x61\x2e\x6f\x72\x67\x5c\x22\x2c\x5c\x22\x40\x74\x79\x70\x65\x5c\x22\x3a\x5c\x22\x42\x72\x65\x61\x64\x63\x72\x75\x6d\x62\x4c\x69\x73\x74\x5c\x22\x2c\x5c\x22\x69\x74\x65\x6d\x4c\x69\x73\x74\x45\x6c\x65\x6d\x65\x6e\x74\x5c\x22\x3a\x5b\x7b\x5c\x22\x40\x74\x79\x70\x65\x5c\x22\x3a\x5c\x22\x4c\x69\x73\x74\x49\x74\x65\x6d\x5c\x22\x2c\x5c\x22\x70\x6f\x73\x69\x74\x69\x6f\x6e\x5c\x22\x3a\x31\x2c\x5c\x22\x69\x74\x65\x6d\x5c\x22\x3a\x7b\x5c\x22\x40\x74\x79\x70\x65\x5c\x22\x3a\x5c\x22\x54\x68\x69\x6e\x67\x5c\x22\x2c\x5c\x22\x40\x69\x64\x5c\x22\x3a\x5c\x22\x2f\x63\x6f\x6e\x74\x65\x6e\x74\x73\x2d\x73\x65\x6c\x66\x74\x65\x73\x74\x2e\x68\x74\x6d\x6c\x5c\x22\x2c\x5c\x22\x6e\x61\x6d\x65\x5c\x22\x3a\x5c\x22\x73\x65\x6c\x66\x74\x65\x73\x74\x5c\x22\x7d\x7d\x2c\x7b\x5c\x22\x40\x74\x79\x70\x65\x5c\x22\x3a\x5c\x22\x4c\x69\x73\x74\x49\x74\x65\x6d\x5c\x22\x2c\x5c\x22\x70\x6f\x73\x69\x74\x69\x6f\x6e\x5c\x22\x3a\x32\x2c\x5c\x22\x69\x74\x65\x6d\x5c\x22\x3a\x7b\x5c\x22\x40\x74\x79\x70\x65\x5c\x22\x3a\x5c\x22\x54\x68\x69\x6e\x67\x5c\x22\x2c\x5c\x22\x40\x69\x64\x5c\x22\x3a\x5c\x22\x2f\x73\x65\x6c\x66\x74\x65\x73\x74\x2f\x64\x65\x62\x69\x6d\x61\x6e\x2d\x73\x65\x6c\x66\x74\x65\x73\x74\x2f\x69\x6e\x64\x65\x78\x2e\x68\x74\x6d\x6c\x5c\x22\x2c\x5c\x22\x6e\x61\x6d\x65\x5c\x22\x3a\x5c\x22\x64\x65\x62\x69\x6d\x61\x6e\x2d\x73\x65\x6c\x66\x74\x65\x73\x74\x5c\x22\x7d\x7d\x2c\x7b\x5c\x22\x40\x74\x79\x70\x65\x5c\x22\x3a\x5c\x22\x4c\x69\x73\x74\x49\x74\x65\x6d\x5c\x22\x2c\x5c\x22\x70\x6f\x73\x69\x74\x69\x6f\x6e\x5c\x22\x3a\x33\x2c\x5c\x22\x69\x74\x65\x6d\x5c\x22\x3a\x7b\x5c\x22\x40\x74\x79\x70\x65\x5c\x22\x3a\x5c\x22\x54\x68\x69\x6e\x67\x5c\x22\x2c\x5c\x22\x40\x69\x64\x5c\x22\x3a\x5c\x22\x5c\x22\x2c\x5c\x22\x6e\x61\x6d\x65\x5c\x22\x3a\x5c\x22\x69\x33\x6c\x6f\x63\x6b\x28\x31\x29\x5c\x22\x7d\x7d\x5d\x7d\x22\x0a\x3c\x2f\x73\x63\x72\x69\x70\x74\x3e\x0a\x3c\x73\x63\x72\x69\x70\x74\x20\x74\x79\x70\x65\x3d\x22\x61\x70\x70\x6c\x69\x63\x61\x74\x69\x6f\x6e\x2f\x6c\x64\x2b\x6a\x73\x6f\x6e\x22\x3e\x0a\x22\x7b\x5c\x22\x40\x63\x6f\x6e\x74\x65\x78\x74\x5c\x22\x3a\x5c\x22\x68\x74\x74\x70\x3a\x2f\x2f\x73\x63\x68\x65\x6d\x61\x2e\x6f\x72\x67\x5c\x22\x2c\x5c\x22\x40\x74\x79\x70\x65\x5c\x22\x3a\x5c\x22\x54\x65\x63\x68\x41\x72\x74\x69\x63\x6c\x65\x5c\x22\x2c\x5c\x22\x6e\x61\x6d\x65\x5c\x22\x3a\x5c\x22\x69\x33\x6c\x6f\x63\x6b\x28\x31\x29\x5c\x22\x2c\x5c\x22\x61\x72\x74\x69\x63\x6c\x65\x53\x65\x63\x74\x69\x6f\x6e\x5c\x22\x3a\x5c\x22\x31\x5c\x22\x2c\x5c\x22\x61\x62\x6f\x75\x74\x5c\x22\x3a\x7b\x5c\x22\x40\x74\x79\x70\x65\x5c\x22\x3a\x5c\x22\x53\x6f\x66\x74\x77\x61\x72\x65\x41\x70\x70\x6c\x69\x63\x61\x74\x69\x6f\x6e\x5c\x22\x2c\x5c\x22\x6e\x61\x6d\x65\x5c\x22\x3a\x5c\x22\x64\x65\x62\x69\x6d\x61\x6e\x2d\x73\x65\x6c\x66\x74\x65\x73\x74\x5c\x22\x2c\x5c\x22\x73\x6f\x66\x74\x77\x61\x72\x65\x56\x65\x72\x73\x69\x6f\x6e\x5c\x22\x3a\x5c\x22\x31\x2e\x30\x5c\x22\x7d\x2c\x5c\x22\x64\x61\x74\x65\x4d\x6f\x64\x69\x66\x69\x65\x64\x5c\x22\x3a\x5c\x22\x30\x30\x30\x30\x2d\x30\x30\x2d\x30\x30\x54\x30\x30\x3a\x30\x30\x3a\x30\x30\x5a\x5c\x22\x7d\x22\x0a\x3c\x2f\x73\x63\x72\x69\x70\x74\x3e\x0a"
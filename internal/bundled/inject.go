@@ -57,3 +57,21 @@ func AssetsFiltered(cb func(string) bool) map[string]string {
 	}
 	return result
 }
+
+// InjectFiles is like Inject, but only considers the files with the
+// given names, which do not all need to be present in dir.
+func InjectFiles(dir string, names []string) error {
+	for _, fn := range names {
+		path := filepath.Join(dir, fn)
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		log.Printf("Overwriting bundled asset %q (len %d) with %q (len %d)", fn, len(assets["assets/"+fn]), path, len(b))
+		assets["assets/"+fn] = string(b)
+	}
+	return nil
+}
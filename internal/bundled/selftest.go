@@ -0,0 +1,14 @@
+package bundled
+
+import "strings"
+
+// SelftestFiles returns the samples of debiman’s -selftest and their
+// golden pages (see assets/selftest), keyed by their file name, e.g.
+// i3lock.1 and i3lock.1.html. Unlike assets, they cannot be injected.
+func SelftestFiles() map[string]string {
+	result := make(map[string]string, len(selftest))
+	for fn, val := range selftest {
+		result[strings.TrimPrefix(fn, "assets/selftest/")] = val
+	}
+	return result
+}
@@ -1,7 +1,9 @@
 package commontmpl
 
 import (
+	"crypto/sha256"
 	"flag"
+	"fmt"
 	"html/template"
 	"log"
 	"net/url"
@@ -13,6 +15,8 @@ import (
 	"golang.org/x/text/language/display"
 
 	"github.com/Debian/debiman/internal/bundled"
+	"github.com/Debian/debiman/internal/l10n"
+	"github.com/Debian/debiman/internal/manpage"
 )
 
 const iso8601Format = "2006-01-02T15:04:05Z"
@@ -41,8 +45,74 @@ func BaseURLPath() string {
 	return baseURLPath
 }
 
+// InlineCSS returns whether the -inline_css flag is set. Programs which
+// do not define the flag (e.g. debiman-minisrv) link the stylesheet.
+func InlineCSS() bool {
+	f := flag.Lookup("inline_css")
+	return f != nil && f.Value.String() == "true"
+}
+
+// Offline returns whether the -offline flag is set, in which case pages
+// omit the links which only work when served by debiman-auxserver (e.g.
+// the search box). Programs which do not define the flag keep them.
+func Offline() bool {
+	f := flag.Lookup("offline")
+	return f != nil && f.Value.String() == "true"
+}
+
+// PageMetadata returns whether the -page_metadata flag is set, in which
+// case pages state the license and source of their manpage in meta
+// tags. Programs which do not define the flag omit the meta tags.
+func PageMetadata() bool {
+	f := flag.Lookup("page_metadata")
+	return f != nil && f.Value.String() == "true"
+}
+
+// Icons are the names of the bundled icon assets, which debiman places
+// in -serving_dir and debiman-auxserver serves. They can be replaced
+// using the -icons flag.
+var Icons = []string{"favicon.ico", "favicon.svg", "apple-touch-icon.png"}
+
+// AssetVersion returns a short hash of the bundled asset with the
+// given name, to be used for cache-busting in URLs.
+func AssetVersion(basename string) string {
+	h := sha256.Sum256([]byte(bundled.Asset(basename)))
+	return fmt.Sprintf("%x", h[:4])
+}
+
+// StylesheetURL returns the URL of the stylesheet, which pages link
+// to unless -inline_css is set.
+func StylesheetURL() string {
+	return BaseURLPath() + "/style.css?" + AssetVersion("style.css")
+}
+
+// PreloadLinks returns the values of the Link headers with which
+// debiman-auxserver advertises the stylesheet of the pages it serves
+// and redirects to. The web fonts are not preloaded: style.css prefers
+// locally installed fonts, so they might not be needed at all.
+func PreloadLinks() []string {
+	return []string{"<" + StylesheetURL() + ">; rel=preload; as=style"}
+}
+
 func MustParseCommonTmpls() *template.Template {
+	// Assets do not change after parsing the templates, so
+	// AssetVersion only needs to be computed once per asset.
+	var (
+		versionsMu sync.Mutex
+		versions   = make(map[string]string)
+	)
+	assetVersion := func(basename string) string {
+		versionsMu.Lock()
+		defer versionsMu.Unlock()
+		if v, ok := versions[basename]; ok {
+			return v
+		}
+		v := AssetVersion(basename)
+		versions[basename] = v
+		return v
+	}
 	funcmap := template.FuncMap{
+		"AssetVersion": assetVersion,
 		"DisplayLang": func(tag language.Tag) string {
 			lang := display.Self.Name(tag)
 			// Some languages are not present in the Unicode CLDR,
@@ -70,6 +140,25 @@ func MustParseCommonTmpls() *template.Template {
 		"BaseURLPath": func() string {
 			return BaseURLPath()
 		},
+		"StylesheetURL": func() string {
+			// Like StylesheetURL, but with the cached version.
+			return BaseURLPath() + "/style.css?" + assetVersion("style.css")
+		},
+		"InlineCSS": func() bool {
+			return InlineCSS()
+		},
+		"PageMetadata": func() bool {
+			return PageMetadata()
+		},
+		"Offline": func() bool {
+			return Offline()
+		},
+		"T": func(m *manpage.Meta, msg string) string {
+			if m == nil {
+				return msg
+			}
+			return l10n.Translate(m.Language, msg)
+		},
 		"Now": func() string {
 			return time.Now().UTC().Format(iso8601Format)
 		}}
@@ -78,5 +167,6 @@ func MustParseCommonTmpls() *template.Template {
 	t = template.Must(t.New("header").Funcs(funcmap).Parse(bundled.Asset("header.tmpl")))
 	t = template.Must(t.New("footer").Funcs(funcmap).Parse(bundled.Asset("footer.tmpl")))
 	t = template.Must(t.New("style").Funcs(funcmap).Parse(bundled.Asset("style.css")))
+	t = template.Must(t.New("manifest").Funcs(funcmap).Parse(bundled.Asset("manifest.webmanifest")))
 	return t
 }
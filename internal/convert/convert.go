@@ -10,6 +10,8 @@ import (
 	"unicode"
 
 	"golang.org/x/net/html"
+
+	"github.com/Debian/debiman/internal/manpage"
 )
 
 var heading = map[string]bool{
@@ -257,128 +259,20 @@ func stripAttr(n *html.Node, key, val string) {
 	n.Attr = stripped
 }
 
-func postprocess(resolve func(ref string) string, n *html.Node, toc *[]string) error {
-	if n.Parent == nil {
-		return nil
-	}
-
-	// Remove <html>, <head> and <body> tags, as we are dealing with
-	// an HTML fragment that is included in an existing document, not
-	// a document itself.
-	if n.Type == html.ElementNode &&
-		(n.Data == "html" ||
-			n.Data == "head" ||
-			n.Data == "body") {
-		c := n.FirstChild
-		for c != nil {
-			next := c.NextSibling
-			n.RemoveChild(c)
-			n.Parent.InsertBefore(c, n)
-			c = next
-		}
-		n.Parent.RemoveChild(n)
-		return nil
-	}
-
-	if n.Type == html.ElementNode && n.Data == "a" {
-		// Remove title= attribute which mandoc ≥ 1.14.2 spits out: browsers
-		// show it as a mouse hover text, but it just contains the tag type
-		// (e.g. Lk for links).
-		stripAttr(n, "title", "Lk")
-		return nil
-	}
-
-	if n.Type == html.ElementNode && heading[n.Data] {
-		// Derive and set an id="" attribute for the heading
-		text := plaintext(n)
-		// Remove any line breaks (observed with mandoc ≥ 1.14.2): they are
-		// invisible and change the IDs, which we would like to keep stable to
-		// prevent dead links.
-		text = strings.Replace(text, "\n", " ", -1)
-		text = strings.Replace(text, "\r", " ", -1)
-		for strings.Contains(text, "  ") {
-			text = strings.Replace(text, "  ", " ", -1)
-		}
-		// Remove <a class="selflink"> which mandoc ≥ 1.14.2 spits out.
-		for n.FirstChild != nil {
-			n.RemoveChild(n.FirstChild)
-		}
-		n.AppendChild(&html.Node{
-			Type: html.TextNode,
-			Data: text,
-		})
-		// Remove title= attribute which mandoc ≥ 1.14.2 spits out: browsers
-		// show it as a mouse hover text, but it just contains the tag type
-		// (e.g. Sh for section headers).
-		stripAttr(n, "title", "")
-		// HTML5 requires that ids must contain at least one character
-		// and may not contain any spaces, see
-		// http://stackoverflow.com/a/79022/712014
-		id := strings.Replace(text, " ", "_", -1)
-		u := url.URL{Fragment: id}
-		replaceId(n, id)
-		// Insert an <a> element into the heading, after the text. Via
-		// CSS, this link will only be made visible while hovering.
-		a := &html.Node{
-			Type: html.ElementNode,
-			Data: "a",
-			Attr: []html.Attribute{
-				{Key: "class", Val: "anchor"},
-				{Key: "href", Val: u.String()},
-			},
-		}
-		a.AppendChild(&html.Node{
-			Type: html.TextNode,
-			Data: "¶",
-		})
-		n.AppendChild(a)
-
-		if n.Data == "h1" && toc != nil {
-			*toc = append(*toc, text)
-		}
-	}
-
-	if resolve == nil {
-		return nil
-	}
-
-	// resolve cross references
-	if n.Type == html.TextNode {
-		replacements := xref(n.Data, resolve)
-		for _, r := range replacements {
-			n.Parent.InsertBefore(r, n)
-		}
-		if replacements != nil {
-			n.Parent.RemoveChild(n)
-			return nil
-		}
-	}
-	if n.Type == html.TextNode &&
-		strings.HasPrefix(n.Data, "(") &&
-		strings.Index(n.Data, ")") > -1 &&
-		n.PrevSibling != nil {
-		replacements := xref(plaintext(n.PrevSibling)+n.Data, resolve)
-		if replacements != nil {
-			n.Parent.RemoveChild(n.PrevSibling)
-			for _, r := range replacements {
-				n.Parent.InsertBefore(r, n)
-			}
-			n.Parent.RemoveChild(n)
-		}
-		return nil
-	}
-
-	return nil
-
-}
-
 // TODO(stapelberg): ToHTML’s output currently is used directly as
 // (html/template).HTML, i.e. “known safe HTML document fragment”. We
 // should be more aggressive in whitelisting the allowed tags.
 //
 // resolve, if non-nil, will be called to resolve a reference (like
-// “rm(1)”) into a URL.
+// “rm(1)”) into a URL. The output of mandoc is processed by the post
+// processors of p (see WithPostProcessors).
 func (p *Process) ToHTML(r io.Reader, resolve func(ref string) string) (doc string, toc []string, err error) {
+	return p.ToHTMLFor(r, resolve, nil)
+}
+
+// ToHTMLFor is like ToHTML, but passes meta, the manpage read from r,
+// to the post processors.
+func (p *Process) ToHTMLFor(r io.Reader, resolve func(ref string) string, meta *manpage.Meta) (doc string, toc []string, err error) {
 	stdout, stderr, err := p.mandoc(r)
 	if stderr != "" {
 		return "", nil, fmt.Errorf("mandoc failed: %v", stderr)
@@ -392,13 +286,13 @@ func (p *Process) ToHTML(r io.Reader, resolve func(ref string) string) (doc stri
 		return "", nil, err
 	}
 
-	err = recurse(parsed, func(n *html.Node) error { return postprocess(resolve, n, &toc) })
-	if err != nil {
-		return "", toc, err
+	ctx := &PostContext{Meta: meta, Resolve: resolve}
+	if err := postprocess(p.postProcessors, parsed, ctx); err != nil {
+		return "", ctx.TOC, err
 	}
 	var rendered bytes.Buffer
 	if err := html.Render(&rendered, parsed); err != nil {
-		return "", toc, err
+		return "", ctx.TOC, err
 	}
-	return rendered.String(), toc, nil
+	return rendered.String(), ctx.TOC, nil
 }
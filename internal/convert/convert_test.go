@@ -401,7 +401,7 @@ func TestFormattedXref(t *testing.T) {
 	want := []*html.Node{p}
 
 	got := formattedXrefInput()
-	if err := recurse(got, func(n *html.Node) error { return postprocess(func(ref string) string { return ref }, n, nil) }); err != nil {
+	if err := XrefPostProcessor.Process(got, &PostContext{Resolve: func(ref string) string { return ref }}); err != nil {
 		t.Fatal(err)
 	}
 	if err := cmpElems(input, []*html.Node{got}, want); err != nil {
@@ -9,21 +9,165 @@ import (
 	"net"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
 	"strings"
 	"syscall"
 
 	"golang.org/x/sync/errgroup"
 )
 
+// MinMandocVersion is the oldest mandoc version whose HTML output
+// works with debiman’s post-processing and stylesheet.
+const MinMandocVersion = "1.13.3"
+
+// Command is a mandoc(1) invocation which converts a manpage on stdin
+// into an HTML fragment on stdout.
+type Command struct {
+	// Path is the mandoc program, either a file name which is looked
+	// up in $PATH or a path.
+	Path string
+
+	// Args are passed to mandoc to convert a manpage.
+	Args []string
+}
+
+// DefaultArgs are the arguments which debiman is tested with.
+var DefaultArgs = []string{"-Ofragment", "-Thtml"}
+
+// Mandoc is used by CheckMandoc, Probe and all Processes.
+var Mandoc = Command{Path: "mandoc", Args: DefaultArgs}
+
+// mandocd returns the path of the mandocd(8) program which belongs to
+// Mandoc.Path, i.e. which is located in the same directory.
+func mandocd() (string, error) {
+	if strings.Contains(Mandoc.Path, "/") {
+		return exec.LookPath(filepath.Join(filepath.Dir(Mandoc.Path), "mandocd"))
+	}
+	return exec.LookPath("mandocd")
+}
+
+var mandocVersionRe = regexp.MustCompile(`\bmandoc[^0-9]*([0-9]+(?:\.[0-9]+)*)`)
+
+// parseMandocVersion extracts the version number from the output of
+// mandoc -V, e.g. “mandoc 1.14.3”.
+func parseMandocVersion(out string) (string, error) {
+	matches := mandocVersionRe.FindStringSubmatch(out)
+	if matches == nil {
+		return "", fmt.Errorf("could not find version number in %q", strings.TrimSpace(out))
+	}
+	return matches[1], nil
+}
+
+// compareVersions compares two dot-separated version numbers and
+// returns -1, 0 or +1 when a is older, equal or newer than b.
+func compareVersions(a, b string) int {
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var an, bn int
+		if i < len(as) {
+			an, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			bn, _ = strconv.Atoi(bs[i])
+		}
+		if an < bn {
+			return -1
+		}
+		if an > bn {
+			return 1
+		}
+	}
+	return 0
+}
+
+// CheckMandoc locates mandoc(1) (see Mandoc) and verifies that it is at least
+// minVersion (MinMandocVersion if empty). The detected version is
+// returned, so that it can be recorded for provenance.
+func CheckMandoc(minVersion string) (version string, err error) {
+	if minVersion == "" {
+		minVersion = MinMandocVersion
+	}
+	path, err := exec.LookPath(Mandoc.Path)
+	if err != nil {
+		return "", fmt.Errorf("%s not found (install the mandoc Debian package): %v", Mandoc.Path, err)
+	}
+	var out bytes.Buffer
+	cmd := exec.Command(path, "-V")
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s -V: %v (output: %q)", path, err, strings.TrimSpace(out.String()))
+	}
+	version, err = parseMandocVersion(out.String())
+	if err != nil {
+		return "", fmt.Errorf("%s -V: %v", path, err)
+	}
+	if compareVersions(version, minVersion) < 0 {
+		return version, fmt.Errorf("%s is version %s, but at least version %s is required", path, version, minVersion)
+	}
+	return version, nil
+}
+
+// probeManpage is a minimal manpage, see Probe.
+const probeManpage = `.TH PROBE 1
+.SH NAME
+probe \- verify the mandoc invocation
+`
+
+// Probe converts a minimal manpage with Mandoc, so that e.g. arguments
+// which mandoc does not understand result in an error at startup
+// instead of in an error for every single manpage.
+func Probe() error {
+	p, err := NewProcess()
+	if err != nil {
+		return err
+	}
+	defer p.Kill()
+	invocation := strings.Join(append([]string{Mandoc.Path}, Mandoc.Args...), " ")
+	doc, _, err := p.ToHTML(strings.NewReader(probeManpage), nil)
+	if err != nil {
+		return fmt.Errorf("converting a test manpage with %q: %v", invocation, err)
+	}
+	if !strings.Contains(doc, "verify the mandoc invocation") {
+		return fmt.Errorf("converting a test manpage with %q: manpage text not found in output %q", invocation, doc)
+	}
+	return nil
+}
+
 // Process starts a mandoc process to convert manpages to HTML.
 type Process struct {
 	mandocConn    *net.UnixConn
 	mandocProcess *os.Process
 	stopWait      chan bool
+
+	// postProcessors are run in order by each conversion of ToHTML
+	// and ToHTMLFor.
+	postProcessors []PostProcessor
+}
+
+// An Option configures a Process, see NewProcess.
+type Option func(*Process)
+
+// WithPostProcessors makes the Process run processors (in order) on
+// the output of mandoc instead of DefaultPostProcessors.
+func WithPostProcessors(processors []PostProcessor) Option {
+	processors = append([]PostProcessor(nil), processors...)
+	return func(p *Process) {
+		p.postProcessors = processors
+	}
 }
 
-func NewProcess() (*Process, error) {
-	p := &Process{}
+func NewProcess(opts ...Option) (*Process, error) {
+	p := &Process{
+		postProcessors: append([]PostProcessor(nil), DefaultPostProcessors...),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
 	return p, p.initMandoc()
 }
 
@@ -36,6 +180,21 @@ func (p *Process) Kill() error {
 }
 
 func (p *Process) initMandoc() error {
+	if !reflect.DeepEqual(Mandoc.Args, DefaultArgs) {
+		// mandocd(8) does not accept the arguments of mandoc(1).
+		log.Printf("using mandoc arguments %q, falling back to fork+exec for each manpage", Mandoc.Args)
+		return nil
+	}
+
+	path, err := mandocd()
+	if err != nil {
+		if ee, ok := err.(*exec.Error); ok && (ee.Err == exec.ErrNotFound || os.IsNotExist(ee.Err)) {
+			log.Printf("mandocd not found, falling back to fork+exec for each manpage")
+			return nil
+		}
+		return err
+	}
+
 	pair, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM, 0)
 	if err != nil {
 		return err
@@ -50,15 +209,6 @@ func (p *Process) initMandoc() error {
 	}
 	conn := fc.(*net.UnixConn)
 
-	path, err := exec.LookPath("mandocd")
-	if err != nil {
-		if ee, ok := err.(*exec.Error); ok && ee.Err == exec.ErrNotFound {
-			log.Printf("mandocd not found, falling back to fork+exec for each manpage")
-			return nil
-		}
-		return err
-	}
-
 	cmd := exec.Command(path, "-Thtml", "3") // Go dup2()s ExtraFiles to 3 and onwards
 	cmd.ExtraFiles = []*os.File{os.NewFile(uintptr(pair[1]), "")}
 	cmd.Stdout = os.Stdout
@@ -109,7 +259,7 @@ func (p *Process) mandoc(r io.Reader) (stdout string, stderr string, err error)
 
 func (p *Process) mandocFork(r io.Reader) (stdout string, stderr string, err error) {
 	var stdoutb, stderrb bytes.Buffer
-	cmd := exec.Command("mandoc", "-Ofragment", "-Thtml")
+	cmd := exec.Command(Mandoc.Path, Mandoc.Args...)
 	cmd.Stdin = r
 	cmd.Stdout = &stdoutb
 	cmd.Stderr = &stderrb
@@ -0,0 +1,128 @@
+package convert
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseMandocVersion(t *testing.T) {
+	table := []struct {
+		out  string
+		want string
+	}{
+		{out: "mandoc 1.14.3\n", want: "1.14.3"},
+		{out: "mandoc 1.13.3\n", want: "1.13.3"},
+		{out: "mandoc version 1.14.1", want: "1.14.1"},
+		{out: "mandoc: -V: Bad argument\n", want: ""},
+		{out: "", want: ""},
+	}
+	for _, entry := range table {
+		got, err := parseMandocVersion(entry.out)
+		if entry.want == "" {
+			if err == nil {
+				t.Errorf("parseMandocVersion(%q) = %q, want error", entry.out, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseMandocVersion(%q): %v", entry.out, err)
+			continue
+		}
+		if got != entry.want {
+			t.Errorf("parseMandocVersion(%q) = %q, want %q", entry.out, got, entry.want)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	table := []struct {
+		a, b string
+		want int
+	}{
+		{"1.14.3", "1.14.3", 0},
+		{"1.14.3", "1.13.3", 1},
+		{"1.13.3", "1.14.1", -1},
+		{"1.14", "1.14.0", 0},
+		{"1.14", "1.14.1", -1},
+		{"1.10.1", "1.9.9", 1},
+	}
+	for _, entry := range table {
+		if got := compareVersions(entry.a, entry.b); got != entry.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", entry.a, entry.b, got, entry.want)
+		}
+	}
+}
+
+func TestProbe(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "debiman-probe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+	defer func(m Command) { Mandoc = m }(Mandoc)
+
+	good := filepath.Join(tmpdir, "good")
+	if err := ioutil.WriteFile(good, []byte("#!/bin/sh\ncat >/dev/null\necho '<p>probe - verify the mandoc invocation</p>'\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	bad := filepath.Join(tmpdir, "bad")
+	if err := ioutil.WriteFile(bad, []byte("#!/bin/sh\necho \"mandoc: $1: Bad argument\" >&2\nexit 5\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range []struct {
+		cmd    Command
+		wantOK bool
+	}{
+		{Command{Path: good, Args: DefaultArgs}, true},
+		{Command{Path: good, Args: []string{"-Thtml", "-Ostyle=/style.css"}}, true},
+		{Command{Path: bad, Args: []string{"-Obogus"}}, false},
+		{Command{Path: filepath.Join(tmpdir, "missing"), Args: DefaultArgs}, false},
+	} {
+		Mandoc = entry.cmd
+		if err := Probe(); (err == nil) != entry.wantOK {
+			t.Errorf("Probe() with %v = %v, want success: %v", entry.cmd, err, entry.wantOK)
+		}
+	}
+}
+
+func TestInitMandocFallbackFds(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "debiman-initmandoc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+	defer func(m Command) { Mandoc = m }(Mandoc)
+
+	openFds := func() int {
+		fds, err := ioutil.ReadDir("/proc/self/fd")
+		if err != nil {
+			t.Skip(err)
+		}
+		return len(fds)
+	}
+
+	// Neither of these start mandocd, so they must not leave any file
+	// descriptors open.
+	for _, cmd := range []Command{
+		{Path: "mandoc", Args: []string{"-Thtml", "-Ostyle=/style.css"}},
+		{Path: filepath.Join(tmpdir, "mandoc"), Args: DefaultArgs},
+	} {
+		Mandoc = cmd
+		before := openFds()
+		for i := 0; i < 10; i++ {
+			p := &Process{}
+			if err := p.initMandoc(); err != nil {
+				t.Fatal(err)
+			}
+			if p.mandocConn != nil {
+				t.Fatalf("initMandoc() with %v started mandocd", cmd)
+			}
+		}
+		if after := openFds(); after != before {
+			t.Errorf("initMandoc() with %v: %d file descriptors open, want %d", cmd, after, before)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package convert
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+
+	"github.com/Debian/debiman/internal/manpage"
+)
+
+// PostContext is passed to each PostProcessor of a conversion.
+type PostContext struct {
+	// Meta is the manpage being converted, or nil if unknown (e.g.
+	// for ToHTML).
+	Meta *manpage.Meta
+
+	// Resolve, if non-nil, resolves a reference (like “rm(1)”) into a
+	// URL.
+	Resolve func(ref string) string
+
+	// TOC collects the section headings of the manpage, see ToHTML.
+	TOC []string
+}
+
+// A PostProcessor is a pass over the HTML which mandoc generated for a
+// manpage, modifying the parsed document in place.
+type PostProcessor struct {
+	// Name identifies the processor in RegisterPostProcessor and
+	// FindPostProcessors, e.g. “xref”.
+	Name string
+
+	// Process modifies doc, the parsed output of mandoc (or of the
+	// previous processor).
+	Process func(doc *html.Node, ctx *PostContext) error
+
+	// PerManpage must be true if the output of Process depends on
+	// ctx.Meta, i.e. if manpages with identical content can result in
+	// different HTML. Callers must not reuse such output across
+	// manpages (e.g. debiman’s render cache).
+	PerManpage bool
+}
+
+// The built-in processors, in the order in which DefaultPostProcessors
+// runs them.
+var (
+	// XrefPostProcessor turns references to other manpages (e.g.
+	// “rm(1)”, see PostContext.Resolve) and URLs into links.
+	XrefPostProcessor = PostProcessor{Name: "xref", Process: xrefPass}
+
+	// AnchorsPostProcessor derives stable ids from the text of
+	// headings, appends a ¶ link to each heading and collects the
+	// section headings into PostContext.TOC.
+	AnchorsPostProcessor = PostProcessor{Name: "anchors", Process: anchorsPass}
+
+	// SanitizePostProcessor removes the <html>, <head> and <body>
+	// elements, as the result is a fragment, and the title attributes
+	// which mandoc ≥ 1.14.2 adds. Without it, the result is not
+	// suitable for debiman’s templates.
+	SanitizePostProcessor = PostProcessor{Name: "sanitize", Process: sanitizePass}
+)
+
+// DefaultPostProcessors are the processors a Process runs unless
+// configured otherwise with WithPostProcessors.
+var DefaultPostProcessors = []PostProcessor{
+	XrefPostProcessor,
+	AnchorsPostProcessor,
+	SanitizePostProcessor,
+}
+
+// registered are the processors FindPostProcessors can find by name,
+// see RegisterPostProcessor.
+var registered = struct {
+	sync.Mutex
+	processors []PostProcessor
+}{processors: append([]PostProcessor(nil), DefaultPostProcessors...)}
+
+// RegisterPostProcessor makes p available to FindPostProcessors. To add
+// site-specific processing without modifying debiman, call it (e.g.
+// from an init function) before flags are evaluated.
+func RegisterPostProcessor(p PostProcessor) {
+	registered.Lock()
+	defer registered.Unlock()
+	registered.processors = append(registered.processors, p)
+}
+
+// RegisteredPostProcessors returns a copy of the processors
+// FindPostProcessors can find by name: the built-in processors,
+// followed by those added with RegisterPostProcessor.
+func RegisteredPostProcessors() []PostProcessor {
+	registered.Lock()
+	defer registered.Unlock()
+	return append([]PostProcessor(nil), registered.processors...)
+}
+
+// FindPostProcessors returns the RegisteredPostProcessors of names, in
+// the order of names.
+func FindPostProcessors(names []string) ([]PostProcessor, error) {
+	candidates := RegisteredPostProcessors()
+	result := make([]PostProcessor, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if seen[name] {
+			return nil, fmt.Errorf("post processor %q specified more than once", name)
+		}
+		seen[name] = true
+		var found bool
+		for _, p := range candidates {
+			if p.Name == name {
+				result = append(result, p)
+				found = true
+				break
+			}
+		}
+		if !found {
+			known := make([]string, 0, len(candidates))
+			for _, p := range candidates {
+				known = append(known, p.Name)
+			}
+			return nil, fmt.Errorf("unknown post processor %q (known: %s)", name, strings.Join(known, ", "))
+		}
+	}
+	return result, nil
+}
+
+// postprocess runs processors on doc.
+func postprocess(processors []PostProcessor, doc *html.Node, ctx *PostContext) error {
+	for _, p := range processors {
+		if err := p.Process(doc, ctx); err != nil {
+			return fmt.Errorf("post processor %q: %v", p.Name, err)
+		}
+	}
+	return nil
+}
+
+func xrefPass(doc *html.Node, ctx *PostContext) error {
+	if ctx.Resolve == nil {
+		return nil
+	}
+	return recurse(doc, func(n *html.Node) error {
+		if n.Type != html.TextNode {
+			return nil
+		}
+		replacements := xref(n.Data, ctx.Resolve)
+		for _, r := range replacements {
+			n.Parent.InsertBefore(r, n)
+		}
+		if replacements != nil {
+			n.Parent.RemoveChild(n)
+			return nil
+		}
+		if strings.HasPrefix(n.Data, "(") &&
+			strings.Index(n.Data, ")") > -1 &&
+			n.PrevSibling != nil {
+			replacements := xref(plaintext(n.PrevSibling)+n.Data, ctx.Resolve)
+			if replacements != nil {
+				n.Parent.RemoveChild(n.PrevSibling)
+				for _, r := range replacements {
+					n.Parent.InsertBefore(r, n)
+				}
+				n.Parent.RemoveChild(n)
+			}
+		}
+		return nil
+	})
+}
+
+func anchorsPass(doc *html.Node, ctx *PostContext) error {
+	return recurse(doc, func(n *html.Node) error {
+		if n.Type != html.ElementNode || !heading[n.Data] {
+			return nil
+		}
+		// Derive and set an id="" attribute for the heading
+		text := plaintext(n)
+		// Remove any line breaks (observed with mandoc ≥ 1.14.2): they are
+		// invisible and change the IDs, which we would like to keep stable to
+		// prevent dead links.
+		text = strings.Replace(text, "\n", " ", -1)
+		text = strings.Replace(text, "\r", " ", -1)
+		for strings.Contains(text, "  ") {
+			text = strings.Replace(text, "  ", " ", -1)
+		}
+		// Remove <a class="selflink"> which mandoc ≥ 1.14.2 spits out.
+		for n.FirstChild != nil {
+			n.RemoveChild(n.FirstChild)
+		}
+		n.AppendChild(&html.Node{
+			Type: html.TextNode,
+			Data: text,
+		})
+		// HTML5 requires that ids must contain at least one character
+		// and may not contain any spaces, see
+		// http://stackoverflow.com/a/79022/712014
+		id := strings.Replace(text, " ", "_", -1)
+		u := url.URL{Fragment: id}
+		replaceId(n, id)
+		// Insert an <a> element into the heading, after the text. Via
+		// CSS, this link will only be made visible while hovering.
+		a := &html.Node{
+			Type: html.ElementNode,
+			Data: "a",
+			Attr: []html.Attribute{
+				{Key: "class", Val: "anchor"},
+				{Key: "href", Val: u.String()},
+			},
+		}
+		a.AppendChild(&html.Node{
+			Type: html.TextNode,
+			Data: "¶",
+		})
+		n.AppendChild(a)
+
+		if n.Data == "h1" {
+			ctx.TOC = append(ctx.TOC, text)
+		}
+		return nil
+	})
+}
+
+func sanitizePass(doc *html.Node, ctx *PostContext) error {
+	return recurse(doc, func(n *html.Node) error {
+		if n.Type != html.ElementNode {
+			return nil
+		}
+		switch {
+		case n.Data == "html" || n.Data == "head" || n.Data == "body":
+			// Remove <html>, <head> and <body> tags, as we are dealing
+			// with an HTML fragment that is included in an existing
+			// document, not a document itself.
+			c := n.FirstChild
+			for c != nil {
+				next := c.NextSibling
+				n.RemoveChild(c)
+				n.Parent.InsertBefore(c, n)
+				c = next
+			}
+			n.Parent.RemoveChild(n)
+
+		case n.Data == "a":
+			// Remove title= attribute which mandoc ≥ 1.14.2 spits out:
+			// browsers show it as a mouse hover text, but it just
+			// contains the tag type (e.g. Lk for links).
+			stripAttr(n, "title", "Lk")
+
+		case heading[n.Data]:
+			// Remove title= attribute which mandoc ≥ 1.14.2 spits out
+			// (e.g. Sh for section headers).
+			stripAttr(n, "title", "")
+		}
+		return nil
+	})
+}
@@ -0,0 +1,131 @@
+package convert
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+
+	"github.com/Debian/debiman/internal/manpage"
+)
+
+func runPostProcessors(t *testing.T, processors []PostProcessor, in string, ctx *PostContext) string {
+	doc, err := html.Parse(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := postprocess(processors, doc, ctx); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := html.Render(&buf, doc); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+// mandocOutput resembles the output of mandoc ≥ 1.14.2.
+const mandocOutput = `<html><head></head><body><h1 class="Sh" title="Sh" id="SEE_ALSO"><a class="selflink" href="#SEE_ALSO">SEE
+ALSO</a></h1><b>rm</b>(1), <a href="https://www.debian.org/" title="Lk">debian</a></body></html>`
+
+func TestPostProcessors(t *testing.T) {
+	resolve := func(ref string) string { return "/" + ref }
+	for _, entry := range []struct {
+		processors []PostProcessor
+		want       string
+		toc        []string
+	}{
+		{
+			processors: []PostProcessor{XrefPostProcessor},
+			want:       `<html><head></head><body><h1 class="Sh" title="Sh" id="SEE_ALSO"><a class="selflink" href="#SEE_ALSO">SEE` + "\n" + `ALSO</a></h1><a href="/rm(1)">rm(1)</a>, <a href="https://www.debian.org/" title="Lk">debian</a></body></html>`,
+		},
+		{
+			processors: []PostProcessor{AnchorsPostProcessor},
+			want:       `<html><head></head><body><h1 class="Sh" title="Sh" id="SEE_ALSO">SEE ALSO<a class="anchor" href="#SEE_ALSO">¶</a></h1><b>rm</b>(1), <a href="https://www.debian.org/" title="Lk">debian</a></body></html>`,
+			toc:        []string{"SEE ALSO"},
+		},
+		{
+			processors: []PostProcessor{SanitizePostProcessor},
+			want:       `<h1 class="Sh" id="SEE_ALSO"><a class="selflink" href="#SEE_ALSO">SEE` + "\n" + `ALSO</a></h1><b>rm</b>(1), <a href="https://www.debian.org/">debian</a>`,
+		},
+		{
+			processors: DefaultPostProcessors,
+			want:       `<h1 class="Sh" id="SEE_ALSO">SEE ALSO<a class="anchor" href="#SEE_ALSO">¶</a></h1><a href="/rm(1)">rm(1)</a>, <a href="https://www.debian.org/">debian</a>`,
+			toc:        []string{"SEE ALSO"},
+		},
+	} {
+		var names []string
+		for _, p := range entry.processors {
+			names = append(names, p.Name)
+		}
+		ctx := &PostContext{Resolve: resolve}
+		if got := runPostProcessors(t, entry.processors, mandocOutput, ctx); got != entry.want {
+			t.Errorf("%v: unexpected output:\ngot  %s\nwant %s", names, got, entry.want)
+		}
+		if !reflect.DeepEqual(ctx.TOC, entry.toc) {
+			t.Errorf("%v: unexpected TOC: got %q, want %q", names, ctx.TOC, entry.toc)
+		}
+	}
+}
+
+func TestPostProcessorCopies(t *testing.T) {
+	// Modifying the registered processors of the caller must not
+	// affect DefaultPostProcessors or the registry.
+	processors := RegisteredPostProcessors()
+	processors[0] = SanitizePostProcessor
+	if got := RegisteredPostProcessors()[0].Name; got != "xref" {
+		t.Errorf("RegisteredPostProcessors()[0] = %q after modifying a copy, want xref", got)
+	}
+	if got := DefaultPostProcessors[0].Name; got != "xref" {
+		t.Errorf("DefaultPostProcessors[0] = %q after modifying a copy, want xref", got)
+	}
+
+	// Each Process keeps its own processors.
+	var p Process
+	WithPostProcessors(processors)(&p)
+	processors[1] = XrefPostProcessor
+	var names []string
+	for _, pp := range p.postProcessors {
+		names = append(names, pp.Name)
+	}
+	if want := []string{"sanitize", "anchors", "sanitize"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("post processors of the Process = %q, want %q", names, want)
+	}
+}
+
+func TestCustomPostProcessor(t *testing.T) {
+	notice := PostProcessor{
+		Name: "notice",
+		Process: func(doc *html.Node, ctx *PostContext) error {
+			p := &html.Node{Type: html.ElementNode, Data: "p"}
+			p.AppendChild(&html.Node{Type: html.TextNode, Data: "Packaged by " + ctx.Meta.Package.Binarypkg})
+			doc.AppendChild(p)
+			return nil
+		},
+		PerManpage: true,
+	}
+	old := RegisteredPostProcessors()
+	defer func() { registered.processors = old }()
+	RegisterPostProcessor(notice)
+
+	processors, err := FindPostProcessors([]string{"sanitize", "notice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := &PostContext{Meta: &manpage.Meta{Package: &manpage.PkgMeta{Binarypkg: "i3-wm"}}}
+	got := runPostProcessors(t, processors, `<p>i3(1)</p>`, ctx)
+	if want := `<p>i3(1)</p><p>Packaged by i3-wm</p>`; got != want {
+		t.Errorf("unexpected output: got %s, want %s", got, want)
+	}
+
+	for _, names := range [][]string{
+		{"bogus"},
+		{"xref", "xref"},
+	} {
+		if _, err := FindPostProcessors(names); err == nil {
+			t.Errorf("FindPostProcessors(%q) unexpectedly succeeded", names)
+		}
+	}
+}
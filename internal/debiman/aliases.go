@@ -0,0 +1,195 @@
+package debiman
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/Debian/debiman/internal/manpage"
+)
+
+var soAliases = flag.Bool("so_aliases",
+	true,
+	"Treat manpages which consist only of a .so request referring to another manpage of the same suite (e.g. gunzip.1, containing “.so man1/gzip.1”) as aliases: instead of rendering the referenced content a second time, they are recorded in the auxserver index with the referenced manpage as their target, to which debiman-auxserver and debiman-idx2rwmap resolve them. Chains of aliases are followed. Takes effect for packages which are extracted (see -force_reextract)")
+
+// maxAliasChain is the maximum number of aliases which resolveAliases
+// follows from a manpage, so that cycles cannot go undetected.
+const maxAliasChain = 16
+
+// maxAliasBytes is the size above which manpage sources are not
+// checked for being an alias, as they cannot consist of a single .so
+// request (plus a copyright notice) only.
+const maxAliasBytes = 4096
+
+// soTarget returns the argument of the .so request of source if source
+// consists only of that request, comments and empty lines.
+func soTarget(source []byte) (string, bool) {
+	if len(source) > maxAliasBytes || !bytes.Contains(source, []byte(".so ")) {
+		return "", false
+	}
+	var so string
+	for _, line := range strings.Split(string(source), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || line == "." || line == "'":
+			continue
+		case strings.HasPrefix(line, `.\"`) ||
+			strings.HasPrefix(line, `'\"`) ||
+			strings.HasPrefix(line, `\"`) ||
+			strings.HasPrefix(line, `.\#`) ||
+			strings.HasPrefix(line, `\#`):
+			continue // comment
+		case strings.HasPrefix(line, ".so ") && so == "":
+			so = strings.TrimSpace(line[len(".so "):])
+		default:
+			return "", false
+		}
+	}
+	return so, so != ""
+}
+
+// aliasTarget returns the serving path of the manpage which so (the
+// .so request of the manpage m, extracted from src) refers to, or false
+// if so does not refer to another manpage of the same suite.
+func aliasTarget(logger *log.Logger, src, so string, m *manpage.Meta, contentByPath map[string][]*contentEntry) (string, bool) {
+	resolved, ref, ok := findFile(logger, src, so, contentByPath)
+	if !ok || ref != "" {
+		return "", false // missing or not a manpage
+	}
+	target := strings.TrimSuffix(resolved, ".gz")
+	if !strings.HasPrefix(target, manpage.URLCase.Path(m.Package.Suite)+"/") || target == m.ServingPath() {
+		return "", false
+	}
+	return target, true
+}
+
+// aliasMarker returns the path of the file which marks the manpage
+// source dest (e.g. …/gunzip.1.en.gz) as an alias and contains the
+// serving path of its target. The marker persists until the package is
+// extracted again. As a dot file, it is not published.
+func aliasMarker(dest string) string {
+	dir, base := filepath.Split(dest)
+	return filepath.Join(dir, "."+strings.TrimSuffix(base, ".gz")+".alias")
+}
+
+// markAlias records dest as an alias of target, removing its source and
+// any pages rendered from it (e.g. before -so_aliases was enabled), or
+// removes its aliasMarker if target is empty.
+func markAlias(dest, target string) error {
+	fn := aliasMarker(dest)
+	if target == "" {
+		if err := os.Remove(fn); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	dir, base := filepath.Split(dest)
+	prefix := strings.TrimSuffix(base, ".gz") + "."
+	names, err := readDirNames(dir)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return ioutil.WriteFile(fn, []byte(target), 0644)
+}
+
+func readDirNames(dir string) ([]string, error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Readdirnames(-1)
+}
+
+// readAlias returns the target which the aliasMarker of the manpage at
+// servingPath contains.
+func readAlias(servingDir, servingPath string) (string, bool) {
+	b, err := ioutil.ReadFile(aliasMarker(filepath.Join(servingDir, servingPath+".gz")))
+	if err != nil {
+		return "", false
+	}
+	return string(b), true
+}
+
+// aliasOf returns the serving path of the manpage which the manpage at
+// servingPath is an alias of: the target of its aliasMarker or, for
+// symlinks to aliases, the alias the symlink refers to.
+func aliasOf(servingDir, servingPath string) (string, bool) {
+	if target, ok := readAlias(servingDir, servingPath); ok {
+		return target, true
+	}
+	link, err := os.Readlink(filepath.Join(servingDir, servingPath+".gz"))
+	if err != nil {
+		return "", false
+	}
+	resolved := strings.TrimSuffix(filepath.Join(filepath.Dir(servingPath), link), ".gz")
+	if _, ok := readAlias(servingDir, resolved); !ok {
+		return "", false
+	}
+	return resolved, true
+}
+
+// followAlias returns the serving path of the manpage at the end of the
+// chain of aliases (as in next, or on disk) starting at servingPath.
+func followAlias(servingDir string, next map[string]string, servingPath string) (string, error) {
+	target := servingPath
+	for i := 0; ; i++ {
+		t, ok := next[target]
+		if !ok {
+			// Not (yet) in next, e.g. aliases outside of xref.
+			t, ok = aliasOf(servingDir, target)
+		}
+		if !ok {
+			break
+		}
+		if i == maxAliasChain {
+			return "", fmt.Errorf("more than %d aliases in a row (cycle?)", maxAliasChain)
+		}
+		target = t
+	}
+	if _, err := os.Stat(filepath.Join(servingDir, target+".gz")); err != nil {
+		return "", fmt.Errorf("target %q: %v", target, err)
+	}
+	return target, nil
+}
+
+// resolveAliases returns the aliases among the manpages of xref, mapping
+// their serving path to the serving path of the manpage they resolve
+// to. Broken aliases (whose chain ends in a cycle or a missing manpage)
+// are logged and map to the empty string.
+func resolveAliases(servingDir string, xref map[string][]*manpage.Meta, stats *stats) map[string]string {
+	next := make(map[string]string)
+	for _, x := range xref {
+		for _, m := range x {
+			if target, ok := aliasOf(servingDir, m.ServingPath()); ok {
+				next[m.ServingPath()] = target
+			}
+		}
+	}
+	aliases := make(map[string]string, len(next))
+	for servingPath := range next {
+		target, err := followAlias(servingDir, next, servingPath)
+		if err != nil {
+			log.Printf("WARNING: alias %q is broken, omitting it from the index: %v", servingPath, err)
+			atomic.AddUint64(&stats.AliasesBroken, 1)
+		} else {
+			atomic.AddUint64(&stats.Aliases, 1)
+		}
+		aliases[servingPath] = target
+	}
+	return aliases
+}
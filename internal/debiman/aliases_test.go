@@ -0,0 +1,114 @@
+package debiman
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Debian/debiman/internal/manpage"
+)
+
+func TestSoTarget(t *testing.T) {
+	for _, entry := range []struct {
+		source string
+		want   string
+	}{
+		{source: ".so man1/gzip.1\n", want: "man1/gzip.1"},
+		{source: ".\\\" Copyright notice\n.\\\"\n\n.so man3/printf.3\n", want: "man3/printf.3"},
+		{source: ".so man1/gzip.1\n.so man1/zcat.1\n"},
+		{source: ".TH GZIP 1\n.so man1/gzip.1\n"},
+		{source: ".TH GZIP 1\n.SH NAME\ngzip \\- compress files\n"},
+		{source: ""},
+	} {
+		entry := entry // copy
+		t.Run(entry.source, func(t *testing.T) {
+			t.Parallel()
+			got, ok := soTarget([]byte(entry.source))
+			if ok != (entry.want != "") || got != entry.want {
+				t.Errorf("soTarget(%q) = %q, %v, want %q", entry.source, got, ok, entry.want)
+			}
+		})
+	}
+}
+
+func TestResolveAliases(t *testing.T) {
+	servingDir, err := ioutil.TempDir("", "debiman-aliases")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(servingDir)
+
+	dir := filepath.Join(servingDir, "jessie", "gzip")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"gzip.1.en.gz", "gunzip.1.en.gz", "gunzip.1.en.html.gz", "zcat.1.en.gz", "uncompress.1.en.gz", "a.1.en.gz", "b.1.en.gz"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// gunzip → gzip, zcat → gunzip → gzip, a → b → a, missing → nowhere
+	for _, alias := range []struct{ dest, target string }{
+		{"gunzip.1.en.gz", "jessie/gzip/gzip.1.en"},
+		{"zcat.1.en.gz", "jessie/gzip/gunzip.1.en"},
+		{"a.1.en.gz", "jessie/gzip/b.1.en"},
+		{"b.1.en.gz", "jessie/gzip/a.1.en"},
+		{"missing.1.en.gz", "jessie/gzip/nowhere.1.en"},
+	} {
+		if err := markAlias(filepath.Join(dir, alias.dest), alias.target); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Remove(filepath.Join(dir, "uncompress.1.en.gz")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("gunzip.1.en.gz", filepath.Join(dir, "uncompress.1.en.gz")); err != nil {
+		t.Fatal(err)
+	}
+
+	// markAlias removes the source and whatever was rendered from it.
+	for _, name := range []string{"gunzip.1.en.gz", "gunzip.1.en.html.gz"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s unexpectedly still present: %v", name, err)
+		}
+	}
+	if got, ok := readAlias(servingDir, "jessie/gzip/gunzip.1.en"); !ok || got != "jessie/gzip/gzip.1.en" {
+		t.Errorf(`readAlias(gunzip) = %q, %v, want "jessie/gzip/gzip.1.en", true`, got, ok)
+	}
+
+	xref := make(map[string][]*manpage.Meta)
+	for _, name := range []string{"gzip", "gunzip", "zcat", "uncompress", "a", "b", "missing"} {
+		xref[name] = []*manpage.Meta{{
+			Name:     name,
+			Section:  "1",
+			Language: "en",
+			Package:  &manpage.PkgMeta{Binarypkg: "gzip", Suite: "jessie"},
+		}}
+	}
+	var st stats
+	got := resolveAliases(servingDir, xref, &st)
+	want := map[string]string{
+		"jessie/gzip/gunzip.1.en":     "jessie/gzip/gzip.1.en",
+		"jessie/gzip/zcat.1.en":       "jessie/gzip/gzip.1.en",
+		"jessie/gzip/uncompress.1.en": "jessie/gzip/gzip.1.en",
+		"jessie/gzip/a.1.en":          "",
+		"jessie/gzip/b.1.en":          "",
+		"jessie/gzip/missing.1.en":    "",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("resolveAliases: got %v, want %v", got, want)
+	}
+	if st.Aliases != 3 || st.AliasesBroken != 3 {
+		t.Errorf("unexpected stats: got %d aliases (%d broken), want 3 (3 broken)", st.Aliases, st.AliasesBroken)
+	}
+
+	// Removing the marker turns the manpage back into a regular one.
+	if err := markAlias(filepath.Join(dir, "gunzip.1.en.gz"), ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := readAlias(servingDir, "jessie/gzip/gunzip.1.en"); ok {
+		t.Errorf("gunzip unexpectedly still an alias")
+	}
+}
@@ -0,0 +1,119 @@
+package debiman
+
+import (
+	"flag"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/Debian/debiman/internal/manpage"
+)
+
+var primaryArchitecture = flag.String("primary_architecture",
+	mostPopularArchitecture,
+	"Architecture from which to extract the manpages of packages which are available on multiple architectures. Manpages are usually architecture-independent, so only one .deb is downloaded per package; for the rare packages whose manpages differ across architectures (according to the Contents files, which is logged), this determines which variant is served. Packages not available on this architecture are extracted from the lowest available architecture in string order")
+
+// archRanks returns the preference of each of archs (lower is better):
+// -primary_architecture first, then string order. Unlike the order of
+// architectures in the Release file, this does not depend on the
+// archive.
+func archRanks(archs []string) []int {
+	order := make([]int, len(archs))
+	for idx := range order {
+		order[idx] = idx
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := archs[order[i]], archs[order[j]]
+		if (a == *primaryArchitecture) != (b == *primaryArchitecture) {
+			return a == *primaryArchitecture
+		}
+		return a < b
+	})
+	ranks := make([]int, len(archs))
+	for rank, idx := range order {
+		ranks[idx] = rank
+	}
+	return ranks
+}
+
+// markArchSpecific sets the archs field of the entries (which must be
+// those of a single Contents file, masks[i] being the set of indexes
+// into archs of the architectures listing entries[i]) of packages whose
+// manpages differ across architectures.
+func markArchSpecific(entries []*contentEntry, masks []uint64, archs []string) {
+	first := make(map[string]uint64)
+	archSpecific := make(map[string]bool)
+	for idx, e := range entries {
+		if mask, ok := first[e.binarypkg]; !ok {
+			first[e.binarypkg] = masks[idx]
+		} else if mask != masks[idx] {
+			archSpecific[e.binarypkg] = true
+		}
+	}
+	if len(archSpecific) == 0 {
+		return
+	}
+	for idx, e := range entries {
+		if !archSpecific[e.binarypkg] {
+			continue
+		}
+		for bit, arch := range archs {
+			if masks[idx]&(1<<uint(bit)) != 0 {
+				e.archs = append(e.archs, arch)
+			}
+		}
+	}
+}
+
+// restrictArchSpecific returns content without the entries of
+// architecture-specific packages (see contentEntry.archs) which are not
+// shipped by the architecture of the package in pkgs, i.e. the .deb
+// which will be extracted, and records that architecture in
+// latestVersion. Each architecture-specific package is logged.
+func restrictArchSpecific(content []*contentEntry, pkgs []*pkgEntry, latestVersion map[string]*manpage.PkgMeta) []*contentEntry {
+	archByKey := make(map[string]string, len(pkgs))
+	for _, p := range pkgs {
+		archByKey[p.suite+"/"+p.binarypkg] = p.arch
+	}
+	archsByKey := make(map[string]map[string]bool)
+	restricted := make([]*contentEntry, 0, len(content))
+	for _, c := range content {
+		key := c.suite + "/" + c.binarypkg
+		arch, ok := archByKey[key]
+		if c.archs == nil || !ok {
+			restricted = append(restricted, c)
+			continue
+		}
+		if archsByKey[key] == nil {
+			archsByKey[key] = make(map[string]bool)
+		}
+		shipped := false
+		for _, a := range c.archs {
+			archsByKey[key][a] = true
+			if a == arch {
+				shipped = true
+			}
+		}
+		if shipped {
+			restricted = append(restricted, c)
+		}
+	}
+
+	keys := make([]string, 0, len(archsByKey))
+	for key := range archsByKey {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		archs := make([]string, 0, len(archsByKey[key]))
+		for arch := range archsByKey[key] {
+			archs = append(archs, arch)
+		}
+		sort.Strings(archs)
+		log.Printf("package %q ships different manpages on architectures %s, using %s (see -primary_architecture)", key, strings.Join(archs, ", "), archByKey[key])
+		if meta, ok := latestVersion[key]; ok {
+			meta.Architecture = archByKey[key]
+		}
+	}
+	return restricted
+}
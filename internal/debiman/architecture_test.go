@@ -0,0 +1,86 @@
+package debiman
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Debian/debiman/internal/manpage"
+)
+
+func TestArchRanks(t *testing.T) {
+	archs := []string{"i386", "arm64", "amd64", "armhf"}
+	if got, want := archRanks(archs), []int{3, 1, 0, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("archRanks(%q) = %v, want %v", archs, got, want)
+	}
+
+	old := *primaryArchitecture
+	defer func() { *primaryArchitecture = old }()
+	*primaryArchitecture = "s390x"
+	if got, want := archRanks(archs), []int{3, 1, 0, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("archRanks(%q) without the primary architecture = %v, want %v", archs, got, want)
+	}
+	*primaryArchitecture = "i386"
+	if got, want := archRanks(archs), []int{0, 2, 1, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("archRanks(%q) with -primary_architecture=i386 = %v, want %v", archs, got, want)
+	}
+}
+
+func TestArchSpecific(t *testing.T) {
+	archs := []string{"amd64", "i386"}
+	both, amd64, i386 := uint64(3), uint64(1), uint64(2)
+	entry := func(pkg, filename, arch string) *contentEntry {
+		return &contentEntry{suite: "jessie", binarypkg: pkg, filename: filename, arch: arch}
+	}
+	content := []*contentEntry{
+		entry("coreutils", "man1/ls.1.gz", "amd64"),
+		entry("coreutils", "man1/cat.1.gz", "amd64"),
+		entry("inventor-clients", "man1/ivview.1.gz", "i386"),
+		entry("grub-pc", "man8/grub-install.8.gz", "amd64"),
+		entry("grub-pc", "man8/grub-bios-setup.8.gz", "amd64"),
+		entry("grub-pc", "man8/grub-ofpathname.8.gz", "i386"),
+	}
+	markArchSpecific(content, []uint64{both, both, i386, both, amd64, i386}, archs)
+	for _, c := range content {
+		if got, want := c.archs != nil, c.binarypkg == "grub-pc"; got != want {
+			t.Errorf("%s %s: architecture-specific = %v, want %v", c.binarypkg, c.filename, got, want)
+		}
+	}
+	if got, want := content[3].archs, archs; !reflect.DeepEqual(got, want) {
+		t.Errorf("grub-install.8: archs = %q, want %q", got, want)
+	}
+
+	pkgs := []*pkgEntry{
+		{suite: "jessie", binarypkg: "coreutils", arch: "amd64"},
+		{suite: "jessie", binarypkg: "inventor-clients", arch: "i386"},
+		{suite: "jessie", binarypkg: "grub-pc", arch: "amd64"},
+	}
+	latestVersion := map[string]*manpage.PkgMeta{
+		"jessie/coreutils":        {Binarypkg: "coreutils"},
+		"jessie/inventor-clients": {Binarypkg: "inventor-clients"},
+		"jessie/grub-pc":          {Binarypkg: "grub-pc"},
+	}
+	restricted := restrictArchSpecific(content, pkgs, latestVersion)
+	var filenames []string
+	for _, c := range restricted {
+		filenames = append(filenames, c.filename)
+	}
+	want := []string{
+		"man1/ls.1.gz",
+		"man1/cat.1.gz",
+		"man1/ivview.1.gz",
+		"man8/grub-install.8.gz",
+		"man8/grub-bios-setup.8.gz",
+	}
+	if !reflect.DeepEqual(filenames, want) {
+		t.Errorf("restrictArchSpecific() = %q, want %q", filenames, want)
+	}
+	for key, want := range map[string]string{
+		"jessie/coreutils":        "",
+		"jessie/inventor-clients": "",
+		"jessie/grub-pc":          "amd64",
+	} {
+		if got := latestVersion[key].Architecture; got != want {
+			t.Errorf("%s: Architecture = %q, want %q", key, got, want)
+		}
+	}
+}
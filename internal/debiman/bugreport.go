@@ -0,0 +1,58 @@
+package debiman
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"net/url"
+	"text/template"
+
+	"github.com/Debian/debiman/internal/manpage"
+)
+
+var bugReportURL = flag.String("bug_report_url",
+	"https://bugs.debian.org/src:{{ .Sourcepkg }}",
+	"Template (text/template syntax) for the URL at which bugs in a manpage can be reported, linked in the footer of each manpage. The fields .Sourcepkg, .Binarypkg, .Version, .Suite, .Name and .Section are URL-escaped. The link is omitted for manpages without a source package. Empty disables the link")
+
+// bugReportTmpl is parsed from -bug_report_url by logic, nil if empty.
+var bugReportTmpl *template.Template
+
+type bugReportFields struct {
+	Sourcepkg, Binarypkg, Version, Suite, Name, Section string
+}
+
+// parseBugReportURL parses tmpl (see -bug_report_url) and verifies that
+// it only refers to the fields of bugReportFields.
+func parseBugReportURL(tmpl string) (*template.Template, error) {
+	if tmpl == "" {
+		return nil, nil
+	}
+	t, err := template.New("bug_report_url").Parse(tmpl)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Execute(ioutil.Discard, bugReportFields{}); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// bugReportLink returns the URL at which bugs in m can be reported, or
+// the empty string if t is nil or the source package of m is unknown.
+func bugReportLink(t *template.Template, m *manpage.Meta) (string, error) {
+	if t == nil || m.Package == nil || m.Package.Sourcepkg == "" {
+		return "", nil
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, bugReportFields{
+		Sourcepkg: url.QueryEscape(m.Package.Sourcepkg),
+		Binarypkg: url.QueryEscape(m.Package.Binarypkg),
+		Version:   url.QueryEscape(m.Package.Version.String()),
+		Suite:     url.QueryEscape(m.Package.Suite),
+		Name:      url.QueryEscape(m.Name),
+		Section:   url.QueryEscape(m.Section),
+	}); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,62 @@
+package debiman
+
+import (
+	"testing"
+
+	"github.com/Debian/debiman/internal/manpage"
+
+	"pault.ag/go/debian/version"
+)
+
+func TestBugReportLink(t *testing.T) {
+	v, err := version.Parse("1:2.0+dfsg-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &manpage.Meta{
+		Name:    "foo",
+		Section: "1",
+		Package: &manpage.PkgMeta{
+			Binarypkg: "foo-utils",
+			Sourcepkg: "foo",
+			Suite:     "jessie",
+			Version:   v,
+		},
+	}
+	for _, entry := range []struct {
+		tmpl string
+		want string
+	}{
+		{"https://bugs.debian.org/src:{{ .Sourcepkg }}", "https://bugs.debian.org/src:foo"},
+		{"https://bugs.example.org/new?pkg={{ .Binarypkg }}&version={{ .Version }}&page={{ .Name }}.{{ .Section }}",
+			"https://bugs.example.org/new?pkg=foo-utils&version=1%3A2.0%2Bdfsg-1&page=foo.1"},
+		{"", ""},
+	} {
+		tmpl, err := parseBugReportURL(entry.tmpl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := bugReportLink(tmpl, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != entry.want {
+			t.Errorf("bugReportLink(%q) = %q, want %q", entry.tmpl, got, entry.want)
+		}
+	}
+
+	tmpl, err := parseBugReportURL("https://bugs.debian.org/src:{{ .Sourcepkg }}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	noSource := &manpage.Meta{Name: "foo", Section: "1", Package: &manpage.PkgMeta{Binarypkg: "foo"}}
+	if got, err := bugReportLink(tmpl, noSource); err != nil || got != "" {
+		t.Errorf("bugReportLink(no source package) = %q, %v, want no link", got, err)
+	}
+
+	for _, tmpl := range []string{"{{ .Sourcepkg", "https://example.org/{{ .Maintainer }}"} {
+		if _, err := parseBugReportURL(tmpl); err == nil {
+			t.Errorf("parseBugReportURL(%q) unexpectedly succeeded", tmpl)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package debiman
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Debian/debiman/internal/convert"
+	"pault.ag/go/archive"
+)
+
+// buildInfo describes what produced the serving directory, see
+// writeBuildInfo.
+type buildInfo struct {
+	DebimanVersion  string            `json:"debiman_version"`
+	GoVersion       string            `json:"go_version"`
+	MandocVersion   string            `json:"mandoc_version"`
+	MandocArgs      []string          `json:"mandoc_args"`
+	Start           time.Time         `json:"start"`
+	End             time.Time         `json:"end"`
+	SourceDateEpoch string            `json:"source_date_epoch,omitempty"`
+	Suites          []buildInfoSuite  `json:"suites"`
+	Flags           map[string]string `json:"flags"`
+}
+
+// buildInfoSuite describes the archives from which the packages of a
+// suite were obtained.
+type buildInfoSuite struct {
+	Suite        string             `json:"suite"`
+	Distribution string             `json:"distribution"` // as in -sync_codenames or -sync_suites
+	Sources      []buildInfoRelease `json:"sources"`
+}
+
+type buildInfoRelease struct {
+	Mirror     string   `json:"mirror"`
+	Suite      string   `json:"release_suite"`
+	Codename   string   `json:"release_codename"`
+	Components []string `json:"components"`
+
+	// Indices maps the Packages and Contents files of Components (as
+	// listed in the Release file) to their SHA256 hash, which
+	// identifies the exact state of the archive.
+	Indices map[string]string `json:"indices"`
+}
+
+// newBuildInfoRelease describes release as obtained from src.
+func newBuildInfoRelease(src *archiveSource, release *archive.Release) buildInfoRelease {
+	mirror := src.ar.Mirror
+	if src.ar.LocalMirror != "" {
+		mirror = src.ar.LocalMirror
+	}
+	r := buildInfoRelease{
+		Mirror:     redactURLs(mirror),
+		Suite:      release.Suite,
+		Codename:   release.Codename,
+		Components: src.components,
+		Indices:    make(map[string]string),
+	}
+	for _, fh := range release.SHA256 {
+		for _, component := range src.components {
+			if !strings.HasPrefix(fh.Filename, component+"/") {
+				continue
+			}
+			base := fh.Filename[strings.LastIndex(fh.Filename, "/")+1:]
+			if strings.HasPrefix(base, "Packages") || strings.HasPrefix(base, "Contents-") {
+				r.Indices[fh.Filename] = fh.Hash
+			}
+		}
+	}
+	return r
+}
+
+var urlUserinfoRe = regexp.MustCompile(`[a-z][a-z0-9+.-]*://[^/@\s;]+@[^\s;]*`)
+
+// redactURLs replaces the passwords of all URLs in s (e.g. of
+// -sources) with “xxxxx”.
+func redactURLs(s string) string {
+	return urlUserinfoRe.ReplaceAllStringFunc(s, func(raw string) string {
+		u, err := url.Parse(raw)
+		if err != nil || u.User == nil {
+			return raw
+		}
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "xxxxx")
+		}
+		return u.String()
+	})
+}
+
+// flagValues returns the values of all flags (with redacted URLs).
+func flagValues(fs *flag.FlagSet) map[string]string {
+	values := make(map[string]string)
+	fs.VisitAll(func(f *flag.Flag) {
+		values[f.Name] = redactURLs(f.Value.String())
+	})
+	return values
+}
+
+// writeBuildInfo writes the provenance of the serving directory of gv
+// (see buildInfo) as JSON to w.
+func writeBuildInfo(w io.Writer, gv globalView, start, end time.Time) error {
+	suites := append([]buildInfoSuite(nil), gv.provenance...)
+	sort.Slice(suites, func(i, j int) bool { return suites[i].Suite < suites[j].Suite })
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(buildInfo{
+		DebimanVersion:  debimanVersion,
+		GoVersion:       runtime.Version(),
+		MandocVersion:   gv.stats.MandocVersion,
+		MandocArgs:      convert.Mandoc.Args,
+		Start:           start.UTC(),
+		End:             end.UTC(),
+		SourceDateEpoch: os.Getenv("SOURCE_DATE_EPOCH"),
+		Suites:          suites,
+		Flags:           flagValues(flag.CommandLine),
+	})
+}
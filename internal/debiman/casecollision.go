@@ -0,0 +1,90 @@
+package debiman
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/Debian/debiman/internal/manpage"
+)
+
+// caseInsensitiveDir returns whether the file system on which dir is
+// located treats file names which differ only in case as the same file
+// (e.g. HFS+ and APFS on macOS in their default configuration).
+func caseInsensitiveDir(dir string) (bool, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return false, err
+	}
+	f, err := ioutil.TempFile(dir, "debiman-CaseCheck-")
+	if err != nil {
+		return false, err
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+	lower := filepath.Join(dir, strings.ToLower(filepath.Base(f.Name())))
+	if _, err := os.Stat(lower); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// manpageID identifies m independently of manpage.URLCase.
+func manpageID(m *manpage.Meta) string {
+	return m.Package.Suite + "/" + m.Package.Binarypkg + "/" + m.Name + "." + m.Section + "." + m.Language
+}
+
+// resolveCaseCollisions finds manpages whose serving paths differ only
+// in case, which would be written to the same file when fold is true
+// (-url_case=lower or a case-insensitive file system). Of each such
+// group, the manpage whose name sorts first is kept. The others are
+// logged, removed from xref (so that neither rendered pages nor the
+// auxserver index refer to them) and returned, so that they are not
+// extracted.
+func resolveCaseCollisions(xref map[string][]*manpage.Meta, fold bool, stats *stats) map[string]bool {
+	dropped := make(map[string]bool)
+	if !fold {
+		return dropped
+	}
+	byPath := make(map[string][]*manpage.Meta)
+	for _, metas := range xref {
+		for _, m := range metas {
+			key := strings.ToLower(manpageID(m))
+			byPath[key] = append(byPath[key], m)
+		}
+	}
+	for key, metas := range byPath {
+		if len(metas) < 2 {
+			continue
+		}
+		sort.Slice(metas, func(i, j int) bool { return manpageID(metas[i]) < manpageID(metas[j]) })
+		for _, m := range metas[1:] {
+			log.Printf("WARNING: %q collides with %q in file name %q (case-insensitive), not serving %q", manpageID(m), manpageID(metas[0]), key, manpageID(m))
+			dropped[manpageID(m)] = true
+			atomic.AddUint64(&stats.CaseCollisions, 1)
+		}
+	}
+	if len(dropped) == 0 {
+		return dropped
+	}
+	for name, metas := range xref {
+		kept := metas[:0]
+		for _, m := range metas {
+			if !dropped[manpageID(m)] {
+				kept = append(kept, m)
+			}
+		}
+		if len(kept) == 0 {
+			delete(xref, name)
+		} else {
+			xref[name] = kept
+		}
+	}
+	return dropped
+}
@@ -0,0 +1,72 @@
+package debiman
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/Debian/debiman/internal/manpage"
+)
+
+func TestResolveCaseCollisions(t *testing.T) {
+	pkg := &manpage.PkgMeta{Binarypkg: "libcgi-pm-perl", Suite: "jessie"}
+	other := &manpage.PkgMeta{Binarypkg: "perl-doc", Suite: "jessie"}
+	newXref := func() map[string][]*manpage.Meta {
+		return map[string][]*manpage.Meta{
+			"CGI": {
+				{Name: "CGI", Section: "3pm", Language: "en", Package: pkg},
+			},
+			"cgi": {
+				{Name: "cgi", Section: "3pm", Language: "en", Package: pkg},
+				// Different binary package, hence different directory.
+				{Name: "cgi", Section: "3pm", Language: "en", Package: other},
+			},
+			"ls": {
+				{Name: "ls", Section: "1", Language: "en", Package: pkg},
+			},
+		}
+	}
+
+	var st stats
+	xref := newXref()
+	if got := resolveCaseCollisions(xref, false, &st); len(got) != 0 {
+		t.Fatalf("unexpected collisions on a case-sensitive file system: %v", got)
+	}
+
+	dropped := resolveCaseCollisions(xref, true, &st)
+	want := "jessie/libcgi-pm-perl/cgi.3pm.en"
+	if len(dropped) != 1 || !dropped[want] {
+		t.Fatalf("unexpected collisions: got %v, want [%s]", dropped, want)
+	}
+	if got, want := st.CaseCollisions, uint64(1); got != want {
+		t.Fatalf("unexpected CaseCollisions: got %d, want %d", got, want)
+	}
+	if got, want := len(xref["CGI"]), 1; got != want {
+		t.Fatalf("unexpected number of CGI entries: got %d, want %d", got, want)
+	}
+	if got, want := len(xref["cgi"]), 1; got != want {
+		t.Fatalf("unexpected number of cgi entries: got %d, want %d", got, want)
+	}
+	if got, want := xref["cgi"][0].Package.Binarypkg, "perl-doc"; got != want {
+		t.Fatalf("unexpected cgi entry: got package %q, want %q", got, want)
+	}
+}
+
+func TestCaseInsensitiveDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "debiman-casecollision")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := caseInsensitiveDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 0 {
+		t.Fatalf("caseInsensitiveDir left behind %d files", len(infos))
+	}
+}
@@ -0,0 +1,142 @@
+package debiman
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/Debian/debiman/internal/write"
+)
+
+var changelogEntries = flag.Int("changelog_entries",
+	0,
+	"If positive, extract the newest this many entries of the Debian changelog of each package (/usr/share/doc/<package>/changelog.Debian.gz) and show them in a collapsible “Recent changes” section in the footer of its manpages. Packages without such a changelog (e.g. because their documentation directory is a symlink to that of another package) get no such section. Increases the extraction work and the size of the rendered pages, and requires -force_reextract and -force_rerender once after enabling this flag. 0 disables the section")
+
+// changelogEntry is an entry of a Debian changelog, see
+// https://www.debian.org/doc/debian-policy/ch-source.html#debian-changelog-debian-changelog
+type changelogEntry struct {
+	Version      string `json:"version"`
+	Distribution string `json:"distribution"`
+
+	// Changes are the change details, with their original indentation
+	// (e.g. "  * New upstream release.") and without surrounding empty
+	// lines.
+	Changes    string `json:"changes"`
+	Maintainer string `json:"maintainer"`
+	Date       string `json:"date"`
+}
+
+// changelogName returns the path of the changelog of binarypkg within
+// the package’s data.tar.
+func changelogName(binarypkg string) string {
+	return "./usr/share/doc/" + binarypkg + "/changelog.Debian.gz"
+}
+
+// changelogMarker returns the path of the file which keeps the
+// changelog entries of the package whose manpages are in pkgdir. As a
+// dot file, it is not published.
+func changelogMarker(pkgdir string) string {
+	return filepath.Join(pkgdir, ".changelog.json")
+}
+
+var (
+	// e.g. “i3-wm (4.13-1) unstable; urgency=medium”
+	changelogHeader = regexp.MustCompile(`^\S+ \(([^()\s]+)\) ([^;]+);`)
+	// e.g. “ -- Michael Stapelberg <stapelberg@debian.org>  Sun, 06 Nov 2016 16:48:07 +0100”
+	changelogTrailer = regexp.MustCompile(`^ -- (.*?)  (\S.*)$`)
+)
+
+// parseChangelog returns the first n entries of the (decompressed)
+// changelog r. Parsing stops at the first line which cannot be part of
+// an entry (e.g. the “Local variables:” footer or an old changelog in
+// a different format), and entries without trailer are discarded.
+func parseChangelog(r io.Reader, n int) ([]changelogEntry, error) {
+	var (
+		entries []changelogEntry
+		cur     *changelogEntry
+		changes []string
+	)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() && len(entries) < n {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if cur == nil {
+			if line == "" {
+				continue
+			}
+			m := changelogHeader.FindStringSubmatch(line)
+			if m == nil {
+				break
+			}
+			cur = &changelogEntry{Version: m[1], Distribution: strings.TrimSpace(m[2])}
+			changes = changes[:0]
+			continue
+		}
+		if m := changelogTrailer.FindStringSubmatch(line); m != nil {
+			for len(changes) > 0 && changes[0] == "" {
+				changes = changes[1:]
+			}
+			for len(changes) > 0 && changes[len(changes)-1] == "" {
+				changes = changes[:len(changes)-1]
+			}
+			cur.Changes = strings.Join(changes, "\n")
+			cur.Maintainer = m[1]
+			cur.Date = m[2]
+			entries = append(entries, *cur)
+			cur = nil
+			continue
+		}
+		if line != "" && line[0] != ' ' && line[0] != '\t' {
+			break // not part of an entry
+		}
+		changes = append(changes, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// writeChangelog records entries for the manpages in pkgdir, or removes
+// the changelogMarker of a previous extraction if there are none.
+func writeChangelog(pkgdir string, entries []changelogEntry) error {
+	fn := changelogMarker(pkgdir)
+	if len(entries) == 0 {
+		if err := os.Remove(fn); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(pkgdir, 0755); err != nil {
+		return err
+	}
+	return write.Atomically(fn, false, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(entries)
+	})
+}
+
+// readChangelog returns the first n changelog entries which
+// writeChangelog recorded for pkgdir, if any.
+func readChangelog(pkgdir string, n int) ([]changelogEntry, error) {
+	b, err := ioutil.ReadFile(changelogMarker(pkgdir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var entries []changelogEntry
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return nil, err
+	}
+	if len(entries) > n {
+		entries = entries[:n]
+	}
+	return entries, nil
+}
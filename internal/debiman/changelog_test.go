@@ -0,0 +1,122 @@
+package debiman
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testChangelog = `i3-wm (4.13-1) unstable; urgency=medium
+
+  * New upstream release.
+  * Drop patches applied upstream.
+
+ -- Michael Stapelberg <stapelberg@debian.org>  Sun, 06 Nov 2016 16:48:07 +0100
+
+i3-wm (4.12-1) unstable; urgency=medium
+
+  [ Jakob Haufe ]
+  * New upstream release.
+
+ -- Michael Stapelberg <stapelberg@debian.org>  Sun, 06 Mar 2016 14:01:10 +0100
+
+i3-wm (4.11-1) unstable; urgency=medium
+
+  * New upstream release.
+
+ -- Michael Stapelberg <stapelberg@debian.org>  Wed, 30 Sep 2015 09:03:02 +0200
+
+Local variables:
+mode: debian-changelog
+End:
+`
+
+func TestParseChangelog(t *testing.T) {
+	want := []changelogEntry{
+		{
+			Version:      "4.13-1",
+			Distribution: "unstable",
+			Changes:      "  * New upstream release.\n  * Drop patches applied upstream.",
+			Maintainer:   "Michael Stapelberg <stapelberg@debian.org>",
+			Date:         "Sun, 06 Nov 2016 16:48:07 +0100",
+		},
+		{
+			Version:      "4.12-1",
+			Distribution: "unstable",
+			Changes:      "  [ Jakob Haufe ]\n  * New upstream release.",
+			Maintainer:   "Michael Stapelberg <stapelberg@debian.org>",
+			Date:         "Sun, 06 Mar 2016 14:01:10 +0100",
+		},
+		{
+			Version:      "4.11-1",
+			Distribution: "unstable",
+			Changes:      "  * New upstream release.",
+			Maintainer:   "Michael Stapelberg <stapelberg@debian.org>",
+			Date:         "Wed, 30 Sep 2015 09:03:02 +0200",
+		},
+	}
+
+	for _, entry := range []struct {
+		name      string
+		changelog string
+		n         int
+		want      []changelogEntry
+	}{
+		{name: "all", changelog: testChangelog, n: 10, want: want},
+		{name: "limit", changelog: testChangelog, n: 2, want: want[:2]},
+		{
+			name:      "truncated",
+			changelog: testChangelog[:strings.Index(testChangelog, " -- Michael Stapelberg <stapelberg@debian.org>  Sun, 06 Mar")],
+			n:         10,
+			want:      want[:1],
+		},
+		{name: "garbage", changelog: "Old Changelog:\n", n: 10},
+		{name: "empty", n: 10},
+	} {
+		entry := entry // copy
+		t.Run(entry.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := parseChangelog(strings.NewReader(entry.changelog), entry.n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, entry.want) {
+				t.Errorf("parseChangelog: got %+v, want %+v", got, entry.want)
+			}
+		})
+	}
+}
+
+func TestWriteChangelog(t *testing.T) {
+	pkgdir, err := ioutil.TempDir("", "debiman-changelog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(pkgdir)
+
+	entries, err := parseChangelog(strings.NewReader(testChangelog), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeChangelog(pkgdir, entries); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readChangelog(pkgdir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, entries[:2]) {
+		t.Errorf("readChangelog: got %+v, want %+v", got, entries[:2])
+	}
+
+	// Packages without changelog (in the next extraction) have no
+	// recent changes.
+	if err := writeChangelog(pkgdir, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := readChangelog(pkgdir, 2); err != nil || got != nil {
+		t.Errorf("readChangelog = %+v, %v, want nil, nil", got, err)
+	}
+}
@@ -0,0 +1,168 @@
+package debiman
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Debian/debiman/internal/convert"
+)
+
+var checkOnly = flag.Bool("check",
+	false,
+	"Validate the configuration and exit without a run: parse all flags, verify mandoc (see -mandoc_path), check that -serving_dir is writable and that each source serves the Release file of each distribution to synchronize (see -sync_codenames, -sync_suites and -sources), listing the configured components and their Packages and Contents files. Prints PASS or FAIL for each check; the exit status is 1 if any check failed")
+
+// checkResult is the outcome of one check of the -check report.
+type checkResult struct {
+	name   string
+	detail string // e.g. the mandoc version
+	err    error
+}
+
+// preflight runs the checks of -check, writes a report to w and returns
+// whether all checks passed. Checks which depend on a failed check
+// (e.g. on the parsed -sources) are skipped.
+func preflight(w io.Writer) bool {
+	var results []checkResult
+	rf, err := parseRunFlags()
+	results = append(results, checkResult{name: "flags", err: err})
+	if err == nil {
+		results = append(results, checkResult{name: "HTTP client", err: setupHTTPClient(rf)})
+	}
+
+	version, err := setupMandoc()
+	results = append(results, checkResult{
+		name:   "mandoc",
+		detail: version + " " + strings.Join(convert.Mandoc.Args, " "),
+		err:    err,
+	})
+
+	results = append(results, checkResult{
+		name: "serving directory " + *servingDir,
+		err:  checkWritable(*servingDir),
+	})
+
+	if rf.srcs != nil {
+		dists := distributions(
+			strings.Split(*syncCodenames, ","),
+			strings.Split(*syncSuites, ","))
+		if len(dists) == 0 {
+			results = append(results, checkResult{
+				name: "distributions",
+				err:  fmt.Errorf("neither -sync_codenames nor -sync_suites specify a distribution"),
+			})
+		}
+		for _, dist := range dists {
+			results = append(results, checkDistribution(rf.srcs, dist)...)
+		}
+	}
+
+	failed := 0
+	for _, r := range results {
+		status := "PASS"
+		detail := strings.TrimSpace(r.detail)
+		if r.err != nil {
+			status = "FAIL"
+			detail = r.err.Error()
+			failed++
+		}
+		if detail != "" {
+			fmt.Fprintf(w, "%s  %s: %s\n", status, r.name, detail)
+		} else {
+			fmt.Fprintf(w, "%s  %s\n", status, r.name)
+		}
+	}
+	if failed > 0 {
+		fmt.Fprintf(w, "%d of %d checks failed\n", failed, len(results))
+		return false
+	}
+	fmt.Fprintf(w, "all %d checks passed\n", len(results))
+	return true
+}
+
+// checkWritable verifies that files can be created in dir.
+func checkWritable(dir string) error {
+	f, err := ioutil.TempFile(dir, ".debiman-check")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	return f.Close()
+}
+
+// checkDistribution fetches the Release file of dist from each source
+// serving it, like buildGlobalView, and verifies that it lists the
+// configured components and their index files (see pickIndexVariant).
+func checkDistribution(srcs []*archiveSource, dist distribution) []checkResult {
+	var results []checkResult
+	for _, src := range srcs {
+		if !src.serves(dist.name) {
+			continue
+		}
+		mirror := src.ar.Mirror
+		if src.ar.LocalMirror != "" {
+			mirror = src.ar.LocalMirror
+		}
+		if mirror == "" {
+			mirror = "the default mirror"
+		}
+		r := checkResult{name: fmt.Sprintf("Release of %s from %s", dist.name, mirror)}
+		r.detail, r.err = checkRelease(src, dist.name)
+		results = append(results, r)
+	}
+	if len(results) == 0 {
+		results = append(results, checkResult{
+			name: "Release of " + dist.name,
+			err:  fmt.Errorf("no source configured for distribution %q", dist.name),
+		})
+	}
+	return results
+}
+
+func checkRelease(src *archiveSource, dist string) (string, error) {
+	release, _, err := src.release(dist)
+	if err != nil {
+		return "", err
+	}
+	listed := make(map[string]bool, len(release.Components))
+	for _, component := range release.Components {
+		listed[component] = true
+	}
+	indexes := make(map[string]bool, len(release.SHA256))
+	for _, fh := range release.SHA256 {
+		indexes[fh.Filename] = true
+	}
+	var missing []string
+	for _, component := range src.components {
+		if !listed[component] {
+			missing = append(missing, "component "+component)
+			continue
+		}
+		for _, arch := range release.Architectures {
+			for _, base := range []string{
+				component + "/binary-" + arch.String() + "/Packages",
+				component + "/Contents-" + arch.String(),
+			} {
+				found := false
+				for _, ext := range indexCompressions {
+					if indexes[base+ext] {
+						found = true
+						break
+					}
+				}
+				if !found {
+					missing = append(missing, base)
+				}
+			}
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return "", fmt.Errorf("suite %s (%s) lacks %s", release.Suite, release.Codename, abbreviate(missing))
+	}
+	return fmt.Sprintf("suite %s (%s), components %s, %d architectures", release.Suite, release.Codename, strings.Join(src.components, ", "), len(release.Architectures)), nil
+}
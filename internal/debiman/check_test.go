@@ -0,0 +1,61 @@
+package debiman
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckWritable(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "debiman-check")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	if err := checkWritable(tmpdir); err != nil {
+		t.Fatalf("checkWritable(%q) = %v", tmpdir, err)
+	}
+	if fis, err := ioutil.ReadDir(tmpdir); err != nil || len(fis) > 0 {
+		t.Errorf("checkWritable left files behind: %v, %v", fis, err)
+	}
+	if err := checkWritable(filepath.Join(tmpdir, "missing")); err == nil {
+		t.Errorf("checkWritable unexpectedly succeeded for a missing directory")
+	}
+}
+
+func TestPreflight(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "debiman-check")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	oldServingDir, oldIndexCompression := *servingDir, *indexCompression
+	defer func() { *servingDir, *indexCompression = oldServingDir, oldIndexCompression }()
+	*servingDir = tmpdir
+	*indexCompression = "zstd"
+
+	var buf bytes.Buffer
+	if preflight(&buf) {
+		t.Fatalf("preflight unexpectedly passed with an invalid flag:\n%s", buf.String())
+	}
+	report := buf.String()
+	for _, want := range []string{
+		`FAIL  flags: invalid -index_compression="zstd"`,
+		"PASS  serving directory " + tmpdir + "\n",
+		"checks failed\n",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report does not contain %q:\n%s", want, report)
+		}
+	}
+	// The sources could not be parsed, so their Release files are not
+	// checked.
+	if strings.Contains(report, "Release of") {
+		t.Errorf("report unexpectedly checks Release files:\n%s", report)
+	}
+}
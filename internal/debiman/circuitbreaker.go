@@ -0,0 +1,275 @@
+package debiman
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	mirrorFailureThreshold = flag.Int("mirror_failure_threshold",
+		5,
+		"Number of consecutive failed requests (connection errors or HTTP status 429, 500, 502, 503 or 504) to a mirror host after which requests to that host are paused for -mirror_backoff before a single request probes whether the host recovered")
+
+	mirrorBackoff = flag.Duration("mirror_backoff",
+		30*time.Second,
+		"How long to pause requests to a failing mirror host. Doubles each time a probe fails, up to 10 minutes. A Retry-After response header overrides the pause")
+
+	mirrorOutageDeadline = flag.Duration("mirror_outage_deadline",
+		1*time.Hour,
+		"Fail the run if a mirror host keeps failing for longer than this duration")
+)
+
+// maxMirrorBackoff caps the exponential backoff of -mirror_backoff.
+const maxMirrorBackoff = 10 * time.Minute
+
+type circuitState int
+
+const (
+	circuitClosed   circuitState = iota // requests pass
+	circuitOpen                         // requests wait until resumeAt
+	circuitHalfOpen                     // a single probe request passes
+)
+
+// hostCircuit is the state of the circuit breaker for one host.
+type hostCircuit struct {
+	state        circuitState
+	failures     int       // consecutive failures
+	failingSince time.Time // time of the first of failures
+	resumeAt     time.Time // when the open circuit becomes half-open
+	backoff      time.Duration
+	probing      bool // whether a probe request is in flight
+
+	// changed is closed (and replaced) whenever a probe finishes, so
+	// that waiting requests re-evaluate the state.
+	changed chan struct{}
+}
+
+// circuitBreaker is an http.RoundTripper which stops sending requests
+// to a host which keeps failing (e.g. a mirror undergoing maintenance)
+// until its backoff window elapsed, and honors Retry-After response
+// headers.
+type circuitBreaker struct {
+	next      http.RoundTripper
+	threshold int
+	backoff   time.Duration
+	deadline  time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*hostCircuit
+}
+
+func newCircuitBreaker(next http.RoundTripper, threshold int, backoff, deadline time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		next:      next,
+		threshold: threshold,
+		backoff:   backoff,
+		deadline:  deadline,
+		hosts:     make(map[string]*hostCircuit),
+	}
+}
+
+// outageError is returned when a host keeps failing for longer than
+// -mirror_outage_deadline.
+type outageError struct {
+	host     string
+	since    time.Time
+	deadline time.Duration
+}
+
+func (e *outageError) Error() string {
+	return fmt.Sprintf("%s has been failing since %v, giving up after -mirror_outage_deadline=%v", e.host, e.since.Format(time.RFC3339), e.deadline)
+}
+
+// retryAfter parses the Retry-After header of resp, which is either a
+// number of seconds or an HTTP date. It returns 0 if resp carries no
+// (valid) Retry-After header.
+func retryAfter(resp *http.Response, now time.Time) time.Duration {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// failed returns whether a request resulting in resp and err indicates
+// that the host is unavailable.
+func failed(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func (b *circuitBreaker) circuit(host string) *hostCircuit {
+	c, ok := b.hosts[host]
+	if !ok {
+		c = &hostCircuit{changed: make(chan struct{})}
+		b.hosts[host] = c
+	}
+	return c
+}
+
+// admit blocks until a request to host may be sent. It returns whether
+// the request is the probe of a half-open circuit.
+func (b *circuitBreaker) admit(req *http.Request) (probe bool, err error) {
+	host := req.URL.Host
+	for {
+		b.mu.Lock()
+		c := b.circuit(host)
+		now := time.Now()
+		// Give up instead of waiting past the deadline.
+		if c.state != circuitClosed && c.resumeAt.Sub(c.failingSince) > b.deadline {
+			b.mu.Unlock()
+			return false, &outageError{host: host, since: c.failingSince, deadline: b.deadline}
+		}
+		var wait <-chan time.Time
+		switch {
+		case c.state == circuitClosed:
+			b.mu.Unlock()
+			return false, nil
+		case c.state == circuitOpen && now.Before(c.resumeAt):
+			wait = time.After(c.resumeAt.Sub(now))
+		case !c.probing:
+			if c.state == circuitOpen {
+				log.Printf("mirror %s: circuit half-open, probing", host)
+				c.state = circuitHalfOpen
+			}
+			c.probing = true
+			b.mu.Unlock()
+			return true, nil
+		}
+		changed := c.changed
+		b.mu.Unlock()
+
+		select {
+		case <-wait:
+		case <-changed:
+		case <-req.Context().Done():
+			return false, req.Context().Err()
+		}
+	}
+}
+
+// record updates the circuit of host with the outcome of a request. It
+// returns whether the request should be retried, i.e. whether the host
+// asked to retry later (Retry-After) or its circuit is open.
+func (b *circuitBreaker) record(host string, probe bool, resp *http.Response, err error) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuit(host)
+	if probe {
+		c.endProbe()
+	}
+	now := time.Now()
+	if !failed(resp, err) {
+		if c.state != circuitClosed {
+			log.Printf("mirror %s: recovered after %v, circuit closed", host, now.Sub(c.failingSince))
+		}
+		*c = hostCircuit{changed: c.changed}
+		return false
+	}
+
+	if c.failures == 0 {
+		c.failingSince = now
+	}
+	c.failures++
+	var pause time.Duration
+	if resp != nil {
+		pause = retryAfter(resp, now)
+	}
+	switch {
+	case pause > 0:
+		log.Printf("mirror %s: HTTP status %d, honoring Retry-After: pausing requests for %v", host, resp.StatusCode, pause)
+	case c.state == circuitHalfOpen:
+		c.backoff *= 2
+		if c.backoff == 0 {
+			// The circuit was opened by a Retry-After header.
+			c.backoff = b.backoff
+		}
+		if c.backoff > maxMirrorBackoff {
+			c.backoff = maxMirrorBackoff
+		}
+		pause = c.backoff
+		log.Printf("mirror %s: probe failed (%v), circuit open, pausing requests for %v", host, describeFailure(resp, err), pause)
+	case c.state == circuitClosed && c.failures >= b.threshold:
+		c.backoff = b.backoff
+		pause = c.backoff
+		log.Printf("mirror %s: %d consecutive failures (last: %v), circuit open, pausing requests for %v", host, c.failures, describeFailure(resp, err), pause)
+	}
+	if pause > 0 {
+		c.state = circuitOpen
+		if resume := now.Add(pause); resume.After(c.resumeAt) {
+			c.resumeAt = resume
+		}
+	}
+	return c.state != circuitClosed
+}
+
+// abandon ends the probe of a request which was canceled by the caller
+// and hence says nothing about the host.
+func (b *circuitBreaker) abandon(host string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.circuit(host).endProbe()
+}
+
+func (c *hostCircuit) endProbe() {
+	c.probing = false
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+func describeFailure(resp *http.Response, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return resp.Status
+}
+
+// RoundTrip implements http.RoundTripper. Failed requests are retried
+// (once the circuit allows it) if the host asked to retry later or its
+// circuit is open, and if the request has no body. Otherwise, failures
+// are returned to the caller, which may retry on its own.
+func (b *circuitBreaker) RoundTrip(req *http.Request) (*http.Response, error) {
+	for {
+		probe, err := b.admit(req)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := b.next.RoundTrip(req)
+		if err != nil && req.Context().Err() != nil {
+			if probe {
+				b.abandon(req.URL.Host)
+			}
+			return resp, err
+		}
+		if !b.record(req.URL.Host, probe, resp, err) || req.Body != nil {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+	}
+}
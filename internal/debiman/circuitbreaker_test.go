@@ -0,0 +1,118 @@
+package debiman
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2017, 1, 20, 12, 0, 0, 0, time.UTC)
+	for _, entry := range []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", 0},
+		{"120", 2 * time.Minute},
+		{"Fri, 20 Jan 2017 12:00:30 GMT", 30 * time.Second},
+		{"Fri, 20 Jan 2017 11:00:00 GMT", 0}, // in the past
+		{"soon", 0},
+	} {
+		resp := &http.Response{Header: http.Header{}}
+		if entry.header != "" {
+			resp.Header.Set("Retry-After", entry.header)
+		}
+		if got := retryAfter(resp, now); got != entry.want {
+			t.Errorf("retryAfter(%q) = %v, want %v", entry.header, got, entry.want)
+		}
+	}
+}
+
+// flakyServer responds with 503 (and retryAfter, if non-empty) to the
+// first failures requests.
+func flakyServer(failures int64, retryAfter string) (*httptest.Server, *int64) {
+	var requests int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&requests, 1) <= failures {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			http.Error(w, "down for maintenance", http.StatusServiceUnavailable)
+		}
+	}))
+	return ts, &requests
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	ts, requests := flakyServer(3, "")
+	defer ts.Close()
+	client := &http.Client{Transport: newCircuitBreaker(http.DefaultTransport, 2, 20*time.Millisecond, time.Minute)}
+
+	// Below the threshold, failures are returned to the caller.
+	resp, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got, want := resp.StatusCode, http.StatusServiceUnavailable; got != want {
+		t.Fatalf("unexpected status code: got %d, want %d", got, want)
+	}
+
+	// The second failure opens the circuit. The first probe (after
+	// 20ms) fails, the second one (after another 40ms) succeeds.
+	start := time.Now()
+	resp, err = client.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got, want := resp.StatusCode, http.StatusOK; got != want {
+		t.Fatalf("unexpected status code: got %d, want %d", got, want)
+	}
+	if elapsed, want := time.Since(start), 60*time.Millisecond; elapsed < want {
+		t.Errorf("request returned after %v, want at least %v of backoff", elapsed, want)
+	}
+	if got, want := atomic.LoadInt64(requests), int64(4); got != want {
+		t.Errorf("unexpected number of requests: got %d, want %d", got, want)
+	}
+}
+
+func TestCircuitBreakerRetryAfter(t *testing.T) {
+	ts, requests := flakyServer(1, "1")
+	defer ts.Close()
+	client := &http.Client{Transport: newCircuitBreaker(http.DefaultTransport, 5, time.Minute, time.Minute)}
+
+	start := time.Now()
+	resp, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got, want := resp.StatusCode, http.StatusOK; got != want {
+		t.Fatalf("unexpected status code: got %d, want %d", got, want)
+	}
+	if elapsed, want := time.Since(start), 1*time.Second; elapsed < want {
+		t.Errorf("request returned after %v, want at least %v (Retry-After)", elapsed, want)
+	}
+	if got, want := atomic.LoadInt64(requests), int64(2); got != want {
+		t.Errorf("unexpected number of requests: got %d, want %d", got, want)
+	}
+}
+
+func TestCircuitBreakerDeadline(t *testing.T) {
+	ts, _ := flakyServer(1<<30, "")
+	defer ts.Close()
+	client := &http.Client{Transport: newCircuitBreaker(http.DefaultTransport, 1, 20*time.Millisecond, 100*time.Millisecond)}
+
+	_, err := client.Get(ts.URL)
+	uerr, ok := err.(*url.Error)
+	if !ok {
+		t.Fatalf("unexpected error: got %v, want *url.Error", err)
+	}
+	if _, ok := uerr.Err.(*outageError); !ok {
+		t.Fatalf("unexpected error: got %v, want *outageError", uerr.Err)
+	}
+}
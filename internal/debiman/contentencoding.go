@@ -0,0 +1,83 @@
+package debiman
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// fileCompressionSuffixes are the suffixes of files which some web
+// servers (e.g. Apache with AddEncoding) serve with a Content-Encoding
+// describing the file itself instead of the transfer. Such files must
+// not be decoded, as the archive package decompresses them based on
+// their file name after verifying their hash.
+var fileCompressionSuffixes = []string{".gz", ".tgz"}
+
+// contentDecoder is an http.RoundTripper which decodes responses with
+// Content-Encoding: gzip exactly once. net/http only decodes responses
+// transparently if it added the Accept-Encoding header itself, so
+// responses to requests which carry their own Accept-Encoding header
+// (or which a proxy encoded regardless) would otherwise be stored in
+// encoded form, e.g. as a corrupt Packages file.
+type contentDecoder struct {
+	next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (d *contentDecoder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Accept-Encoding") == "" && req.Header.Get("Range") == "" && req.Method != "HEAD" {
+		// Ask for gzip explicitly (where net/http would), so that
+		// we (and not net/http) decide whether to decode the
+		// response.
+		r := req.WithContext(req.Context())
+		r.Header = make(http.Header, len(req.Header)+1)
+		for k, v := range req.Header {
+			r.Header[k] = v
+		}
+		r.Header.Set("Accept-Encoding", "gzip")
+		req = r
+	}
+	resp, err := d.next.RoundTrip(req)
+	if err != nil || resp.Uncompressed || !strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		return resp, err
+	}
+	if req.Method == "HEAD" || resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified {
+		return resp, nil
+	}
+	for _, suffix := range fileCompressionSuffixes {
+		if strings.HasSuffix(req.URL.Path, suffix) {
+			return resp, nil
+		}
+	}
+	resp.Body = &gzipBody{body: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return resp, nil
+}
+
+// gzipBody decompresses body, reading the gzip header on first use
+// (like net/http), so that an error surfaces when reading the body.
+type gzipBody struct {
+	body io.ReadCloser
+	zr   *gzip.Reader
+	err  error
+}
+
+func (b *gzipBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	if b.zr == nil {
+		if b.zr, b.err = gzip.NewReader(b.body); b.err != nil {
+			return 0, b.err
+		}
+	}
+	return b.zr.Read(p)
+}
+
+func (b *gzipBody) Close() error {
+	return b.body.Close()
+}
@@ -0,0 +1,145 @@
+package debiman
+
+import (
+	"crypto/sha256"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	dedupManpages = flag.Bool("dedup",
+		false,
+		"Hard-link extracted manpages whose content is identical to another manpage of the same suite, saving disk space and page cache")
+
+	dedupNormalize = flag.String("dedup_normalize",
+		"",
+		"Comma-separated list of normalization rules to apply before comparing manpages for -dedup, so that manpages differing only in volatile tokens share an inode. Available rules: th_date, build_path, generator_comment. Normalization is only used for comparison, never for display")
+)
+
+type normalizeRule struct {
+	re   *regexp.Regexp
+	repl []byte
+}
+
+// normalizeRules are deliberately conservative: each only matches
+// tokens which are known to vary between otherwise identical builds.
+var normalizeRules = map[string]normalizeRule{
+	// The third argument of .TH is the date, e.g.:
+	// .TH LS 1 "2017-03-11" "GNU coreutils 8.26" "User Commands"
+	"th_date": {
+		re:   regexp.MustCompile(`(?m)^(\.TH[ \t]+(?:"[^"\n]*"|[^ \t\n]+)[ \t]+(?:"[^"\n]*"|[^ \t\n]+))[ \t]+(?:"[^"\n]*"|[^ \t\n]+)`),
+		repl: []byte(`$1 ""`),
+	},
+
+	// sbuild and pbuilder build in e.g. /build/coreutils-H8fj2b/,
+	// which ends up in manpages generated by help2man et al.
+	"build_path": {
+		re:   regexp.MustCompile(`/build/[^/\s]+/`),
+		repl: []byte("/build/"),
+	},
+
+	// e.g. .\" Generated by help2man 1.47.4 on Sat Mar 11 2017.
+	"generator_comment": {
+		re:   regexp.MustCompile(`(?m)^\.\\"[ \t]*(?:DO NOT MODIFY THIS FILE!  It was generated|Automatically generated|Generated) by .*$`),
+		repl: nil,
+	},
+}
+
+// deduper hard-links manpages with equal (or, with normalization,
+// equivalent) content within a suite.
+type deduper struct {
+	rules []normalizeRule
+	stats *stats
+
+	mu         sync.Mutex
+	exact      map[string]string // suite/sha256 → path
+	normalized map[string]string // suite/sha256 (normalized) → path
+}
+
+func newDeduper(ruleNames string, st *stats) (*deduper, error) {
+	d := &deduper{
+		stats:      st,
+		exact:      make(map[string]string),
+		normalized: make(map[string]string),
+	}
+	for _, name := range strings.Split(ruleNames, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		rule, ok := normalizeRules[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown -dedup_normalize rule %q", name)
+		}
+		d.rules = append(d.rules, rule)
+	}
+	return d, nil
+}
+
+func (d *deduper) normalize(content []byte) []byte {
+	for _, rule := range d.rules {
+		content = rule.re.ReplaceAll(content, rule.repl)
+	}
+	return content
+}
+
+// lookup returns the path of a previously recorded manpage in suite
+// whose content is equivalent to content, and whether normalization
+// was required to find it. If there is no such manpage, dest is
+// recorded and the empty string is returned.
+func (d *deduper) lookup(suite, dest string, content []byte) (existing string, normalized bool) {
+	exactKey := fmt.Sprintf("%s/%x", suite, sha256.Sum256(content))
+	var normKey string
+	if len(d.rules) > 0 {
+		normKey = fmt.Sprintf("%s/%x", suite, sha256.Sum256(d.normalize(content)))
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if existing, ok := d.exact[exactKey]; ok {
+		return existing, false
+	}
+	d.exact[exactKey] = dest
+	if normKey == "" {
+		return "", false
+	}
+	if existing, ok := d.normalized[normKey]; ok {
+		return existing, true
+	}
+	d.normalized[normKey] = dest
+	return "", false
+}
+
+// dedup replaces dest (which must contain content) with a hard link
+// to an equivalent manpage of the same suite, if any.
+func (d *deduper) dedup(suite, dest string, content []byte) error {
+	existing, normalized := d.lookup(suite, dest, content)
+	if existing == "" || existing == dest {
+		return nil
+	}
+	// Link to a temporary name first so that dest is swapped
+	// atomically, just like write.Atomically does.
+	tmp := filepath.Join(filepath.Dir(dest), ".debiman-link-"+filepath.Base(dest))
+	if err := os.Link(existing, tmp); err != nil {
+		if os.IsNotExist(err) {
+			return nil // existing was removed in the meantime
+		}
+		return err
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if normalized {
+		atomic.AddUint64(&d.stats.ManpagesDedupedNormalized, 1)
+	} else {
+		atomic.AddUint64(&d.stats.ManpagesDeduped, 1)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package debiman
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	table := []struct {
+		rules string
+		in    string
+		want  string
+	}{
+		{
+			rules: "th_date",
+			in:    ".TH LS 1 \"2017-03-11\" \"GNU coreutils 8.26\" \"User Commands\"\n",
+			want:  ".TH LS 1 \"\" \"GNU coreutils 8.26\" \"User Commands\"\n",
+		},
+
+		{
+			rules: "th_date",
+			in:    ".TH \"ls\" \"1\" 2017-03-11\n.SH NAME\n",
+			want:  ".TH \"ls\" \"1\" \"\"\n.SH NAME\n",
+		},
+
+		{
+			rules: "th_date",
+			in:    ".SH EXAMPLE\n.TH LS 1\n",
+			want:  ".SH EXAMPLE\n.TH LS 1\n",
+		},
+
+		{
+			rules: "build_path",
+			in:    "see /build/coreutils-H8fj2b/src/ls.c\n",
+			want:  "see /build/src/ls.c\n",
+		},
+
+		{
+			rules: "generator_comment",
+			in:    ".\\\" DO NOT MODIFY THIS FILE!  It was generated by help2man 1.47.4.\n.TH LS 1\n",
+			want:  "\n.TH LS 1\n",
+		},
+
+		{
+			rules: "generator_comment",
+			in:    ".\\\" written by hand\n",
+			want:  ".\\\" written by hand\n",
+		},
+
+		{
+			rules: "th_date,build_path",
+			in:    ".TH LS 1 2017-03-11\n/build/x-1/y\n",
+			want:  ".TH LS 1 \"\"\n/build/y\n",
+		},
+	}
+	for _, entry := range table {
+		entry := entry // capture
+		t.Run(entry.in, func(t *testing.T) {
+			t.Parallel()
+			d, err := newDeduper(entry.rules, &stats{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := string(d.normalize([]byte(entry.in))), entry.want; got != want {
+				t.Fatalf("Unexpected normalize() result: got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestNewDeduperUnknownRule(t *testing.T) {
+	if _, err := newDeduper("th_date,nonexistent", &stats{}); err == nil {
+		t.Fatalf("newDeduper() unexpectedly succeeded for an unknown rule")
+	}
+}
+
+func TestDedup(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "debiman-dedup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	files := []struct {
+		suite   string
+		name    string
+		content string
+	}{
+		{"stretch", "a.1.en.gz", ".TH A 1 2017-01-01\n"},
+		{"stretch", "b.1.en.gz", ".TH A 1 2017-01-01\n"}, // exact
+		{"stretch", "c.1.en.gz", ".TH A 1 2017-02-02\n"}, // normalized
+		{"jessie", "d.1.en.gz", ".TH A 1 2017-01-01\n"},  // other suite
+		{"stretch", "e.1.en.gz", ".TH E 1 2017-01-01\n"}, // different
+	}
+	var st stats
+	d, err := newDeduper("th_date", &st)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		path := filepath.Join(tmpdir, f.name)
+		if err := ioutil.WriteFile(path, []byte(f.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.dedup(f.suite, path, []byte(f.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a, err := os.Stat(filepath.Join(tmpdir, "a.1.en.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{
+		"b.1.en.gz": true,
+		"c.1.en.gz": true,
+		"d.1.en.gz": false,
+		"e.1.en.gz": false,
+	} {
+		fi, err := os.Stat(filepath.Join(tmpdir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := os.SameFile(a, fi); got != want {
+			t.Errorf("os.SameFile(a.1.en.gz, %s) = %v, want %v", name, got, want)
+		}
+	}
+	if got, want := st.ManpagesDeduped, uint64(1); got != want {
+		t.Errorf("ManpagesDeduped = %d, want %d", got, want)
+	}
+	if got, want := st.ManpagesDedupedNormalized, uint64(1); got != want {
+		t.Errorf("ManpagesDedupedNormalized = %d, want %d", got, want)
+	}
+}
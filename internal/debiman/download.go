@@ -1,4 +1,4 @@
-package main
+package debiman
 
 import (
 	"archive/tar"
@@ -24,8 +24,6 @@ import (
 	"github.com/Debian/debiman/internal/write"
 
 	"pault.ag/go/archive"
-	"pault.ag/go/debian/control"
-	"pault.ag/go/debian/deb"
 	"pault.ag/go/debian/version"
 )
 
@@ -159,6 +157,22 @@ func findFile(logger *log.Logger, src, name string, contentByPath map[string][]*
 	return name, "", false
 }
 
+// utf8BOM is the UTF-8 encoding of the byte order mark U+FEFF.
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// normalizeSource strips a leading byte order mark from the manpage
+// source and converts CRLF and CR line endings to LF. mandoc handles
+// neither: it shows the byte order mark as stray characters and does not
+// recognize requests and macros on lines ending in CR.
+func normalizeSource(source []byte) []byte {
+	source = bytes.TrimPrefix(source, utf8BOM)
+	if bytes.IndexByte(source, '\r') == -1 {
+		return source
+	}
+	source = bytes.Replace(source, []byte("\r\n"), []byte("\n"), -1)
+	return bytes.Replace(source, []byte("\r"), []byte("\n"), -1)
+}
+
 func soElim(logger *log.Logger, src string, r io.Reader, w io.Writer, contentByPath map[string][]*contentEntry) ([]string, error) {
 	var refs []string
 	scanner := bufio.NewScanner(r)
@@ -186,58 +200,84 @@ func soElim(logger *log.Logger, src string, r io.Reader, w io.Writer, contentByP
 	return refs, scanner.Err()
 }
 
-func writeManpage(logger *log.Logger, src, dest string, r io.Reader, m *manpage.Meta, contentByPath map[string][]*contentEntry) ([]string, error) {
+// writeManpage writes the manpage read from r to dest and returns the
+// .so references which need to be extracted as well, plus the
+// (uncompressed) content which was written.
+func writeManpage(logger *log.Logger, src, dest string, r io.Reader, m *manpage.Meta, contentByPath map[string][]*contentEntry) ([]string, []byte, error) {
 	var refs []string
 	content, err := ioutil.ReadAll(r)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if !utf8.Valid(content) {
 		content, err = ioutil.ReadAll(recode.Reader(bytes.NewReader(content), m.Language))
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
+	var buf bytes.Buffer
 	err = write.Atomically(dest, true, func(w io.Writer) error {
 		var err error
-		refs, err = soElim(logger, src, bytes.NewReader(content), w, contentByPath)
+		refs, err = soElim(logger, src, bytes.NewReader(content), io.MultiWriter(w, &buf), contentByPath)
 		return err
 	})
-	return refs, err
+	return refs, buf.Bytes(), err
 }
 
 func downloadPkg(ar *archive.Downloader, p pkgEntry, gv globalView) error {
 	vPath := filepath.Join(*servingDir, p.suite, p.binarypkg, "VERSION")
 
-	if !*forceReextract && canSkip(p, vPath) {
+	// Packages of -rebuild_package are always extracted.
+	if !*forceReextract && gv.rebuild == nil && canSkip(p, vPath) {
 		return nil
 	}
 
+	defer gv.log.Timed("extracting %s/%s %v", p.suite, p.binarypkg, p.version)()
+
 	logger := log.New(os.Stderr, p.suite+"/"+p.binarypkg+": ", log.LstdFlags)
 
-	tmp, err := ar.TempFile(control.FileHash{
-		Filename:  p.filename,
-		Algorithm: "sha256",
-		Hash:      fmt.Sprintf("%x", p.sha256),
-	})
-	if err != nil {
-		return fmt.Errorf("archive download: %v", err)
+	var (
+		src dataSource
+		cw  *cacheWriter
+	)
+	if gv.manpageCache != nil {
+		cached, err := gv.manpageCache.open(p)
+		if err != nil {
+			return err
+		}
+		if cached != nil {
+			atomic.AddUint64(&gv.stats.ManpageCacheHits, 1)
+			src = cached
+		} else {
+			atomic.AddUint64(&gv.stats.ManpageCacheMisses, 1)
+			if cw, err = gv.manpageCache.create(p); err != nil {
+				return err
+			}
+			defer cw.abort() // no-op after commit
+		}
 	}
-	defer os.Remove(tmp.Name())
-	defer tmp.Close()
-
-	if _, err := tmp.Seek(0, os.SEEK_SET); err != nil {
-		return err
+	if src == nil {
+		deb, err := downloadDeb(ar, p, gv.stats)
+		if err != nil {
+			return err
+		}
+		src = deb
 	}
+	defer src.Close()
 
 	allRefs := make(map[string]bool)
+	infoDocs := make(infoDocuments)
+	var changelog []changelogEntry
+	// found contains the manpages (relative to usr/share/man) of the
+	// package, see -verify_contents.
+	found := make(map[string]bool)
 
-	d, err := deb.Load(tmp, p.filename)
+	data, err := src.data()
 	if err != nil {
-		return fmt.Errorf("loading %q: %v", p.filename, err)
+		return err
 	}
 	for {
-		header, err := d.Data.Next()
+		header, err := data.Next()
 		if err == io.EOF {
 			break
 		}
@@ -254,9 +294,44 @@ func downloadPkg(ar *archive.Downloader, p pkgEntry, gv globalView) error {
 		if header.FileInfo().IsDir() {
 			continue
 		}
+		if *infoPages && strings.HasPrefix(header.Name, "./usr/share/info/") {
+			if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+				continue
+			}
+			content, err := ioutil.ReadAll(data)
+			if err != nil {
+				return err
+			}
+			if err := cw.add(header, content); err != nil {
+				return err
+			}
+			infoDocs.add(header.Name, content, header.ModTime)
+			continue
+		}
+		if *changelogEntries > 0 && header.Name == changelogName(p.binarypkg) {
+			if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+				continue
+			}
+			content, err := ioutil.ReadAll(data)
+			if err != nil {
+				return err
+			}
+			if err := cw.add(header, content); err != nil {
+				return err
+			}
+			gzr, err := gzip.NewReader(bytes.NewReader(content))
+			if err == nil {
+				changelog, err = parseChangelog(gzr, *changelogEntries)
+			}
+			if err != nil {
+				logger.Printf("WARNING: omitting changelog %q: %v", header.Name, err)
+			}
+			continue
+		}
 		if !strings.HasPrefix(header.Name, "./usr/share/man/") {
 			continue
 		}
+		found[strings.TrimPrefix(header.Name, "./usr/share/man/")] = true
 
 		destdir := filepath.Join(*servingDir, p.suite, p.binarypkg)
 		if err := os.MkdirAll(destdir, 0755); err != nil {
@@ -274,6 +349,23 @@ func downloadPkg(ar *archive.Downloader, p pkgEntry, gv globalView) error {
 			continue
 		}
 
+		// Whether a manpage collides depends on the other packages, so
+		// the -manpage_cache entry contains colliding manpages, too.
+		var content []byte
+		if header.Typeflag == tar.TypeReg || header.Typeflag == tar.TypeRegA {
+			if content, err = ioutil.ReadAll(data); err != nil {
+				return err
+			}
+		}
+		if err := cw.add(header, content); err != nil {
+			return err
+		}
+
+		if gv.caseCollisions[manpageID(m)] {
+			logger.Printf("WARNING: skipping %q, its file name collides with another manpage", header.Name)
+			continue
+		}
+
 		destPath := filepath.Join(*servingDir, m.ServingPath()+".gz")
 		if header.Typeflag == tar.TypeLink {
 			d, err := manpage.FromManPath(strings.TrimPrefix(header.Linkname, "./usr/share/man/"), &manpage.PkgMeta{
@@ -284,6 +376,13 @@ func downloadPkg(ar *archive.Downloader, p pkgEntry, gv globalView) error {
 				logger.Printf("WARNING: hard link name %q (underneath /usr/share/man) cannot be parsed: %v", header.Linkname, err)
 				continue
 			}
+			if _, ok := readAlias(*servingDir, d.ServingPath()); ok {
+				// The link target was not extracted, see -so_aliases.
+				if err := markAlias(destPath, d.ServingPath()); err != nil {
+					return err
+				}
+				continue
+			}
 			if err := os.Link(filepath.Join(*servingDir, d.ServingPath()+".gz"), m.ServingPath()+".gz"); err != nil {
 				if os.IsExist(err) {
 					continue
@@ -329,24 +428,62 @@ func downloadPkg(ar *archive.Downloader, p pkgEntry, gv globalView) error {
 			continue
 		}
 
-		r := io.Reader(d.Data)
+		r := io.Reader(bytes.NewReader(content))
 		var gzr *gzip.Reader
 		if strings.HasSuffix(header.Name, ".gz") {
-			gzr, err = gzip.NewReader(d.Data)
+			gzr, err = gzip.NewReader(r)
 			if err != nil {
 				return err
 			}
 			r = gzr
 		}
-		refs, err := writeManpage(logger, header.Name, destPath, r, m, gv.contentByPath)
+		source, err := readSource(r)
+		if err != nil {
+			return err
+		}
+		if gzr != nil {
+			if err := gzr.Close(); err != nil {
+				return err
+			}
+		}
+		// The size filter applies to the source as shipped.
+		size := len(source)
+		source = normalizeSource(source)
+		var (
+			target string
+			alias  bool
+		)
+		if so, ok := soTarget(source); ok && *soAliases {
+			target, alias = aliasTarget(logger, header.Name, so, m, gv.contentByPath)
+		}
+		var reason string
+		if !alias {
+			reason = sizeFilterReason(size)
+		}
+		if err := markSizeFiltered(destPath, reason != ""); err != nil {
+			return err
+		}
+		if err := markAlias(destPath, target); err != nil {
+			return err
+		}
+		if alias {
+			logger.Printf("%q is an alias of %q (see -so_aliases)", header.Name, target)
+			continue
+		}
+		if reason != "" {
+			atomic.AddUint64(&gv.stats.ManpagesSizeFiltered, 1)
+			logger.Printf("WARNING: skipping %q: %s", header.Name, reason)
+			continue
+		}
+		refs, written, err := writeManpage(logger, header.Name, destPath, bytes.NewReader(source), m, gv.contentByPath)
 		if err != nil {
 			return err
 		}
 		if err := os.Chtimes(destPath, header.ModTime, header.ModTime); err != nil {
 			return err
 		}
-		if gzr != nil {
-			if err := gzr.Close(); err != nil {
+		if gv.dedup != nil {
+			if err := gv.dedup.dedup(p.suite, destPath, written); err != nil {
 				return err
 			}
 		}
@@ -356,6 +493,41 @@ func downloadPkg(ar *archive.Downloader, p pkgEntry, gv globalView) error {
 		}
 	}
 
+	if expected := gv.contentsManpages[p.suite+"/"+p.binarypkg]; expected != nil {
+		missing, unexpected := diffManpages(expected, found, func(filename string) bool {
+			// Manpages which are skipped during extraction are
+			// missing from -manpage_cache entries written by
+			// older versions of debiman.
+			m, err := manpage.FromManPath(filename, &manpage.PkgMeta{
+				Binarypkg: p.binarypkg,
+				Suite:     p.suite,
+			})
+			return err == nil && !gv.caseCollisions[manpageID(m)]
+		})
+		if len(missing) > 0 || len(unexpected) > 0 {
+			atomic.AddUint64(&gv.stats.ContentsDivergent, 1)
+			logger.Printf("WARNING: manpages diverge from the Contents index: %d missing (%s), %d not listed (%s)", len(missing), abbreviate(missing), len(unexpected), abbreviate(unexpected))
+		}
+		if len(missing) > 0 && *verifyContents == "fail" {
+			atomic.AddUint64(&gv.stats.ContentsDiscarded, 1)
+			logger.Printf("discarding the extracted manpages (see -verify_contents), they will be extracted again in the next run")
+			if _, ok := src.(*cacheSource); ok {
+				if err := os.Remove(gv.manpageCache.path(p)); err != nil && !os.IsNotExist(err) {
+					return err
+				}
+			}
+			return os.RemoveAll(filepath.Join(*servingDir, p.suite, p.binarypkg))
+		}
+	}
+
+	if err := infoDocs.write(logger, p); err != nil {
+		return err
+	}
+
+	if err := writeChangelog(filepath.Join(*servingDir, p.suite, p.binarypkg), changelog); err != nil {
+		return err
+	}
+
 	// Create all symlinks for slave alternatives.
 	key := p.suite + "/" + p.binarypkg
 	logger.Printf("creating %d links for binary package %q", len(gv.alternatives[key]), p.binarypkg)
@@ -373,6 +545,11 @@ func downloadPkg(ar *archive.Downloader, p pkgEntry, gv globalView) error {
 			continue
 		}
 
+		if gv.caseCollisions[manpageID(m)] {
+			logger.Printf("WARNING: skipping %q, its file name collides with another manpage", link.from)
+			continue
+		}
+
 		resolved := link.to
 		if !strings.HasSuffix(resolved, ".gz") {
 			resolved = resolved + ".gz"
@@ -411,16 +588,12 @@ func downloadPkg(ar *archive.Downloader, p pkgEntry, gv globalView) error {
 	// Extract all non-manpage files which were referenced via .so
 	// statements, if any.
 	if len(allRefs) > 0 {
-		if _, err := tmp.Seek(0, os.SEEK_SET); err != nil {
-			return err
-		}
-
-		d, err = deb.Load(tmp, p.filename)
+		data, err := src.data()
 		if err != nil {
 			return err
 		}
 		for {
-			header, err := d.Data.Next()
+			header, err := data.Next()
 			if err == io.EOF {
 				break
 			}
@@ -447,8 +620,15 @@ func downloadPkg(ar *archive.Downloader, p pkgEntry, gv globalView) error {
 			if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
 				return err
 			}
+			content, err := ioutil.ReadAll(data)
+			if err != nil {
+				return err
+			}
+			if err := cw.add(header, content); err != nil {
+				return err
+			}
 			if err := write.Atomically(destPath, false, func(w io.Writer) error {
-				_, err := io.Copy(w, d.Data)
+				_, err := w.Write(content)
 				return err
 			}); err != nil {
 				return err
@@ -456,6 +636,10 @@ func downloadPkg(ar *archive.Downloader, p pkgEntry, gv globalView) error {
 		}
 	}
 
+	if err := cw.commit(); err != nil {
+		return fmt.Errorf("writing manpage cache: %v", err)
+	}
+
 	if err := ioutil.WriteFile(vPath, []byte(p.version.String()), 0644); err != nil {
 		if os.IsNotExist(err) {
 			// If the directory does not exist, we did not extract any
@@ -471,14 +655,20 @@ func downloadPkg(ar *archive.Downloader, p pkgEntry, gv globalView) error {
 	return nil
 }
 
-func parallelDownload(ar *archive.Downloader, gv globalView) error {
+func parallelDownload(gv globalView) error {
+	if *verifyContents != "off" {
+		gv.contentsManpages = expectedManpages(gv.contentByPath)
+	}
 	eg, ctx := errgroup.WithContext(context.Background())
 	downloadChan := make(chan pkgEntry)
 	// TODO: flag for parallelism level
 	for i := 0; i < 10; i++ {
 		eg.Go(func() error {
 			for p := range downloadChan {
-				if err := downloadPkg(ar, p, gv); err != nil {
+				release := gv.pool.acquire(phaseDownload)
+				err := downloadPkg(p.ar, p, gv)
+				release()
+				if err != nil {
 					return fmt.Errorf("downloading %s/src:%s %v: %v", p.suite, p.source, p.version, err)
 				}
 			}
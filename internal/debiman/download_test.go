@@ -1,11 +1,14 @@
-package main
+package debiman
 
 import (
 	"bytes"
+	"io/ioutil"
 	"log"
 	"os"
 	"strings"
 	"testing"
+
+	"github.com/Debian/debiman/internal/convert"
 )
 
 func TestWriteManpage(t *testing.T) {
@@ -179,3 +182,58 @@ func TestWriteManpage(t *testing.T) {
 		})
 	}
 }
+
+// normalizeFixtures are variants of testdata/i3lock.1 with a byte
+// order mark and with mixed CRLF and CR line endings.
+var normalizeFixtures = []string{"i3lock.bom.1", "i3lock.crlf.1"}
+
+func TestNormalizeSource(t *testing.T) {
+	clean, err := ioutil.ReadFile("../../testdata/i3lock.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := normalizeSource(clean); !bytes.Equal(got, clean) {
+		t.Errorf("normalizeSource modified the clean source")
+	}
+	for _, fixture := range normalizeFixtures {
+		b, err := ioutil.ReadFile("../../testdata/" + fixture)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Equal(b, clean) {
+			t.Fatalf("%s is identical to i3lock.1", fixture)
+		}
+		if got := normalizeSource(b); !bytes.Equal(got, clean) {
+			t.Errorf("normalizeSource(%s) differs from i3lock.1", fixture)
+		}
+	}
+}
+
+func TestNormalizedRender(t *testing.T) {
+	converter, err := convert.NewProcess()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer converter.Kill()
+	render := func(source []byte) string {
+		doc, _, err := converter.ToHTML(bytes.NewReader(source), func(ref string) string { return ref })
+		if err != nil {
+			t.Fatal(err)
+		}
+		return doc
+	}
+	clean, err := ioutil.ReadFile("../../testdata/i3lock.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := render(clean)
+	for _, fixture := range normalizeFixtures {
+		b, err := ioutil.ReadFile("../../testdata/" + fixture)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := render(normalizeSource(b)); got != want {
+			t.Errorf("rendering %s differs from rendering i3lock.1", fixture)
+		}
+	}
+}
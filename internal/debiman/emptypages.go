@@ -0,0 +1,125 @@
+package debiman
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/html"
+
+	"github.com/Debian/debiman/internal/manpage"
+)
+
+var (
+	minTextLength = flag.Int("min_text_length",
+		32,
+		"Warn about rendered manpages whose text (excluding the header and footer lines mandoc adds to every manpage) has fewer than this many non-whitespace characters, e.g. stubs or manpages whose .so reference could not be resolved. Manpages with a NAME section are considered legitimately terse and never flagged. 0 disables the check")
+
+	dropEmptyManpages = flag.Bool("drop_empty_manpages",
+		false,
+		"Omit manpages flagged by -min_text_length from the auxserver index. They are still rendered, so that existing links keep working")
+)
+
+// skippedClasses are the classes of the elements which mandoc
+// generates for every manpage: the header and footer tables and the
+// anchors of section headings.
+var skippedClasses = map[string]bool{
+	"head":   true,
+	"foot":   true,
+	"anchor": true,
+}
+
+var voidElements = map[string]bool{
+	"br":  true,
+	"hr":  true,
+	"img": true,
+	"wbr": true,
+}
+
+// textLength returns the number of non-whitespace characters of the
+// text of content (as converted by mandoc), without the elements of
+// skippedClasses.
+func textLength(content string) int {
+	var (
+		n    int
+		skip int // depth within an element whose text is skipped
+	)
+	z := html.NewTokenizer(strings.NewReader(content))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return n // io.EOF, or truncated HTML
+		case html.StartTagToken:
+			name, hasAttr := z.TagName()
+			if voidElements[string(name)] {
+				continue // no end tag
+			}
+			if skip > 0 {
+				skip++
+				continue
+			}
+			for hasAttr {
+				var key, val []byte
+				key, val, hasAttr = z.TagAttr()
+				if string(key) == "class" && skippedClasses[string(val)] {
+					skip = 1
+				}
+			}
+		case html.EndTagToken:
+			if skip > 0 {
+				skip--
+			}
+		case html.TextToken:
+			if skip > 0 {
+				continue
+			}
+			for _, r := range string(z.Text()) {
+				if !unicode.IsSpace(r) {
+					n++
+				}
+			}
+		}
+	}
+}
+
+// hasNameSection returns whether toc (the section headings of a
+// manpage) contains a NAME section.
+func hasNameSection(toc []string) bool {
+	for _, heading := range toc {
+		if strings.EqualFold(strings.TrimSpace(heading), "NAME") {
+			return true
+		}
+	}
+	return false
+}
+
+// emptyMarker returns the path of the file which marks the rendered
+// manpage dest (e.g. …/ls.1.en.html.gz) as flagged by
+// -min_text_length. The marker persists across runs in which the
+// manpage is not re-rendered. As a dot file, it is not published.
+func emptyMarker(dest string) string {
+	dir, base := filepath.Split(dest)
+	return filepath.Join(dir, "."+strings.TrimSuffix(base, ".html.gz")+".empty")
+}
+
+// markEmpty creates or removes the emptyMarker of dest.
+func markEmpty(dest string, empty bool) error {
+	fn := emptyMarker(dest)
+	if empty {
+		return ioutil.WriteFile(fn, nil, 0644)
+	}
+	if err := os.Remove(fn); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// flaggedEmpty returns whether the rendered HTML of m in servingDir was
+// flagged by -min_text_length.
+func flaggedEmpty(servingDir string, m *manpage.Meta) bool {
+	_, err := os.Stat(emptyMarker(filepath.Join(servingDir, m.ServingPath()+".html.gz")))
+	return err == nil
+}
@@ -0,0 +1,80 @@
+package debiman
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// stub is what mandoc produces for a manpage consisting only of .TH
+// (e.g. after a failed .so resolution).
+const stub = `<div class="mandoc">
+<table class="head">
+  <tbody><tr>
+    <td class="head-ltitle">FOO(1)</td>
+    <td class="head-vol">General Commands Manual</td>
+    <td class="head-rtitle">FOO(1)</td>
+  </tr>
+</tbody></table>
+<div class="manual-text"><br>
+</div>
+<table class="foot">
+  <tbody><tr>
+    <td class="foot-date">January 2017</td>
+    <td class="foot-os">Debian</td>
+  </tr>
+</tbody></table>
+</div>`
+
+func TestTextLength(t *testing.T) {
+	if got := textLength(stub); got != 0 {
+		t.Errorf("textLength(stub) = %d, want 0", got)
+	}
+	terse := `<div class="manual-text">
+<h1 class="Sh" id="NAME">NAME<a class="anchor" href="#NAME">¶</a></h1>
+foo - bar
+</div>`
+	if got, want := textLength(terse), len("NAMEfoo-bar"); got != want {
+		t.Errorf("textLength(terse) = %d, want %d", got, want)
+	}
+	b, err := ioutil.ReadFile("../../testdata/i3lock.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := textLength(string(b)); got < 500 {
+		t.Errorf("textLength(i3lock.html) = %d, want at least 500", got)
+	}
+
+	if hasNameSection([]string{"SYNOPSIS"}) {
+		t.Errorf("hasNameSection unexpectedly found a NAME section")
+	}
+	if !hasNameSection([]string{"Name", "SYNOPSIS"}) {
+		t.Errorf("hasNameSection did not find the NAME section")
+	}
+}
+
+func TestEmptyMarker(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "debiman-empty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	m := mustParseFromServingPath(t, "jessie/foo/foo.1.en")
+	dest := filepath.Join(tmpdir, m.ServingPath()+".html.gz")
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := emptyMarker(dest), filepath.Join(tmpdir, "jessie/foo/.foo.1.en.empty"); got != want {
+		t.Fatalf("emptyMarker(%q) = %q, want %q", dest, got, want)
+	}
+	for _, empty := range []bool{true, false, false} {
+		if err := markEmpty(dest, empty); err != nil {
+			t.Fatal(err)
+		}
+		if got := flaggedEmpty(tmpdir, m); got != empty {
+			t.Errorf("flaggedEmpty = %v, want %v", got, empty)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package debiman
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/Debian/debiman/internal/blob"
+	"github.com/Debian/debiman/internal/write"
+	"golang.org/x/net/context"
+	"golang.org/x/sync/errgroup"
+)
+
+var etagManifest = flag.Bool("etag_manifest",
+	false,
+	"Write the content hash of each file of -serving_dir to "+blob.ETagManifestName+" at the end of each run, from which debiman-minisrv derives ETags: weak ETags for pages, which stay the same when a page is rendered again with identical content, and strong ETags plus immutable caching for assets requested with their version (e.g. style.css?<version>). Only files which changed since the last run are hashed")
+
+// hashFile returns the hex-encoded SHA-256 hash of the content of
+// path, which is decompressed first if gzipped is true.
+func hashFile(path string, gzipped bool) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	r := io.Reader(f)
+	if gzipped {
+		gzipr, err := gzip.NewReader(f)
+		if err != nil {
+			return "", err
+		}
+		defer gzipr.Close()
+		r = gzipr
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeETagManifest writes the blob.ETagManifest of servingDir, taking
+// the hashes of files which did not change from the previous manifest.
+func writeETagManifest(servingDir string) error {
+	path := filepath.Join(servingDir, blob.ETagManifestName)
+	prev, err := blob.ReadETagManifest(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Hashing all files: reading %q: %v", path, err)
+		}
+		prev = &blob.ETagManifest{}
+	}
+	files, err := scanServingDir(servingDir)
+	if err != nil {
+		return err
+	}
+
+	m := blob.ETagManifest{
+		Version: blob.ETagManifestVersion,
+		Files:   make(map[string]blob.ETagFile, len(files)),
+	}
+	var (
+		mu    sync.Mutex
+		stale []blob.ETagFile
+	)
+	for name, f := range files {
+		o := describe(files, name)
+		if o.Shadowed || o.ContentEncoding == "zstd" {
+			continue // another variant of the same content is hashed
+		}
+		e := blob.ETagFile{Name: name, Size: f.Size, ModTime: f.ModTime}
+		if p, ok := prev.Files[o.Key]; ok && p.Name == e.Name && p.Size == e.Size && p.ModTime == e.ModTime {
+			m.Files[o.Key] = p
+			continue
+		}
+		stale = append(stale, e)
+	}
+
+	work := make(chan blob.ETagFile)
+	eg, ctx := errgroup.WithContext(context.Background())
+	for i := 0; i < runtime.NumCPU(); i++ {
+		eg.Go(func() error {
+			for e := range work {
+				o := blob.Describe(e.Name)
+				hash, err := hashFile(filepath.Join(servingDir, filepath.FromSlash(e.Name)), o.ContentEncoding == "gzip")
+				if err != nil {
+					return fmt.Errorf("hashing %q: %v", e.Name, err)
+				}
+				e.SHA256 = hash
+				mu.Lock()
+				m.Files[o.Key] = e
+				mu.Unlock()
+			}
+			return nil
+		})
+	}
+	eg.Go(func() error {
+		defer close(work)
+		for _, e := range stale {
+			select {
+			case work <- e:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return nil
+	})
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+	log.Printf("Hashed %d changed files (of %d) for the ETag manifest", len(stale), len(m.Files))
+
+	return write.Atomically(path, true, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(&m)
+	})
+}
@@ -0,0 +1,89 @@
+package debiman
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Debian/debiman/internal/aux"
+	"github.com/Debian/debiman/internal/blob"
+	"github.com/Debian/debiman/internal/write"
+)
+
+func TestETagManifestNoopRebuild(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "debiman-etags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	const key = "jessie/i3-wm/i3.1.en.html"
+	page := filepath.Join(tmpdir, "jessie", "i3-wm", "i3.1.en.html.gz")
+	if err := os.MkdirAll(filepath.Dir(page), 0755); err != nil {
+		t.Fatal(err)
+	}
+	render := func(content string, modTime time.Time) {
+		if err := write.Atomically(page, true, func(w io.Writer) error {
+			_, err := io.WriteString(w, content)
+			return err
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(page, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	etag := func() (string, bool) {
+		if err := writeETagManifest(tmpdir); err != nil {
+			t.Fatal(err)
+		}
+		m, err := blob.ReadETagManifest(filepath.Join(tmpdir, blob.ETagManifestName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		hash, ok := m.Lookup(tmpdir, key)
+		if !ok {
+			t.Fatalf("ETag manifest lacks %q: %+v", key, m.Files)
+		}
+		rec := httptest.NewRecorder()
+		notModified := aux.FileETag(rec, httptest.NewRequest("GET", "/"+key, nil), key, hash, "gzip")
+		return rec.Header().Get("ETag"), notModified
+	}
+
+	mtime := time.Now().Add(-1 * time.Hour)
+	render("<p>i3</p>", mtime)
+	before, _ := etag()
+
+	// The page is rendered again with identical content, e.g. after a
+	// mandoc upgrade which did not affect it.
+	render("<p>i3</p>", mtime.Add(30*time.Minute))
+	after, _ := etag()
+	if after != before {
+		t.Errorf("ETag changed in a no-op rebuild: got %q, want %q", after, before)
+	}
+	req := httptest.NewRequest("GET", "/"+key, nil)
+	req.Header.Set("If-None-Match", before)
+	if !aux.FileETag(httptest.NewRecorder(), req, key, after[len(`W/"`):len(after)-1], "gzip") {
+		t.Errorf("If-None-Match %q does not match after a no-op rebuild", before)
+	}
+
+	render("<p>i3 window manager</p>", mtime.Add(45*time.Minute))
+	if changed, _ := etag(); changed == before {
+		t.Errorf("ETag %q did not change with the content", changed)
+	}
+
+	// Entries of files which changed after the manifest was written
+	// are not used.
+	m, err := blob.ReadETagManifest(filepath.Join(tmpdir, blob.ETagManifestName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	render("<p>i3</p>", mtime)
+	if hash, ok := m.Lookup(tmpdir, key); ok {
+		t.Errorf("Lookup unexpectedly returned %q for a changed file", hash)
+	}
+}
@@ -0,0 +1,50 @@
+package debiman
+
+import (
+	"bytes"
+	"html/template"
+	"net/url"
+	"strings"
+
+	"github.com/Debian/debiman/internal/bundled"
+)
+
+var manpagefragmentTmpl = mustParseManpagefragmentTmpl()
+
+func mustParseManpagefragmentTmpl() *template.Template {
+	return template.Must(template.Must(commonTmpls.Clone()).New("manpage-fragment").
+		Parse(bundled.Asset("manpagefragment.tmpl")))
+}
+
+// fragmentDest returns the path of the fragment variant of the
+// rendered manpage dest, e.g. i3.1.en.frag.html.gz for i3.1.en.html.gz.
+func fragmentDest(dest string) string {
+	return strings.TrimSuffix(dest, ".html.gz") + ".frag.html.gz"
+}
+
+// absoluteLinks makes all host-relative links in content (i.e. the
+// cross-references) absolute, so that they keep working when the
+// fragment is embedded into a page served by a different host.
+func absoluteLinks(content template.HTML, baseURL string) (template.HTML, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", err
+	}
+	origin := u.Scheme + "://" + u.Host
+	return template.HTML(strings.Replace(string(content), `href="/`, `href="`+origin+`/`, -1)), nil
+}
+
+// renderFragment renders only the manpage itself (or the error
+// message), without header, footer and navigation panels.
+func renderFragment(data manpagePrepData) ([]byte, error) {
+	var err error
+	data.Content, err = absoluteLinks(data.Content, *baseURL)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := manpagefragmentTmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,48 @@
+package debiman
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/language"
+
+	"github.com/Debian/debiman/internal/manpage"
+)
+
+func TestRenderFragment(t *testing.T) {
+	if got, want := fragmentDest("/srv/man/jessie/i3-wm/i3.1.en.html.gz"), "/srv/man/jessie/i3-wm/i3.1.en.frag.html.gz"; got != want {
+		t.Fatalf("unexpected fragmentDest: got %q, want %q", got, want)
+	}
+
+	content, err := absoluteLinks(template.HTML(`<a href="/jessie/i3-wm/i3-msg.1.en.html">i3-msg(1)</a> <a href="#NAME">NAME</a> <a href="https://i3wm.org/">i3wm.org</a>`), "https://manpages.example.org/sub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(content), `<a href="https://manpages.example.org/jessie/i3-wm/i3-msg.1.en.html">i3-msg(1)</a> <a href="#NAME">NAME</a> <a href="https://i3wm.org/">i3wm.org</a>`; got != want {
+		t.Fatalf("unexpected absoluteLinks result: got %q, want %q", got, want)
+	}
+
+	frag, err := renderFragment(manpagePrepData{
+		Meta: &manpage.Meta{
+			Name:        "i3",
+			Section:     "1",
+			Language:    "en",
+			LanguageTag: language.English,
+			Package:     &manpage.PkgMeta{Binarypkg: "i3-wm", Suite: "jessie"},
+		},
+		Content: template.HTML(`<div class="mandoc">i3</div>`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(frag)
+	for _, want := range []string{`<article class="debiman-manpage" lang="en">`, `<div class="mandoc">i3</div>`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("fragment %q does not contain %q", got, want)
+		}
+	}
+	if strings.Contains(got, `id="header"`) {
+		t.Errorf("fragment %q unexpectedly contains the page header", got)
+	}
+}
@@ -1,13 +1,14 @@
-package main
+package debiman
 
 import (
 	"bufio"
 	"bytes"
-	"fmt"
 	"io"
 	"log"
 	"os"
+	"strings"
 
+	"github.com/Debian/debiman/internal/manpage"
 	"golang.org/x/sync/errgroup"
 
 	"pault.ag/go/archive"
@@ -18,22 +19,52 @@ type contentEntry struct {
 	suite     string
 	arch      string
 	binarypkg string
-	filename  string
+
+	// filename is relative to usr/share/man for manpages. Info
+	// documents (see -info_pages) are identified by their full path,
+	// e.g. usr/share/info/coreutils.info.gz.
+	filename string
+
+	// archs lists the architectures whose Contents file lists
+	// filename for binarypkg if the manpages of binarypkg differ
+	// across architectures, and is nil otherwise. arch is the
+	// preferred one of them (see -primary_architecture).
+	archs []string
 }
 
-var manPrefix = []byte("usr/share/man/")
+var (
+	manPrefix  = []byte("usr/share/man/")
+	infoPrefix = []byte("usr/share/info/")
+)
+
+// path returns the path of e in the Contents file, by which the
+// Contents files are sorted.
+func (e *contentEntry) path() string {
+	if strings.HasPrefix(e.filename, string(infoPrefix)) {
+		return e.filename
+	}
+	return string(manPrefix) + e.filename
+}
 
 func parseContentsEntry(scanner *bufio.Scanner) ([]*contentEntry, error) {
 	for scanner.Scan() {
 		text := scanner.Bytes()
+		prefix := manPrefix
 		if !bytes.HasPrefix(text, manPrefix) {
-			continue
+			if !*infoPages || !bytes.HasPrefix(text, infoPrefix) {
+				continue
+			}
+			prefix = nil // info documents keep their full path
 		}
 
 		idx := bytes.LastIndex(text, []byte{' '})
 		if idx == -1 {
 			continue
 		}
+		filename := string(bytes.TrimSpace(text[len(prefix):idx]))
+		if prefix == nil && !manpage.IsInfoDocument(strings.TrimPrefix(filename, string(infoPrefix))) {
+			continue
+		}
 		parts := bytes.Split(text[idx:], []byte{','})
 		entries := make([]*contentEntry, 0, len(parts))
 		for _, part := range parts {
@@ -43,7 +74,7 @@ func parseContentsEntry(scanner *bufio.Scanner) ([]*contentEntry, error) {
 			}
 			entries = append(entries, &contentEntry{
 				binarypkg: string(part[idx2+1:]),
-				filename:  string(bytes.TrimSpace(text[len(manPrefix):idx])),
+				filename:  filename,
 			})
 		}
 		if len(entries) > 0 {
@@ -69,10 +100,9 @@ func getContents(ar *archive.Downloader, suite string, component string, archs [
 		idx := idx   // copy
 		arch := arch // copy
 		eg.Go(func() error {
-			path := component + "/Contents-" + arch + ".gz"
-			fh, ok := hashByFilename[path]
-			if !ok {
-				return fmt.Errorf("ERROR: expected path %q not found in Release file", path)
+			path, fh, err := pickIndexVariant(hashByFilename, component+"/Contents-"+arch, *indexCompression)
+			if err != nil {
+				return err
 			}
 
 			log.Printf("getting %q (hash %v)", suite+"/"+path, fh.Hash)
@@ -108,6 +138,15 @@ func getContents(ar *archive.Downloader, suite string, component string, archs [
 		return nil, err
 	}
 
+	ranks := archRanks(archs)
+	// masks[i] is the set of architectures (as bits, by index into
+	// archs) whose Contents file lists entries[i].
+	var masks []uint64
+	detect := len(archs) <= 64
+	if !detect {
+		log.Printf("not detecting architecture-specific manpages in %s/%s: %d architectures exceed the supported 64", suite, component, len(archs))
+	}
+
 	var entries []*contentEntry
 	for {
 		for idx, move := range advance {
@@ -137,7 +176,7 @@ func getContents(ar *archive.Downloader, suite string, component string, archs [
 			if exhausted[idx] {
 				continue
 			}
-			if len(contents[lowest]) == 0 || contents[idx][0].filename < contents[lowest][0].filename {
+			if len(contents[lowest]) == 0 || contents[idx][0].path() < contents[lowest][0].path() {
 				lowest = idx
 			}
 		}
@@ -146,39 +185,50 @@ func getContents(ar *archive.Downloader, suite string, component string, archs [
 			advance[idx] = !exhausted[idx] && contents[lowest][0].filename == contents[idx][0].filename
 		}
 
-		binarypkgs := make(map[string]string, sum)
+		type shippedBy struct {
+			best int    // index into archs of the preferred architecture
+			mask uint64 // all architectures, see masks
+		}
+		binarypkgs := make(map[string]shippedBy, sum)
 		for idx := range archs {
 			if !advance[idx] {
 				continue
 			}
 
 			for _, e := range contents[idx] {
-				// first arch (amd64) wins
-				if _, ok := binarypkgs[e.binarypkg]; !ok {
-					binarypkgs[e.binarypkg] = archs[idx]
+				s, ok := binarypkgs[e.binarypkg]
+				if !ok || ranks[idx] < ranks[s.best] {
+					s.best = idx
 				}
+				if detect {
+					s.mask |= 1 << uint(idx)
+				}
+				binarypkgs[e.binarypkg] = s
 			}
 		}
 
-		for pkg, arch := range binarypkgs {
+		for pkg, s := range binarypkgs {
 			entries = append(entries, &contentEntry{
 				binarypkg: pkg,
-				arch:      arch,
+				arch:      archs[s.best],
 				filename:  contents[lowest][0].filename,
 				suite:     suite,
 			})
+			masks = append(masks, s.mask)
 		}
 	}
 
+	if detect {
+		markArchSpecific(entries, masks, archs)
+	}
 	return entries, nil
 }
 
-func getAllContents(ar *archive.Downloader, suite string, release *archive.Release, hashByFilename map[string]*control.SHA256FileHash) ([]*contentEntry, error) {
+func getAllContents(ar *archive.Downloader, suite string, components []string, release *archive.Release, hashByFilename map[string]*control.SHA256FileHash) ([]*contentEntry, error) {
 	// We skip archAll, because there is no Contents-all file. The
 	// contents of Architecture: all packages are included in the
 	// architecture-specific Contents-* files.
 
-	var components = [...]string{"main", "contrib"}
 	parts := make([][]*contentEntry, len(components))
 	var sum int
 	for idx, component := range components {
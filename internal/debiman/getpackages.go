@@ -1,12 +1,14 @@
-package main
+package debiman
 
 import (
 	"bufio"
 	"bytes"
 	"encoding/hex"
+	"flag"
 	"fmt"
 	"io"
 	"log"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
@@ -20,6 +22,43 @@ import (
 	"pault.ag/go/debian/version"
 )
 
+var indexCompression = flag.String("index_compression",
+	"smallest",
+	"Which compressed variant of the Packages and Contents files listed in the Release file to download: “smallest” (least bandwidth, usually xz), “gz” (fastest to decompress) or “xz”. If the preferred variant is not available, the smallest available one is used")
+
+// indexCompressions lists the variants of index files (e.g. Packages)
+// which the archive downloader decompresses transparently, in order of
+// preference for equally large files.
+var indexCompressions = []string{".xz", ".gz", ""}
+
+// pickIndexVariant returns the path (within the Release file) and hash
+// of the variant of the index file base (e.g. “main/binary-amd64/Packages”)
+// to download, as configured by preference (see -index_compression).
+func pickIndexVariant(hashByFilename map[string]*control.SHA256FileHash, base, preference string) (string, *control.SHA256FileHash, error) {
+	if preference != "smallest" {
+		if fh, ok := hashByFilename[base+"."+preference]; ok {
+			return base + "." + preference, fh, nil
+		}
+	}
+	var (
+		bestPath string
+		best     *control.SHA256FileHash
+	)
+	for _, ext := range indexCompressions {
+		fh, ok := hashByFilename[base+ext]
+		if !ok {
+			continue
+		}
+		if best == nil || fh.Size < best.Size {
+			bestPath, best = base+ext, fh
+		}
+	}
+	if best == nil {
+		return "", nil, fmt.Errorf("ERROR: none of the expected paths %q (with suffixes %q) found in Release file", base, indexCompressions)
+	}
+	return bestPath, best, nil
+}
+
 type pkgEntry struct {
 	source    string
 	suite     string
@@ -30,6 +69,16 @@ type pkgEntry struct {
 	sha256    []byte
 	bytes     int64
 	replaces  []string
+
+	// description is the short description of the package.
+	description string
+
+	// ar is the archive from which the package is downloaded.
+	ar *archive.Downloader
+
+	// client is used to download the package (see
+	// archiveSource.client).
+	client *http.Client
 }
 
 // TODO(later): containsMans could be a map[string]bool, if only all
@@ -55,7 +104,7 @@ func buildContainsMains(content []*contentEntry, links map[string][]link) map[st
 		idx := strings.Index(key, "/")
 		binarypkg := key[idx+1:]
 		if containsMans[binarypkg] == nil {
-			containsMans[binarypkg] = map[string]bool{mostPopularArchitecture: true}
+			containsMans[binarypkg] = map[string]bool{*primaryArchitecture: true}
 		}
 	}
 	log.Printf("%d content entries, %d packages\n", len(content), len(containsMans))
@@ -64,13 +113,14 @@ func buildContainsMains(content []*contentEntry, links map[string][]link) map[st
 
 var emptyVersion version.Version
 var (
-	prefixPackage  = []byte("Package")
-	prefixSource   = []byte("Source")
-	prefixVersion  = []byte("Version")
-	prefixFilename = []byte("Filename")
-	prefixSize     = []byte("Size")
-	prefixSHA256   = []byte("SHA256")
-	prefixReplaces = []byte("Replaces")
+	prefixPackage     = []byte("Package")
+	prefixSource      = []byte("Source")
+	prefixVersion     = []byte("Version")
+	prefixFilename    = []byte("Filename")
+	prefixSize        = []byte("Size")
+	prefixSHA256      = []byte("SHA256")
+	prefixReplaces    = []byte("Replaces")
+	prefixDescription = []byte("Description")
 )
 
 func parsePackageParagraph(scanner *bufio.Scanner, arch string, containsMans map[string]map[string]bool) (pkgEntry, error) {
@@ -121,6 +171,10 @@ func parsePackageParagraph(scanner *bufio.Scanner, arch string, containsMans map
 				}
 				entry.replaces = append(entry.replaces, pkg)
 			}
+		} else if bytes.Equal(key, prefixDescription) {
+			// Packages files contain only the short description,
+			// which precedes Filename, Size and SHA256.
+			entry.description = string(text[idx+2:])
 		}
 
 		if entry.binarypkg != "" &&
@@ -176,15 +230,9 @@ func getPackages(ar *archive.Downloader, rd *archive.ReleaseDownloader, suite st
 		idx := idx   // copy
 		arch := arch // copy
 		eg.Go(func() error {
-			// Prefer gzip over xz because gzip uncompresses faster.
-			path := component + "/binary-" + arch + "/Packages.gz"
-			fh, ok := hashByFilename[path]
-			if !ok {
-				path = component + "/binary-" + arch + "/Packages.xz"
-				fh, ok = hashByFilename[path]
-				if !ok {
-					return fmt.Errorf("ERROR: expected path %q not found in Release file", path)
-				}
+			path, fh, err := pickIndexVariant(hashByFilename, component+"/binary-"+arch+"/Packages", *indexCompression)
+			if err != nil {
+				return err
 			}
 
 			log.Printf("getting %q (hash %v)", suite+"/"+path, fh.Hash)
@@ -211,6 +259,7 @@ func getPackages(ar *archive.Downloader, rd *archive.ReleaseDownloader, suite st
 		return nil, nil, err
 	}
 
+	ranks := archRanks(archs)
 	byVersion := make(map[string]*pkgEntry)
 	for {
 		for idx, move := range advance {
@@ -269,7 +318,9 @@ func getPackages(ar *archive.Downloader, rd *archive.ReleaseDownloader, suite st
 			continue
 		}
 
+		// prefer -primary_architecture, see archRanks
 		var best *pkgEntry
+		bestRank := -1
 		for idx, p := range pkgs {
 			if exhausted[idx] {
 				continue
@@ -280,24 +331,9 @@ func getPackages(ar *archive.Downloader, rd *archive.ReleaseDownloader, suite st
 			if p.version != newest.version {
 				continue
 			}
-			if p.arch == mostPopularArchitecture {
-				best = &(pkgs[idx])
-				break
-			}
-		}
-		if best == nil {
-			for idx, p := range pkgs {
-				if exhausted[idx] {
-					continue
-				}
-				if less(pkgs[lowest], pkgs[idx]) {
-					continue
-				}
-				if p.version != newest.version {
-					continue
-				}
+			if bestRank == -1 || ranks[idx] < bestRank {
 				best = &(pkgs[idx])
-				break
+				bestRank = ranks[idx]
 			}
 		}
 
@@ -317,14 +353,15 @@ func getPackages(ar *archive.Downloader, rd *archive.ReleaseDownloader, suite st
 			Binarypkg: p.binarypkg,
 			Suite:     p.suite,
 			Version:   p.version,
+
+			Description: p.description,
 		}
 	}
 
 	return result, latestVersion, nil
 }
 
-func getAllPackages(ar *archive.Downloader, rd *archive.ReleaseDownloader, suite string, release *archive.Release, hashByFilename map[string]*control.SHA256FileHash, containsMans map[string]map[string]bool) ([]*pkgEntry, map[string]*manpage.PkgMeta, error) {
-	var components = [...]string{"main", "contrib"}
+func getAllPackages(ar *archive.Downloader, rd *archive.ReleaseDownloader, suite string, components []string, release *archive.Release, hashByFilename map[string]*control.SHA256FileHash, containsMans map[string]map[string]bool) ([]*pkgEntry, map[string]*manpage.PkgMeta, error) {
 	partsp := make([][]*pkgEntry, len(components))
 	partsl := make([]map[string]*manpage.PkgMeta, len(components))
 	latestVersion := make(map[string]*manpage.PkgMeta)
@@ -0,0 +1,44 @@
+package debiman
+
+import (
+	"testing"
+
+	"pault.ag/go/debian/control"
+)
+
+func TestPickIndexVariant(t *testing.T) {
+	fh := func(size int64) *control.SHA256FileHash {
+		return &control.SHA256FileHash{FileHash: control.FileHash{Size: size}}
+	}
+	both := map[string]*control.SHA256FileHash{
+		"main/binary-amd64/Packages":    fh(40000),
+		"main/binary-amd64/Packages.gz": fh(10000),
+		"main/binary-amd64/Packages.xz": fh(8000),
+	}
+	gzOnly := map[string]*control.SHA256FileHash{
+		"main/binary-amd64/Packages.gz": fh(10000),
+	}
+	for _, entry := range []struct {
+		hashes     map[string]*control.SHA256FileHash
+		preference string
+		want       string
+	}{
+		{both, "smallest", "main/binary-amd64/Packages.xz"},
+		{both, "gz", "main/binary-amd64/Packages.gz"},
+		{both, "xz", "main/binary-amd64/Packages.xz"},
+		{gzOnly, "smallest", "main/binary-amd64/Packages.gz"},
+		{gzOnly, "xz", "main/binary-amd64/Packages.gz"},
+	} {
+		got, _, err := pickIndexVariant(entry.hashes, "main/binary-amd64/Packages", entry.preference)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != entry.want {
+			t.Errorf("pickIndexVariant(%v, %q) = %q, want %q", entry.hashes, entry.preference, got, entry.want)
+		}
+	}
+
+	if _, _, err := pickIndexVariant(gzOnly, "contrib/binary-amd64/Packages", "smallest"); err == nil {
+		t.Errorf("pickIndexVariant unexpectedly succeeded for a missing file")
+	}
+}
@@ -0,0 +1,565 @@
+package debiman
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/Debian/debiman/internal/logging"
+	"github.com/Debian/debiman/internal/manpage"
+	"github.com/Debian/debiman/internal/redirect"
+	"github.com/Debian/debiman/internal/releases"
+
+	"pault.ag/go/archive"
+	"pault.ag/go/debian/control"
+)
+
+// mostPopularArchitecture is the default -primary_architecture, which
+// is used as preferred architecture when we need to pick an arbitrary
+// architecture. The rationale is that downloading the package for the
+// most popular architecture has the least bad influence on the mirror
+// server’s caches.
+const mostPopularArchitecture = "amd64"
+
+type stats struct {
+	PackagesExtracted uint64
+	PackagesDeleted   uint64
+	ManpagesRendered  uint64
+	ManpageBytes      uint64
+	HtmlBytes         uint64
+	IndexBytes        uint64
+	InlinedCSSBytes   uint64
+	WriteQueueMax     uint64
+
+	ManpagesDeduped           uint64
+	ManpagesDedupedNormalized uint64
+
+	ManpageCacheHits   uint64
+	ManpageCacheMisses uint64
+
+	// DownloadsResumed counts .deb downloads which continued a
+	// partial download, see -partial_dir.
+	DownloadsResumed uint64
+
+	RenderCacheHits   uint64
+	RenderCacheMisses uint64
+
+	// IndexEntriesOrphaned counts index entries whose rendered
+	// manpage does not exist.
+	IndexEntriesOrphaned uint64
+
+	// Aliases counts manpages which are recorded as aliases of
+	// another manpage, AliasesBroken those whose target is missing
+	// (see -so_aliases).
+	Aliases       uint64
+	AliasesBroken uint64
+
+	// CaseCollisions counts manpages which are not served because
+	// their file name differs only in case from another manpage’s.
+	CaseCollisions uint64
+
+	// ManpagesTooLarge counts manpages which were not written because
+	// their rendered size exceeds -max_output_bytes.
+	ManpagesTooLarge uint64
+
+	// ManpagesEmpty counts rendered manpages which were flagged by
+	// -min_text_length.
+	ManpagesEmpty uint64
+
+	// ManpagesSizeFiltered counts manpages which were not extracted
+	// because of -min_manpage_bytes or -max_manpage_bytes.
+	ManpagesSizeFiltered uint64
+
+	// ContentsDivergent counts extracted packages whose manpages
+	// diverge from the Contents index, ContentsDiscarded those which
+	// were discarded because of it (see -verify_contents).
+	ContentsDivergent uint64
+	ContentsDiscarded uint64
+
+	// FilesPublished and FilesUnpublished count the files uploaded to
+	// and deleted from -publish_to.
+	FilesPublished   uint64
+	FilesUnpublished uint64
+
+	// PoolSlots is -concurrency (0 if unlimited), PoolBusyMax the
+	// largest number of slots in use at once, PoolBusySeconds the used
+	// slots integrated over the runtime and PoolWaitSeconds the time
+	// spent waiting for slots by phase, see workerPool.
+	PoolSlots       uint64
+	PoolBusyMax     uint64
+	PoolBusySeconds uint64
+	PoolWaitSeconds map[string]uint64
+
+	// MandocVersion is the version of the mandoc binary which was
+	// used for rendering, e.g. “1.14.3”.
+	MandocVersion string
+}
+
+// noteWriteQueueDepth records depth if it is the largest write queue
+// depth observed so far.
+func (s *stats) noteWriteQueueDepth(depth uint64) {
+	for {
+		max := atomic.LoadUint64(&s.WriteQueueMax)
+		if depth <= max || atomic.CompareAndSwapUint64(&s.WriteQueueMax, max, depth) {
+			return
+		}
+	}
+}
+
+type link struct {
+	from string
+	to   string
+}
+
+type globalView struct {
+	// pkgs contains all binary packages we know of.
+	pkgs []*pkgEntry
+
+	// suites contains the Debian suites that we know of. Can either be a codename or a suite,
+	// depending on the values of -sync_codenames and -sync_suites.
+	// e.g. “stretch” (codename) or “stable” (suite)
+	suites map[string]bool
+
+	// skippedSuites contains the suites (as in suites) whose Release
+	// is unchanged since the previous run, see -only_changed_suites.
+	// Their manpages are in xref, but their packages not in pkgs.
+	skippedSuites map[string]bool
+
+	// suiteStates contains the state of the processed suites which is
+	// recorded for the next run if -only_changed_suites is set.
+	suiteStates map[string]*suiteState
+
+	// idxSuites maps codename, suite and command-line argument to suite (as in
+	// suites).
+	// e.g. map[oldoldstable:wheezy wheezy:wheezy]
+	idxSuites map[string]string
+
+	// contentByPath maps from paths underneath /usr/share/man to a contentEntry.
+	contentByPath map[string][]*contentEntry
+
+	// contentsManpages is set by parallelDownload if -verify_contents
+	// is enabled, see expectedManpages.
+	contentsManpages map[string]map[string]bool
+
+	// xref maps from manpage.Meta.Name (e.g. “w3m” or “systemd.service”) to
+	// the corresponding manpage.Meta.
+	xref map[string][]*manpage.Meta
+
+	// alternatives maps from Debian binary package to a slice of
+	// links (from→to pairs).
+	alternatives map[string][]link
+
+	// dedup is nil unless -dedup is specified.
+	dedup *deduper
+
+	// manpageCache is nil unless -manpage_cache is specified.
+	manpageCache *manpageCache
+
+	// renderCache is set by logic once the mandoc version is known.
+	renderCache *renderCache
+
+	// latestSuite is the suite to which the alias “latest” refers, or
+	// empty unless -latest_suite is specified.
+	latestSuite string
+
+	// whatis is set by renderAll if -whatis is set.
+	whatis *whatisIndex
+
+	// descriptions is set by renderAll, see descriptionsPath.
+	descriptions *whatisIndex
+
+	// provenance describes the archives from which each suite was
+	// obtained, see writeBuildInfo.
+	provenance []buildInfoSuite
+
+	// pool limits the work in flight across phases (see
+	// -concurrency). nil if unlimited.
+	pool *workerPool
+
+	// aliases maps the serving paths of aliases to the serving path of
+	// the manpage they resolve to (empty if broken), see
+	// resolveAliases. Set after extracting the manpages.
+	aliases map[string]string
+
+	// rebuild contains the binary packages (as in the URL) of
+	// -rebuild_package, to which pkgs is restricted. nil in regular
+	// runs.
+	rebuild map[string]bool
+
+	// rebuildIndex is the index whose entries of other packages are
+	// kept when rebuilding, see restrictToRebuild.
+	rebuildIndex redirect.Index
+
+	// caseCollisions contains the manpageIDs of manpages which must
+	// not be extracted, see resolveCaseCollisions.
+	caseCollisions map[string]bool
+
+	// log is used for debug output. A nil log discards all messages.
+	log *logging.Logger
+
+	stats *stats
+	start time.Time
+}
+
+type distributionIdentifier int
+
+const (
+	fromCodename = iota
+	fromSuite
+)
+
+type distribution struct {
+	name       string
+	identifier distributionIdentifier
+}
+
+// distributions returns a list of all distributions (either codenames
+// [e.g. wheezy, jessie] or suites [e.g. testing, unstable]) from the
+// -sync_codenames and -sync_suites flags.
+func distributions(codenames []string, suites []string) []distribution {
+	distributions := make([]distribution, 0, len(codenames)+len(suites))
+	for _, e := range codenames {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		distributions = append(distributions, distribution{
+			name:       e,
+			identifier: fromCodename})
+	}
+	for _, e := range suites {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		distributions = append(distributions, distribution{
+			name:       e,
+			identifier: fromSuite})
+	}
+	return distributions
+}
+
+func parseAlternativesFile(fn, prefix string) (map[string][]link, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	res := make(map[string][]link)
+	dec := json.NewDecoder(r)
+	// read open bracket
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	for dec.More() {
+		var m struct {
+			Binpackage string
+			From       string
+			To         string
+		}
+		if err := dec.Decode(&m); err != nil {
+			return nil, err
+		}
+		log.Printf("adding from %q to %q to pkg %q", m.From, m.To, m.Binpackage)
+		key := prefix + "/" + m.Binpackage
+		res[key] = append(res[key], link{
+			from: m.From,
+			to:   m.To,
+		})
+	}
+	return res, nil
+}
+
+func parseAlternativesDir(dir string) (map[string][]link, error) {
+	if dir == "" {
+		return map[string][]link{}, nil
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]map[string][]link, len(infos))
+	var eg errgroup.Group
+	for idx, fi := range infos {
+		idx, fi := idx, fi // copy
+		eg.Go(func() error {
+			suite := strings.TrimSuffix(fi.Name(), ".json.gz")
+			res, err := parseAlternativesFile(filepath.Join(dir, fi.Name()), suite)
+			results[idx] = res
+			return err
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return nil, err
+	}
+	// Merge all subresults into one map. This is non-destructive
+	// because the keys are prefixed by the Debian suite, which is
+	// derived from the filename and hence unique.
+	merged := make(map[string][]link)
+	for idx := range infos {
+		for key, val := range results[idx] {
+			merged[key] = val
+		}
+	}
+	return merged, nil
+}
+
+func markPresent(latestVersion map[string]*manpage.PkgMeta, xref map[string][]*manpage.Meta, filename string, key string) error {
+	if _, ok := latestVersion[key]; !ok {
+		return fmt.Errorf("Could not determine latest version")
+	}
+	var (
+		m   *manpage.Meta
+		err error
+	)
+	if strings.HasPrefix(filename, "usr/share/info/") {
+		m, err = manpage.FromInfoPath(strings.TrimPrefix(filename, "usr/share/info/"), latestVersion[key])
+	} else {
+		m, err = manpage.FromManPath(strings.TrimPrefix(filename, "usr/share/man/"), latestVersion[key])
+	}
+	if err != nil {
+		return fmt.Errorf("Trying to interpret path %q: %v", filename, err)
+	}
+	if m.SanitizedFrom != "" {
+		log.Printf("WARNING: package %q: serving manpage %q as %q (see -manpage_names)", key, m.SanitizedFrom, m.Name)
+	}
+	// NOTE(stapelberg): this additional verification step
+	// is necessary because manpages such as the French
+	// manpage for qelectrotech(1) are present in multiple
+	// encodings. manpageFromManPath ignores encodings, so
+	// if we didn’t filter, we would end up with what
+	// looks like duplicates.
+	present := false
+	for _, x := range xref[m.Name] {
+		if x.ServingPath() == m.ServingPath() {
+			present = true
+			break
+		}
+	}
+	if !present {
+		xref[m.Name] = append(xref[m.Name], m)
+	}
+	return nil
+}
+
+// buildGlobalView discovers the packages of the distributions dists
+// which are selected by sel (nil selects all).
+func buildGlobalView(srcs []*archiveSource, dists []distribution, sel *releases.Selection, alternativesDir string, start time.Time) (globalView, error) {
+	var stats stats
+	res := globalView{
+		suites:        make(map[string]bool, len(dists)),
+		idxSuites:     make(map[string]string, len(dists)),
+		contentByPath: make(map[string][]*contentEntry),
+		xref:          make(map[string][]*manpage.Meta),
+		skippedSuites: make(map[string]bool),
+		suiteStates:   make(map[string]*suiteState),
+		stats:         &stats,
+		start:         start,
+	}
+
+	var err error
+	res.alternatives, err = parseAlternativesDir(alternativesDir)
+	if err != nil {
+		return res, err
+	}
+
+	if *manpageCacheDir != "" {
+		res.manpageCache = &manpageCache{dir: *manpageCacheDir}
+	}
+
+	if *dedupManpages {
+		res.dedup, err = newDeduper(*dedupNormalize, &stats)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	type fetchedRelease struct {
+		src            *archiveSource
+		release        *archive.Release
+		rd             *archive.ReleaseDownloader
+		hashByFilename map[string]*control.SHA256FileHash
+	}
+	type fetchedDist struct {
+		dist    distribution
+		suite   string
+		fetched []fetchedRelease
+	}
+	// Fetch the Release files of all distributions first: the suite
+	// names they contain are required to apply -suites.
+	fetchedDists := make([]fetchedDist, 0, len(dists))
+	candidates := make(map[string][]string, len(dists))
+	for _, dist := range dists {
+		var fetched []fetchedRelease
+		for _, src := range srcs {
+			if !src.serves(dist.name) {
+				continue
+			}
+			release, rd, err := src.release(dist.name)
+			if err != nil {
+				return res, err
+			}
+			hashByFilename := make(map[string]*control.SHA256FileHash, len(release.SHA256))
+			for idx, fh := range release.SHA256 {
+				// fh.Filename contains e.g. “non-free/source/Sources”
+				hashByFilename[fh.Filename] = &(release.SHA256[idx])
+			}
+			fetched = append(fetched, fetchedRelease{
+				src:            src,
+				release:        release,
+				rd:             rd,
+				hashByFilename: hashByFilename,
+			})
+		}
+		if len(fetched) == 0 {
+			return res, fmt.Errorf("no source configured for distribution %q", dist.name)
+		}
+
+		// The source with the highest precedence defines the suite name.
+		release := fetched[0].release
+		var suite string
+		if dist.identifier == fromCodename {
+			suite = release.Codename // e.g. “stretch”
+		} else {
+			suite = release.Suite // e.g. “stable”
+		}
+		fetchedDists = append(fetchedDists, fetchedDist{
+			dist:    dist,
+			suite:   suite,
+			fetched: fetched,
+		})
+		candidates[suite] = append(candidates[suite], release.Suite, release.Codename, dist.name)
+	}
+
+	// Unselected suites are deleted from -serving_dir, so a typo
+	// must not select (and therefore delete) nothing.
+	if err := sel.Check(candidates); err != nil {
+		return res, fmt.Errorf("-suites: %v", err)
+	}
+	selected := sel.Select(candidates)
+	for _, fd := range fetchedDists {
+		dist, suite, fetched := fd.dist, fd.suite, fd.fetched
+		if !selected[suite] {
+			log.Printf("Skipping suite %q (not selected by -suites=%v)", suite, sel)
+			continue
+		}
+		release := fetched[0].release
+
+		res.suites[suite] = true
+		res.idxSuites[release.Suite] = suite
+		res.idxSuites[release.Codename] = suite
+		res.idxSuites[dist.name] = suite
+
+		prov := buildInfoSuite{Suite: suite, Distribution: dist.name}
+		for _, f := range fetched {
+			prov.Sources = append(prov.Sources, newBuildInfoRelease(f.src, f.release))
+		}
+		res.provenance = append(res.provenance, prov)
+
+		var (
+			hash string
+			prev *suiteState
+		)
+		if *onlyChangedSuites {
+			if hash, err = releaseHash(prov); err != nil {
+				return res, err
+			}
+			if !*forceRerender && len(rebuildPackages) == 0 {
+				if prev, err = readSuiteState(*servingDir, suite); err != nil {
+					return res, fmt.Errorf("reading state of suite %q: %v", suite, err)
+				}
+			}
+		}
+
+		var content []*contentEntry
+		var latestVersion map[string]*manpage.PkgMeta
+		if prev != nil && prev.ReleaseHash == hash {
+			log.Printf("Skipping suite %q: Release unchanged since the previous run (see -only_changed_suites)", suite)
+			res.skippedSuites[suite] = true
+			if latestVersion, content, err = prev.restore(suite); err != nil {
+				return res, fmt.Errorf("restoring state of suite %q: %v", suite, err)
+			}
+		} else {
+			if *onlyChangedSuites {
+				log.Printf("Processing suite %q: Release changed since the previous run", suite)
+			}
+			for _, f := range fetched {
+				part, err := getAllContents(f.src.ar, suite, f.src.components, f.release, f.hashByFilename)
+				if err != nil {
+					return res, err
+				}
+				content = append(content, part...)
+			}
+
+			// Collect package download work units
+			containsMans := buildContainsMains(content, res.alternatives)
+			partsp := make([][]*pkgEntry, len(fetched))
+			partsl := make([]map[string]*manpage.PkgMeta, len(fetched))
+			for idx, f := range fetched {
+				var err error
+				partsp[idx], partsl[idx], err = getAllPackages(f.src.ar, f.rd, suite, f.src.components, f.release, f.hashByFilename, containsMans)
+				if err != nil {
+					return res, err
+				}
+				for _, p := range partsp[idx] {
+					p.ar = f.src.ar
+					p.client = f.src.httpClient()
+				}
+			}
+			var pkgs []*pkgEntry
+			pkgs, latestVersion = mergePackages(partsp, partsl)
+			content = restrictArchSpecific(content, pkgs, latestVersion)
+
+			log.Printf("Adding %d packages from suite %q (%d sources)", len(pkgs), suite, len(fetched))
+			res.pkgs = append(res.pkgs, pkgs...)
+
+			for _, c := range content {
+				res.contentByPath[c.filename] = append(res.contentByPath[c.filename], c)
+			}
+
+			if *onlyChangedSuites {
+				res.suiteStates[suite] = newSuiteState(hash, latestVersion, content)
+			}
+		}
+
+		knownIssues := make(map[string][]error)
+
+		// Build a global view of all the manpages (required for cross-referencing).
+		// TODO(issue): edge case: packages which got renamed between releases
+		for _, c := range content {
+			key := c.suite + "/" + c.binarypkg
+			if err := markPresent(latestVersion, res.xref, c.filename, key); err != nil {
+				knownIssues[key] = append(knownIssues[key], err)
+			}
+		}
+
+		for key, links := range res.alternatives {
+			for _, link := range links {
+				log.Printf("key=%q, link=%v, latest = %v", key, link, latestVersion[key])
+				if err := markPresent(latestVersion, res.xref, strings.TrimPrefix(link.from, "/"), key); err != nil {
+					knownIssues[key] = append(knownIssues[key], err)
+				}
+			}
+		}
+
+		for key, errors := range knownIssues {
+			// TODO: write these to a known-issues file, parse bug numbers from an auxilliary file
+			log.Printf("package %q has errors: %v", key, errors)
+		}
+	}
+	return res, nil
+}
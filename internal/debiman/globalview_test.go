@@ -0,0 +1,37 @@
+package debiman
+
+import (
+	"testing"
+
+	"github.com/Debian/debiman/internal/manpage"
+)
+
+func TestMarkPresentCanonicalLanguages(t *testing.T) {
+	defer func(prev bool) { manpage.CanonicalLanguages = prev }(manpage.CanonicalLanguages)
+	manpage.CanonicalLanguages = true
+
+	latestVersion := map[string]*manpage.PkgMeta{
+		"testing/deja-dup": {Binarypkg: "deja-dup", Suite: "testing"},
+	}
+	xref := make(map[string][]*manpage.Meta)
+	for _, fn := range []string{
+		"usr/share/man/pt_BR/man1/deja-dup.1.gz",
+		"usr/share/man/pt_br/man1/deja-dup.1.gz",
+		"usr/share/man/pt-BR/man1/deja-dup.1.gz",
+		"usr/share/man/man1/deja-dup.1.gz",
+	} {
+		if err := markPresent(latestVersion, xref, fn, "testing/deja-dup"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var langs []string
+	for _, m := range xref["deja-dup"] {
+		langs = append(langs, m.Language)
+	}
+	if got, want := len(langs), 2; got != want {
+		t.Fatalf("unexpected number of manpages: got %d (%v), want %d", got, langs, want)
+	}
+	if got, want := langs[0], "pt_BR"; got != want {
+		t.Errorf("unexpected language: got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,264 @@
+package debiman
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Debian/debiman/internal/manpage"
+	"github.com/Debian/debiman/internal/write"
+	"golang.org/x/net/context"
+	"golang.org/x/sync/errgroup"
+)
+
+var groupLanguages = flag.Bool("group_languages",
+	false,
+	"Show all languages of a manpage on one language-independent page (e.g. /jessie/cron/crontab.5.html), with a tab per language: the fragment (e.g. #lang-de, or ?lang=de with JavaScript) selects the language, English (or the first language) is shown by default. Only languages shipped by the same binary package are grouped, and manpages in only one language keep their per-language page. All links, the auxserver index and debiman-auxserver’s redirects refer to the grouped pages. The per-language pages are still written (the grouped pages are assembled from them), so existing links to them keep working")
+
+// markGrouped sets manpage.Meta.Grouped for the manpages of metas
+// (which all have the same name) whose binary package ships them in
+// more than one language, if -group_languages is set. Manpages for
+// which skip returns true (e.g. aliases) are not rendered, so they do
+// not count.
+func markGrouped(metas []*manpage.Meta, skip func(*manpage.Meta) bool) {
+	if !*groupLanguages {
+		return
+	}
+	langs := make(map[string]map[string]bool)
+	for _, m := range metas {
+		if skip(m) {
+			continue
+		}
+		p := m.PermaLink()
+		if langs[p] == nil {
+			langs[p] = make(map[string]bool)
+		}
+		langs[p][m.Language] = true
+	}
+	for _, m := range metas {
+		m.Grouped = !skip(m) && len(langs[m.PermaLink()]) > 1
+	}
+}
+
+// languagePanel is the content of one language on the page of a
+// Grouped manpage.
+type languagePanel struct {
+	Meta    *manpage.Meta
+	Content template.HTML
+	Default bool
+}
+
+// prefixIDs prefixes all ids in content, and the links to them, with
+// prefix, so that the headings of multiple languages can be shown on
+// one page.
+func prefixIDs(content, prefix string) string {
+	content = strings.Replace(content, ` id="`, ` id="`+prefix, -1)
+	return strings.Replace(content, ` href="#`, ` href="#`+prefix, -1)
+}
+
+// defaultLanguage returns the index of the manpage of group (sorted by
+// language) which a grouped page shows by default: English, if
+// present, and the first language otherwise.
+func defaultLanguage(group []*manpage.Meta) int {
+	for idx, m := range group {
+		if m.Language == "en" {
+			return idx
+		}
+	}
+	return 0
+}
+
+// groupedDest returns the path of the page of the Grouped manpage m.
+func groupedDest(m *manpage.Meta) string {
+	return filepath.Join(*servingDir, m.PermaLink()+".html.gz")
+}
+
+// renderGroupedPage assembles the page of group (the Grouped manpages
+// of one binary package, name and section, sorted by language) from
+// their rendered per-language pages.
+func renderGroupedPage(gv globalView, group []*manpage.Meta) error {
+	def := group[defaultLanguage(group)]
+	dest := groupedDest(def)
+
+	// The default language comes last, so that the stylesheet can hide
+	// it when another language is selected.
+	var tabs, panels []languagePanel
+	for _, m := range group {
+		content, _, err := reuse(filepath.Join(*servingDir, m.ServingPath()+".html.gz"))
+		if err != nil {
+			if m == def {
+				// rendermanpageprep needs to reuse it, and the index
+				// links all languages to dest.
+				return fmt.Errorf("reading the page of the default language %q: %v", m.ServingPath(), err)
+			}
+			log.Printf("WARNING: not grouping %q on %q: %v", m.ServingPath(), dest, err)
+			continue
+		}
+		if m != def {
+			content = prefixIDs(content, m.LanguageFragment()+"-")
+		}
+		p := languagePanel{
+			Meta:    m,
+			Content: template.HTML(content),
+			Default: m == def,
+		}
+		tabs = append(tabs, p)
+		if !p.Default {
+			panels = append(panels, p)
+		}
+	}
+	for _, p := range tabs {
+		if p.Default {
+			panels = append(panels, p)
+		}
+	}
+
+	src := filepath.Join(*servingDir, def.RawPath())
+	st, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	t, data, err := rendermanpageprep(nil, renderJob{
+		dest:        dest,
+		src:         src,
+		meta:        def,
+		versions:    gv.xref[def.Name],
+		xref:        gv.xref,
+		aliases:     gv.aliases,
+		modTime:     st.ModTime(),
+		reuse:       filepath.Join(*servingDir, def.ServingPath()+".html.gz"),
+		latestSuite: gv.latestSuite,
+	})
+	if err != nil {
+		return err
+	}
+	data.Tabs = tabs
+	data.Panels = panels
+	data.HrefLangs = nil // all languages are on this page
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return err
+	}
+	return write.Atomically(dest, true, func(w io.Writer) error {
+		_, err := w.Write(buf.Bytes())
+		return err
+	})
+}
+
+// upToDate returns whether the page of group is newer than the
+// per-language pages it was assembled from.
+func upToDate(group []*manpage.Meta) bool {
+	st, err := os.Stat(groupedDest(group[0]))
+	if err != nil {
+		return false
+	}
+	for _, m := range group {
+		mst, err := os.Stat(filepath.Join(*servingDir, m.ServingPath()+".html.gz"))
+		if err != nil || mst.ModTime().After(st.ModTime()) {
+			return false
+		}
+	}
+	return true
+}
+
+// renderGroupedPages renders the pages of all Grouped manpages (see
+// -group_languages) whose per-language pages changed.
+func renderGroupedPages(gv globalView) error {
+	groups := make(map[string][]*manpage.Meta)
+	for _, x := range gv.xref {
+		for _, m := range x {
+			if !m.Grouped || gv.skippedSuites[m.Package.Suite] {
+				continue
+			}
+			if gv.rebuild != nil && !gv.rebuild[manpage.URLCase.Path(m.Package.Binarypkg)] {
+				continue
+			}
+			groups[m.PermaLink()] = append(groups[m.PermaLink()], m)
+		}
+	}
+
+	start := time.Now()
+	work := make(chan []*manpage.Meta)
+	eg, ctx := errgroup.WithContext(context.Background())
+	for i := 0; i < *renderConcurrency; i++ {
+		eg.Go(func() error {
+			for group := range work {
+				if err := renderGroupedPage(gv, group); err != nil {
+					return fmt.Errorf("%s: %v", group[0].PermaLink(), err)
+				}
+			}
+			return nil
+		})
+	}
+	var rendered int
+	eg.Go(func() error {
+		defer close(work)
+		for _, group := range groups {
+			sort.Sort(byLanguage(group))
+			if !gv.rerenderAll() && upToDate(group) {
+				continue
+			}
+			rendered++
+			select {
+			case work <- group:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return nil
+	})
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+	log.Printf("Rendered %d pages of grouped languages (of %d) in %v", rendered, len(groups), time.Since(start))
+	return nil
+}
+
+// removeStaleGroupedPages removes the pages of manpages which are no
+// longer Grouped, e.g. because their binary package now ships them in
+// only one language, or because -group_languages was disabled.
+func removeStaleGroupedPages(gv globalView) error {
+	grouped := make(map[string]bool)
+	for _, x := range gv.xref {
+		for _, m := range x {
+			if m.Grouped {
+				grouped[m.PermaLink()] = true
+			}
+		}
+	}
+	var removed int
+	for _, x := range gv.xref {
+		for _, m := range x {
+			if grouped[m.PermaLink()] || gv.skippedSuites[m.Package.Suite] {
+				continue
+			}
+			if gv.rebuild != nil && !gv.rebuild[manpage.URLCase.Path(m.Package.Binarypkg)] {
+				continue
+			}
+			dest := groupedDest(m)
+			if err := os.Remove(dest); err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return err
+			}
+			if err := write.RemoveVariants(dest); err != nil {
+				return err
+			}
+			removed++
+		}
+	}
+	if removed > 0 {
+		log.Printf("Removed %d pages of no longer grouped languages", removed)
+	}
+	return nil
+}
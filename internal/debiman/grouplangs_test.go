@@ -0,0 +1,118 @@
+package debiman
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Debian/debiman/internal/manpage"
+)
+
+func TestMarkGrouped(t *testing.T) {
+	meta := func(pkg, section, lang string) *manpage.Meta {
+		return &manpage.Meta{
+			Name:     "crontab",
+			Package:  &manpage.PkgMeta{Suite: "jessie", Binarypkg: pkg},
+			Section:  section,
+			Language: lang,
+		}
+	}
+	en := meta("cron", "5", "en")
+	de := meta("cron", "5", "de")
+	alias := meta("cron", "5", "fr")
+	single := meta("cron", "1", "en")
+	other := meta("manpages-fr", "5", "fr")
+	metas := []*manpage.Meta{en, de, alias, single, other}
+	skip := func(m *manpage.Meta) bool { return m == alias }
+
+	old := *groupLanguages
+	defer func() { *groupLanguages = old }()
+	*groupLanguages = false
+	markGrouped(metas, skip)
+	if en.Grouped || de.Grouped {
+		t.Errorf("markGrouped grouped manpages without -group_languages")
+	}
+	if got, want := en.PageURL(), "jessie/cron/crontab.5.en.html"; got != want {
+		t.Errorf("PageURL() = %q, want %q", got, want)
+	}
+
+	*groupLanguages = true
+	markGrouped(metas, skip)
+	for _, entry := range []struct {
+		m       *manpage.Meta
+		grouped bool
+		pageURL string
+	}{
+		{en, true, "jessie/cron/crontab.5.html#lang-en"},
+		{de, true, "jessie/cron/crontab.5.html#lang-de"},
+		{alias, false, "jessie/cron/crontab.5.fr.html"},
+		{single, false, "jessie/cron/crontab.1.en.html"},
+		{other, false, "jessie/manpages-fr/crontab.5.fr.html"},
+	} {
+		if got, want := entry.m.Grouped, entry.grouped; got != want {
+			t.Errorf("%s: Grouped = %v, want %v", entry.m.ServingPath(), got, want)
+		}
+		if got, want := entry.m.PageURL(), entry.pageURL; got != want {
+			t.Errorf("%s: PageURL() = %q, want %q", entry.m.ServingPath(), got, want)
+		}
+	}
+}
+
+func TestPrefixIDs(t *testing.T) {
+	const content = `<h1 id="NAME"><a class="anchor" href="#NAME">NAME</a></h1><p>see <a href="crontab.1.de.html">crontab(1)</a></p>`
+	const want = `<h1 id="lang-de-NAME"><a class="anchor" href="#lang-de-NAME">NAME</a></h1><p>see <a href="crontab.1.de.html">crontab(1)</a></p>`
+	if got := prefixIDs(content, "lang-de-"); got != want {
+		t.Errorf("prefixIDs:\ngot  %s\nwant %s", got, want)
+	}
+}
+
+func TestRemoveStaleGroupedPages(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "debiman-grouplangs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+	old := *servingDir
+	defer func() { *servingDir = old }()
+	*servingDir = tmpdir
+
+	meta := func(section, lang string, grouped bool) *manpage.Meta {
+		return &manpage.Meta{
+			Name:     "crontab",
+			Package:  &manpage.PkgMeta{Suite: "jessie", Binarypkg: "cron"},
+			Section:  section,
+			Language: lang,
+			Grouped:  grouped,
+		}
+	}
+	en := meta("5", "en", true)
+	de := meta("5", "de", true)
+	alias := meta("5", "fr", false)
+	// crontab(1) used to be shipped in English and German, too.
+	single := meta("1", "en", false)
+	for _, m := range []*manpage.Meta{en, single} {
+		dest := groupedDest(m)
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(dest, []byte("grouped"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gv := globalView{
+		xref: map[string][]*manpage.Meta{
+			"crontab": {en, de, alias, single},
+		},
+	}
+	if err := removeStaleGroupedPages(gv); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(groupedDest(en)); err != nil {
+		t.Errorf("page of grouped manpage %s removed: %v", en.PermaLink(), err)
+	}
+	if _, err := os.Stat(groupedDest(single)); !os.IsNotExist(err) {
+		t.Errorf("page of no longer grouped manpage %s not removed (stat: %v)", single.PermaLink(), err)
+	}
+}
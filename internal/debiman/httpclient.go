@@ -0,0 +1,59 @@
+package debiman
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+)
+
+var (
+	caCert = flag.String("ca_cert",
+		"",
+		"If non-empty, path to a PEM file containing additional CA certificates to trust (in addition to the system certificate pool) for HTTPS connections to the mirror, e.g. for an internal CA")
+
+	httpTimeout = flag.Duration("http_timeout",
+		1*time.Minute,
+		"Timeout for connecting to the mirror (or proxy), for the TLS handshake and for receiving response headers. Does not limit the duration of a download")
+)
+
+// newHTTPClient returns the http.Client used to talk to mirrors. Like
+// http.DefaultTransport, it uses the proxy configured in the
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables. Responses
+// are decoded by contentDecoder.
+func newHTTPClient(caCertPath string, timeout time.Duration) (*http.Client, error) {
+	tlsConfig := &tls.Config{}
+	if caCertPath != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			return nil, fmt.Errorf("loading system certificate pool: %v", err)
+		}
+		pem, err := ioutil.ReadFile(caCertPath)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: no PEM-encoded certificates found", caCertPath)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return &http.Client{
+		Transport: &contentDecoder{next: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   timeout,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			TLSClientConfig:       tlsConfig,
+			TLSHandshakeTimeout:   timeout,
+			ResponseHeaderTimeout: timeout,
+			ExpectContinueTimeout: 1 * time.Second,
+			IdleConnTimeout:       90 * time.Second,
+			MaxIdleConns:          100,
+		}},
+	}, nil
+}
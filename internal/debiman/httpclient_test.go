@@ -0,0 +1,148 @@
+package debiman
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"pault.ag/go/archive"
+)
+
+func TestHTTPClientCACert(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	f, err := ioutil.TempFile("", "debiman-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if err := pem.Encode(f, &pem.Block{Type: "CERTIFICATE", Bytes: ts.TLS.Certificates[0].Certificate[0]}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := newHTTPClient("", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Get(ts.URL); err == nil {
+		t.Errorf("request to server with untrusted certificate unexpectedly succeeded")
+	}
+
+	client, err = newHTTPClient(f.Name(), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if _, err := newHTTPClient(os.DevNull, time.Minute); err == nil {
+		t.Errorf("newHTTPClient(%q) unexpectedly succeeded", os.DevNull)
+	}
+}
+
+func TestHTTPClientContentEncoding(t *testing.T) {
+	const packages = "Package: i3-wm\nVersion: 4.13-1\n"
+	var packagesGz bytes.Buffer
+	gzw := gzip.NewWriter(&packagesGz)
+	if _, err := gzw.Write([]byte(packages)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Like a misconfigured proxy, encode regardless of the
+		// Accept-Encoding request header.
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(packagesGz.Bytes())
+	}))
+	defer ts.Close()
+
+	client, err := newHTTPClient("", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range []struct {
+		path           string
+		acceptEncoding string
+		want           []byte
+	}{
+		// Transfer-level encoding of a by-hash file, which net/http
+		// would decode on its own.
+		{"/dists/sid/main/binary-amd64/by-hash/SHA256/1234", "", []byte(packages)},
+		// Transfer-level encoding which net/http would not decode.
+		{"/dists/sid/main/binary-amd64/Packages", "gzip", []byte(packages)},
+		{"/dists/sid/main/binary-amd64/Packages", "identity", []byte(packages)},
+		// The encoding describes the file itself, which the archive
+		// package decompresses.
+		{"/dists/sid/main/binary-amd64/Packages.gz", "", packagesGz.Bytes()},
+	} {
+		req, err := http.NewRequest("GET", ts.URL+entry.path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry.acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", entry.acceptEncoding)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", entry.path, err)
+		}
+		if !bytes.Equal(got, entry.want) {
+			t.Errorf("%s (Accept-Encoding %q): got %q, want %q", entry.path, entry.acceptEncoding, got, entry.want)
+		}
+	}
+}
+
+func TestDownloadDebClient(t *testing.T) {
+	content := []byte("!<arch>\n")
+	sum := sha256.Sum256(content)
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "debiman-client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	oldPartialDir := *partialDir
+	defer func() { *partialDir = oldPartialDir }()
+	*partialDir = dir
+
+	// Only the client of the source trusts the certificate of the
+	// server, so the download fails unless it uses that client.
+	p := pkgEntry{
+		binarypkg: "i3-wm",
+		filename:  "pool/main/i/i3-wm/i3-wm.deb",
+		sha256:    sum[:],
+		bytes:     int64(len(content)),
+		client:    ts.Client(),
+	}
+	deb, err := downloadDeb(&archive.Downloader{Mirror: ts.URL}, p, &stats{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	deb.Close()
+}
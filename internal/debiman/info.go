@@ -0,0 +1,145 @@
+package debiman
+
+import (
+	"bytes"
+	"compress/gzip"
+	"flag"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Debian/debiman/internal/info"
+	"github.com/Debian/debiman/internal/manpage"
+	"github.com/Debian/debiman/internal/write"
+)
+
+var infoPages = flag.Bool("info_pages",
+	false,
+	"Additionally extract the info documents (/usr/share/info) of all packages, render them like manpages and index them in the pseudo-section “info” (e.g. /coreutils.info). Info documents are converted by debiman itself, not by mandoc")
+
+// infoDocuments collects the files of the info documents of a
+// package, keyed by their name underneath usr/share/info. Large
+// documents are split into multiple files (e.g. coreutils.info.gz,
+// coreutils.info-1.gz, coreutils.info-2.gz, …), which can appear in
+// any order in the package.
+type infoDocuments map[string]infoFile
+
+type infoFile struct {
+	content []byte // as found in the package, i.e. possibly compressed
+	modTime time.Time
+}
+
+func (d infoDocuments) add(name string, content []byte, modTime time.Time) {
+	d[strings.TrimPrefix(name, "./usr/share/info/")] = infoFile{content: content, modTime: modTime}
+}
+
+// infoPart returns the number of the part of the document main which
+// name is, e.g. 2 for coreutils.info-2.gz and coreutils.info.gz.
+func infoPart(main, name string) (int, bool) {
+	prefix := strings.TrimSuffix(main, ".gz") + "-"
+	if !strings.HasPrefix(name, prefix) {
+		return 0, false
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".gz"))
+	return n, err == nil && n > 0
+}
+
+// files returns the (decompressed) content of the document main,
+// i.e. of the main file followed by all parts in order, and the
+// newest modification time of these files.
+func (d infoDocuments) files(main string) ([]byte, time.Time, error) {
+	names := []string{main}
+	part := make(map[string]int)
+	for name := range d {
+		if n, ok := infoPart(main, name); ok {
+			names = append(names, name)
+			part[name] = n
+		}
+	}
+	sort.Slice(names[1:], func(i, j int) bool { return part[names[1+i]] < part[names[1+j]] })
+
+	var (
+		buf     bytes.Buffer
+		modTime time.Time
+	)
+	for _, name := range names {
+		f := d[name]
+		if f.modTime.After(modTime) {
+			modTime = f.modTime
+		}
+		r := io.Reader(bytes.NewReader(f.content))
+		if strings.HasSuffix(name, ".gz") {
+			gzr, err := gzip.NewReader(r)
+			if err != nil {
+				return nil, time.Time{}, err
+			}
+			r = gzr
+		}
+		if _, err := io.Copy(&buf, r); err != nil {
+			return nil, time.Time{}, err
+		}
+	}
+	return buf.Bytes(), modTime, nil
+}
+
+// write places each info document of the package p into -serving_dir,
+// next to the manpages of the package.
+func (d infoDocuments) write(logger *log.Logger, p pkgEntry) error {
+	for name := range d {
+		if !manpage.IsInfoDocument(name) {
+			continue
+		}
+		m, err := manpage.FromInfoPath(name, &manpage.PkgMeta{
+			Binarypkg: p.binarypkg,
+			Suite:     p.suite,
+		})
+		if err != nil {
+			logger.Printf("WARNING: info file name %q cannot be parsed: %v", name, err)
+			continue
+		}
+		content, modTime, err := d.files(name)
+		if err != nil {
+			logger.Printf("WARNING: reading info document %q: %v", name, err)
+			continue
+		}
+		destPath := filepath.Join(*servingDir, m.ServingPath()+".gz")
+		if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+			return err
+		}
+		if err := write.Atomically(destPath, true, func(w io.Writer) error {
+			_, err := w.Write(content)
+			return err
+		}); err != nil {
+			return err
+		}
+		if err := os.Chtimes(destPath, modTime, modTime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// convertInfoFile is like convertFile, but for info documents.
+func convertInfoFile(src string, resolve func(ref string) string) (doc string, toc []string, err error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		return "", nil, err
+	}
+	defer r.Close()
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", nil, err
+	}
+	return info.ToHTML(bytes.NewReader(b), resolve)
+}
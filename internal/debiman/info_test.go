@@ -0,0 +1,51 @@
+package debiman
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+	"time"
+)
+
+func gzipped(t *testing.T, s string) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestInfoDocuments(t *testing.T) {
+	older := time.Date(2017, 1, 20, 12, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+	d := make(infoDocuments)
+	// Parts are added in tar order, which need not be their order.
+	d.add("./usr/share/info/coreutils.info-10.gz", gzipped(t, "ten\n"), older)
+	d.add("./usr/share/info/coreutils.info-2.gz", gzipped(t, "two\n"), newer)
+	d.add("./usr/share/info/coreutils.info.gz", gzipped(t, "main\n"), older)
+	d.add("./usr/share/info/coreutils.info-1.gz", gzipped(t, "one\n"), older)
+	d.add("./usr/share/info/coreutils-extra.info", []byte("other document\n"), older)
+
+	content, modTime, err := d.files("coreutils.info.gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(content), "main\none\ntwo\nten\n"; got != want {
+		t.Errorf("unexpected content: got %q, want %q", got, want)
+	}
+	if !modTime.Equal(newer) {
+		t.Errorf("unexpected modification time: got %v, want %v", modTime, newer)
+	}
+
+	content, _, err = d.files("coreutils-extra.info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(content), "other document\n"; got != want {
+		t.Errorf("unexpected content: got %q, want %q", got, want)
+	}
+}
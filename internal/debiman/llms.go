@@ -0,0 +1,131 @@
+package debiman
+
+import (
+	"flag"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/Debian/debiman/internal/bundled"
+	"github.com/Debian/debiman/internal/write"
+)
+
+const defaultLLMsPolicy = "Manpages are copyrighted by their authors and distributed under the license of their Debian source package. Automated consumers (e.g. crawlers collecting training data for language models) may use them under these licenses, but must attribute the authors and link to the page they used."
+
+var (
+	llmsTxt = flag.Bool("llms_txt",
+		false,
+		"If true, write llms.txt (see https://llmstxt.org/) to the root of -serving_dir, describing the suites and URL structure of the site along with -llms_policy. See also -page_metadata")
+
+	llmsPolicy = flag.String("llms_policy",
+		defaultLLMsPolicy,
+		"Policy for automated consumers (e.g. AI crawlers), stated in llms.txt. Only used with -llms_txt")
+)
+
+var llmsTmpl = template.Must(template.New("llms").Funcs(template.FuncMap{
+	"join": strings.Join,
+}).Parse(bundled.Asset("llms.tmpl")))
+
+// llmsSuite summarizes a suite for llms.txt.
+type llmsSuite struct {
+	Name       string
+	Aliases    []string
+	Manpages   int
+	Binarypkgs int
+}
+
+// llmsSuites returns the suites of gv, in the order of the suite
+// switcher.
+func llmsSuites(gv globalView) []llmsSuite {
+	names := make([]string, 0, len(gv.suites))
+	for suite := range gv.suites {
+		names = append(names, suite)
+	}
+	sort.Stable(bySuiteStr(names))
+
+	manpages := make(map[string]int)
+	binarypkgs := make(map[string]map[string]bool)
+	for _, x := range gv.xref {
+		for _, m := range x {
+			suite := m.Package.Suite
+			manpages[suite]++
+			if binarypkgs[suite] == nil {
+				binarypkgs[suite] = make(map[string]bool)
+			}
+			binarypkgs[suite][m.Package.Binarypkg] = true
+		}
+	}
+	aliases := make(map[string][]string)
+	for name, suite := range gv.idxSuites {
+		if name != suite {
+			aliases[suite] = append(aliases[suite], name)
+		}
+	}
+
+	suites := make([]llmsSuite, 0, len(names))
+	for _, name := range names {
+		sort.Strings(aliases[name])
+		suites = append(suites, llmsSuite{
+			Name:       name,
+			Aliases:    aliases[name],
+			Manpages:   manpages[name],
+			Binarypkgs: len(binarypkgs[name]),
+		})
+	}
+	return suites
+}
+
+// llmsExample returns the serving path of a manpage of gv, used to
+// illustrate the URL structure.
+func llmsExample(gv globalView) string {
+	for _, name := range []string{"ls", "man"} {
+		if x := gv.xref[name]; len(x) > 0 {
+			return x[0].ServingPath()
+		}
+	}
+	var first string
+	for _, x := range gv.xref {
+		if len(x) == 0 {
+			continue
+		}
+		if sp := x[0].ServingPath(); first == "" || sp < first {
+			first = sp
+		}
+	}
+	return first
+}
+
+func writeLLMsTxt(w io.Writer, gv globalView) error {
+	return llmsTmpl.Execute(w, struct {
+		DebimanVersion string
+		Policy         string
+		BaseURL        string
+		Example        string
+		Suites         []llmsSuite
+	}{
+		DebimanVersion: debimanVersion,
+		Policy:         *llmsPolicy,
+		BaseURL:        *baseURL,
+		Example:        llmsExample(gv),
+		Suites:         llmsSuites(gv),
+	})
+}
+
+// renderLLMsTxt writes llms.txt (and, for nginx’s gzip_static, a
+// compressed copy) to destDir.
+func renderLLMsTxt(destDir string, gv globalView) error {
+	for _, gz := range []bool{false, true} {
+		fn := filepath.Join(destDir, "llms.txt")
+		if gz {
+			fn += ".gz"
+		}
+		if err := write.Atomically(fn, gz, func(w io.Writer) error {
+			return writeLLMsTxt(w, gv)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package debiman
+
+import (
+	"bytes"
+	"html/template"
+	"strings"
+	"testing"
+
+	"github.com/Debian/debiman/internal/manpage"
+
+	"pault.ag/go/debian/version"
+)
+
+func TestLLMsTxt(t *testing.T) {
+	meta := func(name, binarypkg, suite string) *manpage.Meta {
+		return &manpage.Meta{
+			Name:     name,
+			Section:  "1",
+			Language: "en",
+			Package:  &manpage.PkgMeta{Binarypkg: binarypkg, Suite: suite},
+		}
+	}
+	gv := globalView{
+		suites: map[string]bool{"jessie": true, "stretch": true},
+		idxSuites: map[string]string{
+			"jessie":    "jessie",
+			"oldstable": "jessie",
+			"stretch":   "stretch",
+			"stable":    "stretch",
+			"wheezy":    "wheezy", // not selected
+		},
+		xref: map[string][]*manpage.Meta{
+			"ls":    {meta("ls", "coreutils", "stretch"), meta("ls", "coreutils", "jessie")},
+			"cp":    {meta("cp", "coreutils", "stretch")},
+			"crond": {meta("crond", "cron", "stretch")},
+		},
+	}
+	var buf bytes.Buffer
+	if err := writeLLMsTxt(&buf, gv); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		"# some debiman installation\n",
+		"\n" + defaultLLMsPolicy + "\n",
+		"(e.g. " + *baseURL + "/stretch/coreutils/ls.1.en.html)",
+		"- [jessie](" + *baseURL + "/contents-jessie.html): 1 manpages in 1 binary packages (also reachable as oldstable)\n" +
+			"- [stretch](" + *baseURL + "/contents-stretch.html): 3 manpages in 2 binary packages (also reachable as stable)\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("llms.txt does not contain %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "wheezy") {
+		t.Errorf("llms.txt unexpectedly mentions the unselected suite wheezy:\n%s", got)
+	}
+}
+
+func TestPageMetadata(t *testing.T) {
+	v, err := version.Parse("1:2.0+dfsg-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := manpagePrepData{
+		Meta: &manpage.Meta{
+			Name:     "foo",
+			Section:  "1",
+			Language: "en",
+			Package: &manpage.PkgMeta{
+				Binarypkg: "foo-utils",
+				Sourcepkg: "foo",
+				Suite:     "jessie",
+				Version:   v,
+			},
+		},
+		Content: template.HTML(`<div class="mandoc">foo</div>`),
+	}
+	data.Article.Meta = data.Meta
+	want := `<meta name="dcterms.license" content="https://sources.debian.org/src/foo/1:2.0&#43;dfsg-1/debian/copyright/">`
+
+	defer func(old bool) { *pageMetadata = old }(*pageMetadata)
+	for _, enabled := range []bool{false, true} {
+		*pageMetadata = enabled
+		var buf bytes.Buffer
+		if err := manpageTmpl.Execute(&buf, data); err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Contains(buf.String(), want); got != enabled {
+			t.Errorf("-page_metadata=%v: page contains %q = %v, want %v", enabled, want, got, enabled)
+		}
+	}
+}
@@ -0,0 +1,565 @@
+// Package debiman implements the debiman program (see cmd/debiman) and
+// the rendering of single manpages of cmd/debiman-render.
+package debiman
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "net/http/pprof"
+
+	"github.com/Debian/debiman/internal/blob"
+	"github.com/Debian/debiman/internal/bundled"
+	"github.com/Debian/debiman/internal/commontmpl"
+	"github.com/Debian/debiman/internal/convert"
+	"github.com/Debian/debiman/internal/logging"
+	"github.com/Debian/debiman/internal/manpage"
+	"github.com/Debian/debiman/internal/redirect"
+	"github.com/Debian/debiman/internal/releases"
+	"github.com/Debian/debiman/internal/urlcase"
+	"github.com/Debian/debiman/internal/write"
+)
+
+var (
+	servingDir = flag.String("serving_dir",
+		"/srv/man",
+		"Directory in which to place the manpages which should be served")
+
+	indexPath = flag.String("index",
+		"<serving_dir>/auxserver.idx",
+		"Path to an auxserver index to generate")
+
+	syncCodenames = flag.String("sync_codenames",
+		"",
+		"Debian codenames to synchronize (e.g. wheezy, jessie, …)")
+
+	syncSuites = flag.String("sync_suites",
+		"testing",
+		"Debian suites to synchronize (e.g. testing, unstable)")
+
+	onlyRender = flag.String("only_render_pkgs",
+		"",
+		"If non-empty, a comma-separated whitelist of packages to render (for developing)")
+
+	forceRerender = flag.Bool("force_rerender",
+		false,
+		"Forces all manpages to be re-rendered, even if they are up to date")
+
+	forceReextract = flag.Bool("force_reextract",
+		false,
+		"Forces all manpages to be re-extracted, even if there is no newer package version")
+
+	localMirror = flag.String("local_mirror",
+		"",
+		"If non-empty, a file system path to a Debian mirror, e.g. /srv/mirrors/debian on DSA-maintained machines")
+
+	injectAssets = flag.String("inject_assets",
+		"",
+		"If non-empty, a file system path to a directory containing assets to overwrite")
+
+	icons = flag.String("icons",
+		"",
+		"If non-empty, a file system path to a directory containing replacements for some or all of favicon.ico, favicon.svg and apple-touch-icon.png (e.g. for rebranding)")
+
+	manpageNames = flag.String("manpage_names",
+		manpage.Names.String(),
+		"How to treat manpages whose names contain path separators, whitespace, control characters or other characters which break file names or URLs: “sanitize” (replace each such character with an underscore) or “reject” (skip the manpage). Affected packages are logged")
+
+	canonicalLanguages = flag.Bool("canonical_languages",
+		manpage.CanonicalLanguages,
+		"Spell the languages of manpages canonically (language in lowercase, territory in uppercase, separated by an underscore, e.g. pt_BR), so that manpages which packages ship in differently spelled language directories (e.g. pt_BR, pt_br and pt-BR) are merged into one language, in serving paths and in the index. If false, languages are used as spelled in the package. Pages written under a previous spelling are not deleted")
+
+	alternativesDir = flag.String("alternatives_dir",
+		"",
+		"If non-empty, a directory containing JSON-encoded lists of slave alternative links, named after the suite (e.g. sid.json.gz, testing.json.gz, etc.)")
+
+	showVersion = flag.Bool("version",
+		false,
+		"Show debiman version and exit")
+
+	logLevel = flag.String("log_level",
+		"info",
+		"One of error (only log errors), info (log progress) or debug (additionally log per-package and per-manpage timings)")
+
+	verbose = flag.Bool("v",
+		false,
+		"Shorthand for -log_level=debug")
+)
+
+// use go build -ldflags "-X github.com/Debian/debiman/internal/debiman.debimanVersion=<version>"
+// to set the version
+var debimanVersion = "HEAD"
+
+// runFlags are the flags which are parsed before a run, see
+// parseRunFlags.
+type runFlags struct {
+	selection *releases.Selection
+	weights   map[string]int
+	srcs      []*archiveSource
+	store     blob.Store // nil unless -publish_to is set
+}
+
+// parseRunFlags parses and validates the flags which configure a run
+// (and sets the policies of the manpage package), without accessing
+// the network.
+func parseRunFlags() (runFlags, error) {
+	var rf runFlags
+	var err error
+	manpage.URLCase, err = urlcase.Parse(*urlCase)
+	if err != nil {
+		return rf, err
+	}
+	manpage.CanonicalLanguages = *canonicalLanguages
+	manpage.Names, err = manpage.ParseNamePolicy(*manpageNames)
+	if err != nil {
+		return rf, err
+	}
+	rf.selection, err = releases.ParseSelection(*suitesFlag)
+	if err != nil {
+		return rf, fmt.Errorf("parsing -suites: %v", err)
+	}
+	bugReportTmpl, err = parseBugReportURL(*bugReportURL)
+	if err != nil {
+		return rf, fmt.Errorf("parsing -bug_report_url: %v", err)
+	}
+	rf.weights, err = parseWeights(*concurrencyWeights)
+	if err != nil {
+		return rf, fmt.Errorf("parsing -concurrency_weights: %v", err)
+	}
+
+	enabledPostProcessors, err = convert.FindPostProcessors(postProcessorNames())
+	if err != nil {
+		return rf, fmt.Errorf("parsing -postprocessors: %v", err)
+	}
+
+	write.ZstdVariant = encodedVariant
+	if *offline {
+		write.PlainVariant = encodedVariant
+		write.Transform = offlineTransform(*servingDir, commontmpl.BaseURLPath())
+	}
+
+	switch *indexCompression {
+	case "smallest", "gz", "xz":
+	default:
+		return rf, fmt.Errorf("invalid -index_compression=%q: expected one of smallest, gz, xz", *indexCompression)
+	}
+
+	switch *verifyContents {
+	case "off", "warn", "fail":
+	default:
+		return rf, fmt.Errorf("invalid -verify_contents=%q: expected one of off, warn, fail", *verifyContents)
+	}
+
+	rf.srcs, err = parseSources(*sources, *localMirror)
+	if err != nil {
+		return rf, fmt.Errorf("parsing -sources: %v", err)
+	}
+
+	overrides, err := blob.ParseContentTypes(*publishContentTypes)
+	if err != nil {
+		return rf, fmt.Errorf("parsing -content_types: %v", err)
+	}
+	blob.OverrideContentTypes(overrides)
+
+	if *publishTo != "" {
+		if rf.store, err = blob.Open(*publishTo); err != nil {
+			return rf, fmt.Errorf("parsing -publish_to: %v", err)
+		}
+	}
+	return rf, nil
+}
+
+// setupMandoc configures convert.Mandoc and verifies that it works, so
+// that debiman fails fast instead of failing to render every single
+// manpage.
+func setupMandoc() (version string, err error) {
+	convert.Mandoc = convert.Command{Path: *mandocPath, Args: strings.Fields(*mandocArgs)}
+	version, err = convert.CheckMandoc(*minMandocVersion)
+	if err != nil {
+		return "", err
+	}
+	if err := convert.Probe(); err != nil {
+		return "", fmt.Errorf("verifying -mandoc_path and -mandoc_args: %v", err)
+	}
+	return version, nil
+}
+
+// converterVersion returns the version of the conversion (see
+// renderCache), i.e. mandocVersion and all non-default flags which
+// change the output of the conversion.
+func converterVersion(mandocVersion string) string {
+	// A custom build of mandoc can differ from a stock build of the
+	// same version. The default program is not part of the version
+	// (and neither are the default arguments), so that existing cache
+	// entries remain valid.
+	version := mandocVersion
+	if path := convert.Mandoc.Path; path != flag.Lookup("mandoc_path").DefValue {
+		version += " path=" + path
+	}
+	// Custom arguments change the output of mandoc.
+	if args := strings.Join(convert.Mandoc.Args, " "); args != strings.Join(convert.DefaultArgs, " ") {
+		version += " " + args
+	}
+	// Likewise for the post processors.
+	if names := strings.Join(postProcessorNames(), ","); names != defaultPostProcessors {
+		version += " postprocessors=" + names
+	}
+	return version
+}
+
+// setupHTTPClient configures the HTTP client (see newHTTPClient) for
+// all requests to the sources of rf and to -publish_to.
+func setupHTTPClient(rf runFlags) error {
+	client, err := newHTTPClient(*caCert, *httpTimeout)
+	if err != nil {
+		return fmt.Errorf("configuring HTTP client: %v", err)
+	}
+	client.Transport = newCircuitBreaker(client.Transport, *mirrorFailureThreshold, *mirrorBackoff, *mirrorOutageDeadline)
+
+	if s3, ok := rf.store.(*blob.S3); ok {
+		s3.Client = client
+	}
+	for _, src := range rf.srcs {
+		src.client = client
+	}
+	return nil
+}
+
+// TODO: handle deleted packages, i.e. packages which are present on
+// disk but not in pkgs
+
+// TODO(later): add memory usage estimates to the big structures, set
+// parallelism level according to available memory on the system
+func logic(lg *logging.Logger) error {
+	start := time.Now()
+
+	rf, err := parseRunFlags()
+	if err != nil {
+		return err
+	}
+	selection, srcs, store := rf.selection, rf.srcs, rf.store
+	pool := newWorkerPool(*concurrency, rf.weights)
+	pool.publish()
+
+	mandocVersion, err := setupMandoc()
+	if err != nil {
+		return err
+	}
+	log.Printf("using mandoc %s (%s)", mandocVersion, strings.Join(convert.Mandoc.Args, " "))
+
+	if err := setupHTTPClient(rf); err != nil {
+		return err
+	}
+
+	// Stage 1: all Debian packages of all architectures of the
+	// specified suites are discovered.
+	done := lg.Timed("stage 1 (discovering packages)")
+	globalView, err := buildGlobalView(srcs, distributions(
+		strings.Split(*syncCodenames, ","),
+		strings.Split(*syncSuites, ",")),
+		selection,
+		*alternativesDir,
+		start)
+	if err != nil {
+		return fmt.Errorf("gathering packages: %v", err)
+	}
+	if selection != nil {
+		if err := pruneUnselectedSuites(*servingDir, globalView.suites); err != nil {
+			return fmt.Errorf("pruning unselected suites: %v", err)
+		}
+	}
+	if *latestSuite != "" {
+		if err := addLatestAlias(&globalView, *latestSuite); err != nil {
+			return fmt.Errorf("-latest_suite: %v", err)
+		}
+	}
+
+	done()
+	globalView.log = lg
+	globalView.pool = pool
+	globalView.stats.MandocVersion = mandocVersion
+	// The output of per-manpage post processors cannot be shared
+	// between manpages with the same content.
+	if !perManpagePostProcessing() {
+		globalView.renderCache = newRenderCache(converterVersion(mandocVersion)+" "+debimanVersion, *renderCacheMemBytes, *renderCacheDir, globalView.stats)
+	}
+
+	if err := pruneOnURLCaseChange(*servingDir, globalView.suites); err != nil {
+		return fmt.Errorf("pruning files after -url_case change: %v", err)
+	}
+
+	fold := manpage.URLCase == urlcase.Lower
+	if !fold {
+		fold, err = caseInsensitiveDir(*servingDir)
+		if err != nil {
+			return fmt.Errorf("checking whether -serving_dir is case-insensitive: %v", err)
+		}
+	}
+	globalView.caseCollisions = resolveCaseCollisions(globalView.xref, fold, globalView.stats)
+
+	log.Printf("gathered packages of all suites, total %d packages", len(globalView.pkgs))
+
+	path := strings.Replace(*indexPath, "<serving_dir>", *servingDir, -1)
+	if len(rebuildPackages) > 0 {
+		if err := restrictToRebuild(&globalView, path); err != nil {
+			return fmt.Errorf("-rebuild_package: %v", err)
+		}
+	}
+	// fullRun is false when only -rebuild_package is updated, which
+	// leaves everything but their pages and index entries as it is.
+	fullRun := globalView.rebuild == nil
+
+	// Stage 2: man pages and auxilliary files (e.g. content fragment
+	// files which are included by a number of manpages) are extracted
+	// from the identified Debian packages.
+	done = lg.Timed("stage 2 (extracting manpages)")
+	if err := parallelDownload(globalView); err != nil {
+		return fmt.Errorf("extracting manpages: %v", err)
+	}
+	done()
+
+	globalView.aliases = resolveAliases(*servingDir, globalView.xref, globalView.stats)
+	for _, x := range globalView.xref {
+		markGrouped(x, func(m *manpage.Meta) bool {
+			if _, alias := globalView.aliases[m.ServingPath()]; alias {
+				return true
+			}
+			return (*minManpageBytes > 0 || *maxManpageBytes > 0) && sizeFiltered(*servingDir, m)
+		})
+	}
+
+	if globalView.manpageCache != nil {
+		if err := globalView.manpageCache.evict(*manpageCacheMaxBytes); err != nil {
+			return fmt.Errorf("evicting manpage cache entries: %v", err)
+		}
+	}
+
+	log.Printf("Extracted all manpages, now rendering")
+
+	// Stage 3: all man pages are rendered into an HTML representation
+	// using mandoc(1), directory index files are rendered, contents
+	// files are rendered.
+	done = lg.Timed("stage 3 (rendering manpages)")
+	if err := renderAll(globalView); err != nil {
+		return fmt.Errorf("rendering manpages: %v", err)
+	}
+	if *groupLanguages {
+		if err := renderGroupedPages(globalView); err != nil {
+			return fmt.Errorf("rendering grouped pages: %v", err)
+		}
+	}
+	if err := removeStaleGroupedPages(globalView); err != nil {
+		return fmt.Errorf("removing stale grouped pages: %v", err)
+	}
+	done()
+
+	if *renderCacheDir != "" {
+		if err := evictLRU(*renderCacheDir, ".json.gz", *renderCacheMaxBytes); err != nil {
+			return fmt.Errorf("evicting render cache entries: %v", err)
+		}
+	}
+
+	log.Printf("Rendered all manpages, writing index")
+
+	// Stage 4: write the index only after all rendering is complete,
+	// otherwise debiman-auxserver might serve redirects to pages
+	// which cannot be served yet.
+	log.Printf("Writing debiman-auxserver index to %q", path)
+	var prevIndex redirect.Index
+	if *newsMaxEntries > 0 && fullRun {
+		prevIndex = readPreviousIndex(path)
+	}
+	done = lg.Timed("stage 4 (writing index)")
+	if err := writeIndex(path, globalView); err != nil {
+		return fmt.Errorf("writing index: %v", err)
+	}
+	done()
+
+	if fullRun {
+		if err := writeSuiteStates(*servingDir, globalView); err != nil {
+			return fmt.Errorf("writing suite states: %v", err)
+		}
+	}
+
+	if *newsMaxEntries > 0 && fullRun {
+		cur, err := redirect.IndexFromProto(path)
+		if err != nil {
+			return fmt.Errorf("reading index: %v", err)
+		}
+		if err := renderNews(*servingDir, prevIndex, cur, globalView); err != nil {
+			return fmt.Errorf("rendering news: %v", err)
+		}
+	}
+
+	if fullRun {
+		if err := renderAux(*servingDir, globalView); err != nil {
+			return fmt.Errorf("rendering aux files: %v", err)
+		}
+
+		if *llmsTxt {
+			if err := renderLLMsTxt(*servingDir, globalView); err != nil {
+				return fmt.Errorf("writing llms.txt: %v", err)
+			}
+		}
+
+		if err := write.Atomically(filepath.Join(*servingDir, "build-info.json"), false, func(w io.Writer) error {
+			return writeBuildInfo(w, globalView, start, time.Now())
+		}); err != nil {
+			return fmt.Errorf("writing build info: %v", err)
+		}
+	}
+
+	if *etagManifest {
+		done = lg.Timed("writing ETag manifest")
+		if err := writeETagManifest(*servingDir); err != nil {
+			return fmt.Errorf("writing ETag manifest: %v", err)
+		}
+		done()
+	}
+
+	// Stage 5: publish the serving directory, now that it is complete.
+	if store != nil {
+		done = lg.Timed("stage 5 (publishing)")
+		if err := publish(*servingDir, *publishTo, store, globalView.stats); err != nil {
+			return fmt.Errorf("publishing to %q: %v", *publishTo, err)
+		}
+		done()
+	}
+
+	pool.report(globalView.stats)
+
+	fmt.Printf("mandoc version:           %s\n", globalView.stats.MandocVersion)
+	reportSuites(os.Stdout, globalView)
+	fmt.Printf("total number of packages: %d\n", len(globalView.pkgs))
+	fmt.Printf("packages extracted:       %d\n", globalView.stats.PackagesExtracted)
+	fmt.Printf("packages deleted:         %d\n", globalView.stats.PackagesDeleted)
+	fmt.Printf("Contents divergences:     %d (%d discarded)\n", globalView.stats.ContentsDivergent, globalView.stats.ContentsDiscarded)
+	fmt.Printf("manpages rendered:        %d\n", globalView.stats.ManpagesRendered)
+	fmt.Printf("total manpage bytes:      %d\n", globalView.stats.ManpageBytes)
+	fmt.Printf("total HTML bytes:         %d\n", globalView.stats.HtmlBytes)
+	fmt.Printf("inlined CSS bytes:        %d\n", globalView.stats.InlinedCSSBytes)
+	fmt.Printf("downloads resumed:        %d\n", globalView.stats.DownloadsResumed)
+	fmt.Printf("manpage cache hits:       %d (of %d)\n", globalView.stats.ManpageCacheHits, globalView.stats.ManpageCacheHits+globalView.stats.ManpageCacheMisses)
+	fmt.Printf("render cache hits:        %d (of %d)\n", globalView.stats.RenderCacheHits, globalView.stats.RenderCacheHits+globalView.stats.RenderCacheMisses)
+	fmt.Printf("manpages deduplicated:    %d (+%d normalized)\n", globalView.stats.ManpagesDeduped, globalView.stats.ManpagesDedupedNormalized)
+	fmt.Printf("max write queue depth:    %d (of %d)\n", globalView.stats.WriteQueueMax, *writeQueueLen)
+	fmt.Printf("auxserver index bytes:    %d\n", globalView.stats.IndexBytes)
+	fmt.Printf("orphaned index entries:   %d\n", globalView.stats.IndexEntriesOrphaned)
+	fmt.Printf("aliases:                  %d (%d broken)\n", globalView.stats.Aliases, globalView.stats.AliasesBroken)
+	fmt.Printf("case collisions:          %d\n", globalView.stats.CaseCollisions)
+	fmt.Printf("manpages too large:       %d\n", globalView.stats.ManpagesTooLarge)
+	fmt.Printf("manpages (nearly) empty:  %d\n", globalView.stats.ManpagesEmpty)
+	fmt.Printf("manpages size-filtered:   %d\n", globalView.stats.ManpagesSizeFiltered)
+	fmt.Printf("files published:          %d (%d deleted)\n", globalView.stats.FilesPublished, globalView.stats.FilesUnpublished)
+	if s := globalView.stats; s.PoolSlots > 0 {
+		fmt.Printf("worker pool slot-seconds: %d (peak %d of %d slots; waited %s)\n", s.PoolBusySeconds, s.PoolBusyMax, s.PoolSlots, s.waitSummary())
+	}
+	fmt.Printf("wall-clock runtime (s):   %d\n", int(time.Now().Sub(start).Seconds()))
+
+	if !fullRun {
+		return nil // the metrics describe full runs
+	}
+	return write.Atomically(filepath.Join(*servingDir, "metrics.txt"), false, func(w io.Writer) error {
+		if err := writeMetrics(w, globalView, start); err != nil {
+			return fmt.Errorf("writing metrics: %v", err)
+		}
+		return nil
+	})
+}
+
+// applyAssetFlags injects the assets of -inject_assets and -icons (if
+// set) and parses the templates again.
+func applyAssetFlags() error {
+	if *injectAssets == "" && *icons == "" {
+		return nil
+	}
+	if *injectAssets != "" {
+		if err := bundled.Inject(*injectAssets); err != nil {
+			return err
+		}
+	}
+	if *icons != "" {
+		if err := bundled.InjectFiles(*icons, commontmpl.Icons); err != nil {
+			return err
+		}
+	}
+
+	commonTmpls = commontmpl.MustParseCommonTmpls()
+	contentsTmpl = mustParseContentsTmpl()
+	newsTmpl = mustParseNewsTmpl()
+	filesTmpl = mustParseFilesTmpl()
+	filespageTmpl = mustParseFilespageTmpl()
+	sectionindexTmpl = mustParseSectionindexTmpl()
+	sectionpageTmpl = mustParseSectionpageTmpl()
+	pkgindexTmpl = mustParsePkgindexTmpl()
+	srcpkgindexTmpl = mustParseSrcPkgindexTmpl()
+	indexTmpl = mustParseIndexTmpl()
+	faqTmpl = mustParseFaqTmpl()
+	aboutTmpl = mustParseAboutTmpl()
+	manpageTmpl = mustParseManpageTmpl()
+	manpageerrorTmpl = mustParseManpageerrorTmpl()
+	manpagefooterextraTmpl = mustParseManpagefooterextraTmpl()
+	manpagefragmentTmpl = mustParseManpagefragmentTmpl()
+	manpageminimalTmpl = mustParseManpageminimalTmpl()
+	return nil
+}
+
+// Main runs debiman (see cmd/debiman) with the flags of the command
+// line.
+func Main() {
+	flag.Parse()
+
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+
+	level, err := logging.ParseLevel(*logLevel)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *verbose {
+		level = logging.Debug
+	}
+	lg := logging.SetupStd(level)
+
+	if *showVersion {
+		fmt.Printf("debiman %s\n", debimanVersion)
+		return
+	}
+
+	if *checkOnly {
+		if !preflight(os.Stdout) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *selftest {
+		if !runSelftest(os.Stdout) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := applyAssetFlags(); err != nil {
+		lg.Fatalf("%v", err)
+	}
+
+	// All of our .so references are relative to *servingDir. For
+	// mandoc(1) to find the files, we need to change the working
+	// directory now.
+	if err := os.Chdir(*servingDir); err != nil {
+		lg.Fatalf("%v", err)
+	}
+
+	go http.ListenAndServe(":4414", nil)
+
+	if err := logic(lg); err != nil {
+		lg.Fatalf("%v", err)
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package debiman
 
 import (
 	"flag"
@@ -15,7 +15,7 @@ func TestEndToEnd(t *testing.T) {
 	defer os.RemoveAll(dir)
 	flag.Set("serving_dir", dir)
 	flag.Set("local_mirror", "../../testdata/tinymirror")
-	if err := logic(); err != nil {
+	if err := logic(nil); err != nil {
 		t.Fatal(err)
 	}
 }
@@ -0,0 +1,276 @@
+package debiman
+
+import (
+	"archive/tar"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"pault.ag/go/archive"
+	"pault.ag/go/debian/control"
+	"pault.ag/go/debian/deb"
+)
+
+var (
+	manpageCacheDir = flag.String("manpage_cache",
+		"",
+		"If non-empty, a directory in which to retain the manpage files (and referenced auxiliary files) of each extracted package version, so that re-extracting (e.g. with -force_reextract or after deleting a suite directory) does not need to download the package again")
+
+	manpageCacheMaxBytes = flag.Int64("manpage_cache_max_bytes",
+		10*1024*1024*1024,
+		"Maximum size of -manpage_cache. After extraction, the least recently used entries are deleted until the cache fits")
+)
+
+// dataSource provides (repeatable) access to the data.tar of a
+// package: downloadPkg iterates over it once for manpages and again
+// for files referenced by .so statements.
+type dataSource interface {
+	data() (*tar.Reader, error)
+	Close() error
+}
+
+// debSource reads data.tar from a downloaded Debian package.
+type debSource struct {
+	f        *os.File
+	filename string
+
+	// partial is the partial download f refers to, or nil if f is a
+	// temporary file of the archive package.
+	partial *partialFile
+}
+
+func downloadDeb(ar *archive.Downloader, p pkgEntry, s *stats) (*debSource, error) {
+	if ar.LocalMirror == "" {
+		// archive.Downloader has no way to specify a client.
+		client := p.client
+		if client == nil {
+			client = http.DefaultClient
+		}
+		url := strings.TrimSuffix(ar.Mirror, "/") + "/" + p.filename
+		pf := usePartial(partialPath(partialDownloadDir(), p))
+		pf.mu.Lock()
+		f, err := fetchResumable(client, url, pf.path, p.bytes, p.sha256, *downloadRetries, &s.DownloadsResumed)
+		pf.mu.Unlock()
+		if err != nil {
+			pf.release(false)
+			return nil, fmt.Errorf("downloading %s: %v", p.filename, err)
+		}
+		return &debSource{f: f, filename: p.filename, partial: pf}, nil
+	}
+	tmp, err := ar.TempFile(control.FileHash{
+		Filename:  p.filename,
+		Algorithm: "sha256",
+		Hash:      fmt.Sprintf("%x", p.sha256),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("archive download: %v", err)
+	}
+	return &debSource{f: tmp, filename: p.filename}, nil
+}
+
+func (s *debSource) data() (*tar.Reader, error) {
+	if _, err := s.f.Seek(0, os.SEEK_SET); err != nil {
+		return nil, err
+	}
+	d, err := deb.Load(s.f, s.filename)
+	if err != nil {
+		return nil, fmt.Errorf("loading %q: %v", s.filename, err)
+	}
+	return d.Data, nil
+}
+
+func (s *debSource) Close() error {
+	if s.partial != nil {
+		s.partial.release(true)
+	} else {
+		os.Remove(s.f.Name())
+	}
+	return s.f.Close()
+}
+
+// cacheSource reads a tar file previously written by cacheWriter.
+type cacheSource struct {
+	f *os.File
+}
+
+func (s *cacheSource) data() (*tar.Reader, error) {
+	if _, err := s.f.Seek(0, os.SEEK_SET); err != nil {
+		return nil, err
+	}
+	return tar.NewReader(s.f), nil
+}
+
+func (s *cacheSource) Close() error {
+	return s.f.Close()
+}
+
+// manpageCache stores the subset of a package’s data.tar which
+// downloadPkg extracts, keyed by binary package, version and hash.
+type manpageCache struct {
+	dir string
+}
+
+func (c *manpageCache) path(p pkgEntry) string {
+	// Like in the Debian pool, the version’s epoch colon is escaped.
+	v := strings.Replace(p.version.String(), ":", "%3a", -1)
+	// Entries only contain the changelog with -changelog_entries. Its
+	// entries are parsed when reading the cache entry, so the number
+	// does not matter. Likewise, entries only contain info documents
+	// with -info_pages.
+	var suffix string
+	if *changelogEntries > 0 {
+		suffix += "_changelog"
+	}
+	if *infoPages {
+		suffix += "_info"
+	}
+	return filepath.Join(c.dir, fmt.Sprintf("%s_%s_%x%s.tar", p.binarypkg, v, p.sha256, suffix))
+}
+
+// open returns the cached data of p, or nil if p is not cached.
+func (c *manpageCache) open(p pkgEntry) (*cacheSource, error) {
+	path := c.path(p)
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	// The modification time is used for least recently used eviction.
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &cacheSource{f: f}, nil
+}
+
+// cacheWriter records the files which downloadPkg extracts. The cache
+// entry only becomes visible once commit is called.
+type cacheWriter struct {
+	f         *os.File
+	tw        *tar.Writer
+	dest      string
+	committed bool
+}
+
+func (c *manpageCache) create(p pkgEntry) (*cacheWriter, error) {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return nil, err
+	}
+	f, err := ioutil.TempFile(c.dir, ".debiman-")
+	if err != nil {
+		return nil, err
+	}
+	return &cacheWriter{
+		f:    f,
+		tw:   tar.NewWriter(f),
+		dest: c.path(p),
+	}, nil
+}
+
+// add records a file. content is ignored for symlinks and hard links.
+// A nil *cacheWriter ignores all calls, so that callers need not check
+// whether caching is enabled.
+func (w *cacheWriter) add(header *tar.Header, content []byte) error {
+	if w == nil {
+		return nil
+	}
+	h := *header // copy
+	if h.Typeflag == tar.TypeRegA {
+		h.Typeflag = tar.TypeReg
+	}
+	if h.Typeflag == tar.TypeSymlink || h.Typeflag == tar.TypeLink {
+		h.Size = 0
+		content = nil
+	} else {
+		h.Size = int64(len(content))
+	}
+	if err := w.tw.WriteHeader(&h); err != nil {
+		return err
+	}
+	_, err := w.tw.Write(content)
+	return err
+}
+
+func (w *cacheWriter) commit() error {
+	if w == nil {
+		return nil
+	}
+	if err := w.tw.Close(); err != nil {
+		w.abort()
+		return err
+	}
+	if err := w.f.Close(); err != nil {
+		os.Remove(w.f.Name())
+		return err
+	}
+	if err := os.Rename(w.f.Name(), w.dest); err != nil {
+		os.Remove(w.f.Name())
+		return err
+	}
+	w.committed = true
+	return nil
+}
+
+// abort discards the cache entry, unless it was already committed.
+func (w *cacheWriter) abort() {
+	if w == nil || w.committed {
+		return
+	}
+	w.f.Close()
+	os.Remove(w.f.Name())
+}
+
+type byModTime []os.FileInfo
+
+func (p byModTime) Len() int           { return len(p) }
+func (p byModTime) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p byModTime) Less(i, j int) bool { return p[i].ModTime().Before(p[j].ModTime()) }
+
+// evict deletes the least recently used cache entries until the cache
+// is at most maxBytes large.
+func (c *manpageCache) evict(maxBytes int64) error {
+	return evictLRU(c.dir, ".tar", maxBytes)
+}
+
+// evictLRU deletes the least recently modified files in dir whose name
+// ends in suffix until their total size is at most maxBytes.
+func evictLRU(dir, suffix string, maxBytes int64) error {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	var entries []os.FileInfo
+	var total int64
+	for _, fi := range infos {
+		if !fi.Mode().IsRegular() || !strings.HasSuffix(fi.Name(), suffix) {
+			continue
+		}
+		entries = append(entries, fi)
+		total += fi.Size()
+	}
+	sort.Sort(byModTime(entries))
+	for _, fi := range entries {
+		if total <= maxBytes {
+			break
+		}
+		log.Printf("evicting %q from %q", fi.Name(), dir)
+		if err := os.Remove(filepath.Join(dir, fi.Name())); err != nil {
+			return err
+		}
+		total -= fi.Size()
+	}
+	return nil
+}
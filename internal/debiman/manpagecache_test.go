@@ -0,0 +1,154 @@
+package debiman
+
+import (
+	"archive/tar"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"pault.ag/go/debian/version"
+)
+
+func TestManpageCache(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "debiman-manpagecache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	v, err := version.Parse("1:4.13-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := pkgEntry{
+		binarypkg: "i3-wm",
+		version:   v,
+		sha256:    []byte{0xde, 0xad},
+	}
+	c := &manpageCache{dir: tmpdir}
+
+	if src, err := c.open(p); err != nil || src != nil {
+		t.Fatalf("open() on empty cache = %v, %v, want nil, nil", src, err)
+	}
+
+	cw, err := c.create(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.add(&tar.Header{Name: "./usr/share/man/man1/i3.1.gz", Typeflag: tar.TypeReg, Mode: 0644}, []byte("content")); err != nil {
+		t.Fatal(err)
+	}
+	if err := cw.add(&tar.Header{Name: "./usr/share/man/man1/i3-wm.1.gz", Typeflag: tar.TypeSymlink, Linkname: "i3.1.gz"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if src, err := c.open(p); err != nil || src != nil {
+		t.Fatalf("open() before commit = %v, %v, want nil, nil", src, err)
+	}
+	if err := cw.commit(); err != nil {
+		t.Fatal(err)
+	}
+	cw.abort() // must not remove the committed entry
+
+	src, err := c.open(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src == nil {
+		t.Fatalf("open() after commit unexpectedly returned nil")
+	}
+	defer src.Close()
+	// data must be repeatable, as downloadPkg reads it twice.
+	for i := 0; i < 2; i++ {
+		tr, err := src.data()
+		if err != nil {
+			t.Fatal(err)
+		}
+		h, err := tr.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := string(b), "content"; got != want {
+			t.Errorf("%s: got %q, want %q", h.Name, got, want)
+		}
+		h, err = tr.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := h.Linkname, "i3.1.gz"; got != want {
+			t.Errorf("%s: got link target %q, want %q", h.Name, got, want)
+		}
+	}
+}
+
+func TestManpageCachePath(t *testing.T) {
+	defer func(prev int) { *changelogEntries = prev }(*changelogEntries)
+	defer func(prev bool) { *infoPages = prev }(*infoPages)
+	v, err := version.Parse("1:4.13-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := pkgEntry{
+		binarypkg: "i3-wm",
+		version:   v,
+		sha256:    []byte{0xde, 0xad},
+	}
+	c := &manpageCache{dir: "/cache"}
+	for _, entry := range []struct {
+		changelogEntries int
+		infoPages        bool
+		want             string
+	}{
+		{0, false, "/cache/i3-wm_1%3a4.13-1_dead.tar"},
+		{5, false, "/cache/i3-wm_1%3a4.13-1_dead_changelog.tar"},
+		{10, false, "/cache/i3-wm_1%3a4.13-1_dead_changelog.tar"},
+		{0, true, "/cache/i3-wm_1%3a4.13-1_dead_info.tar"},
+		{5, true, "/cache/i3-wm_1%3a4.13-1_dead_changelog_info.tar"},
+	} {
+		*changelogEntries = entry.changelogEntries
+		*infoPages = entry.infoPages
+		if got := c.path(p); got != entry.want {
+			t.Errorf("-changelog_entries=%d -info_pages=%v: path() = %q, want %q", entry.changelogEntries, entry.infoPages, got, entry.want)
+		}
+	}
+}
+
+func TestManpageCacheEvict(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "debiman-manpagecache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	now := time.Now()
+	for i, name := range []string{"old.tar", "middle.tar", "new.tar"} {
+		fn := filepath.Join(tmpdir, name)
+		if err := ioutil.WriteFile(fn, make([]byte, 100), 0644); err != nil {
+			t.Fatal(err)
+		}
+		mtime := now.Add(time.Duration(i-3) * time.Hour)
+		if err := os.Chtimes(fn, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := &manpageCache{dir: tmpdir}
+	if err := c.evict(250); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{
+		"old.tar":    false,
+		"middle.tar": true,
+		"new.tar":    true,
+	} {
+		_, err := os.Stat(filepath.Join(tmpdir, name))
+		if got := err == nil; got != want {
+			t.Errorf("%s present = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package debiman
+
+import (
+	"encoding/json"
+	"flag"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/Debian/debiman/internal/commontmpl"
+)
+
+var emitMetadata = flag.Bool("emit_metadata",
+	false,
+	"Additionally write a .json file next to each manpage (e.g. i3.1.en.json next to i3.1.en.html), containing its structured metadata for indexers: name, section, language, binary package, version, suite, the description of its NAME section, its section titles and its cross-references to other manpages. Roughly doubles the number of files. Existing pages only get metadata when they are re-rendered, so use -force_rerender once after enabling this flag")
+
+// manpageMetadata is the content of the .json file of a manpage.
+type manpageMetadata struct {
+	Name            string         `json:"name"`
+	Section         string         `json:"section"`
+	Language        string         `json:"language"`
+	Package         string         `json:"package"`
+	Version         string         `json:"version"`
+	Suite           string         `json:"suite"`
+	Description     string         `json:"description,omitempty"`
+	Sections        []string       `json:"sections"`
+	CrossReferences []metadataXref `json:"cross_references"`
+}
+
+// metadataXref is a cross-reference to another rendered manpage.
+type metadataXref struct {
+	Name    string `json:"name"`
+	Section string `json:"section"`
+	Path    string `json:"path"` // serving path, e.g. jessie/i3-wm/i3-msg.1.en
+}
+
+// metadataDest returns the path of the metadata of the rendered
+// manpage dest, e.g. i3.1.en.json.gz for i3.1.en.html.gz.
+func metadataDest(dest string) string {
+	return strings.TrimSuffix(dest, ".html.gz") + ".json.gz"
+}
+
+// linkedCrossReference matches the links which rendermanpageprep
+// generates for cross-references, along with their text, e.g. “i3-msg(1)”.
+var linkedCrossReference = regexp.MustCompile(`<a href="([^"#?]+)\.html">([^<]+)\(([^()<]+)\)</a>`)
+
+// crossReferences returns the distinct cross-references of content (as
+// converted), in order of their first occurrence.
+func crossReferences(content string) []metadataXref {
+	prefix := commontmpl.BaseURLPath() + "/"
+	xrefs := []metadataXref{}
+	seen := make(map[string]bool)
+	for _, m := range linkedCrossReference.FindAllStringSubmatch(content, -1) {
+		if !strings.HasPrefix(m[1], prefix) {
+			continue
+		}
+		path := html.UnescapeString(strings.TrimPrefix(m[1], prefix))
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+		xrefs = append(xrefs, metadataXref{
+			Name:    html.UnescapeString(m[2]),
+			Section: html.UnescapeString(m[3]),
+			Path:    path,
+		})
+	}
+	return xrefs
+}
+
+// renderMetadata returns the metadata of the manpage of data. Manpages
+// which could not be converted have no description, sections or
+// cross-references.
+func renderMetadata(data manpagePrepData) ([]byte, error) {
+	m := data.Meta
+	md := manpageMetadata{
+		Name:            m.Name,
+		Section:         m.Section,
+		Language:        m.Language,
+		Package:         m.Package.Binarypkg,
+		Version:         m.Package.Version.String(),
+		Suite:           m.Package.Suite,
+		Sections:        []string{},
+		CrossReferences: []metadataXref{},
+	}
+	if data.Error == nil {
+		content := string(data.Content)
+		if m.Format() != "info" {
+			_, md.Description, _ = parseNameSection(content)
+		}
+		if data.TOC != nil {
+			md.Sections = data.TOC
+		}
+		md.CrossReferences = crossReferences(content)
+	}
+	return json.Marshal(md)
+}
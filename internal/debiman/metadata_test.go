@@ -0,0 +1,71 @@
+package debiman
+
+import (
+	"encoding/json"
+	"html/template"
+	"reflect"
+	"testing"
+
+	"pault.ag/go/debian/version"
+
+	"github.com/Debian/debiman/internal/manpage"
+)
+
+func TestRenderMetadata(t *testing.T) {
+	if got, want := metadataDest("/srv/man/jessie/i3-wm/i3.1.en.html.gz"), "/srv/man/jessie/i3-wm/i3.1.en.json.gz"; got != want {
+		t.Fatalf("unexpected metadataDest: got %q, want %q", got, want)
+	}
+
+	meta := &manpage.Meta{
+		Name:     "i3",
+		Section:  "1",
+		Language: "en",
+		Package: &manpage.PkgMeta{
+			Binarypkg: "i3-wm",
+			Suite:     "jessie",
+			Version:   version.Version{Version: "4.8", Revision: "2"},
+		},
+	}
+	data := manpagePrepData{
+		Meta: meta,
+		TOC:  []string{"NAME", "SEE ALSO"},
+		Content: template.HTML(`<div class="mandoc"><section class="Sh"><h1 id="NAME">NAME<a class="anchor" href="#NAME">¶</a></h1>i3 - improved dynamic tiling window manager</section>` +
+			`<a href="/jessie/i3-wm/i3-msg.1.en.html">i3-msg(1)</a>, <a href="/jessie/x11-common/X.7.en.html">X(7)</a>, ` +
+			`<a href="/jessie/i3-wm/i3-msg.1.en.html">i3-msg(1)</a>, <a href="#NAME">NAME</a>, <a href="https://i3wm.org/">https://i3wm.org/</a></div>`),
+	}
+	b, err := renderMetadata(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got manpageMetadata
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := manpageMetadata{
+		Name:        "i3",
+		Section:     "1",
+		Language:    "en",
+		Package:     "i3-wm",
+		Version:     "4.8-2",
+		Suite:       "jessie",
+		Description: "improved dynamic tiling window manager",
+		Sections:    []string{"NAME", "SEE ALSO"},
+		CrossReferences: []metadataXref{
+			{Name: "i3-msg", Section: "1", Path: "jessie/i3-wm/i3-msg.1.en"},
+			{Name: "X", Section: "7", Path: "jessie/x11-common/X.7.en"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected metadata:\ngot  %+v\nwant %+v", got, want)
+	}
+
+	// Manpages which could not be converted have empty lists, not null.
+	data.Error = notYetRenderedSentinel
+	b, err = renderMetadata(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), `{"name":"i3","section":"1","language":"en","package":"i3-wm","version":"4.8-2","suite":"jessie","sections":[],"cross_references":[]}`; got != want {
+		t.Errorf("unexpected metadata for an unconverted manpage:\ngot  %s\nwant %s", got, want)
+	}
+}
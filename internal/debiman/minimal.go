@@ -0,0 +1,48 @@
+package debiman
+
+import (
+	"bytes"
+	"html/template"
+	"regexp"
+	"strings"
+
+	"github.com/Debian/debiman/internal/bundled"
+)
+
+var manpageminimalTmpl = mustParseManpageminimalTmpl()
+
+func mustParseManpageminimalTmpl() *template.Template {
+	fragment := template.Must(template.Must(commonTmpls.Clone()).New("manpage-fragment").
+		Parse(bundled.Asset("manpagefragment.tmpl")))
+	return template.Must(fragment.New("manpage-minimal").
+		Parse(bundled.Asset("manpageminimal.tmpl")))
+}
+
+// minimalDest returns the path of the minimal variant of the rendered
+// manpage dest, e.g. i3.1.en.min.html.gz for i3.1.en.html.gz.
+func minimalDest(dest string) string {
+	return strings.TrimSuffix(dest, ".html.gz") + ".min.html.gz"
+}
+
+// crossReference matches the links which rendermanpageprep generates
+// for cross-references, i.e. links to other rendered manpages.
+var crossReference = regexp.MustCompile(`href="(/[^"#?]+)\.html"`)
+
+// minimalLinks makes all cross-references in content point to the
+// minimal variant of the referenced manpage, so that readers stay
+// within the minimal profile. Anchors and external links are retained.
+func minimalLinks(content template.HTML) template.HTML {
+	return template.HTML(crossReference.ReplaceAllString(string(content), `href="$1.min.html"`))
+}
+
+// renderMinimal renders a self-contained page with only the manpage
+// itself (or the error message), a link to the full page and a minimal
+// inlined stylesheet, but no scripts, switchers or table of contents.
+func renderMinimal(data manpagePrepData) ([]byte, error) {
+	data.Content = minimalLinks(data.Content)
+	var buf bytes.Buffer
+	if err := manpageminimalTmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
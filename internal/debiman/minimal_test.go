@@ -0,0 +1,56 @@
+package debiman
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/language"
+
+	"github.com/Debian/debiman/internal/manpage"
+)
+
+func TestRenderMinimal(t *testing.T) {
+	if got, want := minimalDest("/srv/man/jessie/i3-wm/i3.1.en.html.gz"), "/srv/man/jessie/i3-wm/i3.1.en.min.html.gz"; got != want {
+		t.Fatalf("unexpected minimalDest: got %q, want %q", got, want)
+	}
+
+	content := minimalLinks(template.HTML(`<a href="/jessie/i3-wm/i3-msg.1.en.html">i3-msg(1)</a> <a href="#NAME">NAME</a> <a href="https://i3wm.org/docs/userguide.html">userguide</a>`))
+	if got, want := string(content), `<a href="/jessie/i3-wm/i3-msg.1.en.min.html">i3-msg(1)</a> <a href="#NAME">NAME</a> <a href="https://i3wm.org/docs/userguide.html">userguide</a>`; got != want {
+		t.Fatalf("unexpected minimalLinks result: got %q, want %q", got, want)
+	}
+
+	page, err := renderMinimal(manpagePrepData{
+		Title: "i3(1) — i3-wm — Debian jessie",
+		Meta: &manpage.Meta{
+			Name:        "i3",
+			Section:     "1",
+			Language:    "en",
+			LanguageTag: language.English,
+			Package:     &manpage.PkgMeta{Binarypkg: "i3-wm", Suite: "jessie"},
+		},
+		TOC:     []string{"NAME", "SYNOPSIS"},
+		Content: template.HTML(`<div class="mandoc"><h1 id="NAME">NAME<a class="anchor" href="#NAME">¶</a></h1><a href="/jessie/i3-wm/i3-msg.1.en.html">i3-msg(1)</a></div>`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(page)
+	for _, want := range []string{
+		`<html lang="en">`,
+		`<style type="text/css">`,
+		`<link rel="canonical" href="/jessie/i3-wm/i3.1.en.html">`,
+		`<article class="debiman-manpage" lang="en">`,
+		`<a class="anchor" href="#NAME">`,
+		`href="/jessie/i3-wm/i3-msg.1.en.min.html"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("minimal page does not contain %q", want)
+		}
+	}
+	for _, unwanted := range []string{`<script`, `stylesheet`, `class="panel`, `toclink`} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("minimal page unexpectedly contains %q", unwanted)
+		}
+	}
+}
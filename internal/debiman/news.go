@@ -0,0 +1,284 @@
+package debiman
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Debian/debiman/internal/bundled"
+	"github.com/Debian/debiman/internal/manpage"
+	"github.com/Debian/debiman/internal/redirect"
+	"github.com/Debian/debiman/internal/section"
+	"github.com/Debian/debiman/internal/write"
+)
+
+var newsMaxEntries = flag.Int("news_max_entries",
+	0,
+	"If positive, write a news page (/<suite>/news.html) for each suite, listing the manpages which were added to or removed from the suite since the previous run (found by comparing the auxserver index against the previous one, see -index), newest first, along with the date of the run. The entries of previous runs are kept in -serving_dir, up to this many per suite. 0 disables the news pages")
+
+var newsTmpl = mustParseNewsTmpl()
+
+func mustParseNewsTmpl() *template.Template {
+	return template.Must(template.Must(commonTmpls.Clone()).New("news").Parse(bundled.Asset("news.tmpl")))
+}
+
+// newsEntry is a manpage which was added to or removed from a suite.
+type newsEntry struct {
+	Date      string // of the run which noticed the change, e.g. 2017-05-23
+	Removed   bool
+	Binarypkg string
+	Name      string
+	Section   string
+
+	// Language is the language of the manpage to link to (English, if
+	// available). Empty for removed manpages.
+	Language string
+
+	// Page is the URL path of the page of the manpage in the current
+	// index (see linkNews), e.g. jessie/cron/crontab.5.html#lang-en
+	// with -group_languages. Not kept across runs, as the page of a
+	// manpage can change (e.g. by becoming an alias).
+	Page string `json:"-"`
+}
+
+// PageURL returns the URL path of the page of the manpage within
+// suite, or "" if the manpage was removed. Falls back to the page in
+// Language for manpages which are no longer in the index.
+func (e newsEntry) PageURL(suite string) string {
+	if e.Removed {
+		return ""
+	}
+	if e.Page != "" {
+		return e.Page
+	}
+	return suite + "/" + e.Binarypkg + "/" + e.Name + "." + e.Section + "." + e.Language + ".html"
+}
+
+// linkNews returns a copy of entries in which the added manpages link
+// to their page in cur (the indexedManpages of a suite), respecting
+// redirect.IndexEntry.Target and redirect.IndexEntry.Grouped.
+func linkNews(entries []newsEntry, cur map[string]redirect.IndexEntry) []newsEntry {
+	linked := append([]newsEntry(nil), entries...)
+	for idx, e := range linked {
+		ie, ok := cur[e.Binarypkg+"/"+e.Name+"."+e.Section]
+		if e.Removed || !ok {
+			continue
+		}
+		page := strings.TrimPrefix(ie.ServingPath(".html"), "/")
+		if fragment := ie.Fragment(); fragment != "" {
+			page += "#" + fragment
+		}
+		linked[idx].Page = page
+	}
+	return linked
+}
+
+// newsDay groups the entries of one date for the news page.
+type newsDay struct {
+	Date    string
+	Added   []newsEntry
+	Removed []newsEntry
+}
+
+// newsState returns the path of the file which keeps the news entries
+// of suite across runs. As a dot file, it is not published.
+func newsState(servingDir, suite string) string {
+	return filepath.Join(servingDir, suite, ".news.json")
+}
+
+// indexedManpages returns the manpages of idx (ignoring their
+// language and architecture) by suite, keyed by binarypkg/name.section.
+// The value is the index entry to link to.
+func indexedManpages(idx redirect.Index) map[string]map[string]redirect.IndexEntry {
+	result := make(map[string]map[string]redirect.IndexEntry)
+	for _, entries := range idx.Entries {
+		for _, e := range entries {
+			bySuite, ok := result[e.Suite]
+			if !ok {
+				bySuite = make(map[string]redirect.IndexEntry)
+				result[e.Suite] = bySuite
+			}
+			key := e.Binarypkg + "/" + e.Name + "." + e.Section
+			if prev, ok := bySuite[key]; ok &&
+				(prev.Language == "en" || (e.Language != "en" && prev.Language < e.Language)) {
+				continue
+			}
+			bySuite[key] = e
+		}
+	}
+	return result
+}
+
+// diffNews returns the news entries between prev and cur (the
+// indexedManpages of a suite), added manpages first, each sorted by
+// binarypkg/name.section.
+func diffNews(prev, cur map[string]redirect.IndexEntry, date string) []newsEntry {
+	var added, removed []newsEntry
+	for key, e := range cur {
+		if _, ok := prev[key]; !ok {
+			added = append(added, newsEntry{
+				Date:      date,
+				Binarypkg: e.Binarypkg,
+				Name:      e.Name,
+				Section:   e.Section,
+				Language:  e.Language,
+			})
+		}
+	}
+	for key, e := range prev {
+		if _, ok := cur[key]; !ok {
+			removed = append(removed, newsEntry{
+				Date:      date,
+				Removed:   true,
+				Binarypkg: e.Binarypkg,
+				Name:      e.Name,
+				Section:   e.Section,
+			})
+		}
+	}
+	for _, entries := range [][]newsEntry{added, removed} {
+		sort.Slice(entries, func(i, j int) bool {
+			a, b := entries[i], entries[j]
+			if a.Binarypkg != b.Binarypkg {
+				return a.Binarypkg < b.Binarypkg
+			}
+			if a.Name != b.Name {
+				return a.Name < b.Name
+			}
+			return section.Less(a.Section, b.Section)
+		})
+	}
+	return append(added, removed...)
+}
+
+// mergeNews prepends the entries of the current run to the entries of
+// previous runs, keeping at most max entries.
+func mergeNews(current, previous []newsEntry, max int) []newsEntry {
+	merged := append(append([]newsEntry{}, current...), previous...)
+	if len(merged) > max {
+		merged = merged[:max]
+	}
+	return merged
+}
+
+// newsDays groups entries (newest first) by date.
+func newsDays(entries []newsEntry) []newsDay {
+	var days []newsDay
+	for _, e := range entries {
+		if len(days) == 0 || days[len(days)-1].Date != e.Date {
+			days = append(days, newsDay{Date: e.Date})
+		}
+		day := &days[len(days)-1]
+		if e.Removed {
+			day.Removed = append(day.Removed, e)
+		} else {
+			day.Added = append(day.Added, e)
+		}
+	}
+	return days
+}
+
+func readNews(fn string) ([]newsEntry, error) {
+	b, err := ioutil.ReadFile(fn)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var entries []newsEntry
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return nil, fmt.Errorf("%s: %v", fn, err)
+	}
+	return entries, nil
+}
+
+// readPreviousIndex reads the index of the previous run from path,
+// which stays in place until writeIndex replaces it. A missing or
+// unreadable index results in the zero Index: the news pages then only
+// list the entries of previous runs.
+func readPreviousIndex(path string) redirect.Index {
+	idx, err := redirect.IndexFromProto(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Not updating news pages: could not read previous index: %v", err)
+		}
+		return redirect.Index{}
+	}
+	return idx
+}
+
+// renderNews updates the news state and page of each suite of gv with
+// the changes between the indexes prev (see readPreviousIndex) and cur.
+// The news pages link to the pages of the manpages in cur.
+func renderNews(servingDir string, prev, cur redirect.Index, gv globalView) error {
+	date := gv.start.UTC().Format("2006-01-02")
+	var prevBySuite map[string]map[string]redirect.IndexEntry
+	if prev.Entries != nil {
+		prevBySuite = indexedManpages(prev)
+	}
+	curBySuite := indexedManpages(cur)
+	for suite := range gv.suites {
+		if fi, err := os.Stat(filepath.Join(servingDir, suite)); err != nil || !fi.IsDir() {
+			continue // no contents page to link to, either
+		}
+		fn := newsState(servingDir, suite)
+		entries, err := readNews(fn)
+		if err != nil {
+			return err
+		}
+		if prev.Entries != nil {
+			current := diffNews(prevBySuite[suite], curBySuite[suite], date)
+			if len(current) > 0 {
+				log.Printf("news of %s: %d manpages added or removed", suite, len(current))
+			}
+			entries = mergeNews(current, entries, *newsMaxEntries)
+			b, err := json.Marshal(entries)
+			if err != nil {
+				return err
+			}
+			if err := write.Atomically(fn, false, func(w io.Writer) error {
+				_, err := w.Write(b)
+				return err
+			}); err != nil {
+				return err
+			}
+		}
+		if err := write.Atomically(filepath.Join(servingDir, suite, "news.html.gz"), true, func(w io.Writer) error {
+			return writeNews(w, suite, linkNews(entries, curBySuite[suite]))
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeNews(w io.Writer, suite string, entries []newsEntry) error {
+	return newsTmpl.Execute(w, struct {
+		Title          string
+		DebimanVersion string
+		Breadcrumbs    breadcrumbs
+		FooterExtra    string
+		Suite          string
+		Days           []newsDay
+		Meta           *manpage.Meta
+		HrefLangs      []*manpage.Meta
+	}{
+		Title:          fmt.Sprintf("News of Debian %s", suite),
+		DebimanVersion: debimanVersion,
+		Breadcrumbs: breadcrumbs{
+			{fmt.Sprintf("/contents-%s.html", suite), suite},
+			{"", "News"},
+		},
+		Suite: suite,
+		Days:  newsDays(entries),
+	})
+}
@@ -0,0 +1,111 @@
+package debiman
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Debian/debiman/internal/redirect"
+)
+
+func TestNews(t *testing.T) {
+	entry := func(binarypkg, name, section, language string) redirect.IndexEntry {
+		return redirect.IndexEntry{
+			Name:      name,
+			Suite:     "jessie",
+			Binarypkg: binarypkg,
+			Section:   section,
+			Language:  language,
+		}
+	}
+	prev := redirect.Index{Entries: map[string][]redirect.IndexEntry{
+		"ls":    {entry("coreutils", "ls", "1", "en"), entry("coreutils", "ls", "1", "de")},
+		"ifup":  {entry("ifupdown", "ifup", "8", "en")},
+		"gpart": {entry("gpart", "gpart", "8", "en")},
+	}}
+	cur := redirect.Index{Entries: map[string][]redirect.IndexEntry{
+		"ls":   {entry("coreutils", "ls", "1", "en")},
+		"ifup": {entry("ifupdown", "ifup", "8", "en"), entry("ifupdown", "ifup", "5", "fr"), entry("ifupdown", "ifup", "5", "de")},
+		"cat":  {entry("coreutils", "cat", "1", "en")},
+	}}
+	got := diffNews(indexedManpages(prev)["jessie"], indexedManpages(cur)["jessie"], "2017-05-23")
+	want := []newsEntry{
+		{Date: "2017-05-23", Binarypkg: "coreutils", Name: "cat", Section: "1", Language: "en"},
+		{Date: "2017-05-23", Binarypkg: "ifupdown", Name: "ifup", Section: "5", Language: "de"},
+		{Date: "2017-05-23", Removed: true, Binarypkg: "gpart", Name: "gpart", Section: "8"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("diffNews() = %+v, want %+v", got, want)
+	}
+
+	previous := []newsEntry{
+		{Date: "2017-05-22", Binarypkg: "grep", Name: "grep", Section: "1", Language: "en"},
+		{Date: "2017-05-21", Binarypkg: "sed", Name: "sed", Section: "1", Language: "en"},
+	}
+	merged := mergeNews(got, previous, 4)
+	if got, want := len(merged), 4; got != want {
+		t.Fatalf("mergeNews(): got %d entries, want %d", got, want)
+	}
+	if got, want := merged[3].Name, "grep"; got != want {
+		t.Errorf("mergeNews(): last entry = %q, want %q", got, want)
+	}
+
+	days := newsDays(merged)
+	if got, want := len(days), 2; got != want {
+		t.Fatalf("newsDays(): got %d days, want %d", got, want)
+	}
+	if got, want := len(days[0].Added), 2; got != want {
+		t.Errorf("newsDays(): %s: got %d added, want %d", days[0].Date, got, want)
+	}
+	if got, want := len(days[0].Removed), 1; got != want {
+		t.Errorf("newsDays(): %s: got %d removed, want %d", days[0].Date, got, want)
+	}
+
+	var buf bytes.Buffer
+	if err := writeNews(&buf, "jessie", merged); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`href="/jessie/coreutils/cat.1.en.html"`,
+		`href="/jessie/ifupdown/ifup.5.de.html"`,
+		`<li>gpart(8) from gpart</li>`,
+		`id="2017-05-22"`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("news page does not contain %q", want)
+		}
+	}
+}
+
+func TestLinkNews(t *testing.T) {
+	cur := redirect.Index{Entries: map[string][]redirect.IndexEntry{
+		"crontab": {
+			{Name: "crontab", Suite: "jessie", Binarypkg: "cron", Section: "5", Language: "en", Grouped: true},
+			{Name: "crontab", Suite: "jessie", Binarypkg: "cron", Section: "5", Language: "de", Grouped: true},
+		},
+		"gls": {
+			{Name: "gls", Suite: "jessie", Binarypkg: "coreutils", Section: "1", Language: "en", Target: "jessie/coreutils/ls.1.en"},
+		},
+	}}
+	entries := []newsEntry{
+		{Date: "2017-05-23", Binarypkg: "cron", Name: "crontab", Section: "5", Language: "en"},
+		{Date: "2017-05-23", Binarypkg: "coreutils", Name: "gls", Section: "1", Language: "en"},
+		{Date: "2017-05-22", Binarypkg: "grep", Name: "grep", Section: "1", Language: "en"},
+		{Date: "2017-05-22", Removed: true, Binarypkg: "gpart", Name: "gpart", Section: "8"},
+	}
+	linked := linkNews(entries, indexedManpages(cur)["jessie"])
+	if entries[0].Page != "" {
+		t.Errorf("linkNews() modified its argument")
+	}
+	for idx, want := range []string{
+		"jessie/cron/crontab.5.html#lang-en",
+		"jessie/coreutils/ls.1.en.html",
+		"jessie/grep/grep.1.en.html", // no longer in the index
+		"",
+	} {
+		if got := linked[idx].PageURL("jessie"); got != want {
+			t.Errorf("%s(%s): PageURL() = %q, want %q", linked[idx].Name, linked[idx].Section, got, want)
+		}
+	}
+}
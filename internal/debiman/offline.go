@@ -0,0 +1,66 @@
+package debiman
+
+import (
+	"flag"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var offline = flag.Bool("offline",
+	false,
+	"Make all links of the generated pages (cross-references, version and language switchers, breadcrumbs, assets) relative to the page, and additionally write an uncompressed variant (e.g. ls.1.en.html) next to each gzip-compressed page, so that -serving_dir can be browsed from disk via file:// (e.g. on a USB stick) without a web server. Pages omit what requires debiman-auxserver, like the search box and language-independent links. Takes effect for files which are written, see -force_rerender")
+
+// relativeURL returns the shortest relative URL which refers to the
+// slash-separated path to (relative to the root of the serving
+// directory) from the page at the slash-separated path from.
+func relativeURL(from, to string) string {
+	fromDirs := strings.Split(path.Dir(from), "/")
+	if fromDirs[0] == "." {
+		fromDirs = nil
+	}
+	toParts := strings.Split(to, "/")
+	common := 0
+	for common < len(fromDirs) && common < len(toParts)-1 && fromDirs[common] == toParts[common] {
+		common++
+	}
+	return strings.Repeat("../", len(fromDirs)-common) + strings.Join(toParts[common:], "/")
+}
+
+// relativeLinks returns a function which makes the host-relative links
+// (below basePath, see commontmpl.BaseURLPath) of the page at the
+// slash-separated path page relative to the page. Links to directories
+// refer to their index.html, which web servers serve for them.
+func relativeLinks(basePath string) func(page string, content []byte) []byte {
+	link := regexp.MustCompile(`((?:href|src|action)="|url\()` + regexp.QuoteMeta(basePath) + `/((?:[^/"')\s?#][^"')\s?#]*)?)(["')\s?#])`)
+	return func(page string, content []byte) []byte {
+		return link.ReplaceAllFunc(content, func(m []byte) []byte {
+			sub := link.FindSubmatch(m)
+			target := string(sub[2])
+			if target == "" || strings.HasSuffix(target, "/") {
+				target += "index.html"
+			}
+			rewritten := append([]byte(nil), sub[1]...)
+			rewritten = append(rewritten, relativeURL(page, target)...)
+			return append(rewritten, sub[3]...)
+		})
+	}
+}
+
+// offlineTransform returns a write.Transform which applies relativeLinks
+// to the pages and stylesheets written to servingDir.
+func offlineTransform(servingDir, basePath string) func(dest string, content []byte) []byte {
+	relative := relativeLinks(basePath)
+	return func(dest string, content []byte) []byte {
+		rel, err := filepath.Rel(servingDir, dest)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return content
+		}
+		page := strings.TrimSuffix(filepath.ToSlash(rel), ".gz")
+		if ext := path.Ext(page); ext != ".html" && ext != ".css" {
+			return content
+		}
+		return relative(page, content)
+	}
+}
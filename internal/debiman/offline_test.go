@@ -0,0 +1,215 @@
+package debiman
+
+import (
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/Debian/debiman/internal/manpage"
+	"github.com/Debian/debiman/internal/write"
+	"golang.org/x/net/html"
+)
+
+func TestRelativeURL(t *testing.T) {
+	for _, entry := range []struct {
+		from, to string
+		want     string
+	}{
+		{"index.html", "contents-jessie.html", "contents-jessie.html"},
+		{"index.html", "jessie/i3-wm/i3.1.en.html", "jessie/i3-wm/i3.1.en.html"},
+		{"jessie/i3-wm/i3.1.en.html", "index.html", "../../index.html"},
+		{"jessie/i3-wm/i3.1.en.html", "jessie/i3-wm/i3-msg.1.en.html", "i3-msg.1.en.html"},
+		{"jessie/i3-wm/i3.1.en.html", "jessie/i3lock/i3lock.1.en.html", "../i3lock/i3lock.1.en.html"},
+		{"jessie/i3-wm/i3.1.en.html", "stretch/i3-wm/i3.1.en.html", "../../stretch/i3-wm/i3.1.en.html"},
+		{"jessie/i3-wm/i3.1.en.html", "jessie/i3-wm/i3.1.en.html", "i3.1.en.html"},
+		{"jessie/man1/a.html", "jessie/files.html", "../files.html"},
+		// A file named like a directory of the page is not a directory.
+		{"jessie/i3-wm/i3.1.en.html", "jessie", "../../jessie"},
+	} {
+		if got := relativeURL(entry.from, entry.to); got != entry.want {
+			t.Errorf("relativeURL(%q, %q) = %q, want %q", entry.from, entry.to, got, entry.want)
+		}
+	}
+}
+
+func TestRelativeLinks(t *testing.T) {
+	for _, entry := range []struct {
+		basePath string
+		page     string
+		content  string
+		want     string
+	}{
+		{
+			page:    "jessie/i3-wm/i3.1.en.html",
+			content: `<a href="/jessie/i3-wm/i3-msg.1.en.html#SYNOPSIS">i3-msg</a>`,
+			want:    `<a href="i3-msg.1.en.html#SYNOPSIS">i3-msg</a>`,
+		},
+		{
+			page:    "jessie/i3-wm/i3.1.en.html",
+			content: `<a href="/">Index</a> <a href="/jessie/">jessie</a>`,
+			want:    `<a href="../../index.html">Index</a> <a href="../index.html">jessie</a>`,
+		},
+		{
+			page:    "jessie/i3-wm/i3.1.en.html",
+			content: `<link rel="icon" href="/favicon.ico?0123abcd"><form action="/jump">`,
+			want:    `<link rel="icon" href="../../favicon.ico?0123abcd"><form action="../../jump">`,
+		},
+		{
+			page:    "style.css",
+			content: `src: url(/Inconsolata.woff2) format('woff2')`,
+			want:    `src: url(Inconsolata.woff2) format('woff2')`,
+		},
+		{
+			page:    "jessie/i3-wm/i3.1.en.html",
+			content: `<a href="https://tracker.debian.org/pkg/i3-wm">tracker</a> <a href="//example.com/">x</a> <a href="#SYNOPSIS">y</a>`,
+			want:    `<a href="https://tracker.debian.org/pkg/i3-wm">tracker</a> <a href="//example.com/">x</a> <a href="#SYNOPSIS">y</a>`,
+		},
+		{
+			basePath: "/sub",
+			page:     "jessie/i3-wm/i3.1.en.html",
+			content:  `<a href="/sub/jessie/i3-wm/index.html">i3-wm</a> <a href="/subway/x.html">x</a>`,
+			want:     `<a href="index.html">i3-wm</a> <a href="/subway/x.html">x</a>`,
+		},
+	} {
+		if got := string(relativeLinks(entry.basePath)(entry.page, []byte(entry.content))); got != entry.want {
+			t.Errorf("relativeLinks(%q)(%q, %q) = %q, want %q", entry.basePath, entry.page, entry.content, got, entry.want)
+		}
+	}
+}
+
+// cssURL matches the URLs of stylesheets.
+var cssURL = regexp.MustCompile(`url\(([^)]*)\)`)
+
+// localLinks returns the links (including stylesheet URLs) of the page
+// at path which do not refer to other hosts, without their fragment.
+func localLinks(t *testing.T, path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var links []string
+	z := html.NewTokenizer(f)
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if z.Err() != io.EOF {
+				t.Fatal(z.Err())
+			}
+			break
+		}
+		switch tt {
+		case html.TextToken:
+			for _, m := range cssURL.FindAllSubmatch(z.Text(), -1) {
+				links = append(links, string(m[1]))
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			for {
+				key, val, more := z.TagAttr()
+				if k := string(key); k == "href" || k == "src" || k == "action" {
+					links = append(links, string(val))
+				}
+				if !more {
+					break
+				}
+			}
+		}
+	}
+	var local []string
+	for _, l := range links {
+		if strings.HasPrefix(l, "#") || strings.Contains(l, "://") || strings.HasPrefix(l, "//") {
+			continue
+		}
+		local = append(local, strings.SplitN(l, "#", 2)[0])
+	}
+	return local
+}
+
+// TestOfflinePages renders the pages of a tiny serving directory with
+// -offline and opens them from disk, like a browser would via file://:
+// all their links must refer to files in the serving directory.
+func TestOfflinePages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "debiman-offline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	serving := filepath.Join(dir, "serving")
+
+	oldServingDir, oldOffline, oldPlain, oldTransform, oldBugReport := *servingDir, *offline, write.PlainVariant, write.Transform, bugReportTmpl
+	defer func() {
+		*servingDir, *offline, write.PlainVariant, write.Transform, bugReportTmpl = oldServingDir, oldOffline, oldPlain, oldTransform, oldBugReport
+	}()
+	*servingDir = serving
+	*offline = true
+	write.PlainVariant = encodedVariant
+	write.Transform = offlineTransform(serving, "")
+	bugReportTmpl = nil
+
+	samples, err := selftestSamples()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pages, err := renderSelftest(nil, serving, samples, func(m *manpage.Meta) string {
+		return writeSelftestReusePage(t, dir, m)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := samples[0].meta
+	dest := filepath.Join(serving, m.ServingPath()+".html.gz")
+	if err := write.Atomically(dest, true, func(w io.Writer) error {
+		_, err := w.Write(pages[0])
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := renderPkgindex(filepath.Join(serving, "selftest", "debiman-selftest", "index.html.gz"), map[string]*manpage.Meta{"i3lock.1": m}); err != nil {
+		t.Fatal(err)
+	}
+	if err := renderContents(filepath.Join(serving, "contents-selftest.html.gz"), "selftest", []string{"debiman-selftest"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := renderFiles(filepath.Join(serving, "selftest"), "selftest", map[string][]*manpage.Meta{"1": {m}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := renderAux(serving, globalView{suites: map[string]bool{"selftest": true}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, page := range []string{
+		m.ServingPath() + ".html",
+		"selftest/debiman-selftest/index.html",
+		"contents-selftest.html",
+		"selftest/files.html",
+		"selftest/" + filesPageName("1", 1),
+		"index.html",
+		"style.css",
+	} {
+		path := filepath.Join(serving, filepath.FromSlash(page))
+		base := &url.URL{Scheme: "file", Path: filepath.ToSlash(path)}
+		links := localLinks(t, path)
+		if len(links) == 0 {
+			t.Errorf("%s: no links found", page)
+		}
+		for _, l := range links {
+			if strings.HasPrefix(l, "/") {
+				t.Errorf("%s: link %q is not relative", page, l)
+				continue
+			}
+			u, err := base.Parse(l)
+			if err != nil {
+				t.Errorf("%s: link %q: %v", page, l, err)
+				continue
+			}
+			if _, err := os.Stat(filepath.FromSlash(u.Path)); err != nil {
+				t.Errorf("%s: link %q does not resolve to a file: %v", page, l, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package debiman
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+)
+
+var dropOrphanedEntries = flag.Bool("drop_orphaned_index_entries",
+	false,
+	"Omit index entries whose rendered manpage is missing in -serving_dir (e.g. because rendering failed) from the auxserver index, so that debiman-auxserver does not redirect to pages which result in HTTP 404. Orphaned entries are always logged and counted")
+
+// orphaned returns whether the rendered HTML of the manpage at
+// servingPath is missing in servingDir. Dangling symlinks count as
+// missing.
+func orphaned(servingDir, servingPath string) bool {
+	_, err := os.Stat(filepath.Join(servingDir, servingPath+".html.gz"))
+	return os.IsNotExist(err)
+}
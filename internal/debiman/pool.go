@@ -0,0 +1,217 @@
+package debiman
+
+import (
+	"expvar"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	concurrency = flag.Int("concurrency",
+		0,
+		"If positive, the number of slots of work (downloading and extracting a package, converting a manpage, compressing and writing a manpage) which may be in flight at once, shared across all phases, e.g. the number of CPU cores. The -concurrency_* flags still determine the number of workers per phase, but workers wait for a free slot. 0 disables the limit")
+
+	concurrencyWeights = flag.String("concurrency_weights",
+		"",
+		"Comma-separated list of phase=weight pairs, e.g. “render=2,write=1”: the number of -concurrency slots each unit of work of the phase (download, render or write) occupies. Phases default to weight 1")
+)
+
+// The phases of work which share the workerPool.
+const (
+	phaseDownload = "download"
+	phaseRender   = "render"
+	phaseWrite    = "write"
+)
+
+var poolPhases = []string{phaseDownload, phaseRender, phaseWrite}
+
+// workerPool limits the work in flight across all phases to a number
+// of slots (see -concurrency). A nil *workerPool does not limit work.
+type workerPool struct {
+	size    int
+	weights map[string]int
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	used    int
+	usedMax int
+	since   time.Time                // of the last change of used
+	busy    time.Duration            // used slots integrated over time
+	wait    map[string]time.Duration // waiting for slots, by phase
+}
+
+// parseWeights parses -concurrency_weights.
+func parseWeights(spec string) (map[string]int, error) {
+	weights := make(map[string]int, len(poolPhases))
+	for _, phase := range poolPhases {
+		weights[phase] = 1
+	}
+	for _, pair := range strings.Split(spec, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		parts := strings.Split(pair, "=")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%q: expected phase=weight", pair)
+		}
+		phase := strings.TrimSpace(parts[0])
+		if _, ok := weights[phase]; !ok {
+			return nil, fmt.Errorf("%q: unknown phase %q, expected one of %s", pair, phase, strings.Join(poolPhases, ", "))
+		}
+		w, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || w < 1 {
+			return nil, fmt.Errorf("%q: weight must be a positive integer", pair)
+		}
+		weights[phase] = w
+	}
+	return weights, nil
+}
+
+// newWorkerPool returns a workerPool with size slots, or nil if size is
+// not positive. Weights larger than size are capped to size, so that
+// work of every phase can make progress.
+func newWorkerPool(size int, weights map[string]int) *workerPool {
+	if size <= 0 {
+		return nil
+	}
+	p := &workerPool{
+		size:    size,
+		weights: make(map[string]int, len(weights)),
+		since:   time.Now(),
+		wait:    make(map[string]time.Duration),
+	}
+	for phase, w := range weights {
+		if w > size {
+			w = size
+		}
+		p.weights[phase] = w
+	}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+// account must be called with p.mu held before changing p.used.
+func (p *workerPool) account(now time.Time) {
+	p.busy += time.Duration(p.used) * now.Sub(p.since)
+	p.since = now
+}
+
+// acquire blocks until the slots for a unit of work of phase are free
+// and returns a function which frees them.
+func (p *workerPool) acquire(phase string) (release func()) {
+	if p == nil {
+		return func() {}
+	}
+	w := p.weights[phase]
+	if w == 0 {
+		w = 1
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.used+w > p.size {
+		start := time.Now()
+		for p.used+w > p.size {
+			p.cond.Wait()
+		}
+		p.wait[phase] += time.Since(start)
+	}
+	p.account(time.Now())
+	p.used += w
+	if p.used > p.usedMax {
+		p.usedMax = p.used
+	}
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.account(time.Now())
+		p.used -= w
+		p.cond.Broadcast()
+	}
+}
+
+// snapshot returns the current utilization of the pool, see publish.
+func (p *workerPool) snapshot() interface{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.account(time.Now())
+	wait := make(map[string]float64, len(p.wait))
+	for phase, d := range p.wait {
+		wait[phase] = d.Seconds()
+	}
+	return struct {
+		Slots       int                `json:"slots"`
+		Busy        int                `json:"busy"`
+		BusyMax     int                `json:"busy_max"`
+		BusySeconds float64            `json:"busy_seconds"`
+		WaitSeconds map[string]float64 `json:"wait_seconds"`
+	}{
+		Slots:       p.size,
+		Busy:        p.used,
+		BusyMax:     p.usedMax,
+		BusySeconds: p.busy.Seconds(),
+		WaitSeconds: wait,
+	}
+}
+
+var (
+	publishOnce sync.Once
+	published   struct {
+		sync.Mutex
+		pool *workerPool
+	}
+)
+
+// publish makes the current utilization of the pool available as
+// worker_pool on /debug/vars of the debug HTTP listener.
+func (p *workerPool) publish() {
+	if p == nil {
+		return
+	}
+	published.Lock()
+	published.pool = p
+	published.Unlock()
+	// expvar.Publish panics when called twice for the same name.
+	publishOnce.Do(func() {
+		expvar.Publish("worker_pool", expvar.Func(func() interface{} {
+			published.Lock()
+			defer published.Unlock()
+			return published.pool.snapshot()
+		}))
+	})
+}
+
+// report copies the utilization of the pool into s.
+func (p *workerPool) report(s *stats) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.account(time.Now())
+	s.PoolSlots = uint64(p.size)
+	s.PoolBusyMax = uint64(p.usedMax)
+	s.PoolBusySeconds = uint64(p.busy.Seconds())
+	s.PoolWaitSeconds = make(map[string]uint64, len(poolPhases))
+	for _, phase := range poolPhases {
+		s.PoolWaitSeconds[phase] = uint64(p.wait[phase].Seconds())
+	}
+}
+
+// waitSummary formats s.PoolWaitSeconds for the end-of-run summary.
+func (s *stats) waitSummary() string {
+	phases := make([]string, 0, len(s.PoolWaitSeconds))
+	for phase := range s.PoolWaitSeconds {
+		phases = append(phases, phase)
+	}
+	sort.Strings(phases)
+	parts := make([]string, len(phases))
+	for i, phase := range phases {
+		parts[i] = fmt.Sprintf("%s %ds", phase, s.PoolWaitSeconds[phase])
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,87 @@
+package debiman
+
+import (
+	"expvar"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseWeights(t *testing.T) {
+	got, err := parseWeights(" render=2, write=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]int{"download": 1, "render": 2, "write": 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseWeights() = %v, want %v", got, want)
+	}
+	for _, spec := range []string{"render", "render=0", "render=x", "publish=1"} {
+		if _, err := parseWeights(spec); err == nil {
+			t.Errorf("parseWeights(%q) unexpectedly succeeded", spec)
+		}
+	}
+}
+
+func TestWorkerPool(t *testing.T) {
+	if newWorkerPool(0, nil) != nil {
+		t.Fatalf("newWorkerPool(0) should not limit work")
+	}
+	var unlimited *workerPool
+	unlimited.acquire(phaseRender)()
+
+	weights, err := parseWeights("render=2,write=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := newWorkerPool(3, weights)
+	var (
+		wg       sync.WaitGroup
+		inFlight int64
+		exceeded int64
+	)
+	for i := 0; i < 30; i++ {
+		phase := poolPhases[i%len(poolPhases)]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release := p.acquire(phase)
+			w := int64(p.weights[phase])
+			if atomic.AddInt64(&inFlight, w) > 3 {
+				atomic.StoreInt64(&exceeded, 1)
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt64(&inFlight, -w)
+			release()
+		}()
+	}
+	wg.Wait()
+	if exceeded != 0 {
+		t.Errorf("more than 3 slots were in use at once")
+	}
+
+	var s stats
+	p.report(&s)
+	// The write weight is capped to the size, so writes use all slots.
+	if got, want := s.PoolBusyMax, uint64(3); got != want {
+		t.Errorf("PoolBusyMax = %d, want %d", got, want)
+	}
+	if got, want := len(s.PoolWaitSeconds), len(poolPhases); got != want {
+		t.Errorf("len(PoolWaitSeconds) = %d, want %d", got, want)
+	}
+}
+
+func TestWorkerPoolPublish(t *testing.T) {
+	// Publishing multiple pools (e.g. one per run) must not panic.
+	newWorkerPool(1, nil).publish()
+	newWorkerPool(2, nil).publish()
+	v := expvar.Get("worker_pool")
+	if v == nil {
+		t.Fatalf("worker_pool not published")
+	}
+	if got := v.String(); !strings.Contains(got, `"slots":2`) {
+		t.Errorf("worker_pool = %s, want slots 2", got)
+	}
+}
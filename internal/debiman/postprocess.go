@@ -0,0 +1,48 @@
+package debiman
+
+import (
+	"flag"
+	"strings"
+
+	"github.com/Debian/debiman/internal/convert"
+)
+
+// defaultPostProcessors are the names of convert.DefaultPostProcessors.
+const defaultPostProcessors = "xref,anchors,sanitize"
+
+var postProcessors = flag.String("postprocessors",
+	defaultPostProcessors,
+	"Comma-separated list of the passes over the HTML generated by mandoc, in the order in which they are run: xref (link references to other manpages and URLs), anchors (heading ids, ¶ links and the table of contents) and sanitize (required for well-formed pages), plus any processors registered with convert.RegisterPostProcessor. Leave out a pass to disable it. Changing the list invalidates -render_cache entries; -force_rerender applies it to existing pages")
+
+// postProcessorNames returns the non-empty names of -postprocessors.
+func postProcessorNames() []string {
+	var names []string
+	for _, name := range strings.Split(*postProcessors, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// enabledPostProcessors are the post processors of -postprocessors, as
+// set by parseRunFlags.
+var enabledPostProcessors = convert.DefaultPostProcessors
+
+// newConverter starts a convert.Process which runs enabledPostProcessors.
+func newConverter() (*convert.Process, error) {
+	return convert.NewProcess(convert.WithPostProcessors(enabledPostProcessors))
+}
+
+// perManpagePostProcessing returns whether any of enabledPostProcessors
+// depends on the manpage being converted (see
+// convert.PostProcessor.PerManpage), in which case the render cache
+// must not be used.
+func perManpagePostProcessing() bool {
+	for _, p := range enabledPostProcessors {
+		if p.PerManpage {
+			return true
+		}
+	}
+	return false
+}
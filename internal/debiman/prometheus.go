@@ -0,0 +1,155 @@
+package debiman
+
+import (
+	"html/template"
+	"io"
+	"time"
+)
+
+const metricsTmplContent = `
+# HELP packages_total The total number of Debian binary packages processed.
+# TYPE packages_total gauge
+packages_total {{ .Packages }}
+
+# HELP suites_skipped Number of suites skipped because their Release is unchanged (see -only_changed_suites).
+# TYPE suites_skipped gauge
+suites_skipped {{ .SuitesSkipped }}
+
+# HELP packages_extracted Number of Debian binary packages from which manpages were extracted.
+# TYPE packages_extracted gauge
+packages_extracted {{ .Stats.PackagesExtracted }}
+
+# HELP packages_deleted Number of Debian binary packages deleted because they were no longer present.
+# TYPE packages_deleted gauge
+packages_deleted {{ .Stats.PackagesDeleted }}
+
+# HELP contents_divergent_packages Number of extracted packages whose manpages diverge from the Contents index (see -verify_contents).
+# TYPE contents_divergent_packages gauge
+contents_divergent_packages {{ .Stats.ContentsDivergent }}
+
+# HELP contents_discarded_packages Number of extracted packages discarded because they lack manpages listed in the Contents index (see -verify_contents=fail).
+# TYPE contents_discarded_packages gauge
+contents_discarded_packages {{ .Stats.ContentsDiscarded }}
+
+# HELP manpages_rendered Number of manpages rendered to HTML
+# TYPE manpages_rendered gauge
+manpages_rendered {{ .Stats.ManpagesRendered }}
+
+# HELP manpage_bytes Total number of bytes used by manpages (by format).
+# TYPE manpage_bytes gauge
+manpage_bytes{format="man"} {{ .Stats.ManpageBytes }}
+manpage_bytes{format="html"} {{ .Stats.HtmlBytes }}
+
+# HELP inlined_css_bytes Total number of (uncompressed) HTML bytes caused by -inline_css.
+# TYPE inlined_css_bytes gauge
+inlined_css_bytes {{ .Stats.InlinedCSSBytes }}
+
+# HELP downloads_resumed Number of .deb downloads which continued a partial download (see -partial_dir).
+# TYPE downloads_resumed gauge
+downloads_resumed {{ .Stats.DownloadsResumed }}
+
+# HELP manpage_cache_lookups Number of packages looked up in -manpage_cache (by result).
+# TYPE manpage_cache_lookups gauge
+manpage_cache_lookups{result="hit"} {{ .Stats.ManpageCacheHits }}
+manpage_cache_lookups{result="miss"} {{ .Stats.ManpageCacheMisses }}
+
+# HELP render_cache_lookups Number of manpage conversions looked up in the render cache (by result).
+# TYPE render_cache_lookups gauge
+render_cache_lookups{result="hit"} {{ .Stats.RenderCacheHits }}
+render_cache_lookups{result="miss"} {{ .Stats.RenderCacheMisses }}
+
+# HELP manpages_deduped Number of manpages replaced by a hard link to an equivalent manpage (see -dedup).
+# TYPE manpages_deduped gauge
+manpages_deduped{match="exact"} {{ .Stats.ManpagesDeduped }}
+manpages_deduped{match="normalized"} {{ .Stats.ManpagesDedupedNormalized }}
+
+# HELP write_queue_max Largest number of rendered manpages waiting to be written (see -write_queue).
+# TYPE write_queue_max gauge
+write_queue_max {{ .Stats.WriteQueueMax }}
+
+# HELP index_bytes Total number of bytes used for the auxserver index.
+# TYPE index_bytes gauge
+index_bytes {{ .Stats.IndexBytes }}
+
+# HELP index_entries_orphaned Number of auxserver index entries whose rendered manpage is missing (see -drop_orphaned_index_entries).
+# TYPE index_entries_orphaned gauge
+index_entries_orphaned {{ .Stats.IndexEntriesOrphaned }}
+
+# HELP manpage_aliases Number of manpages recorded as aliases of other manpages in the auxserver index, by whether their target exists (see -so_aliases).
+# TYPE manpage_aliases gauge
+manpage_aliases{broken="false"} {{ .Stats.Aliases }}
+manpage_aliases{broken="true"} {{ .Stats.AliasesBroken }}
+
+# HELP case_collisions Number of manpages not served because their file name differs only in case from another manpage.
+# TYPE case_collisions gauge
+case_collisions {{ .Stats.CaseCollisions }}
+
+# HELP manpages_too_large Number of manpages not written because their rendered size exceeds -max_output_bytes.
+# TYPE manpages_too_large gauge
+manpages_too_large {{ .Stats.ManpagesTooLarge }}
+
+# HELP manpages_empty Number of manpages rendered in this run whose text is (nearly) empty (see -min_text_length).
+# TYPE manpages_empty gauge
+manpages_empty {{ .Stats.ManpagesEmpty }}
+
+# HELP manpages_size_filtered Number of manpages not extracted because their source size is outside of -min_manpage_bytes and -max_manpage_bytes.
+# TYPE manpages_size_filtered gauge
+manpages_size_filtered {{ .Stats.ManpagesSizeFiltered }}
+
+# HELP files_published Number of files uploaded to or deleted from -publish_to (by operation).
+# TYPE files_published gauge
+files_published{op="put"} {{ .Stats.FilesPublished }}
+files_published{op="delete"} {{ .Stats.FilesUnpublished }}
+
+{{ if .Stats.PoolSlots -}}
+# HELP worker_pool_slots Number of slots of work which may be in flight at once (see -concurrency).
+# TYPE worker_pool_slots gauge
+worker_pool_slots {{ .Stats.PoolSlots }}
+
+# HELP worker_pool_busy_max Largest number of slots in use at once.
+# TYPE worker_pool_busy_max gauge
+worker_pool_busy_max {{ .Stats.PoolBusyMax }}
+
+# HELP worker_pool_busy_seconds Slots in use, integrated over the runtime (divide by slots and runtime for the utilization).
+# TYPE worker_pool_busy_seconds gauge
+worker_pool_busy_seconds {{ .Stats.PoolBusySeconds }}
+
+# HELP worker_pool_wait_seconds Time spent waiting for free slots (by phase).
+# TYPE worker_pool_wait_seconds gauge
+{{ range $phase, $seconds := .Stats.PoolWaitSeconds -}}
+worker_pool_wait_seconds{phase="{{ $phase }}"} {{ $seconds }}
+{{ end }}
+{{ end -}}
+# HELP mandoc_version_info Version of the mandoc binary used for rendering.
+# TYPE mandoc_version_info gauge
+mandoc_version_info{version="{{ .Stats.MandocVersion }}"} 1
+
+# HELP runtime Wall-clock runtime in seconds.
+# TYPE runtime gauge
+runtime {{ .Seconds }}
+
+# HELP last_successful_run Last successful run in seconds since the epoch.
+# TYPE last_successful_run gauge
+last_successful_run {{ .LastSuccessfulRun }}
+`
+
+var metricsTmpl = template.Must(template.New("metrics").Parse(metricsTmplContent))
+
+func writeMetrics(w io.Writer, gv globalView, start time.Time) error {
+	now := time.Now()
+	return metricsTmpl.Execute(w, struct {
+		Packages          int
+		SuitesSkipped     int
+		Stats             *stats
+		Now               time.Time
+		Seconds           int
+		LastSuccessfulRun int64
+	}{
+		Packages:          len(gv.pkgs),
+		SuitesSkipped:     len(gv.skippedSuites),
+		Stats:             gv.stats,
+		Now:               now,
+		Seconds:           int(now.Sub(start).Seconds()),
+		LastSuccessfulRun: now.Unix(),
+	})
+}
@@ -0,0 +1,240 @@
+package debiman
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Debian/debiman/internal/blob"
+	"github.com/Debian/debiman/internal/write"
+	"golang.org/x/net/context"
+	"golang.org/x/sync/errgroup"
+)
+
+var (
+	publishTo = flag.String("publish_to",
+		"",
+		"If non-empty, where to publish -serving_dir to at the end of each run: an S3 bucket (s3://bucket/prefix, optionally with ?region=…&endpoint=… for S3-compatible services; credentials are read from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN) or a local directory. Only files which changed since the last publish are uploaded")
+
+	publishConcurrency = flag.Int("concurrency_publish",
+		20,
+		"Concurrency level for uploading files to -publish_to")
+
+	publishContentTypes = flag.String("content_types",
+		"",
+		"Comma-separated list of extension=type pairs (e.g. “.txt=text/plain; charset=us-ascii”) overriding the Content-Type of files published to -publish_to. See “Security headers and content types” in README.md")
+)
+
+// publishedFile is what publish remembers about each file it
+// published, so that the next run only uploads changed files.
+type publishedFile struct {
+	Size    int64
+	ModTime int64  // Unix nanoseconds
+	Link    string `json:",omitempty"` // symlink target, if any
+}
+
+// publishManifestPath returns the path of the manifest of files
+// published to target. Dot files are not published themselves.
+func publishManifestPath(servingDir, target string) string {
+	h := sha256.Sum256([]byte(target))
+	return filepath.Join(servingDir, ".published-"+hex.EncodeToString(h[:4])+".json.gz")
+}
+
+func readPublishManifest(path string) (map[string]publishedFile, error) {
+	manifest := make(map[string]publishedFile)
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return manifest, nil // first publish
+		}
+		return nil, err
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	if err := json.NewDecoder(r).Decode(&manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// scanServingDir returns the current state of all files in servingDir,
+// keyed by their slash-separated path. Symlinks are represented by the
+// file they point to, as object stores do not support symlinks.
+func scanServingDir(servingDir string) (map[string]publishedFile, error) {
+	files := make(map[string]publishedFile)
+	err := filepath.Walk(servingDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(info.Name(), ".") && path != servingDir {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() {
+			return nil
+		}
+		var link string
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+			if info, err = os.Stat(path); err != nil {
+				log.Printf("WARNING: not publishing dangling symlink %q: %v", path, err)
+				return nil
+			}
+			if info.IsDir() {
+				return nil
+			}
+		}
+		rel, err := filepath.Rel(servingDir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = publishedFile{
+			Size:    info.Size(),
+			ModTime: info.ModTime().UnixNano(),
+			Link:    link,
+		}
+		return nil
+	})
+	return files, err
+}
+
+// describe returns the blob.Object for name, marking it as shadowed if
+// files also contains its uncompressed variant (or, for a zstd variant,
+// its gzip variant).
+func describe(files map[string]publishedFile, name string) blob.Object {
+	o := blob.Describe(name)
+	if o.Key != o.Name {
+		_, o.Shadowed = files[o.Key]
+		if _, ok := files[o.Key+".gz"]; ok && o.ContentEncoding == "zstd" {
+			o.Shadowed = true
+		}
+	}
+	return o
+}
+
+// byDepth sorts deeper paths first, so that manpages are published
+// before the index pages linking to them.
+type byDepth []string
+
+func (p byDepth) Len() int      { return len(p) }
+func (p byDepth) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p byDepth) Less(i, j int) bool {
+	di, dj := strings.Count(p[i], "/"), strings.Count(p[j], "/")
+	if di != dj {
+		return di > dj
+	}
+	return p[i] < p[j]
+}
+
+// publish uploads all files of servingDir which changed since the last
+// publish to st, then deletes the files which no longer exist. The
+// manifest of published files is kept in servingDir, so that removing
+// it results in all files being uploaded again.
+func publish(servingDir, target string, st blob.Store, stats *stats) error {
+	manifestPath := publishManifestPath(servingDir, target)
+	published, err := readPublishManifest(manifestPath)
+	if err != nil {
+		return fmt.Errorf("reading %q: %v", manifestPath, err)
+	}
+	files, err := scanServingDir(servingDir)
+	if err != nil {
+		return err
+	}
+
+	var changed, deleted []string
+	for name, f := range files {
+		if prev, ok := published[name]; !ok || prev != f {
+			changed = append(changed, name)
+		}
+	}
+	for name := range published {
+		if _, ok := files[name]; !ok {
+			deleted = append(deleted, name)
+		}
+	}
+	sort.Sort(byDepth(changed))
+	sort.Strings(deleted)
+	log.Printf("Publishing %d changed files (of %d) to %q, deleting %d files", len(changed), len(files), target, len(deleted))
+
+	var mu sync.Mutex
+	work := make(chan string)
+	eg, ctx := errgroup.WithContext(context.Background())
+	for i := 0; i < *publishConcurrency; i++ {
+		eg.Go(func() error {
+			for name := range work {
+				if err := publishFile(servingDir, st, describe(files, name)); err != nil {
+					return err
+				}
+				mu.Lock()
+				published[name] = files[name]
+				mu.Unlock()
+				atomic.AddUint64(&stats.FilesPublished, 1)
+			}
+			return nil
+		})
+	}
+	eg.Go(func() error {
+		defer close(work)
+		for _, name := range changed {
+			select {
+			case work <- name:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return nil
+	})
+	err = eg.Wait()
+	if err == nil {
+		// Delete only after all uploads succeeded, so that published
+		// pages do not link to deleted pages in the meantime.
+		for _, name := range deleted {
+			if err = st.Delete(describe(published, name)); err != nil {
+				err = fmt.Errorf("deleting %q: %v", name, err)
+				break
+			}
+			delete(published, name)
+			atomic.AddUint64(&stats.FilesUnpublished, 1)
+		}
+	}
+
+	// Record the progress even if publishing failed, so that the next
+	// run does not need to start over.
+	if werr := write.Atomically(manifestPath, true, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(published)
+	}); werr != nil && err == nil {
+		err = fmt.Errorf("writing %q: %v", manifestPath, werr)
+	}
+	return err
+}
+
+func publishFile(servingDir string, st blob.Store, o blob.Object) error {
+	f, err := os.Open(filepath.Join(servingDir, filepath.FromSlash(o.Name)))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := st.Put(o, f); err != nil {
+		return fmt.Errorf("publishing %q: %v", o.Name, err)
+	}
+	return nil
+}
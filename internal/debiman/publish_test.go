@@ -0,0 +1,144 @@
+package debiman
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Debian/debiman/internal/blob"
+)
+
+// recordingStore records the names of the objects it was asked to put
+// and delete.
+type recordingStore struct {
+	mu      sync.Mutex
+	put     []string
+	deleted []string
+}
+
+func (s *recordingStore) Put(o blob.Object, content io.Reader) error {
+	if _, err := ioutil.ReadAll(content); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !o.Shadowed {
+		s.put = append(s.put, o.Key)
+	}
+	return nil
+}
+
+func (s *recordingStore) Delete(o blob.Object) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deleted = append(s.deleted, o.Key)
+	return nil
+}
+
+func (s *recordingStore) reset() (put, deleted []string) {
+	put, deleted = s.put, s.deleted
+	sort.Strings(put)
+	s.put, s.deleted = nil, nil
+	return put, deleted
+}
+
+func TestPublish(t *testing.T) {
+	servingDir, err := ioutil.TempDir("", "debiman-publish")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(servingDir)
+
+	for name, content := range map[string]string{
+		"jessie/cron/crontab.5.en.html.gz":  "rendered",
+		"jessie/cron/crontab.5.en.html.zst": "rendered (zstd)",
+		"jessie/cron/crontab.5.en.gz":       "raw",
+		"contents-jessie.html.gz":           "contents",
+		"manifest.webmanifest":              "{}",
+		"manifest.webmanifest.gz":           "{} (compressed)",
+	} {
+		path := filepath.Join(servingDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("../contents-jessie.html.gz", filepath.Join(servingDir, "jessie", "index.html.gz")); err != nil {
+		t.Fatal(err)
+	}
+
+	const target = "s3://manpages"
+	var st recordingStore
+	var stats stats
+	if err := publish(servingDir, target, &st, &stats); err != nil {
+		t.Fatal(err)
+	}
+	put, deleted := st.reset()
+	wantPut := []string{
+		"contents-jessie.html",
+		"jessie/cron/crontab.5.en.gz",
+		"jessie/cron/crontab.5.en.html",
+		"jessie/index.html",
+		"manifest.webmanifest",
+	}
+	if !reflect.DeepEqual(put, wantPut) || len(deleted) != 0 {
+		t.Fatalf("unexpected first publish: put %v, deleted %v, want put %v", put, deleted, wantPut)
+	}
+
+	// Publishing again without changes must not upload anything.
+	if err := publish(servingDir, target, &st, &stats); err != nil {
+		t.Fatal(err)
+	}
+	if put, deleted := st.reset(); len(put) != 0 || len(deleted) != 0 {
+		t.Fatalf("unexpected publish without changes: put %v, deleted %v", put, deleted)
+	}
+
+	// Re-render one manpage, remove another.
+	rendered := filepath.Join(servingDir, "jessie", "cron", "crontab.5.en.html.gz")
+	if err := ioutil.WriteFile(rendered, []byte("re-rendered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(1 * time.Hour)
+	if err := os.Chtimes(rendered, future, future); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(servingDir, "jessie", "cron", "crontab.5.en.gz")); err != nil {
+		t.Fatal(err)
+	}
+	if err := publish(servingDir, target, &st, &stats); err != nil {
+		t.Fatal(err)
+	}
+	put, deleted = st.reset()
+	if want := []string{"jessie/cron/crontab.5.en.html"}; !reflect.DeepEqual(put, want) {
+		t.Fatalf("unexpected files re-published: got %v, want %v", put, want)
+	}
+	if want := []string{"jessie/cron/crontab.5.en.gz"}; !reflect.DeepEqual(deleted, want) {
+		t.Fatalf("unexpected files deleted: got %v, want %v", deleted, want)
+	}
+
+	// The first publish includes the shadowed manifest.webmanifest.gz
+	// and crontab.5.en.html.zst.
+	if got, want := stats.FilesPublished, uint64(len(wantPut)+3); got != want {
+		t.Fatalf("unexpected FilesPublished: got %d, want %d", got, want)
+	}
+	if got, want := stats.FilesUnpublished, uint64(1); got != want {
+		t.Fatalf("unexpected FilesUnpublished: got %d, want %d", got, want)
+	}
+
+	// A different target has its own manifest (crontab.5.en.gz was
+	// removed in the meantime).
+	if err := publish(servingDir, "/srv/man-mirror", &st, &stats); err != nil {
+		t.Fatal(err)
+	}
+	if put, _ := st.reset(); len(put) != len(wantPut)-1 {
+		t.Fatalf("unexpected publish to a new target: put %v", put)
+	}
+}
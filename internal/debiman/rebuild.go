@@ -0,0 +1,118 @@
+package debiman
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/Debian/debiman/internal/manpage"
+	pb "github.com/Debian/debiman/internal/proto"
+	"github.com/Debian/debiman/internal/redirect"
+)
+
+// stringList is a flag.Value for flags which can be specified more
+// than once.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+var rebuildPackages stringList
+
+func init() {
+	flag.Var(&rebuildPackages, "rebuild_package",
+		"Binary package to rebuild, e.g. after fixing how its manpages are rendered (may be specified more than once). Only these packages are extracted and rendered again, in all synchronized suites and at their current versions on the mirror (even if they did not change), and only their entries of the existing auxserver index (see -index) are replaced: the entries of all other packages are kept as they are. Suite-wide pages (contents, section indexes, sitemaps, news, …), build-info.json, metrics.txt and the state of -only_changed_suites are not updated, so run debiman without this flag to pick up other changes")
+}
+
+// restrictToRebuild restricts gv to the packages of -rebuild_package
+// and reads the index (at indexPath) whose other entries are kept.
+func restrictToRebuild(gv *globalView, indexPath string) error {
+	gv.rebuild = make(map[string]bool, len(rebuildPackages))
+	for _, name := range rebuildPackages {
+		gv.rebuild[manpage.URLCase.Path(name)] = true
+	}
+
+	found := make(map[string]bool)
+	var pkgs []*pkgEntry
+	for _, p := range gv.pkgs {
+		if name := manpage.URLCase.Path(p.binarypkg); gv.rebuild[name] {
+			pkgs = append(pkgs, p)
+			found[name] = true
+		}
+	}
+	var missing []string
+	for name := range gv.rebuild {
+		if !found[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("no manpages of %s found in any suite", strings.Join(missing, ", "))
+	}
+	log.Printf("Restricting the run to %d binary packages (in all suites) of -rebuild_package=%s", len(pkgs), rebuildPackages.String())
+	gv.pkgs = pkgs
+
+	idx, err := redirect.IndexFromProto(indexPath)
+	if err != nil {
+		return fmt.Errorf("reading the index to update: %v", err)
+	}
+	if idx.URLCase != manpage.URLCase {
+		return fmt.Errorf("the index %q uses -url_case=%v, not %v: run debiman without -rebuild_package", indexPath, idx.URLCase, manpage.URLCase)
+	}
+	gv.rebuildIndex = idx
+	return nil
+}
+
+// writeKeptEntries writes the entries of gv.rebuildIndex which do not
+// belong to the packages of -rebuild_package (or to suites which are
+// no longer synchronized) to iw, noting their languages and sections.
+func writeKeptEntries(iw *pb.IndexWriter, gv globalView, langs, sections map[string]bool) error {
+	suites := make(map[string]bool, len(gv.suites))
+	for suite := range gv.suites {
+		suites[manpage.URLCase.Path(suite)] = true
+	}
+	names := make([]string, 0, len(gv.rebuildIndex.Entries))
+	for name := range gv.rebuildIndex.Entries {
+		names = append(names, name)
+	}
+	sort.Strings(names) // for a deterministic index
+	for _, name := range names {
+		for _, e := range gv.rebuildIndex.Entries[name] {
+			if gv.rebuild[e.Binarypkg] || !suites[e.Suite] {
+				continue // replaced or pruned
+			}
+			if err := iw.WriteEntry(&pb.IndexEntry{
+				Name:      e.Name,
+				Suite:     e.Suite,
+				Binarypkg: e.Binarypkg,
+				Section:   e.Section,
+				Language:  e.Language,
+
+				Architecture: e.Architecture,
+				Target:       e.Target,
+			}); err != nil {
+				return err
+			}
+			langs[e.Language] = true
+			sections[e.Section] = true
+			sections[e.Section[:1]] = true
+		}
+	}
+	return nil
+}
+
+// rerenderAll returns whether all manpages which are walked are to be
+// rendered, regardless of whether they are up to date: with
+// -force_rerender, and for the packages of -rebuild_package.
+func (gv globalView) rerenderAll() bool {
+	return *forceRerender || gv.rebuild != nil
+}
@@ -0,0 +1,118 @@
+package debiman
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/Debian/debiman/internal/manpage"
+	"github.com/Debian/debiman/internal/redirect"
+)
+
+func TestRebuildPackage(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "debiman-rebuild")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	oldServingDir := *servingDir
+	defer func() { *servingDir = oldServingDir }()
+	*servingDir = tmpdir
+
+	render := func(servingPaths ...string) []*manpage.Meta {
+		var metas []*manpage.Meta
+		for _, sp := range servingPaths {
+			m := mustParseFromServingPath(t, sp)
+			fn := filepath.Join(tmpdir, m.ServingPath()+".html.gz")
+			if err := os.MkdirAll(filepath.Dir(fn), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(fn, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+			metas = append(metas, m)
+		}
+		return metas
+	}
+
+	// The previous run indexed three packages.
+	dest := filepath.Join(tmpdir, "auxserver.idx")
+	if err := writeIndex(dest, globalView{
+		xref: map[string][]*manpage.Meta{
+			"crontab": render("jessie/cron/crontab.5.en", "jessie/systemd-cron/crontab.5.en"),
+			"i3":      render("jessie/i3-wm/i3.1.en"),
+		},
+		suites:    map[string]bool{"jessie": true},
+		idxSuites: map[string]string{"jessie": "jessie"},
+		stats:     new(stats),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// In the mirror, cron now ships crontab(8) in addition, and
+	// systemd-cron crontab(1), which is not extracted because only
+	// cron is rebuilt.
+	gv := globalView{
+		pkgs: []*pkgEntry{
+			{suite: "jessie", binarypkg: "cron"},
+			{suite: "jessie", binarypkg: "systemd-cron"},
+			{suite: "jessie", binarypkg: "i3-wm"},
+		},
+		xref: map[string][]*manpage.Meta{
+			"crontab": append(render("jessie/cron/crontab.5.en", "jessie/cron/crontab.8.en"),
+				mustParseFromServingPath(t, "jessie/systemd-cron/crontab.1.en")),
+			"i3": {mustParseFromServingPath(t, "jessie/i3-wm/i3.1.en")},
+		},
+		suites:    map[string]bool{"jessie": true},
+		idxSuites: map[string]string{"jessie": "jessie"},
+		stats:     new(stats),
+	}
+
+	defer func(old stringList) { rebuildPackages = old }(rebuildPackages)
+	rebuildPackages = stringList{"cron", "nonexistent"}
+	if err := restrictToRebuild(&gv, dest); err == nil {
+		t.Errorf("restrictToRebuild unexpectedly succeeded for a package without manpages")
+	}
+
+	rebuildPackages = stringList{"cron"}
+	if err := restrictToRebuild(&gv, dest); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(gv.pkgs), 1; got != want {
+		t.Fatalf("restrictToRebuild: got %d packages, want %d", got, want)
+	}
+	if !gv.rerenderAll() {
+		t.Errorf("rerenderAll unexpectedly false for -rebuild_package")
+	}
+	if err := writeIndex(dest, gv); err != nil {
+		t.Fatal(err)
+	}
+
+	idx, err := redirect.IndexFromProto(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, entries := range idx.Entries {
+		for _, e := range entries {
+			got = append(got, e.ServingPath(""))
+		}
+	}
+	sort.Strings(got)
+	want := []string{
+		"/jessie/cron/crontab.5.en",
+		"/jessie/cron/crontab.8.en",
+		"/jessie/i3-wm/i3.1.en",
+		"/jessie/systemd-cron/crontab.5.en",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected index entries after rebuilding cron: got %q, want %q", got, want)
+	}
+	if !idx.Sections["8"] || !idx.Langs["en"] {
+		t.Errorf("index lacks section 8 or language en: %v, %v", idx.Sections, idx.Langs)
+	}
+}
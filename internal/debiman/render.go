@@ -1,4 +1,4 @@
-package main
+package debiman
 
 import (
 	"compress/gzip"
@@ -25,6 +25,7 @@ import (
 	"github.com/Debian/debiman/internal/write"
 	"golang.org/x/net/context"
 	"golang.org/x/sync/errgroup"
+	"golang.org/x/text/language"
 )
 
 var (
@@ -36,10 +37,50 @@ var (
 		5,
 		"Concurrency level for rendering manpages using mandoc")
 
+	writeConcurrency = flag.Int("write_concurrency",
+		5,
+		"Concurrency level for compressing and writing rendered manpages to disk. Tune independently from -concurrency_render, e.g. lower for spinning disks")
+
+	writeQueueLen = flag.Int("write_queue",
+		10,
+		"Number of rendered manpages which may be waiting to be written. When the queue is full, rendering blocks until the writers catch up")
+
+	minMandocVersion = flag.String("min_mandoc_version",
+		convert.MinMandocVersion,
+		"Oldest mandoc version to accept. debiman refuses to start when mandoc is missing or older")
+
+	mandocPath = flag.String("mandoc_path",
+		convert.Mandoc.Path,
+		"mandoc program to convert manpages with, either looked up in $PATH or a path (e.g. to a custom build). mandocd is used from the same directory, if present")
+
+	mandocArgs = flag.String("mandoc_args",
+		strings.Join(convert.DefaultArgs, " "),
+		"Space-separated arguments for mandoc to convert a manpage on stdin into an HTML fragment on stdout, e.g. to add -Ostyle=… or other -O options. mandocd is only used with the default arguments. debiman converts a test manpage at startup to verify the arguments")
+
 	gzipLevel = flag.Int("gzip",
 		9,
 		"gzip compression level to use for compressing HTML versions of manpages. defaults to 9 to keep network traffic minimal, but useful to reduce for development/disaster recovery (level 1 results in a 2x speedup!)")
 
+	inlineCSS = flag.Bool("inline_css",
+		false,
+		"Inline the stylesheet into each page’s <head> instead of linking to /style.css. Saves one HTTP request per page view, at the cost of browser cache reuse and a larger (pre-compressed) page size")
+
+	pageMetadata = flag.Bool("page_metadata",
+		false,
+		"If true, add meta tags (dcterms.license and dcterms.source) to the page of each manpage, linking to the copyright file and the snapshot of its source package version, e.g. for automated consumers (see -llms_txt)")
+
+	embedFragments = flag.Bool("embed_fragments",
+		false,
+		"Additionally write a .frag.html variant of each manpage, containing only the rendered manpage (without header, footer and navigation), for embedding into other sites. debiman-auxserver redirects requests with ?embed=1 to these variants")
+
+	minimalPages = flag.Bool("minimal_pages",
+		false,
+		"Additionally write a .min.html variant of each manpage for mobile and low-bandwidth readers: a small self-contained page (with a minimal inlined stylesheet and no JavaScript) without navigation panels and table of contents, whose cross-references point to other .min.html pages")
+
+	maxOutputBytes = flag.Int("max_output_bytes",
+		64<<20,
+		"Maximum size in bytes of a single rendered (uncompressed) manpage, checked for the page and each of its variants (see -embed_fragments, -minimal_pages and -emit_metadata) on its own. Larger pages (e.g. resulting from corrupted or malicious input) are not written, but logged and counted as failures. The largest legitimate manpages (e.g. ffmpeg-all(1)) are a few MB. 0 disables the limit")
+
 	baseURL = flag.String("base_url",
 		"https://manpages.debian.org",
 		"Base URL (without trailing slash) to the site. Used where absolute URLs are required, e.g. sitemaps.")
@@ -91,6 +132,57 @@ func (b breadcrumbs) ToJSON() template.HTML {
 	return template.HTML(jsonb)
 }
 
+// manpageArticle describes a manpage as a schema.org TechArticle for
+// search engines.
+type manpageArticle struct {
+	Meta     *manpage.Meta
+	Modified time.Time
+}
+
+func (a manpageArticle) ToJSON() template.HTML {
+	type softwareApplication struct {
+		Type    string `json:"@type"`
+		Name    string `json:"name"`
+		Version string `json:"softwareVersion,omitempty"`
+	}
+	type techArticle struct {
+		Context      string               `json:"@context"`
+		Type         string               `json:"@type"`
+		Name         string               `json:"name"`
+		Section      string               `json:"articleSection,omitempty"`
+		Description  string               `json:"description,omitempty"`
+		About        *softwareApplication `json:"about,omitempty"`
+		InLanguage   string               `json:"inLanguage,omitempty"`
+		DateModified string               `json:"dateModified,omitempty"`
+	}
+	m := a.Meta
+	ta := techArticle{
+		Context:     "http://schema.org",
+		Type:        "TechArticle",
+		Name:        fmt.Sprintf("%s(%s)", m.Name, m.Section),
+		Section:     m.Section,
+		Description: m.Package.Description,
+	}
+	if m.Package.Binarypkg != "" {
+		ta.About = &softwareApplication{
+			Type:    "SoftwareApplication",
+			Name:    m.Package.Binarypkg,
+			Version: m.Package.Version.String(),
+		}
+	}
+	if m.LanguageTag != language.Und {
+		ta.InLanguage = m.LanguageTag.String()
+	}
+	if !a.Modified.IsZero() {
+		ta.DateModified = a.Modified.UTC().Format(iso8601Format)
+	}
+	jsonb, err := json.Marshal(ta)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return template.HTML(jsonb)
+}
+
 var commonTmpls = commontmpl.MustParseCommonTmpls()
 
 type renderingMode int
@@ -139,7 +231,8 @@ func listManpages(dir string) (map[string]*manpage.Meta, error) {
 
 		for _, fn := range names {
 			if !strings.HasSuffix(fn, ".gz") ||
-				strings.HasSuffix(fn, ".html.gz") {
+				strings.HasSuffix(fn, ".html.gz") ||
+				strings.HasSuffix(fn, ".json.gz") { // -emit_metadata
 				continue
 			}
 			full := filepath.Join(dir, fn)
@@ -158,9 +251,9 @@ func listManpages(dir string) (map[string]*manpage.Meta, error) {
 	return manpageByName, nil
 }
 
-func renderDirectoryIndex(dir string, newestModTime time.Time) error {
+func renderDirectoryIndex(dir string, newestModTime time.Time, force bool) error {
 	st, err := os.Stat(filepath.Join(dir, "index.html.gz"))
-	if !*forceRerender && err == nil && st.ModTime().After(newestModTime) {
+	if !force && err == nil && st.ModTime().After(newestModTime) {
 		return nil
 	}
 
@@ -168,6 +261,14 @@ func renderDirectoryIndex(dir string, newestModTime time.Time) error {
 	if err != nil {
 		return err
 	}
+	metas := make([]*manpage.Meta, 0, len(manpageByName))
+	for _, m := range manpageByName {
+		metas = append(metas, m)
+	}
+	markGrouped(metas, func(m *manpage.Meta) bool {
+		_, alias := aliasOf(*servingDir, m.ServingPath())
+		return alias
+	})
 
 	if len(manpageByName) == 0 {
 		log.Printf("WARNING: empty directory %q, not generating package index", dir)
@@ -218,7 +319,8 @@ func walkManContents(ctx context.Context, renderChan chan<- renderJob, dir strin
 
 		for _, fn := range names {
 			if !strings.HasSuffix(fn, ".gz") ||
-				strings.HasSuffix(fn, ".html.gz") {
+				strings.HasSuffix(fn, ".html.gz") ||
+				strings.HasSuffix(fn, ".json.gz") { // -emit_metadata
 				continue
 			}
 			full := filepath.Join(dir, fn)
@@ -242,12 +344,21 @@ func walkManContents(ctx context.Context, renderChan chan<- renderJob, dir strin
 				continue
 			}
 
+			if symlink {
+				// Symlinks to aliases are aliases themselves, see
+				// -so_aliases.
+				rel := strings.TrimPrefix(full, filepath.Clean(*servingDir)+"/")
+				if _, ok := gv.aliases[strings.TrimSuffix(rel, ".gz")]; ok {
+					continue
+				}
+			}
+
 			n := strings.TrimSuffix(fn, ".gz") + ".html.gz"
 			htmlst, err := os.Stat(filepath.Join(dir, n))
 			if err == nil {
 				atomic.AddUint64(&gv.stats.HtmlBytes, uint64(htmlst.Size()))
 			}
-			if err != nil || *forceRerender || htmlst.ModTime().Before(st.ModTime()) {
+			if err != nil || gv.rerenderAll() || htmlst.ModTime().Before(st.ModTime()) {
 				m, err := manpage.FromServingPath(*servingDir, full)
 				if err != nil {
 					// If we run into this case, our code cannot correctly
@@ -270,9 +381,12 @@ func walkManContents(ctx context.Context, renderChan chan<- renderJob, dir strin
 				// Render dependent manpages first to properly resume
 				// in case debiman is interrupted.
 				for _, v := range versions {
-					if v == m || *forceRerender {
+					if v == m || gv.rerenderAll() {
 						continue
 					}
+					if _, ok := gv.aliases[v.ServingPath()]; ok {
+						continue // not rendered
+					}
 
 					vfull := filepath.Join(*servingDir, v.RawPath())
 					vfn := filepath.Join(*servingDir, v.ServingPath()+".html.gz")
@@ -297,13 +411,18 @@ func walkManContents(ctx context.Context, renderChan chan<- renderJob, dir strin
 
 					select {
 					case renderChan <- renderJob{
-						dest:     vfn,
-						src:      vfull,
-						meta:     v,
-						versions: versions,
-						xref:     gv.xref,
-						modTime:  vst.ModTime(),
-						reuse:    vreuse,
+						dest:         vfn,
+						src:          vfull,
+						meta:         v,
+						versions:     versions,
+						xref:         gv.xref,
+						aliases:      gv.aliases,
+						modTime:      vst.ModTime(),
+						reuse:        vreuse,
+						cache:        gv.renderCache,
+						latestSuite:  gv.latestSuite,
+						whatis:       gv.whatis,
+						descriptions: gv.descriptions,
 					}:
 					case <-ctx.Done():
 						break
@@ -311,7 +430,9 @@ func walkManContents(ctx context.Context, renderChan chan<- renderJob, dir strin
 				}
 
 				var reuse string
-				if symlink {
+				// The HTML of the symlink target was post processed
+				// for the target, see perManpagePostProcessing.
+				if symlink && !perManpagePostProcessing() {
 					link, err := os.Readlink(full)
 					if err == nil {
 						resolved := filepath.Join(dir, link)
@@ -321,13 +442,18 @@ func walkManContents(ctx context.Context, renderChan chan<- renderJob, dir strin
 
 				select {
 				case renderChan <- renderJob{
-					dest:     filepath.Join(dir, n),
-					src:      full,
-					meta:     m,
-					versions: versions,
-					xref:     gv.xref,
-					modTime:  st.ModTime(),
-					reuse:    reuse,
+					dest:         filepath.Join(dir, n),
+					src:          full,
+					meta:         m,
+					versions:     versions,
+					xref:         gv.xref,
+					aliases:      gv.aliases,
+					modTime:      st.ModTime(),
+					reuse:        reuse,
+					cache:        gv.renderCache,
+					latestSuite:  gv.latestSuite,
+					whatis:       gv.whatis,
+					descriptions: gv.descriptions,
 				}:
 				case <-ctx.Done():
 					break
@@ -353,6 +479,14 @@ func walkContents(ctx context.Context, renderChan chan<- renderJob, whitelist ma
 		if !gv.suites[sfi.Name()] {
 			continue
 		}
+		sitemapPath := filepath.Join(*servingDir, sfi.Name(), "sitemap.xml.gz")
+		if gv.skippedSuites[sfi.Name()] {
+			// Keep the sitemap of the previous run.
+			if st, err := os.Stat(sitemapPath); err == nil {
+				sitemaps[sfi.Name()] = st.ModTime()
+			}
+			continue
+		}
 		bins, err := os.Open(filepath.Join(*servingDir, sfi.Name()))
 		if err != nil {
 			return err
@@ -380,6 +514,9 @@ func walkContents(ctx context.Context, renderChan chan<- renderJob, whitelist ma
 				if whitelist != nil && !whitelist[bfn] {
 					continue
 				}
+				if isSectionIndexDir(bfn) {
+					continue
+				}
 
 				bfn := bfn // copy
 				dir := filepath.Join(*servingDir, sfi.Name(), bfn)
@@ -407,7 +544,7 @@ func walkContents(ctx context.Context, renderChan chan<- renderJob, whitelist ma
 
 					// and finally render the package index files which need to
 					// consider both regular files and symlinks.
-					if err := renderDirectoryIndex(dir, newestModTime); err != nil {
+					if err := renderDirectoryIndex(dir, newestModTime, gv.rerenderAll()); err != nil {
 						return err
 					}
 
@@ -426,7 +563,9 @@ func walkContents(ctx context.Context, renderChan chan<- renderJob, whitelist ma
 		}
 		bins.Close()
 
-		sitemapPath := filepath.Join(*servingDir, sfi.Name(), "sitemap.xml.gz")
+		if gv.rebuild != nil {
+			continue // keep the sitemap, see -rebuild_package
+		}
 		if err := write.Atomically(sitemapPath, true, func(w io.Writer) error {
 			return sitemap.WriteTo(w, *baseURL+"/"+sfi.Name(), sitemapEntries)
 		}); err != nil {
@@ -437,6 +576,9 @@ func walkContents(ctx context.Context, renderChan chan<- renderJob, whitelist ma
 			sitemaps[sfi.Name()] = st.ModTime()
 		}
 	}
+	if gv.rebuild != nil {
+		return nil
+	}
 	return write.Atomically(filepath.Join(*servingDir, "sitemapindex.xml.gz"), true, func(w io.Writer) error {
 		return sitemap.WriteIndexTo(w, *baseURL, sitemaps)
 	})
@@ -446,6 +588,9 @@ func writeSourceIndex(gv globalView, newestForSource map[string]time.Time) error
 	// Partition by suite for reduced memory usage and better locality of file
 	// system access
 	for suite := range gv.suites {
+		if gv.skippedSuites[suite] {
+			continue
+		}
 		binariesBySource := make(map[string][]string)
 		for _, p := range gv.pkgs {
 			if p.suite != suite {
@@ -493,6 +638,9 @@ func writeSourceIndex(gv globalView, newestForSource map[string]time.Time) error
 
 func writeSourcesWithManpages(gv globalView) error {
 	for suite := range gv.suites {
+		if gv.skippedSuites[suite] {
+			continue
+		}
 		hasManpages := make(map[string]bool)
 		for _, p := range gv.pkgs {
 			if p.suite != suite {
@@ -530,16 +678,68 @@ func renderAll(gv globalView) error {
 	}
 	log.Printf("%d sourceByBinary entries, %d newestForSource entries", len(sourceByBinary), len(newestForSource))
 
+	// cssBytes is the per-page size increase when inlining the
+	// stylesheet, which we track to help judge -inline_css.
+	var cssBytes countingWriter
+	if *inlineCSS {
+		if err := commonTmpls.ExecuteTemplate(&cssBytes, "style", nil); err != nil {
+			return err
+		}
+	}
+
 	eg, ctx := errgroup.WithContext(context.Background())
 	renderChan := make(chan renderJob)
+	// writeChan is bounded so that a slow disk applies backpressure
+	// to the renderers instead of accumulating rendered pages in
+	// memory.
+	writeChan := make(chan writeJob, *writeQueueLen)
+	var renderers sync.WaitGroup
 	for i := 0; i < *renderConcurrency; i++ {
+		renderers.Add(1)
 		eg.Go(func() error {
-			converter, err := convert.NewProcess()
+			defer renderers.Done()
+			converter, err := newConverter()
 			if err != nil {
 				return err
 			}
 			defer converter.Kill()
 
+			for r := range renderChan {
+				release := gv.pool.acquire(phaseRender)
+				done := gv.log.Timed("converting %s", r.dest)
+				wj, err := renderHTML(converter, r)
+				done()
+				release()
+				if tooLarge, ok := err.(*outputTooLargeError); ok {
+					log.Printf("WARNING: not writing %v", tooLarge)
+					atomic.AddUint64(&gv.stats.ManpagesTooLarge, 1)
+					continue
+				}
+				if err != nil {
+					// renderHTML renders an error page if mandoc
+					// failed, any returned error is severe and
+					// should lead to termination.
+					return err
+				}
+				if wj.empty {
+					atomic.AddUint64(&gv.stats.ManpagesEmpty, 1)
+				}
+				select {
+				case writeChan <- wj:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+				gv.stats.noteWriteQueueDepth(uint64(len(writeChan)))
+			}
+			return nil
+		})
+	}
+	go func() {
+		renderers.Wait()
+		close(writeChan)
+	}()
+	for i := 0; i < *writeConcurrency; i++ {
+		eg.Go(func() error {
 			// NOTE(stapelberg): gzip’s decompression phase takes the same
 			// time, regardless of compression level. Hence, we invest the
 			// maximum CPU time once to achieve the best compression.
@@ -548,16 +748,20 @@ func renderAll(gv globalView) error {
 				return err
 			}
 
-			for r := range renderChan {
-				n, err := rendermanpage(gzipw, converter, r)
+			for wj := range writeChan {
+				release := gv.pool.acquire(phaseWrite)
+				done := gv.log.Timed("writing %s", wj.dest)
+				n, err := wj.write(gzipw)
+				done()
+				release()
 				if err != nil {
-					// rendermanpage writes an error page if rendering
-					// failed, any returned error is severe (e.g. file
-					// system full) and should lead to termination.
+					// Write errors are severe (e.g. file system
+					// full) and should lead to termination.
 					return err
 				}
 
 				atomic.AddUint64(&gv.stats.HtmlBytes, n)
+				atomic.AddUint64(&gv.stats.InlinedCSSBytes, uint64(cssBytes))
 				atomic.AddUint64(&gv.stats.ManpagesRendered, 1)
 			}
 			return nil
@@ -565,7 +769,9 @@ func renderAll(gv globalView) error {
 	}
 
 	var whitelist map[string]bool
-	if *onlyRender != "" {
+	if gv.rebuild != nil {
+		whitelist = gv.rebuild
+	} else if *onlyRender != "" {
 		whitelist = make(map[string]bool)
 		log.Printf("Restricting rendering to the following binary packages:")
 		for _, e := range strings.Split(strings.TrimSpace(*onlyRender), ",") {
@@ -575,6 +781,16 @@ func renderAll(gv globalView) error {
 		log.Printf("(total: %d whitelist entries)", len(whitelist))
 	}
 
+	var err error
+	if gv.descriptions, err = loadDescriptions(*servingDir, gv.suites); err != nil {
+		return err
+	}
+	if *writeWhatis {
+		if gv.whatis, err = loadWhatisIndex(*servingDir, gv.suites); err != nil {
+			return err
+		}
+	}
+
 	if err := walkContents(ctx, renderChan, whitelist, gv); err != nil {
 		return err
 	}
@@ -584,6 +800,10 @@ func renderAll(gv globalView) error {
 		return err
 	}
 
+	if gv.rebuild != nil {
+		return nil // suite-wide pages are not updated, see -rebuild_package
+	}
+
 	if err := writeSourceIndex(gv, newestForSource); err != nil {
 		return fmt.Errorf("writing source index: %v", err)
 	}
@@ -596,11 +816,13 @@ func renderAll(gv globalView) error {
 	if err != nil {
 		return err
 	}
+	files := filesBySuite(gv.xref)
+	sections := manpagesBySuite(gv.xref, indexSections)
 	for _, sfi := range suitedirs {
 		if !sfi.IsDir() {
 			continue
 		}
-		if !gv.suites[sfi.Name()] {
+		if !gv.suites[sfi.Name()] || gv.skippedSuites[sfi.Name()] {
 			continue
 		}
 		bins, err := os.Open(filepath.Join(*servingDir, sfi.Name()))
@@ -614,10 +836,29 @@ func renderAll(gv globalView) error {
 			return err
 		}
 
-		if err := renderContents(filepath.Join(*servingDir, fmt.Sprintf("contents-%s.html.gz", sfi.Name())), sfi.Name(), names); err != nil {
+		bySection := sections[sfi.Name()]
+		if err := renderContents(filepath.Join(*servingDir, fmt.Sprintf("contents-%s.html.gz", sfi.Name())), sfi.Name(), names, indexedSections(bySection)); err != nil {
 			return err
 		}
 
+		if err := renderFiles(filepath.Join(*servingDir, sfi.Name()), sfi.Name(), files[sfi.Name()]); err != nil {
+			return err
+		}
+
+		if err := renderSectionIndexes(filepath.Join(*servingDir, sfi.Name()), sfi.Name(), bySection, gv.descriptions.description); err != nil {
+			return err
+		}
+
+		if err := gv.descriptions.write(descriptionsPath(*servingDir, sfi.Name()), sfi.Name(), gv.xref); err != nil {
+			return fmt.Errorf("writing descriptions: %v", err)
+		}
+
+		if gv.whatis != nil {
+			if err := gv.whatis.write(whatisPath(*servingDir, sfi.Name()), sfi.Name(), gv.xref); err != nil {
+				return fmt.Errorf("writing whatis index: %v", err)
+			}
+		}
+
 		bins.Close()
 	}
 
@@ -0,0 +1,56 @@
+package debiman
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"pault.ag/go/debian/version"
+)
+
+func TestBreadcrumbsToJSON(t *testing.T) {
+	const breadcrumbsJson = `{"@context":"http://schema.org","@type":"BreadcrumbList","itemListElement":[{"@type":"ListItem","position":1,"item":{"@type":"Thing","@id":"/contents-jessie.html","name":"jessie"}},{"@type":"ListItem","position":2,"item":{"@type":"Thing","@id":"/jessie/i3-wm/index.html","name":"i3-wm"}},{"@type":"ListItem","position":3,"item":{"@type":"Thing","@id":"","name":"i3(1)"}}]}`
+
+	const Suite = "jessie"
+	const Binarypkg = "i3-wm"
+	b := breadcrumbs{
+		{fmt.Sprintf("/contents-%s.html", Suite), Suite},
+		{fmt.Sprintf("/%s/%s/index.html", Suite, Binarypkg), Binarypkg},
+		{"", "i3(1)"},
+	}
+	if got, want := string(b.ToJSON()), breadcrumbsJson; got != want {
+		fmt.Printf("%s\n", got)
+		t.Fatalf("unexpected breadcrumbs JSON: got %q, want %q", got, want)
+	}
+}
+
+func TestManpageArticleToJSON(t *testing.T) {
+	m := mustParseFromServingPath(t, "jessie/i3-wm/i3.1.pt_BR")
+	v, err := version.Parse("4.8-2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Package.Version = v
+	m.Package.Description = "improved dynamic tiling window manager"
+	modified := time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	for _, entry := range []struct {
+		article manpageArticle
+		want    string
+	}{
+		{
+			article: manpageArticle{Meta: m, Modified: modified},
+			want:    `{"@context":"http://schema.org","@type":"TechArticle","name":"i3(1)","articleSection":"1","description":"improved dynamic tiling window manager","about":{"@type":"SoftwareApplication","name":"i3-wm","softwareVersion":"4.8-2"},"inLanguage":"pt-BR","dateModified":"2017-01-02T03:04:05Z"}`,
+		},
+
+		{
+			// Fields which are unknown are omitted.
+			article: manpageArticle{Meta: mustParseFromServingPath(t, "jessie/i3-wm/i3.1.en")},
+			want:    `{"@context":"http://schema.org","@type":"TechArticle","name":"i3(1)","articleSection":"1","about":{"@type":"SoftwareApplication","name":"i3-wm"},"inLanguage":"en"}`,
+		},
+	} {
+		if got, want := string(entry.article.ToJSON()), entry.want; got != want {
+			t.Errorf("unexpected article JSON: got %s, want %s", got, want)
+		}
+	}
+}
@@ -1,6 +1,7 @@
-package main
+package debiman
 
 import (
+	"bytes"
 	"fmt"
 	"html/template"
 	"io"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/Debian/debiman/internal/bundled"
 	"github.com/Debian/debiman/internal/manpage"
+	"github.com/Debian/debiman/internal/releases"
 	"github.com/Debian/debiman/internal/write"
 )
 
@@ -36,10 +38,10 @@ type bySuiteStr []string
 func (p bySuiteStr) Len() int      { return len(p) }
 func (p bySuiteStr) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
 func (p bySuiteStr) Less(i, j int) bool {
-	orderi, oki := sortOrder[p[i]]
-	orderj, okj := sortOrder[p[j]]
+	orderi, oki := releases.SortOrder[p[i]]
+	orderj, okj := releases.SortOrder[p[j]]
 	if !oki || !okj {
-		panic(fmt.Sprintf("either %q or %q is an unknown suite. known: %+v", p[i], p[j], sortOrder))
+		panic(fmt.Sprintf("either %q or %q is an unknown suite. known: %+v", p[i], p[j], releases.SortOrder))
 	}
 	return orderi < orderj
 }
@@ -101,8 +103,45 @@ func renderAux(destDir string, gv globalView) error {
 		return err
 	}
 
+	// style.css is a template (it refers to BaseURLPath), so it needs
+	// to be executed instead of copied verbatim.
+	var style bytes.Buffer
+	if err := commonTmpls.ExecuteTemplate(&style, "style", nil); err != nil {
+		return err
+	}
+	if err := write.Atomically(filepath.Join(destDir, "style.css.gz"), true, func(w io.Writer) error {
+		_, err := w.Write(style.Bytes())
+		return err
+	}); err != nil {
+		return err
+	}
+	if err := write.Atomically(filepath.Join(destDir, "style.css"), false, func(w io.Writer) error {
+		_, err := w.Write(style.Bytes())
+		return err
+	}); err != nil {
+		return err
+	}
+
+	// manifest.webmanifest refers to BaseURLPath, too.
+	var manifest bytes.Buffer
+	if err := commonTmpls.ExecuteTemplate(&manifest, "manifest", nil); err != nil {
+		return err
+	}
+	if err := write.Atomically(filepath.Join(destDir, "manifest.webmanifest.gz"), true, func(w io.Writer) error {
+		_, err := w.Write(manifest.Bytes())
+		return err
+	}); err != nil {
+		return err
+	}
+	if err := write.Atomically(filepath.Join(destDir, "manifest.webmanifest"), false, func(w io.Writer) error {
+		_, err := w.Write(manifest.Bytes())
+		return err
+	}); err != nil {
+		return err
+	}
+
 	for name, content := range bundled.AssetsFiltered(func(fn string) bool {
-		return !strings.HasSuffix(fn, ".tmpl") && !strings.HasSuffix(fn, "style.css")
+		return !strings.HasSuffix(fn, ".tmpl") && !strings.HasSuffix(fn, "style.css") && fn != "manifest.webmanifest"
 	}) {
 		if err := write.Atomically(filepath.Join(destDir, filepath.Base(name)+".gz"), true, func(w io.Writer) error {
 			_, err := io.WriteString(w, content)
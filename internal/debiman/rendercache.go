@@ -0,0 +1,311 @@
+package debiman
+
+import (
+	"bytes"
+	"compress/gzip"
+	"container/list"
+	"crypto/sha256"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/html"
+
+	"github.com/Debian/debiman/internal/write"
+)
+
+var (
+	renderCacheMemBytes = flag.Int64("render_cache_mem_bytes",
+		256*1024*1024,
+		"Maximum number of bytes of rendered manpages to keep in memory, so that manpages with identical content (e.g. the same package version in multiple suites) are converted only once per run. 0 disables the in-memory cache")
+
+	renderCacheDir = flag.String("render_cache",
+		"",
+		"If non-empty, a directory in which to additionally persist rendered manpages across runs (keyed on the manpage content, the mandoc version and non-default -mandoc_path, -mandoc_args and -postprocessors)")
+
+	renderCacheMaxBytes = flag.Int64("render_cache_max_bytes",
+		2*1024*1024*1024,
+		"Maximum size of -render_cache. After rendering, the least recently used entries are deleted until the cache fits")
+)
+
+// renderCacheFormat must be incremented when the postprocessing of
+// mandoc’s output (see internal/convert) changes, so that previously
+// persisted entries are no longer used.
+const renderCacheFormat = 1
+
+// renderCacheEntry is the result of converting a manpage, with the
+// URLs of cross-references replaced by placeholders: the same manpage
+// links to different URLs in each suite.
+type renderCacheEntry struct {
+	Doc string
+	TOC []string
+
+	// Linked contains the references for which resolve returned a
+	// URL. The URL of Linked[i] is represented by placeholder(i).
+	Linked []string
+
+	// Unlinked contains the references for which resolve returned
+	// the empty string, i.e. which were not turned into links.
+	Unlinked []string
+}
+
+func placeholder(i int) string {
+	return "\x00" + strconv.Itoa(i) + "\x00"
+}
+
+// size approximates the memory usage of e.
+func (e *renderCacheEntry) size() int64 {
+	n := len(e.Doc)
+	for _, s := range e.TOC {
+		n += len(s)
+	}
+	for _, s := range e.Linked {
+		n += len(s)
+	}
+	for _, s := range e.Unlinked {
+		n += len(s)
+	}
+	return int64(n)
+}
+
+// apply returns e.Doc with the placeholders replaced by the URLs which
+// resolve returns. ok is false if resolve does not link the same
+// references as when e was created, in which case the manpage needs to
+// be converted again.
+func (e *renderCacheEntry) apply(resolve func(ref string) string) (doc string, ok bool) {
+	for _, ref := range e.Unlinked {
+		if resolve(ref) != "" {
+			return "", false
+		}
+	}
+	oldnew := make([]string, 0, 2*len(e.Linked))
+	for i, ref := range e.Linked {
+		url := resolve(ref)
+		if url == "" {
+			return "", false
+		}
+		// The placeholder is located in an href attribute, which
+		// (x/net/html).Render escapes.
+		oldnew = append(oldnew, placeholder(i), html.EscapeString(url))
+	}
+	if len(oldnew) == 0 {
+		return e.Doc, true
+	}
+	return strings.NewReplacer(oldnew...).Replace(e.Doc), true
+}
+
+type renderCacheItem struct {
+	key   string
+	entry *renderCacheEntry
+}
+
+// renderCache is a conversion cache keyed on the hash of the
+// (decompressed) manpage source and the converter version. It is
+// complementary to -dedup: deduplication saves disk space, whereas
+// renderCache saves CPU time. An in-memory least recently used cache
+// is used within a run, optionally backed by dir across runs.
+type renderCache struct {
+	converterVersion string
+	maxBytes         int64
+	dir              string
+	stats            *stats
+
+	mu    sync.Mutex
+	bytes int64
+	lru   *list.List // of *renderCacheItem, most recently used first
+	items map[string]*list.Element
+}
+
+func newRenderCache(converterVersion string, maxBytes int64, dir string, stats *stats) *renderCache {
+	return &renderCache{
+		converterVersion: converterVersion,
+		maxBytes:         maxBytes,
+		dir:              dir,
+		stats:            stats,
+		lru:              list.New(),
+		items:            make(map[string]*list.Element),
+	}
+}
+
+func (c *renderCache) key(src []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\x00%s\x00", renderCacheFormat, c.converterVersion)
+	h.Write(src)
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+func (c *renderCache) path(key string) string {
+	return filepath.Join(c.dir, key+".json.gz")
+}
+
+func (c *renderCache) getMem(key string) *renderCacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil
+	}
+	c.lru.MoveToFront(el)
+	return el.Value.(*renderCacheItem).entry
+}
+
+func (c *renderCache) putMem(key string, e *renderCacheEntry) {
+	size := e.size()
+	if size > c.maxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.items[key]; ok {
+		return
+	}
+	c.items[key] = c.lru.PushFront(&renderCacheItem{key: key, entry: e})
+	c.bytes += size
+	for c.bytes > c.maxBytes {
+		el := c.lru.Back()
+		item := el.Value.(*renderCacheItem)
+		c.lru.Remove(el)
+		delete(c.items, item.key)
+		c.bytes -= item.entry.size()
+	}
+}
+
+func (c *renderCache) getDisk(key string) (*renderCacheEntry, error) {
+	path := c.path(key)
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var e renderCacheEntry
+	if err := json.NewDecoder(r).Decode(&e); err != nil {
+		return nil, err
+	}
+	// The modification time is used for least recently used eviction.
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (c *renderCache) putDisk(key string, e *renderCacheEntry) error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+	return write.Atomically(c.path(key), true, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(e)
+	})
+}
+
+func (c *renderCache) get(key string) *renderCacheEntry {
+	if c.maxBytes > 0 {
+		if e := c.getMem(key); e != nil {
+			return e
+		}
+	}
+	if c.dir == "" {
+		return nil
+	}
+	e, err := c.getDisk(key)
+	if err != nil {
+		log.Printf("WARNING: reading render cache entry %q: %v", key, err)
+		return nil
+	}
+	if e != nil && c.maxBytes > 0 {
+		c.putMem(key, e)
+	}
+	return e
+}
+
+func (c *renderCache) put(key string, e *renderCacheEntry) {
+	if c.maxBytes > 0 {
+		c.putMem(key, e)
+	}
+	if c.dir == "" {
+		return
+	}
+	if err := c.putDisk(key, e); err != nil {
+		log.Printf("WARNING: writing render cache entry %q: %v", key, err)
+	}
+}
+
+// convert returns the result of convert(src, resolve), re-using the
+// result of an earlier conversion of the same src if possible. A nil
+// *renderCache always calls convert.
+func (c *renderCache) convert(src []byte, resolve func(ref string) string, convert func(r io.Reader, resolve func(ref string) string) (doc string, toc []string, err error)) (doc string, toc []string, err error) {
+	// The placeholders could not be told apart from NUL bytes in the
+	// manpage itself.
+	if c == nil || resolve == nil || bytes.IndexByte(src, 0) != -1 {
+		return convert(bytes.NewReader(src), resolve)
+	}
+
+	key := c.key(src)
+	cached := c.get(key)
+	if cached != nil {
+		if doc, ok := cached.apply(resolve); ok {
+			atomic.AddUint64(&c.stats.RenderCacheHits, 1)
+			return doc, cached.TOC, nil
+		}
+	}
+	atomic.AddUint64(&c.stats.RenderCacheMisses, 1)
+
+	var (
+		e       renderCacheEntry
+		indices = make(map[string]int)
+		urls    []string
+	)
+	seen := make(map[string]bool)
+	doc, toc, err = convert(bytes.NewReader(src), func(ref string) string {
+		if idx, ok := indices[ref]; ok {
+			return placeholder(idx)
+		}
+		if seen[ref] {
+			return ""
+		}
+		seen[ref] = true
+		url := resolve(ref)
+		if url == "" {
+			e.Unlinked = append(e.Unlinked, ref)
+			return ""
+		}
+		indices[ref] = len(e.Linked)
+		e.Linked = append(e.Linked, ref)
+		urls = append(urls, url)
+		return placeholder(indices[ref])
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	e.Doc = doc
+	e.TOC = toc
+	// When the links differ (cached != nil), the first entry is kept,
+	// so that the in-memory and on-disk caches stay consistent.
+	if cached == nil {
+		c.put(key, &e)
+	}
+	doc, _ = e.apply(func(ref string) string {
+		if idx, ok := indices[ref]; ok {
+			return urls[idx]
+		}
+		return ""
+	})
+	return doc, toc, nil
+}
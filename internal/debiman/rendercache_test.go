@@ -0,0 +1,161 @@
+package debiman
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Debian/debiman/internal/convert"
+	"golang.org/x/net/html"
+)
+
+func TestRenderCache(t *testing.T) {
+	var conversions int
+	// fakeConvert links the references “ls(1)” and “foo(1)” like
+	// (*convert.Process).ToHTML would.
+	fakeConvert := func(r io.Reader, resolve func(ref string) string) (string, []string, error) {
+		conversions++
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			return "", nil, err
+		}
+		doc := string(b)
+		for _, ref := range []string{"ls(1)", "foo(1)", "ls(1)"} {
+			if url := resolve(ref); url != "" {
+				doc += `<a href="` + html.EscapeString(url) + `">` + ref + `</a>`
+			} else {
+				doc += ref
+			}
+		}
+		return doc, []string{"NAME"}, nil
+	}
+	suiteResolver := func(suite string, refs ...string) func(string) string {
+		return func(ref string) string {
+			for _, r := range refs {
+				if r == ref {
+					return "/" + suite + "/" + strings.TrimSuffix(ref, "(1)") + ".1.en.html?a&b"
+				}
+			}
+			return ""
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "debiman-rendercache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var st stats
+	c := newRenderCache("1.14.3", 1024*1024, dir, &st)
+	src := []byte("manpage source")
+
+	for _, tt := range []struct {
+		name            string
+		cache           *renderCache
+		resolve         func(string) string
+		wantDoc         string
+		wantConversions int
+	}{
+		{
+			name:            "miss",
+			cache:           c,
+			resolve:         suiteResolver("jessie", "ls(1)"),
+			wantDoc:         `manpage source<a href="/jessie/ls.1.en.html?a&amp;b">ls(1)</a>foo(1)<a href="/jessie/ls.1.en.html?a&amp;b">ls(1)</a>`,
+			wantConversions: 1,
+		},
+
+		{
+			name:            "other suite",
+			cache:           c,
+			resolve:         suiteResolver("stretch", "ls(1)"),
+			wantDoc:         `manpage source<a href="/stretch/ls.1.en.html?a&amp;b">ls(1)</a>foo(1)<a href="/stretch/ls.1.en.html?a&amp;b">ls(1)</a>`,
+			wantConversions: 1,
+		},
+
+		{
+			name:            "different links",
+			cache:           c,
+			resolve:         suiteResolver("sid", "ls(1)", "foo(1)"),
+			wantDoc:         `manpage source<a href="/sid/ls.1.en.html?a&amp;b">ls(1)</a><a href="/sid/foo.1.en.html?a&amp;b">foo(1)</a><a href="/sid/ls.1.en.html?a&amp;b">ls(1)</a>`,
+			wantConversions: 2,
+		},
+
+		{
+			name:            "persisted",
+			cache:           newRenderCache("1.14.3", 0, dir, &st),
+			resolve:         suiteResolver("buster", "ls(1)"),
+			wantDoc:         `manpage source<a href="/buster/ls.1.en.html?a&amp;b">ls(1)</a>foo(1)<a href="/buster/ls.1.en.html?a&amp;b">ls(1)</a>`,
+			wantConversions: 2,
+		},
+
+		{
+			name:            "other mandoc version",
+			cache:           newRenderCache("1.14.4", 0, dir, &st),
+			resolve:         suiteResolver("buster", "ls(1)"),
+			wantDoc:         `manpage source<a href="/buster/ls.1.en.html?a&amp;b">ls(1)</a>foo(1)<a href="/buster/ls.1.en.html?a&amp;b">ls(1)</a>`,
+			wantConversions: 3,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, toc, err := tt.cache.convert(src, tt.resolve, fakeConvert)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := doc, tt.wantDoc; got != want {
+				t.Fatalf("unexpected doc: got %q, want %q", got, want)
+			}
+			if got, want := len(toc), 1; got != want {
+				t.Fatalf("unexpected TOC length: got %d, want %d", got, want)
+			}
+			if got, want := conversions, tt.wantConversions; got != want {
+				t.Fatalf("unexpected number of conversions: got %d, want %d", got, want)
+			}
+		})
+	}
+	if got, want := st.RenderCacheHits, uint64(2); got != want {
+		t.Fatalf("unexpected number of render cache hits: got %d, want %d", got, want)
+	}
+}
+
+func TestRenderCacheEvict(t *testing.T) {
+	var st stats
+	c := newRenderCache("1.14.3", 10, "", &st)
+	c.putMem("a", &renderCacheEntry{Doc: "aaaaaa"})
+	c.putMem("b", &renderCacheEntry{Doc: "bbbbbb"})
+	if c.getMem("a") != nil {
+		t.Fatalf("least recently used entry a unexpectedly not evicted")
+	}
+	if c.getMem("b") == nil {
+		t.Fatalf("entry b unexpectedly evicted")
+	}
+	c.putMem("c", &renderCacheEntry{Doc: "ccccccccccc"})
+	if c.getMem("c") != nil {
+		t.Fatalf("entry c exceeding the cache size unexpectedly cached")
+	}
+}
+
+func TestConverterVersion(t *testing.T) {
+	defer func(old convert.Command) { convert.Mandoc = old }(convert.Mandoc)
+
+	convert.Mandoc = convert.Command{Path: "mandoc", Args: convert.DefaultArgs}
+	if got, want := converterVersion("1.14.3"), "1.14.3"; got != want {
+		t.Errorf("converterVersion() with the default invocation = %q, want %q", got, want)
+	}
+	// Entries of a different mandoc build, or of different arguments,
+	// must not be reused.
+	versions := map[string]bool{"1.14.3": true}
+	for _, cmd := range []convert.Command{
+		{Path: "/opt/mandoc/bin/mandoc", Args: convert.DefaultArgs},
+		{Path: "mandoc", Args: []string{"-Ofragment,toc", "-Thtml"}},
+	} {
+		convert.Mandoc = cmd
+		v := converterVersion("1.14.3")
+		if versions[v] {
+			t.Errorf("converterVersion() for %+v = %q, which is not unique", cmd, v)
+		}
+		versions[v] = true
+	}
+}
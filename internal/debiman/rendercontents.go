@@ -1,4 +1,4 @@
-package main
+package debiman
 
 import (
 	"fmt"
@@ -19,8 +19,17 @@ func mustParseContentsTmpl() *template.Template {
 	return template.Must(template.Must(commonTmpls.Clone()).New("contents").Parse(bundled.Asset("contents.tmpl")))
 }
 
-func renderContents(dest, suite string, bins []string) error {
-	sort.Strings(bins)
+// renderContents writes the contents page of suite, listing the binary
+// packages bins (directory names of the suite directory) and linking to
+// the section indexes of sections.
+func renderContents(dest, suite string, bins, sections []string) error {
+	pkgs := make([]string, 0, len(bins))
+	for _, bin := range bins {
+		if !isSectionIndexDir(bin) {
+			pkgs = append(pkgs, bin)
+		}
+	}
+	sort.Strings(pkgs)
 
 	if err := write.Atomically(dest, true, func(w io.Writer) error {
 		return contentsTmpl.Execute(w, struct {
@@ -30,6 +39,8 @@ func renderContents(dest, suite string, bins []string) error {
 			FooterExtra    string
 			Bins           []string
 			Suite          string
+			Sections       []string
+			News           bool
 			Meta           *manpage.Meta
 			HrefLangs      []*manpage.Meta
 		}{
@@ -39,8 +50,10 @@ func renderContents(dest, suite string, bins []string) error {
 				{fmt.Sprintf("/contents-%s.html", suite), suite},
 				{"", "Contents"},
 			},
-			Bins:  bins,
-			Suite: suite,
+			Bins:     pkgs,
+			Suite:    suite,
+			Sections: sections,
+			News:     *newsMaxEntries > 0,
 		})
 	}); err != nil {
 		return err
@@ -0,0 +1,233 @@
+package debiman
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Debian/debiman/internal/bundled"
+	"github.com/Debian/debiman/internal/manpage"
+	"github.com/Debian/debiman/internal/section"
+	"github.com/Debian/debiman/internal/write"
+)
+
+var filesTmpl = mustParseFilesTmpl()
+
+func mustParseFilesTmpl() *template.Template {
+	return template.Must(template.Must(commonTmpls.Clone()).New("files").Parse(bundled.Asset("files.tmpl")))
+}
+
+var filespageTmpl = mustParseFilespageTmpl()
+
+func mustParseFilespageTmpl() *template.Template {
+	return template.Must(template.Must(commonTmpls.Clone()).New("filespage").Parse(bundled.Asset("filespage.tmpl")))
+}
+
+// fileSections are the main sections whose manpages usually document a
+// file of the same name: programs (1 and 8) and file formats (5).
+var fileSections = []string{"5", "8", "1"}
+
+// filesPerPage keeps the pages of section 1 (tens of thousands of
+// manpages) at a size which browsers render quickly.
+const filesPerPage = 1000
+
+type filesPageLink struct {
+	Number int
+	Link   string
+}
+
+type filesGroup struct {
+	Section     string
+	Description string
+	Count       int
+	Pages       []filesPageLink
+}
+
+// filesPageName returns the file name of the page (counting from 1) of
+// section, relative to the suite directory.
+func filesPageName(section string, page int) string {
+	return fmt.Sprintf("files-%s-%d.html", section, page)
+}
+
+// filesBySuite returns the manpages to list on the files pages, by
+// suite and main section.
+func filesBySuite(xref map[string][]*manpage.Meta) map[string]map[string][]*manpage.Meta {
+	return manpagesBySuite(xref, fileSections)
+}
+
+// manpagesBySuite returns the manpages of the main sections in xref,
+// by suite and main section, sorted by name. Of each manpage, only one
+// language is listed (English if available).
+func manpagesBySuite(xref map[string][]*manpage.Meta, sections []string) map[string]map[string][]*manpage.Meta {
+	include := make(map[string]bool)
+	for _, section := range sections {
+		include[section] = true
+	}
+	best := make(map[string]*manpage.Meta)
+	for _, metas := range xref {
+		for _, m := range metas {
+			if !include[m.MainSection()] {
+				continue
+			}
+			key := m.Package.Suite + "/" + m.Package.Binarypkg + "/" + m.Name + "." + m.Section
+			if b, ok := best[key]; ok && (b.Language == "en" || (m.Language != "en" && b.Language < m.Language)) {
+				continue
+			}
+			best[key] = m
+		}
+	}
+	bySuite := make(map[string]map[string][]*manpage.Meta)
+	for _, m := range best {
+		bySection, ok := bySuite[m.Package.Suite]
+		if !ok {
+			bySection = make(map[string][]*manpage.Meta)
+			bySuite[m.Package.Suite] = bySection
+		}
+		bySection[m.MainSection()] = append(bySection[m.MainSection()], m)
+	}
+	for _, bySection := range bySuite {
+		for _, metas := range bySection {
+			sort.Slice(metas, func(i, j int) bool {
+				if metas[i].Name != metas[j].Name {
+					return metas[i].Name < metas[j].Name
+				}
+				if metas[i].Section != metas[j].Section {
+					return section.Less(metas[i].Section, metas[j].Section)
+				}
+				return metas[i].Package.Binarypkg < metas[j].Package.Binarypkg
+			})
+		}
+	}
+	return bySuite
+}
+
+// renderFiles writes the files overview page (files.html) and the
+// paginated files pages of each section into dir, the directory of
+// suite, and removes pages which are no longer needed.
+func renderFiles(dir, suite string, bySection map[string][]*manpage.Meta) error {
+	var groups []filesGroup
+	for _, section := range fileSections {
+		metas := bySection[section]
+		if len(metas) == 0 {
+			if err := removeStaleFilesPages(dir, section, 0); err != nil {
+				return err
+			}
+			continue
+		}
+		pages := (len(metas) + filesPerPage - 1) / filesPerPage
+		g := filesGroup{
+			Section:     section,
+			Description: longSections[section],
+			Count:       len(metas),
+		}
+		for page := 1; page <= pages; page++ {
+			g.Pages = append(g.Pages, filesPageLink{Number: page, Link: filesPageName(section, page)})
+		}
+		groups = append(groups, g)
+
+		for page := 1; page <= pages; page++ {
+			if err := renderFilesPage(dir, suite, g, metas, page); err != nil {
+				return err
+			}
+		}
+		if err := removeStaleFilesPages(dir, section, pages); err != nil {
+			return err
+		}
+	}
+
+	return write.Atomically(filepath.Join(dir, "files.html.gz"), true, func(w io.Writer) error {
+		return filesTmpl.Execute(w, struct {
+			Title          string
+			DebimanVersion string
+			Breadcrumbs    breadcrumbs
+			FooterExtra    string
+			Suite          string
+			Groups         []filesGroup
+			Meta           *manpage.Meta
+			HrefLangs      []*manpage.Meta
+		}{
+			Title:          fmt.Sprintf("Manpages by file name in Debian %s", suite),
+			DebimanVersion: debimanVersion,
+			Breadcrumbs: breadcrumbs{
+				{fmt.Sprintf("/contents-%s.html", suite), suite},
+				{"", "Files"},
+			},
+			Suite:  suite,
+			Groups: groups,
+		})
+	})
+}
+
+func renderFilesPage(dir, suite string, g filesGroup, metas []*manpage.Meta, page int) error {
+	start := (page - 1) * filesPerPage
+	end := start + filesPerPage
+	if end > len(metas) {
+		end = len(metas)
+	}
+	var prev, next string
+	if page > 1 {
+		prev = filesPageName(g.Section, page-1)
+	}
+	if page < len(g.Pages) {
+		next = filesPageName(g.Section, page+1)
+	}
+	dest := filepath.Join(dir, filesPageName(g.Section, page)+".gz")
+	return write.Atomically(dest, true, func(w io.Writer) error {
+		return filespageTmpl.Execute(w, struct {
+			Title          string
+			DebimanVersion string
+			Breadcrumbs    breadcrumbs
+			FooterExtra    string
+			Suite          string
+			Description    string
+			Metas          []*manpage.Meta
+			Page           int
+			Pages          int
+			Prev           string
+			Next           string
+			Meta           *manpage.Meta
+			HrefLangs      []*manpage.Meta
+		}{
+			Title:          fmt.Sprintf("Files documented in section %s in Debian %s (page %d)", g.Section, suite, page),
+			DebimanVersion: debimanVersion,
+			Breadcrumbs: breadcrumbs{
+				{fmt.Sprintf("/contents-%s.html", suite), suite},
+				{fmt.Sprintf("/%s/files.html", suite), "Files"},
+				{"", fmt.Sprintf("Section %s, page %d", g.Section, page)},
+			},
+			Suite:       suite,
+			Description: g.Description,
+			Metas:       metas[start:end],
+			Page:        page,
+			Pages:       len(g.Pages),
+			Prev:        prev,
+			Next:        next,
+		})
+	})
+}
+
+// removeStaleFilesPages removes the pages of section beyond pages, which
+// were written when the section contained more manpages.
+func removeStaleFilesPages(dir, section string, pages int) error {
+	matches, err := filepath.Glob(filepath.Join(dir, "files-"+section+"-*.html.gz"))
+	if err != nil {
+		return err
+	}
+	for _, match := range matches {
+		n := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(match), "files-"+section+"-"), ".html.gz")
+		if page, err := strconv.Atoi(n); err == nil && page > pages {
+			if err := os.Remove(match); err != nil {
+				return err
+			}
+			if err := write.RemoveVariants(match); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package debiman
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Debian/debiman/internal/manpage"
+)
+
+func readGzipped(t *testing.T, path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestRenderFiles(t *testing.T) {
+	jessie := &manpage.PkgMeta{Binarypkg: "openssh-server", Suite: "jessie"}
+	stretch := &manpage.PkgMeta{Binarypkg: "openssh-server", Suite: "stretch"}
+	xref := map[string][]*manpage.Meta{
+		"sshd_config": {
+			{Name: "sshd_config", Section: "5", Language: "fr", Package: jessie},
+			{Name: "sshd_config", Section: "5", Language: "en", Package: jessie},
+			{Name: "sshd_config", Section: "5", Language: "en", Package: stretch},
+		},
+		"sshd": {
+			{Name: "sshd", Section: "8", Language: "de", Package: jessie},
+		},
+		"ssh-agent": {
+			// libraries are not listed
+			{Name: "ssh-agent", Section: "3", Language: "en", Package: jessie},
+		},
+	}
+	// Enough programs for two pages.
+	for i := 0; i <= filesPerPage; i++ {
+		name := fmt.Sprintf("prog%04d", i)
+		xref[name] = []*manpage.Meta{{Name: name, Section: "1", Language: "en", Package: jessie}}
+	}
+
+	files := filesBySuite(xref)
+	bySection := files["jessie"]
+	if got, want := len(bySection["5"]), 1; got != want {
+		t.Fatalf("unexpected number of section 5 manpages: got %d, want %d", got, want)
+	}
+	if got, want := bySection["5"][0].Language, "en"; got != want {
+		t.Fatalf("unexpected language of sshd_config(5): got %q, want %q", got, want)
+	}
+	if got, want := bySection["8"][0].Language, "de"; got != want {
+		t.Fatalf("unexpected language of sshd(8): got %q, want %q", got, want)
+	}
+	if _, ok := bySection["3"]; ok {
+		t.Fatalf("unexpectedly listing section 3")
+	}
+
+	dir, err := ioutil.TempDir("", "debiman-renderfiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Pages left over from a previous run, when there were more manpages.
+	for _, stale := range []string{"files-1-3.html.gz", "files-8-2.html.gz"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, stale), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := renderFiles(dir, "jessie", bySection); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, stale := range []string{"files-1-3.html.gz", "files-8-2.html.gz"} {
+		if _, err := os.Stat(filepath.Join(dir, stale)); !os.IsNotExist(err) {
+			t.Errorf("stale page %q not removed (err = %v)", stale, err)
+		}
+	}
+
+	overview := readGzipped(t, filepath.Join(dir, "files.html.gz"))
+	for _, want := range []string{"/jessie/files-5-1.html", "/jessie/files-1-2.html"} {
+		if !strings.Contains(overview, want) {
+			t.Errorf("overview page does not link to %q", want)
+		}
+	}
+
+	page := readGzipped(t, filepath.Join(dir, "files-5-1.html.gz"))
+	if want := "/jessie/openssh-server/sshd_config.5.en.html"; !strings.Contains(page, want) {
+		t.Errorf("section 5 page does not link to %q", want)
+	}
+
+	second := readGzipped(t, filepath.Join(dir, "files-1-2.html.gz"))
+	if want := fmt.Sprintf("prog%04d", filesPerPage); !strings.Contains(second, want) {
+		t.Errorf("second section 1 page does not list %q", want)
+	}
+	if !strings.Contains(second, `rel="prev"`) || strings.Contains(second, `rel="next"`) {
+		t.Errorf("last page has unexpected navigation links")
+	}
+}
@@ -1,4 +1,4 @@
-package main
+package debiman
 
 import (
 	"bytes"
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"html/template"
 	"io"
+	"io/ioutil"
 	"log"
 	"net/url"
 	"os"
@@ -19,47 +20,14 @@ import (
 	"github.com/Debian/debiman/internal/commontmpl"
 	"github.com/Debian/debiman/internal/convert"
 	"github.com/Debian/debiman/internal/manpage"
+	"github.com/Debian/debiman/internal/releases"
+	"github.com/Debian/debiman/internal/section"
 	"github.com/Debian/debiman/internal/write"
 	"golang.org/x/text/language"
 )
 
 const iso8601Format = "2006-01-02T15:04:05Z"
 
-// TODO(later): move this list to a package within pault.ag/debian/?
-var releaseList = []string{
-	"buzz",
-	"rex",
-	"bo",
-	"hamm",
-	"slink",
-	"potato",
-	"woody",
-	"sarge",
-	"etch",
-	"lenny",
-	"squeeze",
-	"wheezy",
-	"wheezy-backports",
-	"jessie",
-	"jessie-backports",
-	"stretch",
-	"stretch-backports",
-	"buster",
-	"buster-backports",
-	"bullseye",
-	"bullseye-backports",
-}
-var sortOrder = make(map[string]int)
-
-func init() {
-	for idx, r := range releaseList {
-		sortOrder[r] = idx
-	}
-	sortOrder["testing"] = sortOrder["stretch"]
-	sortOrder["unstable"] = len(releaseList)
-	sortOrder["experimental"] = sortOrder["unstable"] + 1
-}
-
 // stapelberg came up with the following abbreviations:
 var shortSections = map[string]string{
 	"1": "progs",
@@ -71,6 +39,7 @@ var shortSections = map[string]string{
 	"7": "misc",
 	"8": "sysadmin",
 	"9": "kernel",
+	"i": "info",
 }
 
 // taken from man(1)
@@ -84,6 +53,7 @@ var longSections = map[string]string{
 	"7": "Miscellaneous (including macro packages and conventions), e.g. man(7), groff(7)",
 	"8": "System administration commands (usually only for root)",
 	"9": "Kernel routines [Non standard]",
+	"i": "Info documents (GNU Texinfo manuals, see -info_pages)",
 }
 
 var manpageTmpl = mustParseManpageTmpl()
@@ -136,7 +106,7 @@ func mustParseManpagefooterextraTmpl() *template.Template {
 		Parse(bundled.Asset("manpagefooterextra.tmpl")))
 }
 
-func convertFile(converter *convert.Process, src string, resolve func(ref string) string) (doc string, toc []string, err error) {
+func convertFile(converter *convert.Process, cache *renderCache, src string, meta *manpage.Meta, resolve func(ref string) string) (doc string, toc []string, err error) {
 	f, err := os.Open(src)
 	if err != nil {
 		return "", nil, err
@@ -151,7 +121,13 @@ func convertFile(converter *convert.Process, src string, resolve func(ref string
 		return "", nil, err
 	}
 	defer r.Close()
-	out, toc, err := converter.ToHTML(r, resolve)
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", nil, err
+	}
+	out, toc, err := cache.convert(b, resolve, func(r io.Reader, resolve func(ref string) string) (string, []string, error) {
+		return converter.ToHTMLFor(r, resolve, meta)
+	})
 	if err != nil {
 		return "", nil, fmt.Errorf("convert(%q): %v", src, err)
 	}
@@ -226,8 +202,22 @@ type renderJob struct {
 	meta     *manpage.Meta
 	versions []*manpage.Meta
 	xref     map[string][]*manpage.Meta
+	aliases  map[string]string // see globalView.aliases
 	modTime  time.Time
 	reuse    string
+	cache    *renderCache
+
+	// latestSuite is the suite to which the alias “latest” refers (see
+	// -latest_suite), if any.
+	latestSuite string
+
+	// whatis collects the NAME sections of rendered manpages if
+	// -whatis is set.
+	whatis *whatisIndex
+
+	// descriptions collects the NAME sections of all rendered manpages
+	// for the section indexes.
+	descriptions *whatisIndex
 }
 
 var notYetRenderedSentinel = errors.New("Not yet rendered")
@@ -236,8 +226,10 @@ type manpagePrepData struct {
 	Title          string
 	DebimanVersion string
 	Breadcrumbs    breadcrumbs
+	Article        manpageArticle
 	FooterExtra    template.HTML
 	Suites         []*manpage.Meta
+	Latest         string // serving path via the “latest” alias, if any
 	Versions       []*manpage.Meta
 	Sections       []*manpage.Meta
 	Bins           []*manpage.Meta
@@ -248,6 +240,12 @@ type manpagePrepData struct {
 	Ambiguous      map[*manpage.Meta]bool
 	Content        template.HTML
 	Error          error
+
+	// Tabs (in language order) and Panels (with the default language
+	// last) are only set on the pages of Grouped manpages, see
+	// -group_languages.
+	Tabs   []languagePanel
+	Panels []languagePanel
 }
 
 type bySuite []*manpage.Meta
@@ -255,19 +253,19 @@ type bySuite []*manpage.Meta
 func (p bySuite) Len() int      { return len(p) }
 func (p bySuite) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
 func (p bySuite) Less(i, j int) bool {
-	orderi, oki := sortOrder[p[i].Package.Suite]
-	orderj, okj := sortOrder[p[j].Package.Suite]
+	orderi, oki := releases.SortOrder[p[i].Package.Suite]
+	orderj, okj := releases.SortOrder[p[j].Package.Suite]
 	if !oki || !okj {
-		panic(fmt.Sprintf("either %q or %q is an unknown suite. known: %+v", p[i].Package.Suite, p[j].Package.Suite, sortOrder))
+		panic(fmt.Sprintf("either %q or %q is an unknown suite. known: %+v", p[i].Package.Suite, p[j].Package.Suite, releases.SortOrder))
 	}
 	return orderi < orderj
 }
 
-type byMainSection []*manpage.Meta
+type bySectionOrder []*manpage.Meta
 
-func (p byMainSection) Len() int           { return len(p) }
-func (p byMainSection) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
-func (p byMainSection) Less(i, j int) bool { return p[i].MainSection() < p[j].MainSection() }
+func (p bySectionOrder) Len() int           { return len(p) }
+func (p bySectionOrder) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p bySectionOrder) Less(i, j int) bool { return section.Less(p[i].Section, p[j].Section) }
 
 type byBinarypkg []*manpage.Meta
 
@@ -293,7 +291,7 @@ func rendermanpageprep(converter *convert.Process, job renderJob) (*template.Tem
 		}
 	}
 	if renderErr != nil {
-		content, toc, renderErr = convertFile(converter, job.src, func(ref string) string {
+		resolve := func(ref string) string {
 			idx := strings.LastIndex(ref, "(")
 			if idx == -1 {
 				return ""
@@ -317,8 +315,18 @@ func rendermanpageprep(converter *convert.Process, job renderJob) (*template.Tem
 			if len(filtered) == 0 {
 				return ""
 			}
-			return commontmpl.BaseURLPath() + "/" + bestLanguageMatch(meta, filtered).ServingPath() + ".html"
-		})
+			best := bestLanguageMatch(meta, filtered)
+			if target := job.aliases[best.ServingPath()]; target != "" {
+				// link to the page directly
+				return commontmpl.BaseURLPath() + "/" + target + ".html"
+			}
+			return commontmpl.BaseURLPath() + "/" + best.PageURL()
+		}
+		if meta.Format() == "info" {
+			content, toc, renderErr = convertInfoFile(job.src, resolve)
+		} else {
+			content, toc, renderErr = convertFile(converter, job.cache, job.src, meta, resolve)
+		}
 	}
 
 	log.Printf("rendering %q", job.dest)
@@ -353,7 +361,7 @@ func rendermanpageprep(converter *convert.Process, job renderJob) (*template.Tem
 	for _, all := range bySection {
 		sections = append(sections, bestLanguageMatch(meta, all))
 	}
-	sort.Stable(byMainSection(sections))
+	sort.Stable(bySectionOrder(sections))
 
 	conflicting := make(map[string]bool)
 	bins := make([]*manpage.Meta, 0, len(job.versions))
@@ -425,17 +433,31 @@ func rendermanpageprep(converter *convert.Process, job renderJob) (*template.Tem
 		title = "Error: " + title
 	}
 
+	bugReport, err := bugReportLink(bugReportTmpl, meta)
+	if err != nil {
+		return nil, manpagePrepData{}, err
+	}
+	var changelog []changelogEntry
+	if *changelogEntries > 0 {
+		if changelog, err = readChangelog(filepath.Dir(job.src), *changelogEntries); err != nil {
+			return nil, manpagePrepData{}, err
+		}
+	}
 	var footerExtra bytes.Buffer
 	if err := manpagefooterextraTmpl.Execute(&footerExtra, struct {
-		SourceFile  string
-		LastUpdated time.Time
-		Converted   time.Time
-		Meta        *manpage.Meta
+		SourceFile   string
+		LastUpdated  time.Time
+		Converted    time.Time
+		Meta         *manpage.Meta
+		BugReportURL string
+		Changelog    []changelogEntry
 	}{
-		SourceFile:  filepath.Base(job.src),
-		LastUpdated: job.modTime,
-		Converted:   time.Now(),
-		Meta:        meta,
+		SourceFile:   filepath.Base(job.src),
+		LastUpdated:  job.modTime,
+		Converted:    time.Now(),
+		Meta:         meta,
+		BugReportURL: bugReport,
+		Changelog:    changelog,
 	}); err != nil {
 		return nil, manpagePrepData{}, err
 	}
@@ -448,8 +470,13 @@ func rendermanpageprep(converter *convert.Process, job renderJob) (*template.Tem
 			{fmt.Sprintf("/%s/%s/index.html", meta.Package.Suite, meta.Package.Binarypkg), meta.Package.Binarypkg},
 			{"", shorttitle},
 		},
+		Article: manpageArticle{
+			Meta:     meta,
+			Modified: job.modTime,
+		},
 		FooterExtra: template.HTML(footerExtra.String()),
 		Suites:      suites,
+		Latest:      latestPath(suites, job.latestSuite),
 		Versions:    job.versions,
 		Sections:    sections,
 		Bins:        bins,
@@ -463,6 +490,22 @@ func rendermanpageprep(converter *convert.Process, job renderJob) (*template.Tem
 	}, nil
 }
 
+// latestPath returns the serving path of the manpage of suites which is
+// part of latestSuite, but via the suite alias “latest”, e.g.
+// latest/coreutils/ls.1.en for stretch/coreutils/ls.1.en. It returns
+// the empty string if no manpage is part of latestSuite.
+func latestPath(suites []*manpage.Meta, latestSuite string) string {
+	if latestSuite == "" {
+		return ""
+	}
+	for _, m := range suites {
+		if m.Package.Suite == latestSuite {
+			return latestAlias + strings.TrimPrefix(m.ServingPath(), manpage.URLCase.Path(latestSuite))
+		}
+	}
+	return ""
+}
+
 type countingWriter int64
 
 func (c *countingWriter) Write(p []byte) (n int, err error) {
@@ -470,18 +513,170 @@ func (c *countingWriter) Write(p []byte) (n int, err error) {
 	return len(p), nil
 }
 
-func rendermanpage(gzipw *gzip.Writer, converter *convert.Process, job renderJob) (uint64, error) {
+// writeJob is a rendered (but not yet compressed) manpage, to be
+// written to dest by the writer stage.
+type writeJob struct {
+	dest    string
+	content []byte
+
+	// fragment is nil unless -embed_fragments is specified.
+	fragment []byte
+
+	// minimal is nil unless -minimal_pages is specified.
+	minimal []byte
+
+	// metadata is nil unless -emit_metadata is specified.
+	metadata []byte
+
+	// empty is whether the manpage was flagged by -min_text_length.
+	empty bool
+
+	// err is the error converting the manpage, if content is its error
+	// page.
+	err error
+}
+
+// outputTooLargeError is returned by renderHTML when the rendered
+// manpage exceeds -max_output_bytes.
+type outputTooLargeError struct {
+	dest  string
+	size  int
+	limit int
+}
+
+func (e *outputTooLargeError) Error() string {
+	return fmt.Sprintf("%q: rendered size of %d bytes exceeds -max_output_bytes=%d", e.dest, e.size, e.limit)
+}
+
+// size returns the number of (uncompressed) bytes of j, including its
+// variants.
+func (j writeJob) size() int {
+	return len(j.content) + len(j.fragment) + len(j.minimal) + len(j.metadata)
+}
+
+// checkSize returns an *outputTooLargeError for the first file of j
+// (the page or one of its variants) which is larger than limit bytes.
+// A limit of 0 disables the check.
+func (j writeJob) checkSize(limit int) error {
+	if limit <= 0 {
+		return nil
+	}
+	for _, f := range []struct {
+		dest    string
+		content []byte
+	}{
+		{j.dest, j.content},
+		{fragmentDest(j.dest), j.fragment},
+		{minimalDest(j.dest), j.minimal},
+		{metadataDest(j.dest), j.metadata},
+	} {
+		if size := len(f.content); size > limit {
+			return &outputTooLargeError{dest: f.dest, size: size, limit: limit}
+		}
+	}
+	return nil
+}
+
+// renderHTML converts job into a writeJob. The CPU-heavy part of
+// rendering (mandoc and template execution) happens here.
+func renderHTML(converter *convert.Process, job renderJob) (writeJob, error) {
 	t, data, err := rendermanpageprep(converter, job)
 	if err != nil {
-		return 0, err
+		return writeJob{}, err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return writeJob{}, err
 	}
 
-	var written countingWriter
-	if err := write.AtomicallyWithGz(job.dest, gzipw, func(w io.Writer) error {
-		return t.Execute(io.MultiWriter(w, &written), data)
+	wj := writeJob{dest: job.dest, content: buf.Bytes(), err: data.Error}
+	if *minTextLength > 0 && data.Error == nil {
+		if n := textLength(string(data.Content)); n < *minTextLength && !hasNameSection(data.TOC) {
+			log.Printf("WARNING: package %q: manpage %q has only %d characters of text and no NAME section after conversion (see -min_text_length)", job.meta.Package.Binarypkg, job.meta.ServingPath(), n)
+			wj.empty = true
+		}
+	}
+	if data.Error == nil && job.meta.Format() != "info" {
+		if job.whatis != nil {
+			job.whatis.set(job.meta, string(data.Content), job.reuse != "")
+		}
+		if job.descriptions != nil {
+			// Symlinked manpages are listed in the section indexes, too.
+			job.descriptions.set(job.meta, string(data.Content), false)
+		}
+	}
+	if *embedFragments {
+		if wj.fragment, err = renderFragment(data); err != nil {
+			return writeJob{}, err
+		}
+	}
+	if *minimalPages {
+		if wj.minimal, err = renderMinimal(data); err != nil {
+			return writeJob{}, err
+		}
+	}
+	if *emitMetadata {
+		if wj.metadata, err = renderMetadata(data); err != nil {
+			return writeJob{}, err
+		}
+	}
+	if err := wj.checkSize(*maxOutputBytes); err != nil {
+		return writeJob{}, err
+	}
+	return wj, nil
+}
+
+// write compresses the rendered manpage using gzipw and atomically
+// places it at dest.
+func (j writeJob) write(gzipw *gzip.Writer) (uint64, error) {
+	if err := write.AtomicallyWithGz(j.dest, gzipw, func(w io.Writer) error {
+		_, err := w.Write(j.content)
+		return err
 	}); err != nil {
 		return 0, err
 	}
 
-	return uint64(written), nil
+	if j.fragment != nil {
+		if err := write.AtomicallyWithGz(fragmentDest(j.dest), gzipw, func(w io.Writer) error {
+			_, err := w.Write(j.fragment)
+			return err
+		}); err != nil {
+			return 0, err
+		}
+	}
+
+	if j.minimal != nil {
+		if err := write.AtomicallyWithGz(minimalDest(j.dest), gzipw, func(w io.Writer) error {
+			_, err := w.Write(j.minimal)
+			return err
+		}); err != nil {
+			return 0, err
+		}
+	}
+
+	if j.metadata != nil {
+		if err := write.AtomicallyWithGz(metadataDest(j.dest), gzipw, func(w io.Writer) error {
+			_, err := w.Write(j.metadata)
+			return err
+		}); err != nil {
+			return 0, err
+		}
+	}
+
+	if *minTextLength > 0 {
+		if err := markEmpty(j.dest, j.empty); err != nil {
+			return 0, err
+		}
+	}
+
+	return uint64(j.size()), nil
+}
+
+func rendermanpage(gzipw *gzip.Writer, converter *convert.Process, job renderJob) (uint64, error) {
+	wj, err := renderHTML(converter, job)
+	if err != nil {
+		return 0, err
+	}
+	return wj.write(gzipw)
 }
@@ -1,12 +1,18 @@
-package main
+package debiman
 
 import (
+	"bytes"
 	"compress/gzip"
+	"html/template"
 	"io/ioutil"
 	"os"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"golang.org/x/text/language"
+
 	"github.com/Debian/debiman/internal/convert"
 	"github.com/Debian/debiman/internal/manpage"
 )
@@ -187,3 +193,118 @@ baz
 		}
 	})
 }
+
+func TestCheckSize(t *testing.T) {
+	// A synthetic conversion result, as a corrupted manpage might
+	// produce it.
+	wj := writeJob{
+		dest:     "/srv/man/jessie/evil/evil.1.en.html",
+		content:  bytes.Repeat([]byte("<p>x</p>"), 1024),
+		fragment: []byte("<article></article>"),
+		minimal:  bytes.Repeat([]byte("<p>y</p>"), 2048),
+	}
+
+	// Each file is checked on its own, not their combined size.
+	table := []struct {
+		limit int
+		dest  string // empty if not too large
+		size  int
+	}{
+		{limit: 0},
+		{limit: len(wj.minimal)},
+		{limit: len(wj.minimal) - 1, dest: minimalDest(wj.dest), size: len(wj.minimal)},
+		{limit: len(wj.content) - 1, dest: wj.dest, size: len(wj.content)},
+	}
+	for _, entry := range table {
+		err := wj.checkSize(entry.limit)
+		if entry.dest == "" {
+			if err != nil {
+				t.Errorf("checkSize(%d) = %v, want nil", entry.limit, err)
+			}
+			continue
+		}
+		tooLarge, ok := err.(*outputTooLargeError)
+		if !ok {
+			t.Fatalf("checkSize(%d) = %v, want an *outputTooLargeError", entry.limit, err)
+		}
+		if tooLarge.dest != entry.dest {
+			t.Errorf("checkSize(%d): unexpected file: got %q, want %q", entry.limit, tooLarge.dest, entry.dest)
+		}
+		if tooLarge.size != entry.size {
+			t.Errorf("checkSize(%d): unexpected size: got %d, want %d", entry.limit, tooLarge.size, entry.size)
+		}
+		if !strings.Contains(err.Error(), strconv.Itoa(entry.size)) {
+			t.Errorf("checkSize(%d): error %q does not mention the size %d", entry.limit, err, entry.size)
+		}
+	}
+}
+
+func TestLatestPath(t *testing.T) {
+	suites := []*manpage.Meta{
+		mustParseFromServingPath(t, "jessie/cron/crontab.5.en"),
+		mustParseFromServingPath(t, "stretch/cron/crontab.5.en"),
+	}
+	for _, entry := range []struct {
+		latestSuite string
+		want        string
+	}{
+		{"", ""},
+		{"stretch", "latest/cron/crontab.5.en"},
+		{"buster", ""}, // crontab(5) is not part of buster
+	} {
+		if got := latestPath(suites, entry.latestSuite); got != entry.want {
+			t.Errorf("latestPath(%q) = %q, want %q", entry.latestSuite, got, entry.want)
+		}
+	}
+}
+
+func TestTypesetting(t *testing.T) {
+	for _, entry := range []struct {
+		lang     string
+		tag      language.Tag
+		wantPage string
+		wantFrag string
+	}{
+		{"ja", language.Japanese,
+			`<div class="manpage-text script-cjk">`,
+			`<article class="debiman-manpage script-cjk" lang="ja">`},
+		{"ar", language.Arabic,
+			`<div class="manpage-text" dir="rtl">`,
+			`<article class="debiman-manpage" lang="ar" dir="rtl">`},
+		{"en", language.English,
+			`<div class="manpage-text">`,
+			`<article class="debiman-manpage" lang="en">`},
+	} {
+		data := manpagePrepData{
+			Meta: &manpage.Meta{
+				Name:        "i3",
+				Section:     "1",
+				Language:    entry.lang,
+				LanguageTag: entry.tag,
+				Package:     &manpage.PkgMeta{Binarypkg: "i3-wm", Suite: "jessie"},
+			},
+			Content: template.HTML(`<div class="mandoc">i3</div>`),
+		}
+		data.Article.Meta = data.Meta
+		var buf bytes.Buffer
+		if err := manpageTmpl.Execute(&buf, data); err != nil {
+			t.Fatal(err)
+		}
+		page := buf.String()
+		for _, want := range []string{
+			`<html lang="` + entry.lang + `">`,
+			entry.wantPage + "\n" + `<div class="mandoc">i3</div>`,
+		} {
+			if !strings.Contains(page, want) {
+				t.Errorf("%s: page does not contain %q", entry.lang, want)
+			}
+		}
+		frag, err := renderFragment(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(frag), entry.wantFrag) {
+			t.Errorf("%s: fragment %q does not contain %q", entry.lang, frag, entry.wantFrag)
+		}
+	}
+}
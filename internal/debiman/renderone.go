@@ -0,0 +1,216 @@
+package debiman
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Debian/debiman/internal/manpage"
+	"github.com/Debian/debiman/internal/recode"
+	"github.com/Debian/debiman/internal/redirect"
+	"github.com/Debian/debiman/internal/tag"
+	"github.com/Debian/debiman/internal/write"
+)
+
+// renderMeta returns the metadata of the manpage at path for RenderOne,
+// taken from the serving path as if non-empty.
+func renderMeta(path, as string) (*manpage.Meta, error) {
+	if as != "" {
+		return manpage.FromServingPath("", as)
+	}
+	if path == "-" {
+		return nil, fmt.Errorf("the serving path is required when reading the manpage from stdin")
+	}
+	base := filepath.Base(path)
+	ext := filepath.Ext(strings.TrimSuffix(base, ".gz"))
+	if len(ext) < 2 {
+		return nil, fmt.Errorf("file name %q lacks a section, specify its serving path", base)
+	}
+	// Like in /usr/share/man/(<lang>/|)man<section>/<name>.<section>.gz
+	manDir := "man" + ext[1:2]
+	rel := manDir + "/" + base
+	if dir := filepath.Dir(path); filepath.Base(dir) == manDir {
+		if lang := filepath.Base(filepath.Dir(dir)); lang != "man" && lang != "." && lang != string(filepath.Separator) {
+			rel = lang + "/" + rel
+		}
+	}
+	return manpage.FromManPath(rel, &manpage.PkgMeta{
+		Binarypkg: "local",
+		Suite:     "local",
+	})
+}
+
+// indexXref returns the cross-references (like globalView.xref) and
+// aliases (like globalView.aliases) of the manpages of idx.
+func indexXref(idx redirect.Index) (map[string][]*manpage.Meta, map[string]string, error) {
+	xref := make(map[string][]*manpage.Meta)
+	aliases := make(map[string]string)
+	err := idx.ForEach(func(name string, e redirect.IndexEntry) error {
+		t, err := tag.FromLocale(e.Language)
+		if err != nil {
+			return fmt.Errorf("%s: %v", e.ServingPath(""), err)
+		}
+		m := &manpage.Meta{
+			Name: e.Name,
+			Package: &manpage.PkgMeta{
+				Binarypkg: e.Binarypkg,
+				Suite:     e.Suite,
+			},
+			Section:     e.Section,
+			Language:    e.Language,
+			LanguageTag: t,
+			Grouped:     e.Grouped,
+		}
+		if e.Target != "" {
+			aliases[m.ServingPath()] = e.Target
+		}
+		xref[m.Name] = append(xref[m.Name], m)
+		return nil
+	})
+	return xref, aliases, err
+}
+
+// readManpageSource reads the manpage source from r, decompressing it
+// if it is gzip-compressed (like the files in /usr/share/man), and
+// normalizes it like debiman does when extracting manpages.
+func readManpageSource(r io.Reader, m *manpage.Meta) ([]byte, error) {
+	br := bufio.NewReader(r)
+	r = br
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gzipr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gzipr.Close()
+		r = gzipr
+	}
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	content = normalizeSource(content)
+	if !utf8.Valid(content) {
+		return ioutil.ReadAll(recode.Reader(bytes.NewReader(content), m.Language))
+	}
+	return content, nil
+}
+
+// RenderOne converts the manpage file at path (optionally
+// gzip-compressed, or “-” for stdin) into the page debiman would write
+// for it, using the flags of the command line (mandoc invocation, post
+// processors, templates and -inject_assets), and writes the page to w.
+//
+// The serving path as (e.g. sid/coreutils/ls.1.de) determines the
+// metadata of the manpage and defaults to
+// local/local/<name>.<section>.<language>, taken from path like for
+// /usr/share/man. If indexPath is non-empty, cross-references are linked
+// to the manpages of that auxserver index, within the suite of the
+// manpage. .so requests are not resolved.
+//
+// The page is written (as the error page) even if the manpage cannot be
+// converted, in which case RenderOne returns an error.
+func RenderOne(w io.Writer, path, as, indexPath string) error {
+	// Apply the policies and post processors a run would use.
+	if _, err := parseRunFlags(); err != nil {
+		return err
+	}
+	if err := applyAssetFlags(); err != nil {
+		return err
+	}
+	m, err := renderMeta(path, as)
+	if err != nil {
+		return err
+	}
+
+	r := io.Reader(os.Stdin)
+	modTime := time.Now()
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		st, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		r, modTime = f, st.ModTime()
+	}
+	source, err := readManpageSource(r, m)
+	if err != nil {
+		return err
+	}
+
+	if _, err := setupMandoc(); err != nil {
+		return err
+	}
+
+	xref := make(map[string][]*manpage.Meta)
+	var aliases map[string]string
+	if indexPath != "" {
+		idx, err := redirect.IndexFromProto(indexPath)
+		if err != nil {
+			return fmt.Errorf("loading index: %v", err)
+		}
+		if xref, aliases, err = indexXref(idx); err != nil {
+			return fmt.Errorf("loading index: %v", err)
+		}
+	}
+	// The rendered manpage replaces its index entry, if any.
+	versions := []*manpage.Meta{m}
+	for _, v := range xref[m.Name] {
+		if v.ServingPath() != m.ServingPath() {
+			versions = append(versions, v)
+		}
+	}
+	xref[m.Name] = versions
+
+	dir, err := ioutil.TempDir("", "debiman-render")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, m.RawPath())
+	if err := os.MkdirAll(filepath.Dir(src), 0755); err != nil {
+		return err
+	}
+	if err := write.Atomically(src, true, func(w io.Writer) error {
+		_, err := w.Write(source)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	converter, err := newConverter()
+	if err != nil {
+		return err
+	}
+	defer converter.Kill()
+	wj, err := renderHTML(converter, renderJob{
+		dest:     filepath.Join(dir, m.ServingPath()+".html.gz"),
+		src:      src,
+		meta:     m,
+		versions: versions,
+		xref:     xref,
+		aliases:  aliases,
+		modTime:  modTime,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(wj.content); err != nil {
+		return err
+	}
+	if wj.err != nil {
+		return fmt.Errorf("converting %s: %v", filepath.Base(m.ServingPath()), wj.err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package debiman
+
+import (
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"testing"
+
+	"github.com/Debian/debiman/internal/redirect"
+)
+
+func TestRenderMeta(t *testing.T) {
+	for _, entry := range []struct {
+		path, as string
+		want     string
+	}{
+		{path: "/usr/share/man/man1/ls.1.gz", want: "local/local/ls.1.en"},
+		{path: "/usr/share/man/de/man1/ls.1.gz", want: "local/local/ls.1.de"},
+		{path: "/usr/share/man/pt_BR.UTF-8/man5/crontab.5.gz", want: "local/local/crontab.5.pt_BR"},
+		{path: "man3/printf.3posix", want: "local/local/printf.3posix.en"},
+		{path: "i3lock.1", want: "local/local/i3lock.1.en"},
+		{path: "/tmp/ls.1.gz", as: "sid/coreutils/ls.1.de", want: "sid/coreutils/ls.1.de"},
+		{path: "-", as: "sid/coreutils/ls.1.de", want: "sid/coreutils/ls.1.de"},
+	} {
+		m, err := renderMeta(entry.path, entry.as)
+		if err != nil {
+			t.Fatalf("renderMeta(%q, %q): %v", entry.path, entry.as, err)
+		}
+		if got := m.ServingPath(); got != entry.want {
+			t.Errorf("renderMeta(%q, %q) = %q, want %q", entry.path, entry.as, got, entry.want)
+		}
+	}
+
+	for _, entry := range []struct {
+		path, as string
+	}{
+		{path: "-"},
+		{path: "README"},
+		{path: "/tmp/ls.1.gz", as: "ls.1.de"},
+	} {
+		if _, err := renderMeta(entry.path, entry.as); err == nil {
+			t.Errorf("renderMeta(%q, %q) unexpectedly succeeded", entry.path, entry.as)
+		}
+	}
+}
+
+func TestIndexXref(t *testing.T) {
+	idx := redirect.NewIndex(map[string][]redirect.IndexEntry{
+		"ls": {
+			{Name: "ls", Suite: "sid", Binarypkg: "coreutils", Section: "1", Language: "en"},
+			{Name: "ls", Suite: "sid", Binarypkg: "coreutils", Section: "1", Language: "de", Grouped: true},
+		},
+		"gls": {
+			{Name: "gls", Suite: "sid", Binarypkg: "coreutils", Section: "1", Language: "en", Target: "sid/coreutils/ls.1.en"},
+		},
+	}, map[string]string{"sid": "sid"})
+
+	xref, aliases, err := indexXref(idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string][]string)
+	for name, metas := range xref {
+		for _, m := range metas {
+			got[name] = append(got[name], m.ServingPath())
+			if m.LanguageTag.String() == "und" {
+				t.Errorf("%s: language tag not set", m.ServingPath())
+			}
+			if want := m.Language == "de"; m.Grouped != want {
+				t.Errorf("%s: Grouped = %v, want %v", m.ServingPath(), m.Grouped, want)
+			}
+		}
+	}
+	want := map[string][]string{
+		"ls":  {"sid/coreutils/ls.1.en", "sid/coreutils/ls.1.de"},
+		"gls": {"sid/coreutils/gls.1.en"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("indexXref: xref = %v, want %v", got, want)
+	}
+	if want := map[string]string{"sid/coreutils/gls.1.en": "sid/coreutils/ls.1.en"}; !reflect.DeepEqual(aliases, want) {
+		t.Errorf("indexXref: aliases = %v, want %v", aliases, want)
+	}
+}
+
+func TestReadManpageSource(t *testing.T) {
+	const source = ".TH LS 1\r\n.SH NAME\r\nls \\- list directory contents\r\n"
+	const want = ".TH LS 1\n.SH NAME\nls \\- list directory contents\n"
+	var compressed bytes.Buffer
+	gzipw := gzip.NewWriter(&compressed)
+	gzipw.Write([]byte(source))
+	gzipw.Close()
+
+	m, err := renderMeta("ls.1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, input := range [][]byte{[]byte(source), compressed.Bytes()} {
+		got, err := readManpageSource(bytes.NewReader(input), m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("readManpageSource() = %q, want %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package debiman
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Debian/debiman/internal/bundled"
+	"github.com/Debian/debiman/internal/manpage"
+	"github.com/Debian/debiman/internal/write"
+)
+
+var sectionindexTmpl = mustParseSectionindexTmpl()
+
+func mustParseSectionindexTmpl() *template.Template {
+	return template.Must(template.Must(commonTmpls.Clone()).New("sectionindex").Parse(bundled.Asset("sectionindex.tmpl")))
+}
+
+var sectionpageTmpl = mustParseSectionpageTmpl()
+
+func mustParseSectionpageTmpl() *template.Template {
+	return template.Must(template.Must(commonTmpls.Clone()).New("sectionpage").Parse(bundled.Asset("sectionpage.tmpl")))
+}
+
+// indexSections are the main sections which get a section index
+// (/<suite>/man<section>/) listing all of their manpages.
+var indexSections = []string{"1", "2", "3", "4", "5", "6", "7", "8", "9"}
+
+// otherBucket collects the manpages whose name does not start with a
+// letter (e.g. “0trace” or “[”).
+const otherBucket = "other"
+
+// sectionIndexDir returns the name of the directory of the section
+// index of section, relative to the suite directory.
+func sectionIndexDir(section string) string {
+	return "man" + section
+}
+
+// isSectionIndexDir returns whether name (relative to the suite
+// directory) is the directory of a section index, as opposed to that
+// of a binary package.
+func isSectionIndexDir(name string) bool {
+	for _, section := range indexSections {
+		if name == sectionIndexDir(section) {
+			return true
+		}
+	}
+	return false
+}
+
+// sectionBucket returns the (lower case) first letter of name, or
+// otherBucket. The manpages of a section are split into one page per
+// bucket, as e.g. section 1 contains tens of thousands of manpages.
+func sectionBucket(name string) string {
+	r, _ := utf8.DecodeRuneInString(strings.ToLower(name))
+	if r < 'a' || r > 'z' {
+		return otherBucket
+	}
+	return string(r)
+}
+
+// indexedSections returns the sections of indexSections which contain
+// manpages, i.e. which have a section index.
+func indexedSections(bySection map[string][]*manpage.Meta) []string {
+	var sections []string
+	for _, section := range indexSections {
+		if len(bySection[section]) > 0 {
+			sections = append(sections, section)
+		}
+	}
+	return sections
+}
+
+type sectionBucketLink struct {
+	Bucket string
+	Count  int
+}
+
+type sectionEntry struct {
+	Meta        *manpage.Meta
+	Description string
+}
+
+// renderSectionIndexes writes the section index of each of
+// indexSections into dir, the directory of suite: man<section>/index.html
+// links to one page per bucket, which lists the manpages of the bucket
+// alphabetically. Indexes of sections without manpages and pages of
+// buckets without manpages are removed. describe returns the
+// description of a manpage, if known.
+func renderSectionIndexes(dir, suite string, bySection map[string][]*manpage.Meta, describe func(*manpage.Meta) string) error {
+	sections := indexedSections(bySection)
+	for _, section := range indexSections {
+		sectionDir := filepath.Join(dir, sectionIndexDir(section))
+		metas := bySection[section]
+		if len(metas) == 0 {
+			if err := os.RemoveAll(sectionDir); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.MkdirAll(sectionDir, 0755); err != nil {
+			return err
+		}
+		if err := renderSectionIndex(sectionDir, suite, section, sections, metas, describe); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func renderSectionIndex(dir, suite, section string, sections []string, metas []*manpage.Meta, describe func(*manpage.Meta) string) error {
+	byBucket := make(map[string][]sectionEntry)
+	for _, m := range metas {
+		bucket := sectionBucket(m.Name)
+		byBucket[bucket] = append(byBucket[bucket], sectionEntry{
+			Meta:        m,
+			Description: describe(m),
+		})
+	}
+	var buckets []sectionBucketLink
+	for r := 'a'; r <= 'z'; r++ {
+		if entries := byBucket[string(r)]; len(entries) > 0 {
+			buckets = append(buckets, sectionBucketLink{Bucket: string(r), Count: len(entries)})
+		}
+	}
+	if entries := byBucket[otherBucket]; len(entries) > 0 {
+		buckets = append(buckets, sectionBucketLink{Bucket: otherBucket, Count: len(entries)})
+	}
+
+	for idx, b := range buckets {
+		var prev, next string
+		if idx > 0 {
+			prev = buckets[idx-1].Bucket
+		}
+		if idx < len(buckets)-1 {
+			next = buckets[idx+1].Bucket
+		}
+		if err := renderSectionPage(dir, suite, section, b.Bucket, byBucket[b.Bucket], prev, next); err != nil {
+			return err
+		}
+	}
+	if err := removeStaleSectionPages(dir, byBucket); err != nil {
+		return err
+	}
+
+	return write.Atomically(filepath.Join(dir, "index.html.gz"), true, func(w io.Writer) error {
+		return sectionindexTmpl.Execute(w, struct {
+			Title          string
+			DebimanVersion string
+			Breadcrumbs    breadcrumbs
+			FooterExtra    string
+			Suite          string
+			Section        string
+			Description    string
+			Count          int
+			Buckets        []sectionBucketLink
+			Sections       []string
+			Meta           *manpage.Meta
+			HrefLangs      []*manpage.Meta
+		}{
+			Title:          fmt.Sprintf("Manpages of section %s in Debian %s", section, suite),
+			DebimanVersion: debimanVersion,
+			Breadcrumbs: breadcrumbs{
+				{fmt.Sprintf("/contents-%s.html", suite), suite},
+				{"", fmt.Sprintf("Section %s", section)},
+			},
+			Suite:       suite,
+			Section:     section,
+			Description: longSections[section],
+			Count:       len(metas),
+			Buckets:     buckets,
+			Sections:    sections,
+		})
+	})
+}
+
+func renderSectionPage(dir, suite, section, bucket string, entries []sectionEntry, prev, next string) error {
+	return write.Atomically(filepath.Join(dir, bucket+".html.gz"), true, func(w io.Writer) error {
+		return sectionpageTmpl.Execute(w, struct {
+			Title          string
+			DebimanVersion string
+			Breadcrumbs    breadcrumbs
+			FooterExtra    string
+			Suite          string
+			Section        string
+			Description    string
+			Bucket         string
+			Entries        []sectionEntry
+			Prev           string
+			Next           string
+			Meta           *manpage.Meta
+			HrefLangs      []*manpage.Meta
+		}{
+			Title:          fmt.Sprintf("Manpages of section %s in Debian %s (%s)", section, suite, bucket),
+			DebimanVersion: debimanVersion,
+			Breadcrumbs: breadcrumbs{
+				{fmt.Sprintf("/contents-%s.html", suite), suite},
+				{fmt.Sprintf("/%s/%s/index.html", suite, sectionIndexDir(section)), fmt.Sprintf("Section %s", section)},
+				{"", strings.ToUpper(bucket)},
+			},
+			Suite:       suite,
+			Section:     section,
+			Description: longSections[section],
+			Bucket:      bucket,
+			Entries:     entries,
+			Prev:        prev,
+			Next:        next,
+		})
+	})
+}
+
+// removeStaleSectionPages removes the pages of buckets which no longer
+// contain manpages.
+func removeStaleSectionPages(dir string, byBucket map[string][]sectionEntry) error {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.html.gz"))
+	if err != nil {
+		return err
+	}
+	for _, match := range matches {
+		bucket := strings.TrimSuffix(filepath.Base(match), ".html.gz")
+		if bucket == "index" || len(byBucket[bucket]) > 0 {
+			continue
+		}
+		if err := os.Remove(match); err != nil {
+			return err
+		}
+		if err := write.RemoveVariants(match); err != nil {
+			return err
+		}
+	}
+	return nil
+}
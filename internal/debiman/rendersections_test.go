@@ -0,0 +1,117 @@
+package debiman
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Debian/debiman/internal/manpage"
+)
+
+func TestSectionBucket(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		want string
+	}{
+		{"ls", "l"},
+		{"Xorg", "x"},
+		{"0trace", otherBucket},
+		{"[", otherBucket},
+		{"ärger", otherBucket},
+	} {
+		if got := sectionBucket(tt.name); got != tt.want {
+			t.Errorf("sectionBucket(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRenderSectionIndexes(t *testing.T) {
+	coreutils := &manpage.PkgMeta{Binarypkg: "coreutils", Suite: "jessie"}
+	cron := &manpage.PkgMeta{Binarypkg: "cron", Suite: "jessie"}
+	xref := map[string][]*manpage.Meta{
+		"ls": {
+			{Name: "ls", Section: "1", Language: "de", Package: coreutils},
+			{Name: "ls", Section: "1", Language: "en", Package: coreutils},
+		},
+		"cat": {
+			{Name: "cat", Section: "1", Language: "en", Package: coreutils},
+		},
+		"[": {
+			{Name: "[", Section: "1", Language: "en", Package: coreutils},
+		},
+		"crontab": {
+			{Name: "crontab", Section: "5", Language: "en", Package: cron},
+		},
+	}
+	bySection := manpagesBySuite(xref, indexSections)["jessie"]
+	if got, want := indexedSections(bySection), []string{"1", "5"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("unexpected indexed sections: got %q, want %q", got, want)
+	}
+
+	dir, err := ioutil.TempDir("", "debiman-rendersections")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Left over from a previous run, when there were more manpages.
+	for _, stale := range []string{"man1/z.html.gz", "man8/c.html.gz"} {
+		path := filepath.Join(dir, stale)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	describe := func(m *manpage.Meta) string {
+		if m.Name == "ls" {
+			return "list directory contents"
+		}
+		return ""
+	}
+	if err := renderSectionIndexes(dir, "jessie", bySection, describe); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, stale := range []string{"man1/z.html.gz", "man8"} {
+		if _, err := os.Stat(filepath.Join(dir, stale)); !os.IsNotExist(err) {
+			t.Errorf("stale %q not removed (err = %v)", stale, err)
+		}
+	}
+
+	index := readGzipped(t, filepath.Join(dir, "man1", "index.html.gz"))
+	for _, want := range []string{
+		"/jessie/man1/c.html",
+		"/jessie/man1/l.html",
+		"/jessie/man1/other.html",
+		"/jessie/man5/index.html",
+	} {
+		if !strings.Contains(index, want) {
+			t.Errorf("section 1 index does not link to %q", want)
+		}
+	}
+
+	page := readGzipped(t, filepath.Join(dir, "man1", "l.html.gz"))
+	for _, want := range []string{
+		"/jessie/coreutils/ls.1.en.html",
+		"list directory contents",
+		"/jessie/coreutils/index.html",
+		`rel="prev"`,
+		`rel="next"`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("section 1 page l does not contain %q", want)
+		}
+	}
+	if strings.Contains(page, "ls.1.de.html") {
+		t.Errorf("section 1 page l lists the German translation of ls(1)")
+	}
+
+	if !isSectionIndexDir("man5") || isSectionIndexDir("man-db") {
+		t.Errorf("isSectionIndexDir misclassifies directories")
+	}
+}
@@ -0,0 +1,236 @@
+package debiman
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	partialDir = flag.String("partial_dir",
+		"",
+		"Directory in which incomplete downloads of .deb files are kept, so that retries (within a run, or in the next run) continue from the last byte received using HTTP Range requests. Defaults to debiman-partial in the system’s temporary directory. Concurrent extractions of the same .deb (e.g. in multiple suites) share its file, which is removed once its download completed and was extracted by all of them, or when its SHA256 does not match the Packages file")
+
+	downloadRetries = flag.Int("download_retries",
+		3,
+		"How often to resume an interrupted download of a .deb file from a mirror before giving up, waiting one second longer before each retry. With 0, the download is resumed in the next run only. Has no effect for local mirrors")
+)
+
+// downloadRetryDelay is the delay before the first retry of
+// fetchResumable. Each further retry waits this much longer than the
+// previous one.
+var downloadRetryDelay = 1 * time.Second
+
+// httpStatusError is returned by fetchRange for unexpected responses.
+type httpStatusError struct {
+	url    string
+	status string
+	code   int
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("%s: unexpected HTTP status %q", e.url, e.status)
+}
+
+// permanent returns whether retrying the request will not help, e.g.
+// for HTTP 404.
+func (e *httpStatusError) permanent() bool {
+	return e.code < 500
+}
+
+// partialPath returns the path at which the partial download of p is
+// kept in dir.
+func partialPath(dir string, p pkgEntry) string {
+	return filepath.Join(dir, fmt.Sprintf("%s_%x.deb.partial", p.binarypkg, p.sha256))
+}
+
+// partialFile is a partial download which is in use. The same .deb
+// (e.g. of a package version which is in multiple suites) can be
+// extracted multiple times concurrently, and all of them use the same
+// partial file: the first one downloads it, the others wait for the
+// download and then find the complete file.
+type partialFile struct {
+	path string
+
+	// mu is held while downloading into path.
+	mu sync.Mutex
+
+	// refs is the number of users of path, guarded by partialFiles.
+	refs int
+}
+
+var partialFiles = struct {
+	sync.Mutex
+	m map[string]*partialFile
+}{m: make(map[string]*partialFile)}
+
+// usePartial returns the partialFile for path, which the caller must
+// release.
+func usePartial(path string) *partialFile {
+	partialFiles.Lock()
+	defer partialFiles.Unlock()
+	pf, ok := partialFiles.m[path]
+	if !ok {
+		pf = &partialFile{path: path}
+		partialFiles.m[path] = pf
+	}
+	pf.refs++
+	return pf
+}
+
+// release ends the use of pf. If the caller is done with the file
+// (remove is true), the file is removed unless it is still in use.
+func (pf *partialFile) release(remove bool) error {
+	partialFiles.Lock()
+	defer partialFiles.Unlock()
+	pf.refs--
+	if pf.refs > 0 {
+		return nil
+	}
+	delete(partialFiles.m, pf.path)
+	if !remove {
+		return nil
+	}
+	if err := os.Remove(pf.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// fetchRange downloads url into the file at path, continuing after the
+// bytes the file already contains (if any). size is the expected size
+// of the complete file, or 0 if unknown. resumed is incremented if the
+// server continued the download.
+func fetchRange(client *http.Client, url, path string, size int64, resumed *uint64) error {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	offset, err := f.Seek(0, os.SEEK_END)
+	if err != nil {
+		return err
+	}
+	if size > 0 && offset >= size {
+		return nil // complete, the caller verifies the hash
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	// Offsets refer to the file, not to an encoded transfer, see also
+	// contentDecoder.
+	req.Header.Set("Accept-Encoding", "identity")
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		var start int64
+		if _, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-", &start); err != nil || start != offset {
+			// Start over instead of appending the wrong bytes.
+			if err := f.Truncate(0); err != nil {
+				return err
+			}
+			return fmt.Errorf("%s: requested bytes from %d, got Content-Range %q", url, offset, resp.Header.Get("Content-Range"))
+		}
+		atomic.AddUint64(resumed, 1)
+
+	case http.StatusOK:
+		// The server does not support Range requests (or this is
+		// the first request): the body is the entire file.
+		if err := f.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := f.Seek(0, os.SEEK_SET); err != nil {
+			return err
+		}
+
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial file is larger than the file on the server,
+		// i.e. it belongs to a different file.
+		if err := f.Truncate(0); err != nil {
+			return err
+		}
+		return fmt.Errorf("%s: partial download of %d bytes is not a prefix of the file", url, offset)
+
+	default:
+		return &httpStatusError{url: url, status: resp.Status, code: resp.StatusCode}
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// fetchResumable downloads url into the file at path (see fetchRange),
+// resuming up to retries times after errors, and returns the file once
+// its content matches sum. Interrupted downloads are kept at path, so
+// that the next call resumes them. Files whose content does not match
+// sum are removed.
+func fetchResumable(client *http.Client, url, path string, size int64, sum []byte, retries int, resumed *uint64) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	var err error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			delay := time.Duration(attempt) * downloadRetryDelay
+			log.Printf("resuming download of %s in %v (retry %d of %d): %v", url, delay, attempt, retries, err)
+			time.Sleep(delay)
+		}
+		if err = fetchRange(client, url, path, size, resumed); err == nil {
+			break
+		}
+		if se, ok := err.(*httpStatusError); ok && se.permanent() {
+			os.Remove(path)
+			return nil, err
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if got := h.Sum(nil); !bytes.Equal(got, sum) {
+		f.Close()
+		os.Remove(path)
+		return nil, fmt.Errorf("%s: SHA256 mismatch: got %x, want %x", url, got, sum)
+	}
+	if _, err := f.Seek(0, os.SEEK_SET); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// partialDownloadDir returns -partial_dir or its default.
+func partialDownloadDir() string {
+	if *partialDir != "" {
+		return *partialDir
+	}
+	return filepath.Join(os.TempDir(), "debiman-partial")
+}
@@ -0,0 +1,193 @@
+package debiman
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"pault.ag/go/archive"
+)
+
+func TestFetchResumable(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "debiman-resume")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	content := make([]byte, 64*1024)
+	rand.New(rand.NewSource(1)).Read(content)
+	sum := sha256.Sum256(content)
+
+	var (
+		mu        sync.Mutex
+		ranges    []string
+		interrupt bool
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		abort := interrupt
+		interrupt = false
+		mu.Unlock()
+		if abort {
+			// Drop the connection after half of the file.
+			w.Header().Set("Content-Length", "65536")
+			w.Write(content[:len(content)/2])
+			panic(http.ErrAbortHandler)
+		}
+		http.ServeContent(w, r, "i3-wm.deb", time.Time{}, bytes.NewReader(content))
+	}))
+	defer srv.Close()
+
+	oldDelay := downloadRetryDelay
+	defer func() { downloadRetryDelay = oldDelay }()
+	downloadRetryDelay = time.Millisecond
+
+	fetch := func(path string, sum []byte, retries int) ([]byte, uint64, error) {
+		var resumed uint64
+		f, err := fetchResumable(http.DefaultClient, srv.URL+"/pool/main/i/i3-wm/i3-wm.deb", path, int64(len(content)), sum, retries, &resumed)
+		if err != nil {
+			return nil, resumed, err
+		}
+		defer f.Close()
+		b, err := ioutil.ReadAll(f)
+		return b, resumed, err
+	}
+
+	t.Run("Interrupted", func(t *testing.T) {
+		ranges, interrupt = nil, true
+		got, resumed, err := fetch(filepath.Join(tmpdir, "interrupted.partial"), sum[:], 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, content) {
+			t.Fatalf("resumed download differs from the file")
+		}
+		if resumed != 1 {
+			t.Errorf("resumed = %d, want 1", resumed)
+		}
+		want := []string{"", "bytes=32768-"}
+		if len(ranges) != len(want) || ranges[0] != want[0] || ranges[1] != want[1] {
+			t.Errorf("unexpected Range headers: got %q, want %q", ranges, want)
+		}
+	})
+
+	t.Run("PreviousRun", func(t *testing.T) {
+		ranges, interrupt = nil, false
+		path := filepath.Join(tmpdir, "previous.partial")
+		if err := ioutil.WriteFile(path, content[:1000], 0644); err != nil {
+			t.Fatal(err)
+		}
+		got, resumed, err := fetch(path, sum[:], 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, content) || resumed != 1 {
+			t.Fatalf("download not resumed correctly: %d bytes, resumed = %d", len(got), resumed)
+		}
+		if len(ranges) != 1 || ranges[0] != "bytes=1000-" {
+			t.Errorf("unexpected Range headers: got %q, want [bytes=1000-]", ranges)
+		}
+	})
+
+	t.Run("Mismatch", func(t *testing.T) {
+		ranges, interrupt = nil, true
+		path := filepath.Join(tmpdir, "mismatch.partial")
+		wrong := sha256.Sum256([]byte("something else"))
+		if _, _, err := fetch(path, wrong[:], 1); err == nil {
+			t.Fatalf("fetchResumable unexpectedly succeeded despite a SHA256 mismatch")
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("partial download %q not removed after a SHA256 mismatch (stat: %v)", path, err)
+		}
+	})
+
+	t.Run("Exhausted", func(t *testing.T) {
+		ranges, interrupt = nil, true
+		path := filepath.Join(tmpdir, "exhausted.partial")
+		if _, _, err := fetch(path, sum[:], 0); err == nil {
+			t.Fatalf("fetchResumable unexpectedly succeeded without retries")
+		}
+		// The partial download is kept for the next attempt.
+		if st, err := os.Stat(path); err != nil || st.Size() != int64(len(content)/2) {
+			t.Errorf("partial download not kept: stat = %v, %v", st, err)
+		}
+	})
+}
+
+func TestDownloadDebShared(t *testing.T) {
+	content := []byte("!<arch>\n")
+	sum := sha256.Sum256(content)
+	var (
+		mu   sync.Mutex
+		gets int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		gets++
+		mu.Unlock()
+		w.Write(content)
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "debiman-resume")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	oldPartialDir := *partialDir
+	defer func() { *partialDir = oldPartialDir }()
+	*partialDir = dir
+
+	// The same package version in two suites.
+	p := pkgEntry{
+		binarypkg: "i3-wm",
+		filename:  "pool/main/i/i3-wm/i3-wm.deb",
+		sha256:    sum[:],
+		bytes:     int64(len(content)),
+	}
+	ar := &archive.Downloader{Mirror: srv.URL}
+	debs := make([]*debSource, 2)
+	var wg sync.WaitGroup
+	for i := range debs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			deb, err := downloadDeb(ar, p, &stats{})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			debs[i] = deb
+		}(i)
+	}
+	wg.Wait()
+	if t.Failed() {
+		t.FailNow()
+	}
+	if gets != 1 {
+		t.Errorf("the .deb was requested %d times, want 1", gets)
+	}
+
+	path := partialPath(dir, p)
+	debs[0].Close()
+	if got, err := ioutil.ReadAll(debs[1].f); err != nil || !bytes.Equal(got, content) {
+		t.Errorf("reading the .deb after the other download was closed: %q, %v", got, err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("partial download removed while in use: %v", err)
+	}
+	debs[1].Close()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("partial download %q not removed after the last Close (stat: %v)", path, err)
+	}
+}
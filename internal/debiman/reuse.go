@@ -1,4 +1,4 @@
-package main
+package debiman
 
 import (
 	"bufio"
@@ -11,6 +11,10 @@ var mandocDivB = []byte(`<div class="mandoc">`)
 var tocLinkPrefix = []byte(`  <a class="toclink"`)
 var footerB = []byte(`<div id="footer">`)
 
+// manpageTextB starts the line preceding mandocDivB in pages which
+// wrap the content for typesetting (see manpage.Typesetting).
+var manpageTextB = []byte(`<div class="manpage-text`)
+
 func reuse(src string) (doc string, toc []string, err error) {
 	f, err := os.Open(src)
 	if err != nil {
@@ -27,16 +31,24 @@ func reuse(src string) (doc string, toc []string, err error) {
 	var (
 		buf       bytes.Buffer
 		inManpage bool
+		wrapped   bool
+		prev      []byte
 	)
 	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		b := scanner.Bytes()
-		if bytes.Equal(b, mandocDivB) {
+		if bytes.Equal(b, mandocDivB) && !inManpage {
 			inManpage = true
+			wrapped = bytes.HasPrefix(prev, manpageTextB)
 		}
+		prev = append(prev[:0], b...)
 		if bytes.Equal(b, footerB) {
 			all := buf.Bytes()
-			all = bytes.TrimSuffix(all, []byte("</div>\n</div>\n"))
+			suffix := "</div>\n</div>\n"
+			if wrapped {
+				suffix = "</div>\n" + suffix
+			}
+			all = bytes.TrimSuffix(all, []byte(suffix))
 			return string(bytes.TrimSpace(all)), toc, nil
 		}
 
@@ -1,4 +1,4 @@
-package main
+package debiman
 
 import (
 	"compress/gzip"
@@ -93,3 +93,37 @@ baz
 		}
 	}
 }
+
+func TestReuseTypesetting(t *testing.T) {
+	const content = "<div class=\"mandoc\">\n<h1>NAME</h1>\n</div>"
+	for _, entry := range []struct {
+		desc string
+		page string
+	}{
+		{"unwrapped", "<div class=\"maincontent\">\n" + content + "\n</div>\n</div>\n<div id=\"footer\">\n"},
+		{"wrapped", "<div class=\"maincontent\">\n<div class=\"manpage-text\" dir=\"rtl\">\n" + content + "\n</div>\n</div>\n</div>\n<div id=\"footer\">\n"},
+	} {
+		f, err := ioutil.TempFile("", "debiman-test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(f.Name())
+		gzipw := gzip.NewWriter(f)
+		if _, err := gzipw.Write([]byte(entry.page)); err != nil {
+			t.Fatal(err)
+		}
+		if err := gzipw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+		got, _, err := reuse(f.Name())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != content {
+			t.Errorf("%s: unexpected HTML fragment: got %q, want %q", entry.desc, got, content)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package debiman
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Debian/debiman/internal/bundled"
+	"github.com/Debian/debiman/internal/convert"
+	"github.com/Debian/debiman/internal/manpage"
+	"github.com/Debian/debiman/internal/write"
+	"pault.ag/go/debian/version"
+)
+
+var (
+	selftest = flag.Bool("selftest",
+		false,
+		"Render the sample manpages bundled into debiman through the full pipeline (mandoc, see -mandoc_path, the post processors and the bundled templates), compare the pages with the golden files bundled alongside them and exit without a run. Prints PASS or FAIL (with the first differing line) for each sample; the exit status is 1 if any page differs, which usually means that mandoc or the templates changed. Needs neither a mirror nor -serving_dir")
+
+	selftestUpdate = flag.String("selftest_update",
+		"",
+		"If non-empty, a directory to which -selftest writes the pages it rendered as new golden files, e.g. assets/selftest after a deliberate template change or mandoc upgrade (run go generate and rebuild debiman afterwards, as the golden files are bundled)")
+)
+
+// selftestModTime is the time at which the samples were last updated,
+// as shown on their pages.
+var selftestModTime = time.Date(2017, 5, 23, 14, 15, 0, 0, time.UTC)
+
+// selftestTimestamp matches the timestamps of rendered pages (see
+// iso8601Format), which golden files contain as selftestTimestampB, as
+// the conversion time differs between runs. Likewise, golden files
+// contain the debiman version as selftestVersion.
+var (
+	selftestTimestamp  = regexp.MustCompile(`\d{4}-\d\d-\d\dT\d\d:\d\d:\d\dZ`)
+	selftestTimestampB = []byte("0000-00-00T00:00:00Z")
+	selftestVersion    = "VERSION"
+)
+
+// normalizeSelftestPage replaces the parts of page which differ between
+// runs and builds of debiman, see selftestTimestamp.
+func normalizeSelftestPage(page []byte) []byte {
+	page = selftestTimestamp.ReplaceAll(page, selftestTimestampB)
+	return bytes.Replace(page, []byte("debiman "+debimanVersion+","), []byte("debiman "+selftestVersion+","), -1)
+}
+
+// selftestSample is a sample of -selftest.
+type selftestSample struct {
+	file   string // e.g. i3lock.1
+	meta   *manpage.Meta
+	source []byte
+	golden []byte
+}
+
+// selftestSamples returns the samples bundled into debiman (manpage
+// sources named <name>.<section>, e.g. i3lock.1, next to their golden
+// pages named <name>.<section>.html), see bundled.SelftestFiles.
+func selftestSamples() ([]selftestSample, error) {
+	files := bundled.SelftestFiles()
+	names := make([]string, 0, len(files))
+	for file := range files {
+		names = append(names, file)
+	}
+	sort.Strings(names)
+	v, err := version.Parse("1.0")
+	if err != nil {
+		return nil, err
+	}
+	var samples []selftestSample
+	for _, file := range names {
+		if strings.HasSuffix(file, ".html") {
+			continue
+		}
+		idx := strings.LastIndex(file, ".")
+		if idx == -1 {
+			return nil, fmt.Errorf("sample %q: file name lacks a section", file)
+		}
+		source := []byte(files[file])
+		golden, ok := files[file+".html"]
+		if !ok {
+			return nil, fmt.Errorf("sample %q: golden file %q not bundled", file, file+".html")
+		}
+		samples = append(samples, selftestSample{
+			file: file,
+			meta: &manpage.Meta{
+				Name: file[:idx],
+				Package: &manpage.PkgMeta{
+					Sourcepkg: "debiman",
+					Binarypkg: "debiman-selftest",
+					Version:   v,
+					Suite:     "selftest",
+				},
+				Section:  file[idx+1:],
+				Language: "en",
+			},
+			source: source,
+			golden: []byte(golden),
+		})
+	}
+	return samples, nil
+}
+
+// renderSelftest renders samples with converter in dir, a temporary
+// serving directory, and returns their pages (in the order of samples)
+// as normalized by normalizeSelftestPage. If reuse is non-nil, the
+// content of sample pages is taken from the page it returns instead,
+// like with renderJob.reuse.
+func renderSelftest(converter *convert.Process, dir string, samples []selftestSample, reuse func(m *manpage.Meta) string) ([][]byte, error) {
+	xref := make(map[string][]*manpage.Meta)
+	for _, s := range samples {
+		src := filepath.Join(dir, s.meta.RawPath())
+		if err := os.MkdirAll(filepath.Dir(src), 0755); err != nil {
+			return nil, err
+		}
+		if err := write.Atomically(src, true, func(w io.Writer) error {
+			_, err := w.Write(normalizeSource(s.source))
+			return err
+		}); err != nil {
+			return nil, err
+		}
+		xref[s.meta.Name] = append(xref[s.meta.Name], s.meta)
+	}
+
+	pages := make([][]byte, 0, len(samples))
+	for _, s := range samples {
+		job := renderJob{
+			dest:     filepath.Join(dir, s.meta.ServingPath()+".html.gz"),
+			src:      filepath.Join(dir, s.meta.RawPath()),
+			meta:     s.meta,
+			versions: xref[s.meta.Name],
+			xref:     xref,
+			modTime:  selftestModTime,
+		}
+		if reuse != nil {
+			job.reuse = reuse(s.meta)
+		}
+		wj, err := renderHTML(converter, job)
+		if err != nil {
+			return nil, fmt.Errorf("sample %q: %v", s.file, err)
+		}
+		pages = append(pages, normalizeSelftestPage(wj.content))
+	}
+	return pages, nil
+}
+
+// firstDifference describes the first line in which got differs from
+// want.
+func firstDifference(got, want []byte) string {
+	gotLines := strings.Split(string(got), "\n")
+	wantLines := strings.Split(string(want), "\n")
+	for i := 0; i < len(gotLines) || i < len(wantLines); i++ {
+		var g, w string
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if g != w || i >= len(gotLines) || i >= len(wantLines) {
+			return fmt.Sprintf("line %d: got %q, want %q", i+1, g, w)
+		}
+	}
+	return "identical"
+}
+
+// runSelftest runs -selftest, writes a report to w and returns whether
+// all pages matched their golden files.
+func runSelftest(w io.Writer) bool {
+	fail := func(format string, args ...interface{}) bool {
+		fmt.Fprintf(w, "FAIL  "+format+"\n", args...)
+		return false
+	}
+	samples, err := selftestSamples()
+	if err != nil {
+		return fail("samples: %v", err)
+	}
+	mandocVersion, err := setupMandoc()
+	if err != nil {
+		return fail("mandoc: %v", err)
+	}
+	fmt.Fprintf(w, "using mandoc %s\n", mandocVersion)
+	// The golden files are rendered with the default post processors,
+	// regardless of -postprocessors.
+	converter, err := convert.NewProcess()
+	if err != nil {
+		return fail("mandoc: %v", err)
+	}
+	defer converter.Kill()
+
+	dir, err := ioutil.TempDir("", "debiman-selftest")
+	if err != nil {
+		return fail("%v", err)
+	}
+	defer os.RemoveAll(dir)
+	pages, err := renderSelftest(converter, dir, samples, nil)
+	if err != nil {
+		return fail("%v", err)
+	}
+
+	failed := 0
+	for idx, s := range samples {
+		if *selftestUpdate != "" {
+			golden := filepath.Join(*selftestUpdate, s.file+".html")
+			if err := ioutil.WriteFile(golden, pages[idx], 0644); err != nil {
+				return fail("%v", err)
+			}
+		}
+		if bytes.Equal(pages[idx], s.golden) {
+			fmt.Fprintf(w, "PASS  %s\n", s.file)
+			continue
+		}
+		failed++
+		fail("%s: page differs from the golden file, %s", s.file, firstDifference(pages[idx], s.golden))
+	}
+	if failed > 0 {
+		fmt.Fprintf(w, "%d of %d samples differ from their golden files\n", failed, len(samples))
+		return false
+	}
+	fmt.Fprintf(w, "all %d samples match their golden files\n", len(samples))
+	return true
+}
@@ -0,0 +1,113 @@
+package debiman
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/Debian/debiman/internal/manpage"
+)
+
+// selftestHeading matches the section headings of converted manpages.
+var selftestHeading = regexp.MustCompile(`<h1 class="Sh" id="[^"]*">([^<]*)<a`)
+
+// writeSelftestReusePage writes a page whose content (see reuse) is
+// testdata/<name>.html, the output of convert.ToHTML which TestToHTML
+// verifies, with the cross-references resolved like renderJob does.
+func writeSelftestReusePage(t *testing.T, dir string, m *manpage.Meta) string {
+	b, err := ioutil.ReadFile("../../testdata/" + m.Name + ".html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := strings.TrimSpace(string(b))
+	content = strings.Replace(content, `href="testing/i3lock/i3lock.1.C"`, `href="/selftest/debiman-selftest/i3lock.1.en.html"`, -1)
+	var page bytes.Buffer
+	for _, match := range selftestHeading.FindAllStringSubmatch(content, -1) {
+		fmt.Fprintf(&page, "  <a class=\"toclink\" href=\"#\">%s</a>\n", match[1])
+	}
+	page.WriteString(content + "\n</div>\n</div>\n" + string(footerB) + "\n")
+
+	path := filepath.Join(dir, "reuse", m.ServingPath()+".html.gz")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	gzipw := gzip.NewWriter(&buf)
+	gzipw.Write(page.Bytes())
+	if err := gzipw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestSelftestGoldens verifies that the golden files of -selftest match
+// the templates, without requiring mandoc.
+func TestSelftestGoldens(t *testing.T) {
+	dir, err := ioutil.TempDir("", "debiman-selftest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// -selftest runs before logic parses -bug_report_url.
+	old := bugReportTmpl
+	defer func() { bugReportTmpl = old }()
+	bugReportTmpl = nil
+
+	samples, err := selftestSamples()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) == 0 {
+		t.Fatalf("no -selftest samples bundled")
+	}
+	pages, err := renderSelftest(nil, dir, samples, func(m *manpage.Meta) string {
+		return writeSelftestReusePage(t, dir, m)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for idx, s := range samples {
+		if !bytes.Equal(pages[idx], s.golden) {
+			t.Errorf("%s: golden file differs from the rendered page, %s", s.file, firstDifference(pages[idx], s.golden))
+		}
+		if bytes.Contains(pages[idx], []byte(debimanVersion+",")) {
+			t.Errorf("%s: debiman version not normalized", s.file)
+		}
+	}
+}
+
+func TestSelftest(t *testing.T) {
+	oldTmpl := bugReportTmpl
+	defer func() { bugReportTmpl = oldTmpl }()
+	bugReportTmpl = nil
+
+	var buf bytes.Buffer
+	if !runSelftest(&buf) {
+		t.Errorf("-selftest failed:\n%s", buf.String())
+	}
+}
+
+func TestFirstDifference(t *testing.T) {
+	for _, entry := range []struct {
+		got, want string
+		diff      string
+	}{
+		{"a\nb\n", "a\nb\n", "identical"},
+		{"a\nb\n", "a\nc\n", `line 2: got "b", want "c"`},
+		{"a\n", "a\nc\n", `line 2: got "", want "c"`},
+	} {
+		if got := firstDifference([]byte(entry.got), []byte(entry.want)); got != entry.diff {
+			t.Errorf("firstDifference(%q, %q) = %q, want %q", entry.got, entry.want, got, entry.diff)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package debiman
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Debian/debiman/internal/manpage"
+)
+
+var (
+	minManpageBytes = flag.Int("min_manpage_bytes",
+		0,
+		"Skip manpages whose (decompressed) source is smaller than this many bytes during extraction, e.g. stubs. Skipped manpages are logged, not rendered and omitted from the auxserver index. Unlike -min_text_length, this is checked before conversion. Takes effect for packages which are extracted (see -force_reextract). 0 disables the check")
+
+	maxManpageBytes = flag.Int("max_manpage_bytes",
+		0,
+		"Skip manpages whose (decompressed) source is larger than this many bytes during extraction, e.g. machine-generated ones. Skipped manpages are logged, not rendered and omitted from the auxserver index. Unlike -max_output_bytes, this is checked before conversion, and at most this many bytes are read. Takes effect for packages which are extracted (see -force_reextract). 0 disables the check")
+)
+
+// readSource reads the decompressed manpage source from r. With
+// -max_manpage_bytes, at most one byte more than the limit is read, so
+// that oversized manpages are never held in memory entirely.
+func readSource(r io.Reader) ([]byte, error) {
+	if *maxManpageBytes > 0 {
+		r = io.LimitReader(r, int64(*maxManpageBytes)+1)
+	}
+	return ioutil.ReadAll(r)
+}
+
+// sizeFilterReason returns why a manpage source of size bytes (as read
+// by readSource) is skipped, or "" if it is not.
+func sizeFilterReason(size int) string {
+	if *maxManpageBytes > 0 && size > *maxManpageBytes {
+		return fmt.Sprintf("source exceeds -max_manpage_bytes=%d", *maxManpageBytes)
+	}
+	if size < *minManpageBytes {
+		return fmt.Sprintf("source size of %d bytes is below -min_manpage_bytes=%d", size, *minManpageBytes)
+	}
+	return ""
+}
+
+// sizeMarker returns the path of the file which marks the manpage
+// source dest (e.g. …/ls.1.en.gz) as skipped by -min_manpage_bytes or
+// -max_manpage_bytes. The marker persists until the package is
+// extracted again. As a dot file, it is not published.
+func sizeMarker(dest string) string {
+	dir, base := filepath.Split(dest)
+	return filepath.Join(dir, "."+strings.TrimSuffix(base, ".gz")+".sizefiltered")
+}
+
+// markSizeFiltered creates or removes the sizeMarker of dest. A source
+// written before the limits were changed is removed, so that it is no
+// longer rendered.
+func markSizeFiltered(dest string, filtered bool) error {
+	fn := sizeMarker(dest)
+	if filtered {
+		if err := os.Remove(dest); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return ioutil.WriteFile(fn, nil, 0644)
+	}
+	if err := os.Remove(fn); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// sizeFiltered returns whether the source of m in servingDir was
+// skipped by -min_manpage_bytes or -max_manpage_bytes.
+func sizeFiltered(servingDir string, m *manpage.Meta) bool {
+	_, err := os.Stat(sizeMarker(filepath.Join(servingDir, m.ServingPath()+".gz")))
+	return err == nil
+}
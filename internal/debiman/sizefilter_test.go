@@ -0,0 +1,75 @@
+package debiman
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSizeFilter(t *testing.T) {
+	oldMin, oldMax := *minManpageBytes, *maxManpageBytes
+	defer func() { *minManpageBytes, *maxManpageBytes = oldMin, oldMax }()
+	*minManpageBytes, *maxManpageBytes = 10, 100
+
+	for _, entry := range []struct {
+		size     int
+		filtered bool
+	}{
+		{0, true},
+		{9, true},
+		{10, false},
+		{100, false},
+		{101, true},
+		{4096, true},
+	} {
+		source, err := readSource(strings.NewReader(strings.Repeat(".", entry.size)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(source) > *maxManpageBytes+1 {
+			t.Errorf("size %d: readSource read %d bytes, want at most %d", entry.size, len(source), *maxManpageBytes+1)
+		}
+		if got := sizeFilterReason(len(source)) != ""; got != entry.filtered {
+			t.Errorf("size %d: filtered = %v, want %v", entry.size, got, entry.filtered)
+		}
+	}
+
+	*minManpageBytes, *maxManpageBytes = 0, 0
+	if reason := sizeFilterReason(0); reason != "" {
+		t.Errorf("sizeFilterReason(0) with the default limits = %q, want \"\"", reason)
+	}
+}
+
+func TestSizeMarker(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "debiman-sizefilter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	m := mustParseFromServingPath(t, "jessie/foo/foo.1.en")
+	dest := filepath.Join(tmpdir, m.ServingPath()+".gz")
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := sizeMarker(dest), filepath.Join(tmpdir, "jessie/foo/.foo.1.en.sizefiltered"); got != want {
+		t.Fatalf("sizeMarker(%q) = %q, want %q", dest, got, want)
+	}
+	// Written by a previous run with different limits.
+	if err := ioutil.WriteFile(dest, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, filtered := range []bool{true, false, false} {
+		if err := markSizeFiltered(dest, filtered); err != nil {
+			t.Fatal(err)
+		}
+		if got := sizeFiltered(tmpdir, m); got != filtered {
+			t.Errorf("sizeFiltered = %v, want %v", got, filtered)
+		}
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Errorf("source %q not removed (err = %v)", dest, err)
+	}
+}
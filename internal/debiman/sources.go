@@ -0,0 +1,201 @@
+package debiman
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Debian/debiman/internal/manpage"
+	"github.com/Debian/debiman/internal/write"
+
+	"pault.ag/go/archive"
+	"pault.ag/go/debian/version"
+)
+
+var sources = flag.String("sources",
+	"",
+	"If non-empty, a semicolon-separated list of archives to merge, each of the form “<mirror> <dists> <components>”, e.g. “http://deb.debian.org/debian stable,testing main,contrib,non-free; http://backports.example.org/debian stable-backports main”. <mirror> is either a URL or a local file system path, <dists> refers to entries of -sync_codenames and -sync_suites (or “*” for all of them). When a package is available in the same version from multiple archives, the archive listed first is used. If empty, -local_mirror (if set) or a local apt-cacher-ng instance is used for all distributions, with components main and contrib")
+
+var defaultComponents = []string{"main", "contrib"}
+
+// archiveSource is an archive from which packages of one or more
+// distributions are obtained.
+type archiveSource struct {
+	ar *archive.Downloader
+
+	// client is used for all requests to this source (see
+	// newHTTPClient).
+	client *http.Client
+
+	// dists contains the names (as specified in -sync_codenames and
+	// -sync_suites) of the distributions which are obtained from
+	// this source. A nil map stands for all distributions.
+	dists map[string]bool
+
+	// components contains e.g. “main” and “contrib”.
+	components []string
+}
+
+func (s *archiveSource) serves(dist string) bool {
+	return s.dists == nil || s.dists[dist]
+}
+
+// httpClient returns s.client, or http.DefaultClient if none was
+// configured (e.g. in tests).
+func (s *archiveSource) httpClient() *http.Client {
+	if s.client == nil {
+		return http.DefaultClient
+	}
+	return s.client
+}
+
+// release fetches the Release file of dist and returns a
+// ReleaseDownloader for its indexes, both using s.httpClient(). As
+// archive.Downloader has no way to specify a client, InRelease is
+// downloaded into a temporary directory, from which archive.Downloader
+// reads it like from a local mirror.
+func (s *archiveSource) release(dist string) (*archive.Release, *archive.ReleaseDownloader, error) {
+	if s.ar.LocalMirror != "" {
+		return s.ar.Release(dist)
+	}
+	dir, err := ioutil.TempDir("", "debiman-release")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer os.RemoveAll(dir)
+	rel := "dists/" + dist + "/InRelease"
+	url := strings.TrimSuffix(s.ar.Mirror, "/") + "/" + rel
+	resp, err := s.httpClient().Get(url)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, &httpStatusError{url: url, status: resp.Status, code: resp.StatusCode}
+	}
+	dest := filepath.Join(dir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return nil, nil, err
+	}
+	if err := write.Atomically(dest, false, func(w io.Writer) error {
+		_, err := io.Copy(w, resp.Body)
+		return err
+	}); err != nil {
+		return nil, nil, fmt.Errorf("downloading %s: %v", url, err)
+	}
+
+	ar := *s.ar
+	ar.LocalMirror = dir
+	release, rd, err := ar.Release(dist)
+	if err != nil {
+		return nil, nil, err
+	}
+	rd.LocalMirror = ""
+	rd.Mirror = s.ar.Mirror
+	rd.Client = s.httpClient()
+	return release, rd, nil
+}
+
+func newDownloader(mirror string) *archive.Downloader {
+	ar := &archive.Downloader{
+		Parallel:            10,
+		MaxTransientRetries: 3,
+		Mirror:              "http://localhost:3142/deb.debian.org/debian",
+	}
+	if strings.HasPrefix(mirror, "/") {
+		ar.LocalMirror = mirror
+	} else {
+		ar.Mirror = mirror
+	}
+	return ar
+}
+
+// parseSources parses the -sources flag. For an empty spec, the
+// single source configured by -local_mirror is returned.
+func parseSources(spec, localMirror string) ([]*archiveSource, error) {
+	if strings.TrimSpace(spec) == "" {
+		ar := newDownloader("")
+		ar.LocalMirror = localMirror
+		return []*archiveSource{{ar: ar, components: defaultComponents}}, nil
+	}
+	var result []*archiveSource
+	for _, part := range strings.Split(spec, ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		fields := strings.Fields(part)
+		if got, want := len(fields), 3; got != want {
+			return nil, fmt.Errorf("source %q: got %d fields, want %d (<mirror> <dists> <components>)", part, got, want)
+		}
+		s := &archiveSource{ar: newDownloader(fields[0])}
+		if fields[1] != "*" {
+			s.dists = make(map[string]bool)
+			for _, dist := range strings.Split(fields[1], ",") {
+				if dist != "" {
+					s.dists[dist] = true
+				}
+			}
+		}
+		for _, component := range strings.Split(fields[2], ",") {
+			if component != "" {
+				s.components = append(s.components, component)
+			}
+		}
+		if len(s.components) == 0 {
+			return nil, fmt.Errorf("source %q: no components specified", part)
+		}
+		result = append(result, s)
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("no sources in %q", spec)
+	}
+	return result, nil
+}
+
+// mergePackages merges the packages (and their latest versions) which
+// were obtained from multiple sources, given in order of
+// precedence. For each package, the highest version wins. If the
+// highest version is available from multiple sources, the source with
+// the highest precedence (i.e. the lowest index) wins.
+func mergePackages(pkgs [][]*pkgEntry, latestVersions []map[string]*manpage.PkgMeta) ([]*pkgEntry, map[string]*manpage.PkgMeta) {
+	if len(pkgs) == 1 {
+		return pkgs[0], latestVersions[0]
+	}
+	var sum int
+	for _, part := range pkgs {
+		sum += len(part)
+	}
+	type winner struct {
+		pkg    *pkgEntry
+		source int
+	}
+	best := make(map[string]winner, sum)
+	var order []string
+	for idx, part := range pkgs {
+		for _, p := range part {
+			key := p.suite + "/" + p.binarypkg
+			w, ok := best[key]
+			if !ok {
+				order = append(order, key)
+			}
+			if ok && version.Compare(w.pkg.version, p.version) >= 0 {
+				continue
+			}
+			best[key] = winner{pkg: p, source: idx}
+		}
+	}
+
+	result := make([]*pkgEntry, 0, len(order))
+	latestVersion := make(map[string]*manpage.PkgMeta, len(order))
+	for _, key := range order {
+		w := best[key]
+		result = append(result, w.pkg)
+		latestVersion[key] = latestVersions[w.source][key]
+	}
+	return result, latestVersion
+}
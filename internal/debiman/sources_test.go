@@ -0,0 +1,112 @@
+package debiman
+
+import (
+	"testing"
+
+	"github.com/Debian/debiman/internal/manpage"
+
+	"pault.ag/go/debian/version"
+)
+
+func TestParseSources(t *testing.T) {
+	srcs, err := parseSources("http://deb.debian.org/debian stable,testing main,contrib,non-free; /srv/mirrors/backports stable-backports main", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(srcs), 2; got != want {
+		t.Fatalf("Unexpected number of sources: got %d, want %d", got, want)
+	}
+	if got, want := srcs[0].ar.Mirror, "http://deb.debian.org/debian"; got != want {
+		t.Errorf("Unexpected mirror: got %q, want %q", got, want)
+	}
+	if got, want := srcs[1].ar.LocalMirror, "/srv/mirrors/backports"; got != want {
+		t.Errorf("Unexpected local mirror: got %q, want %q", got, want)
+	}
+	if got, want := len(srcs[0].components), 3; got != want {
+		t.Errorf("Unexpected number of components: got %d, want %d", got, want)
+	}
+	for _, entry := range []struct {
+		src  int
+		dist string
+		want bool
+	}{
+		{0, "stable", true},
+		{0, "testing", true},
+		{0, "stable-backports", false},
+		{1, "stable-backports", true},
+		{1, "stable", false},
+	} {
+		if got := srcs[entry.src].serves(entry.dist); got != entry.want {
+			t.Errorf("source %d serves(%q) = %v, want %v", entry.src, entry.dist, got, entry.want)
+		}
+	}
+
+	for _, spec := range []string{
+		"http://deb.debian.org/debian stable",
+		"http://deb.debian.org/debian stable ,",
+		";",
+	} {
+		if _, err := parseSources(spec, ""); err == nil {
+			t.Errorf("parseSources(%q) unexpectedly succeeded", spec)
+		}
+	}
+}
+
+func TestMergePackages(t *testing.T) {
+	mustParse := func(v string) version.Version {
+		parsed, err := version.Parse(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+	mk := func(binarypkg, v, filename string) (*pkgEntry, *manpage.PkgMeta) {
+		return &pkgEntry{
+			suite:     "stretch",
+			binarypkg: binarypkg,
+			version:   mustParse(v),
+			filename:  filename,
+		}, &manpage.PkgMeta{
+			Suite:     "stretch",
+			Binarypkg: binarypkg,
+			Version:   mustParse(v),
+			Filename:  filename,
+		}
+	}
+	parts := make([][]*pkgEntry, 2)
+	latest := []map[string]*manpage.PkgMeta{
+		make(map[string]*manpage.PkgMeta),
+		make(map[string]*manpage.PkgMeta),
+	}
+	add := func(idx int, binarypkg, v, filename string) {
+		p, m := mk(binarypkg, v, filename)
+		parts[idx] = append(parts[idx], p)
+		latest[idx]["stretch/"+binarypkg] = m
+	}
+	add(0, "i3-wm", "4.13-1", "a/i3-wm.deb")
+	add(1, "i3-wm", "4.13-1", "b/i3-wm.deb") // same version: first source wins
+	add(0, "bash", "4.4-4", "a/bash.deb")
+	add(1, "bash", "4.4-5", "b/bash.deb") // newer version: second source wins
+	add(1, "zsh", "5.3-1", "b/zsh.deb")   // only in second source
+
+	// Merging must be deterministic.
+	for i := 0; i < 10; i++ {
+		pkgs, latestVersion := mergePackages(parts, latest)
+		if got, want := len(pkgs), 3; got != want {
+			t.Fatalf("Unexpected number of merged packages: got %d, want %d", got, want)
+		}
+		want := map[string]string{
+			"i3-wm": "a/i3-wm.deb",
+			"bash":  "b/bash.deb",
+			"zsh":   "b/zsh.deb",
+		}
+		for _, p := range pkgs {
+			if got, want := p.filename, want[p.binarypkg]; got != want {
+				t.Errorf("package %q: got filename %q, want %q", p.binarypkg, got, want)
+			}
+			if got, want := latestVersion["stretch/"+p.binarypkg].Filename, p.filename; got != want {
+				t.Errorf("package %q: latest version filename %q does not match merged package filename %q", p.binarypkg, got, want)
+			}
+		}
+	}
+}
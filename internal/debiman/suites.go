@@ -0,0 +1,73 @@
+package debiman
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/Debian/debiman/internal/releases"
+)
+
+var latestSuite = flag.String("latest_suite",
+	"",
+	"If non-empty, the suite (e.g. stable, or a codename) to which the suite alias “latest” refers, so that /latest/<binarypkg>/<name>… URLs follow e.g. the current stable release from run to run. Must be one of the synchronized suites")
+
+// latestAlias is the suite alias configured by -latest_suite.
+const latestAlias = "latest"
+
+var suitesFlag = flag.String("suites",
+	"",
+	"If non-empty, restricts the suites (of -sync_codenames and -sync_suites) to publish: either a comma-separated list of suites, codenames or aliases (e.g. stretch,buster,unstable) or “latest:N” for the N newest suites. Unselected suites are neither downloaded nor indexed, and their previously published files are deleted from -serving_dir. The run fails if a listed name is none of the synchronized suites")
+
+// pruneUnselectedSuites deletes the files of all known suites (see
+// releases.SortOrder) which are not in suites, i.e. which are no
+// longer selected by -suites.
+func pruneUnselectedSuites(servingDir string, suites map[string]bool) error {
+	known := make([]string, 0, len(releases.SortOrder))
+	for suite := range releases.SortOrder {
+		known = append(known, suite)
+	}
+	sort.Strings(known)
+	for _, suite := range known {
+		if suites[suite] {
+			continue
+		}
+		for _, fn := range []string{
+			filepath.Join(servingDir, suite),
+			filepath.Join(servingDir, "contents-"+suite+".html.gz"),
+			whatisPath(servingDir, suite),
+			descriptionsPath(servingDir, suite),
+		} {
+			if _, err := os.Lstat(fn); err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return err
+			}
+			log.Printf("suite %q is not selected by -suites, deleting %q", suite, fn)
+			if err := os.RemoveAll(fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// addLatestAlias makes the suite alias “latest” refer to the suite
+// which name (see -latest_suite) refers to.
+func addLatestAlias(gv *globalView, name string) error {
+	suite, ok := gv.idxSuites[name]
+	if !ok {
+		return fmt.Errorf("%q is not one of the synchronized suites", name)
+	}
+	if gv.suites[latestAlias] {
+		return fmt.Errorf("the alias %q collides with the suite of the same name", latestAlias)
+	}
+	log.Printf("suite alias %q refers to suite %q", latestAlias, suite)
+	gv.idxSuites[latestAlias] = suite
+	gv.latestSuite = suite
+	return nil
+}
@@ -0,0 +1,73 @@
+package debiman
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPruneUnselectedSuites(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "debiman-suites")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	for _, fn := range []string{
+		"wheezy/cron/crontab.5.en.html.gz",
+		"contents-wheezy.html.gz",
+		"stretch/cron/crontab.5.en.html.gz",
+		"contents-stretch.html.gz",
+		"index.html.gz",
+	} {
+		path := filepath.Join(tmpdir, fn)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := pruneUnselectedSuites(tmpdir, map[string]bool{"stretch": true}); err != nil {
+		t.Fatal(err)
+	}
+
+	for fn, want := range map[string]bool{
+		"wheezy":                            false,
+		"contents-wheezy.html.gz":           false,
+		"stretch/cron/crontab.5.en.html.gz": true,
+		"contents-stretch.html.gz":          true,
+		"index.html.gz":                     true,
+	} {
+		_, err := os.Stat(filepath.Join(tmpdir, fn))
+		if got := err == nil; got != want {
+			t.Errorf("%s: exists = %v, want %v", fn, got, want)
+		}
+	}
+}
+
+func TestAddLatestAlias(t *testing.T) {
+	gv := globalView{
+		suites: map[string]bool{"stretch": true, "testing": true},
+		idxSuites: map[string]string{
+			"stretch": "stretch",
+			"stable":  "stretch",
+			"testing": "testing",
+			"buster":  "testing",
+		},
+	}
+	if err := addLatestAlias(&gv, "oldstable"); err == nil {
+		t.Fatalf("addLatestAlias unexpectedly accepted a suite which is not synchronized")
+	}
+	if err := addLatestAlias(&gv, "stable"); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := gv.idxSuites["latest"], "stretch"; got != want {
+		t.Errorf(`unexpected idxSuites["latest"]: got %q, want %q`, got, want)
+	}
+	if got, want := gv.latestSuite, "stretch"; got != want {
+		t.Errorf("unexpected latestSuite: got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,203 @@
+package debiman
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Debian/debiman/internal/manpage"
+	"github.com/Debian/debiman/internal/write"
+	"pault.ag/go/debian/version"
+)
+
+var onlyChangedSuites = flag.Bool("only_changed_suites",
+	false,
+	"Skip all work for suites whose Release file (more precisely: the verified hashes of the Packages and Contents files of the configured components, as recorded in build-info.json) is unchanged since the previous run with this flag: their Packages and Contents files are not downloaded, their packages not extracted and their manpages not rendered. Their manpages are still contained in the auxserver index and cross-referenced, based on what the previous run recorded in -serving_dir. Changes to debiman itself or to flags which affect the rendered pages are not noticed, so use -force_rerender (which disables this fast path) after such changes")
+
+// suiteState is what a run records about a suite for the next run with
+// -only_changed_suites.
+type suiteState struct {
+	// ReleaseHash identifies the Release of the suite, see releaseHash.
+	ReleaseHash string `json:"release_hash"`
+
+	// Packages are the binary packages of the suite which contain
+	// manpages, i.e. the latestVersion entries of buildGlobalView.
+	Packages []suiteStatePackage `json:"packages"`
+}
+
+type suiteStatePackage struct {
+	Binarypkg    string   `json:"binarypkg"`
+	Sourcepkg    string   `json:"sourcepkg"`
+	Version      string   `json:"version"`
+	Component    string   `json:"component"`
+	Filename     string   `json:"filename"`
+	Replaces     []string `json:"replaces,omitempty"`
+	Description  string   `json:"description,omitempty"`
+	Architecture string   `json:"architecture,omitempty"`
+
+	// Manpages are the filenames of the contentEntry values of the
+	// package, e.g. man1/i3.1.gz.
+	Manpages []string `json:"manpages"`
+}
+
+// suiteStatePath returns the path of the file which keeps the
+// suiteState of suite. As a dot file, it is not published.
+func suiteStatePath(servingDir, suite string) string {
+	return filepath.Join(servingDir, suite, ".suite-state.json.gz")
+}
+
+// releaseHash returns a hash of the Release files of a suite, as
+// described by prov, and of the debiman version which processed them.
+func releaseHash(prov buildInfoSuite) (string, error) {
+	b, err := json.Marshal(struct {
+		DebimanVersion string
+		Sources        []buildInfoRelease
+	}{debimanVersion, prov.Sources})
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(b)), nil
+}
+
+// newSuiteState returns the suiteState of a suite from the
+// latestVersion and content which buildGlobalView obtained for it.
+func newSuiteState(hash string, latestVersion map[string]*manpage.PkgMeta, content []*contentEntry) *suiteState {
+	manpages := make(map[string][]string, len(latestVersion))
+	for _, c := range content {
+		key := c.suite + "/" + c.binarypkg
+		manpages[key] = append(manpages[key], c.filename)
+	}
+	keys := make([]string, 0, len(latestVersion))
+	for key := range latestVersion {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	s := &suiteState{
+		ReleaseHash: hash,
+		Packages:    make([]suiteStatePackage, 0, len(keys)),
+	}
+	for _, key := range keys {
+		p := latestVersion[key]
+		s.Packages = append(s.Packages, suiteStatePackage{
+			Binarypkg:    p.Binarypkg,
+			Sourcepkg:    p.Sourcepkg,
+			Version:      p.Version.String(),
+			Component:    p.Component,
+			Filename:     p.Filename,
+			Replaces:     p.Replaces,
+			Description:  p.Description,
+			Architecture: p.Architecture,
+			Manpages:     manpages[key],
+		})
+	}
+	return s
+}
+
+// restore returns the latestVersion and content entries of suite which
+// s recorded, as buildGlobalView would have obtained them from the
+// archive.
+func (s *suiteState) restore(suite string) (map[string]*manpage.PkgMeta, []*contentEntry, error) {
+	latestVersion := make(map[string]*manpage.PkgMeta, len(s.Packages))
+	var content []*contentEntry
+	for _, p := range s.Packages {
+		v, err := version.Parse(p.Version)
+		if err != nil {
+			return nil, nil, fmt.Errorf("package %q: %v", p.Binarypkg, err)
+		}
+		latestVersion[suite+"/"+p.Binarypkg] = &manpage.PkgMeta{
+			Binarypkg:    p.Binarypkg,
+			Sourcepkg:    p.Sourcepkg,
+			Version:      v,
+			Component:    p.Component,
+			Filename:     p.Filename,
+			Replaces:     p.Replaces,
+			Suite:        suite,
+			Description:  p.Description,
+			Architecture: p.Architecture,
+		}
+		for _, filename := range p.Manpages {
+			content = append(content, &contentEntry{
+				binarypkg: p.Binarypkg,
+				suite:     suite,
+				filename:  filename,
+			})
+		}
+	}
+	return latestVersion, content, nil
+}
+
+// readSuiteState returns the suiteState of suite which the previous
+// run recorded, or nil if there is none.
+func readSuiteState(servingDir, suite string) (*suiteState, error) {
+	f, err := os.Open(suiteStatePath(servingDir, suite))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var s suiteState
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// writeSuiteStates records the state of the suites which gv processed
+// for the next run. Without -only_changed_suites, the states of
+// previous runs are removed instead, as they no longer describe the
+// contents of servingDir once the suite is processed.
+func writeSuiteStates(servingDir string, gv globalView) error {
+	for suite := range gv.suites {
+		if gv.skippedSuites[suite] {
+			continue
+		}
+		path := suiteStatePath(servingDir, suite)
+		s, ok := gv.suiteStates[suite]
+		if !ok {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := write.Atomically(path, true, func(w io.Writer) error {
+			return json.NewEncoder(w).Encode(s)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reportSuites prints which suites were skipped because of
+// -only_changed_suites and which were processed.
+func reportSuites(w io.Writer, gv globalView) {
+	var skipped, processed []string
+	for suite := range gv.suites {
+		if gv.skippedSuites[suite] {
+			skipped = append(skipped, suite)
+		} else {
+			processed = append(processed, suite)
+		}
+	}
+	sort.Strings(skipped)
+	sort.Strings(processed)
+	fmt.Fprintf(w, "suites processed:         %d (%s)\n", len(processed), strings.Join(processed, ", "))
+	fmt.Fprintf(w, "suites skipped:           %d (%s)\n", len(skipped), strings.Join(skipped, ", "))
+}
@@ -0,0 +1,139 @@
+package debiman
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/Debian/debiman/internal/manpage"
+	"pault.ag/go/debian/version"
+)
+
+func TestSuiteState(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "debiman-suitestate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	v, err := version.Parse("4.13-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	latestVersion := map[string]*manpage.PkgMeta{
+		"jessie/i3-wm": {
+			Binarypkg:   "i3-wm",
+			Sourcepkg:   "i3-wm",
+			Version:     v,
+			Component:   "main",
+			Filename:    "pool/main/i/i3-wm/i3-wm_4.13-1_amd64.deb",
+			Suite:       "jessie",
+			Description: "improved dynamic tiling window manager",
+		},
+		"jessie/libc-bin": {
+			Binarypkg:    "libc-bin",
+			Sourcepkg:    "glibc",
+			Version:      v,
+			Component:    "main",
+			Replaces:     []string{"libc0.1"},
+			Suite:        "jessie",
+			Architecture: "amd64",
+		},
+	}
+	content := []*contentEntry{
+		{suite: "jessie", binarypkg: "i3-wm", filename: "man1/i3.1.gz"},
+		{suite: "jessie", binarypkg: "i3-wm", filename: "fr/man1/i3.1.gz"},
+		{suite: "jessie", binarypkg: "libc-bin", filename: "man8/ldconfig.8.gz"},
+	}
+
+	gv := globalView{
+		suites:        map[string]bool{"jessie": true, "stretch": true},
+		skippedSuites: map[string]bool{"stretch": true},
+		suiteStates: map[string]*suiteState{
+			"jessie": newSuiteState("0123abcd", latestVersion, content),
+		},
+	}
+	if err := writeSuiteStates(tmpdir, gv); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(suiteStatePath(tmpdir, "stretch")); !os.IsNotExist(err) {
+		t.Errorf("state of skipped suite unexpectedly written: %v", err)
+	}
+
+	s, err := readSuiteState(tmpdir, "jessie")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := s.ReleaseHash, "0123abcd"; got != want {
+		t.Errorf("unexpected release hash: got %q, want %q", got, want)
+	}
+	restoredVersion, restoredContent, err := s.restore("jessie")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(restoredVersion, latestVersion) {
+		t.Errorf("unexpected latest versions: got %+v, want %+v", restoredVersion, latestVersion)
+	}
+	if !reflect.DeepEqual(restoredContent, content) {
+		t.Errorf("unexpected content: got %+v, want %+v", restoredContent, content)
+	}
+
+	// Processing the suite without -only_changed_suites removes its
+	// state, as it becomes outdated.
+	gv.suiteStates = nil
+	if err := writeSuiteStates(tmpdir, gv); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := readSuiteState(tmpdir, "jessie"); err != nil || s != nil {
+		t.Errorf("readSuiteState = %v, %v, want nil, nil", s, err)
+	}
+}
+
+func TestReleaseHash(t *testing.T) {
+	prov := func(hash string) buildInfoSuite {
+		return buildInfoSuite{
+			Suite:        "jessie",
+			Distribution: "jessie",
+			Sources: []buildInfoRelease{{
+				Mirror:     "https://deb.debian.org/debian",
+				Suite:      "oldstable",
+				Codename:   "jessie",
+				Components: []string{"main"},
+				Indices:    map[string]string{"main/binary-amd64/Packages.gz": hash},
+			}},
+		}
+	}
+	a, err := releaseHash(prov("aaaa"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := releaseHash(prov("aaaa"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Errorf("releaseHash of the same Release differs: %q != %q", a, b)
+	}
+	c, err := releaseHash(prov("bbbb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == c {
+		t.Errorf("releaseHash unexpectedly unchanged after the Packages file changed")
+	}
+}
+
+func TestReportSuites(t *testing.T) {
+	var buf bytes.Buffer
+	reportSuites(&buf, globalView{
+		suites:        map[string]bool{"jessie": true, "stretch": true, "sid": true},
+		skippedSuites: map[string]bool{"jessie": true},
+	})
+	want := "suites processed:         2 (sid, stretch)\n" +
+		"suites skipped:           1 (jessie)\n"
+	if got := buf.String(); got != want {
+		t.Errorf("unexpected report: got %q, want %q", got, want)
+	}
+}
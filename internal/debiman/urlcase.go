@@ -0,0 +1,62 @@
+package debiman
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Debian/debiman/internal/manpage"
+	"github.com/Debian/debiman/internal/urlcase"
+	"github.com/Debian/debiman/internal/write"
+)
+
+var urlCase = flag.String("url_case",
+	"default",
+	"How to case manpage names, binary packages and suites in URLs: “default” (lookups are case-insensitive, files keep the manpage name’s case), “lower” (everything is lowercased) or “preserve” (lookups are case-sensitive). The policy is stored in the index, so that debiman-auxserver and debiman-idx2rwmap agree with it")
+
+// urlCaseFile records the URL case policy with which the files in
+// -serving_dir were written.
+const urlCaseFile = ".url_case"
+
+// pruneOnURLCaseChange deletes all files of the specified suites if
+// they were written with a URL case policy resulting in different
+// serving paths than the current policy, so that they are
+// re-extracted and re-rendered instead of lingering around.
+func pruneOnURLCaseChange(servingDir string, suites map[string]bool) error {
+	fn := filepath.Join(servingDir, urlCaseFile)
+	previous := urlcase.Default // files written before -url_case existed
+	b, err := ioutil.ReadFile(fn)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	recorded := err == nil
+	if recorded {
+		previous, err = urlcase.Parse(strings.TrimSpace(string(b)))
+		if err != nil {
+			return fmt.Errorf("%s: %v", fn, err)
+		}
+	}
+	current := manpage.URLCase
+	if !current.SamePaths(previous) {
+		log.Printf("URL case policy changed from %v to %v, deleting stale files", previous, current)
+		for suite := range suites {
+			for _, dir := range []string{previous.Path(suite), current.Path(suite)} {
+				if err := os.RemoveAll(filepath.Join(servingDir, dir)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if recorded && current == previous {
+		return nil
+	}
+	return write.Atomically(fn, false, func(w io.Writer) error {
+		_, err := fmt.Fprintln(w, current)
+		return err
+	})
+}
@@ -0,0 +1,59 @@
+package debiman
+
+import (
+	"flag"
+	"sort"
+	"strings"
+)
+
+var verifyContents = flag.String("verify_contents",
+	"off",
+	"Whether to compare the manpages found in each extracted package with the manpages the Contents index lists for it (by path): “off”, “warn” (log and count divergent packages) or “fail” (additionally discard packages which lack manpages listed in Contents, e.g. because of a corrupt download, so that they are extracted again in the next run instead of rendering an incomplete set). Contents files may lag behind Packages files, so occasional divergences are expected")
+
+// expectedManpages returns the manpages (relative to usr/share/man) which
+// the Contents index lists for each binary package, keyed by
+// suite/binarypkg.
+func expectedManpages(contentByPath map[string][]*contentEntry) map[string]map[string]bool {
+	expected := make(map[string]map[string]bool)
+	for filename, entries := range contentByPath {
+		if strings.HasPrefix(filename, "usr/share/info/") {
+			continue // see -info_pages
+		}
+		for _, c := range entries {
+			key := c.suite + "/" + c.binarypkg
+			if expected[key] == nil {
+				expected[key] = make(map[string]bool)
+			}
+			expected[key][filename] = true
+		}
+	}
+	return expected
+}
+
+// diffManpages returns the manpages which are expected (and extracted,
+// i.e. which name a manpage debiman serves), but were not found, and
+// those which were found, but are not expected (both sorted).
+func diffManpages(expected, found map[string]bool, extracted func(filename string) bool) (missing, unexpected []string) {
+	for filename := range expected {
+		if !found[filename] && extracted(filename) {
+			missing = append(missing, filename)
+		}
+	}
+	for filename := range found {
+		if !expected[filename] {
+			unexpected = append(unexpected, filename)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(unexpected)
+	return missing, unexpected
+}
+
+// abbreviate returns the first few of names for log messages.
+func abbreviate(names []string) string {
+	const max = 5
+	if len(names) <= max {
+		return strings.Join(names, ", ")
+	}
+	return strings.Join(names[:max], ", ") + ", …"
+}
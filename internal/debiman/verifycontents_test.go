@@ -0,0 +1,54 @@
+package debiman
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVerifyContents(t *testing.T) {
+	contentByPath := map[string][]*contentEntry{
+		"man1/i3.1.gz": {
+			{suite: "jessie", binarypkg: "i3-wm", filename: "man1/i3.1.gz"},
+			{suite: "stretch", binarypkg: "i3-wm", filename: "man1/i3.1.gz"},
+		},
+		"man1/i3-msg.1.gz": {
+			{suite: "jessie", binarypkg: "i3-wm", filename: "man1/i3-msg.1.gz"},
+		},
+		"man1/bogus name.1.gz": {
+			{suite: "jessie", binarypkg: "i3-wm", filename: "man1/bogus name.1.gz"},
+		},
+		"usr/share/info/i3.info.gz": {
+			{suite: "jessie", binarypkg: "i3-wm", filename: "usr/share/info/i3.info.gz"},
+		},
+	}
+	expected := expectedManpages(contentByPath)
+	want := map[string]map[string]bool{
+		"jessie/i3-wm": {
+			"man1/i3.1.gz":         true,
+			"man1/i3-msg.1.gz":     true,
+			"man1/bogus name.1.gz": true,
+		},
+		"stretch/i3-wm": {"man1/i3.1.gz": true},
+	}
+	if !reflect.DeepEqual(expected, want) {
+		t.Fatalf("expectedManpages() = %v, want %v", expected, want)
+	}
+
+	found := map[string]bool{
+		"man1/i3.1.gz":      true,
+		"man1/i3-dump.1.gz": true,
+	}
+	extracted := func(filename string) bool { return filename != "man1/bogus name.1.gz" }
+	missing, unexpected := diffManpages(expected["jessie/i3-wm"], found, extracted)
+	if want := []string{"man1/i3-msg.1.gz"}; !reflect.DeepEqual(missing, want) {
+		t.Errorf("missing = %v, want %v", missing, want)
+	}
+	if want := []string{"man1/i3-dump.1.gz"}; !reflect.DeepEqual(unexpected, want) {
+		t.Errorf("unexpected = %v, want %v", unexpected, want)
+	}
+
+	missing, unexpected = diffManpages(expected["stretch/i3-wm"], map[string]bool{"man1/i3.1.gz": true}, extracted)
+	if len(missing) > 0 || len(unexpected) > 0 {
+		t.Errorf("diffManpages(identical) = %v, %v, want no divergence", missing, unexpected)
+	}
+}
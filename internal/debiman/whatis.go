@@ -0,0 +1,214 @@
+package debiman
+
+import (
+	"bufio"
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+
+	"github.com/Debian/debiman/internal/manpage"
+	"github.com/Debian/debiman/internal/write"
+)
+
+var writeWhatis = flag.Bool("whatis",
+	false,
+	"Write a whatis-style index of the NAME sections of all manpages of each suite to whatis-<suite>.txt.gz in -serving_dir, e.g. to build a server-side apropos. Descriptions are extracted when manpages are rendered, so use -force_rerender once after enabling this flag")
+
+// whatisSeparators separate the names from the description in the NAME
+// section, as converted by mandoc: man(7) pages use “\-”, which mandoc
+// renders as a hyphen or minus sign, mdoc(7) pages use an em dash.
+var whatisSeparators = []string{" - ", " − ", " — ", " – "}
+
+// parseNameSection returns the names and the description of the NAME
+// section (i.e. the first section) of content (as converted by mandoc),
+// e.g. “foo, bar - do things” results in [foo bar] and “do things”.
+func parseNameSection(content string) (names []string, description string, ok bool) {
+	var (
+		text  []string
+		state int // 0: before, 1: within the heading, 2: within the section
+	)
+	z := html.NewTokenizer(strings.NewReader(content))
+tokens:
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			break tokens // io.EOF, or truncated HTML
+		case html.StartTagToken:
+			name, _ := z.TagName()
+			if string(name) != "h1" {
+				continue
+			}
+			if state == 2 {
+				break tokens
+			}
+			state = 1
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			if state == 1 && string(name) == "h1" {
+				state = 2
+			} else if state == 2 && string(name) == "section" {
+				break tokens
+			}
+		case html.TextToken:
+			if state == 2 {
+				text = append(text, string(z.Text()))
+			}
+		}
+	}
+	line := strings.Join(strings.Fields(strings.Join(text, "")), " ")
+	for _, sep := range whatisSeparators {
+		idx := strings.Index(line, sep)
+		if idx == -1 {
+			continue
+		}
+		for _, name := range strings.Split(line[:idx], ",") {
+			if name = strings.TrimSpace(name); name != "" && !strings.ContainsAny(name, " \t") {
+				names = append(names, name)
+			}
+		}
+		description = strings.TrimSpace(line[idx+len(sep):])
+		return names, description, len(names) > 0 && description != ""
+	}
+	return nil, "", false
+}
+
+// whatisPath returns the path of the whatis index of suite.
+func whatisPath(servingDir, suite string) string {
+	return filepath.Join(servingDir, "whatis-"+suite+".txt.gz")
+}
+
+// descriptionsPath returns the path of the file in which the whatis
+// lines of all manpages of suite (including symlinked manpages) persist
+// across runs, so that the section indexes can show the descriptions of
+// manpages which are not rendered in the current run. As a dot file, it
+// is not published.
+func descriptionsPath(servingDir, suite string) string {
+	return filepath.Join(servingDir, ".descriptions-"+suite+".txt.gz")
+}
+
+// whatisIndex collects the whatis lines (e.g. “ls(1) - list directory
+// contents”) of manpages, keyed by their serving path. Each line of a
+// whatis index file consists of the serving path, a tab and the whatis
+// line, so that the index can be updated with the entries of the
+// manpages rendered in the current run.
+type whatisIndex struct {
+	mu      sync.Mutex
+	entries map[string][]string
+}
+
+// loadWhatisIndex reads the whatis index files of the suites, if any.
+func loadWhatisIndex(servingDir string, suites map[string]bool) (*whatisIndex, error) {
+	return loadWhatisFiles(servingDir, suites, whatisPath)
+}
+
+// loadDescriptions reads the descriptions files (see descriptionsPath)
+// of the suites, if any.
+func loadDescriptions(servingDir string, suites map[string]bool) (*whatisIndex, error) {
+	return loadWhatisFiles(servingDir, suites, descriptionsPath)
+}
+
+func loadWhatisFiles(servingDir string, suites map[string]bool, path func(servingDir, suite string) string) (*whatisIndex, error) {
+	w := &whatisIndex{entries: make(map[string][]string)}
+	for suite := range suites {
+		fn := path(servingDir, suite)
+		if err := w.load(fn); err != nil {
+			return nil, fmt.Errorf("reading %q: %v", fn, err)
+		}
+	}
+	return w, nil
+}
+
+func (w *whatisIndex) load(fn string) error {
+	f, err := os.Open(fn)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "\t", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		w.entries[parts[0]] = append(w.entries[parts[0]], parts[1])
+	}
+	return scanner.Err()
+}
+
+// set replaces the whatis lines of m with those parsed from content.
+// Symlinked manpages (reuse) have no lines of their own, as they would
+// duplicate the lines of the manpage they refer to.
+func (w *whatisIndex) set(m *manpage.Meta, content string, reuse bool) {
+	var lines []string
+	if names, description, ok := parseNameSection(content); ok && !reuse {
+		for _, name := range names {
+			lines = append(lines, fmt.Sprintf("%s(%s) - %s", name, m.Section, description))
+		}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if lines == nil {
+		delete(w.entries, m.ServingPath())
+	} else {
+		w.entries[m.ServingPath()] = lines
+	}
+}
+
+// description returns the description of the first whatis line of m,
+// e.g. “list directory contents” for ls(1), or "" if m has none.
+func (w *whatisIndex) description(m *manpage.Meta) string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	lines := w.entries[m.ServingPath()]
+	if len(lines) == 0 {
+		return ""
+	}
+	if idx := strings.Index(lines[0], ") - "); idx > -1 {
+		return lines[0][idx+len(") - "):]
+	}
+	return ""
+}
+
+// write atomically writes the whatis index of the manpages of suite in
+// xref to dest. Manpages which are no longer in xref are dropped.
+func (w *whatisIndex) write(dest, suite string, xref map[string][]*manpage.Meta) error {
+	var metas []*manpage.Meta
+	for _, versions := range xref {
+		for _, m := range versions {
+			if m.Package.Suite == suite {
+				metas = append(metas, m)
+			}
+		}
+	}
+	sort.Slice(metas, func(i, j int) bool { return metas[i].ServingPath() < metas[j].ServingPath() })
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return write.Atomically(dest, true, func(f io.Writer) error {
+		bw := bufio.NewWriter(f)
+		for _, m := range metas {
+			for _, line := range w.entries[m.ServingPath()] {
+				if _, err := fmt.Fprintf(bw, "%s\t%s\n", m.ServingPath(), line); err != nil {
+					return err
+				}
+			}
+		}
+		return bw.Flush()
+	})
+}
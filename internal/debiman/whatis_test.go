@@ -0,0 +1,153 @@
+package debiman
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Debian/debiman/internal/manpage"
+)
+
+func TestParseNameSection(t *testing.T) {
+	b, err := ioutil.ReadFile("../../testdata/i3lock.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range []struct {
+		content     string
+		names       []string
+		description string
+		ok          bool
+	}{
+		{string(b), []string{"i3lock"}, "improved screen locker", true},
+		{`<h1 class="Sh" id="NAME">NAME<a class="anchor" href="#NAME">¶</a></h1>
+foo, bar − do
+  things
+<h1 class="Sh" id="SYNOPSIS">SYNOPSIS</h1>`, []string{"foo", "bar"}, "do things", true},
+		{`<section class="Sh"><h1 class="Sh" id="NAME"><a class="permalink" href="#NAME">NAME</a></h1>
+<code class="Nm">ls</code> — <span class="Nd">list directory contents</span></section>
+<section class="Sh"><h1 class="Sh" id="SYNOPSIS">SYNOPSIS</h1> a - b</section>`, []string{"ls"}, "list directory contents", true},
+		{`<h1 class="Sh" id="NAME">NAME</h1>foo`, nil, "", false},
+		{stub, nil, "", false},
+	} {
+		names, description, ok := parseNameSection(entry.content)
+		if !reflect.DeepEqual(names, entry.names) || description != entry.description || ok != entry.ok {
+			t.Errorf("parseNameSection(%q) = %v, %q, %v, want %v, %q, %v", entry.content, names, description, ok, entry.names, entry.description, entry.ok)
+		}
+	}
+}
+
+func TestDescriptions(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "debiman-whatis")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	pkg := &manpage.PkgMeta{Binarypkg: "coreutils", Suite: "jessie"}
+	ls := &manpage.Meta{Name: "ls", Section: "1", Language: "en", Package: pkg}
+	dir := &manpage.Meta{Name: "dir", Section: "1", Language: "en", Package: pkg}
+	xref := map[string][]*manpage.Meta{
+		"ls":  {ls},
+		"dir": {dir},
+	}
+	const content = `<section class="Sh"><h1 class="Sh" id="NAME"><a class="permalink" href="#NAME">NAME</a></h1>
+<code class="Nm">ls</code> — <span class="Nd">list directory contents</span></section>`
+
+	d, err := loadDescriptions(tmpdir, map[string]bool{"jessie": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.set(ls, content, false)
+	if err := d.write(descriptionsPath(tmpdir, "jessie"), "jessie", xref); err != nil {
+		t.Fatal(err)
+	}
+
+	// The description is retained across runs in which ls is not
+	// rendered, without reading its page.
+	if err := os.RemoveAll(filepath.Join(tmpdir, "jessie")); err != nil {
+		t.Fatal(err)
+	}
+	d, err = loadDescriptions(tmpdir, map[string]bool{"jessie": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := d.description(ls), "list directory contents"; got != want {
+		t.Errorf("description(ls) = %q, want %q", got, want)
+	}
+	if got := d.description(dir); got != "" {
+		t.Errorf("description(dir) = %q, want \"\" (not rendered)", got)
+	}
+}
+
+func TestWhatisIndex(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "debiman-whatis")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	pkg := &manpage.PkgMeta{Binarypkg: "foo", Suite: "jessie"}
+	foo := &manpage.Meta{Name: "foo", Section: "1", Language: "en", Package: pkg}
+	bar := &manpage.Meta{Name: "bar", Section: "1", Language: "en", Package: pkg}
+	gone := &manpage.Meta{Name: "gone", Section: "1", Language: "en", Package: pkg}
+	xref := map[string][]*manpage.Meta{
+		"foo": {foo},
+		"bar": {bar},
+	}
+	const section = `<h1 class="Sh" id="NAME">NAME</h1>foo, bar - do things`
+
+	w, err := loadWhatisIndex(tmpdir, map[string]bool{"jessie": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.set(foo, section, false)
+	w.set(bar, section, true) // symlink to foo
+	w.set(gone, section, false)
+	dest := whatisPath(tmpdir, "jessie")
+	if err := w.write(dest, "jessie", xref); err != nil {
+		t.Fatal(err)
+	}
+
+	// Entries are retained across runs in which foo is not rendered.
+	w, err = loadWhatisIndex(tmpdir, map[string]bool{"jessie": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.write(dest, "jessie", xref); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join([]string{
+		"jessie/foo/foo.1.en\tfoo(1) - do things",
+		"jessie/foo/foo.1.en\tbar(1) - do things",
+		"",
+	}, "\n")
+	if got := string(b); got != want {
+		t.Errorf("%s: got %q, want %q", filepath.Base(dest), got, want)
+	}
+
+	if got, want := w.description(foo), "do things"; got != want {
+		t.Errorf("description(foo) = %q, want %q", got, want)
+	}
+	if got, want := w.description(bar), ""; got != want {
+		t.Errorf("description(bar) = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,167 @@
+package debiman
+
+import (
+	"flag"
+	"io"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Debian/debiman/internal/manpage"
+	pb "github.com/Debian/debiman/internal/proto"
+	"github.com/Debian/debiman/internal/write"
+)
+
+const indexWriteAttempts = 3
+
+var sectionPriority = flag.String("section_priority",
+	"",
+	"Comma-separated list of sections (e.g. “1,8,6”), most preferred first, to which debiman-auxserver and debiman-idx2rwmap resolve requests which do not specify a section, e.g. /ls. Stored in the auxserver index. If empty (or none of the listed sections contain the manpage), the lowest section in string order is used")
+
+// sectionPriorityList returns the non-empty sections of -section_priority.
+func sectionPriorityList() []string {
+	var sections []string
+	for _, s := range strings.Split(*sectionPriority, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			sections = append(sections, s)
+		}
+	}
+	return sections
+}
+
+// writeIndex serializes an index for the redirect package (used in
+// debiman-auxserver) to dest, one entry of gv.xref at a time instead of
+// marshaling a pb.Index. The entries are still taken from gv.xref, which
+// holds all manpages of the run for resolving cross-references, so this
+// does not reduce the memory debiman requires for large mirrors.
+func writeIndex(dest string, gv globalView) error {
+	var (
+		size    countingWriter
+		orphans uint64
+	)
+	// Retry transient failures (e.g. a full disk which is being
+	// cleaned up), as failing here wastes the entire run. The
+	// previous index stays in place until the new one is complete.
+	var err error
+	for attempt := 1; ; attempt++ {
+		size = 0
+		err = write.AtomicallySynced(dest, func(w io.Writer) error {
+			var err error
+			orphans, err = streamIndex(io.MultiWriter(w, &size), gv)
+			return err
+		})
+		if err == nil || attempt == indexWriteAttempts {
+			break
+		}
+		log.Printf("writing index %q failed (attempt %d of %d), retrying: %v", dest, attempt, indexWriteAttempts, err)
+		time.Sleep(time.Duration(attempt) * time.Second)
+	}
+	if err != nil {
+		return err
+	}
+	atomic.AddUint64(&gv.stats.IndexBytes, uint64(size))
+	atomic.AddUint64(&gv.stats.IndexEntriesOrphaned, orphans)
+	return nil
+}
+
+// streamIndex writes the index to w and returns the number of orphaned
+// entries.
+func streamIndex(w io.Writer, gv globalView) (orphans uint64, err error) {
+	iw := pb.NewIndexWriter(w)
+	path := manpage.URLCase.Path
+
+	// Only languages and sections are aggregated, as they need to be
+	// written after all entries.
+	langs := make(map[string]bool)
+	sections := make(map[string]bool)
+	if gv.rebuild != nil {
+		if err := writeKeptEntries(iw, gv, langs, sections); err != nil {
+			return 0, err
+		}
+	}
+	for _, x := range gv.xref {
+		for _, m := range x {
+			if gv.rebuild != nil && !gv.rebuild[path(m.Package.Binarypkg)] {
+				continue // kept, see writeKeptEntries
+			}
+			if (*minManpageBytes > 0 || *maxManpageBytes > 0) && sizeFiltered(*servingDir, m) {
+				continue // logged during extraction
+			}
+			servingPath := m.ServingPath()
+			target, alias := gv.aliases[servingPath]
+			if alias {
+				if target == "" {
+					continue // broken, logged by resolveAliases
+				}
+				servingPath = target
+			}
+			if orphaned(*servingDir, servingPath) {
+				orphans++
+				log.Printf("index entry %s points to missing %q", m.PermaLink(), servingPath+".html.gz")
+				if *dropOrphanedEntries {
+					continue
+				}
+			}
+			if *dropEmptyManpages && *minTextLength > 0 && flaggedEmpty(*servingDir, m) {
+				log.Printf("omitting index entry %s: %q is (nearly) empty", m.PermaLink(), m.ServingPath()+".html.gz")
+				continue
+			}
+			if err := iw.WriteEntry(&pb.IndexEntry{
+				Name:      path(m.Name),
+				Suite:     path(m.Package.Suite),
+				Binarypkg: path(m.Package.Binarypkg),
+				Section:   m.Section,
+				Language:  m.Language,
+
+				Architecture: m.Package.Architecture,
+				Target:       target,
+			}); err != nil {
+				return orphans, err
+			}
+			langs[m.Language] = true
+			sections[m.Section] = true
+			sections[m.MainSection()] = true
+		}
+	}
+
+	for lang := range langs {
+		if err := iw.WriteLanguage(lang); err != nil {
+			return orphans, err
+		}
+	}
+
+	// Names can collide after applying the URL case policy.
+	suites := make(map[string]string, len(gv.idxSuites))
+	for name, suite := range gv.idxSuites {
+		if !gv.suites[suite] {
+			continue // not selected by -suites
+		}
+		suites[path(name)] = path(suite)
+	}
+	for name, suite := range suites {
+		if err := iw.WriteSuite(name, suite); err != nil {
+			return orphans, err
+		}
+	}
+
+	for section := range sections {
+		if err := iw.WriteSection(section); err != nil {
+			return orphans, err
+		}
+	}
+
+	if err := iw.WriteURLCase(manpage.URLCase.String()); err != nil {
+		return orphans, err
+	}
+
+	for _, section := range sectionPriorityList() {
+		if err := iw.WriteSectionPriority(section); err != nil {
+			return orphans, err
+		}
+	}
+
+	iw.SetBuilt(gv.start)
+	iw.SetGroupLanguages(*groupLanguages)
+	return orphans, iw.Close()
+}
@@ -0,0 +1,63 @@
+package debiman
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Debian/debiman/internal/manpage"
+	"github.com/Debian/debiman/internal/redirect"
+)
+
+func TestWriteIndexOrphaned(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "debiman-writeindex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	rendered := mustParseFromServingPath(t, "jessie/cron/crontab.5.en")
+	missing := mustParseFromServingPath(t, "jessie/manpages-ja/crontab.5.ja")
+	fn := filepath.Join(tmpdir, rendered.ServingPath()+".html.gz")
+	if err := os.MkdirAll(filepath.Dir(fn), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(fn, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	oldServingDir, oldDrop := *servingDir, *dropOrphanedEntries
+	defer func() { *servingDir, *dropOrphanedEntries = oldServingDir, oldDrop }()
+	*servingDir = tmpdir
+
+	for _, drop := range []bool{false, true} {
+		*dropOrphanedEntries = drop
+		gv := globalView{
+			xref: map[string][]*manpage.Meta{
+				"crontab": []*manpage.Meta{rendered, missing},
+			},
+			suites:    map[string]bool{"jessie": true},
+			idxSuites: map[string]string{"jessie": "jessie"},
+			stats:     new(stats),
+		}
+		dest := filepath.Join(tmpdir, "auxserver.idx")
+		if err := writeIndex(dest, gv); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := gv.stats.IndexEntriesOrphaned, uint64(1); got != want {
+			t.Errorf("drop=%v: IndexEntriesOrphaned = %d, want %d", drop, got, want)
+		}
+		idx, err := redirect.IndexFromProto(dest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := 2
+		if drop {
+			want = 1
+		}
+		if got := len(idx.Entries["crontab"]); got != want {
+			t.Errorf("drop=%v: got %d index entries, want %d", drop, got, want)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package debiman
+
+import (
+	"path/filepath"
+
+	"github.com/Debian/debiman/internal/blob"
+)
+
+// encodedVariant returns whether dest is served with a Content-Encoding
+// (as opposed to e.g. raw manpages, which are served as .gz files), so
+// that a zstd variant of it is useful.
+func encodedVariant(dest string) bool {
+	return blob.Describe(filepath.ToSlash(dest)).ContentEncoding == "gzip"
+}
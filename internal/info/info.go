@@ -0,0 +1,219 @@
+// Package info converts GNU info documents (as installed into
+// /usr/share/info by makeinfo) into HTML.
+package info
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Node is a node of an info document.
+type Node struct {
+	Name string
+
+	// Next, Prev and Up are the names of the nodes the header of this
+	// node points to, empty if there is no such pointer. Nodes of
+	// other documents are prefixed with the document, e.g. “(dir)”.
+	Next, Prev, Up string
+
+	// Text is the content of the node, without its header line.
+	Text string
+}
+
+var headerField = regexp.MustCompile(`(File|Node|Next|Prev|Previous|Up):[ \t]*([^,\t\n]+)`)
+
+// markup matches the sequences with which makeinfo marks up index
+// entries and images, which are not displayed by info readers.
+var markup = regexp.MustCompile("\x00\x08\\[[^\x00]*\x00\x08\\]")
+
+// Parse returns the nodes of the info document read from r. The
+// document can consist of multiple files (e.g. coreutils.info and
+// coreutils.info-1, coreutils.info-2, …), which need to be
+// concatenated. Tag tables and indirect tables are skipped.
+func Parse(r io.Reader) ([]Node, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var nodes []Node
+	for _, chunk := range bytes.Split(b, []byte{0x1f}) {
+		chunk = bytes.TrimLeft(chunk, "\f\r\n")
+		header := chunk
+		var text []byte
+		if idx := bytes.IndexByte(chunk, '\n'); idx > -1 {
+			header, text = chunk[:idx], chunk[idx+1:]
+		}
+		var n Node
+		for _, m := range headerField.FindAllSubmatch(header, -1) {
+			value := normalize(string(m[2]))
+			switch string(m[1]) {
+			case "Node":
+				n.Name = value
+			case "Next":
+				n.Next = value
+			case "Prev", "Previous":
+				n.Prev = value
+			case "Up":
+				n.Up = value
+			}
+		}
+		if n.Name == "" {
+			// The preamble, the tag table or the indirect table.
+			continue
+		}
+		n.Text = string(markup.ReplaceAll(text, nil))
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
+
+// normalize returns the node name s with the quoting of special
+// characters removed and whitespace collapsed, so that references
+// spanning multiple lines match the node names.
+func normalize(s string) string {
+	return strings.Join(strings.Fields(strings.Replace(s, "\x7f", "", -1)), " ")
+}
+
+// Anchor returns the id attribute of the HTML element of the node
+// named name. Like headings of manpages, spaces are replaced with
+// underscores.
+func Anchor(name string) string {
+	return strings.Replace(name, " ", "_", -1)
+}
+
+func fragment(name string) string {
+	u := url.URL{Fragment: Anchor(name)}
+	return u.String()
+}
+
+// reference matches cross-references (“*Note Node::”, “*note Label:
+// (file)Node.”) and menu entries (“* Node::”, “* Label: Node.”). Each
+// form captures the node name in its own group.
+var reference = regexp.MustCompile(`(?m)` +
+	`\*[Nn]ote\s+([^:*]+?)::` +
+	`|\*[Nn]ote\s+[^:*]+?:\s+((?:\([^)\s]+\))?[^.,:*]*?)[.,]` +
+	`|^\* ([^:\n]+?)::` +
+	`|^\* [^:\n]+?:[ \t]+((?:\([^)\s]+\))?[^.,:\t\n]*?)[.,\t\n]`)
+
+type converter struct {
+	known   map[string]bool
+	resolve func(ref string) string
+}
+
+// href returns the link target of a reference to the node named node,
+// or the empty string if node cannot be found.
+func (c *converter) href(node string) string {
+	node = normalize(node)
+	if !strings.HasPrefix(node, "(") {
+		if c.known[node] {
+			return fragment(node)
+		}
+		return ""
+	}
+	end := strings.Index(node, ")")
+	if end == -1 || c.resolve == nil {
+		return ""
+	}
+	doc := strings.TrimSuffix(path.Base(node[1:end]), ".info")
+	if doc == "dir" {
+		return "" // the directory of all info documents
+	}
+	u := c.resolve(doc + "(i)")
+	if u == "" {
+		return ""
+	}
+	if name := strings.TrimSpace(node[end+1:]); name != "" && name != "Top" {
+		u += fragment(name)
+	}
+	return u
+}
+
+// link writes text with its references turned into links.
+func (c *converter) link(buf *bytes.Buffer, text string) {
+	var last int
+	for _, m := range reference.FindAllStringSubmatchIndex(text, -1) {
+		start, end := m[0], m[1]
+		var node string
+		switch {
+		case m[2] != -1: // *Note Node::
+			node = text[m[2]:m[3]]
+		case m[4] != -1: // *Note Label: Node.
+			node, end = text[m[4]:m[5]], m[5]
+		case m[6] != -1: // * Node::
+			node = text[m[6]:m[7]]
+		case m[8] != -1: // * Label: Node.
+			node, end = text[m[8]:m[9]], m[9]
+		}
+		if strings.HasPrefix(text[start:], "* ") {
+			start += len("* ") // keep the menu bullet outside of the link
+		}
+		target := c.href(node)
+		if target == "" {
+			continue
+		}
+		buf.WriteString(html.EscapeString(text[last:start]))
+		fmt.Fprintf(buf, `<a href="%s">%s</a>`, html.EscapeString(target), html.EscapeString(text[start:end]))
+		last = end
+	}
+	buf.WriteString(html.EscapeString(text[last:]))
+}
+
+// ToHTML converts the info document read from r into HTML: a <pre>
+// element per node, with cross-references, menu entries and the
+// pointers of each node turned into links. resolve is called for
+// references to other info documents, with e.g. “coreutils(i)”, and
+// returns the URL of the document or the empty string. The node names
+// are returned as table of contents.
+func ToHTML(r io.Reader, resolve func(ref string) string) (doc string, toc []string, err error) {
+	nodes, err := Parse(r)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(nodes) == 0 {
+		return "", nil, fmt.Errorf("no info nodes found")
+	}
+	c := &converter{
+		known:   make(map[string]bool, len(nodes)),
+		resolve: resolve,
+	}
+	for _, n := range nodes {
+		c.known[n.Name] = true
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("<div class=\"mandoc\">\n")
+	for _, n := range nodes {
+		toc = append(toc, n.Name)
+		fmt.Fprintf(&buf, "<section class=\"info-node\" id=\"%s\">\n", html.EscapeString(Anchor(n.Name)))
+		var nav []string
+		for _, p := range []struct{ label, node string }{
+			{"Next", n.Next},
+			{"Prev", n.Prev},
+			{"Up", n.Up},
+		} {
+			if p.node == "" {
+				continue
+			}
+			entry := p.label + ": " + html.EscapeString(p.node)
+			if target := c.href(p.node); target != "" {
+				entry = fmt.Sprintf(`%s: <a href="%s">%s</a>`, p.label, html.EscapeString(target), html.EscapeString(p.node))
+			}
+			nav = append(nav, entry)
+		}
+		if len(nav) > 0 {
+			fmt.Fprintf(&buf, "<p class=\"info-nav\">%s</p>\n", strings.Join(nav, ", "))
+		}
+		buf.WriteString("<pre>")
+		c.link(&buf, strings.TrimRight(n.Text, "\n"))
+		buf.WriteString("</pre>\n</section>\n")
+	}
+	buf.WriteString("</div>\n")
+	return buf.String(), toc, nil
+}
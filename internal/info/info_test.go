@@ -0,0 +1,107 @@
+package info
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// doc is a (shortened) split info document as written by makeinfo:
+// the main file with the indirect and tag tables, followed by the
+// nodes of the first part.
+const doc = "This is hello.info, produced by makeinfo version 6.3 from hello.texi.\n" +
+	"\x1f\nIndirect:\nhello.info-1: 1000\n" +
+	"\x1f\nTag Table:\n(Indirect)\nNode: Top\x7f1000\nNode: Invoking hello\x7f1500\n" +
+	"\x1f\nEnd Tag Table\n" +
+	"This is hello.info, produced by makeinfo version 6.3 from hello.texi.\n" +
+	"\x1f\n" +
+	"File: hello.info,  Node: Top,  Next: Invoking hello,  Up: (dir)\n" +
+	"\n" +
+	"GNU Hello\n" +
+	"*********\n" +
+	"\n" +
+	"This manual documents GNU Hello <greeting>.\n" +
+	"\n" +
+	"* Menu:\n" +
+	"\n" +
+	"* Invoking hello::     How to run hello.\n" +
+	"* Output: Invoking hello.  What hello prints.\n" +
+	"* Bugs::               Not a node of this document.\n" +
+	"\n" +
+	"\x1f\n" +
+	"File: hello.info,  Node: Invoking hello,  Prev: Top,  Up: Top\n" +
+	"\n" +
+	"1 Invoking 'hello'\n" +
+	"******************\n" +
+	"\n" +
+	"\x00\x08[index\x00\x08]\n" +
+	"Options are parsed as usual, *note (coreutils)Common\n" +
+	"options::.  See also *note Top::, *note the manual: (sed)Top, and\n" +
+	"*note Hello: Top.\n"
+
+func TestParse(t *testing.T) {
+	nodes, err := Parse(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, n := range nodes {
+		names = append(names, n.Name)
+	}
+	if want := []string{"Top", "Invoking hello"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("unexpected nodes: got %q, want %q", names, want)
+	}
+	if got, want := nodes[0].Next, "Invoking hello"; got != want {
+		t.Errorf("unexpected Next pointer: got %q, want %q", got, want)
+	}
+	if got, want := nodes[0].Up, "(dir)"; got != want {
+		t.Errorf("unexpected Up pointer: got %q, want %q", got, want)
+	}
+	if got, want := nodes[1].Prev, "Top"; got != want {
+		t.Errorf("unexpected Prev pointer: got %q, want %q", got, want)
+	}
+	if strings.Contains(nodes[1].Text, "index") {
+		t.Errorf("index entry markup not removed: %q", nodes[1].Text)
+	}
+}
+
+func TestToHTML(t *testing.T) {
+	var refs []string
+	out, toc, err := ToHTML(strings.NewReader(doc), func(ref string) string {
+		refs = append(refs, ref)
+		if ref == "coreutils(i)" {
+			return "/jessie/coreutils/coreutils.info.en.html"
+		}
+		return ""
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Top", "Invoking hello"}; !reflect.DeepEqual(toc, want) {
+		t.Errorf("unexpected toc: got %q, want %q", toc, want)
+	}
+	if want := []string{"coreutils(i)", "sed(i)"}; !reflect.DeepEqual(refs, want) {
+		t.Errorf("unexpected references resolved: got %q, want %q", refs, want)
+	}
+	for _, want := range []string{
+		`<div class="mandoc">`,
+		`<section class="info-node" id="Invoking_hello">`,
+		`<p class="info-nav">Next: <a href="#Invoking_hello">Invoking hello</a>, Up: (dir)</p>`,
+		`This manual documents GNU Hello &lt;greeting&gt;.`,
+		`* <a href="#Invoking_hello">Invoking hello::</a>     How to run hello.`,
+		`* <a href="#Invoking_hello">Output: Invoking hello</a>.  What hello prints.`,
+		`* Bugs::`,
+		"<a href=\"/jessie/coreutils/coreutils.info.en.html#Common_options\">*note (coreutils)Common\noptions::</a>.",
+		`<a href="#Top">*note Top::</a>`,
+		`*note the manual: (sed)Top,`,
+		`<a href="#Top">*note Hello: Top</a>.`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+
+	if _, _, err := ToHTML(strings.NewReader("not an info document"), nil); err == nil {
+		t.Errorf("ToHTML unexpectedly accepted a document without nodes")
+	}
+}
@@ -0,0 +1,32 @@
+package l10n
+
+func init() {
+	register("de", map[string]string{
+		"links":                "Links",
+		"language-indep link":  "sprachunabhängiger Link",
+		"package tracker":      "Paket-Tracker",
+		"raw man page":         "Rohfassung der Handbuchseite",
+		"table of contents":    "Inhaltsverzeichnis",
+		"other versions":       "andere Versionen",
+		"other languages":      "andere Sprachen",
+		"other sections":       "andere Abschnitte",
+		"conflicting packages": "kollidierende Pakete",
+		"Scroll to navigation": "Zur Navigation",
+		"manpage name":         "Name der Handbuchseite",
+		"Jump":                 "Los",
+		"Skip Quicknav":        "Schnellnavigation überspringen",
+		"Index":                "Übersicht",
+		"Page last updated":    "Seite zuletzt aktualisiert",
+		"Source file:":         "Quelldatei:",
+		"from":                 "aus",
+		"Source last updated:": "Quelle zuletzt aktualisiert:",
+		"Report a bug:":        "Fehler melden:",
+		"Converted to HTML:":   "In HTML konvertiert:",
+		"Sorry, the manpage could not be rendered!": "Die Handbuchseite konnte leider nicht dargestellt werden!",
+		"Error message:": "Fehlermeldung:",
+		"This manpage is not available in the requested suite": "Diese Handbuchseite ist in der angeforderten Suite nicht verfügbar",
+		"Showing the version from":                             "Angezeigt wird die Version aus",
+		"latest":                                               "neueste",
+		"Recent changes":                                       "Letzte Änderungen",
+	})
+}
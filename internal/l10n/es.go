@@ -0,0 +1,32 @@
+package l10n
+
+func init() {
+	register("es", map[string]string{
+		"links":                "enlaces",
+		"language-indep link":  "enlace independiente del idioma",
+		"package tracker":      "seguimiento del paquete",
+		"raw man page":         "página de manual sin formato",
+		"table of contents":    "índice",
+		"other versions":       "otras versiones",
+		"other languages":      "otros idiomas",
+		"other sections":       "otras secciones",
+		"conflicting packages": "paquetes en conflicto",
+		"Scroll to navigation": "Ir a la navegación",
+		"manpage name":         "nombre de la página de manual",
+		"Jump":                 "Ir",
+		"Skip Quicknav":        "Saltar la navegación rápida",
+		"Index":                "Inicio",
+		"Page last updated":    "Última actualización de la página",
+		"Source file:":         "Archivo fuente:",
+		"from":                 "de",
+		"Source last updated:": "Última actualización de la fuente:",
+		"Report a bug:":        "Informar de un error:",
+		"Converted to HTML:":   "Convertida a HTML:",
+		"Sorry, the manpage could not be rendered!": "¡Lo sentimos, no se pudo mostrar la página de manual!",
+		"Error message:": "Mensaje de error:",
+		"This manpage is not available in the requested suite": "Esta página de manual no está disponible en la suite solicitada",
+		"Showing the version from":                             "Se muestra la versión de",
+		"latest":                                               "más reciente",
+		"Recent changes":                                       "Cambios recientes",
+	})
+}
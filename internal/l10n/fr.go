@@ -0,0 +1,32 @@
+package l10n
+
+func init() {
+	register("fr", map[string]string{
+		"links":                "liens",
+		"language-indep link":  "lien indépendant de la langue",
+		"package tracker":      "suivi du paquet",
+		"raw man page":         "page de manuel brute",
+		"table of contents":    "table des matières",
+		"other versions":       "autres versions",
+		"other languages":      "autres langues",
+		"other sections":       "autres sections",
+		"conflicting packages": "paquets en conflit",
+		"Scroll to navigation": "Aller à la navigation",
+		"manpage name":         "nom de la page de manuel",
+		"Jump":                 "Aller",
+		"Skip Quicknav":        "Passer la navigation rapide",
+		"Index":                "Accueil",
+		"Page last updated":    "Dernière mise à jour de la page",
+		"Source file:":         "Fichier source :",
+		"from":                 "de",
+		"Source last updated:": "Dernière mise à jour de la source :",
+		"Report a bug:":        "Signaler un bogue :",
+		"Converted to HTML:":   "Convertie en HTML :",
+		"Sorry, the manpage could not be rendered!": "Désolé, la page de manuel n’a pas pu être affichée !",
+		"Error message:": "Message d’erreur :",
+		"This manpage is not available in the requested suite": "Cette page de manuel n’est pas disponible dans la suite demandée",
+		"Showing the version from":                             "Version affichée :",
+		"latest":                                               "dernière",
+		"Recent changes":                                       "Modifications récentes",
+	})
+}
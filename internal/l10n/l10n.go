@@ -0,0 +1,44 @@
+// Package l10n translates the strings of the debiman UI chrome
+// (panel headings, links, search box, …) into the language of the
+// page. The manpage content itself is not touched.
+//
+// To add a language, add a file containing an init function which
+// calls register with a catalog mapping the English message to its
+// translation (see de.go). Untranslated messages fall back to English.
+package l10n
+
+import "strings"
+
+// catalogs maps from a language (as in manpage.Meta.Language,
+// e.g. “fr” or “pt_BR”) to a catalog.
+var catalogs = make(map[string]map[string]string)
+
+func register(lang string, catalog map[string]string) {
+	catalogs[lang] = catalog
+}
+
+// Translate returns the translation of the English message msg into
+// lang. If lang is a locale like “pt_BR” without a catalog of its
+// own, the catalog of the base language (“pt”) is used.
+func Translate(lang, msg string) string {
+	for lang != "" {
+		if translated, ok := catalogs[lang][msg]; ok {
+			return translated
+		}
+		idx := strings.IndexAny(lang, "_@")
+		if idx == -1 {
+			break
+		}
+		lang = lang[:idx]
+	}
+	return msg
+}
+
+// Languages returns the languages for which a catalog is available.
+func Languages() []string {
+	langs := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	return langs
+}
@@ -0,0 +1,59 @@
+package l10n
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestTranslate(t *testing.T) {
+	table := []struct {
+		lang string
+		msg  string
+		want string
+	}{
+		{lang: "fr", msg: "other languages", want: "autres langues"},
+		{lang: "de_CH", msg: "other languages", want: "andere Sprachen"},
+		{lang: "ca@valencia", msg: "other languages", want: "other languages"},
+		{lang: "en", msg: "other languages", want: "other languages"},
+		{lang: "", msg: "other languages", want: "other languages"},
+		{lang: "fr", msg: "not in any catalog", want: "not in any catalog"},
+	}
+	for _, entry := range table {
+		if got := Translate(entry.lang, entry.msg); got != entry.want {
+			t.Errorf("Translate(%q, %q) = %q, want %q", entry.lang, entry.msg, got, entry.want)
+		}
+	}
+}
+
+// TestCatalogsMatchTemplates verifies that every translated message is
+// actually used in the templates, which catches typos and messages
+// which were changed in the templates only.
+func TestCatalogsMatchTemplates(t *testing.T) {
+	tmpls, err := filepath.Glob("../../assets/*.tmpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	used := make(map[string]bool)
+	re := regexp.MustCompile(`\{\{ T \$\.Meta "([^"]+)" \}\}`)
+	for _, fn := range tmpls {
+		b, err := ioutil.ReadFile(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range re.FindAllStringSubmatch(string(b), -1) {
+			used[m[1]] = true
+		}
+	}
+	if len(used) == 0 {
+		t.Fatalf("no translatable messages found in %v", tmpls)
+	}
+	for _, lang := range Languages() {
+		for msg := range catalogs[lang] {
+			if !used[msg] {
+				t.Errorf("catalog %q: message %q is not used in any template", lang, msg)
+			}
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Package logging implements a small leveled wrapper around the
+// standard log package.
+//
+// The standard logger (as used by log.Printf throughout debiman)
+// corresponds to the Info level: SetupStd discards its output at the
+// Error level, so that only errors remain.
+package logging
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+)
+
+type Level int
+
+const (
+	// Error only logs errors.
+	Error Level = iota
+
+	// Info logs progress and summary lines. This is the default.
+	Info
+
+	// Debug additionally logs timings and decisions, e.g. how long
+	// each package took to extract or which suite a redirect picked.
+	Debug
+)
+
+var names = map[Level]string{
+	Error: "error",
+	Info:  "info",
+	Debug: "debug",
+}
+
+func (l Level) String() string {
+	if name, ok := names[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("Level(%d)", int(l))
+}
+
+// ParseLevel parses a level name as returned by String.
+func ParseLevel(s string) (Level, error) {
+	for l, name := range names {
+		if name == s {
+			return l, nil
+		}
+	}
+	return Info, fmt.Errorf("unknown log level %q (want one of error, info, debug)", s)
+}
+
+// Logger logs messages of up to its level. A nil *Logger discards all
+// messages, so that callers need not check whether logging is
+// configured.
+type Logger struct {
+	level Level
+	l     *log.Logger
+}
+
+// New returns a Logger which writes to stderr using the flags of the
+// standard logger.
+func New(level Level) *Logger {
+	return &Logger{
+		level: level,
+		l:     log.New(os.Stderr, "", log.Flags()),
+	}
+}
+
+// SetupStd configures the standard logger according to level and
+// returns a Logger for the same level.
+func SetupStd(level Level) *Logger {
+	l := New(level)
+	if level < Info {
+		log.SetOutput(ioutil.Discard)
+	}
+	return l
+}
+
+// Enabled returns whether messages of level are logged.
+func (l *Logger) Enabled(level Level) bool {
+	return l != nil && level <= l.level
+}
+
+func (l *Logger) logf(level Level, format string, v ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	// calldepth 3 attributes the message to the caller of
+	// Errorf/Infof/Debugf when log.Lshortfile is set.
+	l.l.Output(3, fmt.Sprintf(format, v...))
+}
+
+func (l *Logger) Errorf(format string, v ...interface{}) { l.logf(Error, format, v...) }
+func (l *Logger) Infof(format string, v ...interface{})  { l.logf(Info, format, v...) }
+func (l *Logger) Debugf(format string, v ...interface{}) { l.logf(Debug, format, v...) }
+
+// Fatalf logs at the Error level (even if l is nil) and exits the
+// program.
+func (l *Logger) Fatalf(format string, v ...interface{}) {
+	if l == nil {
+		log.New(os.Stderr, "", log.Flags()).Output(2, fmt.Sprintf(format, v...))
+	} else {
+		l.l.Output(2, fmt.Sprintf(format, v...))
+	}
+	os.Exit(1)
+}
+
+// Timed logs how long what took at the Debug level once the returned
+// function is called, e.g.:
+//
+//	defer l.Timed("extracting %s", pkg)()
+func (l *Logger) Timed(format string, v ...interface{}) func() {
+	if !l.Enabled(Debug) {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		l.l.Output(2, fmt.Sprintf(format, v...)+" took "+time.Since(start).String())
+	}
+}
@@ -0,0 +1,45 @@
+package logging
+
+import "testing"
+
+func TestParseLevel(t *testing.T) {
+	for _, l := range []Level{Error, Info, Debug} {
+		got, err := ParseLevel(l.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != l {
+			t.Errorf("ParseLevel(%q) = %v, want %v", l.String(), got, l)
+		}
+	}
+	if _, err := ParseLevel("trace"); err == nil {
+		t.Errorf("ParseLevel(\"trace\") unexpectedly succeeded")
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	table := []struct {
+		logger *Logger
+		level  Level
+		want   bool
+	}{
+		{New(Info), Error, true},
+		{New(Info), Info, true},
+		{New(Info), Debug, false},
+		{New(Debug), Debug, true},
+		{New(Error), Info, false},
+		{nil, Error, false},
+	}
+	for _, entry := range table {
+		if got := entry.logger.Enabled(entry.level); got != entry.want {
+			t.Errorf("Enabled(%v) = %v, want %v", entry.level, got, entry.want)
+		}
+	}
+}
+
+func TestNilLogger(t *testing.T) {
+	var l *Logger
+	l.Errorf("discarded")
+	l.Debugf("discarded")
+	l.Timed("discarded")()
+}
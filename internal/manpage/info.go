@@ -0,0 +1,51 @@
+package manpage
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// InfoSection is the pseudo-section of info documents (see
+// FromInfoPath), which distinguishes them from manpages in serving
+// paths and in the auxserver index.
+const InfoSection = "info"
+
+// Format returns “info” for info documents and “man” for manpages.
+func (m *Meta) Format() string {
+	if m.Section == InfoSection {
+		return "info"
+	}
+	return "man"
+}
+
+// IsInfoDocument returns whether path (relative underneath
+// /usr/share/info) is the main file of an info document, e.g.
+// coreutils.info.gz, but not coreutils.info-1.gz (a part of the same
+// document) or dir (the index of all info documents).
+func IsInfoDocument(path string) bool {
+	return !strings.Contains(path, "/") &&
+		strings.HasSuffix(strings.TrimSuffix(path, ".gz"), ".info")
+}
+
+// FromInfoPath constructs an info document, gathering details from path
+// (relative underneath /usr/share/info). Info documents are treated
+// like English manpages of section InfoSection, so that e.g.
+// coreutils.info.gz is served as <suite>/<binarypkg>/coreutils.info.en.
+func FromInfoPath(path string, p *PkgMeta) (*Meta, error) {
+	if !IsInfoDocument(path) {
+		return nil, fmt.Errorf("%q is not the main file of an info document", path)
+	}
+	m := &Meta{
+		Name:        strings.TrimSuffix(strings.TrimSuffix(path, ".gz"), ".info"),
+		Package:     p,
+		Section:     InfoSection,
+		Language:    "en",
+		LanguageTag: language.English,
+	}
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/Debian/debiman/internal/tag"
+	"github.com/Debian/debiman/internal/urlcase"
 	"golang.org/x/text/language"
 	"pault.ag/go/debian/version"
 )
@@ -24,6 +25,16 @@ type PkgMeta struct {
 	// Suite is the Debian suite in which this binary package was
 	// found.
 	Suite string
+
+	// Description is the short description (synopsis) of the binary
+	// package, e.g. “improved dynamic tiling window manager”. Empty if
+	// unknown.
+	Description string
+
+	// Architecture is the architecture of the .deb from which the
+	// manpages were extracted if they differ across architectures
+	// (e.g. “amd64”), empty otherwise.
+	Architecture string
 }
 
 func (p *PkgMeta) SameBinary(o *PkgMeta) bool {
@@ -61,8 +72,24 @@ type Meta struct {
 	// manpage was found.
 	Language    string
 	LanguageTag language.Tag
+
+	// SanitizedFrom is the name of the manpage file if FromManPath
+	// sanitized it into Name (see Names), empty otherwise.
+	SanitizedFrom string
+
+	// Grouped is set by debiman’s -group_languages for manpages whose
+	// binary package ships them in more than one language: all of
+	// their languages are shown on one page (see PageURL).
+	Grouped bool
 }
 
+// CanonicalLanguages makes FromManPath spell the language of manpages
+// canonically (see tag.Canonical), so that e.g. the manpages found in
+// pt_BR, pt_br and pt-BR are merged into one language. debiman sets it
+// from its -canonical_languages flag (which defaults to the initial
+// value) before doing any work.
+var CanonicalLanguages = true
+
 // FromManPath constructs a manpage, gathering details from path (relative underneath /usr/share/man).
 func FromManPath(path string, p *PkgMeta) (*Meta, error) {
 	// man pages are in /usr/share/man/(<lang>/|)man<section>/<name>.<section>.gz
@@ -89,6 +116,9 @@ func FromManPath(path string, p *PkgMeta) (*Meta, error) {
 	if lang == "C" || lang == "POSIX" {
 		lang = "en"
 	}
+	if CanonicalLanguages {
+		lang = tag.Canonical(lang)
+	}
 
 	tag, err := tag.FromLocale(lang)
 	if err != nil {
@@ -113,13 +143,17 @@ func FromManPath(path string, p *PkgMeta) (*Meta, error) {
 		section = section + matches[1]
 	}
 
-	return &Meta{
+	m := &Meta{
 		Name:        strings.TrimSuffix(parts[1], "."+section+".gz"),
 		Package:     p,
 		Section:     strings.ToLower(section),
 		Language:    lang,
 		LanguageTag: tag,
-	}, nil
+	}
+	if err := m.check(); err != nil {
+		return nil, err
+	}
+	return m, nil
 }
 
 // FromServingPath constructs a manpage, gathering details from path
@@ -169,19 +203,47 @@ func (m *Meta) String() string {
 	return m.ServingPath()
 }
 
+// URLCase governs the case of the suite, binary package and name
+// components of ServingPath, RawPath and PermaLink. debiman sets it
+// from its -url_case flag before doing any work. Note that Debian
+// suite and binary package names are lowercase by policy, so in
+// practice, only manpage names are affected.
+var URLCase urlcase.Policy
+
+func (m *Meta) prefix() string {
+	return URLCase.Path(m.Package.Suite) + "/" + URLCase.Path(m.Package.Binarypkg) + "/" + URLCase.Path(m.Name)
+}
+
 func (m *Meta) ServingPath() string {
-	return m.Package.Suite + "/" + m.Package.Binarypkg + "/" + m.Name + "." + m.Section + "." + m.Language
+	return m.prefix() + "." + m.Section + "." + m.Language
 }
 
 // RawPath returns the path to access the raw manpage equivalent of
 // what is currently being served, i.e. locked to the current
 // language.
 func (m *Meta) RawPath() string {
-	return m.Package.Suite + "/" + m.Package.Binarypkg + "/" + m.Name + "." + m.Section + "." + m.Language + ".gz"
+	return m.prefix() + "." + m.Section + "." + m.Language + ".gz"
 }
 
 func (m *Meta) PermaLink() string {
-	return m.Package.Suite + "/" + m.Package.Binarypkg + "/" + m.Name + "." + m.Section
+	return m.prefix() + "." + m.Section
+}
+
+// LanguageFragment is the id of the tab panel of m on the page of a
+// Grouped manpage, e.g. “lang-de”.
+func (m *Meta) LanguageFragment() string {
+	return "lang-" + m.Language
+}
+
+// PageURL returns the path (relative to the base URL) of the page
+// which shows m: ServingPath().html, or, for Grouped manpages, the
+// language-independent page with the fragment selecting the language
+// of m, e.g. jessie/cron/crontab.5.html#lang-de.
+func (m *Meta) PageURL() string {
+	if m.Grouped {
+		return m.PermaLink() + ".html#" + m.LanguageFragment()
+	}
+	return m.ServingPath() + ".html"
 }
 
 func (m *Meta) MainSection() string {
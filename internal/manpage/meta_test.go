@@ -113,3 +113,66 @@ func TestLanguageTag(t *testing.T) {
 		})
 	}
 }
+
+func TestFromInfoPath(t *testing.T) {
+	pkg := &PkgMeta{Binarypkg: "coreutils", Suite: "testing"}
+	m, err := FromInfoPath("coreutils.info.gz", pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := m.ServingPath(), "testing/coreutils/coreutils.info.en"; got != want {
+		t.Fatalf("Unexpected serving path: got %q, want %q", got, want)
+	}
+	if got, want := m.Format(), "info"; got != want {
+		t.Fatalf("Unexpected format: got %q, want %q", got, want)
+	}
+
+	// The serving path must be understood when rendering.
+	sm, err := FromServingPath("/srv/man", "/srv/man/"+m.ServingPath()+".gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := sm.Section, InfoSection; got != want {
+		t.Fatalf("Unexpected section parsed from serving path: got %q, want %q", got, want)
+	}
+
+	for _, path := range []string{"coreutils.info-1.gz", "dir", "dir.old", "emacs-24/emacs.info.gz"} {
+		if _, err := FromInfoPath(path, pkg); err == nil {
+			t.Errorf("FromInfoPath(%q) unexpectedly succeeded", path)
+		}
+	}
+}
+
+func TestCanonicalLanguagesDefault(t *testing.T) {
+	// Like debiman’s -canonical_languages flag, so that other users of
+	// FromManPath (e.g. tests) merge differently spelled languages, too.
+	if !CanonicalLanguages {
+		t.Errorf("CanonicalLanguages = false, want true")
+	}
+}
+
+func TestCanonicalLanguages(t *testing.T) {
+	defer func(prev bool) { CanonicalLanguages = prev }(CanonicalLanguages)
+	pkg := PkgMeta{Binarypkg: "deja-dup", Suite: "testing"}
+	for _, entry := range []struct {
+		canonical bool
+		want      []string
+	}{
+		{false, []string{"testing/deja-dup/deja-dup.1.pt_BR", "testing/deja-dup/deja-dup.1.pt-BR", "testing/deja-dup/deja-dup.1.pt_br"}},
+		{true, []string{"testing/deja-dup/deja-dup.1.pt_BR", "testing/deja-dup/deja-dup.1.pt_BR", "testing/deja-dup/deja-dup.1.pt_BR"}},
+	} {
+		CanonicalLanguages = entry.canonical
+		for idx, path := range []string{"pt_BR/man1/deja-dup.1.gz", "pt-BR/man1/deja-dup.1.gz", "pt_br.UTF-8/man1/deja-dup.1.gz"} {
+			m, err := FromManPath(path, &pkg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := m.ServingPath(), entry.want[idx]; got != want {
+				t.Errorf("CanonicalLanguages=%v: FromManPath(%q): unexpected serving path: got %q, want %q", entry.canonical, path, got, want)
+			}
+			if got, want := m.LanguageTag, language.BrazilianPortuguese; got != want {
+				t.Errorf("CanonicalLanguages=%v: FromManPath(%q): unexpected language tag: got %v, want %v", entry.canonical, path, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package manpage
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// NamePolicy governs how FromManPath treats manpage names which cannot
+// safely be used in a serving path, i.e. as a file name underneath
+// -serving_dir and as a URL path component.
+type NamePolicy int
+
+const (
+	// RejectNames makes FromManPath return an *UnsafeNameError.
+	RejectNames NamePolicy = iota
+
+	// SanitizeNames makes FromManPath replace each offending
+	// character with an underscore, e.g. “foo bar” with “foo_bar”.
+	SanitizeNames
+)
+
+var namePolicies = map[NamePolicy]string{
+	RejectNames:   "reject",
+	SanitizeNames: "sanitize",
+}
+
+func (p NamePolicy) String() string {
+	if name, ok := namePolicies[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("NamePolicy(%d)", int(p))
+}
+
+// ParseNamePolicy parses a policy name as returned by String.
+func ParseNamePolicy(s string) (NamePolicy, error) {
+	for p, name := range namePolicies {
+		if name == s {
+			return p, nil
+		}
+	}
+	return RejectNames, fmt.Errorf("unknown manpage name policy %q (want one of reject, sanitize)", s)
+}
+
+// Names is the policy with which FromManPath treats unsafe manpage
+// names. debiman sets it from its -manpage_names flag (which defaults
+// to the initial value) before doing any work.
+var Names = SanitizeNames
+
+// UnsafeNameError is returned by FromManPath for manpages whose name,
+// section or language cannot safely be used in a serving path.
+type UnsafeNameError struct {
+	// Component is e.g. “name” or “section”.
+	Component string
+	Value     string
+}
+
+func (e *UnsafeNameError) Error() string {
+	return fmt.Sprintf("unsafe manpage %s %q", e.Component, e.Value)
+}
+
+// unsafeRune returns whether r must not appear in a serving path
+// component: path separators, characters which end the path of a URL,
+// whitespace (breaking URLs and rewrite maps) and non-printable
+// characters such as control characters or bidirectional overrides.
+func unsafeRune(r rune) bool {
+	switch r {
+	case '/', '\\', '?', '#', utf8.RuneError:
+		return true
+	}
+	return unicode.IsSpace(r) || !unicode.IsPrint(r)
+}
+
+// safeComponent returns whether s can be used as a serving path
+// component without escaping its directory or resulting in a hidden
+// file.
+func safeComponent(s string) bool {
+	if s == "" || strings.HasPrefix(s, ".") {
+		return false
+	}
+	return strings.IndexFunc(s, unsafeRune) == -1
+}
+
+// SanitizeName returns name with each unsafe character (and a leading
+// dot) replaced by an underscore. The empty name cannot be sanitized
+// and is returned as is.
+func SanitizeName(name string) string {
+	// strings.Map passes invalid bytes as utf8.RuneError, so the
+	// result is valid UTF-8.
+	name = strings.Map(func(r rune) rune {
+		if unsafeRune(r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if strings.HasPrefix(name, ".") {
+		name = "_" + name[1:]
+	}
+	return name
+}
+
+// checkName applies the Names policy to name, returning the name to
+// use.
+func checkName(name string) (string, error) {
+	if safeComponent(name) {
+		return name, nil
+	}
+	if Names == SanitizeNames && name != "" {
+		return SanitizeName(name), nil
+	}
+	return "", &UnsafeNameError{Component: "name", Value: name}
+}
+
+// check applies the Names policy to the name of m and verifies that
+// the other components of its serving path are safe.
+func (m *Meta) check() error {
+	name, err := checkName(m.Name)
+	if err != nil {
+		return err
+	}
+	if name != m.Name {
+		m.SanitizedFrom, m.Name = m.Name, name
+	}
+	// The other components are only verified: in practice, they are
+	// well-formed, so a bad one indicates a corrupt archive.
+	for _, c := range []struct{ component, value string }{
+		{"section", m.Section},
+		{"language", m.Language},
+		{"suite", m.Package.Suite},
+		{"binary package", m.Package.Binarypkg},
+	} {
+		if !safeComponent(c.value) {
+			return &UnsafeNameError{Component: c.component, Value: c.value}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package manpage
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNamesDefault(t *testing.T) {
+	// Like debiman’s -manpage_names flag, so that other users of
+	// FromManPath (e.g. tests) sanitize names, too.
+	if got, want := Names, SanitizeNames; got != want {
+		t.Errorf("Names = %v, want %v", got, want)
+	}
+}
+
+func TestUnsafeNames(t *testing.T) {
+	defer func(p NamePolicy) { Names = p }(Names)
+
+	pkg := PkgMeta{Binarypkg: "evil", Suite: "testing"}
+	table := []struct {
+		path string
+		pkg  PkgMeta
+		// wantSanitized is the expected name with SanitizeNames, or
+		// empty if FromManPath must fail with either policy.
+		wantSanitized string
+	}{
+		{path: "man1/foo bar.1.gz", pkg: pkg, wantSanitized: "foo_bar"},
+		{path: `man1/..\..\..\etc\passwd.1.gz`, pkg: pkg, wantSanitized: "_._.._.._etc_passwd"},
+		{path: "man1/..1.gz", pkg: pkg, wantSanitized: "_"},
+		{path: "man1/.hidden.1.gz", pkg: pkg, wantSanitized: "_hidden"},
+		{path: "man1/evil\x1b[31m.1.gz", pkg: pkg, wantSanitized: "evil_[31m"},
+		{path: "man1/\u202egnp.exe.1.gz", pkg: pkg, wantSanitized: "_gnp.exe"},
+		{path: "man1/bad\xff.1.gz", pkg: pkg, wantSanitized: "bad_"},
+		{path: "man1/what?.1.gz", pkg: pkg, wantSanitized: "what_"},
+
+		{path: "man1/.1.gz", pkg: pkg},
+		{path: "man1/../../../etc/passwd.1.gz", pkg: pkg},
+		{path: "../man1/passwd.1.gz", pkg: pkg},
+		{path: "man1/passwd.1.gz", pkg: PkgMeta{Binarypkg: "..", Suite: "testing"}},
+		{path: "man1/passwd.1.gz", pkg: PkgMeta{Binarypkg: "evil", Suite: "../.."}},
+	}
+
+	root := filepath.Join("srv", "man")
+	for _, policy := range []NamePolicy{RejectNames, SanitizeNames} {
+		Names = policy
+		for _, entry := range table {
+			entry := entry // copy
+			m, err := FromManPath(entry.path, &entry.pkg)
+			if err != nil {
+				if policy == SanitizeNames && entry.wantSanitized != "" {
+					t.Errorf("%v: FromManPath(%q): unexpected error: %v", policy, entry.path, err)
+				}
+				continue
+			}
+			if policy == RejectNames || entry.wantSanitized == "" {
+				t.Errorf("%v: FromManPath(%q) = %q, want error", policy, entry.path, m.ServingPath())
+				continue
+			}
+			if got, want := m.Name, entry.wantSanitized; got != want {
+				t.Errorf("%v: FromManPath(%q): got name %q, want %q", policy, entry.path, got, want)
+			}
+			if m.SanitizedFrom == "" {
+				t.Errorf("%v: FromManPath(%q): SanitizedFrom not set", policy, entry.path)
+			}
+			dest := filepath.Join(root, m.ServingPath()+".gz")
+			if filepath.Dir(dest) != filepath.Join(root, "testing", "evil") {
+				t.Errorf("%v: FromManPath(%q): %q escapes its package directory", policy, entry.path, dest)
+			}
+			if strings.HasPrefix(filepath.Base(dest), ".") {
+				t.Errorf("%v: FromManPath(%q): %q is a hidden file", policy, entry.path, dest)
+			}
+		}
+	}
+}
+
+func TestParseNamePolicy(t *testing.T) {
+	for _, p := range []NamePolicy{RejectNames, SanitizeNames} {
+		got, err := ParseNamePolicy(p.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != p {
+			t.Errorf("ParseNamePolicy(%q) = %v, want %v", p.String(), got, p)
+		}
+	}
+	if _, err := ParseNamePolicy("ignore"); err == nil {
+		t.Errorf("ParseNamePolicy(%q) unexpectedly succeeded", "ignore")
+	}
+}
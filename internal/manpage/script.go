@@ -0,0 +1,37 @@
+package manpage
+
+// Typesetting describes how the text of a manpage should be typeset
+// depending on its script, see Meta.Typesetting.
+type Typesetting struct {
+	// RTL is true for scripts written from right to left, whose
+	// content must carry dir="rtl".
+	RTL bool
+
+	// Class is the CSS class (see style.css) of the content, if any.
+	Class string
+}
+
+// typesettingByScript maps ISO 15924 script codes to their
+// Typesetting. Scripts not listed are typeset left to right without a
+// class.
+var typesettingByScript = map[string]Typesetting{
+	// Right-to-left scripts.
+	"Arab": {RTL: true}, // e.g. ar, fa, ur
+	"Hebr": {RTL: true}, // e.g. he, yi
+	"Syrc": {RTL: true},
+	"Thaa": {RTL: true}, // dv
+
+	// Chinese, Japanese and Korean text is not separated by spaces
+	// (Korean is, see style.css) and has its own line breaking rules.
+	"Hans": {Class: "script-cjk"}, // zh_CN
+	"Hant": {Class: "script-cjk"}, // zh_TW, zh_HK
+	"Jpan": {Class: "script-cjk"}, // ja
+	"Kore": {Class: "script-cjk"}, // ko
+}
+
+// Typesetting returns how to typeset the manpage, based on the (most
+// likely) script of its language.
+func (m *Meta) Typesetting() Typesetting {
+	script, _ := m.LanguageTag.Script()
+	return typesettingByScript[script.String()]
+}
@@ -10,6 +10,7 @@ It is generated from these files:
 
 It has these top-level messages:
 	IndexEntry
+	InternedEntry
 	Index
 */
 package proto
@@ -30,11 +31,13 @@ var _ = math.Inf
 const _ = proto1.ProtoPackageIsVersion2 // please upgrade the proto package
 
 type IndexEntry struct {
-	Name      string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
-	Suite     string `protobuf:"bytes,2,opt,name=suite" json:"suite,omitempty"`
-	Binarypkg string `protobuf:"bytes,3,opt,name=binarypkg" json:"binarypkg,omitempty"`
-	Section   string `protobuf:"bytes,4,opt,name=section" json:"section,omitempty"`
-	Language  string `protobuf:"bytes,5,opt,name=language" json:"language,omitempty"`
+	Name         string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	Suite        string `protobuf:"bytes,2,opt,name=suite" json:"suite,omitempty"`
+	Binarypkg    string `protobuf:"bytes,3,opt,name=binarypkg" json:"binarypkg,omitempty"`
+	Section      string `protobuf:"bytes,4,opt,name=section" json:"section,omitempty"`
+	Language     string `protobuf:"bytes,5,opt,name=language" json:"language,omitempty"`
+	Architecture string `protobuf:"bytes,6,opt,name=architecture" json:"architecture,omitempty"`
+	Target       string `protobuf:"bytes,7,opt,name=target" json:"target,omitempty"`
 }
 
 func (m *IndexEntry) Reset()                    { *m = IndexEntry{} }
@@ -77,17 +80,102 @@ func (m *IndexEntry) GetLanguage() string {
 	return ""
 }
 
+func (m *IndexEntry) GetArchitecture() string {
+	if m != nil {
+		return m.Architecture
+	}
+	return ""
+}
+
+func (m *IndexEntry) GetTarget() string {
+	if m != nil {
+		return m.Target
+	}
+	return ""
+}
+
+type InternedEntry struct {
+	Name         uint32 `protobuf:"varint,1,opt,name=name" json:"name,omitempty"`
+	Suite        uint32 `protobuf:"varint,2,opt,name=suite" json:"suite,omitempty"`
+	Binarypkg    uint32 `protobuf:"varint,3,opt,name=binarypkg" json:"binarypkg,omitempty"`
+	Section      uint32 `protobuf:"varint,4,opt,name=section" json:"section,omitempty"`
+	Language     uint32 `protobuf:"varint,5,opt,name=language" json:"language,omitempty"`
+	Architecture uint32 `protobuf:"varint,6,opt,name=architecture" json:"architecture,omitempty"`
+	Target       uint32 `protobuf:"varint,7,opt,name=target" json:"target,omitempty"`
+}
+
+func (m *InternedEntry) Reset()                    { *m = InternedEntry{} }
+func (m *InternedEntry) String() string            { return proto1.CompactTextString(m) }
+func (*InternedEntry) ProtoMessage()               {}
+func (*InternedEntry) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+
+func (m *InternedEntry) GetName() uint32 {
+	if m != nil {
+		return m.Name
+	}
+	return 0
+}
+
+func (m *InternedEntry) GetSuite() uint32 {
+	if m != nil {
+		return m.Suite
+	}
+	return 0
+}
+
+func (m *InternedEntry) GetBinarypkg() uint32 {
+	if m != nil {
+		return m.Binarypkg
+	}
+	return 0
+}
+
+func (m *InternedEntry) GetSection() uint32 {
+	if m != nil {
+		return m.Section
+	}
+	return 0
+}
+
+func (m *InternedEntry) GetLanguage() uint32 {
+	if m != nil {
+		return m.Language
+	}
+	return 0
+}
+
+func (m *InternedEntry) GetArchitecture() uint32 {
+	if m != nil {
+		return m.Architecture
+	}
+	return 0
+}
+
+func (m *InternedEntry) GetTarget() uint32 {
+	if m != nil {
+		return m.Target
+	}
+	return 0
+}
+
 type Index struct {
-	Entry    []*IndexEntry     `protobuf:"bytes,1,rep,name=entry" json:"entry,omitempty"`
-	Language []string          `protobuf:"bytes,2,rep,name=language" json:"language,omitempty"`
-	Suite    map[string]string `protobuf:"bytes,3,rep,name=suite" json:"suite,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
-	Section  []string          `protobuf:"bytes,4,rep,name=section" json:"section,omitempty"`
+	Entry           []*IndexEntry     `protobuf:"bytes,1,rep,name=entry" json:"entry,omitempty"`
+	Language        []string          `protobuf:"bytes,2,rep,name=language" json:"language,omitempty"`
+	Suite           map[string]string `protobuf:"bytes,3,rep,name=suite" json:"suite,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Section         []string          `protobuf:"bytes,4,rep,name=section" json:"section,omitempty"`
+	UrlCase         string            `protobuf:"bytes,5,opt,name=url_case,json=urlCase" json:"url_case,omitempty"`
+	SectionPriority []string          `protobuf:"bytes,6,rep,name=section_priority,json=sectionPriority" json:"section_priority,omitempty"`
+	Version         uint32            `protobuf:"varint,7,opt,name=version" json:"version,omitempty"`
+	StringTable     []string          `protobuf:"bytes,8,rep,name=string_table,json=stringTable" json:"string_table,omitempty"`
+	InternedEntry   []*InternedEntry  `protobuf:"bytes,9,rep,name=interned_entry,json=internedEntry" json:"interned_entry,omitempty"`
+	Built           int64             `protobuf:"varint,10,opt,name=built" json:"built,omitempty"`
+	GroupLanguages  bool              `protobuf:"varint,11,opt,name=group_languages,json=groupLanguages" json:"group_languages,omitempty"`
 }
 
 func (m *Index) Reset()                    { *m = Index{} }
 func (m *Index) String() string            { return proto1.CompactTextString(m) }
 func (*Index) ProtoMessage()               {}
-func (*Index) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{1} }
+func (*Index) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
 
 func (m *Index) GetEntry() []*IndexEntry {
 	if m != nil {
@@ -117,28 +205,90 @@ func (m *Index) GetSection() []string {
 	return nil
 }
 
+func (m *Index) GetUrlCase() string {
+	if m != nil {
+		return m.UrlCase
+	}
+	return ""
+}
+
+func (m *Index) GetSectionPriority() []string {
+	if m != nil {
+		return m.SectionPriority
+	}
+	return nil
+}
+
+func (m *Index) GetVersion() uint32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *Index) GetStringTable() []string {
+	if m != nil {
+		return m.StringTable
+	}
+	return nil
+}
+
+func (m *Index) GetInternedEntry() []*InternedEntry {
+	if m != nil {
+		return m.InternedEntry
+	}
+	return nil
+}
+
+func (m *Index) GetBuilt() int64 {
+	if m != nil {
+		return m.Built
+	}
+	return 0
+}
+
+func (m *Index) GetGroupLanguages() bool {
+	if m != nil {
+		return m.GroupLanguages
+	}
+	return false
+}
+
 func init() {
 	proto1.RegisterType((*IndexEntry)(nil), "proto.IndexEntry")
+	proto1.RegisterType((*InternedEntry)(nil), "proto.InternedEntry")
 	proto1.RegisterType((*Index)(nil), "proto.Index")
 }
 
 func init() { proto1.RegisterFile("index.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 235 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x54, 0x90, 0x3d, 0x4e, 0xc4, 0x30,
-	0x10, 0x85, 0xe5, 0x78, 0x0d, 0xec, 0x6c, 0x03, 0x23, 0x24, 0xac, 0x15, 0x45, 0xb4, 0x0d, 0xdb,
-	0x90, 0x02, 0x9a, 0x88, 0x9e, 0x82, 0x36, 0x9c, 0xc0, 0x01, 0x2b, 0xb2, 0x12, 0x9c, 0x28, 0x71,
-	0x10, 0xb9, 0x02, 0x97, 0xe3, 0x4a, 0xc8, 0xe3, 0xfc, 0x56, 0x9e, 0x37, 0xcf, 0x7a, 0xfe, 0xfc,
-	0xe0, 0x60, 0xec, 0xa7, 0xfe, 0x49, 0x9a, 0xb6, 0x76, 0x35, 0x0a, 0x3a, 0x4e, 0xbf, 0x0c, 0xe0,
-	0xcd, 0xaf, 0x5f, 0xad, 0x6b, 0x07, 0x44, 0xd8, 0x59, 0xf5, 0xa5, 0x25, 0x8b, 0xd9, 0x79, 0x9f,
-	0xd1, 0x8c, 0xb7, 0x20, 0xba, 0xde, 0x38, 0x2d, 0x23, 0x5a, 0x06, 0x81, 0xf7, 0xb0, 0xcf, 0x8d,
-	0x55, 0xed, 0xd0, 0x94, 0x85, 0xe4, 0xe4, 0x2c, 0x0b, 0x94, 0x70, 0xd9, 0xe9, 0x0f, 0x67, 0x6a,
-	0x2b, 0x77, 0xe4, 0x4d, 0x12, 0x8f, 0x70, 0x55, 0x29, 0x5b, 0xf4, 0xaa, 0xd0, 0x52, 0x90, 0x35,
-	0xeb, 0xd3, 0x1f, 0x03, 0x41, 0x30, 0xf8, 0x00, 0x42, 0x7b, 0x20, 0xc9, 0x62, 0x7e, 0x3e, 0x3c,
-	0xdd, 0x04, 0xe8, 0x64, 0x21, 0xcd, 0x82, 0xbf, 0x89, 0x8b, 0x62, 0xbe, 0x8e, 0xc3, 0xc7, 0x09,
-	0x9c, 0x53, 0xc8, 0xdd, 0x3a, 0x24, 0x79, 0xf7, 0xce, 0x18, 0x15, 0x7e, 0xb4, 0x61, 0xe6, 0x2b,
-	0xe6, 0x63, 0x0a, 0xb0, 0x5c, 0xc7, 0x6b, 0xe0, 0xa5, 0x1e, 0xc6, 0x8a, 0xfc, 0xe8, 0x1b, 0xfa,
-	0x56, 0x55, 0x3f, 0x37, 0x44, 0xe2, 0x25, 0x4a, 0x59, 0x7e, 0x41, 0x4f, 0x3e, 0xff, 0x07, 0x00,
-	0x00, 0xff, 0xff, 0xe2, 0xe5, 0x32, 0xf9, 0x7b, 0x01, 0x00, 0x00,
+	// 426 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x93, 0x4f, 0x6f, 0xd3, 0x30,
+	0x18, 0xc6, 0xe5, 0x7a, 0xe9, 0x9f, 0xb7, 0xf3, 0x36, 0xac, 0x09, 0xcc, 0xc4, 0x21, 0xf4, 0xb2,
+	0x72, 0xa0, 0x07, 0xb8, 0x4c, 0x70, 0x44, 0x1c, 0x26, 0x71, 0x40, 0x81, 0x7b, 0xe4, 0x76, 0x56,
+	0xb0, 0x16, 0x9c, 0xca, 0xb1, 0x27, 0xf2, 0x05, 0x91, 0xb8, 0xf0, 0x99, 0x90, 0x5f, 0x27, 0x69,
+	0x22, 0x58, 0x39, 0xd5, 0xcf, 0xf3, 0xb6, 0x8f, 0xfc, 0x7b, 0x9f, 0x1a, 0x96, 0xda, 0xdc, 0xa9,
+	0x1f, 0x9b, 0xbd, 0xad, 0x5c, 0xc5, 0x13, 0xfc, 0x58, 0xfd, 0x24, 0x00, 0xb7, 0xc1, 0xfe, 0x68,
+	0x9c, 0x6d, 0x38, 0x87, 0x13, 0x23, 0xbf, 0x2b, 0x41, 0x52, 0xb2, 0x5e, 0x64, 0x78, 0xe6, 0x97,
+	0x90, 0xd4, 0x5e, 0x3b, 0x25, 0x26, 0x68, 0x46, 0xc1, 0x5f, 0xc0, 0x62, 0xab, 0x8d, 0xb4, 0xcd,
+	0xfe, 0xbe, 0x10, 0x14, 0x27, 0x07, 0x83, 0x0b, 0x98, 0xd5, 0x6a, 0xe7, 0x74, 0x65, 0xc4, 0x09,
+	0xce, 0x3a, 0xc9, 0xaf, 0x60, 0x5e, 0x4a, 0x53, 0x78, 0x59, 0x28, 0x91, 0xe0, 0xa8, 0xd7, 0x7c,
+	0x05, 0xa7, 0xd2, 0xee, 0xbe, 0x69, 0xa7, 0x76, 0xce, 0x5b, 0x25, 0xa6, 0x38, 0x1f, 0x79, 0xfc,
+	0x29, 0x4c, 0x9d, 0xb4, 0x85, 0x72, 0x62, 0x86, 0xd3, 0x56, 0xad, 0x7e, 0x11, 0x60, 0xb7, 0xc6,
+	0x29, 0x6b, 0xd4, 0xdd, 0xdf, 0x2c, 0xec, 0x5f, 0x2c, 0xec, 0x51, 0x16, 0x76, 0x84, 0x85, 0x3d,
+	0xce, 0xc2, 0xfe, 0xc3, 0xc2, 0x8e, 0xb2, 0xb0, 0x9e, 0xe5, 0x37, 0x85, 0x04, 0x4b, 0xe1, 0xd7,
+	0x90, 0xa8, 0x00, 0x23, 0x48, 0x4a, 0xd7, 0xcb, 0x37, 0x4f, 0x62, 0x79, 0x9b, 0x43, 0x63, 0x59,
+	0x9c, 0x8f, 0xae, 0x32, 0x49, 0xe9, 0x68, 0xad, 0xaf, 0x3b, 0x68, 0x8a, 0x21, 0xcf, 0x86, 0x21,
+	0x9b, 0x2f, 0x61, 0xd2, 0x46, 0xc5, 0x6d, 0x8c, 0x78, 0xe9, 0xb0, 0xbb, 0xe7, 0x30, 0xf7, 0xb6,
+	0xcc, 0x77, 0xb2, 0xee, 0xba, 0x9b, 0x79, 0x5b, 0x7e, 0x90, 0xb5, 0xe2, 0xaf, 0xe0, 0xa2, 0xfd,
+	0x56, 0xbe, 0xb7, 0xba, 0xb2, 0xda, 0x35, 0x62, 0x8a, 0xbf, 0x3e, 0x6f, 0xfd, 0xcf, 0xad, 0x1d,
+	0xf2, 0x1f, 0x94, 0xad, 0x43, 0x7e, 0xc4, 0xee, 0x24, 0x7f, 0x09, 0xa7, 0xb5, 0xb3, 0xda, 0x14,
+	0xb9, 0x93, 0xdb, 0x52, 0x89, 0x39, 0x06, 0x2c, 0xa3, 0xf7, 0x35, 0x58, 0xfc, 0x3d, 0x9c, 0xe9,
+	0xb6, 0xe5, 0x3c, 0x6e, 0x66, 0x81, 0x50, 0x97, 0x3d, 0xd4, 0xe0, 0x2f, 0x90, 0x31, 0x3d, 0x94,
+	0xa1, 0xfd, 0xad, 0xd7, 0xa5, 0x13, 0x90, 0x92, 0x35, 0xcd, 0xa2, 0xe0, 0xd7, 0x70, 0x5e, 0xd8,
+	0xca, 0xef, 0xf3, 0x6e, 0x61, 0xb5, 0x58, 0xa6, 0x64, 0x3d, 0xcf, 0xce, 0xd0, 0xfe, 0xd4, 0xb9,
+	0x57, 0x37, 0x00, 0x87, 0x6d, 0xf1, 0x0b, 0xa0, 0xf7, 0xaa, 0x69, 0x5f, 0x4a, 0x38, 0x86, 0xf8,
+	0x07, 0x59, 0xfa, 0xfe, 0xa1, 0xa0, 0x78, 0x37, 0xb9, 0x21, 0xdb, 0x29, 0x5e, 0xee, 0xed, 0x9f,
+	0x01, 0x00, 0xa6, 0x0a, 0x16, 0xc7, 0x82, 0x03, 0x00, 0x00,
 }
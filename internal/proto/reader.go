@@ -0,0 +1,256 @@
+package proto
+
+import (
+	"errors"
+	"fmt"
+
+	proto1 "github.com/golang/protobuf/proto"
+)
+
+// Key of the entry field (version 0), which IndexWriter does not write.
+const keyEntry = 1<<3 | 2
+
+var errTruncated = errors.New("unexpected end of data")
+
+// decoder decodes the fields of a marshaled message one at a time.
+type decoder struct {
+	b   []byte
+	off int
+}
+
+func (d *decoder) varint() (uint64, error) {
+	v, n := proto1.DecodeVarint(d.b[d.off:])
+	if n == 0 {
+		return 0, errTruncated
+	}
+	d.off += n
+	return v, nil
+}
+
+func (d *decoder) bytes() ([]byte, error) {
+	n, err := d.varint()
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(d.b)-d.off) {
+		return nil, errTruncated
+	}
+	b := d.b[d.off : d.off+int(n)]
+	d.off += int(n)
+	return b, nil
+}
+
+func (d *decoder) string() (string, error) {
+	b, err := d.bytes()
+	return string(b), err
+}
+
+// skip skips the value of a field with the given key, like the proto
+// package skips unknown fields.
+func (d *decoder) skip(key uint64) error {
+	var n int
+	switch key & 7 {
+	case 0: // varint
+		_, err := d.varint()
+		return err
+	case 1: // 64-bit
+		n = 8
+	case 2: // length-delimited
+		_, err := d.bytes()
+		return err
+	case 5: // 32-bit
+		n = 4
+	default:
+		return fmt.Errorf("unsupported wire type %d of field %d", key&7, key>>3)
+	}
+	if n > len(d.b)-d.off {
+		return errTruncated
+	}
+	d.off += n
+	return nil
+}
+
+// DecodeIndex decodes b, a marshaled Index (without trailer), into an
+// Index like proto.Unmarshal, except that the interned_entry field is
+// not decoded: its elements are returned as marshaled InternedEntry
+// messages (referring to b), in order, for DecodeInternedEntry. This
+// way, no interned entry is allocated individually, and callers can
+// decode the entries concurrently.
+func DecodeIndex(b []byte) (*Index, [][]byte, error) {
+	// Count the interned entries and strings first (which only
+	// requires decoding the keys and lengths), so that they are
+	// allocated only once.
+	var entries, strings int
+	d := decoder{b: b}
+	for d.off < len(d.b) {
+		key, err := d.varint()
+		if err != nil {
+			return nil, nil, err
+		}
+		switch key {
+		case keyInterned:
+			entries++
+		case keyString:
+			strings++
+		}
+		if err := d.skip(key); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	idx := &Index{StringTable: make([]string, 0, strings)}
+	interned := make([][]byte, 0, entries)
+	d = decoder{b: b}
+	for d.off < len(d.b) {
+		key, err := d.varint()
+		if err != nil {
+			return nil, nil, err
+		}
+		switch key {
+		case keyInterned:
+			e, err := d.bytes()
+			if err != nil {
+				return nil, nil, err
+			}
+			interned = append(interned, e)
+
+		case keyString:
+			s, err := d.string()
+			if err != nil {
+				return nil, nil, err
+			}
+			idx.StringTable = append(idx.StringTable, s)
+
+		case keyEntry:
+			m, err := d.bytes()
+			if err != nil {
+				return nil, nil, err
+			}
+			var e IndexEntry
+			if err := proto1.Unmarshal(m, &e); err != nil {
+				return nil, nil, err
+			}
+			idx.Entry = append(idx.Entry, &e)
+
+		case keyLanguage, keySection, keyPriority, keyURLCase:
+			s, err := d.string()
+			if err != nil {
+				return nil, nil, err
+			}
+			switch key {
+			case keyLanguage:
+				idx.Language = append(idx.Language, s)
+			case keySection:
+				idx.Section = append(idx.Section, s)
+			case keyPriority:
+				idx.SectionPriority = append(idx.SectionPriority, s)
+			case keyURLCase:
+				idx.UrlCase = s
+			}
+
+		case keySuite:
+			m, err := d.bytes()
+			if err != nil {
+				return nil, nil, err
+			}
+			name, suite, err := decodeMapEntry(m)
+			if err != nil {
+				return nil, nil, err
+			}
+			if idx.Suite == nil {
+				idx.Suite = make(map[string]string)
+			}
+			idx.Suite[name] = suite
+
+		case keyVersion:
+			v, err := d.varint()
+			if err != nil {
+				return nil, nil, err
+			}
+			idx.Version = uint32(v)
+
+		case keyBuilt:
+			v, err := d.varint()
+			if err != nil {
+				return nil, nil, err
+			}
+			idx.Built = int64(v)
+
+		case keyGrouped:
+			v, err := d.varint()
+			if err != nil {
+				return nil, nil, err
+			}
+			idx.GroupLanguages = v != 0
+
+		default:
+			if err := d.skip(key); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return idx, interned, nil
+}
+
+// decodeMapEntry decodes an element of a map<string,string> field.
+func decodeMapEntry(b []byte) (key, value string, err error) {
+	d := decoder{b: b}
+	for d.off < len(d.b) {
+		k, err := d.varint()
+		if err != nil {
+			return "", "", err
+		}
+		switch k {
+		case keyMapKey:
+			key, err = d.string()
+		case keyMapValue:
+			value, err = d.string()
+		default:
+			err = d.skip(k)
+		}
+		if err != nil {
+			return "", "", err
+		}
+	}
+	return key, value, nil
+}
+
+// DecodeInternedEntry decodes b, a marshaled InternedEntry (see
+// DecodeIndex), into e.
+func DecodeInternedEntry(b []byte, e *InternedEntry) error {
+	*e = InternedEntry{}
+	d := decoder{b: b}
+	for d.off < len(d.b) {
+		key, err := d.varint()
+		if err != nil {
+			return err
+		}
+		if key&7 != 0 {
+			if err := d.skip(key); err != nil {
+				return err
+			}
+			continue
+		}
+		v, err := d.varint()
+		if err != nil {
+			return err
+		}
+		switch key >> 3 {
+		case 1:
+			e.Name = uint32(v)
+		case 2:
+			e.Suite = uint32(v)
+		case 3:
+			e.Binarypkg = uint32(v)
+		case 4:
+			e.Section = uint32(v)
+		case 5:
+			e.Language = uint32(v)
+		case 6:
+			e.Architecture = uint32(v)
+		case 7:
+			e.Target = uint32(v)
+		}
+	}
+	return nil
+}
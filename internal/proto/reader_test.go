@@ -0,0 +1,90 @@
+package proto
+
+import (
+	"testing"
+
+	proto1 "github.com/golang/protobuf/proto"
+)
+
+func TestDecodeIndex(t *testing.T) {
+	for _, idx := range []*Index{
+		{},
+		{
+			Entry: []*IndexEntry{
+				{Name: "i3", Suite: "jessie", Binarypkg: "i3-wm", Section: "1", Language: "en"},
+				{Name: "i3", Suite: "jessie", Binarypkg: "i3-wm", Section: "5", Language: "fr", Architecture: "amd64"},
+				{Name: "i3wm", Suite: "jessie", Binarypkg: "i3-wm", Section: "1", Language: "en", Target: "jessie/i3-wm/i3.1.en"},
+			},
+			Language:        []string{"en", "fr", ""},
+			Suite:           map[string]string{"stable": "jessie", "": "", "testing": "stretch"},
+			Section:         []string{"1", "5"},
+			UrlCase:         "lower",
+			SectionPriority: []string{"1", "8"},
+			Built:           1495548900,
+			GroupLanguages:  true,
+		},
+		{
+			Version:     IndexVersion,
+			StringTable: []string{"i3", "jessie", "i3-wm", "1", "en", "amd64"},
+			InternedEntry: []*InternedEntry{
+				{Name: 0, Suite: 1, Binarypkg: 2, Section: 3, Language: 4},
+				{Name: 0, Suite: 1, Binarypkg: 2, Section: 3, Language: 4, Architecture: 5 + 1},
+				{Name: 0, Suite: 1, Binarypkg: 2, Section: 3, Language: 4, Target: 5 + 1},
+				{},
+			},
+			Built: -1,
+		},
+	} {
+		b, err := proto1.Marshal(idx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, interned, err := DecodeIndex(b)
+		if err != nil {
+			t.Fatalf("DecodeIndex(%v): %v", idx, err)
+		}
+		for _, m := range interned {
+			var e InternedEntry
+			if err := DecodeInternedEntry(m, &e); err != nil {
+				t.Fatalf("DecodeInternedEntry(%x): %v", m, err)
+			}
+			got.InternedEntry = append(got.InternedEntry, &e)
+		}
+		if !proto1.Equal(got, idx) {
+			t.Errorf("DecodeIndex: got %v, want %v", got, idx)
+		}
+
+		if len(b) == 0 {
+			continue
+		}
+		if _, _, err := DecodeIndex(b[:len(b)-1]); err == nil {
+			t.Errorf("DecodeIndex unexpectedly accepted truncated %v", idx)
+		}
+	}
+
+	// Unknown fields (e.g. of newer debiman versions) are skipped.
+	b := []byte{
+		12<<3 | 0, 42, // varint
+		13<<3 | 1, 1, 2, 3, 4, 5, 6, 7, 8, // 64-bit
+		14<<3 | 2, 2, 'h', 'i', // length-delimited
+		15<<3 | 5, 1, 2, 3, 4, // 32-bit
+		keyURLCase, 5, 'l', 'o', 'w', 'e', 'r',
+	}
+	got, _, err := DecodeIndex(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (&Index{UrlCase: "lower"}); !proto1.Equal(got, want) {
+		t.Errorf("DecodeIndex: got %v, want %v", got, want)
+	}
+	for _, bogus := range [][]byte{
+		{keyURLCase, 5, 'l'},
+		{keyVersion},
+		{16<<3 | 3},
+		{keyInterned, 0x80},
+	} {
+		if _, _, err := DecodeIndex(bogus); err == nil {
+			t.Errorf("DecodeIndex(%x) unexpectedly succeeded", bogus)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package proto
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+)
+
+// trailerMagic identifies an index file which ends in a trailer.
+var trailerMagic = []byte("debimanI")
+
+// trailerLen is the length of the trailer: the magic, followed by the
+// big-endian length and CRC-32 (IEEE) of the preceding marshaled Index.
+const trailerLen = 8 + 4 + 4
+
+// AppendTrailer appends a trailer to the marshaled Index b, which
+// allows StripTrailer to detect truncated or otherwise corrupted files.
+func AppendTrailer(b []byte) []byte {
+	var trailer [trailerLen]byte
+	copy(trailer[:], trailerMagic)
+	binary.BigEndian.PutUint32(trailer[8:], uint32(len(b)))
+	binary.BigEndian.PutUint32(trailer[12:], crc32.ChecksumIEEE(b))
+	return append(b, trailer[:]...)
+}
+
+// StripTrailer validates the trailer which AppendTrailer added to b and
+// returns the marshaled Index without the trailer.
+func StripTrailer(b []byte) ([]byte, error) {
+	if len(b) < trailerLen || !bytes.Equal(b[len(b)-trailerLen:len(b)-trailerLen+len(trailerMagic)], trailerMagic) {
+		return nil, fmt.Errorf("index trailer not found: file truncated, or written by a debiman version without trailer support (re-run debiman)")
+	}
+	trailer := b[len(b)-trailerLen:]
+	b = b[:len(b)-trailerLen]
+	if got, want := uint32(len(b)), binary.BigEndian.Uint32(trailer[8:]); got != want {
+		return nil, fmt.Errorf("index length mismatch: got %d bytes, trailer specifies %d bytes", got, want)
+	}
+	if got, want := crc32.ChecksumIEEE(b), binary.BigEndian.Uint32(trailer[12:]); got != want {
+		return nil, fmt.Errorf("index checksum mismatch: got %08x, trailer specifies %08x", got, want)
+	}
+	return b, nil
+}
+
+// TrailerChecksum returns the CRC-32 which the trailer of b specifies,
+// e.g. to identify the index file. b must have been validated by
+// StripTrailer.
+func TrailerChecksum(b []byte) uint32 {
+	return binary.BigEndian.Uint32(b[len(b)-4:])
+}
@@ -0,0 +1,38 @@
+package proto
+
+import (
+	"hash/crc32"
+	"testing"
+)
+
+func TestTrailer(t *testing.T) {
+	b := AppendTrailer([]byte("marshaled index"))
+	got, err := StripTrailer(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "marshaled index"; string(got) != want {
+		t.Fatalf("StripTrailer: got %q, want %q", got, want)
+	}
+	if got, want := TrailerChecksum(b), crc32.ChecksumIEEE(got); got != want {
+		t.Errorf("TrailerChecksum: got %08x, want %08x", got, want)
+	}
+
+	corrupt := append([]byte{}, b...)
+	corrupt[0] = 'M'
+	for _, entry := range []struct {
+		desc string
+		b    []byte
+	}{
+		{"empty", nil},
+		{"no trailer", []byte("marshaled index")},
+		{"truncated", b[:len(b)-1]},
+		{"truncated trailer", b[len(b)-trailerLen+2:]},
+		{"corrupt", corrupt},
+		{"length mismatch", AppendTrailer([]byte("marshaled index"))[1:]},
+	} {
+		if _, err := StripTrailer(entry.b); err == nil {
+			t.Errorf("%s: StripTrailer unexpectedly succeeded", entry.desc)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package proto
+
+import (
+	"encoding/binary"
+	"hash"
+	"hash/crc32"
+	"io"
+	"time"
+
+	proto1 "github.com/golang/protobuf/proto"
+)
+
+// Keys (field number and wire type “length-delimited”) of the Index
+// fields, see index.proto.
+const (
+	keyLanguage = 2<<3 | 2
+	keySuite    = 3<<3 | 2
+	keySection  = 4<<3 | 2
+	keyURLCase  = 5<<3 | 2
+	keyPriority = 6<<3 | 2
+	keyVersion  = 7<<3 | 0
+	keyString   = 8<<3 | 2
+	keyInterned = 9<<3 | 2
+	keyBuilt    = 10<<3 | 0
+	keyGrouped  = 11<<3 | 0
+
+	keyMapKey   = 1<<3 | 2
+	keyMapValue = 2<<3 | 2
+)
+
+// IndexVersion is the Index.version written by IndexWriter.
+const IndexVersion = 1
+
+// IndexWriter writes a marshaled Index, followed by a trailer (see
+// AppendTrailer), one field at a time, so that the Index never needs to
+// be held in memory in its entirety.
+//
+// The fields must be written in field number order: all entries, then
+// all languages, suites and sections, then the URL case policy, then
+// the section priority (the version, built and group_languages fields
+// are written by Close). Without entries, the output is identical to
+// AppendTrailer(proto.Marshal(idx)). Entries are interned (see
+// IndexVersion): each distinct string is written to the string_table
+// field right before the first entry referring to it, so the output
+// is not identical to proto.Marshal (which writes all elements of a
+// field together), but unmarshals to the same Index.
+//
+// Apart from the current entry, IndexWriter only keeps the strings it
+// interned, and at most MaxInterned of them: once that many distinct
+// strings were written, it forgets them, and strings which are used
+// again are appended to string_table once more. The memory IndexWriter
+// requires is therefore bounded by MaxInterned, not by the number of
+// entries (the caller provides the entries one at a time).
+type IndexWriter struct {
+	// MaxInterned is the maximum number of strings kept for
+	// interning. Defaults to DefaultMaxInterned.
+	MaxInterned int
+
+	w        io.Writer
+	crc      hash.Hash32
+	n        uint32
+	buf      *proto1.Buffer
+	err      error
+	strings  map[string]uint32 // string → index in string_table
+	nstrings uint32            // number of elements of string_table
+	built    int64
+	grouped  bool
+}
+
+// DefaultMaxInterned is the default IndexWriter.MaxInterned. It exceeds
+// the number of suites, binary packages, sections and languages of the
+// Debian archive, so that such strings are rarely written more than
+// once.
+const DefaultMaxInterned = 1 << 17
+
+// NewIndexWriter returns an IndexWriter writing to w.
+func NewIndexWriter(w io.Writer) *IndexWriter {
+	crc := crc32.NewIEEE()
+	return &IndexWriter{
+		MaxInterned: DefaultMaxInterned,
+		w:           io.MultiWriter(w, crc),
+		crc:         crc,
+		buf:         proto1.NewBuffer(nil),
+		strings:     make(map[string]uint32),
+	}
+}
+
+func (w *IndexWriter) flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	b := w.buf.Bytes()
+	w.n += uint32(len(b))
+	_, w.err = w.w.Write(b)
+	w.buf.Reset()
+	return w.err
+}
+
+func (w *IndexWriter) writeString(key uint64, s string) error {
+	w.buf.EncodeVarint(key)
+	w.buf.EncodeStringBytes(s)
+	return w.flush()
+}
+
+// intern returns the index of s in the string_table field, appending
+// s to it if required.
+func (w *IndexWriter) intern(s string) uint32 {
+	if ref, ok := w.strings[s]; ok {
+		return ref
+	}
+	ref := w.nstrings
+	w.nstrings++
+	w.strings[s] = ref
+	w.buf.EncodeVarint(keyString)
+	w.buf.EncodeStringBytes(s)
+	return ref
+}
+
+// WriteEntry writes e as an element of the interned_entry field.
+func (w *IndexWriter) WriteEntry(e *IndexEntry) error {
+	if len(w.strings) >= w.MaxInterned {
+		w.strings = make(map[string]uint32)
+	}
+	ie := InternedEntry{
+		Name:      w.intern(e.Name),
+		Suite:     w.intern(e.Suite),
+		Binarypkg: w.intern(e.Binarypkg),
+		Section:   w.intern(e.Section),
+		Language:  w.intern(e.Language),
+	}
+	if e.Architecture != "" {
+		ie.Architecture = w.intern(e.Architecture) + 1
+	}
+	if e.Target != "" {
+		ie.Target = w.intern(e.Target) + 1
+	}
+	w.buf.EncodeVarint(keyInterned)
+	if err := w.buf.EncodeMessage(&ie); err != nil {
+		// The string table is incomplete now.
+		w.err = err
+		return err
+	}
+	return w.flush()
+}
+
+// WriteLanguage writes an element of the language field.
+func (w *IndexWriter) WriteLanguage(language string) error {
+	return w.writeString(keyLanguage, language)
+}
+
+// WriteSuite writes an element of the suite map.
+func (w *IndexWriter) WriteSuite(name, suite string) error {
+	// Like the proto package, encode both key and value of map
+	// entries, even if they are empty.
+	entry := proto1.NewBuffer(nil)
+	entry.EncodeVarint(keyMapKey)
+	entry.EncodeStringBytes(name)
+	entry.EncodeVarint(keyMapValue)
+	entry.EncodeStringBytes(suite)
+	w.buf.EncodeVarint(keySuite)
+	w.buf.EncodeRawBytes(entry.Bytes())
+	return w.flush()
+}
+
+// WriteSection writes an element of the section field.
+func (w *IndexWriter) WriteSection(section string) error {
+	return w.writeString(keySection, section)
+}
+
+// WriteURLCase writes the url_case field. Like with proto.Marshal, an
+// empty urlCase is omitted.
+func (w *IndexWriter) WriteURLCase(urlCase string) error {
+	if urlCase == "" {
+		return w.err
+	}
+	return w.writeString(keyURLCase, urlCase)
+}
+
+// WriteSectionPriority writes an element of the section_priority field.
+func (w *IndexWriter) WriteSectionPriority(section string) error {
+	return w.writeString(keyPriority, section)
+}
+
+// SetBuilt sets the built field, which Close writes. The zero time
+// (the default) omits the field.
+func (w *IndexWriter) SetBuilt(built time.Time) {
+	if built.IsZero() {
+		w.built = 0
+		return
+	}
+	w.built = built.Unix()
+}
+
+// SetGroupLanguages sets the group_languages field, which Close
+// writes. false (the default) omits the field.
+func (w *IndexWriter) SetGroupLanguages(grouped bool) {
+	w.grouped = grouped
+}
+
+// Close writes the version, built and group_languages fields and the
+// trailer. It does not close the underlying io.Writer.
+func (w *IndexWriter) Close() error {
+	w.buf.EncodeVarint(keyVersion)
+	w.buf.EncodeVarint(IndexVersion)
+	if w.built != 0 {
+		w.buf.EncodeVarint(keyBuilt)
+		w.buf.EncodeVarint(uint64(w.built))
+	}
+	if w.grouped {
+		w.buf.EncodeVarint(keyGrouped)
+		w.buf.EncodeVarint(1)
+	}
+	if err := w.flush(); err != nil {
+		return err
+	}
+	var trailer [trailerLen]byte
+	copy(trailer[:], trailerMagic)
+	binary.BigEndian.PutUint32(trailer[8:], w.n)
+	binary.BigEndian.PutUint32(trailer[12:], w.crc.Sum32())
+	_, w.err = w.w.Write(trailer[:])
+	return w.err
+}
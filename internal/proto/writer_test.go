@@ -0,0 +1,213 @@
+package proto
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	proto1 "github.com/golang/protobuf/proto"
+)
+
+func TestIndexWriter(t *testing.T) {
+	for _, entry := range []struct {
+		idx  *Index // Entry is written using WriteEntry
+		want *Index
+	}{
+		{&Index{}, &Index{Version: IndexVersion}},
+		{
+			&Index{
+				Entry: []*IndexEntry{
+					{Name: "i3", Suite: "jessie", Binarypkg: "i3-wm", Section: "1", Language: "en"},
+					{Name: "i3", Suite: "jessie", Binarypkg: "i3-wm", Section: "5", Language: "fr", Architecture: "amd64"},
+					{Name: "empty"},
+				},
+				Language: []string{"en", "fr", ""},
+				// The encoding order of map entries is undefined, so only
+				// a single entry can be compared.
+				Suite:           map[string]string{"stable": "jessie"},
+				Section:         []string{"1", "5"},
+				UrlCase:         "lower",
+				SectionPriority: []string{"1", "8"},
+			},
+			&Index{
+				Language:        []string{"en", "fr", ""},
+				Suite:           map[string]string{"stable": "jessie"},
+				Section:         []string{"1", "5"},
+				UrlCase:         "lower",
+				SectionPriority: []string{"1", "8"},
+				Version:         IndexVersion,
+				StringTable:     []string{"i3", "jessie", "i3-wm", "1", "en", "5", "fr", "amd64", "empty", ""},
+				InternedEntry: []*InternedEntry{
+					{Name: 0, Suite: 1, Binarypkg: 2, Section: 3, Language: 4},
+					{Name: 0, Suite: 1, Binarypkg: 2, Section: 5, Language: 6, Architecture: 7 + 1},
+					{Name: 8, Suite: 9, Binarypkg: 9, Section: 9, Language: 9},
+				},
+			},
+		},
+		{
+			&Index{
+				Entry: []*IndexEntry{
+					{Name: "gunzip", Suite: "jessie", Binarypkg: "gzip", Section: "1", Language: "en", Target: "jessie/gzip/gzip.1.en"},
+				},
+			},
+			&Index{
+				Version:     IndexVersion,
+				StringTable: []string{"gunzip", "jessie", "gzip", "1", "en", "jessie/gzip/gzip.1.en"},
+				InternedEntry: []*InternedEntry{
+					{Name: 0, Suite: 1, Binarypkg: 2, Section: 3, Language: 4, Target: 5 + 1},
+				},
+			},
+		},
+		{
+			&Index{Suite: map[string]string{"": ""}},
+			&Index{Suite: map[string]string{"": ""}, Version: IndexVersion},
+		},
+		{
+			&Index{UrlCase: "lower", Built: 1495548900},
+			&Index{UrlCase: "lower", Version: IndexVersion, Built: 1495548900},
+		},
+		{
+			&Index{Built: 1495548900, GroupLanguages: true},
+			&Index{Version: IndexVersion, Built: 1495548900, GroupLanguages: true},
+		},
+	} {
+		idx := entry.idx
+		var buf bytes.Buffer
+		w := NewIndexWriter(&buf)
+		for _, e := range idx.Entry {
+			if err := w.WriteEntry(e); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, l := range idx.Language {
+			if err := w.WriteLanguage(l); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for name, suite := range idx.Suite {
+			if err := w.WriteSuite(name, suite); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, s := range idx.Section {
+			if err := w.WriteSection(s); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.WriteURLCase(idx.UrlCase); err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range idx.SectionPriority {
+			if err := w.WriteSectionPriority(s); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if idx.Built != 0 {
+			w.SetBuilt(time.Unix(idx.Built, 0))
+		}
+		w.SetGroupLanguages(idx.GroupLanguages)
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		b, err := StripTrailer(buf.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got Index
+		if err := proto1.Unmarshal(b, &got); err != nil {
+			t.Fatal(err)
+		}
+		if !proto1.Equal(&got, entry.want) {
+			t.Errorf("IndexWriter output for %v: got %v, want %v", idx, &got, entry.want)
+		}
+
+		// Apart from the entries, the output must be identical to
+		// proto.Marshal.
+		if len(idx.Entry) > 0 {
+			continue
+		}
+		want, err := proto1.Marshal(entry.want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.Bytes(); !bytes.Equal(got, AppendTrailer(want)) {
+			t.Errorf("IndexWriter output differs from proto.Marshal for %v:\ngot  %x\nwant %x", idx, got, AppendTrailer(want))
+		}
+	}
+}
+
+func TestIndexWriterManyEntries(t *testing.T) {
+	var entries []*IndexEntry
+	distinct := make(map[string]bool)
+	for n := 0; n < 500; n++ {
+		for _, suite := range []string{"jessie", "stretch"} {
+			for _, lang := range []string{"en", "de", "fr"} {
+				e := &IndexEntry{
+					Name:      fmt.Sprintf("name%d", n),
+					Suite:     suite,
+					Binarypkg: fmt.Sprintf("pkg%d", n/10),
+					Section:   fmt.Sprintf("%d", n%8+1),
+					Language:  lang,
+				}
+				if n%50 == 0 {
+					e.Target = suite + "/pkg0/name0.1." + lang
+				}
+				entries = append(entries, e)
+				for _, s := range []string{e.Name, e.Suite, e.Binarypkg, e.Section, e.Language, e.Target} {
+					if s != "" {
+						distinct[s] = true
+					}
+				}
+			}
+		}
+	}
+
+	for _, maxInterned := range []int{DefaultMaxInterned, 64} {
+		var buf bytes.Buffer
+		w := NewIndexWriter(&buf)
+		w.MaxInterned = maxInterned
+		for _, e := range entries {
+			if err := w.WriteEntry(e); err != nil {
+				t.Fatal(err)
+			}
+			// At most the strings of one entry are interned on top.
+			if got, limit := len(w.strings), maxInterned+6; got > limit {
+				t.Fatalf("MaxInterned=%d: %d strings interned, want at most %d", maxInterned, got, limit)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		b, err := StripTrailer(buf.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var idx Index
+		if err := proto1.Unmarshal(b, &idx); err != nil {
+			t.Fatal(err)
+		}
+		if maxInterned == DefaultMaxInterned && len(idx.StringTable) != len(distinct) {
+			t.Errorf("MaxInterned=%d: string table has %d strings, want %d", maxInterned, len(idx.StringTable), len(distinct))
+		}
+		if got, want := len(idx.InternedEntry), len(entries); got != want {
+			t.Fatalf("MaxInterned=%d: got %d entries, want %d", maxInterned, got, want)
+		}
+		str := func(ref uint32) string { return idx.StringTable[ref] }
+		for i, ie := range idx.InternedEntry {
+			got := &IndexEntry{
+				Name:      str(ie.Name),
+				Suite:     str(ie.Suite),
+				Binarypkg: str(ie.Binarypkg),
+				Section:   str(ie.Section),
+				Language:  str(ie.Language),
+			}
+			if ie.Target != 0 {
+				got.Target = str(ie.Target - 1)
+			}
+			if !proto1.Equal(got, entries[i]) {
+				t.Errorf("MaxInterned=%d: entry %d: got %v, want %v", maxInterned, i, got, entries[i])
+			}
+		}
+	}
+}
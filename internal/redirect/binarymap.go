@@ -0,0 +1,127 @@
+package redirect
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// A binary rewrite map (as written by debiman-idx2rwmap -format=binary)
+// contains the same keys and targets as the text rewrite map, but can
+// be queried without conversion (e.g. by httxt2dbm), directly from a
+// memory mapping. Its layout is (all integers are little-endian
+// uint32):
+//
+//	header          BinaryMapMagic, BinaryMapVersion, number of keys n,
+//	                number of distinct targets t, length of the keys
+//	                blob, length of the targets blob, 0 (reserved)
+//	key offsets     n+1 offsets into the keys blob: key i is
+//	                keys[offset i:offset i+1]
+//	key targets     n indexes into the target offsets
+//	target offsets  t+1 offsets into the targets blob
+//	keys blob       all keys, in ascending byte order, concatenated
+//	targets blob    all targets, concatenated
+const (
+	BinaryMapMagic   = "dmrwmap\x00"
+	BinaryMapVersion = 1
+
+	binaryMapHeaderLen = len(BinaryMapMagic) + 6*4
+)
+
+// BinaryMap is a binary rewrite map. It is safe for concurrent use.
+type BinaryMap struct {
+	keyOffsets    []byte
+	keyTargets    []byte
+	targetOffsets []byte
+	keys          []byte
+	targets       []byte
+	n             int
+	t             int
+
+	unmap func() error
+}
+
+// ParseBinaryMap returns the binary rewrite map b. b must not be
+// modified while the BinaryMap is in use.
+func ParseBinaryMap(b []byte) (*BinaryMap, error) {
+	if len(b) < binaryMapHeaderLen || string(b[:len(BinaryMapMagic)]) != BinaryMapMagic {
+		return nil, fmt.Errorf("not a binary rewrite map")
+	}
+	var header [6]uint64
+	for i := range header {
+		header[i] = uint64(binary.LittleEndian.Uint32(b[len(BinaryMapMagic)+4*i:]))
+	}
+	if v := header[0]; v != BinaryMapVersion {
+		return nil, fmt.Errorf("unsupported binary rewrite map version %d (want %d)", v, BinaryMapVersion)
+	}
+	n, t, keysLen, targetsLen := header[1], header[2], header[3], header[4]
+	if want := uint64(binaryMapHeaderLen) + 4*(n+1+n+t+1) + keysLen + targetsLen; uint64(len(b)) != want {
+		return nil, fmt.Errorf("binary rewrite map has %d bytes, want %d", len(b), want)
+	}
+	m := &BinaryMap{n: int(n), t: int(t)}
+	rest := b[binaryMapHeaderLen:]
+	for _, s := range []struct {
+		b   *[]byte
+		len uint64
+	}{
+		{&m.keyOffsets, 4 * (n + 1)},
+		{&m.keyTargets, 4 * n},
+		{&m.targetOffsets, 4 * (t + 1)},
+		{&m.keys, keysLen},
+		{&m.targets, targetsLen},
+	} {
+		*s.b = rest[:s.len]
+		rest = rest[s.len:]
+	}
+	if got := uint64(m.offset(m.keyOffsets, m.n)); got != keysLen {
+		return nil, fmt.Errorf("keys end at %d, want %d", got, keysLen)
+	}
+	if got := uint64(m.offset(m.targetOffsets, m.t)); got != targetsLen {
+		return nil, fmt.Errorf("targets end at %d, want %d", got, targetsLen)
+	}
+	return m, nil
+}
+
+func (m *BinaryMap) offset(offsets []byte, i int) int {
+	return int(binary.LittleEndian.Uint32(offsets[4*i:]))
+}
+
+// slice returns element i of blob according to offsets, or nil if the
+// offsets are corrupt.
+func (m *BinaryMap) slice(blob, offsets []byte, i int) []byte {
+	lo, hi := m.offset(offsets, i), m.offset(offsets, i+1)
+	if lo > hi || hi > len(blob) {
+		return nil
+	}
+	return blob[lo:hi]
+}
+
+// Len returns the number of keys of m.
+func (m *BinaryMap) Len() int {
+	return m.n
+}
+
+// Lookup returns the target of key (e.g. /jessie/i3-wm/i3.1.en.html for
+// /i3), like an Apache RewriteMap of the corresponding text rewrite map.
+func (m *BinaryMap) Lookup(key string) (string, bool) {
+	i := sort.Search(m.n, func(i int) bool {
+		return string(m.slice(m.keys, m.keyOffsets, i)) >= key
+	})
+	if i == m.n || string(m.slice(m.keys, m.keyOffsets, i)) != key {
+		return "", false
+	}
+	target := m.offset(m.keyTargets, i)
+	if target >= m.t {
+		return "", false // corrupt
+	}
+	return string(m.slice(m.targets, m.targetOffsets, target)), true
+}
+
+// Close releases the memory mapping of a BinaryMap returned by
+// OpenBinaryMap. m must not be used afterwards.
+func (m *BinaryMap) Close() error {
+	if m.unmap == nil {
+		return nil
+	}
+	return m.unmap()
+}
@@ -0,0 +1,38 @@
+// +build linux
+
+package redirect
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// OpenBinaryMap returns the binary rewrite map stored at path, which
+// is mapped into memory (read-only) instead of being read. Replace the
+// file atomically (i.e. rename a new file over it) while it is in use.
+func OpenBinaryMap(path string) (*BinaryMap, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if st.Size() == 0 {
+		return ParseBinaryMap(nil)
+	}
+	b, err := unix.Mmap(int(f.Fd()), 0, int(st.Size()), unix.PROT_READ, unix.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+	m, err := ParseBinaryMap(b)
+	if err != nil {
+		unix.Munmap(b)
+		return nil, err
+	}
+	m.unmap = func() error { return unix.Munmap(b) }
+	return m, nil
+}
@@ -0,0 +1,14 @@
+// +build !linux
+
+package redirect
+
+import "io/ioutil"
+
+// OpenBinaryMap returns the binary rewrite map stored at path.
+func OpenBinaryMap(path string) (*BinaryMap, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseBinaryMap(b)
+}
@@ -0,0 +1,85 @@
+package redirect
+
+import (
+	"fmt"
+
+	"github.com/Debian/debiman/internal/releases"
+)
+
+// SuiteFallback determines how Redirect handles requests for a suite
+// which does not contain the requested manpage (e.g. a program which
+// was introduced after that suite was released).
+type SuiteFallback int
+
+const (
+	// FallbackAny redirects to any suite which contains the manpage,
+	// preferring the suite of the referrer and the default suite.
+	FallbackAny SuiteFallback = iota
+
+	// FallbackNewer redirects to the nearest newer suite (in
+	// releases.SortOrder) which contains the manpage. The redirect
+	// target carries a from_suite query parameter, so that the page
+	// can display a banner. Results in a NotFoundError if there is no
+	// newer suite.
+	FallbackNewer
+
+	// FallbackNone results in a NotFoundError.
+	FallbackNone
+)
+
+var suiteFallbackNames = map[SuiteFallback]string{
+	FallbackAny:   "any",
+	FallbackNewer: "newer",
+	FallbackNone:  "none",
+}
+
+func (f SuiteFallback) String() string {
+	return suiteFallbackNames[f]
+}
+
+// ParseSuiteFallback returns the SuiteFallback called s (e.g. “newer”).
+func ParseSuiteFallback(s string) (SuiteFallback, error) {
+	for f, name := range suiteFallbackNames {
+		if s == name {
+			return f, nil
+		}
+	}
+	return FallbackAny, fmt.Errorf("unknown suite fallback %q: expected one of any, newer, none", s)
+}
+
+// nearestNewerSuite returns the oldest suite which is newer than suite
+// and contains an entry in section (if non-empty), or "" if there is
+// no such suite.
+func nearestNewerSuite(entries []IndexEntry, suite, section string) string {
+	order, ok := releases.SortOrder[suite]
+	if !ok {
+		return ""
+	}
+	var best string
+	var bestOrder int
+	for _, e := range entries {
+		if section != "" && e.Section[:1] != section[:1] {
+			continue
+		}
+		o, ok := releases.SortOrder[e.Suite]
+		if !ok || o <= order {
+			continue
+		}
+		if best == "" || o < bestOrder || (o == bestOrder && e.Suite < best) {
+			best = e.Suite
+			bestOrder = o
+		}
+	}
+	return best
+}
+
+// containsSuite returns whether entries contains an entry in suite
+// and section (if non-empty).
+func containsSuite(entries []IndexEntry, suite, section string) bool {
+	for _, e := range entries {
+		if e.Suite == suite && (section == "" || e.Section[:1] == section[:1]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package redirect
+
+import "sort"
+
+// byServingPath orders entries by their serving path. Entries which
+// share a page (Grouped entries and aliases of the same manpage) are
+// ordered by their raw manpage and then by architecture.
+type byServingPath []IndexEntry
+
+func (p byServingPath) Len() int      { return len(p) }
+func (p byServingPath) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p byServingPath) Less(i, j int) bool {
+	if pi, pj := p[i].ServingPath(".html"), p[j].ServingPath(".html"); pi != pj {
+		return pi < pj
+	}
+	if pi, pj := p[i].ServingPath(".gz"), p[j].ServingPath(".gz"); pi != pj {
+		return pi < pj
+	}
+	return p[i].Architecture < p[j].Architecture
+}
+
+// ForEach calls fn for each entry of i.Entries, ordered by name (the
+// key of i.Entries) and then by serving path. The order does not depend
+// on how the index was read, so that tools walking the index (e.g.
+// debiman-idx2rwmap) produce reproducible output. ForEach stops at the
+// first error returned by fn and returns it.
+//
+// fn receives copies of the entries, i.e. it may keep and modify them
+// without modifying i (see Index).
+func (i Index) ForEach(fn func(name string, e IndexEntry) error) error {
+	names := make([]string, 0, len(i.Entries))
+	for name := range i.Entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var variants []IndexEntry
+	for _, name := range names {
+		variants = append(variants[:0], i.Entries[name]...)
+		sort.Sort(byServingPath(variants))
+		for _, e := range variants {
+			if err := fn(name, e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package redirect
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+
+	pb "github.com/Debian/debiman/internal/proto"
+	"github.com/Debian/debiman/internal/urlcase"
+)
+
+// entriesFromProto returns the entries of idx and the marshaled
+// interned entries (see pb.DecodeIndex) as Index.Entries, keyed by
+// their name with policy applied. Entries share their strings:
+// each distinct string is allocated only once.
+func entriesFromProto(idx *pb.Index, interned [][]byte, policy urlcase.Policy) (map[string][]IndexEntry, error) {
+	switch idx.Version {
+	case 0:
+		return legacyEntries(idx, policy), nil
+	case pb.IndexVersion:
+		return internedEntries(idx.StringTable, interned, policy)
+	default:
+		return nil, fmt.Errorf("unsupported index version %d (written by a newer debiman version?)", idx.Version)
+	}
+}
+
+// legacyEntries returns the entries of an index written before
+// interning (version 0), which stores all strings of each entry.
+func legacyEntries(idx *pb.Index, policy urlcase.Policy) map[string][]IndexEntry {
+	pool := make(map[string]string)
+	intern := func(s string) string {
+		if p, ok := pool[s]; ok {
+			return p
+		}
+		pool[s] = s
+		return s
+	}
+	entries := make(map[string][]IndexEntry, len(idx.Entry))
+	for _, e := range idx.Entry {
+		name := intern(policy.Name(e.Name))
+		entries[name] = append(entries[name], IndexEntry{
+			Name:      intern(e.Name),
+			Suite:     intern(e.Suite),
+			Binarypkg: intern(e.Binarypkg),
+			Section:   intern(e.Section),
+			Language:  intern(e.Language),
+
+			Architecture: intern(e.Architecture),
+			Target:       intern(e.Target),
+		})
+	}
+	return entries
+}
+
+// minShardEntries is the minimum number of entries per goroutine of
+// internedEntries: below that, goroutines cost more than they save.
+const minShardEntries = 4096
+
+// internedEntries returns the entries of an index stored in its
+// string_table and interned_entry fields (version 1), given as strs
+// and the marshaled interned entries (see pb.DecodeIndex).
+//
+// The entries are decoded concurrently: each goroutine decodes a
+// contiguous range of the entries and distributes them across shards
+// by the hash of their name, then each shard builds the entries of
+// its names. As the shards consume the ranges in order, the entries of
+// each name stay in the order of the index file.
+func internedEntries(strs []string, interned [][]byte, policy urlcase.Policy) (map[string][]IndexEntry, error) {
+	n := runtime.GOMAXPROCS(0)
+	if max := len(interned) / minShardEntries; n > max {
+		n = max
+	}
+	if n < 1 {
+		n = 1
+	}
+
+	// buckets[w][s] are the entries which goroutine w decoded for shard s.
+	buckets := make([][][]pb.InternedEntry, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for w := 0; w < n; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			start, end := w*len(interned)/n, (w+1)*len(interned)/n
+			buckets[w], errs[w] = decodeInterned(strs, interned[start:end], start, policy, n)
+		}(w)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	shards := make([]map[string][]IndexEntry, n)
+	for s := 0; s < n; s++ {
+		wg.Add(1)
+		go func(s int) {
+			defer wg.Done()
+			shards[s] = buildShard(strs, buckets, s, policy)
+		}(s)
+	}
+	wg.Wait()
+
+	if n == 1 {
+		return shards[0], nil
+	}
+	total := 0
+	for _, shard := range shards {
+		total += len(shard)
+	}
+	entries := make(map[string][]IndexEntry, total)
+	for _, shard := range shards {
+		for key, e := range shard {
+			entries[key] = e
+		}
+	}
+	return entries, nil
+}
+
+// decodeInterned decodes interned (starting at entry offset of the
+// index) and distributes the entries across n shards.
+func decodeInterned(strs []string, interned [][]byte, offset int, policy urlcase.Policy, n int) ([][]pb.InternedEntry, error) {
+	buckets := make([][]pb.InternedEntry, n)
+	for s := range buckets {
+		// Names are distributed evenly, give or take a few percent.
+		buckets[s] = make([]pb.InternedEntry, 0, len(interned)/n+len(interned)/(n*20))
+	}
+	shardOf := make(map[uint32]int) // name → shard
+	var e pb.InternedEntry
+	for i, b := range interned {
+		if err := pb.DecodeInternedEntry(b, &e); err != nil {
+			return nil, fmt.Errorf("interned entry %d: %v", offset+i, err)
+		}
+		for _, ref := range [...]uint32{e.Name, e.Suite, e.Binarypkg, e.Section, e.Language} {
+			if int(ref) >= len(strs) {
+				return nil, fmt.Errorf("interned entry %d refers to string %d, but the string table has only %d strings", offset+i, ref, len(strs))
+			}
+		}
+		for _, ref := range [...]uint32{e.Architecture, e.Target} {
+			if int(ref) > len(strs) {
+				return nil, fmt.Errorf("interned entry %d refers to string %d, but the string table has only %d strings", offset+i, ref-1, len(strs))
+			}
+		}
+		s, ok := shardOf[e.Name]
+		if !ok {
+			// Names can collide after applying the URL case policy,
+			// so shard by key, not by name.
+			s = shard(policy.Name(strs[e.Name]), n)
+			shardOf[e.Name] = s
+		}
+		buckets[s] = append(buckets[s], e)
+	}
+	return buckets, nil
+}
+
+// shard returns the shard (out of n) of key, using FNV-1a.
+func shard(key string, n int) int {
+	if n == 1 {
+		return 0
+	}
+	h := uint32(2166136261)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+	return int(h % uint32(n))
+}
+
+// buildShard returns the entries of shard s, consuming the buckets of
+// all goroutines in order.
+func buildShard(strs []string, buckets [][][]pb.InternedEntry, s int, policy urlcase.Policy) map[string][]IndexEntry {
+	// Count the entries per name first, so that each slice of entries
+	// is allocated only once.
+	counts := make(map[uint32]int)
+	for _, b := range buckets {
+		for _, e := range b[s] {
+			counts[e.Name]++
+		}
+	}
+	keys := make(map[uint32]string, len(counts))
+	entries := make(map[string][]IndexEntry, len(counts))
+	for ref, count := range counts {
+		key := policy.Name(strs[ref])
+		keys[ref] = key
+		// Names can collide after applying the URL case policy.
+		entries[key] = make([]IndexEntry, 0, cap(entries[key])+count)
+	}
+	for _, b := range buckets {
+		for _, e := range b[s] {
+			key := keys[e.Name]
+			var arch, target string
+			if e.Architecture > 0 {
+				arch = strs[e.Architecture-1]
+			}
+			if e.Target > 0 {
+				target = strs[e.Target-1]
+			}
+			entries[key] = append(entries[key], IndexEntry{
+				Name:      strs[e.Name],
+				Suite:     strs[e.Suite],
+				Binarypkg: strs[e.Binarypkg],
+				Section:   strs[e.Section],
+				Language:  strs[e.Language],
+
+				Architecture: arch,
+				Target:       target,
+			})
+		}
+	}
+	return entries
+}
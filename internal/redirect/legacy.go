@@ -2,7 +2,7 @@ package redirect
 
 import "strings"
 
-func (i *Index) splitLegacy(path string) (suite string, binarypkg string, name string, section string, lang string) {
+func (i Index) splitLegacy(path string) (suite string, binarypkg string, name string, section string, lang string) {
 	parts := strings.Split(path[1:], "/")
 	// /man/<name>
 	// /man<section>/<name>
@@ -12,8 +12,8 @@ func (i *Index) splitLegacy(path string) (suite string, binarypkg string, name s
 	// /man/<section>/<name>
 	// /man/<lang>/<name>
 	if len(parts) == 3 {
-		if i.Langs[parts[1]] {
-			return "", "", parts[2], "", parts[1]
+		if l := i.language(parts[1]); l != "" {
+			return "", "", parts[2], "", l
 		} else if i.Sections[parts[1]] {
 			return "", "", parts[2], parts[1], ""
 		}
@@ -24,7 +24,11 @@ func (i *Index) splitLegacy(path string) (suite string, binarypkg string, name s
 	}
 	// /man/<suite>/<lang>/<section>/<name>
 	if len(parts) == 5 {
-		return parts[1], "", parts[4], parts[3], parts[2]
+		lang := parts[2]
+		if l := i.language(lang); l != "" {
+			lang = l
+		}
+		return parts[1], "", parts[4], parts[3], lang
 	}
 	return "", "", "", "", ""
 }
@@ -0,0 +1,92 @@
+package redirect
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ParseOverrides parses an overrides file: one “from to” pair of URL
+// paths (e.g. “/legacy/ls /jessie/coreutils/ls.1”) per line. Empty
+// lines and lines starting with # are ignored. The returned errors
+// describe malformed lines, which are skipped.
+func ParseOverrides(r io.Reader) (overrides map[string]string, broken []error, err error) {
+	overrides = make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			broken = append(broken, fmt.Errorf("line %d: expected “from to”, got %q", n, line))
+			continue
+		}
+		from, to := fields[0], fields[1]
+		if !strings.HasPrefix(from, "/") || !strings.HasPrefix(to, "/") {
+			broken = append(broken, fmt.Errorf("line %d: paths must start with /, got %q", n, line))
+			continue
+		}
+		if _, ok := overrides[from]; ok {
+			broken = append(broken, fmt.Errorf("line %d: duplicate override for %q", n, from))
+			continue
+		}
+		overrides[from] = to
+	}
+	return overrides, broken, scanner.Err()
+}
+
+// VerifyOverrides returns the overrides whose target resolves to a
+// manpage of i, and errors describing the others. Targets are resolved
+// without applying i.Overrides, i.e. overrides do not chain.
+func (i Index) VerifyOverrides(overrides map[string]string) (valid map[string]string, broken []error) {
+	valid = make(map[string]string, len(overrides))
+	froms := make([]string, 0, len(overrides))
+	for from := range overrides {
+		froms = append(froms, from)
+	}
+	sort.Strings(froms) // for deterministic errors
+	for _, from := range froms {
+		to := overrides[from]
+		if _, err := i.ResolveOverride(to); err != nil {
+			broken = append(broken, fmt.Errorf("override %q → %q: %v", from, to, err))
+			continue
+		}
+		valid[from] = to
+	}
+	return valid, broken
+}
+
+// ResolveOverride returns the serving path (see Redirect) to which the
+// override target to currently resolves, without applying i.Overrides.
+func (i Index) ResolveOverride(to string) (string, error) {
+	i.Overrides = nil // i is a copy
+	u, err := url.Parse(to)
+	if err != nil {
+		return "", err
+	}
+	return i.Redirect(&http.Request{URL: u, Header: make(http.Header)})
+}
+
+// LoadOverrides reads the overrides file at path (see ParseOverrides)
+// and returns the overrides which are valid for i (see
+// VerifyOverrides), as well as errors describing the broken ones.
+func (i Index) LoadOverrides(path string) (map[string]string, []error, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	overrides, broken, err := ParseOverrides(f)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", path, err)
+	}
+	valid, invalid := i.VerifyOverrides(overrides)
+	return valid, append(broken, invalid...), nil
+}
@@ -5,33 +5,132 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/Debian/debiman/internal/logging"
 	pb "github.com/Debian/debiman/internal/proto"
+	"github.com/Debian/debiman/internal/section"
 	"github.com/Debian/debiman/internal/tag"
-	"github.com/golang/protobuf/proto"
+	"github.com/Debian/debiman/internal/urlcase"
 	"golang.org/x/text/language"
 )
 
 type IndexEntry struct {
-	Name      string // TODO: string pool
+	Name      string
 	Suite     string // TODO: enum to save space
-	Binarypkg string // TODO: sort by popcon, TODO: use a string pool
-	Section   string // TODO: use a string pool
+	Binarypkg string // TODO: sort by popcon
+	Section   string
 	Language  string // TODO: type: would it make sense to use language.Tag?
+
+	// Architecture is the architecture of the .deb the manpage was
+	// extracted from if the manpages of its package differ across
+	// architectures (see debiman’s -primary_architecture), empty
+	// otherwise.
+	Architecture string
+
+	// Target is the serving path (e.g. “jessie/gzip/gzip.1.en”) of the
+	// manpage which this manpage is an alias of (see debiman’s
+	// -so_aliases), empty otherwise. Aliases are not rendered.
+	Target string
+
+	// Grouped is not stored in the index, but set by IndexFromProto
+	// for the entries which are shown on a page grouping all of their
+	// languages, see Index.GroupLanguages.
+	Grouped bool
 }
 
+// ServingPath returns the path of the page of e, i.e. of its Target for
+// aliases. For Grouped entries, the .html suffix refers to the
+// language-independent page (see Fragment).
 func (e IndexEntry) ServingPath(suffix string) string {
+	if e.Target != "" {
+		return "/" + e.Target + suffix
+	}
+	if e.Grouped && suffix == ".html" {
+		return "/" + e.Suite + "/" + e.Binarypkg + "/" + e.Name + "." + e.Section + suffix
+	}
 	return "/" + e.Suite + "/" + e.Binarypkg + "/" + e.Name + "." + e.Section + "." + e.Language + suffix
 }
 
+// Fragment returns the fragment which selects the language of e on its
+// page (e.g. “lang-de”) if e is Grouped, and the empty string
+// otherwise.
+func (e IndexEntry) Fragment() string {
+	if !e.Grouped {
+		return ""
+	}
+	return "lang-" + e.Language
+}
+
+// Format returns “info” for info documents (which debiman indexes in the
+// pseudo-section “info” with its -info_pages flag) and “man” for
+// manpages.
+func (e IndexEntry) Format() string {
+	if e.Section == "info" {
+		return "info"
+	}
+	return "man"
+}
+
+// Index is the in-memory representation of the auxserver index.
+//
+// An Index is immutable once loaded: none of its methods modify it or
+// anything it refers to (Narrow works on a copy of the entries), and
+// everything the methods need is computed by IndexFromProto. Hence, a
+// single Index can serve concurrent lookups without locking. Fields
+// which are configured by the server (SuiteFallback, Overrides, Log)
+// must be set before the Index is shared. To reload, load a new Index
+// and swap it in (see aux.Server.SwapIndex) instead of modifying the
+// current one.
 type Index struct {
 	Entries  map[string][]IndexEntry
 	Suites   map[string]string
 	Langs    map[string]bool
 	Sections map[string]bool
+
+	// URLCase is the policy with which the index was written. It
+	// determines the keys of Entries.
+	URLCase urlcase.Policy
+
+	// SectionPriority lists the sections to prefer (most preferred
+	// first) when a request does not specify a section. Sections not
+	// listed are preferred in string order, i.e. “1” before “5”.
+	SectionPriority []string
+
+	// Built is the start of the debiman run which wrote the index, or
+	// the zero time for indexes written by older debiman versions.
+	Built time.Time
+
+	// GroupLanguages is set if debiman’s -group_languages shows all
+	// languages of each manpage (if there is more than one in its
+	// binary package) on one page, see IndexEntry.Grouped.
+	GroupLanguages bool
+
+	// Version identifies the index file which IndexFromProto read,
+	// e.g. “20170523T141500Z-1a2b3c4d” (the UTC build time and the
+	// checksum of the file, see IndexVersion). Empty for indexes
+	// which were not read from a file, e.g. those of NewIndex.
+	Version string
+
+	// SuiteFallback is not stored in the index, but configured by the
+	// server (see debiman-auxserver’s -suite_fallback flag).
+	SuiteFallback SuiteFallback
+
+	// Overrides maps request paths (e.g. “/legacy/ls”) to the paths
+	// which are resolved instead (e.g. “/jessie/coreutils/ls.1”),
+	// taking precedence over the manpages of the index. They are not
+	// stored in the index, but configured by the server (see
+	// LoadOverrides).
+	Overrides map[string]string
+
+	// Log is used to log the decisions of Narrow at the debug level. A
+	// nil Log discards all messages.
+	Log *logging.Logger
 }
 
 // TODO(later): the default suite should be the latest stable release
@@ -73,6 +172,20 @@ func bestLanguageMatch(t []language.Tag, options []IndexEntry) IndexEntry {
 	return options[0]
 }
 
+// language returns the language of the index which l (from a request)
+// refers to: l itself, or its canonical spelling (see tag.Canonical),
+// so that e.g. /i3.pt-br resolves like /i3.pt_BR. It returns "" if the
+// index contains neither.
+func (i Index) language(l string) string {
+	if i.Langs[l] {
+		return l
+	}
+	if c := tag.Canonical(l); i.Langs[c] {
+		return c
+	}
+	return ""
+}
+
 func (i Index) split(path string) (suite string, binarypkg string, name string, section string, lang string) {
 	dir := strings.TrimPrefix(filepath.Dir(path), "/")
 	base := strings.TrimSpace(filepath.Base(path))
@@ -97,6 +210,9 @@ func (i Index) split(path string) (suite string, binarypkg string, name string,
 		} else if len(parts) == 2 && strings.HasPrefix(parts[1], "man") && i.Sections[strings.TrimPrefix(parts[1], "man")] {
 			// legacy manpages.debian.org
 			lang = parts[0]
+			if l := i.language(lang); l != "" {
+				lang = l
+			}
 			section = strings.TrimPrefix(parts[1], "man")
 		} else if len(parts) == 2 {
 			suite = parts[0]
@@ -112,7 +228,7 @@ func (i Index) split(path string) (suite string, binarypkg string, name string,
 
 	// The last part can either be a language or a section
 	consumed := 0
-	if l := parts[len(parts)-1]; i.Langs[l] {
+	if l := i.language(parts[len(parts)-1]); l != "" {
 		lang = l
 		consumed++
 	} else if l := parts[len(parts)-1]; i.Sections[l] {
@@ -141,13 +257,10 @@ func (p byMainSection) Len() int      { return len(p) }
 func (p byMainSection) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
 func (p byMainSection) Less(i, j int) bool {
 	// Compare main sections first
-	mi := p[i].Section[:1]
-	mj := p[j].Section[:1]
-	if mi < mj {
-		return true
-	}
-	if mi > mj {
-		return false
+	mi := section.Main(p[i].Section)
+	mj := section.Main(p[j].Section)
+	if c := section.Compare(mi, mj); c != 0 {
+		return c < 0
 	}
 	return len(p[i].Section) > len(p[j].Section)
 }
@@ -156,10 +269,13 @@ type bySection []IndexEntry
 
 func (p bySection) Len() int           { return len(p) }
 func (p bySection) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
-func (p bySection) Less(i, j int) bool { return p[i].Section < p[j].Section }
+func (p bySection) Less(i, j int) bool { return section.Less(p[i].Section, p[j].Section) }
 
 func (i Index) Narrow(acceptLang string, template, ref IndexEntry, entries []IndexEntry) []IndexEntry {
 	t := template // for convenience
+	if l := i.language(t.Language); l != "" {
+		t.Language = l
+	}
 
 	fullyQualified := func() bool {
 		if t.Suite == "" || t.Binarypkg == "" || t.Section == "" || t.Language == "" {
@@ -259,6 +375,7 @@ func (i Index) Narrow(acceptLang string, template, ref IndexEntry, entries []Ind
 	}
 
 	filter(func(e IndexEntry) bool { return t.Suite == "" || e.Suite == t.Suite })
+	i.Log.Debugf("narrowing %q: picked suite %q (referrer %q), %d candidates left", t.Name, t.Suite, ref.Suite, len(filtered))
 	if len(filtered) == 0 {
 		return nil
 	}
@@ -282,10 +399,17 @@ func (i Index) Narrow(acceptLang string, template, ref IndexEntry, entries []Ind
 
 	if t.Section == "" {
 		// TODO(later): respect the section preference cookie (+test)
-		t.Section = filtered[0].Section
+		t.Section = i.preferredSection(filtered)
+		if t.Section != filtered[0].Section {
+			// Ensure e.g. “3pm” is picked over “3” if preferred.
+			sort.SliceStable(filtered, func(a, b int) bool {
+				return filtered[a].Section == t.Section && filtered[b].Section != t.Section
+			})
+		}
 	}
 
 	filter(func(e IndexEntry) bool { return t.Section == "" || e.Section[:1] == t.Section[:1] })
+	i.Log.Debugf("narrowing %q: picked section %q, %d candidates left", t.Name, t.Section, len(filtered))
 	if len(filtered) == 0 {
 		return nil
 	}
@@ -303,6 +427,7 @@ func (i Index) Narrow(acceptLang string, template, ref IndexEntry, entries []Ind
 	}
 
 	filter(func(e IndexEntry) bool { return t.Language == "" || e.Language == t.Language })
+	i.Log.Debugf("narrowing %q: picked language %q (Accept-Language %q), %d candidates left", t.Name, t.Language, acceptLang, len(filtered))
 	if len(filtered) == 0 {
 		return nil
 	}
@@ -323,6 +448,38 @@ func (i Index) Narrow(acceptLang string, template, ref IndexEntry, entries []Ind
 	return filtered
 }
 
+// preferredSection returns the first section of SectionPriority which
+// occurs in filtered, or the section of the first entry in filtered.
+func (i Index) preferredSection(filtered []IndexEntry) string {
+	for _, section := range i.SectionPriority {
+		for _, e := range filtered {
+			if e.Section == section {
+				return section
+			}
+		}
+	}
+	return filtered[0].Section
+}
+
+// PreferredSuite returns the suite to pick from suites (e.g. those
+// which contain a binary package) like Narrow picks the suite of a
+// manpage: the suite of the referrer (ref), the default suite, or the
+// first suite.
+func (i Index) PreferredSuite(suites []string, ref string) string {
+	for _, want := range []string{ref, defaultSuite} {
+		for _, suite := range suites {
+			if want != "" && suite == want {
+				return suite
+			}
+		}
+	}
+	if len(suites) == 0 {
+		return ""
+	}
+	return suites[0]
+}
+
+// NotFoundError is returned when no manpage matches the request.
 type NotFoundError struct {
 	Manpage    string
 	BestChoice IndexEntry
@@ -332,20 +489,124 @@ func (e *NotFoundError) Error() string {
 	return "No such man page"
 }
 
+// AmbiguousError is returned by Lookup when multiple binary packages
+// ship the requested manpage (in the same suite, section and language)
+// and the request does not specify a binary package.
+type AmbiguousError struct {
+	Manpage string
+
+	// Candidates contains one entry per binary package. The first
+	// entry is the one which Redirect picks.
+	Candidates []IndexEntry
+}
+
+func (e *AmbiguousError) Error() string {
+	return fmt.Sprintf("man page %q is ambiguous: shipped by %d binary packages", e.Manpage, len(e.Candidates))
+}
+
+// BadPathError is returned for request paths which cannot refer to any
+// manpage.
+type BadPathError struct {
+	Path   string
+	Reason string
+}
+
+func (e *BadPathError) Error() string {
+	return fmt.Sprintf("malformed path %q: %s", e.Path, e.Reason)
+}
+
+// Redirect returns the path (relative to the base URL) to which the
+// request r should be redirected. Unlike Lookup, Redirect resolves
+// ambiguous requests to the preferred manpage (the rendered page lists
+// the alternatives), so it only returns a *NotFoundError or a
+// *BadPathError.
 func (i Index) Redirect(r *http.Request) (string, error) {
+	redir, _, _, err := i.RedirectSpecificity(r)
+	return redir, err
+}
+
+// RedirectSpecificity is like Redirect, but additionally returns the
+// Specificity of the redirect target and, if the request is ambiguous,
+// the paths of the alternatives (see AmbiguousError).
+func (i Index) RedirectSpecificity(r *http.Request) (redir string, spec Specificity, alternatives []string, err error) {
+	suffix, entry, fromSuite, spec, err := i.lookup(r)
+	if amb, ok := err.(*AmbiguousError); ok {
+		entry, err = amb.Candidates[0], nil
+		for _, c := range amb.Candidates[1:] {
+			alternatives = append(alternatives, c.ServingPath(suffix))
+		}
+	}
+	if err != nil {
+		return "", Specificity{}, nil, err
+	}
+	redir = entry.ServingPath(suffix)
+	if fromSuite != "" && suffix == ".html" {
+		redir += "?" + url.Values{"from_suite": []string{fromSuite}}.Encode()
+	}
+	if fragment := entry.Fragment(); fragment != "" && suffix == ".html" {
+		redir += "#" + fragment
+	}
+	return redir, spec, alternatives, nil
+}
+
+// Lookup returns the manpage to which the request r refers. The error
+// is a *NotFoundError, *AmbiguousError or *BadPathError.
+func (i Index) Lookup(r *http.Request) (IndexEntry, error) {
+	_, entry, _, _, err := i.lookup(r)
+	return entry, err
+}
+
+// Match is like Lookup, but additionally returns how well the manpage
+// matches the request, so that callers can decide whether to trust the
+// match or to present the alternatives. For an *AmbiguousError, the
+// Specificity refers to its first candidate.
+func (i Index) Match(r *http.Request) (IndexEntry, Specificity, error) {
+	_, entry, _, spec, err := i.lookup(r)
+	return entry, spec, err
+}
+
+// lookup implements Redirect and Lookup. suffix is ".html", ".gz" (for
+// raw manpages), ".frag.html" (for fragments) or ".min.html" (for
+// minimal pages), fromSuite is the requested suite if entry is in a
+// different one due to SuiteFallback.
+func (i Index) lookup(r *http.Request) (suffix string, entry IndexEntry, fromSuite string, spec Specificity, err error) {
 	path := r.URL.Path
+	if to, ok := i.Overrides[path]; ok {
+		i.Log.Debugf("override %q → %q", path, to)
+		path = to
+	}
 
 	if strings.HasSuffix(path, "/") ||
 		strings.HasSuffix(path, "/index.html") ||
 		strings.HasPrefix(path, "/contents-") {
-		return "", &NotFoundError{}
+		return "", IndexEntry{}, "", Specificity{}, &NotFoundError{}
 	}
 
-	suffix := ".html"
+	if !utf8.ValidString(path) {
+		return "", IndexEntry{}, "", Specificity{}, &BadPathError{Path: path, Reason: "not valid UTF-8"}
+	}
+	if strings.ContainsRune(path, 0) {
+		return "", IndexEntry{}, "", Specificity{}, &BadPathError{Path: path, Reason: "contains a NUL byte"}
+	}
+
+	suffix = ".html"
 	// If a raw manpage was requested, redirect to raw, not HTML
 	if strings.HasSuffix(path, ".gz") && !strings.HasSuffix(path, ".html.gz") {
 		suffix = ".gz"
 	}
+	// Fragments (see debiman’s -embed_fragments) are requested either
+	// directly or using ?embed=1.
+	if strings.HasSuffix(path, ".frag.html") {
+		suffix = ".frag.html"
+		path = strings.TrimSuffix(path, ".frag.html")
+	} else if suffix == ".html" && r.URL.Query().Get("embed") == "1" {
+		suffix = ".frag.html"
+	}
+	// Minimal pages (see debiman’s -minimal_pages).
+	if strings.HasSuffix(path, ".min.html") {
+		suffix = ".min.html"
+		path = strings.TrimSuffix(path, ".min.html")
+	}
 	for strings.HasSuffix(path, ".html") || strings.HasSuffix(path, ".gz") {
 		path = strings.TrimSuffix(path, ".gz")
 		path = strings.TrimSuffix(path, ".html")
@@ -359,6 +620,10 @@ func (i Index) Redirect(r *http.Request) (string, error) {
 	path = strings.Replace(path, "..", ".", -1)
 	path = strings.TrimSuffix(path, ".")
 
+	// With urlcase.Lower, all components (not just the name) are
+	// matched case-insensitively.
+	path = i.URLCase.Path(path)
+
 	var suite, binarypkg, name, section, lang string
 	if strings.HasPrefix(path, "/man") && strings.Index(path[1:], "/") > -1 {
 		suite, binarypkg, name, section, lang = i.splitLegacy(path)
@@ -375,7 +640,11 @@ func (i Index) Redirect(r *http.Request) (string, error) {
 
 	log.Printf("path %q -> suite = %q, binarypkg = %q, name = %q, section = %q, lang = %q", path, suite, binarypkg, name, section, lang)
 
-	lname := strings.ToLower(name)
+	if strings.Trim(name, "./") == "" {
+		return "", IndexEntry{}, "", Specificity{}, &BadPathError{Path: r.URL.Path, Reason: "no manpage name"}
+	}
+
+	lname := i.URLCase.Name(name)
 	entries, ok := i.Entries[lname]
 	if !ok {
 		// Fall back to joining (originally) whitespace-separated
@@ -384,7 +653,7 @@ func (i Index) Redirect(r *http.Request) (string, error) {
 		if !ok {
 			entries, ok = i.Entries[strings.Replace(lname, ".", "_", -1)]
 			if !ok {
-				return "", &NotFoundError{Manpage: name}
+				return "", IndexEntry{}, "", Specificity{}, &NotFoundError{Manpage: name}
 			}
 		}
 	}
@@ -396,25 +665,102 @@ func (i Index) Redirect(r *http.Request) (string, error) {
 		Section:   r.FormValue("section"),
 		Language:  r.FormValue("language"),
 	}
-	filtered := i.Narrow(acceptLang, IndexEntry{
-		Suite:     suite,
-		Binarypkg: binarypkg,
-		Section:   section,
-		Language:  lang,
-	}, ref, entries)
-
-	if len(filtered) == 0 {
+	notFound := func() error {
 		// Present the user with another choice for this manpage.
 		var best IndexEntry
 		if name != "index" && name != "favicon" {
 			best = i.Narrow(acceptLang, IndexEntry{}, ref, entries)[0]
 		}
-		return "", &NotFoundError{
+		return &NotFoundError{
 			Manpage:    name,
 			BestChoice: best}
 	}
 
-	return filtered[0].ServingPath(suffix), nil
+	template := IndexEntry{
+		Suite:     suite,
+		Binarypkg: binarypkg,
+		Section:   section,
+		Language:  lang,
+	}
+	if suite != "" && i.SuiteFallback != FallbackAny && !containsSuite(entries, suite, section) {
+		var newer string
+		if i.SuiteFallback == FallbackNewer {
+			newer = nearestNewerSuite(entries, suite, section)
+		}
+		if newer == "" {
+			return "", IndexEntry{}, "", Specificity{}, notFound()
+		}
+		fromSuite = suite
+		template.Suite = newer
+	}
+
+	filtered := i.Narrow(acceptLang, template, ref, entries)
+
+	if len(filtered) == 0 {
+		return "", IndexEntry{}, "", Specificity{}, notFound()
+	}
+
+	best := filtered[0]
+	// Compare against the request, not against template: a suite
+	// picked by SuiteFallback was not requested.
+	spec = specificity(IndexEntry{
+		Suite:     suite,
+		Binarypkg: binarypkg,
+		Section:   section,
+		Language:  lang,
+	}, best)
+	i.Log.Debugf("matched %q: %v", name, spec)
+	if binarypkg == "" {
+		candidates := []IndexEntry{best}
+		for _, e := range entries {
+			if e.Suite == best.Suite &&
+				e.Section == best.Section &&
+				e.Language == best.Language &&
+				e.Binarypkg != best.Binarypkg {
+				candidates = append(candidates, e)
+			}
+		}
+		if len(candidates) > 1 {
+			return suffix, best, fromSuite, spec, &AmbiguousError{
+				Manpage:    name,
+				Candidates: candidates,
+			}
+		}
+	}
+
+	return suffix, best, fromSuite, spec, nil
+}
+
+// NewIndex returns an Index of entries (keyed by name, see
+// Index.Entries) and suites (aliases and codenames to suites), which
+// are completed with the codename of each entry’s suite. Langs and
+// Sections are computed from entries, like debiman does when writing
+// the auxserver index. NewIndex is intended for tests, which otherwise
+// need to write an index file for IndexFromProto; see also the
+// redirecttest package.
+func NewIndex(entries map[string][]IndexEntry, suites map[string]string) Index {
+	index := Index{
+		Entries:  entries,
+		Suites:   make(map[string]string, len(suites)),
+		Langs:    make(map[string]bool),
+		Sections: map[string]bool{"0": true},
+	}
+	for name, suite := range suites {
+		index.Suites[name] = suite
+	}
+	for _, variants := range entries {
+		for _, e := range variants {
+			if _, ok := index.Suites[e.Suite]; !ok {
+				index.Suites[e.Suite] = e.Suite
+			}
+			index.Langs[e.Language] = true
+			if e.Section != "" {
+				index.Sections[e.Section] = true
+				index.Sections[e.Section[:1]] = true
+			}
+		}
+	}
+	return index
 }
 
 func IndexFromProto(path string) (Index, error) {
@@ -427,20 +773,23 @@ func IndexFromProto(path string) (Index, error) {
 	if err != nil {
 		return index, err
 	}
-	var idx pb.Index
-	if err := proto.Unmarshal(b, &idx); err != nil {
+	stripped, err := pb.StripTrailer(b)
+	if err != nil {
+		return index, fmt.Errorf("%s: %v", path, err)
+	}
+	crc := pb.TrailerChecksum(b)
+	b = stripped
+	idx, interned, err := pb.DecodeIndex(b)
+	if err != nil {
+		return index, fmt.Errorf("%s: %v", path, err)
+	}
+	index.URLCase, err = urlcase.Parse(idx.UrlCase)
+	if err != nil {
 		return index, err
 	}
-	index.Entries = make(map[string][]IndexEntry, len(idx.Entry))
-	for _, e := range idx.Entry {
-		name := strings.ToLower(e.Name)
-		index.Entries[name] = append(index.Entries[name], IndexEntry{
-			Name:      e.Name,
-			Suite:     e.Suite,
-			Binarypkg: e.Binarypkg,
-			Section:   e.Section,
-			Language:  e.Language,
-		})
+	index.Entries, err = entriesFromProto(idx, interned, index.URLCase)
+	if err != nil {
+		return index, fmt.Errorf("%s: %v", path, err)
 	}
 	for _, l := range idx.Language {
 		index.Langs[l] = true
@@ -450,6 +799,51 @@ func IndexFromProto(path string) (Index, error) {
 		index.Sections[l] = true
 	}
 	index.Sections["0"] = true
+	index.SectionPriority = idx.SectionPriority
+	if idx.GroupLanguages {
+		index.GroupLanguages = true
+		markGrouped(index.Entries)
+	}
+	if idx.Built != 0 {
+		index.Built = time.Unix(idx.Built, 0).UTC()
+	}
+	index.Version = IndexVersion(index.Built, crc)
 
 	return index, nil
 }
+
+// markGrouped sets IndexEntry.Grouped for all entries whose binary
+// package ships the manpage in more than one language, like debiman’s
+// -group_languages. Aliases are not rendered, so they do not count.
+func markGrouped(entries map[string][]IndexEntry) {
+	type page struct{ suite, binarypkg, name, section string }
+	for _, variants := range entries {
+		langs := make(map[page]map[string]bool)
+		for _, e := range variants {
+			if e.Target != "" {
+				continue
+			}
+			p := page{e.Suite, e.Binarypkg, e.Name, e.Section}
+			if langs[p] == nil {
+				langs[p] = make(map[string]bool)
+			}
+			langs[p][e.Language] = true
+		}
+		for idx, e := range variants {
+			if e.Target == "" && len(langs[page{e.Suite, e.Binarypkg, e.Name, e.Section}]) > 1 {
+				variants[idx].Grouped = true
+			}
+		}
+	}
+}
+
+// IndexVersion returns the version of an index file built at built
+// (the zero time if unknown) whose trailer specifies checksum crc. The
+// build time makes versions sortable, the checksum distinguishes
+// indexes whose build time is equal or unknown.
+func IndexVersion(built time.Time, crc uint32) string {
+	if built.IsZero() {
+		return fmt.Sprintf("%08x", crc)
+	}
+	return fmt.Sprintf("%s-%08x", built.UTC().Format("20060102T150405Z"), crc)
+}
@@ -1,9 +1,23 @@
 package redirect
 
 import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	pb "github.com/Debian/debiman/internal/proto"
+	"github.com/Debian/debiman/internal/urlcase"
+	"github.com/golang/protobuf/proto"
 )
 
 var testIdx = Index{
@@ -250,7 +264,7 @@ func TestNotFoundFullySpecified(t *testing.T) {
 	}
 	var empty IndexEntry
 	if got, want := e.BestChoice, empty; got != want {
-		t.Fatalf("Unexpected e.BestChoice: got %q, want %q", got, want)
+		t.Fatalf("Unexpected e.BestChoice: got %+v, want %+v", got, want)
 	}
 }
 
@@ -640,8 +654,1043 @@ func TestBlankRedirect(t *testing.T) {
 	}
 }
 
+func TestSuiteFallback(t *testing.T) {
+	table := []struct {
+		fallback SuiteFallback
+		URL      string
+		want     string // empty means NotFoundError
+	}{
+		{FallbackAny, "jessie/javafxpackager", "testing/openjfx/javafxpackager.1.en.html"},
+		{FallbackNewer, "jessie/javafxpackager", "testing/openjfx/javafxpackager.1.en.html?from_suite=jessie"},
+		{FallbackNewer, "oldstable/javafxpackager", "testing/openjfx/javafxpackager.1.en.html?from_suite=wheezy"},
+		{FallbackNewer, "jessie/javafxpackager.1.en.gz", "testing/openjfx/javafxpackager.1.en.gz"},
+		{FallbackNewer, "testing/javafxpackager", "testing/openjfx/javafxpackager.1.en.html"},
+		{FallbackNewer, "testing/systemd.service", ""},
+		{FallbackNewer, "jessie/i3.5", "jessie/i3-wm/i3.5.en.html"},
+		{FallbackNone, "jessie/javafxpackager", ""},
+		{FallbackNone, "testing/i3", "testing/i3-wm/i3.1.en.html"},
+	}
+	for _, entry := range table {
+		entry := entry // capture
+		t.Run(entry.fallback.String()+"/"+entry.URL, func(t *testing.T) {
+			t.Parallel()
+
+			idx := testIdx // copy
+			idx.SuiteFallback = entry.fallback
+			u, err := url.Parse("http://man.debian.org/" + entry.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := idx.Redirect(&http.Request{URL: u})
+			if entry.want == "" {
+				if _, ok := err.(*NotFoundError); !ok {
+					t.Fatalf("Redirect(%q) = %q, %v, want NotFoundError", entry.URL, got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := "/" + entry.want; got != want {
+				t.Fatalf("Unexpected redirect: got %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestParseSuiteFallback(t *testing.T) {
+	for _, f := range []SuiteFallback{FallbackAny, FallbackNewer, FallbackNone} {
+		got, err := ParseSuiteFallback(f.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != f {
+			t.Errorf("ParseSuiteFallback(%q) = %v, want %v", f.String(), got, f)
+		}
+	}
+	if _, err := ParseSuiteFallback("nearest"); err == nil {
+		t.Errorf("ParseSuiteFallback(%q) unexpectedly succeeded", "nearest")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	idx := testIdx // copy
+	idx.Entries = map[string][]IndexEntry{
+		"vi": []IndexEntry{
+			{Name: "vi", Suite: "jessie", Binarypkg: "vim", Section: "1", Language: "en"},
+			{Name: "vi", Suite: "jessie", Binarypkg: "nvi", Section: "1", Language: "en"},
+			{Name: "vi", Suite: "jessie", Binarypkg: "nvi", Section: "1", Language: "fr"},
+		},
+	}
+
+	lookup := func(path string) (IndexEntry, error) {
+		return idx.Lookup(&http.Request{URL: &url.URL{Path: path}})
+	}
+
+	_, err := lookup("/jessie/vi")
+	amb, ok := err.(*AmbiguousError)
+	if !ok {
+		t.Fatalf("Lookup(/jessie/vi): got err %v, want AmbiguousError", err)
+	}
+	if got, want := len(amb.Candidates), 2; got != want {
+		t.Fatalf("unexpected number of candidates: got %d, want %d", got, want)
+	}
+	redir, err := idx.Redirect(&http.Request{URL: &url.URL{Path: "/jessie/vi"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := redir, amb.Candidates[0].ServingPath(".html"); got != want {
+		t.Fatalf("Redirect does not pick the first candidate: got %q, want %q", got, want)
+	}
+
+	for _, path := range []string{"/jessie/nvi/vi", "/jessie/vi.fr"} {
+		if _, err := lookup(path); err != nil {
+			t.Errorf("Lookup(%q): %v", path, err)
+		}
+	}
+
+	if _, err := lookup("/jessie/emacs"); err == nil {
+		t.Errorf("Lookup(/jessie/emacs) unexpectedly succeeded")
+	} else if _, ok := err.(*NotFoundError); !ok {
+		t.Errorf("Lookup(/jessie/emacs): got err %v, want NotFoundError", err)
+	}
+
+	for _, path := range []string{"/.", "/vi\xff", "/vi\x00"} {
+		_, err := lookup(path)
+		if _, ok := err.(*BadPathError); !ok {
+			t.Errorf("Lookup(%q): got err %v, want BadPathError", path, err)
+		}
+	}
+}
+
 // // TODO: no longer supported releases result in an error page with a link to the oldest stable version
 // {
 // 	URL:  "http://man.debian.org/lenny/i3",
 // 	want: "http://man.debian.org/wheezy/i3-wm/i3.1.en.html",
 // },
+
+func TestURLCase(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "debiman-redirect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	for _, entry := range []struct {
+		urlCase string
+		url     string
+		want    string
+	}{
+		{urlCase: "", url: "/xorg", want: "/jessie/xserver-xorg-core/Xorg.1.en.html"},
+		{urlCase: "", url: "/Xorg", want: "/jessie/xserver-xorg-core/Xorg.1.en.html"},
+		{urlCase: "preserve", url: "/Xorg", want: "/jessie/xserver-xorg-core/Xorg.1.en.html"},
+		{urlCase: "preserve", url: "/xorg", want: ""},
+		{urlCase: "lower", url: "/XORG", want: "/jessie/xserver-xorg-core/xorg.1.en.html"},
+		{urlCase: "lower", url: "/Jessie/xorg", want: "/jessie/xserver-xorg-core/xorg.1.en.html"},
+	} {
+		policy, err := urlcase.Parse(entry.urlCase)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Like debiman’s writeIndex, store the components cased
+		// according to the policy.
+		b, err := proto.Marshal(&pb.Index{
+			Entry: []*pb.IndexEntry{
+				{
+					Name:      policy.Path("Xorg"),
+					Suite:     "jessie",
+					Binarypkg: "xserver-xorg-core",
+					Section:   "1",
+					Language:  "en",
+				},
+			},
+			Language: []string{"en"},
+			Section:  []string{"1"},
+			Suite:    map[string]string{"jessie": "jessie"},
+			UrlCase:  entry.urlCase,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		fn := filepath.Join(tmpdir, "auxserver.idx")
+		if err := ioutil.WriteFile(fn, pb.AppendTrailer(b), 0644); err != nil {
+			t.Fatal(err)
+		}
+		idx, err := IndexFromProto(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := idx.URLCase, policy; got != want {
+			t.Fatalf("IndexFromProto: got URL case policy %v, want %v", got, want)
+		}
+		u, err := url.Parse("http://man.debian.org" + entry.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := idx.Redirect(&http.Request{URL: u})
+		if entry.want == "" {
+			if err == nil {
+				t.Errorf("policy %v: Redirect(%q) = %q, want not found", policy, entry.url, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("policy %v: Redirect(%q): %v", policy, entry.url, err)
+			continue
+		}
+		if got != entry.want {
+			t.Errorf("policy %v: Redirect(%q) = %q, want %q", policy, entry.url, got, entry.want)
+		}
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(tmpdir, "bogus.idx"), mustMarshal(t, &pb.Index{UrlCase: "upper"}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := IndexFromProto(filepath.Join(tmpdir, "bogus.idx")); err == nil {
+		t.Errorf("IndexFromProto unexpectedly accepted an unknown URL case policy")
+	}
+}
+
+func TestSectionPriority(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "debiman-redirect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	fn := filepath.Join(tmpdir, "auxserver.idx")
+	b := mustMarshal(t, &pb.Index{
+		Entry: []*pb.IndexEntry{
+			{Name: "editline", Suite: "jessie", Binarypkg: "libeditline-dev", Section: "3", Language: "en"},
+			{Name: "editline", Suite: "jessie", Binarypkg: "libedit-dev", Section: "3edit", Language: "en"},
+			{Name: "editline", Suite: "jessie", Binarypkg: "libedit-dev", Section: "7", Language: "en"},
+		},
+		Language:        []string{"en"},
+		Suite:           map[string]string{"jessie": "jessie"},
+		Section:         []string{"3", "3edit", "7"},
+		SectionPriority: []string{"7", "3edit"},
+	})
+	if err := ioutil.WriteFile(fn, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	idx, err := IndexFromProto(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range []struct {
+		priority []string
+		URL      string
+		want     string
+	}{
+		{idx.SectionPriority, "/editline", "/jessie/libedit-dev/editline.7.en.html"},
+		{[]string{"3edit"}, "/editline", "/jessie/libedit-dev/editline.3edit.en.html"},
+		{nil, "/editline", "/jessie/libeditline-dev/editline.3.en.html"},
+		// An explicitly requested section takes precedence.
+		{idx.SectionPriority, "/editline.3", "/jessie/libeditline-dev/editline.3.en.html"},
+	} {
+		idx.SectionPriority = entry.priority
+		got, err := idx.Redirect(&http.Request{URL: &url.URL{Path: entry.URL}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != entry.want {
+			t.Errorf("section priority %q: Redirect(%q) = %q, want %q", entry.priority, entry.URL, got, entry.want)
+		}
+	}
+}
+
+// mustMarshal marshals idx like debiman’s writeIndex did before
+// interning (index version 0).
+func mustMarshal(t testing.TB, idx *pb.Index) []byte {
+	b, err := proto.Marshal(idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pb.AppendTrailer(b)
+}
+
+// syntheticProtoIndex returns an index of n manpages, each shipped in
+// three suites and two languages, for comparing index versions.
+func syntheticProtoIndex(n int) *pb.Index {
+	idx := &pb.Index{
+		Language: []string{"en", "de"},
+		Section:  []string{"1", "5"},
+		Suite:    map[string]string{"jessie": "jessie", "stretch": "stretch", "sid": "sid"},
+	}
+	for i := 0; i < n; i++ {
+		for _, suite := range []string{"jessie", "stretch", "sid"} {
+			for _, lang := range []string{"en", "de"} {
+				var arch, target string
+				if i%10 == 0 {
+					arch = "amd64" // architecture-specific manpages
+				}
+				if i%10 == 5 {
+					// an alias of the previous manpage
+					target = fmt.Sprintf("%s/package%d/tool%d.1.%s", suite, (i-1)/3, i-1, lang)
+				}
+				idx.Entry = append(idx.Entry, &pb.IndexEntry{
+					Name:         fmt.Sprintf("Tool%d", i),
+					Suite:        suite,
+					Binarypkg:    fmt.Sprintf("package%d", i/3),
+					Section:      []string{"1", "5"}[i%2],
+					Language:     lang,
+					Architecture: arch,
+					Target:       target,
+				})
+			}
+		}
+	}
+	return idx
+}
+
+// mustWrite writes idx using pb.IndexWriter, like debiman’s writeIndex.
+func mustWrite(t testing.TB, idx *pb.Index) []byte {
+	var buf bytes.Buffer
+	w := pb.NewIndexWriter(&buf)
+	for _, e := range idx.Entry {
+		if err := w.WriteEntry(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, l := range idx.Language {
+		if err := w.WriteLanguage(l); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, suite := range idx.Suite {
+		if err := w.WriteSuite(name, suite); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, s := range idx.Section {
+		if err := w.WriteSection(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.WriteURLCase(idx.UrlCase); err != nil {
+		t.Fatal(err)
+	}
+	if idx.Built != 0 {
+		w.SetBuilt(time.Unix(idx.Built, 0))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestIndexVersions(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "debiman-redirect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	load := func(b []byte) (Index, error) {
+		fn := filepath.Join(tmpdir, "auxserver.idx")
+		if err := ioutil.WriteFile(fn, b, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return IndexFromProto(fn)
+	}
+
+	idx := syntheticProtoIndex(100)
+	idx.Built = 1495548900
+	legacyB, internedB := mustMarshal(t, idx), mustWrite(t, idx)
+	legacy, err := load(legacyB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	interned, err := load(internedB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(interned.Entries), 100; got != want {
+		t.Fatalf("Unexpected number of names: got %d, want %d", got, want)
+	}
+	if got, want := interned.Built, time.Date(2017, 5, 23, 14, 15, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Unexpected build time: got %v, want %v", got, want)
+	}
+	// The versions differ in the checksum of the files.
+	for _, v := range []string{legacy.Version, interned.Version} {
+		if !strings.HasPrefix(v, "20170523T141500Z-") {
+			t.Errorf("Version %q does not start with the build time", v)
+		}
+	}
+	if legacy.Version == interned.Version {
+		t.Errorf("Versions of different index files are equal: %q", legacy.Version)
+	}
+	legacy.Version, interned.Version = "", ""
+	if !reflect.DeepEqual(legacy, interned) {
+		t.Fatalf("Indexes differ:\nversion 0: %+v\nversion %d: %+v", legacy, pb.IndexVersion, interned)
+	}
+	if got, want := len(internedB), len(legacyB)/2; got > want {
+		t.Errorf("Interned index is too large: got %d bytes, want at most %d bytes (half of %d bytes)", got, want, len(legacyB))
+	}
+
+	for _, bogus := range []*pb.Index{
+		{Version: pb.IndexVersion + 1},
+		{
+			Version:       pb.IndexVersion,
+			StringTable:   []string{"i3"},
+			InternedEntry: []*pb.InternedEntry{{Name: 0, Suite: 1}},
+		},
+		{
+			Version:       pb.IndexVersion,
+			StringTable:   []string{"i3"},
+			InternedEntry: []*pb.InternedEntry{{Architecture: 2}},
+		},
+		{
+			Version:       pb.IndexVersion,
+			StringTable:   []string{"i3"},
+			InternedEntry: []*pb.InternedEntry{{Target: 2}},
+		},
+	} {
+		if _, err := load(mustMarshal(t, bogus)); err == nil {
+			t.Errorf("IndexFromProto unexpectedly accepted %v", bogus)
+		}
+	}
+}
+
+func TestCanonicalLanguageRequests(t *testing.T) {
+	idx := NewIndex(map[string][]IndexEntry{
+		"deja-dup": {
+			{Name: "deja-dup", Suite: "jessie", Binarypkg: "deja-dup", Section: "1", Language: "en"},
+			{Name: "deja-dup", Suite: "jessie", Binarypkg: "deja-dup", Section: "1", Language: "pt_BR"},
+		},
+	}, map[string]string{"jessie": "jessie"})
+	for _, path := range []string{
+		"/deja-dup.pt_BR",
+		"/deja-dup.pt-br",
+		"/deja-dup.1.pt_br",
+		"/jessie/deja-dup/deja-dup.1.PT-BR",
+		"/man/pt-BR/deja-dup",
+	} {
+		got, err := idx.Redirect(&http.Request{URL: &url.URL{Path: path}})
+		if err != nil {
+			t.Errorf("Redirect(%q): %v", path, err)
+			continue
+		}
+		if want := "/jessie/deja-dup/deja-dup.1.pt_BR.html"; got != want {
+			t.Errorf("Redirect(%q) = %q, want %q", path, got, want)
+		}
+	}
+
+	// Narrow (e.g. in debiman-idx2rwmap) matches canonically, too.
+	filtered := idx.Narrow("", IndexEntry{Language: "pt-br"}, IndexEntry{}, idx.Entries["deja-dup"])
+	if len(filtered) != 1 || filtered[0].Language != "pt_BR" {
+		t.Errorf("Narrow(pt-br) = %+v, want the pt_BR entry", filtered)
+	}
+}
+
+func TestAliasRedirect(t *testing.T) {
+	idx := NewIndex(map[string][]IndexEntry{
+		"gunzip": {
+			{Name: "gunzip", Suite: "jessie", Binarypkg: "gzip", Section: "1", Language: "en", Target: "jessie/gzip/gzip.1.en"},
+		},
+		"gzip": {
+			{Name: "gzip", Suite: "jessie", Binarypkg: "gzip", Section: "1", Language: "en"},
+		},
+	}, map[string]string{"jessie": "jessie"})
+	for _, entry := range []struct {
+		path string
+		want string
+	}{
+		{"/gunzip", "/jessie/gzip/gzip.1.en.html"},
+		{"/jessie/gunzip(1)", "/jessie/gzip/gzip.1.en.html"},
+		{"/jessie/gzip/gunzip.1.en.html", "/jessie/gzip/gzip.1.en.html"},
+		{"/jessie/gzip/gunzip.1.en.gz", "/jessie/gzip/gzip.1.en.gz"},
+		{"/gunzip.1.en.frag.html", "/jessie/gzip/gzip.1.en.frag.html"},
+		{"/gzip", "/jessie/gzip/gzip.1.en.html"},
+	} {
+		got, err := idx.Redirect(&http.Request{URL: &url.URL{Path: entry.path}})
+		if err != nil {
+			t.Errorf("Redirect(%q): %v", entry.path, err)
+			continue
+		}
+		if got != entry.want {
+			t.Errorf("Redirect(%q) = %q, want %q", entry.path, got, entry.want)
+		}
+	}
+}
+
+func TestGroupedRedirect(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "debiman-redirect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	fn := filepath.Join(tmpdir, "auxserver.idx")
+	b := mustMarshal(t, &pb.Index{
+		Entry: []*pb.IndexEntry{
+			{Name: "crontab", Suite: "jessie", Binarypkg: "cron", Section: "5", Language: "en"},
+			{Name: "crontab", Suite: "jessie", Binarypkg: "cron", Section: "5", Language: "de"},
+			{Name: "crontab", Suite: "jessie", Binarypkg: "cron", Section: "1", Language: "en"},
+			{Name: "crontab", Suite: "jessie", Binarypkg: "manpages-fr", Section: "5", Language: "fr"},
+		},
+		Language:       []string{"en", "de", "fr"},
+		Suite:          map[string]string{"jessie": "jessie"},
+		Section:        []string{"1", "5"},
+		GroupLanguages: true,
+	})
+	if err := ioutil.WriteFile(fn, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	idx, err := IndexFromProto(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !idx.GroupLanguages {
+		t.Fatalf("IndexFromProto did not read group_languages")
+	}
+
+	for _, entry := range []struct {
+		path string
+		want string
+	}{
+		{"/crontab.5", "/jessie/cron/crontab.5.html#lang-en"},
+		{"/crontab.5.de", "/jessie/cron/crontab.5.html#lang-de"},
+		{"/jessie/cron/crontab.5.de.html", "/jessie/cron/crontab.5.html#lang-de"},
+		// Raw manpages are per language.
+		{"/jessie/cron/crontab.5.de.gz", "/jessie/cron/crontab.5.de.gz"},
+		// Manpages in only one language of their binary package keep
+		// their per-language page.
+		{"/crontab.1", "/jessie/cron/crontab.1.en.html"},
+		{"/crontab.5.fr", "/jessie/manpages-fr/crontab.5.fr.html"},
+	} {
+		got, err := idx.Redirect(&http.Request{URL: &url.URL{Path: entry.path}})
+		if err != nil {
+			t.Errorf("Redirect(%q): %v", entry.path, err)
+			continue
+		}
+		if got != entry.want {
+			t.Errorf("Redirect(%q) = %q, want %q", entry.path, got, entry.want)
+		}
+	}
+}
+
+func TestForEachOrder(t *testing.T) {
+	entries := []IndexEntry{
+		{Name: "crontab", Suite: "stretch", Binarypkg: "cron", Section: "5", Language: "en"},
+		{Name: "crontab", Suite: "jessie", Binarypkg: "systemd-cron", Section: "8", Language: "en"},
+		{Name: "crontab", Suite: "jessie", Binarypkg: "cron", Section: "5", Language: "de", Grouped: true},
+		{Name: "crontab", Suite: "jessie", Binarypkg: "cron", Section: "5", Language: "en", Grouped: true},
+		{Name: "cron", Suite: "jessie", Binarypkg: "cron", Section: "8", Language: "en", Architecture: "i386"},
+		{Name: "cron", Suite: "jessie", Binarypkg: "cron", Section: "8", Language: "en", Architecture: "amd64"},
+		{Name: "anacron", Suite: "jessie", Binarypkg: "anacron", Section: "8", Language: "en"},
+	}
+	want := []string{
+		"anacron /jessie/anacron/anacron.8.en.gz",
+		"cron /jessie/cron/cron.8.en.gz amd64",
+		"cron /jessie/cron/cron.8.en.gz i386",
+		"crontab /jessie/cron/crontab.5.de.gz",
+		"crontab /jessie/cron/crontab.5.en.gz",
+		"crontab /jessie/systemd-cron/crontab.8.en.gz",
+		"crontab /stretch/cron/crontab.5.en.gz",
+	}
+	// The order must not depend on the order of the entries in the index.
+	for _, perm := range [][]int{
+		{0, 1, 2, 3, 4, 5, 6},
+		{6, 5, 4, 3, 2, 1, 0},
+		{3, 0, 5, 2, 6, 1, 4},
+	} {
+		byName := make(map[string][]IndexEntry)
+		for _, idx := range perm {
+			e := entries[idx]
+			byName[e.Name] = append(byName[e.Name], e)
+		}
+		var got []string
+		if err := NewIndex(byName, nil).ForEach(func(name string, e IndexEntry) error {
+			got = append(got, strings.TrimSpace(name+" "+e.ServingPath(".gz")+" "+e.Architecture))
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ForEach order for permutation %v:\ngot  %q\nwant %q", perm, got, want)
+		}
+	}
+
+	// ForEach stops at the first error.
+	errStop := fmt.Errorf("stop")
+	var calls int
+	err := NewIndex(map[string][]IndexEntry{"crontab": entries[:4]}, nil).ForEach(func(name string, e IndexEntry) error {
+		calls++
+		return errStop
+	})
+	if err != errStop {
+		t.Errorf("ForEach returned %v, want %v", err, errStop)
+	}
+	if got, want := calls, 1; got != want {
+		t.Errorf("ForEach called fn %d times after an error, want %d", got, want)
+	}
+}
+
+func TestNewIndex(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "debiman-redirect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	fn := filepath.Join(tmpdir, "auxserver.idx")
+	b := mustWrite(t, syntheticProtoIndex(10))
+	if err := ioutil.WriteFile(fn, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := IndexFromProto(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := loaded.Version, IndexVersion(time.Time{}, pb.TrailerChecksum(b)); got != want {
+		t.Errorf("Unexpected version: got %q, want %q", got, want)
+	}
+	// NewIndex indexes are not read from a file.
+	loaded.Version = ""
+	// The other suites are completed from the entries.
+	suites := map[string]string{"stretch": "stretch"}
+	if got := NewIndex(loaded.Entries, suites); !reflect.DeepEqual(got, loaded) {
+		t.Errorf("NewIndex differs from IndexFromProto:\ngot  %+v\nwant %+v", got, loaded)
+	}
+	if got, want := len(suites), 1; got != want {
+		t.Errorf("NewIndex modified its suites argument: got %d suites, want %d", got, want)
+	}
+}
+
+func TestIndexFromProtoConcurrent(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "debiman-redirect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	load := func(b []byte) Index {
+		fn := filepath.Join(tmpdir, "auxserver.idx")
+		if err := ioutil.WriteFile(fn, b, 0644); err != nil {
+			t.Fatal(err)
+		}
+		idx, err := IndexFromProto(fn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		idx.Version = ""
+		return idx
+	}
+
+	// Enough entries for several shards, with names which collide
+	// after applying the URL case policy.
+	idx := syntheticProtoIndex(4 * minShardEntries)
+	for _, e := range idx.Entry[:len(idx.Entry)/2] {
+		e.Name = strings.ToLower(e.Name)
+	}
+	idx.UrlCase = "lower"
+	// Version 0 indexes are not sharded.
+	want := load(mustMarshal(t, idx))
+	b := mustWrite(t, idx)
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(0))
+	for _, procs := range []int{1, 3, 8} {
+		runtime.GOMAXPROCS(procs)
+		if got := load(b); !reflect.DeepEqual(got, want) {
+			t.Errorf("GOMAXPROCS=%d: Index differs from the version 0 index", procs)
+		}
+	}
+}
+
+// BenchmarkIndexFromProto loads an index of 600,000 entries, the order
+// of magnitude of manpages.debian.org. Compare the load time with
+// different numbers of goroutines using e.g. -cpu=1,2,4.
+func BenchmarkIndexFromProto(b *testing.B) {
+	tmpdir, err := ioutil.TempDir("", "debiman-redirect")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	idx := syntheticProtoIndex(100000)
+	for _, format := range []struct {
+		name  string
+		bytes []byte
+	}{
+		{"legacy", mustMarshal(b, idx)},
+		{"interned", mustWrite(b, idx)},
+	} {
+		fn := filepath.Join(tmpdir, format.name+".idx")
+		if err := ioutil.WriteFile(fn, format.bytes, 0644); err != nil {
+			b.Fatal(err)
+		}
+		b.Run(format.name, func(b *testing.B) {
+			b.SetBytes(int64(len(format.bytes)))
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				if _, err := IndexFromProto(fn); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestTruncatedIndex(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "debiman-redirect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	idx := &pb.Index{
+		Language: []string{"en"},
+		Section:  []string{"1"},
+		Suite:    map[string]string{"jessie": "jessie"},
+	}
+	for i := 0; i < 100; i++ {
+		idx.Entry = append(idx.Entry, &pb.IndexEntry{
+			Name:      "i3",
+			Suite:     "jessie",
+			Binarypkg: "i3-wm",
+			Section:   "1",
+			Language:  "en",
+		})
+	}
+	b := mustMarshal(t, idx)
+	fn := filepath.Join(tmpdir, "auxserver.idx")
+
+	if err := ioutil.WriteFile(fn, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := IndexFromProto(fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(loaded.Entries["i3"]), 100; got != want {
+		t.Fatalf("Unexpected number of entries: got %d, want %d", got, want)
+	}
+
+	// Every truncation must be rejected, including those which end
+	// on a protobuf field boundary and would hence parse fine.
+	for _, n := range []int{0, 1, len(b) / 2, len(b) - 17, len(b) - 16, len(b) - 1} {
+		if err := ioutil.WriteFile(fn, b[:n], 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := IndexFromProto(fn); err == nil {
+			t.Errorf("IndexFromProto unexpectedly accepted an index truncated to %d of %d bytes", n, len(b))
+		}
+	}
+	if err := proto.Unmarshal(b[:len(b)-16], &pb.Index{}); err != nil {
+		t.Fatalf("test precondition: index without trailer does not unmarshal: %v", err)
+	}
+}
+
+func TestEmbedFragment(t *testing.T) {
+	table := []struct {
+		URL  string
+		want string
+	}{
+		{"testing/i3?embed=1", "testing/i3-wm/i3.1.en.frag.html"},
+		{"testing/i3.frag.html", "testing/i3-wm/i3.1.en.frag.html"},
+		{"jessie/i3.5.frag.html", "jessie/i3-wm/i3.5.en.frag.html"},
+		{"testing/i3.1.en.gz?embed=1", "testing/i3-wm/i3.1.en.gz"},
+		{"testing/i3?embed=0", "testing/i3-wm/i3.1.en.html"},
+		{"testing/i3.min.html", "testing/i3-wm/i3.1.en.min.html"},
+		{"jessie/i3.5.min.html", "jessie/i3-wm/i3.5.en.min.html"},
+	}
+	for _, entry := range table {
+		u, err := url.Parse("http://man.debian.org/" + entry.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := testIdx.Redirect(&http.Request{URL: u})
+		if err != nil {
+			t.Fatalf("Redirect(%q): %v", entry.URL, err)
+		}
+		if want := "/" + entry.want; got != want {
+			t.Errorf("Redirect(%q) = %q, want %q", entry.URL, got, want)
+		}
+	}
+}
+
+func TestSpecificity(t *testing.T) {
+	idx := testIdx // copy
+	idx.Entries = map[string][]IndexEntry{
+		"vi": []IndexEntry{
+			{Name: "vi", Suite: "jessie", Binarypkg: "vim", Section: "1", Language: "en"},
+			{Name: "vi", Suite: "jessie", Binarypkg: "nvi", Section: "1", Language: "en"},
+			{Name: "vi", Suite: "jessie", Binarypkg: "nvi", Section: "1", Language: "fr"},
+			{Name: "vi", Suite: "jessie", Binarypkg: "nvi", Section: "3edit", Language: "en"},
+		},
+	}
+
+	table := []struct {
+		path          string
+		wantRequested Facets
+		wantExact     Facets
+	}{
+		{"/vi", 0, 0},
+		{"/jessie/vi", FacetSuite, FacetSuite},
+		{"/jessie/nvi/vi.1.fr", AllFacets, AllFacets},
+		// not available in Spanish
+		{"/jessie/vi.es", FacetSuite | FacetLanguage, FacetSuite},
+		// suite alias, resolved via Index.Suites
+		{"/stable/vi.1", FacetSuite | FacetSection, FacetSuite | FacetSection},
+		// only subsection 3edit exists, which is not an exact match
+		{"/vi.3", FacetSection, 0},
+	}
+	for _, entry := range table {
+		_, spec, err := idx.Match(&http.Request{URL: &url.URL{Path: entry.path}})
+		if _, ok := err.(*AmbiguousError); err != nil && !ok {
+			t.Errorf("Match(%q): %v", entry.path, err)
+			continue
+		}
+		if spec.Requested != entry.wantRequested || spec.Exact != entry.wantExact {
+			t.Errorf("Match(%q) = %v (requested %v), want exact=%v (requested %v)", entry.path, spec, spec.Requested, entry.wantExact, entry.wantRequested)
+		}
+		if got, want := spec.Score()+spec.Defaulted().Len(), 4; got != want {
+			t.Errorf("Match(%q): exact + defaulted facets = %d, want %d", entry.path, got, want)
+		}
+	}
+
+	if got, want := (Specificity{Requested: AllFacets, Exact: FacetSuite | FacetSection}).String(), "2/4 exact=suite,section defaulted=binarypkg,language"; got != want {
+		t.Errorf("Specificity.String() = %q, want %q", got, want)
+	}
+}
+
+// TestConcurrentLookups verifies that a single Index can serve
+// concurrent requests (run with -race) and is not modified by them.
+func TestConcurrentLookups(t *testing.T) {
+	before := make(map[string][]IndexEntry, len(testIdx.Entries))
+	for name, entries := range testIdx.Entries {
+		before[name] = append([]IndexEntry(nil), entries...)
+	}
+
+	paths := []string{
+		"/i3",
+		"/i3.fr",
+		"/jessie/i3",
+		"/dup",
+		"/dup.2",
+		"/jessie/manpages-dev/dup.2",
+		"/man",
+		"/git-rebase",
+		"/cgi-bin/man.cgi?query=i3",
+		"/notfound",
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 100; n++ {
+				for _, path := range paths {
+					u, err := url.Parse("http://man.debian.org" + path)
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					req := &http.Request{
+						URL: u,
+						Header: http.Header{
+							"Accept-Language": []string{"fr-CH, fr;q=0.9, en;q=0.8"},
+						},
+					}
+					testIdx.Lookup(req)
+					testIdx.Redirect(req)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if !reflect.DeepEqual(testIdx.Entries, before) {
+		t.Fatalf("concurrent lookups modified the index entries")
+	}
+}
+
+func TestInfoDocuments(t *testing.T) {
+	idx := testIdx // copy
+	idx.Sections = map[string]bool{"1": true, "i": true, "info": true}
+	idx.Entries = map[string][]IndexEntry{
+		"sed": []IndexEntry{
+			{Name: "sed", Suite: "jessie", Binarypkg: "sed", Section: "1", Language: "en"},
+			{Name: "sed", Suite: "jessie", Binarypkg: "sed", Section: "info", Language: "en"},
+		},
+		"coreutils": []IndexEntry{
+			{Name: "coreutils", Suite: "jessie", Binarypkg: "coreutils", Section: "info", Language: "en"},
+		},
+	}
+
+	table := []struct {
+		URL        string
+		want       string
+		wantFormat string
+	}{
+		// Manpages are preferred over info documents.
+		{"sed", "jessie/sed/sed.1.en.html", "man"},
+		{"sed.info", "jessie/sed/sed.info.en.html", "info"},
+		{"jessie/sed.info", "jessie/sed/sed.info.en.html", "info"},
+		{"coreutils", "jessie/coreutils/coreutils.info.en.html", "info"},
+	}
+	for _, entry := range table {
+		u, err := url.Parse("http://man.debian.org/" + entry.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := &http.Request{URL: u}
+		got, err := idx.Redirect(req)
+		if err != nil {
+			t.Fatalf("Redirect(%q): %v", entry.URL, err)
+		}
+		if want := "/" + entry.want; got != want {
+			t.Errorf("Redirect(%q) = %q, want %q", entry.URL, got, want)
+		}
+		e, err := idx.Lookup(req)
+		if err != nil {
+			t.Fatalf("Lookup(%q): %v", entry.URL, err)
+		}
+		if got, want := e.Format(), entry.wantFormat; got != want {
+			t.Errorf("Lookup(%q).Format() = %q, want %q", entry.URL, got, want)
+		}
+	}
+}
+
+func TestLatestAlias(t *testing.T) {
+	idx := testIdx // copy
+	idx.Suites = make(map[string]string, len(testIdx.Suites)+1)
+	for name, suite := range testIdx.Suites {
+		idx.Suites[name] = suite
+	}
+	idx.Suites["latest"] = "jessie" // as written by debiman -latest_suite=stable
+
+	table := []struct {
+		URL  string
+		want string
+	}{
+		{"latest/i3", "jessie/i3-wm/i3.1.en.html"},
+		{"latest/i3-wm/i3.1", "jessie/i3-wm/i3.1.en.html"},
+		{"latest/i3-wm/i3.1.en.html", "jessie/i3-wm/i3.1.en.html"},
+	}
+	for _, entry := range table {
+		u, err := url.Parse("http://man.debian.org/" + entry.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := idx.Redirect(&http.Request{URL: u})
+		if err != nil {
+			t.Fatalf("Redirect(%q): %v", entry.URL, err)
+		}
+		if want := "/" + entry.want; got != want {
+			t.Errorf("Redirect(%q) = %q, want %q", entry.URL, got, want)
+		}
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	overrides, broken, err := ParseOverrides(strings.NewReader(`# vanity URLs
+/window-manager /testing/i3-wm/i3.1
+/i3   /jessie/man.1
+
+/broken /jessie/o3
+/nopath i3
+/toomany /i3 /man
+/i3 /git-rebase
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(broken), 3; got != want {
+		t.Errorf("unexpected number of malformed lines: got %d (%v), want %d", got, broken, want)
+	}
+	valid, broken := testIdx.VerifyOverrides(overrides)
+	if got, want := len(broken), 1; got != want {
+		t.Errorf("unexpected number of broken overrides: got %d (%v), want %d", got, broken, want)
+	}
+	if _, ok := valid["/broken"]; ok {
+		t.Errorf("override /broken unexpectedly valid")
+	}
+
+	idx := testIdx // copy
+	idx.Overrides = valid
+	for _, entry := range []struct {
+		path string
+		want string
+	}{
+		{"/window-manager", "/testing/i3-wm/i3.1.en.html"},
+		{"/i3", "/jessie/man-db/man.1.en.html"}, // takes precedence
+		{"/i3.1", "/jessie/i3-wm/i3.1.en.html"},
+		{"/broken", ""},
+	} {
+		u, err := url.Parse(entry.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := idx.Redirect(&http.Request{URL: u})
+		if entry.want == "" {
+			if err == nil {
+				t.Errorf("Redirect(%s) unexpectedly succeeded: %q", entry.path, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Redirect(%s): %v", entry.path, err)
+			continue
+		}
+		if got != entry.want {
+			t.Errorf("Redirect(%s): got %q, want %q", entry.path, got, entry.want)
+		}
+	}
+}
+
+// binaryMap returns a binary rewrite map (see BinaryMap) containing
+// the single key /i3 in the given format version.
+func binaryMap(version uint32) []byte {
+	const (
+		key    = "/i3"
+		target = "/jessie/i3-wm/i3.1.en.html"
+	)
+	var b bytes.Buffer
+	b.WriteString(BinaryMapMagic)
+	for _, v := range []uint32{
+		version, 1, 1, uint32(len(key)), uint32(len(target)), 0, // header
+		0, uint32(len(key)), // key offsets
+		0,                      // key targets
+		0, uint32(len(target)), // target offsets
+	} {
+		b.Write([]byte{byte(v), byte(v >> 8), byte(v >> 16), byte(v >> 24)})
+	}
+	b.WriteString(key)
+	b.WriteString(target)
+	return b.Bytes()
+}
+
+func TestOpenBinaryMap(t *testing.T) {
+	f, err := ioutil.TempFile("", "debiman-rwmap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(binaryMap(BinaryMapVersion)); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := OpenBinaryMap(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if got, ok := m.Lookup("/i3"); !ok || got != "/jessie/i3-wm/i3.1.en.html" {
+		t.Errorf(`Lookup("/i3") = %q, %v, want "/jessie/i3-wm/i3.1.en.html", true`, got, ok)
+	}
+	if got, ok := m.Lookup("/i3."); ok {
+		t.Errorf(`Lookup("/i3.") = %q, want no match`, got)
+	}
+
+	if _, err := ParseBinaryMap(binaryMap(BinaryMapVersion + 1)); err == nil {
+		t.Errorf("ParseBinaryMap unexpectedly accepted an unsupported version")
+	}
+	if _, err := ParseBinaryMap([]byte("/i3 /jessie/i3-wm/i3.1.en.html\n")); err == nil {
+		t.Errorf("ParseBinaryMap unexpectedly accepted a text rewrite map")
+	}
+}
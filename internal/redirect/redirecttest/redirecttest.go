@@ -0,0 +1,44 @@
+// Package redirecttest provides a small redirect.Index for tests of
+// code which consumes an Index, so that they do not need to write an
+// index file.
+package redirecttest
+
+import "github.com/Debian/debiman/internal/redirect"
+
+// Index returns a new Index (see redirect.NewIndex) containing:
+//
+//	i3(1)          i3-wm        jessie, stretch  en, de
+//	crontab(1)     cron         jessie, stretch  en
+//	crontab(5)     cron         jessie, stretch  en, fr
+//	crontab(8)     systemd-cron stretch          en
+//	vi(1)          vim, nvi     stretch          en
+//
+// jessie is also reachable as oldstable, and stretch as stable. Each
+// call returns a separate Index, which the caller may modify.
+func Index() redirect.Index {
+	entries := make(map[string][]redirect.IndexEntry)
+	add := func(name, binarypkg, section string, suites, langs []string) {
+		for _, suite := range suites {
+			for _, lang := range langs {
+				entries[name] = append(entries[name], redirect.IndexEntry{
+					Name:      name,
+					Suite:     suite,
+					Binarypkg: binarypkg,
+					Section:   section,
+					Language:  lang,
+				})
+			}
+		}
+	}
+	both := []string{"jessie", "stretch"}
+	add("i3", "i3-wm", "1", both, []string{"en", "de"})
+	add("crontab", "cron", "1", both, []string{"en"})
+	add("crontab", "cron", "5", both, []string{"en", "fr"})
+	add("crontab", "systemd-cron", "8", []string{"stretch"}, []string{"en"})
+	add("vi", "vim", "1", []string{"stretch"}, []string{"en"})
+	add("vi", "nvi", "1", []string{"stretch"}, []string{"en"})
+	return redirect.NewIndex(entries, map[string]string{
+		"oldstable": "jessie",
+		"stable":    "stretch",
+	})
+}
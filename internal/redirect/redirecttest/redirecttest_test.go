@@ -0,0 +1,52 @@
+package redirecttest
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/Debian/debiman/internal/redirect"
+)
+
+func TestIndex(t *testing.T) {
+	idx := Index()
+	for _, entry := range []struct {
+		path string
+		want string
+	}{
+		{"/i3", "/stretch/i3-wm/i3.1.en.html"},
+		{"/oldstable/i3.de", "/jessie/i3-wm/i3.1.de.html"},
+		{"/crontab.5.fr", "/stretch/cron/crontab.5.fr.html"},
+		{"/systemd-cron/crontab", "/stretch/systemd-cron/crontab.8.en.html"},
+	} {
+		got, err := idx.Redirect(&http.Request{URL: &url.URL{Path: entry.path}})
+		if err != nil {
+			t.Errorf("Redirect(%q): %v", entry.path, err)
+			continue
+		}
+		if got != entry.want {
+			t.Errorf("Redirect(%q) = %q, want %q", entry.path, got, entry.want)
+		}
+	}
+
+	if _, err := idx.Lookup(&http.Request{URL: &url.URL{Path: "/vi"}}); err == nil {
+		t.Errorf("Lookup(/vi) unexpectedly succeeded")
+	} else if _, ok := err.(*redirect.AmbiguousError); !ok {
+		t.Errorf("Lookup(/vi): got %v, want a *redirect.AmbiguousError", err)
+	}
+
+	for _, l := range []string{"en", "de", "fr"} {
+		if !idx.Langs[l] {
+			t.Errorf("Langs does not contain %q", l)
+		}
+	}
+	if got, want := idx.Suites["stretch"], "stretch"; got != want {
+		t.Errorf(`Suites["stretch"] = %q, want %q`, got, want)
+	}
+
+	// Each call returns a separate Index.
+	idx.Entries["i3"] = nil
+	if len(Index().Entries["i3"]) == 0 {
+		t.Errorf("modifying an Index affects subsequent calls")
+	}
+}
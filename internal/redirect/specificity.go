@@ -0,0 +1,105 @@
+package redirect
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Facets is a set of the components by which a request can narrow down
+// a manpage.
+type Facets uint8
+
+const (
+	FacetSuite Facets = 1 << iota
+	FacetBinarypkg
+	FacetSection
+	FacetLanguage
+
+	AllFacets = FacetSuite | FacetBinarypkg | FacetSection | FacetLanguage
+)
+
+var facetNames = []struct {
+	facet Facets
+	name  string
+}{
+	{FacetSuite, "suite"},
+	{FacetBinarypkg, "binarypkg"},
+	{FacetSection, "section"},
+	{FacetLanguage, "language"},
+}
+
+// String returns the comma-separated names of the facets in f, e.g.
+// “suite,section”.
+func (f Facets) String() string {
+	var names []string
+	for _, fn := range facetNames {
+		if f&fn.facet != 0 {
+			names = append(names, fn.name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// Len returns the number of facets in f.
+func (f Facets) Len() int {
+	var n int
+	for _, fn := range facetNames {
+		if f&fn.facet != 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// Specificity describes how well a manpage matches a request.
+type Specificity struct {
+	// Requested contains the facets which the request specified.
+	Requested Facets
+
+	// Exact contains the requested facets which the manpage satisfies
+	// exactly. A requested section is only satisfied exactly by the
+	// same section, e.g. not “3” by “3pm”.
+	Exact Facets
+}
+
+// Defaulted returns the facets which were picked by Narrow because the
+// request did not specify them or because the manpage is not available
+// as requested (e.g. in the requested language).
+func (s Specificity) Defaulted() Facets {
+	return AllFacets &^ s.Exact
+}
+
+// Score returns the number of facets satisfied exactly, from 0 (e.g.
+// /crontab) to 4 (e.g. /jessie/cron/crontab.5.en).
+func (s Specificity) Score() int {
+	return s.Exact.Len()
+}
+
+// String returns e.g. “2/4 exact=suite,section defaulted=binarypkg,language”.
+func (s Specificity) String() string {
+	return fmt.Sprintf("%d/%d exact=%s defaulted=%s", s.Score(), AllFacets.Len(), s.Exact, s.Defaulted())
+}
+
+// specificity returns how well entry matches the request template (as
+// passed to Narrow, i.e. with empty fields for unspecified facets).
+func specificity(template, entry IndexEntry) Specificity {
+	var s Specificity
+	for _, f := range []struct {
+		facet            Facets
+		requested, match string
+	}{
+		{FacetSuite, template.Suite, entry.Suite},
+		{FacetBinarypkg, template.Binarypkg, entry.Binarypkg},
+		{FacetSection, template.Section, entry.Section},
+		{FacetLanguage, template.Language, entry.Language},
+	} {
+		if f.requested == "" {
+			continue
+		}
+		s.Requested |= f.facet
+		if f.requested == f.match {
+			s.Exact |= f.facet
+		}
+	}
+	return s
+}
@@ -0,0 +1,42 @@
+// Package releases orders Debian suites chronologically, e.g. for the
+// suite switcher of a manpage.
+package releases
+
+// TODO(later): move this list to a package within pault.ag/debian/?
+var releaseList = []string{
+	"buzz",
+	"rex",
+	"bo",
+	"hamm",
+	"slink",
+	"potato",
+	"woody",
+	"sarge",
+	"etch",
+	"lenny",
+	"squeeze",
+	"wheezy",
+	"wheezy-backports",
+	"jessie",
+	"jessie-backports",
+	"stretch",
+	"stretch-backports",
+	"buster",
+	"buster-backports",
+	"bullseye",
+	"bullseye-backports",
+}
+
+// SortOrder maps suite names (codenames like “jessie” and the suites
+// “testing”, “unstable” and “experimental”) to their position in
+// chronological order. Suites with a higher value are newer.
+var SortOrder = make(map[string]int)
+
+func init() {
+	for idx, r := range releaseList {
+		SortOrder[r] = idx
+	}
+	SortOrder["testing"] = SortOrder["stretch"]
+	SortOrder["unstable"] = len(releaseList)
+	SortOrder["experimental"] = SortOrder["unstable"] + 1
+}
@@ -0,0 +1,137 @@
+package releases
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Selection restricts the suites to publish, see ParseSelection. A nil
+// *Selection selects all suites.
+type Selection struct {
+	names  map[string]bool
+	latest int
+}
+
+// ParseSelection parses spec, which is either empty (all suites), a
+// comma-separated list of suite names (e.g. “stretch,buster,unstable”)
+// or “latest:N”, which selects the N newest suites in SortOrder.
+func ParseSelection(spec string) (*Selection, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+	if strings.HasPrefix(spec, "latest:") {
+		n, err := strconv.Atoi(strings.TrimPrefix(spec, "latest:"))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid suite selection %q: expected latest:N with N ≥ 1", spec)
+		}
+		return &Selection{latest: n}, nil
+	}
+	s := &Selection{names: make(map[string]bool)}
+	for _, name := range strings.Split(spec, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			s.names[name] = true
+		}
+	}
+	return s, nil
+}
+
+func (s *Selection) String() string {
+	if s == nil {
+		return "all suites"
+	}
+	if s.latest > 0 {
+		return fmt.Sprintf("latest:%d", s.latest)
+	}
+	names := make([]string, 0, len(s.names))
+	for name := range s.names {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// Check returns an error if s selects none of the candidates (see
+// Select), or if one of the suite names of s refers to none of them,
+// e.g. because it is misspelled. A nil *Selection passes the check.
+func (s *Selection) Check(candidates map[string][]string) error {
+	if s == nil {
+		return nil
+	}
+	known := make(map[string]bool, len(candidates))
+	for suite, names := range candidates {
+		known[suite] = true
+		for _, name := range names {
+			known[name] = true
+		}
+	}
+	var unknown []string
+	for name := range s.names {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("suite selection %q: %s not found", s, strings.Join(unknown, ", "))
+	}
+	if len(s.Select(candidates)) == 0 {
+		return fmt.Errorf("suite selection %q selects none of the %d suites", s, len(candidates))
+	}
+	return nil
+}
+
+// Select returns the selected suites of the candidates, which map each
+// suite to the names it is known by (e.g. “stretch” to “stable” and
+// “stretch”). A list selects a suite if it contains the suite or any
+// of its names. Suites missing from SortOrder are considered oldest.
+func (s *Selection) Select(candidates map[string][]string) map[string]bool {
+	selected := make(map[string]bool, len(candidates))
+	if s == nil {
+		for suite := range candidates {
+			selected[suite] = true
+		}
+		return selected
+	}
+	if s.latest > 0 {
+		suites := make([]string, 0, len(candidates))
+		for suite := range candidates {
+			suites = append(suites, suite)
+		}
+		order := func(suite string) int {
+			if o, ok := SortOrder[suite]; ok {
+				return o
+			}
+			return -1
+		}
+		sort.Slice(suites, func(i, j int) bool {
+			oi, oj := order(suites[i]), order(suites[j])
+			if oi != oj {
+				return oi > oj
+			}
+			return suites[i] < suites[j]
+		})
+		if len(suites) > s.latest {
+			suites = suites[:s.latest]
+		}
+		for _, suite := range suites {
+			selected[suite] = true
+		}
+		return selected
+	}
+	for suite, names := range candidates {
+		if s.names[suite] {
+			selected[suite] = true
+			continue
+		}
+		for _, name := range names {
+			if s.names[name] {
+				selected[suite] = true
+				break
+			}
+		}
+	}
+	return selected
+}
@@ -0,0 +1,63 @@
+package releases
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelection(t *testing.T) {
+	candidates := map[string][]string{
+		"wheezy":   {"oldoldstable", "wheezy"},
+		"jessie":   {"jessie", "oldstable"},
+		"stretch":  {"stable", "stretch"},
+		"buster":   {"buster"},
+		"unstable": {"sid", "unstable"},
+	}
+	for _, entry := range []struct {
+		spec string
+		want map[string]bool
+	}{
+		{"", map[string]bool{"wheezy": true, "jessie": true, "stretch": true, "buster": true, "unstable": true}},
+		{"stretch, buster", map[string]bool{"stretch": true, "buster": true}},
+		{"stable,sid", map[string]bool{"stretch": true, "unstable": true}},
+		{"bullseye", map[string]bool{}},
+		{"latest:3", map[string]bool{"stretch": true, "buster": true, "unstable": true}},
+		{"latest:10", map[string]bool{"wheezy": true, "jessie": true, "stretch": true, "buster": true, "unstable": true}},
+	} {
+		s, err := ParseSelection(entry.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := s.Select(candidates); !reflect.DeepEqual(got, entry.want) {
+			t.Errorf("ParseSelection(%q).Select() = %v, want %v", entry.spec, got, entry.want)
+		}
+	}
+
+	for _, entry := range []struct {
+		spec       string
+		candidates map[string][]string
+		wantErr    bool
+	}{
+		{"", candidates, false},
+		{"", nil, false},
+		{"stable,sid", candidates, false},
+		{"latest:3", candidates, false},
+		{"bullseye", candidates, true},
+		{"stretch,bullseye", candidates, true},
+		{"latest:3", nil, true},
+	} {
+		s, err := ParseSelection(entry.spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Check(entry.candidates); (err != nil) != entry.wantErr {
+			t.Errorf("ParseSelection(%q).Check(%v) = %v, want error: %v", entry.spec, entry.candidates, err, entry.wantErr)
+		}
+	}
+
+	for _, spec := range []string{"latest:0", "latest:", "latest:three"} {
+		if _, err := ParseSelection(spec); err == nil {
+			t.Errorf("ParseSelection(%q) unexpectedly succeeded", spec)
+		}
+	}
+}
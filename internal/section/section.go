@@ -0,0 +1,64 @@
+// Package section orders manpage sections, such as “3”, “3perl” or
+// “30”, the way users expect: by their number, then by their suffix.
+package section
+
+import "strings"
+
+// split returns the leading digits of s (without leading zeros) and
+// the remainder, e.g. “3” and “perl” for “3perl”.
+func split(s string) (number, suffix string) {
+	i := 0
+	for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+	return strings.TrimLeft(s[:i], "0"), s[i:]
+}
+
+// Main returns the main section of s, i.e. its leading number (e.g.
+// “3” for “3perl” and “30” for “30”), or its first character for
+// sections which do not start with a number (e.g. “n”).
+func Main(s string) string {
+	number, suffix := split(s)
+	if number != "" || s == "" {
+		return s[:len(s)-len(suffix)]
+	}
+	return s[:1]
+}
+
+// Compare returns -1 if a sorts before b, 1 if a sorts after b and 0
+// if they are equal. Sections are ordered by their leading number
+// (sections without one, e.g. “n”, come last), then by their suffix,
+// with the plain section first: 3, 3p, 3perl, 3ssl, 30, n.
+func Compare(a, b string) int {
+	an, as := split(a)
+	bn, bs := split(b)
+	hasA, hasB := len(a) > len(as), len(b) > len(bs)
+	switch {
+	case hasA && !hasB:
+		return -1
+	case !hasA && hasB:
+		return 1
+	}
+	// Compare the numbers digit by digit, so that arbitrarily long
+	// numbers cannot overflow.
+	if len(an) != len(bn) {
+		if len(an) < len(bn) {
+			return -1
+		}
+		return 1
+	}
+	if c := strings.Compare(an, bn); c != 0 {
+		return c
+	}
+	if c := strings.Compare(as, bs); c != 0 {
+		return c
+	}
+	// Equal except for leading zeros (e.g. “03” and “3”): fall back
+	// to the string order, so that the order is total.
+	return strings.Compare(a, b)
+}
+
+// Less returns whether section a sorts before section b, see Compare.
+func Less(a, b string) bool {
+	return Compare(a, b) < 0
+}
@@ -0,0 +1,55 @@
+package section
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	for _, entry := range []struct {
+		a, b string
+		want int
+	}{
+		{"3", "3", 0},
+		{"3", "3p", -1},
+		{"3p", "3perl", -1},
+		{"3perl", "3ssl", -1},
+		{"3ssl", "30", -1},
+		{"30", "3perl", 1},
+		{"9", "10", -1},
+		{"1", "n", -1},
+		{"n", "l", 1},
+		{"03", "3", -1},
+		{"", "1", 1},
+		{"99999999999999999999", "100000000000000000000", -1},
+	} {
+		if got := Compare(entry.a, entry.b); got != entry.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", entry.a, entry.b, got, entry.want)
+		}
+		if got := Compare(entry.b, entry.a); got != -entry.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", entry.b, entry.a, got, -entry.want)
+		}
+	}
+
+	sections := []string{"30", "3perl", "n", "3", "1", "3ssl", "3p", "8"}
+	sort.Slice(sections, func(i, j int) bool { return Less(sections[i], sections[j]) })
+	if want := []string{"1", "3", "3p", "3perl", "3ssl", "8", "30", "n"}; !reflect.DeepEqual(sections, want) {
+		t.Errorf("sorted sections = %q, want %q", sections, want)
+	}
+}
+
+func TestMainSection(t *testing.T) {
+	for section, want := range map[string]string{
+		"3":     "3",
+		"3perl": "3",
+		"30":    "30",
+		"n":     "n",
+		"nx":    "n",
+		"":      "",
+	} {
+		if got := Main(section); got != want {
+			t.Errorf("Main(%q) = %q, want %q", section, got, want)
+		}
+	}
+}
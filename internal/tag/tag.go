@@ -44,3 +44,39 @@ func FromLocale(l string) (language.Tag, error) {
 
 	return language.Parse(l)
 }
+
+// Canonical returns the canonical spelling of the locale-like language
+// l (language[_territory][@modifier], without codeset), e.g. “pt_BR” for
+// “pt_br”, “pt-BR” or “PT_br”: the language in lowercase and the
+// territory in uppercase, separated by an underscore, as most Debian
+// packages spell it. The modifier is retained as-is. Languages which
+// cannot be parsed or which carry further subtags (e.g. a script) are
+// returned unmodified.
+func Canonical(l string) string {
+	var modifier string
+	if idx := strings.Index(l, "@"); idx > -1 {
+		l, modifier = l[:idx], l[idx:]
+	}
+	// Raw: spell the language as found, do not e.g. replace
+	// deprecated codes, which would move the manpages.
+	t, err := language.Raw.Parse(l)
+	if err != nil {
+		return l + modifier
+	}
+	if _, c := t.Script(); c == language.Exact || len(t.Variants()) > 0 {
+		return l + modifier
+	}
+	base, c := t.Base()
+	if c != language.Exact {
+		return l + modifier
+	}
+	canonical := base.String()
+	if region, c := t.Region(); c == language.Exact {
+		canonical += "_" + region.String()
+	}
+	if !strings.EqualFold(strings.Replace(canonical, "_", "-", -1), strings.Replace(l, "_", "-", -1)) {
+		// e.g. extensions or private use subtags
+		return l + modifier
+	}
+	return canonical + modifier
+}
@@ -0,0 +1,28 @@
+package tag
+
+import "testing"
+
+func TestCanonical(t *testing.T) {
+	for _, entry := range []struct {
+		locale string
+		want   string
+	}{
+		{"pt_BR", "pt_BR"},
+		{"pt_br", "pt_BR"},
+		{"pt-BR", "pt_BR"},
+		{"PT_br", "pt_BR"},
+		{"de", "de"},
+		{"DE", "de"},
+		{"sr@latin", "sr@latin"},
+		{"SR_rs@latin", "sr_RS@latin"},
+		{"ca@valencia", "ca@valencia"},
+		// Not representable as language[_territory], retained as-is.
+		{"zh-Hant", "zh-Hant"},
+		{"en-x-foo", "en-x-foo"},
+		{"not a language", "not a language"},
+	} {
+		if got := Canonical(entry.locale); got != entry.want {
+			t.Errorf("Canonical(%q) = %q, want %q", entry.locale, got, entry.want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Package urlcase implements the policies which govern how manpage
+// names, binary packages and suites are cased in URLs, both in lookup
+// keys (redirects, rewrite maps) and in serving paths.
+package urlcase
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Policy int
+
+const (
+	// Default lowercases manpage names in lookup keys (so that
+	// e.g. /LS redirects to ls(1)), but preserves the case of all
+	// components in serving paths.
+	Default Policy = iota
+
+	// Lower lowercases manpage names, binary packages and suites,
+	// both in lookup keys and in serving paths.
+	Lower
+
+	// Preserve preserves the case of all components, i.e. lookups
+	// are case-sensitive.
+	Preserve
+)
+
+var names = map[Policy]string{
+	Default:  "default",
+	Lower:    "lower",
+	Preserve: "preserve",
+}
+
+func (p Policy) String() string {
+	if name, ok := names[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("Policy(%d)", int(p))
+}
+
+// Parse parses a policy name as returned by String. The empty string
+// is parsed as Default, so that indexes written before policies were
+// introduced keep working.
+func Parse(s string) (Policy, error) {
+	if s == "" {
+		return Default, nil
+	}
+	for p, name := range names {
+		if name == s {
+			return p, nil
+		}
+	}
+	return Default, fmt.Errorf("unknown URL case policy %q (want one of default, lower, preserve)", s)
+}
+
+// Name returns the lookup key for the manpage name s.
+func (p Policy) Name(s string) string {
+	if p == Preserve {
+		return s
+	}
+	return strings.ToLower(s)
+}
+
+// Path returns the serving path component (name, binary package or
+// suite) for s.
+func (p Policy) Path(s string) string {
+	if p == Lower {
+		return strings.ToLower(s)
+	}
+	return s
+}
+
+// SamePaths returns whether p and o result in the same serving paths,
+// i.e. whether files written under policy o can be re-used under p.
+func (p Policy) SamePaths(o Policy) bool {
+	return (p == Lower) == (o == Lower)
+}
@@ -0,0 +1,52 @@
+package urlcase
+
+import "testing"
+
+func TestPolicy(t *testing.T) {
+	table := []struct {
+		policy   Policy
+		name     string
+		wantName string
+		wantPath string
+	}{
+		{Default, "Xorg", "xorg", "Xorg"},
+		{Lower, "Xorg", "xorg", "xorg"},
+		{Preserve, "Xorg", "Xorg", "Xorg"},
+		{Default, "ls", "ls", "ls"},
+	}
+	for _, entry := range table {
+		if got, want := entry.policy.Name(entry.name), entry.wantName; got != want {
+			t.Errorf("%v.Name(%q) = %q, want %q", entry.policy, entry.name, got, want)
+		}
+		if got, want := entry.policy.Path(entry.name), entry.wantPath; got != want {
+			t.Errorf("%v.Path(%q) = %q, want %q", entry.policy, entry.name, got, want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, p := range []Policy{Default, Lower, Preserve} {
+		got, err := Parse(p.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != p {
+			t.Errorf("Parse(%q) = %v, want %v", p.String(), got, p)
+		}
+	}
+	if got, err := Parse(""); err != nil || got != Default {
+		t.Errorf("Parse(\"\") = %v, %v, want %v, nil", got, err, Default)
+	}
+	if _, err := Parse("upper"); err == nil {
+		t.Errorf("Parse(\"upper\") unexpectedly succeeded")
+	}
+}
+
+func TestSamePaths(t *testing.T) {
+	if !Default.SamePaths(Preserve) {
+		t.Errorf("Default and Preserve should result in the same paths")
+	}
+	if Default.SamePaths(Lower) {
+		t.Errorf("Default and Lower should not result in the same paths")
+	}
+}
@@ -20,7 +20,21 @@ func tempDir(dest string) string {
 	return tempdir
 }
 
-func Atomically(dest string, compress bool, write func(w io.Writer) error) (err error) {
+func Atomically(dest string, compress bool, write func(w io.Writer) error) error {
+	return atomically(dest, compress, false, write)
+}
+
+// AtomicallySynced is like Atomically, but additionally fsyncs the
+// file before renaming it into place, so that dest never refers to a
+// partially written file, even after a system crash. This is too
+// expensive for manpages, but appropriate for e.g. the auxserver
+// index.
+func AtomicallySynced(dest string, write func(w io.Writer) error) error {
+	return atomically(dest, false, true, write)
+}
+
+func atomically(dest string, compress, sync bool, write func(w io.Writer) error) (err error) {
+	write = transformed(dest, write)
 	f, err := ioutil.TempFile(tempDir(dest), "debiman-")
 	if err != nil {
 		return err
@@ -36,7 +50,10 @@ func Atomically(dest string, compress bool, write func(w io.Writer) error) (err
 	bufw := bufio.NewWriter(f)
 
 	w := io.Writer(bufw)
-	var gzipw *gzip.Writer
+	var (
+		gzipw *gzip.Writer
+		pw    *plainWriter
+	)
 	if compress {
 		// NOTE(stapelberg): gzip’s decompression phase takes the same
 		// time, regardless of compression level. Hence, we invest the
@@ -47,6 +64,18 @@ func Atomically(dest string, compress bool, write func(w io.Writer) error) (err
 		}
 		defer gzipw.Close()
 		w = gzipw
+
+		if pw, err = newPlainWriter(dest); err != nil {
+			return err
+		}
+		if pw != nil {
+			defer func() {
+				if err != nil {
+					pw.abort()
+				}
+			}()
+			w = io.MultiWriter(w, pw)
+		}
 	}
 
 	if err := write(w); err != nil {
@@ -67,14 +96,33 @@ func Atomically(dest string, compress bool, write func(w io.Writer) error) (err
 		return err
 	}
 
+	if sync {
+		if err := f.Sync(); err != nil {
+			return err
+		}
+	}
+
 	if err := f.Close(); err != nil {
 		return err
 	}
 
+	if compress {
+		if err := removeStaleZstd(dest); err != nil {
+			return err
+		}
+	}
+
+	if pw != nil {
+		if err := pw.commit(); err != nil {
+			return err
+		}
+	}
+
 	return os.Rename(f.Name(), dest)
 }
 
 func AtomicallyWithGz(dest string, gzipw *gzip.Writer, write func(w io.Writer) error) (err error) {
+	write = transformed(dest, write)
 	f, err := ioutil.TempFile(tempDir(dest), "debiman-")
 	if err != nil {
 		return err
@@ -90,7 +138,21 @@ func AtomicallyWithGz(dest string, gzipw *gzip.Writer, write func(w io.Writer) e
 	bufw := bufio.NewWriter(f)
 	gzipw.Reset(bufw)
 
-	if err := write(gzipw); err != nil {
+	w := io.Writer(gzipw)
+	pw, err := newPlainWriter(dest)
+	if err != nil {
+		return err
+	}
+	if pw != nil {
+		defer func() {
+			if err != nil {
+				pw.abort()
+			}
+		}()
+		w = io.MultiWriter(w, pw)
+	}
+
+	if err := write(w); err != nil {
 		return err
 	}
 
@@ -110,5 +172,15 @@ func AtomicallyWithGz(dest string, gzipw *gzip.Writer, write func(w io.Writer) e
 		return err
 	}
 
+	if err := removeStaleZstd(dest); err != nil {
+		return err
+	}
+
+	if pw != nil {
+		if err := pw.commit(); err != nil {
+			return err
+		}
+	}
+
 	return os.Rename(f.Name(), dest)
 }
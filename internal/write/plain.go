@@ -0,0 +1,107 @@
+package write
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// PlainVariant, if non-nil, is consulted for each file which Atomically
+// or AtomicallyWithGz compresses. If it returns true for dest, the
+// uncompressed content is written to PlainPath(dest) as well, e.g. for
+// browsers opening the serving directory via file://, which do not
+// decode gzip-compressed files. Like ZstdVariant, it must not be
+// modified while files are written.
+var PlainVariant func(dest string) bool
+
+// Transform, if non-nil, is applied to the (uncompressed) content of
+// each file which Atomically or AtomicallyWithGz writes to dest before
+// the content is compressed, e.g. to rewrite the links of pages. It
+// must return content unmodified for files it is not interested in.
+// Like ZstdVariant, it must not be modified while files are written.
+var Transform func(dest string, content []byte) []byte
+
+// PlainPath returns the path of the uncompressed variant of dest, e.g.
+// ls.1.en.html for ls.1.en.html.gz.
+func PlainPath(dest string) string {
+	return strings.TrimSuffix(dest, ".gz")
+}
+
+// transformed returns write, with Transform applied to the content
+// which it writes.
+func transformed(dest string, write func(w io.Writer) error) func(w io.Writer) error {
+	if Transform == nil {
+		return write
+	}
+	return func(w io.Writer) error {
+		var buf bytes.Buffer
+		if err := write(&buf); err != nil {
+			return err
+		}
+		_, err := w.Write(Transform(dest, buf.Bytes()))
+		return err
+	}
+}
+
+// plainWriter writes the content written to it into a temporary file,
+// which commit places at the variant path of dest.
+type plainWriter struct {
+	dest string
+	f    *os.File
+	bufw *bufio.Writer
+	done bool // commit was called
+}
+
+// newPlainWriter returns a plainWriter for dest if PlainVariant
+// requests a variant, or nil otherwise.
+func newPlainWriter(dest string) (*plainWriter, error) {
+	if PlainVariant == nil || !PlainVariant(dest) || PlainPath(dest) == dest {
+		return nil, nil
+	}
+	f, err := ioutil.TempFile(tempDir(dest), "debiman-")
+	if err != nil {
+		return nil, err
+	}
+	return &plainWriter{
+		dest: dest,
+		f:    f,
+		bufw: bufio.NewWriter(f),
+	}, nil
+}
+
+func (p *plainWriter) Write(b []byte) (int, error) {
+	return p.bufw.Write(b)
+}
+
+// commit places the variant.
+func (p *plainWriter) commit() (err error) {
+	p.done = true
+	defer func() {
+		if err != nil {
+			os.Remove(p.f.Name())
+		}
+	}()
+	defer p.f.Close()
+	if err := p.bufw.Flush(); err != nil {
+		return err
+	}
+	if err := p.f.Chmod(0644); err != nil {
+		return err
+	}
+	if err := p.f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(p.f.Name(), PlainPath(p.dest))
+}
+
+// abort discards the variant, unless commit was called.
+func (p *plainWriter) abort() {
+	if p.done {
+		return
+	}
+	p.f.Close()
+	os.Remove(p.f.Name())
+}
@@ -0,0 +1,92 @@
+package write
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPlainVariant(t *testing.T) {
+	dir, err := ioutil.TempDir("", "debiman-write")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	oldPlain, oldTransform := PlainVariant, Transform
+	defer func() { PlainVariant, Transform = oldPlain, oldTransform }()
+	PlainVariant = func(dest string) bool { return strings.HasSuffix(dest, ".html.gz") }
+	Transform = func(dest string, content []byte) []byte {
+		return bytes.Replace(content, []byte(`href="/`), []byte(`href="../`), -1)
+	}
+
+	const want = `<a href="../index.html">index</a>`
+	dest := filepath.Join(dir, "ls.1.en.html.gz")
+	if err := Atomically(dest, true, func(w io.Writer) error {
+		_, err := io.WriteString(w, `<a href="/index.html">index</a>`)
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(PlainPath(dest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b); got != want {
+		t.Errorf("%s: got %q, want %q", PlainPath(dest), got, want)
+	}
+	f, err := os.Open(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gzipr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err = ioutil.ReadAll(gzipr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(b); got != want {
+		t.Errorf("%s: got %q, want %q", dest, got, want)
+	}
+
+	if err := RemoveVariants(dest); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(PlainPath(dest)); !os.IsNotExist(err) {
+		t.Errorf("%s: variant not removed: %v", PlainPath(dest), err)
+	}
+
+	// Files without a variant, and failed writes, leave no variant.
+	for _, fn := range []string{"ls.1.en.gz", "cat.1.en.html.gz"} {
+		dest := filepath.Join(dir, fn)
+		Atomically(dest, true, func(w io.Writer) error {
+			if _, err := io.WriteString(w, want); err != nil {
+				return err
+			}
+			if strings.HasSuffix(fn, ".html.gz") {
+				return io.ErrUnexpectedEOF
+			}
+			return nil
+		})
+		if _, err := os.Stat(PlainPath(dest)); !os.IsNotExist(err) {
+			t.Errorf("%s: unexpected variant: %v", PlainPath(dest), err)
+		}
+	}
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fi := range fis {
+		if strings.HasPrefix(fi.Name(), "debiman-") {
+			t.Errorf("temporary file %q left behind", fi.Name())
+		}
+	}
+}
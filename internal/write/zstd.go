@@ -0,0 +1,48 @@
+package write
+
+import (
+	"os"
+	"strings"
+)
+
+// ZstdVariant, if non-nil, is consulted for each file which Atomically
+// or AtomicallyWithGz compresses. If it returns true for dest, the
+// zstd-compressed variant at ZstdPath(dest) is removed when dest is
+// written, as it no longer matches dest. debiman does not write these
+// variants itself, but operators can add them after a run (see the
+// README). Like convert.Mandoc, it must not be modified while files are
+// written.
+var ZstdVariant func(dest string) bool
+
+// ZstdPath returns the path of the zstd-compressed variant of dest,
+// e.g. ls.1.en.html.zst for ls.1.en.html.gz.
+func ZstdPath(dest string) string {
+	return strings.TrimSuffix(dest, ".gz") + ".zst"
+}
+
+// RemoveVariants removes the variants of dest (see ZstdVariant and
+// PlainVariant), e.g. after removing dest itself. Variants which do not
+// exist are ignored.
+func RemoveVariants(dest string) error {
+	if err := os.Remove(ZstdPath(dest)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if PlainVariant != nil && PlainVariant(dest) && PlainPath(dest) != dest {
+		if err := os.Remove(PlainPath(dest)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeStaleZstd removes the zstd variant of dest (see ZstdVariant),
+// if any.
+func removeStaleZstd(dest string) error {
+	if ZstdVariant == nil || !ZstdVariant(dest) {
+		return nil
+	}
+	if err := os.Remove(ZstdPath(dest)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package write
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestZstdVariant(t *testing.T) {
+	dir, err := ioutil.TempDir("", "debiman-write")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	old := ZstdVariant
+	defer func() { ZstdVariant = old }()
+	ZstdVariant = func(dest string) bool { return strings.HasSuffix(dest, ".html.gz") }
+
+	for _, entry := range []struct {
+		fn          string
+		withGz      bool
+		fail        bool
+		wantRemoved bool
+	}{
+		{fn: "ls.1.en.html.gz", wantRemoved: true},
+		{fn: "cat.1.en.html.gz", withGz: true, wantRemoved: true},
+		// Files without a variant, and failed writes, keep the
+		// variant (the file is not replaced).
+		{fn: "ls.1.en.gz"},
+		{fn: "rm.1.en.html.gz", fail: true},
+	} {
+		dest := filepath.Join(dir, entry.fn)
+		// A variant an operator produced after the previous run.
+		if err := ioutil.WriteFile(ZstdPath(dest), []byte("stale"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		write := func(w io.Writer) error {
+			if _, err := io.WriteString(w, "<p>ls</p>\n"); err != nil {
+				return err
+			}
+			if entry.fail {
+				return io.ErrUnexpectedEOF
+			}
+			return nil
+		}
+		if entry.withGz {
+			err = AtomicallyWithGz(dest, gzip.NewWriter(nil), write)
+		} else {
+			err = Atomically(dest, true, write)
+		}
+		if got, want := err != nil, entry.fail; got != want {
+			t.Fatalf("%s: write failed: %v, want failure: %v", entry.fn, err, want)
+		}
+		_, err := os.Stat(ZstdPath(dest))
+		if got := os.IsNotExist(err); got != entry.wantRemoved {
+			t.Errorf("%s: variant removed: %v, want %v (err: %v)", entry.fn, got, entry.wantRemoved, err)
+		}
+	}
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fi := range fis {
+		if strings.HasPrefix(fi.Name(), "debiman-") {
+			t.Errorf("temporary file %q left behind", fi.Name())
+		}
+	}
+}